@@ -2,9 +2,13 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,12 +17,14 @@ import (
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/donaldgifford/zfs_exporter/collector"
 	"github.com/donaldgifford/zfs_exporter/config"
 	"github.com/donaldgifford/zfs_exporter/exporter"
+	"github.com/donaldgifford/zfs_exporter/pkg/custom"
 	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+	"github.com/donaldgifford/zfs_exporter/pkg/sdnotify"
 	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
 )
 
@@ -35,7 +41,19 @@ func main() {
 	app.HelpFlag.Short('h')
 
 	cfg := config.NewConfig(app)
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	app.Command("serve", "Run the exporter (default).").Default()
+
+	healthcheckCmd := app.Command("healthcheck", "Check a running exporter's /healthz, or collect locally with --local, and exit non-zero on failure.")
+	healthcheckURL := healthcheckCmd.Flag("url", "Health URL to check (default: /healthz on --web.listen-address).").String()
+	healthcheckTimeout := healthcheckCmd.Flag("timeout", "Timeout for the health request.").Default("5s").Duration()
+	healthcheckLocal := healthcheckCmd.Flag("local", "Run one collection in this process instead of querying a running exporter.").Bool()
+
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	if err := cfg.LoadFile(); err != nil {
+		kingpin.Fatalf("%v", err)
+	}
 
 	logger := setupLogger(cfg.LogLevel)
 
@@ -44,6 +62,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	if command == healthcheckCmd.FullCommand() && !*healthcheckLocal {
+		os.Exit(healthcheck(cfg.ListenAddress, *healthcheckURL, *healthcheckTimeout, logger))
+	}
+
 	if err := cfg.Validate(); err != nil {
 		logger.Error("Configuration validation failed", "err", err)
 		os.Exit(1)
@@ -54,25 +76,204 @@ func main() {
 		"listen", cfg.ListenAddress,
 		"zpool_path", cfg.ZpoolPath,
 		"zfs_path", cfg.ZfsPath,
+		"backend", cfg.Backend,
+		"sudo", cfg.ZfsSudo,
 		"services", cfg.Services,
 	)
 
-	// Create ZFS client and service checker.
-	runner := zfs.DefaultRunner()
+	// Create ZFS client and service checker. Every command run is timed.
+	cmdDurations := collector.NewCommandDurations()
+	baseRunner, streamRunner, streamer := localRunners(cfg, logger)
+	runner := zfs.TimedRunner(baseRunner, cmdDurations.Observe)
 	client := zfs.NewClient(runner, logger, cfg.ZpoolPath, cfg.ZfsPath)
+	if !cfg.ZfsJSON {
+		client.DisableJSON()
+	}
+
+	client.SetStreamRunner(zfs.TimedStreamRunner(streamRunner, cmdDurations.Observe))
+	client.SetDatasetDepth(cfg.DatasetDepth)
+	client.SetDatasetTypes(cfg.DatasetTypes)
+	client.SetSnapshotLimit(cfg.SnapshotMax)
+	client.SetSnapshotPaging(cfg.SnapshotPerPool)
+	client.SetStatusConcurrency(cfg.StatusConcurrency)
+
+	if cfg.Backend == config.BackendCLI {
+		detectColumns(client, cfg.ScrapeTimeout, logger)
+	}
+
+	initSystem := cfg.HostInit
+	if initSystem == host.InitAuto {
+		initSystem = host.DetectInit()
+	}
+
+	logger.Info("Checking services via init system", "init", initSystem)
+
 	svcChecker := host.NewServiceChecker(runner, logger)
+	svcChecker.SetInit(initSystem)
+	svcChecker.SetPorts(cfg.ServicePorts)
 
 	// Build service map from configured keys.
-	services := buildServiceMap(cfg.Services)
+	services := buildServiceMap(cfg.Services, host.DefaultServices(initSystem), cfg.ServicePorts)
+
+	var hooks []custom.Hook
+
+	if cfg.CustomHooksFile != "" {
+		var err error
+
+		hooks, err = custom.LoadHooks(cfg.CustomHooksFile)
+		if err != nil {
+			logger.Error("Failed to load custom hooks", "err", err)
+			os.Exit(1)
+		}
+
+		logger.Info("Loaded custom hooks", "count", len(hooks))
+	}
+
+	var events *zfs.EventWatcher
+	if cfg.CollectorEvents {
+		events = zfs.NewEventWatcher(streamer, logger, cfg.ZpoolPath)
+	}
+
+	var smart *host.SmartReader
+	if cfg.CollectorSMART && cfg.Backend == config.BackendCLI {
+		smart = host.NewSmartReader(runner, cfg.SmartctlPath)
+	}
+
+	var devices *host.DeviceResolver
+	if cfg.DeviceIDs {
+		devices = host.NewDeviceResolver()
+	}
+
+	var smb *host.SMBReader
+	if cfg.CollectorSMB {
+		smb = host.NewSMBReader(runner, cfg.SmbstatusPath)
+	}
+
+	var iscsi *host.ISCSIReader
+	if cfg.CollectorISCSI {
+		iscsi = host.NewISCSIReader(runner, cfg.CtladmPath)
+	}
+
+	var kstatReader *kstat.Reader
+	if cfg.Backend == config.BackendKstat {
+		kstatReader = kstat.NewReader(cfg.KstatPath)
+		logger.Info("Using kstat backend", "path", cfg.KstatPath)
+	}
 
 	// Register collector.
-	coll := collector.NewCollector(client, svcChecker, logger, cfg.ScrapeTimeout, services)
-	prometheus.MustRegister(coll)
+	coll := collector.NewCollector(client, svcChecker, logger, &collector.Options{
+		Timeout:            cfg.ScrapeTimeout,
+		CollectorTimeouts:  cfg.CollectorTimeouts,
+		CacheTTL:           cfg.ScrapeCacheTTL,
+		Services:           services,
+		Timers:             cfg.Timers,
+		Enabled:            enabledCollectors(cfg),
+		PoolInclude:        cfg.PoolInclude,
+		PoolExclude:        cfg.PoolExclude,
+		PoolHealthStates:   cfg.PoolHealthStates,
+		DatasetInclude:     cfg.DatasetInclude,
+		DatasetExclude:     cfg.DatasetExclude,
+		DatasetMaxSeries:   cfg.DatasetMaxSeries,
+		DatasetProperties:  cfg.DatasetProperties,
+		UserspaceDatasets:  cfg.UserspaceDatasets,
+		SnapshotPolicies:   cfg.SnapshotPolicies,
+		UserPropertyPrefix: cfg.UserPropertyPrefix,
+		Kstat:              kstatReader,
+		Stats:              kstat.NewReader(cfg.KstatPath),
+		Events:             events,
+		Smart:              smart,
+		Devices:            devices,
+		SMB:                smb,
+		ISCSI:              iscsi,
+		CustomHooks:        hooks,
+		CustomRunner:       custom.NewRunner(runner, cfg.ZfsPath),
+	})
+
+	labels, err := hostnameLabels(cfg.HostnameLabel)
+	if err != nil {
+		logger.Error("Failed to read the host name", "err", err)
+		os.Exit(1)
+	}
+
+	metricsOpts := exporter.MetricsOptions{
+		MaxRequestsInFlight: cfg.WebMaxRequests,
+		Timeout:             cfg.WebTimeout,
+		DisableCompression:  cfg.WebDisableCompression,
+		HostnameLabel:       cfg.HostnameLabel,
+		Renames:             cfg.MetricRenames,
+		PDFCompat:           cfg.CompatPDF,
+	}
+
+	if cfg.CompatPDF {
+		logPDFCompat(cfg.MetricRenames, logger)
+	}
+
+	switch {
+	case cfg.Dump:
+		os.Exit(dump(os.Stdout, labels, metricsOpts, coll, cmdDurations, logger))
+	case *healthcheckLocal:
+		os.Exit(dump(io.Discard, labels, metricsOpts, coll, cmdDurations, logger))
+	}
+
+	if err := register(prometheus.WrapRegistererWith(labels, prometheus.DefaultRegisterer), coll, cmdDurations); err != nil {
+		logger.Error("Failed to register collectors", "err", err)
+		os.Exit(1)
+	}
+
+	// Allow twice the longest command budget before declaring a collection
+	// wedged.
+	stallThreshold := 2 * max(cfg.ScrapeTimeout, cfg.CommandTimeouts.Zpool, cfg.CommandTimeouts.ZfsList, cfg.CommandTimeouts.Systemctl)
+	for _, t := range cfg.CollectorTimeouts {
+		stallThreshold = max(stallThreshold, 2*t)
+	}
 
 	// HTTP server.
 	mux := http.NewServeMux()
-	mux.Handle(cfg.MetricsPath, promhttp.Handler())
-	mux.HandleFunc("/", exporter.LandingPageHandler(cfg.MetricsPath, logger))
+	mux.Handle(cfg.MetricsPath, exporter.MetricsHandler(prometheus.DefaultRegisterer, prometheus.DefaultGatherer, metricsOpts, logger))
+	mux.HandleFunc("/healthz", exporter.HealthHandler(coll, stallThreshold, logger))
+
+	if cfg.AdminToken != "" {
+		exporter.RegisterAdminHandlers(mux, coll, cfg.AdminToken, logger)
+		logger.Info("Admin API enabled", "path", "/-/")
+	}
+
+	if len(cfg.ProbeTargets) > 0 {
+		mux.HandleFunc("/probe", exporter.ProbeHandler(newProbeCollectors(cfg, logger), metricsOpts, logger))
+		logger.Info("Probe endpoint enabled", "path", "/probe", "targets", len(cfg.ProbeTargets))
+	}
+
+	var pprofServer *http.Server
+
+	if cfg.EnablePprof {
+		if cfg.PprofAddress == "" {
+			exporter.RegisterPprofHandlers(mux)
+		} else {
+			pprofServer = newPprofServer(cfg.PprofAddress)
+		}
+
+		logger.Info("pprof enabled", "path", "/debug/pprof/", "address", cmp.Or(cfg.PprofAddress, cfg.ListenAddress))
+	}
+
+	landingLinks := []exporter.LandingLink{{Text: "Metrics", Href: cfg.MetricsPath}, {Text: "Health", Href: "/healthz"}}
+	if cfg.EnablePprof && cfg.PprofAddress == "" {
+		landingLinks = append(landingLinks, exporter.LandingLink{Text: "Profiling", Href: "/debug/pprof/"})
+	}
+
+	if cfg.EnableDebugState {
+		mux.HandleFunc("/debug/state", exporter.DebugStateHandler(func() (any, bool) {
+			state, ok := coll.DebugState()
+			return state, ok
+		}, logger))
+		landingLinks = append(landingLinks, exporter.LandingLink{Text: "Debug state", Href: "/debug/state"})
+		logger.Info("Debug state endpoint enabled", "path", "/debug/state")
+	}
+
+	mux.HandleFunc("/", exporter.LandingPageHandler(&exporter.LandingConfig{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		Links:     landingLinks,
+	}, coll, logger))
 
 	server := &http.Server{
 		Addr:              cfg.ListenAddress,
@@ -83,6 +284,12 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
+	notifier := sdnotify.New()
+
+	// Background tasks (watchdog, event watcher) stop on shutdown.
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
 	// Graceful shutdown.
 	go func() {
 		sigCh := make(chan os.Signal, 1)
@@ -90,17 +297,51 @@ func main() {
 		sig := <-sigCh
 		logger.Info("Received signal, shutting down", "signal", sig)
 
+		stopBackground()
+
+		if err := notifier.Notify(sdnotify.Stopping); err != nil {
+			logger.Warn("Failed to notify systemd", "err", err)
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Error("HTTP server shutdown error", "err", err)
 		}
+
+		if pprofServer != nil {
+			if err := pprofServer.Shutdown(ctx); err != nil {
+				logger.Error("pprof server shutdown error", "err", err)
+			}
+		}
 	}()
 
+	listener, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		logger.Error("Failed to listen", "address", cfg.ListenAddress, "err", err)
+		os.Exit(1)
+	}
+
 	logger.Info("Listening", "address", cfg.ListenAddress)
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if pprofServer != nil {
+		go func() {
+			if err := pprofServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("pprof server error", "address", pprofServer.Addr, "err", err)
+			}
+		}()
+	}
+
+	if events != nil {
+		go events.Run(bgCtx)
+	}
+
+	if notifier.Enabled() {
+		go startSystemd(bgCtx, notifier, coll, stallThreshold, logger)
+	}
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("HTTP server error", "err", err)
 		os.Exit(1)
 	}
@@ -125,15 +366,346 @@ func setupLogger(level string) *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl}))
 }
 
-// buildServiceMap maps configured service keys to their candidate systemd unit names.
-func buildServiceMap(keys []string) map[string][]string {
+// healthcheck queries a running exporter's /healthz and returns the process
+// exit code. An empty url targets listenAddress.
+func healthcheck(listenAddress, url string, timeout time.Duration, logger *slog.Logger) int {
+	if url == "" {
+		var err error
+
+		url, err = exporter.HealthURL(listenAddress)
+		if err != nil {
+			logger.Error("Cannot derive health URL", "err", err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := exporter.Healthcheck(ctx, url); err != nil {
+		logger.Error("Health check failed", "err", err)
+		return 1
+	}
+
+	return 0
+}
+
+// dump performs a single collection, writes it to w with labels added to
+// every metric and names served as opts specifies, and returns the process
+// exit code: 1 if gathering failed or the pool listing did.
+func dump(
+	w io.Writer,
+	labels prometheus.Labels,
+	opts exporter.MetricsOptions,
+	coll *collector.Collector,
+	cmdDurations *collector.CommandDurations,
+	logger *slog.Logger,
+) int {
+	reg := prometheus.NewRegistry()
+	if err := register(prometheus.WrapRegistererWith(labels, reg), coll, cmdDurations); err != nil {
+		logger.Error("Failed to register collectors", "err", err)
+		return 1
+	}
+
+	if err := exporter.WriteMetrics(w, opts.Gatherer(reg, logger)); err != nil {
+		logger.Error("Failed to dump metrics", "err", err)
+		return 1
+	}
+
+	if _, _, ok := coll.LastCollection(); !ok {
+		logger.Error("Collection failed, zfs_up is 0")
+		return 1
+	}
+
+	return 0
+}
+
+// logPDFCompat reports the exporter's metrics that pdf/zfs_exporter
+// compatibility serves in the other shape, unless renames keep them under
+// another name.
+func logPDFCompat(renames map[string]string, logger *slog.Logger) {
+	var replaced []string
+
+	for _, name := range exporter.PDFReplacedMetrics {
+		if _, ok := renames[name]; !ok {
+			replaced = append(replaced, name)
+		}
+	}
+
+	logger.Info("pdf/zfs_exporter compatibility enabled", "replaced", replaced)
+}
+
+// register registers the exporter's collectors with reg. It fails rather
+// than panicking when --metrics.hostname-label names a label the exporter
+// already uses.
+func register(reg prometheus.Registerer, colls ...prometheus.Collector) error {
+	for _, c := range colls {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hostnameLabels returns the constant labels added to the local host's
+// metrics: the host name under --metrics.hostname-label, or none when it is
+// unset.
+func hostnameLabels(label string) (prometheus.Labels, error) {
+	if label == "" {
+		return prometheus.Labels{}, nil
+	}
+
+	name, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("reading host name: %w", err)
+	}
+
+	return prometheus.Labels{label: name}, nil
+}
+
+// enabledCollectors returns the startup state of each sub-collector from the
+// --collector.* flags.
+func enabledCollectors(cfg *config.Config) map[string]bool {
+	return map[string]bool{
+		collector.CollectorPoolProperties:   cfg.CollectorPoolProperties,
+		collector.CollectorDatasets:         cfg.CollectorDataset,
+		collector.CollectorDatasetHistogram: cfg.CollectorDatasetHistogram,
+		collector.CollectorDatasetTuning:    cfg.CollectorDatasetTuning,
+		collector.CollectorSnapshots:        cfg.CollectorSnapshot,
+		collector.CollectorNFS:              cfg.CollectorNFS,
+		collector.CollectorHistory:          cfg.CollectorHistory,
+		collector.CollectorLatency:          cfg.CollectorLatency,
+		collector.CollectorScan:             cfg.CollectorScan,
+		collector.CollectorVdev:             cfg.CollectorVdev,
+		collector.CollectorServices:         cfg.CollectorService,
+		collector.CollectorL2ARC:            cfg.CollectorL2ARC,
+		collector.CollectorZIL:              cfg.CollectorZIL,
+		collector.CollectorZfetch:           cfg.CollectorZfetch,
+		collector.CollectorDmuTx:            cfg.CollectorDmuTx,
+		collector.CollectorTxg:              cfg.CollectorTxg,
+		collector.CollectorSPL:              cfg.CollectorSPL,
+		collector.CollectorNodeCompat:       cfg.CompatNode,
+	}
+}
+
+// newProbeCollectors builds one collector per probe target, running
+// zpool/zfs over ssh, with the options probeOptions returns. Collectors live
+// for the process lifetime so each target keeps its own scrape cache and
+// JSON support probe.
+func newProbeCollectors(cfg *config.Config, logger *slog.Logger) map[string]prometheus.Collector {
+	colls := make(map[string]prometheus.Collector, len(cfg.ProbeTargets))
+
+	for name, t := range cfg.ProbeTargets {
+		targetLogger := logger.With("target", name)
+
+		target := zfs.SSHTarget{
+			Host:    t.Host,
+			User:    t.User,
+			Port:    t.Port,
+			KeyFile: t.Key,
+		}
+		runner := zfs.SSHRunner(zfs.LoggingRunner(zfs.DefaultRunner(), targetLogger), cfg.SSHPath, target)
+		runner = zfs.TimeoutRunner(runner, cfg.CommandTimeouts, t.ZpoolPath, t.ZfsPath)
+
+		client := zfs.NewClient(runner, targetLogger, t.ZpoolPath, t.ZfsPath)
+		if !cfg.ZfsJSON {
+			client.DisableJSON()
+		}
+
+		streamRunner := zfs.SSHStreamRunner(zfs.LoggingStreamRunner(zfs.DefaultStreamRunner(), targetLogger), cfg.SSHPath, target)
+		client.SetStreamRunner(zfs.TimeoutStreamRunner(streamRunner, cfg.CommandTimeouts, t.ZpoolPath, t.ZfsPath))
+		client.SetDatasetDepth(cfg.DatasetDepth)
+		client.SetDatasetTypes(cfg.DatasetTypes)
+		client.SetSnapshotLimit(cfg.SnapshotMax)
+		client.SetSnapshotPaging(cfg.SnapshotPerPool)
+		client.SetStatusConcurrency(cfg.StatusConcurrency)
+
+		// Probe in the background so unreachable targets do not delay startup.
+		go detectColumns(client, cfg.ScrapeTimeout, targetLogger)
+
+		colls[name] = collector.NewCollector(client, host.NewServiceChecker(runner, targetLogger), targetLogger, probeOptions(cfg))
+	}
+
+	return colls
+}
+
+// probeOptions returns the collector options for a probe target. Service,
+// timer, kstat, SMART, NFS export, SMB, iSCSI, and custom hook collectors
+// only describe the exporter's own host, so they are off for remote
+// targets, and device names are left as zpool prints them.
+func probeOptions(cfg *config.Config) *collector.Options {
+	enabled := enabledCollectors(cfg)
+	enabled[collector.CollectorServices] = false
+	enabled[collector.CollectorL2ARC] = false
+	enabled[collector.CollectorZIL] = false
+	enabled[collector.CollectorZfetch] = false
+	enabled[collector.CollectorDmuTx] = false
+	enabled[collector.CollectorTxg] = false
+	enabled[collector.CollectorSPL] = false
+	enabled[collector.CollectorNodeCompat] = false
+	enabled[collector.CollectorCustom] = false
+	enabled[collector.CollectorNFS] = false
+	enabled[collector.CollectorTimers] = false
+
+	return &collector.Options{
+		Timeout:            cfg.ScrapeTimeout,
+		CollectorTimeouts:  cfg.CollectorTimeouts,
+		CacheTTL:           cfg.ScrapeCacheTTL,
+		Enabled:            enabled,
+		PoolInclude:        cfg.PoolInclude,
+		PoolExclude:        cfg.PoolExclude,
+		PoolHealthStates:   cfg.PoolHealthStates,
+		DatasetInclude:     cfg.DatasetInclude,
+		DatasetExclude:     cfg.DatasetExclude,
+		DatasetMaxSeries:   cfg.DatasetMaxSeries,
+		DatasetProperties:  cfg.DatasetProperties,
+		UserspaceDatasets:  cfg.UserspaceDatasets,
+		SnapshotPolicies:   cfg.SnapshotPolicies,
+		UserPropertyPrefix: cfg.UserPropertyPrefix,
+	}
+}
+
+// localRunners returns the command runners for the exporter's own host,
+// running zpool, zfs, and systemctl through the --zfs.sudo wrapper when it
+// is enabled and with the --timeout.* deadlines. Commands are debug-logged
+// as executed, wrapper included. Timing is added by the caller, outside the
+// wrapper, so command durations keep the zpool and zfs names.
+func localRunners(cfg *config.Config, logger *slog.Logger) (zfs.Runner, zfs.StreamRunner, zfs.Streamer) {
+	runner := zfs.LoggingRunner(zfs.DefaultRunner(), logger)
+	streamRunner := zfs.LoggingStreamRunner(zfs.DefaultStreamRunner(), logger)
+	streamer := zfs.DefaultStreamer()
+
+	if cfg.ZfsSudo {
+		privileged := []string{cfg.ZpoolPath, cfg.ZfsPath, "systemctl"}
+		runner = zfs.SudoRunner(runner, cfg.SudoCommand, privileged...)
+		streamRunner = zfs.SudoStreamRunner(streamRunner, cfg.SudoCommand, privileged...)
+		streamer = zfs.SudoStreamer(streamer, cfg.SudoCommand, privileged...)
+	}
+
+	// The event stream runs for the process lifetime, so it has no deadline.
+	return zfs.TimeoutRunner(runner, cfg.CommandTimeouts, cfg.ZpoolPath, cfg.ZfsPath),
+		zfs.TimeoutStreamRunner(streamRunner, cfg.CommandTimeouts, cfg.ZpoolPath, cfg.ZfsPath),
+		streamer
+}
+
+// detectColumns probes the zpool and zfs columns supported by client's host
+// within timeout. On failure every column is still requested, as before.
+func detectColumns(client *zfs.Client, timeout time.Duration, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := client.DetectColumns(ctx); err != nil {
+		logger.Warn("Failed to detect supported zpool and zfs columns", "err", err)
+	}
+}
+
+// newPprofServer returns a server for the pprof handlers alone. Its write
+// timeout leaves room for the default 30s CPU profile, which the main
+// server's 30s WriteTimeout would cut off.
+func newPprofServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	exporter.RegisterPprofHandlers(mux)
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      2 * time.Minute,
+	}
+}
+
+// buildServiceMap maps configured service keys to their candidate service
+// names for the init system in use. Keys with no known names are kept when
+// they have a port to check.
+func buildServiceMap(keys []string, defaults map[string][]string, ports map[string]int) map[string][]string {
 	result := make(map[string][]string, len(keys))
 
 	for _, key := range keys {
-		if units, ok := host.DefaultServiceUnits[key]; ok {
+		units, ok := defaults[key]
+		_, hasPort := ports[key]
+
+		if ok || hasPort {
 			result[key] = units
 		}
 	}
 
 	return result
 }
+
+// startSystemd drives the sd_notify protocol. It runs warm-up collections
+// so readiness does not depend on Prometheus scraping first, sends READY=1 once
+// a collection succeeds, and then emits watchdog keepalives while collections
+// keep completing. A collection stuck for longer than stallThreshold withholds
+// the keepalive so systemd restarts the exporter.
+func startSystemd(
+	ctx context.Context,
+	n *sdnotify.Notifier,
+	coll *collector.Collector,
+	stallThreshold time.Duration,
+	logger *slog.Logger,
+) {
+	go warmUp(ctx, coll, logger)
+
+	interval, err := sdnotify.WatchdogInterval()
+	if err != nil && !errors.Is(err, sdnotify.ErrWatchdogDisabled) {
+		logger.Warn("Ignoring systemd watchdog configuration", "err", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-coll.Ready():
+	}
+
+	if err := n.Notify(sdnotify.Ready); err != nil {
+		logger.Warn("Failed to notify systemd readiness", "err", err)
+	}
+
+	logger.Info("Notified systemd readiness")
+
+	if interval <= 0 {
+		return
+	}
+
+	logger.Info("Systemd watchdog enabled", "interval", interval, "stall_threshold", stallThreshold)
+
+	n.RunWatchdog(ctx, interval, func() bool {
+		if coll.Stalled(stallThreshold) {
+			logger.Error("Collection stalled, withholding watchdog keepalive", "threshold", stallThreshold)
+			return false
+		}
+
+		return true
+	}, func(err error) {
+		logger.Warn("Failed to send watchdog keepalive", "err", err)
+	})
+}
+
+// warmUpMaxDelay caps the backoff between warm-up collections.
+const warmUpMaxDelay = time.Minute
+
+// warmUp collects until one succeeds or ctx is done. At boot the pools may
+// not be imported yet when the exporter starts, so a single attempt could
+// leave readiness waiting on the first Prometheus scrape. Retries back off
+// from one second to warmUpMaxDelay.
+func warmUp(ctx context.Context, coll *collector.Collector, logger *slog.Logger) {
+	delay := time.Second
+
+	for {
+		if _, err := prometheus.DefaultGatherer.Gather(); err != nil {
+			logger.Warn("Warm-up collection failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-coll.Ready():
+			return
+		case <-time.After(delay):
+		}
+
+		logger.Debug("Retrying warm-up collection", "after", delay)
+		delay = min(2*delay, warmUpMaxDelay)
+	}
+}
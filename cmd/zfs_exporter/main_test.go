@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/donaldgifford/zfs_exporter/collector"
+	"github.com/donaldgifford/zfs_exporter/config"
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// deadlineClient records the deadline of the pool listing. Only the
+// methods the pool collector calls are implemented.
+type deadlineClient struct {
+	collector.ZFSClient
+
+	remaining time.Duration
+}
+
+func (d *deadlineClient) Features(context.Context) zfs.Features { return zfs.Features{} }
+
+func (d *deadlineClient) GetPools(ctx context.Context) ([]zfs.Pool, error) {
+	deadline, _ := ctx.Deadline()
+	d.remaining = time.Until(deadline)
+
+	return nil, nil
+}
+
+func TestProbeOptions_CollectorTimeouts(t *testing.T) {
+	cfg := &config.Config{
+		ScrapeTimeout:     time.Second,
+		CollectorTimeouts: map[string]time.Duration{"pool": time.Minute},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := &deadlineClient{}
+	coll := collector.NewCollector(client, host.NewServiceChecker(nil, logger), logger, probeOptions(cfg))
+
+	testutil.CollectAndCount(coll)
+
+	// The pool timeout replaces the scrape deadline.
+	if client.remaining > time.Minute || client.remaining < 50*time.Second {
+		t.Errorf("pool listing deadline in %v, want about %v", client.remaining, time.Minute)
+	}
+}
@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// scrapeData is everything a scrape fetched from the host: the result of
+// each registered sub-collector, by name.
+type scrapeData struct {
+	subs map[string]subResult
+
+	// enabled is the sub-collector set the data was fetched for.
+	enabled map[string]bool
+	fetched time.Time
+}
+
+// poolErr returns the error of the pool listing.
+func (d *scrapeData) poolErr() error {
+	return d.subs[collectorPool].err
+}
+
+// scrapeCache holds the last successful scrape so scrapes arriving within
+// ttl (e.g. from several Prometheus servers) reuse it instead of re-running
+// zpool/zfs commands. A zero ttl disables caching.
+type scrapeCache struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	data *scrapeData
+}
+
+// get returns the cached data and its age if it is younger than ttl and was
+// fetched for the same enabled set. Toggling a sub-collector therefore
+// takes effect on the next scrape rather than after the TTL expires.
+func (s *scrapeCache) get(enabled map[string]bool) (*scrapeData, time.Duration) {
+	if s.ttl <= 0 {
+		return nil, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data == nil || !maps.Equal(s.data.enabled, enabled) {
+		return nil, 0
+	}
+
+	age := time.Since(s.data.fetched)
+	if age >= s.ttl {
+		return nil, 0
+	}
+
+	return s.data, age
+}
+
+// put stores d. Failed pool fetches are never cached so recovery shows up
+// on the next scrape.
+func (s *scrapeCache) put(d *scrapeData) {
+	if s.ttl <= 0 || d.poolErr() != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.data = d
+	s.mu.Unlock()
+}
+
+// fetchGroup coalesces concurrent fetches: a Collect that starts while
+// another is already fetching for the same enabled set waits for that fetch
+// and shares its result instead of running zpool/zfs a second time. Unlike
+// scrapeCache it needs no TTL and covers failed fetches too.
+type fetchGroup struct {
+	mu   sync.Mutex
+	call *fetchCall
+}
+
+// fetchCall is one in-flight fetch.
+type fetchCall struct {
+	enabled map[string]bool
+	done    chan struct{}
+	data    *scrapeData
+}
+
+// do returns the result of the in-flight fetch for enabled if there is one,
+// otherwise runs fn.
+func (g *fetchGroup) do(enabled map[string]bool, fn func() *scrapeData) *scrapeData {
+	g.mu.Lock()
+
+	if call := g.call; call != nil && maps.Equal(call.enabled, enabled) {
+		g.mu.Unlock()
+		<-call.done
+
+		return call.data
+	}
+
+	call := &fetchCall{enabled: enabled, done: make(chan struct{})}
+	g.call = call
+	g.mu.Unlock()
+
+	call.data = fn()
+	close(call.done)
+
+	g.mu.Lock()
+	if g.call == call {
+		g.call = nil
+	}
+	g.mu.Unlock()
+
+	return call.data
+}
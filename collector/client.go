@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// ZFSClient is the pool and dataset data the Collector reads. *zfs.Client
+// implements it by running zpool and zfs; other implementations (a JSON
+// feed, a remote agent, a test fixture) can be passed to NewCollector in
+// its place. The kstat backend (Options.Kstat) replaces GetPools and the
+// dataset counters without going through a ZFSClient.
+//
+// Methods are called concurrently within a scrape. Errors are reported per
+// sub-collector through zfs_scrape_collector_success and classified by
+// zfs.Reason, so implementations should return errors that wrap the context
+// error on timeout and a *zfs.CommandError for failed commands.
+type ZFSClient interface {
+	// Features returns the detected ZFS version and the features it
+	// supports. It must not fail; an unknown version disables nothing.
+	Features(ctx context.Context) zfs.Features
+
+	GetPools(ctx context.Context) ([]zfs.Pool, error)
+	GetPoolProperties(ctx context.Context, names []string) (zfs.PoolProperties, error)
+	GetPoolCreationTimes(ctx context.Context) (map[string]time.Time, error)
+	GetDatasets(ctx context.Context) ([]zfs.Dataset, error)
+	GetUserProperties(ctx context.Context, prefix string) (zfs.UserProperties, error)
+	GetDatasetProperties(ctx context.Context, names []string) (zfs.DatasetProperties, error)
+
+	// GetSnapshotCounts also reports whether the listing was truncated.
+	GetSnapshotCounts(ctx context.Context, pools []string) ([]zfs.SnapshotCount, bool, error)
+	GetSnapshots(ctx context.Context) ([]zfs.Snapshot, error)
+	GetSpaceUsage(ctx context.Context, datasets []string) ([]zfs.SpaceUsage, error)
+
+	// GetHistoryCounts counts administrative operations logged since since.
+	GetHistoryCounts(ctx context.Context, since time.Time) ([]zfs.HistoryCount, error)
+	GetLatencyHistograms(ctx context.Context) ([]zfs.LatencyHistogram, error)
+	GetScanStatuses(ctx context.Context, pools []string) ([]zfs.ScanStatus, error)
+	GetVdevStatuses(ctx context.Context, pools []string) ([]zfs.VdevStatus, error)
+	GetVdevCapacities(ctx context.Context) ([]zfs.VdevCapacity, error)
+}
+
+var _ ZFSClient = (*zfs.Client)(nil)
@@ -2,34 +2,188 @@
 package collector
 
 import (
+	"cmp"
 	"context"
 	"log/slog"
-	"strings"
-	"sync"
+	"regexp"
+	"slices"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/donaldgifford/zfs_exporter/pkg/custom"
 	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
 	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
 )
 
 const namespace = "zfs"
 
-// healthStates enumerates all possible pool health states.
-var healthStates = []string{"online", "degraded", "faulted", "offline", "removed", "unavail"}
+// vdevStates enumerates all possible device states.
+var vdevStates = []string{"online", "degraded", "faulted", "offline", "removed", "unavail"}
+
+// healthStates enumerates the known pool health states: the device states
+// plus split and suspended, which only apply to a whole pool. Options.
+// PoolHealthStates extends it.
+var healthStates = append(slices.Clone(vdevStates), "split", "suspended")
+
+// Options configures a Collector.
+type Options struct {
+	// Timeout is the total budget for all commands in a single scrape.
+	Timeout time.Duration
+
+	// CacheTTL, when positive, lets scrapes within this window reuse the
+	// previous scrape's command results instead of re-running them.
+	CacheTTL time.Duration
+
+	// Services maps service keys to candidate systemd unit names.
+	Services map[string][]string
+
+	// Enabled sets the startup state of each optional sub-collector (see
+	// CollectorNames). Names not present in the map, or a nil map, default
+	// to enabled.
+	Enabled map[string]bool
+
+	// PoolInclude and PoolExclude filter every pool-scoped metric (pool,
+	// aggregate, scan, vdev, dataset) by pool name. Nil regexes impose no
+	// constraint.
+	PoolInclude *regexp.Regexp
+	PoolExclude *regexp.Regexp
+
+	// PoolHealthStates are extra states added to the zfs_pool_health
+	// state-set, for health strings a newer ZFS reports. Matched case
+	// insensitively.
+	PoolHealthStates []string
+
+	// DatasetInclude and DatasetExclude filter dataset metrics by full
+	// dataset name. Nil regexes impose no constraint.
+	DatasetInclude *regexp.Regexp
+	DatasetExclude *regexp.Regexp
+
+	// DatasetMaxSeries, when positive, caps the datasets exported per
+	// scrape, keeping the largest by used bytes (busiest with kstat).
+	DatasetMaxSeries int
+
+	// DatasetProperties lists extra zfs get properties exported per dataset
+	// as zfs_dataset_property (numeric) or zfs_dataset_property_info.
+	DatasetProperties []string
+
+	// UserspaceDatasets lists datasets whose per-user and per-group space
+	// usage and quotas are exported (zfs userspace/groupspace). Empty
+	// disables the userspace sub-collector.
+	UserspaceDatasets []string
+
+	// SnapshotPolicies are the retention classes whose per-dataset
+	// snapshot counts and freshness are exported. Empty disables the
+	// snapshot_policy sub-collector.
+	SnapshotPolicies []zfs.SnapshotPolicy
+
+	// UserPropertyPrefix, when non-empty, attaches ZFS user properties
+	// starting with this prefix (e.g. "exporter:") as labels on dataset
+	// metrics.
+	UserPropertyPrefix string
+
+	// Kstat, when set, replaces zpool/zfs for pool state and supplies
+	// per-dataset I/O counters from procfs. Capacity, scan, vdev, and
+	// property metrics are unavailable in this mode.
+	Kstat *kstat.Reader
+
+	// Stats reads global kstats (arcstats, zil, zfetchstats, dmu_tx) and each
+	// pool's txgs kstat for the l2arc, zil, zfetch, dmu_tx, and txg
+	// collectors. It is independent of the backend; nil
+	// disables those collectors.
+	Stats *kstat.Reader
+
+	// Events, when set, supplies zpool event counts. Its Run loop is
+	// managed by the caller.
+	Events *zfs.EventWatcher
+
+	// NFSExportsPath is the kernel NFS export table read by the nfs
+	// collector. Empty means host.DefaultNFSExportsPath.
+	NFSExportsPath string
+
+	// CollectorTimeouts give registered sub-collectors (SubCollectorNames)
+	// their own fetch deadline instead of Timeout, keyed by collector name.
+	CollectorTimeouts map[string]time.Duration
+
+	// Timers lists systemd timer units (or globs) whose schedules are
+	// exported, e.g. scrub and snapshot jobs. Empty disables the timer
+	// sub-collector.
+	Timers []string
+
+	// Smart, when set, reads SMART health for each pool member device.
+	// nil disables the smart collector.
+	Smart *host.SmartReader
+
+	// Devices, when set, replaces kernel device names (sdX) in the device
+	// and vdev labels of vdev and SMART series with their /dev/disk/by-id
+	// names, and exports zfs_vdev_device_info with each device's node and
+	// serial number.
+	Devices *host.DeviceResolver
+
+	// SMB, when set, reads Samba sessions, open files, and share
+	// connections. nil disables the smb collector.
+	SMB *host.SMBReader
+
+	// ISCSI, when set, reads the iSCSI targets, LUNs, and sessions. nil
+	// disables the iscsi collector.
+	ISCSI *host.ISCSIReader
+
+	// CustomHooks are operator-defined commands or channel programs exposed
+	// as zfs_custom_* metrics, run via CustomRunner.
+	CustomHooks  []custom.Hook
+	CustomRunner *custom.Runner
+}
 
 // Collector collects ZFS metrics.
 type Collector struct {
-	client     *zfs.Client
-	svcChecker *host.ServiceChecker
-	logger     *slog.Logger
-	timeout    time.Duration
-	services   map[string][]string
+	client         ZFSClient
+	svcChecker     *host.ServiceChecker
+	logger         *slog.Logger
+	timeout        time.Duration
+	services       map[string][]string
+	timers         []string
+	userPropPrefix string
+	datasetProps   []string
+	userspaceDS    []string
+	snapPolicies   []zfs.SnapshotPolicy
+	poolFilter     nameFilter
+	datasetFilter  nameFilter
+	maxDatasets    int
+	poolStates     []string
+	health         *health
+	toggles        *toggles
+	cache          *scrapeCache
+	flight         *fetchGroup
+	last           atomic.Pointer[scrapeData] // for DebugState
+	customRunner   *custom.Runner
+	kstat          *kstat.Reader
+	stats          *kstat.Reader
+	events         *zfs.EventWatcher
+	smart          *host.SmartReader
+	devices        *host.DeviceResolver
+	smb            *host.SMBReader
+	iscsi          *host.ISCSIReader
+	nfsExports     string
+	subs           []registration
+	started        time.Time // zpool history entries before this are not counted
+	fetchErrors    *errorCounts
 
 	// Meta
 	up             *prometheus.Desc
 	scrapeDuration *prometheus.Desc
+	cacheAge       *prometheus.Desc
+	collectorOK    *prometheus.Desc
+	collectorFail  *prometheus.Desc
+	scrapeErrors   *prometheus.Desc
+	featureEnabled *prometheus.Desc
+
+	// Aggregate
+	totalSize      *prometheus.Desc
+	totalAllocated *prometheus.Desc
+	poolsTotal     *prometheus.Desc
+	poolsUnhealthy *prometheus.Desc
 
 	// Pool
 	poolSize          *prometheus.Desc
@@ -39,39 +193,197 @@ type Collector struct {
 	poolDedup         *prometheus.Desc
 	poolReadOnly      *prometheus.Desc
 	poolHealth        *prometheus.Desc
+	poolHealthUnknown *prometheus.Desc
+	poolCompressRatio *prometheus.Desc
+	poolAshift        *prometheus.Desc
+	poolAutotrim      *prometheus.Desc
+	poolInfo          *prometheus.Desc
+	poolCreated       *prometheus.Desc
 
 	// Pool scan
 	poolScrubActive    *prometheus.Desc
 	poolResilverActive *prometheus.Desc
 	poolScanProgress   *prometheus.Desc
+	poolScrubPaused    *prometheus.Desc
+	poolScanDeferred   *prometheus.Desc
+	poolResilverQueue  *prometheus.Desc
+	poolScanScanned    *prometheus.Desc
+	poolScanIssued     *prometheus.Desc
+	poolScanTotal      *prometheus.Desc
+	poolScanRate       *prometheus.Desc
+	poolScanETA        *prometheus.Desc
+	poolStatusCode     *prometheus.Desc
+	poolDataErrors     *prometheus.Desc
+
+	// Last completed scan
+	poolLastScrubTime        *prometheus.Desc
+	poolLastScrubDuration    *prometheus.Desc
+	poolLastScrubRepaired    *prometheus.Desc
+	poolLastScrubErrors      *prometheus.Desc
+	poolLastScrubAge         *prometheus.Desc
+	poolLastResilverTime     *prometheus.Desc
+	poolLastResilverDuration *prometheus.Desc
+
+	// Events
+	poolEvents   *prometheus.Desc
+	poolAdminOps *prometheus.Desc
+
+	// Latency histograms (zpool iostat -w)
+	poolLatency     *prometheus.Desc
+	poolDiskLatency *prometheus.Desc
+
+	// Vdev
+	vdevReadErrors     *prometheus.Desc
+	vdevWriteErrors    *prometheus.Desc
+	vdevChecksumErrors *prometheus.Desc
+	vdevState          *prometheus.Desc
+	vdevDevice         *prometheus.Desc
+	vdevCapacity       *prometheus.Desc
+	vdevFragmentation  *prometheus.Desc
+	poolSpares         *prometheus.Desc
+	poolSparesInUse    *prometheus.Desc
+	poolCacheDevices   *prometheus.Desc
+	poolLogDevices     *prometheus.Desc
+	poolTopology       *prometheus.Desc
+
+	// SMART
+	vdevSmartHealthy *prometheus.Desc
+	vdevTemperature  *prometheus.Desc
+	vdevReallocated  *prometheus.Desc
 
 	// Dataset
-	datasetUsed       *prometheus.Desc
-	datasetAvailable  *prometheus.Desc
-	datasetReferenced *prometheus.Desc
-	datasetShareNFS   *prometheus.Desc
-	datasetShareSMB   *prometheus.Desc
+	dataset          datasetDescs
+	datasetDescCache datasetDescCache
+	datasetThreshold *prometheus.Desc
+	datasetsLimited  *prometheus.Desc
+
+	// Dataset extra properties
+	datasetProperty     *prometheus.Desc
+	datasetPropertyInfo *prometheus.Desc
+	datasetTuning       *prometheus.Desc
+
+	// Dataset histogram
+	datasetUsedHistogram *prometheus.Desc
+
+	// Snapshots
+	snapshotCount           *prometheus.Desc
+	snapshotHolds           *prometheus.Desc
+	snapshotNewest          *prometheus.Desc
+	snapshotsTruncated      *prometheus.Desc
+	snapshotPolicyCount     *prometheus.Desc
+	snapshotPolicyCompliant *prometheus.Desc
+
+	// NFS exports
+	nfsExportCount  *prometheus.Desc
+	datasetExported *prometheus.Desc
+
+	// User and group space (zfs userspace/groupspace)
+	userUsed   *prometheus.Desc
+	userQuota  *prometheus.Desc
+	groupUsed  *prometheus.Desc
+	groupQuota *prometheus.Desc
+
+	// Dataset I/O (kstat)
+	datasetReads        *prometheus.Desc
+	datasetWrites       *prometheus.Desc
+	datasetReadBytes    *prometheus.Desc
+	datasetWrittenBytes *prometheus.Desc
 
 	// Service
-	serviceUp *prometheus.Desc
+	serviceUp       *prometheus.Desc
+	serviceRestarts *prometheus.Desc
+
+	// Timers (systemd)
+	timerActive      *prometheus.Desc
+	timerLastTrigger *prometheus.Desc
+	timerNextElapse  *prometheus.Desc
+
+	// SMB (smbstatus)
+	smbSessions         *prometheus.Desc
+	smbOpenFiles        *prometheus.Desc
+	smbShareConnections *prometheus.Desc
+
+	// iSCSI (LIO configfs or ctladm)
+	iscsiTargets  *prometheus.Desc
+	iscsiLUNs     *prometheus.Desc
+	iscsiSessions *prometheus.Desc
+	iscsiLUNInfo  *prometheus.Desc
+
+	// L2ARC (arcstats)
+	l2arc []kstatMetric
+
+	// ZIL (zil kstat)
+	zil []kstatMetric
+
+	// Prefetch (zfetchstats kstat)
+	zfetch []kstatMetric
+
+	// DMU transactions (dmu_tx kstat)
+	dmuTx []kstatMetric
+
+	// Transaction groups (per-pool txgs kstat)
+	poolTxgs           *prometheus.Desc
+	poolTxgSyncSeconds *prometheus.Desc
+	poolTxgDirtyBytes  *prometheus.Desc
+
+	// SPL kmem caches (/proc/spl/kmem/slab)
+	splCacheSize    *prometheus.Desc
+	splCacheAlloc   *prometheus.Desc
+	splCacheObjects *prometheus.Desc
+
+	// node_exporter compatibility (global kstats, pool state, objsets)
+	nodePoolState *prometheus.Desc
+	nodeObjsets   []nodeObjsetMetric
+
+	// Custom hooks
+	customHooks    []customHook
+	customSuccess  *prometheus.Desc
+	customDuration *prometheus.Desc
 }
 
 // NewCollector creates a new Collector.
-func NewCollector(
-	client *zfs.Client,
-	svcChecker *host.ServiceChecker,
-	logger *slog.Logger,
-	timeout time.Duration,
-	services map[string][]string,
-) *Collector {
+func NewCollector(client ZFSClient, svcChecker *host.ServiceChecker, logger *slog.Logger, opts *Options) *Collector {
 	c := &Collector{
-		client:     client,
-		svcChecker: svcChecker,
-		logger:     logger,
-		timeout:    timeout,
-		services:   services,
+		client:         client,
+		svcChecker:     svcChecker,
+		logger:         logger,
+		timeout:        opts.Timeout,
+		services:       opts.Services,
+		timers:         opts.Timers,
+		userPropPrefix: opts.UserPropertyPrefix,
+		datasetProps:   opts.DatasetProperties,
+		userspaceDS:    opts.UserspaceDatasets,
+		snapPolicies:   opts.SnapshotPolicies,
+		poolFilter:     nameFilter{include: opts.PoolInclude, exclude: opts.PoolExclude},
+		datasetFilter:  nameFilter{include: opts.DatasetInclude, exclude: opts.DatasetExclude},
+		maxDatasets:    opts.DatasetMaxSeries,
+		poolStates:     poolStates(opts.PoolHealthStates),
+		health:         newHealth(),
+		toggles:        newToggles(opts.Enabled),
+		cache:          &scrapeCache{ttl: opts.CacheTTL},
+		flight:         &fetchGroup{},
+		customRunner:   opts.CustomRunner,
+		kstat:          opts.Kstat,
+		stats:          opts.Stats,
+		events:         opts.Events,
+		smart:          opts.Smart,
+		devices:        opts.Devices,
+		smb:            opts.SMB,
+		iscsi:          opts.ISCSI,
+		nfsExports:     cmp.Or(opts.NFSExportsPath, host.DefaultNFSExportsPath),
+		started:        time.Now(),
+		fetchErrors:    newErrorCounts(),
 	}
 	c.initDescriptors()
+	c.initL2ARCDescriptors()
+	c.initZILDescriptors()
+	c.initZfetchDescriptors()
+	c.initDmuTxDescriptors()
+	c.initTxgDescriptors()
+	c.initSPLDescriptors()
+	c.initNodeCompatDescriptors()
+	c.initCustomDescriptors(opts.CustomHooks)
+	c.initSubCollectors(opts.CollectorTimeouts)
 
 	return c
 }
@@ -88,6 +400,57 @@ func (c *Collector) initDescriptors() {
 		nil,
 		nil,
 	)
+	c.cacheAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "scrape_cache_age_seconds"),
+		"Age of the cached command results served by this scrape, 0 if freshly fetched.",
+		nil,
+		nil,
+	)
+	c.collectorOK = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
+		"1 if the collector's data was fetched without error in this scrape, 0 otherwise.",
+		[]string{"collector"},
+		nil,
+	)
+	c.collectorFail = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_failure"),
+		"1 for a collector whose fetch failed in this scrape, labelled with the failure reason.",
+		[]string{"collector", "reason"},
+		nil,
+	)
+	c.scrapeErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "errors_total"),
+		"Fetches of the subsystem's data that failed, by command or parse error, since the exporter started.",
+		[]string{"subsystem"},
+		nil,
+	)
+	c.featureEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "feature_enabled"),
+		"1 if the host's ZFS version supports the feature and the exporter uses it, 0 otherwise.",
+		[]string{"feature"},
+		nil,
+	)
+
+	// Aggregate.
+	c.totalSize = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_size_bytes"),
+		"Total size of all collected pools in bytes.",
+		nil,
+		nil,
+	)
+	c.totalAllocated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "total_allocated_bytes"),
+		"Allocated space across all collected pools in bytes.",
+		nil,
+		nil,
+	)
+	c.poolsTotal = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "pools_total"), "Number of collected pools.", nil, nil)
+	c.poolsUnhealthy = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "pools_unhealthy"),
+		"Number of collected pools whose health is not ONLINE.",
+		nil,
+		nil,
+	)
 
 	// Pool.
 	c.poolSize = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", "size_bytes"), "Total pool size in bytes.", poolLabels, nil)
@@ -112,6 +475,43 @@ func (c *Collector) initDescriptors() {
 		[]string{"pool", "state"},
 		nil,
 	)
+	c.poolHealthUnknown = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "health_unknown"),
+		"1 if pool reports a health state missing from the zfs_pool_health state-set, labeled with the state as reported.",
+		[]string{"pool", "raw_state"},
+		nil,
+	)
+	c.poolAshift = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "ashift"),
+		"ashift property of the pool (log2 of the sector size used for new vdevs), 0 if detected from each disk.",
+		poolLabels,
+		nil,
+	)
+	c.poolAutotrim = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "autotrim"),
+		"1 if the pool's autotrim property is on, 0 otherwise.",
+		poolLabels,
+		nil,
+	)
+	c.poolInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "info"),
+		"Import settings of the pool as labels; altroot and cachefile are empty when unset. Always 1.",
+		[]string{"pool", "guid", "altroot", "cachefile"},
+		nil,
+	)
+	c.poolCreated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "created_timestamp_seconds"),
+		"Unix time the pool was created, from the creation property of its root dataset.",
+		poolLabels,
+		nil,
+	)
+
+	c.poolCompressRatio = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "compressratio"),
+		"Compression ratio achieved across the pool, from its root dataset (1 = uncompressed).",
+		poolLabels,
+		nil,
+	)
 
 	// Scan.
 	c.poolScrubActive = prometheus.NewDesc(
@@ -132,240 +532,547 @@ func (c *Collector) initDescriptors() {
 		poolLabels,
 		nil,
 	)
+	c.poolScrubPaused = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "scrub_paused"),
+		"1 if a scrub is paused (manually or waiting for resilver), 0 otherwise.",
+		poolLabels,
+		nil,
+	)
+	c.poolScanDeferred = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "scan_deferred"),
+		"1 if a resilver is deferred or the scan is waiting for a resilver, 0 otherwise.",
+		poolLabels,
+		nil,
+	)
+	c.poolResilverQueue = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "resilver_queue_devices"),
+		"Number of devices awaiting a deferred resilver.",
+		poolLabels,
+		nil,
+	)
+	c.poolScanETA = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "scan_eta_seconds"),
+		"Estimated seconds until the active scan completes, 0 if no scan active, paused, or not yet estimated.",
+		poolLabels,
+		nil,
+	)
+	c.poolStatusCode = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "status_code"),
+		"1 if the pool's zpool status message is of the labeled class, 0 otherwise; ok when there is no message.",
+		[]string{"pool", "code"},
+		nil,
+	)
+	c.poolDataErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "data_errors"),
+		"Number of known permanent data errors (files or objects that could not be repaired).",
+		poolLabels,
+		nil,
+	)
+	c.poolScanScanned = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "scan_scanned_bytes"),
+		"Bytes of metadata traversed by the active scan, 0 if no scan active.",
+		poolLabels,
+		nil,
+	)
+	c.poolScanIssued = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "scan_issued_bytes"),
+		"Bytes read and verified by the active scan, 0 if no scan active.",
+		poolLabels,
+		nil,
+	)
+	c.poolScanTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "scan_total_bytes"),
+		"Bytes the active scan has to cover, 0 if no scan active.",
+		poolLabels,
+		nil,
+	)
+	c.poolScanRate = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "scan_rate_bytes_per_second"),
+		"Issue rate of the active scan averaged over the current pass, 0 if unknown or no scan active.",
+		poolLabels,
+		nil,
+	)
+
+	// Last completed scan. zpool status only reports the most recent scan,
+	// so only one of the scrub/resilver families is present per pool.
+	c.poolLastScrubTime = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "last_scrub_timestamp_seconds"),
+		"Unix time the most recent scrub completed.",
+		poolLabels,
+		nil,
+	)
+	c.poolLastScrubDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "last_scrub_duration_seconds"),
+		"Run time of the most recent completed scrub, excluding time paused.",
+		poolLabels,
+		nil,
+	)
+	c.poolLastScrubRepaired = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "last_scrub_repaired_bytes"),
+		"Bytes repaired by the most recent completed scrub.",
+		poolLabels,
+		nil,
+	)
+	c.poolLastScrubErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "last_scrub_errors"),
+		"Unrecoverable errors found by the most recent completed scrub.",
+		poolLabels,
+		nil,
+	)
+	c.poolLastScrubAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "last_scrub_age_seconds"),
+		"Seconds since the most recent completed scrub finished.",
+		poolLabels,
+		nil,
+	)
+	c.poolLastResilverTime = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "last_resilver_timestamp_seconds"),
+		"Unix time the most recent resilver completed.",
+		poolLabels,
+		nil,
+	)
+	c.poolLastResilverDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "last_resilver_duration_seconds"),
+		"Run time of the most recent completed resilver.",
+		poolLabels,
+		nil,
+	)
+
+	// Vdev.
+	vdevLabels := []string{"pool", "vdev", "device"}
+	c.vdevReadErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "read_errors"),
+		"Read I/O errors reported by zpool status for the device.",
+		vdevLabels,
+		nil,
+	)
+	c.vdevWriteErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "write_errors"),
+		"Write I/O errors reported by zpool status for the device.",
+		vdevLabels,
+		nil,
+	)
+	c.vdevChecksumErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "checksum_errors"),
+		"Checksum errors reported by zpool status for the device.",
+		vdevLabels,
+		nil,
+	)
+	c.vdevState = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "state"),
+		"1 if the device is in the labeled state, 0 otherwise.",
+		[]string{"pool", "vdev", "device", "state"},
+		nil,
+	)
+	c.vdevDevice = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "device_info"),
+		"Device node and disk serial number (\"\" if unreadable) of each pool member device, named by its /dev/disk/by-id link where it has one.",
+		[]string{"pool", "vdev", "device", "path", "serial"},
+		nil,
+	)
+	c.vdevCapacity = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "capacity_ratio"),
+		"Allocated share (0-1) of the top-level vdev's size, from zpool list -v.",
+		[]string{"pool", "vdev"},
+		nil,
+	)
+	c.vdevFragmentation = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "fragmentation_ratio"),
+		"Fragmentation of the top-level vdev as a ratio (0-1), NaN if unavailable.",
+		[]string{"pool", "vdev"},
+		nil,
+	)
+	c.vdevSmartHealthy = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "smart_healthy"),
+		"1 if the device's SMART overall-health self-assessment passed, 0 if it reports failing.",
+		[]string{"pool", "vdev", "device"},
+		nil,
+	)
+	c.vdevTemperature = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "temperature_celsius"),
+		"Current device temperature reported by SMART.",
+		[]string{"pool", "vdev", "device"},
+		nil,
+	)
+	c.vdevReallocated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "vdev", "reallocated_sectors"),
+		"Reallocated sectors (ATA) or grown defects (SCSI) reported by SMART.",
+		[]string{"pool", "vdev", "device"},
+		nil,
+	)
+	c.poolSpares = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "spare_count"),
+		"Number of hot spares configured for the pool.",
+		poolLabels,
+		nil,
+	)
+	c.poolSparesInUse = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "spare_in_use"),
+		"Number of hot spares currently replacing a failed device.",
+		poolLabels,
+		nil,
+	)
+	c.poolCacheDevices = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "cache_count"),
+		"Number of cache (L2ARC) devices in the pool.",
+		poolLabels,
+		nil,
+	)
+	c.poolLogDevices = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "log_count"),
+		"Number of log (SLOG) devices in the pool.",
+		poolLabels,
+		nil,
+	)
+	c.poolTopology = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "topology_info"),
+		"Redundancy layout of the pool's data vdevs (mirror, raidz1-3, draid1-3, stripe, or mixed), with their number and disks per vdev.",
+		[]string{"pool", "layout", "vdev_count", "disks_per_vdev"},
+		nil,
+	)
 
 	// Dataset.
-	c.datasetUsed = prometheus.NewDesc(prometheus.BuildFQName(namespace, "dataset", "used_bytes"), "Space consumed by dataset.", datasetLabels, nil)
-	c.datasetAvailable = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "dataset", "available_bytes"),
-		"Space available to dataset.",
-		datasetLabels,
+	c.dataset = *newDatasetDescs(datasetLabels)
+	c.datasetThreshold = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "threshold"),
+		"Per-dataset alert threshold read from a ZFS user property, by kind (ratios 0-1, ages in seconds).",
+		[]string{"dataset", "type", "pool", "kind"},
+		nil,
+	)
+
+	c.datasetsLimited = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "series_truncated"),
+		"1 if per-dataset metrics were limited by --dataset.max-series, 0 otherwise.",
+		nil,
+		nil,
+	)
+
+	// Dataset extra properties.
+	c.datasetProperty = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "property"),
+		"Numeric value of a ZFS property requested with --dataset.properties.",
+		[]string{"dataset", "type", "pool", "property"},
+		nil,
+	)
+	c.datasetPropertyInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "property_info"),
+		"Non-numeric value of a ZFS property requested with --dataset.properties, as a label. Always 1.",
+		[]string{"dataset", "type", "pool", "property", "value"},
+		nil,
+	)
+	c.datasetTuning = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "properties"),
+		"Tuning properties of the dataset as labels; empty where they do not apply (recordsize and atime on volumes). Always 1.",
+		[]string{"dataset", "type", "pool", "recordsize", "sync", "atime", "primarycache", "compression"},
+		nil,
+	)
+
+	// Dataset histogram.
+	c.datasetUsedHistogram = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "used_bytes_histogram"),
+		"Distribution of dataset used bytes per pool.",
+		poolLabels,
+		nil,
+	)
+
+	// Snapshots.
+	c.snapshotCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "snapshot_count"),
+		"Number of snapshots of the dataset (not including its descendants).",
+		[]string{"dataset", "pool"},
 		nil,
 	)
-	c.datasetReferenced = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "dataset", "referenced_bytes"),
-		"Space referenced by dataset.",
-		datasetLabels,
+	c.snapshotHolds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "snapshot_holds"),
+		"User holds (zfs hold) on the dataset's snapshots. Held snapshots cannot be destroyed.",
+		[]string{"dataset", "pool"},
+		nil,
+	)
+	c.snapshotNewest = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "snapshot_newest_timestamp_seconds"),
+		"Unix time the dataset's most recent snapshot was taken.",
+		[]string{"dataset", "pool"},
+		nil,
+	)
+	c.snapshotsTruncated = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "snapshot", "counts_truncated"),
+		"1 if the snapshot counts are incomplete because listing stopped at --snapshot.max or the scrape timeout, 0 otherwise.",
+		nil,
 		nil,
 	)
-	c.datasetShareNFS = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "dataset", "share_nfs"),
-		"1 if NFS sharing is enabled, 0 otherwise.",
-		datasetLabels,
+	c.snapshotPolicyCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "snapshot_policy_count"),
+		"Number of snapshots of the dataset whose name matches the retention class in --snapshot.policies.",
+		[]string{"dataset", "pool", "class"},
 		nil,
 	)
-	c.datasetShareSMB = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "dataset", "share_smb"),
-		"1 if SMB sharing is enabled, 0 otherwise.",
-		datasetLabels,
+	c.snapshotPolicyCompliant = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "snapshot_policy_compliant"),
+		"1 if the newest snapshot of the retention class is younger than the class's maximum age, 0 otherwise.",
+		[]string{"dataset", "pool", "class"},
+		nil,
+	)
+
+	// NFS exports.
+	c.nfsExportCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "nfs", "exports"),
+		"Distinct paths in the kernel NFS export table (/proc/fs/nfsd/exports).",
+		nil,
+		nil,
+	)
+	c.datasetExported = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "nfs_exported"),
+		"1 if the mountpoint of a dataset with sharenfs set is in the kernel NFS export table, 0 otherwise.",
+		[]string{"dataset", "pool"},
+		nil,
+	)
+
+	// User and group space.
+	c.userUsed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "user_used_bytes"),
+		"Space charged to the user in a dataset listed in --dataset.userspace (zfs userspace).",
+		[]string{"dataset", "pool", "user"},
+		nil,
+	)
+	c.userQuota = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "user_quota_bytes"),
+		"The user's userquota on a dataset listed in --dataset.userspace. Absent if no quota is set.",
+		[]string{"dataset", "pool", "user"},
+		nil,
+	)
+	c.groupUsed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "group_used_bytes"),
+		"Space charged to the group in a dataset listed in --dataset.userspace (zfs groupspace).",
+		[]string{"dataset", "pool", "group"},
+		nil,
+	)
+	c.groupQuota = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "group_quota_bytes"),
+		"The group's groupquota on a dataset listed in --dataset.userspace. Absent if no quota is set.",
+		[]string{"dataset", "pool", "group"},
+		nil,
+	)
+
+	// Dataset I/O (kstat).
+	ioLabels := []string{"dataset", "pool"}
+	c.datasetReads = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "reads_total"),
+		"Read operations on the dataset since pool import (kstat backend).",
+		ioLabels,
+		nil,
+	)
+	c.datasetWrites = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "writes_total"),
+		"Write operations on the dataset since pool import (kstat backend).",
+		ioLabels,
+		nil,
+	)
+	c.datasetReadBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "read_bytes_total"),
+		"Bytes read from the dataset since pool import (kstat backend).",
+		ioLabels,
+		nil,
+	)
+	c.datasetWrittenBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "dataset", "written_bytes_total"),
+		"Bytes written to the dataset since pool import (kstat backend).",
+		ioLabels,
+		nil,
+	)
+
+	// Events.
+	c.poolEvents = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "events_total"),
+		"zpool events seen since the exporter started, by event class.",
+		[]string{"class"},
+		nil,
+	)
+	c.poolAdminOps = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "admin_operations_total"),
+		"Administrative commands logged by zpool history since the exporter started, by operation.",
+		[]string{"pool", "operation"},
+		nil,
+	)
+
+	// Latency histograms.
+	c.poolLatency = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "latency_seconds"),
+		"Native histogram of I/O latency from queueing to completion since the pool was imported, by operation (read, write, scrub).",
+		[]string{"pool", "op"},
+		nil,
+	)
+	c.poolDiskLatency = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "disk_latency_seconds"),
+		"Native histogram of I/O latency spent on the disks since the pool was imported, by operation (read, write).",
+		[]string{"pool", "op"},
 		nil,
 	)
 
 	// Service.
 	c.serviceUp = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "service_up"),
-		"1 if systemd unit is active, 0 otherwise.",
+		"1 if the service is running, 0 otherwise.",
+		[]string{"service"},
+		nil,
+	)
+	c.serviceRestarts = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "restarts_total"),
+		"Automatic restarts of the systemd unit (NRestarts) since it was last started manually.",
 		[]string{"service"},
 		nil,
 	)
+
+	// Timers.
+	c.timerActive = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "timer", "active"),
+		"1 if the systemd timer is waiting to elapse, 0 if it is stopped, disabled, or masked.",
+		[]string{"timer"},
+		nil,
+	)
+	c.timerLastTrigger = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "timer", "last_trigger_timestamp_seconds"),
+		"When the systemd timer last elapsed. Absent if it never has.",
+		[]string{"timer"},
+		nil,
+	)
+	c.timerNextElapse = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "timer", "next_elapse_timestamp_seconds"),
+		"When the systemd timer next elapses. Absent if it is not scheduled.",
+		[]string{"timer"},
+		nil,
+	)
+
+	// SMB.
+	c.smbSessions = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "smb", "sessions"),
+		"Authenticated SMB client sessions (smbstatus).",
+		nil,
+		nil,
+	)
+	c.smbOpenFiles = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "smb", "open_files"),
+		"Distinct files held open by SMB clients (smbstatus).",
+		nil,
+		nil,
+	)
+	c.smbShareConnections = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "smb", "share_connections"),
+		"SMB tree connections to the share. Shares with no connections are absent.",
+		[]string{"share"},
+		nil,
+	)
+
+	// iSCSI.
+	c.iscsiTargets = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "iscsi", "targets"),
+		"Configured iSCSI target names.",
+		nil,
+		nil,
+	)
+	c.iscsiLUNs = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "iscsi", "luns"),
+		"LUNs exported by iSCSI targets, over all target portal groups.",
+		nil,
+		nil,
+	)
+	c.iscsiSessions = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "iscsi", "sessions"),
+		"Initiator sessions logged in to iSCSI targets.",
+		nil,
+		nil,
+	)
+	c.iscsiLUNInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "iscsi", "lun_info"),
+		"Always 1; maps an iSCSI LUN and its backstore to the zvol behind it. LUNs not backed by a zvol are absent.",
+		[]string{"target", "lun", "backstore", "dataset", "pool"},
+		nil,
+	)
 }
 
 // Describe sends all metric descriptors.
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.up
 	ch <- c.scrapeDuration
-	ch <- c.poolSize
-	ch <- c.poolAllocated
-	ch <- c.poolFree
-	ch <- c.poolFragmentation
-	ch <- c.poolDedup
-	ch <- c.poolReadOnly
-	ch <- c.poolHealth
-	ch <- c.poolScrubActive
-	ch <- c.poolResilverActive
-	ch <- c.poolScanProgress
-	ch <- c.datasetUsed
-	ch <- c.datasetAvailable
-	ch <- c.datasetReferenced
-	ch <- c.datasetShareNFS
-	ch <- c.datasetShareSMB
-	ch <- c.serviceUp
+	ch <- c.cacheAge
+	ch <- c.collectorOK
+	ch <- c.collectorFail
+	ch <- c.scrapeErrors
+	ch <- c.datasetsLimited
+	ch <- c.poolEvents
+	c.describeSubs(ch)
 }
 
-// Collect fetches ZFS data and emits metrics.
+// Collect fetches ZFS data (or reuses a fresh cached fetch) and emits metrics.
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	start := time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
-	defer cancel()
+	c.health.begin()
 
-	// Fetch pools (required).
-	pools, poolErr := c.client.GetPools(ctx)
+	success := false
+	defer func() { c.health.end(success, time.Since(start)) }()
 
-	duration := time.Since(start).Seconds()
-	ch <- prometheus.MustNewConstMetric(c.scrapeDuration, prometheus.GaugeValue, duration)
+	enabled := c.toggles.snapshot()
 
-	if poolErr != nil {
-		c.logger.Error("Failed to get pools", "err", poolErr)
-		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+	// Concurrent scrapes (e.g. from several Prometheus servers) that miss
+	// the cache share one fetch.
+	data, age := c.cache.get(enabled)
+	if data == nil {
+		data = c.flight.do(enabled, func() *scrapeData {
+			d := c.fetch(enabled)
+			c.countFetchErrors(d)
+			c.cache.put(d)
 
-		return
+			return d
+		})
 	}
 
-	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
-
-	// Emit pool metrics.
-	c.collectPoolMetrics(ch, pools)
+	c.last.Store(data)
 
-	// Fetch optional data concurrently.
-	r := c.fetchOptional(ctx)
-
-	// Dataset metrics (optional).
-	if r.dsErr != nil {
-		c.logger.Warn("Failed to get datasets", "err", r.dsErr)
-	} else {
-		c.collectDatasetMetrics(ch, r.datasets)
-	}
-
-	// Scan metrics (optional).
-	if r.scanErr != nil {
-		c.logger.Warn("Failed to get scan statuses", "err", r.scanErr)
-	} else {
-		c.collectScanMetrics(ch, r.scans)
-	}
+	duration := time.Since(start).Seconds()
+	ch <- prometheus.MustNewConstMetric(c.scrapeDuration, prometheus.GaugeValue, duration)
+	ch <- prometheus.MustNewConstMetric(c.cacheAge, prometheus.GaugeValue, age.Seconds())
 
-	// Service metrics (optional).
-	if r.svcErr != nil {
-		c.logger.Warn("Failed to check services", "err", r.svcErr)
-	} else {
-		c.collectServiceMetrics(ch, r.svcs)
+	// Event counts come from the background watcher and do not depend on
+	// this scrape succeeding.
+	if c.events != nil {
+		c.collectEventMetrics(ch)
 	}
-}
-
-// optionalResults holds the results of the three concurrent optional fetches
-// (datasets, scans, services). Each goroutine in fetchOptional writes to a
-// distinct field, and sync.WaitGroup.Wait() provides a happens-before
-// guarantee that all writes are visible before the struct is returned.
-//
-// INFO(concurrency): This struct exists to make the concurrent data flow
-// explicit. Each goroutine writes to its own field pair (e.g. datasets/dsErr).
-// No two goroutines share a field. The WaitGroup ensures all goroutines
-// complete before fetchOptional returns, so there is no race. This is
-// equivalent to using separate channels but avoids the channel machinery for
-// a fixed fan-out of three.
-type optionalResults struct {
-	datasets []zfs.Dataset
-	dsErr    error
-	scans    []zfs.ScanStatus
-	scanErr  error
-	svcs     []host.ServiceStatus
-	svcErr   error
-}
 
-// fetchOptional fetches datasets, scan statuses, and service states
-// concurrently. All three are optional -- failures are captured in the
-// result's error fields rather than aborting the scrape.
-func (c *Collector) fetchOptional(ctx context.Context) optionalResults {
-	var (
-		r  optionalResults
-		wg sync.WaitGroup
-	)
-
-	wg.Add(3) //nolint:mnd // datasets, scans, and services
-
-	go func() {
-		defer wg.Done()
-		r.datasets, r.dsErr = c.client.GetDatasets(ctx)
-	}()
+	c.collectSuccessMetrics(ch, data)
+	c.collectErrorCounts(ch)
 
-	go func() {
-		defer wg.Done()
-		r.scans, r.scanErr = c.client.GetScanStatuses(ctx)
-	}()
-
-	go func() {
-		defer wg.Done()
-		r.svcs, r.svcErr = c.svcChecker.CheckServices(ctx, c.services)
-	}()
+	if err := data.poolErr(); err != nil {
+		c.logger.Error("Failed to get pools", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
 
-	wg.Wait()
+		return
+	}
 
-	return r
-}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
 
-func (c *Collector) collectPoolMetrics(ch chan<- prometheus.Metric, pools []zfs.Pool) {
-	for _, p := range pools {
-		ch <- prometheus.MustNewConstMetric(c.poolSize, prometheus.GaugeValue, float64(p.Size), p.Name)
-		ch <- prometheus.MustNewConstMetric(c.poolAllocated, prometheus.GaugeValue, float64(p.Allocated), p.Name)
-		ch <- prometheus.MustNewConstMetric(c.poolFree, prometheus.GaugeValue, float64(p.Free), p.Name)
-		ch <- prometheus.MustNewConstMetric(c.poolFragmentation, prometheus.GaugeValue, p.Fragmentation, p.Name)
-		ch <- prometheus.MustNewConstMetric(c.poolDedup, prometheus.GaugeValue, p.DedupRatio, p.Name)
-
-		ro := 0.0
-		if p.ReadOnly {
-			ro = 1.0
-		}
-
-		ch <- prometheus.MustNewConstMetric(c.poolReadOnly, prometheus.GaugeValue, ro, p.Name)
-
-		// Health state-set: one metric per possible state.
-		healthLower := strings.ToLower(p.Health)
-		for _, state := range healthStates {
-			val := 0.0
-			if state == healthLower {
-				val = 1.0
-			}
-
-			ch <- prometheus.MustNewConstMetric(c.poolHealth, prometheus.GaugeValue, val, p.Name, state)
-		}
-	}
-}
+	success = true
 
-func (c *Collector) collectScanMetrics(ch chan<- prometheus.Metric, scans []zfs.ScanStatus) {
-	for _, s := range scans {
-		scrub := 0.0
-		if s.Scrub {
-			scrub = 1.0
-		}
-
-		resilver := 0.0
-		if s.Resilver {
-			resilver = 1.0
-		}
-
-		ch <- prometheus.MustNewConstMetric(c.poolScrubActive, prometheus.GaugeValue, scrub, s.Pool)
-		ch <- prometheus.MustNewConstMetric(c.poolResilverActive, prometheus.GaugeValue, resilver, s.Pool)
-		ch <- prometheus.MustNewConstMetric(c.poolScanProgress, prometheus.GaugeValue, s.Progress, s.Pool)
-	}
+	c.collectSubs(ch, data)
 }
 
-func (c *Collector) collectDatasetMetrics(ch chan<- prometheus.Metric, datasets []zfs.Dataset) {
-	for _, d := range datasets {
-		ch <- prometheus.MustNewConstMetric(c.datasetUsed, prometheus.GaugeValue, float64(d.Used), d.Name, d.Type, d.Pool)
-		ch <- prometheus.MustNewConstMetric(c.datasetAvailable, prometheus.GaugeValue, float64(d.Available), d.Name, d.Type, d.Pool)
-		ch <- prometheus.MustNewConstMetric(c.datasetReferenced, prometheus.GaugeValue, float64(d.Referenced), d.Name, d.Type, d.Pool)
-
-		nfs := 0.0
-		if d.ShareNFS {
-			nfs = 1.0
-		}
+// fetch fetches every enabled sub-collector within the scrape timeout. The
+// pools are listed first (from kstat when configured); if that fails nothing
+// else is fetched. Disabled sub-collectors are skipped entirely, including
+// their commands.
+func (c *Collector) fetch(enabled map[string]bool) *scrapeData {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
 
-		smb := 0.0
-		if d.ShareSMB {
-			smb = 1.0
-		}
+	fetched := time.Now()
 
-		ch <- prometheus.MustNewConstMetric(c.datasetShareNFS, prometheus.GaugeValue, nfs, d.Name, d.Type, d.Pool)
-		ch <- prometheus.MustNewConstMetric(c.datasetShareSMB, prometheus.GaugeValue, smb, d.Name, d.Type, d.Pool)
-	}
+	return &scrapeData{enabled: enabled, fetched: fetched, subs: c.fetchSubs(ctx, enabled)}
 }
 
-func (c *Collector) collectServiceMetrics(ch chan<- prometheus.Metric, svcs []host.ServiceStatus) {
-	for _, s := range svcs {
-		val := 0.0
-		if s.Active {
-			val = 1.0
-		}
-
-		ch <- prometheus.MustNewConstMetric(c.serviceUp, prometheus.GaugeValue, val, s.Name)
+// boolToFloat converts a boolean to 1.0 or 0.0 for gauge values.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1.0
 	}
+
+	return 0.0
 }
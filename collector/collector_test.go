@@ -1,17 +1,31 @@
 package collector
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 
+	"github.com/donaldgifford/zfs_exporter/pkg/custom"
 	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
 	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
 )
 
@@ -29,8 +43,21 @@ type fixtureRunner struct {
 	poolErr    error
 	datasetOut string
 	datasetErr error
+	snapOut    string
+	snapErr    error
+	userOut    string
+	groupOut   string
+	historyOut string
+	iostatOut  string
+	zpoolProps string
+	versionOut string            // zpool version, with or without -j
+	smartOut   map[string]string // smartctl output by device path
+	smbOut     string
 	statusOut  string
 	statusErr  error
+	vdevList   string // zpool list -v
+	propOut    string
+	propErr    error
 	svcResults map[string]struct {
 		output string
 		err    error
@@ -40,12 +67,34 @@ type fixtureRunner struct {
 func (f *fixtureRunner) run(_ context.Context, name string, args ...string) ([]byte, error) {
 	// Determine which command was called.
 	switch {
+	case strings.HasSuffix(name, "zfs") && slices.Contains(args, "snapshot"):
+		return []byte(f.snapOut), f.snapErr
+	case strings.HasSuffix(name, "zfs") && len(args) > 0 && args[0] == "userspace":
+		return []byte(f.userOut), nil
+	case strings.HasSuffix(name, "zfs") && len(args) > 0 && args[0] == "groupspace":
+		return []byte(f.groupOut), nil
+	case name == "smartctl":
+		return []byte(f.smartOut[args[len(args)-1]]), nil
+	case name == "smbstatus":
+		return []byte(f.smbOut), nil
+	case strings.HasSuffix(name, "zpool") && len(args) > 0 && args[0] == "history":
+		return []byte(f.historyOut), nil
+	case strings.HasSuffix(name, "zpool") && len(args) > 0 && args[0] == "get":
+		return []byte(f.zpoolProps), nil
+	case strings.HasSuffix(name, "zpool") && len(args) > 0 && args[0] == "iostat":
+		return []byte(f.iostatOut), nil
+	case strings.HasSuffix(name, "zpool") && len(args) > 0 && args[0] == "version":
+		return []byte(f.versionOut), nil
+	case strings.HasSuffix(name, "zpool") && len(args) > 0 && args[0] == "list" && slices.Contains(args, "-v"):
+		return []byte(f.vdevList), nil
 	case strings.HasSuffix(name, "zpool") && len(args) > 0 && args[0] == "list":
 		return []byte(f.poolOut), f.poolErr
 	case strings.HasSuffix(name, "zfs") && len(args) > 0 && args[0] == "list":
 		return []byte(f.datasetOut), f.datasetErr
 	case strings.HasSuffix(name, "zpool") && len(args) > 0 && args[0] == "status":
 		return []byte(f.statusOut), f.statusErr
+	case strings.HasSuffix(name, "zfs") && len(args) > 0 && args[0] == "get":
+		return []byte(f.propOut), f.propErr
 	case name == "systemctl":
 		if f.svcResults == nil {
 			return []byte(""), errors.New("no service results configured")
@@ -71,13 +120,13 @@ func newTestCollector(f *fixtureRunner) *Collector {
 		"smb": {"smbd.service"},
 	}
 
-	return NewCollector(client, svcChecker, testLogger(), 10*time.Second, services)
+	return NewCollector(client, svcChecker, testLogger(), &Options{Timeout: 10 * time.Second, Services: services})
 }
 
 func TestCollector_HappyPath(t *testing.T) {
 	f := &fixtureRunner{
 		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
-		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\ntank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\ntank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n",
 		statusOut: `  pool: tank
  state: ONLINE
   scan: none requested
@@ -124,6 +173,8 @@ func TestCollector_HappyPath(t *testing.T) {
 		zfs_pool_health{pool="tank",state="faulted"} 0
 		zfs_pool_health{pool="tank",state="offline"} 0
 		zfs_pool_health{pool="tank",state="removed"} 0
+		zfs_pool_health{pool="tank",state="split"} 0
+		zfs_pool_health{pool="tank",state="suspended"} 0
 		zfs_pool_health{pool="tank",state="unavail"} 0
 	`
 
@@ -145,7 +196,7 @@ func TestCollector_HappyPath(t *testing.T) {
 
 	// Verify service_up metric.
 	svcExpected := `
-		# HELP zfs_service_up 1 if systemd unit is active, 0 otherwise.
+		# HELP zfs_service_up 1 if the service is running, 0 otherwise.
 		# TYPE zfs_service_up gauge
 		zfs_service_up{service="nfs"} 1
 		zfs_service_up{service="smb"} 1
@@ -174,6 +225,159 @@ func TestCollector_HappyPath(t *testing.T) {
 	}
 }
 
+func TestCollector_ServiceRestarts(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+	}
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name != "systemctl" {
+			return f.run(ctx, name, args...)
+		}
+
+		if args[0] == "show" {
+			return []byte("LoadState=loaded\nNRestarts=7\n"), nil
+		}
+
+		return []byte("active\n"), nil
+	}
+
+	client := zfs.NewClient(runner, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(runner, testLogger()), testLogger(), &Options{
+		Timeout:  time.Second,
+		Services: map[string][]string{"smb": {"smbd.service"}},
+	})
+
+	expected := `
+		# HELP zfs_service_restarts_total Automatic restarts of the systemd unit (NRestarts) since it was last started manually.
+		# TYPE zfs_service_restarts_total counter
+		zfs_service_restarts_total{service="smb"} 7
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_service_restarts_total"); err != nil {
+		t.Errorf("service restarts mismatch: %v", err)
+	}
+}
+
+func TestCollector_CollectorSuccess(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetErr: &zfs.CommandError{Cmd: "zfs", ExitCode: 1, Stderr: "cannot open '/dev/zfs': Permission denied"},
+		statusOut:  "  pool: tank\n state: ONLINE\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Enabled: map[string]bool{CollectorPoolProperties: false, CollectorSnapshots: false, CollectorServices: false},
+	})
+
+	expected := `
+		# HELP zfs_scrape_collector_success 1 if the collector's data was fetched without error in this scrape, 0 otherwise.
+		# TYPE zfs_scrape_collector_success gauge
+		zfs_scrape_collector_success{collector="dataset"} 0
+		zfs_scrape_collector_success{collector="pool"} 1
+		zfs_scrape_collector_success{collector="scan"} 1
+		zfs_scrape_collector_success{collector="vdev"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_scrape_collector_success"); err != nil {
+		t.Errorf("collector success mismatch: %v", err)
+	}
+
+	expected = `
+		# HELP zfs_scrape_collector_failure 1 for a collector whose fetch failed in this scrape, labelled with the failure reason.
+		# TYPE zfs_scrape_collector_failure gauge
+		zfs_scrape_collector_failure{collector="dataset",reason="permission_denied"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_scrape_collector_failure"); err != nil {
+		t.Errorf("collector failure mismatch: %v", err)
+	}
+
+	f.poolErr = errors.New("command not found")
+
+	expected = `
+		# HELP zfs_scrape_collector_success 1 if the collector's data was fetched without error in this scrape, 0 otherwise.
+		# TYPE zfs_scrape_collector_success gauge
+		zfs_scrape_collector_success{collector="pool"} 0
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_scrape_collector_success"); err != nil {
+		t.Errorf("collector success mismatch on pool failure: %v", err)
+	}
+}
+
+func TestCollector_ScrapeErrors(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetErr: errors.New("zfs list failed"),
+		statusOut:  "  pool: tank\n state: ONLINE\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Enabled: map[string]bool{CollectorPoolProperties: false, CollectorSnapshots: false, CollectorServices: false},
+	})
+
+	testutil.CollectAndCount(coll)
+	testutil.CollectAndCount(coll)
+
+	f.poolErr = errors.New("command not found")
+
+	expected := `
+		# HELP zfs_scrape_errors_total Fetches of the subsystem's data that failed, by command or parse error, since the exporter started.
+		# TYPE zfs_scrape_errors_total counter
+		zfs_scrape_errors_total{subsystem="dataset"} 2
+		zfs_scrape_errors_total{subsystem="pool"} 1
+		zfs_scrape_errors_total{subsystem="scan"} 0
+		zfs_scrape_errors_total{subsystem="service"} 0
+	`
+
+	// This third scrape fails the pool listing and counts nothing else.
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_scrape_errors_total"); err != nil {
+		t.Errorf("scrape errors mismatch: %v", err)
+	}
+}
+
+func TestCollector_DebugState(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetErr: errors.New("zfs list failed"),
+		statusOut:  "  pool: tank\n state: ONLINE\n",
+	}
+
+	coll := newTestCollector(f)
+
+	if _, ok := coll.DebugState(); ok {
+		t.Fatal("DebugState reported data before any collection")
+	}
+
+	testutil.CollectAndCount(coll)
+
+	state, ok := coll.DebugState()
+	if !ok {
+		t.Fatal("DebugState has no data after a collection")
+	}
+
+	if pools, _ := state.Collectors[collectorPool].(poolState); len(pools.Pools) != 1 || pools.Pools[0].Name != "tank" {
+		t.Errorf("pool data = %+v, want tank", state.Collectors[collectorPool])
+	}
+
+	if state.Fetched.IsZero() {
+		t.Error("Fetched not set")
+	}
+
+	if got := state.Errors[CollectorDatasets]; !strings.Contains(got, "zfs list failed") {
+		t.Errorf("dataset error = %q, want it to mention zfs list failed", got)
+	}
+
+	if _, ok := state.Errors[CollectorScan]; ok {
+		t.Errorf("unexpected scan error: %v", state.Errors)
+	}
+}
+
 func TestCollector_PoolFailure_SetsUpZero(t *testing.T) {
 	f := &fixtureRunner{
 		poolErr: errors.New("command not found"),
@@ -244,7 +448,7 @@ func TestCollector_DatasetFailure_StillEmitsPools(t *testing.T) {
 func TestCollector_DescriptorCount(t *testing.T) {
 	f := &fixtureRunner{
 		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
-		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n",
 		statusOut: `  pool: tank
  state: ONLINE
   scan: none requested
@@ -260,9 +464,9 @@ func TestCollector_DescriptorCount(t *testing.T) {
 
 	coll := newTestCollector(f)
 
-	// 18 descriptors total: 2 meta + 7 pool + 3 scan + 5 dataset + 1 service
+	// 159 descriptors total: 7 meta + 4 aggregate + 13 pool + 20 scan + 15 vdev + 18 dataset + 6 snapshot + 2 nfs + 4 userspace + 2 events + 2 latency + 2 service + 3 timer + 3 smb + 4 iscsi + 7 l2arc + 13 zil + 8 zfetch + 13 dmu_tx + 3 txg + 3 spl + 5 node_compat + 2 custom
 	descCount := 0
-	ch := make(chan *prometheus.Desc, 50)
+	ch := make(chan *prometheus.Desc, 256)
 	coll.Describe(ch)
 	close(ch)
 
@@ -270,8 +474,2707 @@ func TestCollector_DescriptorCount(t *testing.T) {
 		descCount++
 	}
 
-	const expectedDescs = 18
+	const expectedDescs = 159
 	if descCount != expectedDescs {
 		t.Errorf("expected %d descriptors, got %d", expectedDescs, descCount)
 	}
 }
+
+func TestMetricNames(t *testing.T) {
+	names := MetricNames()
+
+	// The 132 Collector descriptors plus the command duration histogram;
+	// every descriptor has a distinct name.
+	const expected = 160
+	if len(names) != expected {
+		t.Errorf("got %d names, want %d: %v", len(names), expected, names)
+	}
+
+	if !slices.IsSorted(names) {
+		t.Error("names not sorted")
+	}
+
+	for _, want := range []string{
+		"zfs_up",
+		"zfs_pool_dedup_ratio",
+		"zfs_dataset_referenced_bytes",
+		"zfs_vdev_state",
+		"zfs_dataset_used_bytes_histogram",
+		"zfs_command_duration_seconds",
+	} {
+		if !slices.Contains(names, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
+
+func TestCollector_ReadyAfterSuccessfulCollect(t *testing.T) {
+	f := &fixtureRunner{poolErr: errors.New("command not found")}
+	coll := newTestCollector(f)
+
+	if finished, _, _ := coll.LastCollection(); !finished.IsZero() {
+		t.Errorf("LastCollection finished = %v before any collection", finished)
+	}
+
+	testutil.CollectAndCount(coll)
+
+	select {
+	case <-coll.Ready():
+		t.Fatal("collector reported ready after failed collection")
+	default:
+	}
+
+	if finished, _, ok := coll.LastCollection(); finished.IsZero() || ok {
+		t.Errorf("LastCollection = %v, %v after failed collection", finished, ok)
+	}
+
+	f.poolErr = nil
+	f.poolOut = "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n"
+
+	testutil.CollectAndCount(coll)
+
+	select {
+	case <-coll.Ready():
+	default:
+		t.Fatal("collector not ready after successful collection")
+	}
+
+	if _, _, ok := coll.LastCollection(); !ok {
+		t.Error("LastCollection not ok after successful collection")
+	}
+}
+
+func TestCollector_Stalled(t *testing.T) {
+	coll := newTestCollector(&fixtureRunner{})
+
+	if coll.Stalled(0) {
+		t.Fatal("idle collector reported stalled")
+	}
+
+	coll.health.begin()
+	time.Sleep(5 * time.Millisecond)
+
+	if !coll.Stalled(time.Millisecond) {
+		t.Error("long-running collection not reported stalled")
+	}
+
+	if coll.Stalled(time.Hour) {
+		t.Error("collection within threshold reported stalled")
+	}
+
+	coll.health.end(true, 5*time.Millisecond)
+
+	if coll.Stalled(0) {
+		t.Error("finished collection reported stalled")
+	}
+}
+
+func TestCollector_DisabledCollectorSkipsCommands(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+  scan: none requested
+`,
+	}
+
+	coll := newTestCollector(f)
+
+	if err := coll.SetEnabled(CollectorDatasets, false); err != nil {
+		t.Fatalf("SetEnabled: %v", err)
+	}
+
+	if count := testutil.CollectAndCount(coll, "zfs_dataset_used_bytes"); count != 0 {
+		t.Errorf("expected 0 dataset metrics when disabled, got %d", count)
+	}
+
+	if count := testutil.CollectAndCount(coll, "zfs_pool_scrub_active"); count != 1 {
+		t.Errorf("expected scan metrics to remain, got %d", count)
+	}
+
+	coll.ResetEnabled()
+
+	if count := testutil.CollectAndCount(coll, "zfs_dataset_used_bytes"); count != 1 {
+		t.Errorf("expected 1 dataset metric after reset, got %d", count)
+	}
+
+	if err := coll.SetEnabled("bogus", false); !errors.Is(err, ErrUnknownCollector) {
+		t.Errorf("expected ErrUnknownCollector, got %v", err)
+	}
+}
+
+func TestCollector_StartupDisabled(t *testing.T) {
+	client := zfs.NewClient((&fixtureRunner{}).run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker((&fixtureRunner{}).run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, Enabled: map[string]bool{CollectorScan: false}})
+
+	got := coll.EnabledCollectors()
+	if got[CollectorScan] || !got[CollectorDatasets] || !got[CollectorServices] {
+		t.Errorf("unexpected startup state: %v", got)
+	}
+}
+
+func TestCollector_UserPropertyLabels(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\ntank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n",
+		propOut: "tank/media\texporter:owner\tteamA\n" +
+			"tank/media\texporter:cost-center\t42\n" +
+			"tank/media\texporter:cost_center\t43\n" +
+			"tank/media\texporter:__x\treserved\n" +
+			"tank/media\texporter:pool\tshadowed\n" +
+			"tank/media\tcom.sun:auto-snapshot\ttrue\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:            time.Second,
+		Enabled:            map[string]bool{CollectorScan: false, CollectorServices: false},
+		UserPropertyPrefix: "exporter:",
+	})
+
+	// "__x" is a reserved label name and is dropped. cost-center and
+	// cost_center share a label; the first by property name wins on every
+	// scrape.
+	expected := `
+		# HELP zfs_dataset_used_bytes Space consumed by dataset.
+		# TYPE zfs_dataset_used_bytes gauge
+		zfs_dataset_used_bytes{cost_center="",dataset="tank",owner="",pool="tank",type="filesystem"} 5.36870912e+09
+		zfs_dataset_used_bytes{cost_center="42",dataset="tank/media",owner="teamA",pool="tank",type="filesystem"} 4.294967296e+09
+	`
+
+	for range 5 {
+		if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_dataset_used_bytes"); err != nil {
+			t.Fatalf("user property labels mismatch: %v", err)
+		}
+	}
+}
+
+func TestSanitizeLabelName(t *testing.T) {
+	tests := map[string]string{
+		"owner":       "owner",
+		"cost-center": "cost_center",
+		"9lives":      "_9lives",
+		"a.b:c":       "a_b_c",
+		"":            "",
+	}
+
+	for in, want := range tests {
+		if got := sanitizeLabelName(in); got != want {
+			t.Errorf("sanitizeLabelName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCollector_ThresholdProperties(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n",
+		propOut: "tank/media\texporter:quota_warn_ratio\t80%\n" +
+			"tank/media\texporter:snapshot_max_age\t2d\n" +
+			"tank/media\texporter:quota_crit_ratio\tbogus\n" +
+			"tank/media\texporter:owner\tteamA\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:            time.Second,
+		Enabled:            map[string]bool{CollectorScan: false, CollectorServices: false},
+		UserPropertyPrefix: "exporter:",
+	})
+
+	expected := `
+		# HELP zfs_dataset_threshold Per-dataset alert threshold read from a ZFS user property, by kind (ratios 0-1, ages in seconds).
+		# TYPE zfs_dataset_threshold gauge
+		zfs_dataset_threshold{dataset="tank/media",kind="quota_warn_ratio",pool="tank",type="filesystem"} 0.8
+		zfs_dataset_threshold{dataset="tank/media",kind="snapshot_max_age",pool="tank",type="filesystem"} 172800
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_dataset_threshold"); err != nil {
+		t.Errorf("threshold mismatch: %v", err)
+	}
+
+	// Threshold properties must not leak into labels.
+	labelsExpected := `
+		# HELP zfs_dataset_used_bytes Space consumed by dataset.
+		# TYPE zfs_dataset_used_bytes gauge
+		zfs_dataset_used_bytes{dataset="tank/media",owner="teamA",pool="tank",type="filesystem"} 4.294967296e+09
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(labelsExpected), "zfs_dataset_used_bytes"); err != nil {
+		t.Errorf("labels mismatch: %v", err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]float64{
+		"3600": 3600,
+		"36h":  129600,
+		"7d":   604800,
+		"1w":   604800,
+		"1.5d": 129600,
+	}
+
+	for in, want := range tests {
+		got, err := parseAge(in)
+		if err != nil {
+			t.Errorf("parseAge(%q): %v", in, err)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("parseAge(%q) = %v, want %v", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"soon", "0", "-1d", "-36h", "0s", "NaN", "+Inf"} {
+		if _, err := parseAge(in); err == nil {
+			t.Errorf("parseAge(%q): expected error", in)
+		}
+	}
+}
+
+func TestCollector_AggregateMetrics(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"backup\t5368709120\t1073741824\t4294967296\t10\t1.00\tDEGRADED\toff\n",
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_pools_total Number of collected pools.
+		# TYPE zfs_pools_total gauge
+		zfs_pools_total 2
+		# HELP zfs_pools_unhealthy Number of collected pools whose health is not ONLINE.
+		# TYPE zfs_pools_unhealthy gauge
+		zfs_pools_unhealthy 1
+		# HELP zfs_total_allocated_bytes Allocated space across all collected pools in bytes.
+		# TYPE zfs_total_allocated_bytes gauge
+		zfs_total_allocated_bytes 6.442450944e+09
+		# HELP zfs_total_size_bytes Total size of all collected pools in bytes.
+		# TYPE zfs_total_size_bytes gauge
+		zfs_total_size_bytes 1.610612736e+10
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_pools_total", "zfs_pools_unhealthy", "zfs_total_allocated_bytes", "zfs_total_size_bytes"); err != nil {
+		t.Errorf("aggregate metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_DatasetHistogram(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n" +
+			"tank/small\t524288\t5368709120\t524288\tfilesystem\toff\toff\t1.00\t524288\t524288\n" +
+			"tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Enabled: map[string]bool{CollectorDatasets: false, CollectorDatasetHistogram: true},
+	})
+
+	if count := testutil.CollectAndCount(coll, "zfs_dataset_used_bytes"); count != 0 {
+		t.Errorf("expected no per-dataset series, got %d", count)
+	}
+
+	if count := testutil.CollectAndCount(coll, "zfs_dataset_used_bytes_histogram"); count != 1 {
+		t.Fatalf("expected 1 histogram, got %d", count)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(coll)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	for _, mf := range families {
+		if mf.GetName() != "zfs_dataset_used_bytes_histogram" {
+			continue
+		}
+
+		h := mf.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 3 {
+			t.Errorf("sample count = %d, want 3", h.GetSampleCount())
+		}
+
+		// 512 KiB falls in the first (1 MiB) bucket.
+		if got := h.GetBucket()[0].GetCumulativeCount(); got != 1 {
+			t.Errorf("first bucket = %d, want 1", got)
+		}
+	}
+}
+
+func TestCollector_DatasetTuning(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n" +
+			"tank/vm\t1073741824\t5368709120\t1073741824\tvolume\toff\toff\t1.00\t1073741824\t1073741824\n",
+		propOut: "tank/media\trecordsize\t1048576\n" +
+			"tank/media\tsync\tdisabled\n" +
+			"tank/media\tatime\ton\n" +
+			"tank/media\tprimarycache\tall\n" +
+			"tank/media\tcompression\tzstd\n" +
+			"tank/vm\trecordsize\t-\n" +
+			"tank/vm\tsync\tstandard\n" +
+			"tank/vm\tatime\t-\n" +
+			"tank/vm\tprimarycache\tmetadata\n" +
+			"tank/vm\tcompression\tlz4\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Enabled: map[string]bool{CollectorDatasets: false, CollectorDatasetTuning: true},
+	})
+
+	expected := `
+		# HELP zfs_dataset_properties Tuning properties of the dataset as labels; empty where they do not apply (recordsize and atime on volumes). Always 1.
+		# TYPE zfs_dataset_properties gauge
+		zfs_dataset_properties{atime="on",compression="zstd",dataset="tank/media",pool="tank",primarycache="all",recordsize="1048576",sync="disabled",type="filesystem"} 1
+		zfs_dataset_properties{atime="",compression="lz4",dataset="tank/vm",pool="tank",primarycache="metadata",recordsize="",sync="standard",type="volume"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_dataset_properties"); err != nil {
+		t.Errorf("dataset tuning metrics mismatch: %v", err)
+	}
+
+	if n := testutil.CollectAndCount(coll, "zfs_dataset_used_bytes"); n != 0 {
+		t.Errorf("expected no per-dataset series with the dataset collector off, got %d", n)
+	}
+}
+
+func TestCollector_DatasetHistogramDisabledByDefault(t *testing.T) {
+	coll := newTestCollector(&fixtureRunner{})
+
+	if coll.EnabledCollectors()[CollectorDatasetHistogram] {
+		t.Error("dataset histogram should be disabled by default")
+	}
+}
+
+func TestCollector_CustomHooks(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+	}
+
+	hookRunner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		switch name {
+		case "/usr/local/bin/ok":
+			return []byte(`[{"labels":{"dataset":"tank/a"},"value":3}]`), nil
+		case "/usr/local/bin/broken":
+			return nil, errors.New("exit status 1")
+		case "/usr/local/bin/dupes":
+			return []byte(`[{"labels":{"dataset":"tank/a"},"value":1},{"labels":{"dataset":"tank/a"},"value":2}]`), nil
+		default:
+			return f.run(ctx, name, args...)
+		}
+	}
+
+	hooks := []custom.Hook{
+		{Name: "widgets", Help: "Widgets per dataset.", Labels: []string{"dataset"}, Command: []string{"/usr/local/bin/ok"}},
+		{Name: "broken", Command: []string{"/usr/local/bin/broken"}},
+		{Name: "dupes", Labels: []string{"dataset"}, Command: []string{"/usr/local/bin/dupes"}},
+	}
+
+	if err := custom.ValidateHooks(hooks); err != nil {
+		t.Fatalf("ValidateHooks: %v", err)
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:      time.Second,
+		CustomHooks:  hooks,
+		CustomRunner: custom.NewRunner(hookRunner, "zfs"),
+	})
+
+	expected := `
+		# HELP zfs_custom_success 1 if the custom hook ran and produced valid output, 0 otherwise.
+		# TYPE zfs_custom_success gauge
+		zfs_custom_success{hook="broken"} 0
+		zfs_custom_success{hook="dupes"} 0
+		zfs_custom_success{hook="widgets"} 1
+		# HELP zfs_custom_widgets Widgets per dataset.
+		# TYPE zfs_custom_widgets gauge
+		zfs_custom_widgets{dataset="tank/a"} 3
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_custom_success", "zfs_custom_widgets"); err != nil {
+		t.Errorf("custom hook metrics mismatch: %v", err)
+	}
+
+	// A failing hook must not affect zfs_up.
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(`
+		# HELP zfs_up Whether ZFS commands succeeded.
+		# TYPE zfs_up gauge
+		zfs_up 1
+	`), "zfs_up"); err != nil {
+		t.Errorf("up metric mismatch: %v", err)
+	}
+}
+
+func TestCollector_VdevErrors(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+  scan: none requested
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+	  mirror-0  ONLINE       0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     ONLINE       0     1    12
+
+errors: No known data errors
+`,
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_vdev_checksum_errors Checksum errors reported by zpool status for the device.
+		# TYPE zfs_vdev_checksum_errors gauge
+		zfs_vdev_checksum_errors{device="sda",pool="tank",vdev="mirror-0"} 0
+		zfs_vdev_checksum_errors{device="sdb",pool="tank",vdev="mirror-0"} 12
+		# HELP zfs_vdev_write_errors Write I/O errors reported by zpool status for the device.
+		# TYPE zfs_vdev_write_errors gauge
+		zfs_vdev_write_errors{device="sda",pool="tank",vdev="mirror-0"} 0
+		zfs_vdev_write_errors{device="sdb",pool="tank",vdev="mirror-0"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_vdev_checksum_errors", "zfs_vdev_write_errors"); err != nil {
+		t.Errorf("vdev metrics mismatch: %v", err)
+	}
+
+	if err := coll.SetEnabled(CollectorVdev, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(coll, "zfs_vdev_read_errors"); n != 0 {
+		t.Errorf("expected no vdev metrics when disabled, got %d", n)
+	}
+}
+
+func TestCollector_VdevCapacity(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t4000\t2400\t1600\t20\t1.00\tONLINE\toff\nusb\t1000\t0\t1000\t-\t1.00\tONLINE\toff\n",
+		vdevList: `NAME          SIZE  ALLOC  FREE  CKPOINT  EXPANDSZ  FRAG  CAP  DEDUP  HEALTH  ALTROOT
+tank          4000  2400   1600  -        -         20    60   1.00   ONLINE  -
+  mirror-0    2000  1800   200   -        -         35    90   -      ONLINE
+    sda       2000  -      -     -        -         -     -    -      ONLINE
+    sdb       2000  -      -     -        -         -     -    -      ONLINE
+  mirror-1    2000  600    1400  -        -         5     30   -      ONLINE
+    sdc       2000  -      -     -        -         -     -    -      ONLINE
+    sdd       2000  -      -     -        -         -     -    -      ONLINE
+usb           1000  0      1000  -        -         -     0    1.00   ONLINE  -
+  sde         1000  0      1000  -        -         -     0    -      ONLINE
+`,
+	}
+
+	coll := NewCollector(zfs.NewClient(f.run, testLogger(), "zpool", "zfs"), host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: 10 * time.Second, PoolInclude: regexp.MustCompile(`^tank$`)})
+
+	expected := `
+		# HELP zfs_vdev_capacity_ratio Allocated share (0-1) of the top-level vdev's size, from zpool list -v.
+		# TYPE zfs_vdev_capacity_ratio gauge
+		zfs_vdev_capacity_ratio{pool="tank",vdev="mirror-0"} 0.9
+		zfs_vdev_capacity_ratio{pool="tank",vdev="mirror-1"} 0.3
+		# HELP zfs_vdev_fragmentation_ratio Fragmentation of the top-level vdev as a ratio (0-1), NaN if unavailable.
+		# TYPE zfs_vdev_fragmentation_ratio gauge
+		zfs_vdev_fragmentation_ratio{pool="tank",vdev="mirror-0"} 0.35
+		zfs_vdev_fragmentation_ratio{pool="tank",vdev="mirror-1"} 0.05
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_vdev_capacity_ratio", "zfs_vdev_fragmentation_ratio"); err != nil {
+		t.Errorf("vdev capacity mismatch: %v", err)
+	}
+}
+
+func TestCollector_CompressRatio(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.37\t5368709120\t262144\n" +
+			"tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.01\t4294967296\t4294967296\n",
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_dataset_compressratio Compression ratio achieved by the dataset (1 = uncompressed).
+		# TYPE zfs_dataset_compressratio gauge
+		zfs_dataset_compressratio{dataset="tank",pool="tank",type="filesystem"} 1.37
+		zfs_dataset_compressratio{dataset="tank/media",pool="tank",type="filesystem"} 1.01
+		# HELP zfs_pool_compressratio Compression ratio achieved across the pool, from its root dataset (1 = uncompressed).
+		# TYPE zfs_pool_compressratio gauge
+		zfs_pool_compressratio{pool="tank"} 1.37
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_dataset_compressratio", "zfs_pool_compressratio"); err != nil {
+		t.Errorf("compressratio mismatch: %v", err)
+	}
+}
+
+func TestCollector_LogicalSpace(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.50\t6442450944\t6442450944\n",
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_dataset_logical_referenced_bytes Space referenced by dataset before compression.
+		# TYPE zfs_dataset_logical_referenced_bytes gauge
+		zfs_dataset_logical_referenced_bytes{dataset="tank/media",pool="tank",type="filesystem"} 6.442450944e+09
+		# HELP zfs_dataset_logical_used_bytes Space consumed by dataset before compression.
+		# TYPE zfs_dataset_logical_used_bytes gauge
+		zfs_dataset_logical_used_bytes{dataset="tank/media",pool="tank",type="filesystem"} 6.442450944e+09
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_dataset_logical_used_bytes", "zfs_dataset_logical_referenced_bytes"); err != nil {
+		t.Errorf("logical space mismatch: %v", err)
+	}
+}
+
+func TestCollector_FeatureEnabled(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		versionOut: "zfs-2.2.6-1\nzfs-kmod-2.2.6-1\n",
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_exporter_feature_enabled 1 if the host's ZFS version supports the feature and the exporter uses it, 0 otherwise.
+		# TYPE zfs_exporter_feature_enabled gauge
+		zfs_exporter_feature_enabled{feature="json"} 0
+		zfs_exporter_feature_enabled{feature="latency_histograms"} 1
+		zfs_exporter_feature_enabled{feature="raidz_expansion"} 0
+		zfs_exporter_feature_enabled{feature="trim"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_exporter_feature_enabled"); err != nil {
+		t.Errorf("feature metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_LogicalSpaceUnsupported(t *testing.T) {
+	// Columns dropped after DetectColumns are parsed as "-".
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.50\t-\t-\n",
+	}
+
+	coll := newTestCollector(f)
+
+	if n := testutil.CollectAndCount(coll, "zfs_dataset_logical_used_bytes", "zfs_dataset_logical_referenced_bytes"); n != 0 {
+		t.Errorf("expected no logical space metrics, got %d", n)
+	}
+
+	if n := testutil.CollectAndCount(coll, "zfs_dataset_used_bytes"); n != 1 {
+		t.Errorf("expected the other dataset metrics, got %d used series", n)
+	}
+}
+
+func TestCollector_Snapshots(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"usb\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n",
+		snapOut: "tank@daily-1\t0\n" +
+			"tank/media@daily-1\t0\t1735603200\n" +
+			"tank/media@daily-2\t1\t1735689600\n" +
+			"tank/media@daily-3\t1\t1735646400\n" +
+			"usb/backup@daily-1\t1\t1735689600\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, PoolExclude: regexp.MustCompile("^(?:usb)$")})
+
+	expected := `
+		# HELP zfs_dataset_snapshot_count Number of snapshots of the dataset (not including its descendants).
+		# TYPE zfs_dataset_snapshot_count gauge
+		zfs_dataset_snapshot_count{dataset="tank",pool="tank"} 1
+		zfs_dataset_snapshot_count{dataset="tank/media",pool="tank"} 3
+		# HELP zfs_dataset_snapshot_holds User holds (zfs hold) on the dataset's snapshots. Held snapshots cannot be destroyed.
+		# TYPE zfs_dataset_snapshot_holds gauge
+		zfs_dataset_snapshot_holds{dataset="tank",pool="tank"} 0
+		zfs_dataset_snapshot_holds{dataset="tank/media",pool="tank"} 2
+		# HELP zfs_dataset_snapshot_newest_timestamp_seconds Unix time the dataset's most recent snapshot was taken.
+		# TYPE zfs_dataset_snapshot_newest_timestamp_seconds gauge
+		zfs_dataset_snapshot_newest_timestamp_seconds{dataset="tank/media",pool="tank"} 1.7356896e+09
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_dataset_snapshot_count", "zfs_dataset_snapshot_holds", "zfs_dataset_snapshot_newest_timestamp_seconds"); err != nil {
+		t.Errorf("snapshot metrics mismatch: %v", err)
+	}
+
+	if err := coll.SetEnabled(CollectorSnapshots, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(coll, "zfs_dataset_snapshot_count"); n != 0 {
+		t.Errorf("expected no snapshot metrics when disabled, got %d", n)
+	}
+}
+
+func TestCollector_SnapshotsTruncated(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		snapOut: "tank@daily-1\t0\n" +
+			"tank/media@daily-1\t0\n" +
+			"tank/media@daily-2\t1\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	client.SetSnapshotLimit(2)
+	client.SetSnapshotPaging(true)
+
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{Timeout: time.Second})
+
+	expected := `
+		# HELP zfs_dataset_snapshot_count Number of snapshots of the dataset (not including its descendants).
+		# TYPE zfs_dataset_snapshot_count gauge
+		zfs_dataset_snapshot_count{dataset="tank",pool="tank"} 1
+		zfs_dataset_snapshot_count{dataset="tank/media",pool="tank"} 1
+		# HELP zfs_snapshot_counts_truncated 1 if the snapshot counts are incomplete because listing stopped at --snapshot.max or the scrape timeout, 0 otherwise.
+		# TYPE zfs_snapshot_counts_truncated gauge
+		zfs_snapshot_counts_truncated 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_dataset_snapshot_count", "zfs_snapshot_counts_truncated"); err != nil {
+		t.Errorf("snapshot metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_PoolHealthUnknown(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tRESILVERING\toff\n" +
+			"usb\t536870912\t268435456\t268435456\t0\t1.00\tREBUILDING\toff\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:          time.Second,
+		PoolHealthStates: []string{"Rebuilding", "online"},
+	})
+
+	// The extra state joins the state-set; only tank's state is unknown.
+	expected := `
+		# HELP zfs_pool_health_unknown 1 if pool reports a health state missing from the zfs_pool_health state-set, labeled with the state as reported.
+		# TYPE zfs_pool_health_unknown gauge
+		zfs_pool_health_unknown{pool="tank",raw_state="RESILVERING"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_pool_health_unknown"); err != nil {
+		t.Errorf("unknown health mismatch: %v", err)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(coll)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	for _, mf := range families {
+		if mf.GetName() != "zfs_pool_health" {
+			continue
+		}
+
+		// 8 known states plus rebuilding, for each pool.
+		if got := len(mf.GetMetric()); got != 18 {
+			t.Errorf("zfs_pool_health has %d series, want 18", got)
+		}
+
+		for _, m := range mf.GetMetric() {
+			labels := m.GetLabel()
+			if labels[0].GetValue() == "usb" && labels[1].GetValue() == "rebuilding" && m.GetGauge().GetValue() != 1 {
+				t.Errorf("usb not in the rebuilding state: %v", m)
+			}
+		}
+	}
+}
+
+func TestCollector_PoolStatusMessages(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+status: One or more devices has experienced an unrecoverable error.  An
+	attempt was made to correct the error.  Applications are unaffected.
+action: Determine if the device needs to be replaced, and clear the errors
+	using 'zpool clear' or replace the device with 'zpool replace'.
+  scan: none requested
+config:
+
+errors: 2 data errors, use '-v' for a list
+`,
+	}
+
+	expected := `
+		# HELP zfs_pool_data_errors Number of known permanent data errors (files or objects that could not be repaired).
+		# TYPE zfs_pool_data_errors gauge
+		zfs_pool_data_errors{pool="tank"} 2
+		# HELP zfs_pool_status_code 1 if the pool's zpool status message is of the labeled class, 0 otherwise; ok when there is no message.
+		# TYPE zfs_pool_status_code gauge
+		zfs_pool_status_code{code="checksum_errors",pool="tank"} 1
+		zfs_pool_status_code{code="data_corruption",pool="tank"} 0
+		zfs_pool_status_code{code="device_fault",pool="tank"} 0
+		zfs_pool_status_code{code="device_missing",pool="tank"} 0
+		zfs_pool_status_code{code="device_offline",pool="tank"} 0
+		zfs_pool_status_code{code="device_removed",pool="tank"} 0
+		zfs_pool_status_code{code="errata",pool="tank"} 0
+		zfs_pool_status_code{code="feature_unsupported",pool="tank"} 0
+		zfs_pool_status_code{code="hostid_mismatch",pool="tank"} 0
+		zfs_pool_status_code{code="io_suspended",pool="tank"} 0
+		zfs_pool_status_code{code="non_native_ashift",pool="tank"} 0
+		zfs_pool_status_code{code="ok",pool="tank"} 0
+		zfs_pool_status_code{code="other",pool="tank"} 0
+		zfs_pool_status_code{code="resilver_in_progress",pool="tank"} 0
+		zfs_pool_status_code{code="upgrade_available",pool="tank"} 0
+	`
+
+	if err := testutil.CollectAndCompare(newTestCollector(f), strings.NewReader(expected), "zfs_pool_status_code", "zfs_pool_data_errors"); err != nil {
+		t.Errorf("status message metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_PoolSuspended(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"usb\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+  scan: none requested
+
+  pool: usb
+ state: ONLINE
+status: One or more devices are faulted in response to IO failures.
+action: Make sure the affected devices are connected, then run 'zpool clear'.
+  scan: none requested
+`,
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_pool_health 1 if pool is in the labeled state, 0 otherwise.
+		# TYPE zfs_pool_health gauge
+		zfs_pool_health{pool="tank",state="degraded"} 0
+		zfs_pool_health{pool="tank",state="faulted"} 0
+		zfs_pool_health{pool="tank",state="offline"} 0
+		zfs_pool_health{pool="tank",state="online"} 1
+		zfs_pool_health{pool="tank",state="removed"} 0
+		zfs_pool_health{pool="tank",state="split"} 0
+		zfs_pool_health{pool="tank",state="suspended"} 0
+		zfs_pool_health{pool="tank",state="unavail"} 0
+		zfs_pool_health{pool="usb",state="degraded"} 0
+		zfs_pool_health{pool="usb",state="faulted"} 0
+		zfs_pool_health{pool="usb",state="offline"} 0
+		zfs_pool_health{pool="usb",state="online"} 0
+		zfs_pool_health{pool="usb",state="removed"} 0
+		zfs_pool_health{pool="usb",state="split"} 0
+		zfs_pool_health{pool="usb",state="suspended"} 1
+		zfs_pool_health{pool="usb",state="unavail"} 0
+		# HELP zfs_pools_unhealthy Number of collected pools whose health is not ONLINE.
+		# TYPE zfs_pools_unhealthy gauge
+		zfs_pools_unhealthy 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_pool_health", "zfs_pools_unhealthy"); err != nil {
+		t.Errorf("suspended pool mismatch: %v", err)
+	}
+
+	// The scrape cache still holds the pool as listed.
+	if pools, _ := coll.last.Load().subs[collectorPool].data.(poolState); pools.Pools[1].Health != "ONLINE" {
+		t.Errorf("cached pool health = %q, want ONLINE", pools.Pools[1].Health)
+	}
+}
+
+func TestCollector_Smart(t *testing.T) {
+	dev := t.TempDir()
+	for _, d := range []string{"sda", "sdb", "sdc"} {
+		if err := os.WriteFile(filepath.Join(dev, d), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+  scan: none requested
+config:
+
+	NAME                  STATE     READ WRITE CKSUM
+	tank                  ONLINE       0     0     0
+	  mirror-0            ONLINE       0     0     0
+	    sda               ONLINE       0     0     0
+	    sdb               ONLINE       0     0     0
+	    sdc               ONLINE       0     0     0
+	    /var/tmp/vdev0    ONLINE       0     0     0
+
+errors: No known data errors
+`,
+		smartOut: map[string]string{
+			filepath.Join(dev, "sda"): `{"smartctl": {"exit_status": 0}, "smart_status": {"passed": true},
+				"temperature": {"current": 35}, "ata_smart_attributes": {"table": [{"id": 5, "raw": {"value": 0}}]}}`,
+			filepath.Join(dev, "sdb"): `{"smartctl": {"exit_status": 8}, "smart_status": {"passed": false},
+				"temperature": {"current": 48}, "ata_smart_attributes": {"table": [{"id": 5, "raw": {"value": 1200}}]}}`,
+			filepath.Join(dev, "sdc"): `{"smartctl": {"exit_status": 2, "messages": [{"string": "Device is in STANDBY mode, exit(2)"}]}}`,
+		},
+	}
+
+	smart := host.NewSmartReader(f.run, "smartctl")
+	smart.SetRoots(dev, t.TempDir())
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, Smart: smart, Enabled: map[string]bool{CollectorVdev: false}})
+
+	expected := `
+		# HELP zfs_vdev_reallocated_sectors Reallocated sectors (ATA) or grown defects (SCSI) reported by SMART.
+		# TYPE zfs_vdev_reallocated_sectors gauge
+		zfs_vdev_reallocated_sectors{device="sda",pool="tank",vdev="mirror-0"} 0
+		zfs_vdev_reallocated_sectors{device="sdb",pool="tank",vdev="mirror-0"} 1200
+		# HELP zfs_vdev_smart_healthy 1 if the device's SMART overall-health self-assessment passed, 0 if it reports failing.
+		# TYPE zfs_vdev_smart_healthy gauge
+		zfs_vdev_smart_healthy{device="sda",pool="tank",vdev="mirror-0"} 1
+		zfs_vdev_smart_healthy{device="sdb",pool="tank",vdev="mirror-0"} 0
+		# HELP zfs_vdev_temperature_celsius Current device temperature reported by SMART.
+		# TYPE zfs_vdev_temperature_celsius gauge
+		zfs_vdev_temperature_celsius{device="sda",pool="tank",vdev="mirror-0"} 35
+		zfs_vdev_temperature_celsius{device="sdb",pool="tank",vdev="mirror-0"} 48
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_vdev_smart_healthy", "zfs_vdev_temperature_celsius", "zfs_vdev_reallocated_sectors"); err != nil {
+		t.Errorf("smart metrics mismatch: %v", err)
+	}
+
+	// The vdev collector is off; its device list is still fetched for SMART.
+	if n := testutil.CollectAndCount(coll, "zfs_vdev_state"); n != 0 {
+		t.Errorf("expected no vdev metrics, got %d", n)
+	}
+
+	if err := coll.SetEnabled(CollectorSMART, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(coll, "zfs_vdev_smart_healthy"); n != 0 {
+		t.Errorf("expected no smart metrics when disabled, got %d", n)
+	}
+}
+
+func TestCollector_DeviceIDs(t *testing.T) {
+	root := t.TempDir()
+	dev := filepath.Join(root, "dev")
+	sys := filepath.Join(root, "sys")
+
+	for _, d := range []string{
+		filepath.Join(dev, "disk", "by-id"),
+		filepath.Join(sys, "class", "block", "sda", "device"),
+		filepath.Join(sys, "class", "block", "nvme0n1", "device"),
+	} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		filepath.Join(dev, "sda"):     "",
+		filepath.Join(dev, "sdb"):     "",
+		filepath.Join(dev, "sdc"):     "",
+		filepath.Join(dev, "nvme0n1"): "",
+		filepath.Join(sys, "class", "block", "sda", "device", "vpd_pg80"):   "\x00\x80\x00\x07SERIALA",
+		filepath.Join(sys, "class", "block", "nvme0n1", "device", "serial"): "NVME1\n",
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for link, target := range map[string]string{
+		"ata-DISK_A":         "../../sda",
+		"wwn-0x5000000000a1": "../../sda",
+		"ata-DISK_B":         "../../sdb",
+		"ata-DISK_C":         "../../sdc",
+	} {
+		if err := os.Symlink(target, filepath.Join(dev, "disk", "by-id", link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// nvme0n1 has no by-id link and keeps its name; the file vdev is no
+	// block device and gets no identity.
+	f := &fixtureRunner{
+		poolOut: "tank\t4000\t2400\t1600\t20\t1.00\tONLINE\toff\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+config:
+
+	NAME                STATE     READ WRITE CKSUM
+	tank                ONLINE       0     0     0
+	  mirror-0          ONLINE       0     0     0
+	    sda             ONLINE       0     0     0
+	    sdb             ONLINE       0     0     0
+	  sdc               ONLINE       0     0     0
+	  /var/tmp/vdev0    ONLINE       0     0     0
+	cache
+	  nvme0n1           ONLINE       0     0     0
+
+errors: No known data errors
+`,
+		vdevList: `NAME          SIZE  ALLOC  FREE  CKPOINT  EXPANDSZ  FRAG  CAP  DEDUP  HEALTH  ALTROOT
+tank          4000  2400   1600  -        -         20    60   1.00   ONLINE  -
+  mirror-0    2000  1800   200   -        -         35    90   -      ONLINE
+    sda       2000  -      -     -        -         -     -    -      ONLINE
+    sdb       2000  -      -     -        -         -     -    -      ONLINE
+  sdc         2000  600    1400  -        -         5     30   -      ONLINE
+`,
+	}
+
+	devices := host.NewDeviceResolver()
+	devices.SetRoots(dev, sys)
+
+	coll := NewCollector(zfs.NewClient(f.run, testLogger(), "zpool", "zfs"), host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, Devices: devices})
+
+	expected := fmt.Sprintf(`
+		# HELP zfs_vdev_capacity_ratio Allocated share (0-1) of the top-level vdev's size, from zpool list -v.
+		# TYPE zfs_vdev_capacity_ratio gauge
+		zfs_vdev_capacity_ratio{pool="tank",vdev="ata-DISK_C"} 0.3
+		zfs_vdev_capacity_ratio{pool="tank",vdev="mirror-0"} 0.9
+		# HELP zfs_vdev_device_info Device node and disk serial number ("" if unreadable) of each pool member device, named by its /dev/disk/by-id link where it has one.
+		# TYPE zfs_vdev_device_info gauge
+		zfs_vdev_device_info{device="ata-DISK_A",path="%[1]s/sda",pool="tank",serial="SERIALA",vdev="mirror-0"} 1
+		zfs_vdev_device_info{device="ata-DISK_B",path="%[1]s/sdb",pool="tank",serial="",vdev="mirror-0"} 1
+		zfs_vdev_device_info{device="ata-DISK_C",path="%[1]s/sdc",pool="tank",serial="",vdev="ata-DISK_C"} 1
+		zfs_vdev_device_info{device="nvme0n1",path="%[1]s/nvme0n1",pool="tank",serial="NVME1",vdev="nvme0n1"} 1
+		# HELP zfs_vdev_read_errors Read I/O errors reported by zpool status for the device.
+		# TYPE zfs_vdev_read_errors gauge
+		zfs_vdev_read_errors{device="/var/tmp/vdev0",pool="tank",vdev="/var/tmp/vdev0"} 0
+		zfs_vdev_read_errors{device="ata-DISK_A",pool="tank",vdev="mirror-0"} 0
+		zfs_vdev_read_errors{device="ata-DISK_B",pool="tank",vdev="mirror-0"} 0
+		zfs_vdev_read_errors{device="ata-DISK_C",pool="tank",vdev="ata-DISK_C"} 0
+		zfs_vdev_read_errors{device="nvme0n1",pool="tank",vdev="nvme0n1"} 0
+	`, dev)
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_vdev_capacity_ratio", "zfs_vdev_device_info", "zfs_vdev_read_errors"); err != nil {
+		t.Errorf("device identity mismatch: %v", err)
+	}
+}
+
+func TestCollector_SnapshotPolicies(t *testing.T) {
+	now := time.Now()
+	at := func(ago time.Duration) string { return strconv.FormatInt(now.Add(-ago).Unix(), 10) }
+
+	// tank/home's hourly snapshots stopped three hours ago; tank/db is on
+	// schedule. Manual snapshots match no class.
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		snapOut: "tank/home@autosnap_a_hourly\t" + at(4*time.Hour) + "\n" +
+			"tank/home@autosnap_b_hourly\t" + at(3*time.Hour) + "\n" +
+			"tank/home@autosnap_a_daily\t" + at(20*time.Hour) + "\n" +
+			"tank/home@before-upgrade\t" + at(time.Minute) + "\n" +
+			"tank/db@autosnap_c_hourly\t" + at(30*time.Minute) + "\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		SnapshotPolicies: []zfs.SnapshotPolicy{
+			{Class: "hourly", Pattern: "autosnap_*_hourly", MaxAge: 2 * time.Hour},
+			{Class: "daily", Pattern: "autosnap_*_daily", MaxAge: 26 * time.Hour},
+		},
+	})
+
+	expected := `
+		# HELP zfs_dataset_snapshot_policy_compliant 1 if the newest snapshot of the retention class is younger than the class's maximum age, 0 otherwise.
+		# TYPE zfs_dataset_snapshot_policy_compliant gauge
+		zfs_dataset_snapshot_policy_compliant{class="daily",dataset="tank/home",pool="tank"} 1
+		zfs_dataset_snapshot_policy_compliant{class="hourly",dataset="tank/db",pool="tank"} 1
+		zfs_dataset_snapshot_policy_compliant{class="hourly",dataset="tank/home",pool="tank"} 0
+		# HELP zfs_dataset_snapshot_policy_count Number of snapshots of the dataset whose name matches the retention class in --snapshot.policies.
+		# TYPE zfs_dataset_snapshot_policy_count gauge
+		zfs_dataset_snapshot_policy_count{class="daily",dataset="tank/home",pool="tank"} 1
+		zfs_dataset_snapshot_policy_count{class="hourly",dataset="tank/db",pool="tank"} 1
+		zfs_dataset_snapshot_policy_count{class="hourly",dataset="tank/home",pool="tank"} 2
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_dataset_snapshot_policy_compliant", "zfs_dataset_snapshot_policy_count"); err != nil {
+		t.Errorf("snapshot policy metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_NFSExports(t *testing.T) {
+	exports := filepath.Join(t.TempDir(), "exports")
+	if err := os.WriteFile(exports, []byte("# Version 1.1\n"+
+		"/tank/media\t*(rw,sec=1)\n/tank/media\t10.0.0.5(ro,sec=1)\n/srv/other\t*(ro,sec=1)\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		propOut: "tank\tsharenfs\toff\n" +
+			"tank\tmountpoint\t/tank\n" +
+			"tank\tmounted\tyes\n" +
+			"tank/media\tsharenfs\trw=@10.0.0.0/8\n" +
+			"tank/media\tmountpoint\t/tank/media\n" +
+			"tank/media\tmounted\tyes\n" +
+			"tank/backup\tsharenfs\ton\n" +
+			"tank/backup\tmountpoint\t/tank/backup\n" +
+			"tank/backup\tmounted\tyes\n" +
+			"tank/legacy\tsharenfs\ton\n" +
+			"tank/legacy\tmountpoint\tlegacy\n" +
+			"tank/legacy\tmounted\tyes\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, NFSExportsPath: exports, Enabled: map[string]bool{CollectorNFS: true}})
+
+	expected := `
+		# HELP zfs_dataset_nfs_exported 1 if the mountpoint of a dataset with sharenfs set is in the kernel NFS export table, 0 otherwise.
+		# TYPE zfs_dataset_nfs_exported gauge
+		zfs_dataset_nfs_exported{dataset="tank/backup",pool="tank"} 0
+		zfs_dataset_nfs_exported{dataset="tank/media",pool="tank"} 1
+		# HELP zfs_nfs_exports Distinct paths in the kernel NFS export table (/proc/fs/nfsd/exports).
+		# TYPE zfs_nfs_exports gauge
+		zfs_nfs_exports 2
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_dataset_nfs_exported", "zfs_nfs_exports"); err != nil {
+		t.Errorf("nfs metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_Timers(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		svcResults: map[string]struct {
+			output string
+			err    error
+		}{
+			"sanoid.timer": {output: "Id=zfs-scrub-weekly@tank.timer\nLoadState=loaded\nActiveState=active\n" +
+				"LastTriggerUSec=@1738540800\nNextElapseUSecRealtime=@1739145600\n\n" +
+				"Id=sanoid.timer\nLoadState=masked\nActiveState=inactive\n" +
+				"LastTriggerUSec=@1730000000\nNextElapseUSecRealtime=\n"},
+		},
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, Timers: []string{"zfs-scrub-weekly@*.timer", "sanoid.timer"}})
+
+	expected := `
+		# HELP zfs_timer_active 1 if the systemd timer is waiting to elapse, 0 if it is stopped, disabled, or masked.
+		# TYPE zfs_timer_active gauge
+		zfs_timer_active{timer="sanoid.timer"} 0
+		zfs_timer_active{timer="zfs-scrub-weekly@tank.timer"} 1
+		# HELP zfs_timer_last_trigger_timestamp_seconds When the systemd timer last elapsed. Absent if it never has.
+		# TYPE zfs_timer_last_trigger_timestamp_seconds gauge
+		zfs_timer_last_trigger_timestamp_seconds{timer="sanoid.timer"} 1.73e+09
+		zfs_timer_last_trigger_timestamp_seconds{timer="zfs-scrub-weekly@tank.timer"} 1.7385408e+09
+		# HELP zfs_timer_next_elapse_timestamp_seconds When the systemd timer next elapses. Absent if it is not scheduled.
+		# TYPE zfs_timer_next_elapse_timestamp_seconds gauge
+		zfs_timer_next_elapse_timestamp_seconds{timer="zfs-scrub-weekly@tank.timer"} 1.7391456e+09
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_timer_active",
+		"zfs_timer_last_trigger_timestamp_seconds", "zfs_timer_next_elapse_timestamp_seconds"); err != nil {
+		t.Errorf("timer metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_SMB(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		smbOut: `{"sessions": {"1": {"username": "alice"}, "2": {"username": "bob"}},
+			"tcons": {"10": {"service": "media"}, "11": {"service": "media"}, "12": {"service": "IPC$"}},
+			"open_files": {"/tank/media/a.mkv": {}, "/tank/media/b.mkv": {}, "/tank/media/c.mkv": {}}}`,
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, SMB: host.NewSMBReader(f.run, "smbstatus")})
+
+	expected := `
+		# HELP zfs_smb_open_files Distinct files held open by SMB clients (smbstatus).
+		# TYPE zfs_smb_open_files gauge
+		zfs_smb_open_files 3
+		# HELP zfs_smb_sessions Authenticated SMB client sessions (smbstatus).
+		# TYPE zfs_smb_sessions gauge
+		zfs_smb_sessions 2
+		# HELP zfs_smb_share_connections SMB tree connections to the share. Shares with no connections are absent.
+		# TYPE zfs_smb_share_connections gauge
+		zfs_smb_share_connections{share="IPC$"} 1
+		zfs_smb_share_connections{share="media"} 2
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_smb_sessions", "zfs_smb_open_files", "zfs_smb_share_connections"); err != nil {
+		t.Errorf("smb metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_ISCSI(t *testing.T) {
+	configfs := t.TempDir()
+	tpg := filepath.Join(configfs, "target", "iscsi", "iqn.2003-01.org.linux-iscsi.nas:vms", "tpgt_1")
+
+	// lun_0 is backed by a zvol in tank, lun_1 by one in the excluded pool usb.
+	for i, vol := range []string{"tank/vm1", "usb/vm2"} {
+		backstore := filepath.Join(configfs, "target", "core", "iblock_0", filepath.Base(vol))
+		lun := filepath.Join(tpg, "lun", fmt.Sprintf("lun_%d", i))
+
+		for _, dir := range []string{backstore, lun} {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := os.WriteFile(filepath.Join(backstore, "udev_path"), []byte("/dev/zvol/"+vol+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink(backstore, filepath.Join(lun, "8f3a2c")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(tpg, "dynamic_sessions"), []byte("iqn.1994-05.com.redhat:a\niqn.1994-05.com.redhat:b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixtureRunner{poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n"}
+
+	iscsi := host.NewISCSIReader(f.run, "ctladm")
+	iscsi.SetRoots(configfs, "/dev")
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, ISCSI: iscsi, PoolExclude: regexp.MustCompile("^(?:usb)$")})
+
+	expected := `
+		# HELP zfs_iscsi_lun_info Always 1; maps an iSCSI LUN and its backstore to the zvol behind it. LUNs not backed by a zvol are absent.
+		# TYPE zfs_iscsi_lun_info gauge
+		zfs_iscsi_lun_info{backstore="vm1",dataset="tank/vm1",lun="0",pool="tank",target="iqn.2003-01.org.linux-iscsi.nas:vms"} 1
+		# HELP zfs_iscsi_luns LUNs exported by iSCSI targets, over all target portal groups.
+		# TYPE zfs_iscsi_luns gauge
+		zfs_iscsi_luns 2
+		# HELP zfs_iscsi_sessions Initiator sessions logged in to iSCSI targets.
+		# TYPE zfs_iscsi_sessions gauge
+		zfs_iscsi_sessions 2
+		# HELP zfs_iscsi_targets Configured iSCSI target names.
+		# TYPE zfs_iscsi_targets gauge
+		zfs_iscsi_targets 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_iscsi_lun_info", "zfs_iscsi_luns", "zfs_iscsi_sessions", "zfs_iscsi_targets"); err != nil {
+		t.Errorf("iscsi metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_PoolProperties(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"usb\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n",
+		zpoolProps: "tank\tashift\t12\ntank\tautotrim\ton\ntank\tguid\t1234567890\n" +
+			"tank\taltroot\t-\ntank\tcachefile\t-\n" +
+			"usb\tashift\t0\nusb\tautotrim\toff\nusb\tguid\t987654321\n" +
+			"usb\taltroot\t/mnt\nusb\tcachefile\tnone\n",
+		propOut: "tank\tcreation\t1700000000\nusb\tcreation\t1738540800\n",
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_pool_ashift ashift property of the pool (log2 of the sector size used for new vdevs), 0 if detected from each disk.
+		# TYPE zfs_pool_ashift gauge
+		zfs_pool_ashift{pool="tank"} 12
+		zfs_pool_ashift{pool="usb"} 0
+		# HELP zfs_pool_autotrim 1 if the pool's autotrim property is on, 0 otherwise.
+		# TYPE zfs_pool_autotrim gauge
+		zfs_pool_autotrim{pool="tank"} 1
+		zfs_pool_autotrim{pool="usb"} 0
+		# HELP zfs_pool_created_timestamp_seconds Unix time the pool was created, from the creation property of its root dataset.
+		# TYPE zfs_pool_created_timestamp_seconds gauge
+		zfs_pool_created_timestamp_seconds{pool="tank"} 1.7e+09
+		zfs_pool_created_timestamp_seconds{pool="usb"} 1.7385408e+09
+		# HELP zfs_pool_info Import settings of the pool as labels; altroot and cachefile are empty when unset. Always 1.
+		# TYPE zfs_pool_info gauge
+		zfs_pool_info{altroot="",cachefile="",guid="1234567890",pool="tank"} 1
+		zfs_pool_info{altroot="/mnt",cachefile="none",guid="987654321",pool="usb"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_pool_ashift", "zfs_pool_autotrim", "zfs_pool_created_timestamp_seconds", "zfs_pool_info"); err != nil {
+		t.Errorf("pool property metrics mismatch: %v", err)
+	}
+
+	if err := coll.SetEnabled(CollectorPoolProperties, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(coll, "zfs_pool_ashift", "zfs_pool_autotrim", "zfs_pool_info"); n != 0 {
+		t.Errorf("expected no pool property metrics when disabled, got %d", n)
+	}
+}
+
+func TestCollector_History(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"usb\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n",
+		historyOut: "History for 'tank':\n" +
+			"2025-02-03.10:00:00 zfs snapshot tank@a\n" +
+			"2025-02-03.10:01:00 zfs snapshot tank@b\n" +
+			"2025-02-03.10:02:00 zfs set atime=off tank\n" +
+			"2099-01-01.00:00:00 zfs destroy tank@a\n" +
+			"\nHistory for 'usb':\n" +
+			"2025-02-03.11:00:00 zpool import usb\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, PoolExclude: regexp.MustCompile("^(?:usb)$")})
+
+	if n := testutil.CollectAndCount(coll, "zfs_pool_admin_operations_total"); n != 0 {
+		t.Errorf("expected no history metrics by default, got %d", n)
+	}
+
+	if err := coll.SetEnabled(CollectorHistory, true); err != nil {
+		t.Fatal(err)
+	}
+
+	coll.started = time.Date(2025, time.February, 3, 10, 0, 30, 0, time.Local)
+
+	expected := `
+		# HELP zfs_pool_admin_operations_total Administrative commands logged by zpool history since the exporter started, by operation.
+		# TYPE zfs_pool_admin_operations_total counter
+		zfs_pool_admin_operations_total{operation="dataset_create",pool="tank"} 0
+		zfs_pool_admin_operations_total{operation="dataset_destroy",pool="tank"} 0
+		zfs_pool_admin_operations_total{operation="import",pool="tank"} 0
+		zfs_pool_admin_operations_total{operation="other",pool="tank"} 0
+		zfs_pool_admin_operations_total{operation="property_change",pool="tank"} 1
+		zfs_pool_admin_operations_total{operation="scrub",pool="tank"} 0
+		zfs_pool_admin_operations_total{operation="snapshot_create",pool="tank"} 1
+		zfs_pool_admin_operations_total{operation="snapshot_destroy",pool="tank"} 1
+		zfs_pool_admin_operations_total{operation="vdev_change",pool="tank"} 0
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_pool_admin_operations_total"); err != nil {
+		t.Errorf("history metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_Latency(t *testing.T) {
+	header := "  total_wait     disk_wait    syncq_wait    asyncq_wait\n" +
+		"latency  read  write  read  write  read  write  read  write  scrub  trim\n" +
+		"-------  ----  -----  ----  -----  ----  -----  ----  -----  -----  ----\n"
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"usb\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n",
+		iostatOut: "tank" + header +
+			"15  2  0  2  0  0  0  0  0  0  0\n" +
+			"31  10  4  9  5  0  0  0  0  1  0\n" +
+			"63  1  7  1  6  0  0  0  0  3  0\n" +
+			"\nusb" + header +
+			"15  5  1  5  1  0  0  0  0  0  0\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, PoolExclude: regexp.MustCompile("^(?:usb)$")})
+
+	if n := testutil.CollectAndCount(coll, "zfs_pool_latency_seconds"); n != 0 {
+		t.Errorf("expected no latency metrics by default, got %d", n)
+	}
+
+	if err := coll.SetEnabled(CollectorLatency, true); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(coll)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	want := map[string]uint64{
+		"zfs_pool_latency_seconds/read":       13,
+		"zfs_pool_latency_seconds/write":      11,
+		"zfs_pool_latency_seconds/scrub":      4,
+		"zfs_pool_disk_latency_seconds/read":  12,
+		"zfs_pool_disk_latency_seconds/write": 11,
+	}
+
+	got := make(map[string]uint64)
+
+	for _, mf := range families {
+		if !strings.Contains(mf.GetName(), "latency_seconds") {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+
+			if labels["pool"] != "tank" {
+				t.Errorf("unexpected pool %q", labels["pool"])
+			}
+
+			h := m.GetHistogram()
+			if h.GetSchema() != latencySchema {
+				t.Errorf("%s schema = %d, want %d", mf.GetName(), h.GetSchema(), latencySchema)
+			}
+
+			got[mf.GetName()+"/"+labels["op"]] = h.GetSampleCount()
+		}
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("sample counts = %v, want %v", got, want)
+	}
+}
+
+func TestNativeLatencyBuckets(t *testing.T) {
+	// 16-31 ns is below 32 ns = 2^-24.897 s, in schema 3 bucket -199;
+	// 32-63 ns in bucket -191.
+	buckets, total := nativeLatencyBuckets([]uint64{0, 0, 0, 0, 10, 1})
+
+	if total != 11 {
+		t.Errorf("total = %d, want 11", total)
+	}
+
+	want := map[int]int64{-199: 10, -191: 1}
+	if fmt.Sprint(buckets) != fmt.Sprint(want) {
+		t.Errorf("buckets = %v, want %v", buckets, want)
+	}
+}
+
+func TestCollector_Userspace(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:  "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		userOut:  "alice\t1073741824\t2147483648\nbob\t4096\tnone\n",
+		groupOut: "staff\t1073745920\tnone\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, UserspaceDatasets: []string{"tank/home"}})
+
+	expected := `
+		# HELP zfs_dataset_group_used_bytes Space charged to the group in a dataset listed in --dataset.userspace (zfs groupspace).
+		# TYPE zfs_dataset_group_used_bytes gauge
+		zfs_dataset_group_used_bytes{dataset="tank/home",group="staff",pool="tank"} 1.07374592e+09
+		# HELP zfs_dataset_user_quota_bytes The user's userquota on a dataset listed in --dataset.userspace. Absent if no quota is set.
+		# TYPE zfs_dataset_user_quota_bytes gauge
+		zfs_dataset_user_quota_bytes{dataset="tank/home",pool="tank",user="alice"} 2.147483648e+09
+		# HELP zfs_dataset_user_used_bytes Space charged to the user in a dataset listed in --dataset.userspace (zfs userspace).
+		# TYPE zfs_dataset_user_used_bytes gauge
+		zfs_dataset_user_used_bytes{dataset="tank/home",pool="tank",user="alice"} 1.073741824e+09
+		zfs_dataset_user_used_bytes{dataset="tank/home",pool="tank",user="bob"} 4096
+	`
+
+	names := []string{
+		"zfs_dataset_user_used_bytes", "zfs_dataset_user_quota_bytes",
+		"zfs_dataset_group_used_bytes", "zfs_dataset_group_quota_bytes",
+	}
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), names...); err != nil {
+		t.Errorf("userspace metrics mismatch: %v", err)
+	}
+
+	if err := coll.SetEnabled(CollectorUserspace, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(coll, names...); n != 0 {
+		t.Errorf("expected no userspace metrics when disabled, got %d", n)
+	}
+}
+
+func TestCollector_UserspaceUnconfigured(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		userOut: "alice\t1024\tnone\n",
+	}
+
+	if n := testutil.CollectAndCount(newTestCollector(f), "zfs_dataset_user_used_bytes"); n != 0 {
+		t.Errorf("expected no userspace metrics without --dataset.userspace, got %d", n)
+	}
+}
+
+func TestCollector_CacheTTL(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+	}
+
+	var calls int
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if len(args) > 0 && args[0] == "list" && !slices.Contains(args, "-v") && strings.HasSuffix(name, "zpool") {
+			calls++
+		}
+
+		return f.run(ctx, name, args...)
+	}
+
+	client := zfs.NewClient(runner, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(runner, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, CacheTTL: time.Hour})
+
+	testutil.CollectAndCount(coll)
+	testutil.CollectAndCount(coll)
+
+	if calls != 1 {
+		t.Errorf("expected 1 zpool list within TTL, got %d", calls)
+	}
+
+	// Changing the enabled set bypasses the cache.
+	if err := coll.SetEnabled(CollectorScan, false); err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.CollectAndCount(coll)
+
+	if calls != 2 {
+		t.Errorf("expected refetch after toggle, got %d calls", calls)
+	}
+}
+
+func TestCollector_CacheSkipsFailures(t *testing.T) {
+	f := &fixtureRunner{poolErr: errors.New("zpool exploded")}
+
+	var calls int
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if len(args) > 0 && args[0] == "list" && !slices.Contains(args, "-v") && strings.HasSuffix(name, "zpool") {
+			calls++
+		}
+
+		return f.run(ctx, name, args...)
+	}
+
+	client := zfs.NewClient(runner, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(runner, testLogger()), testLogger(),
+		&Options{Timeout: time.Second, CacheTTL: time.Hour})
+
+	testutil.CollectAndCount(coll)
+	testutil.CollectAndCount(coll)
+
+	if calls != 2 {
+		t.Errorf("failed scrapes must not be cached, got %d zpool list calls", calls)
+	}
+}
+
+func TestCollector_ConcurrentScrapesShareFetch(t *testing.T) {
+	// The bubble lets the test wait until the second scrape is blocked,
+	// either joining the first fetch or stuck in its own zpool list.
+	synctest.Test(t, func(t *testing.T) {
+		f := &fixtureRunner{
+			poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		}
+
+		var calls atomic.Int32
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if len(args) > 0 && args[0] == "list" && !slices.Contains(args, "-v") && strings.HasSuffix(name, "zpool") {
+				if calls.Add(1) == 1 {
+					close(started)
+				}
+
+				<-release
+			}
+
+			return f.run(ctx, name, args...)
+		}
+
+		client := zfs.NewClient(runner, testLogger(), "zpool", "zfs")
+		coll := NewCollector(client, host.NewServiceChecker(runner, testLogger()), testLogger(), &Options{Timeout: 5 * time.Second})
+
+		counts := make([]int, 2)
+
+		var wg sync.WaitGroup
+
+		wg.Go(func() { counts[0] = testutil.CollectAndCount(coll, "zfs_pool_size_bytes") })
+		<-started
+		wg.Go(func() { counts[1] = testutil.CollectAndCount(coll, "zfs_pool_size_bytes") })
+
+		// Hold the first fetch until the second scrape has joined it or
+		// started its own.
+		synctest.Wait()
+
+		if n := calls.Load(); n != 1 {
+			t.Errorf("expected 1 zpool list for concurrent scrapes, got %d", n)
+		}
+
+		close(release)
+		wg.Wait()
+
+		if counts[0] != 1 || counts[1] != 1 {
+			t.Errorf("expected both scrapes to emit the pool, got %v", counts)
+		}
+
+		// Once the fetch finished, the next scrape runs its own.
+		testutil.CollectAndCount(coll)
+
+		if n := calls.Load(); n != 2 {
+			t.Errorf("expected a fresh fetch after the shared one finished, got %d zpool list calls", n)
+		}
+	})
+}
+
+func TestCollector_DatasetFilter(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"rpool\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n" +
+			"tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n" +
+			"tank/docker/abc123\t1024\t5368709120\t1024\tfilesystem\toff\toff\t1.00\t1024\t1024\n" +
+			"rpool/ROOT/ubuntu\t1024\t5368709120\t1024\tfilesystem\toff\toff\t1.00\t1024\t1024\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:        time.Second,
+		DatasetInclude: regexp.MustCompile(`^(?:tank/.*)$`),
+		DatasetExclude: regexp.MustCompile(`^(?:tank/docker/.*)$`),
+	})
+
+	expected := `
+		# HELP zfs_dataset_used_bytes Space consumed by dataset.
+		# TYPE zfs_dataset_used_bytes gauge
+		zfs_dataset_used_bytes{dataset="tank/media",pool="tank",type="filesystem"} 4.294967296e+09
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_dataset_used_bytes"); err != nil {
+		t.Errorf("filtered dataset metrics mismatch: %v", err)
+	}
+
+	// Pool-level compressratio comes from the root dataset even when the
+	// root is filtered out of dataset metrics.
+	if n := testutil.CollectAndCount(coll, "zfs_pool_compressratio"); n != 1 {
+		t.Errorf("expected 1 pool compressratio series, got %d", n)
+	}
+}
+
+func TestCollector_DatasetMaxSeries(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n" +
+			"tank/docker/a\t1024\t5368709120\t1024\tfilesystem\toff\toff\t1.00\t1024\t1024\n" +
+			"tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n" +
+			"tank/docker/b\t2048\t5368709120\t2048\tfilesystem\toff\toff\t1.00\t2048\t2048\n",
+		snapOut: "tank/docker/a@1\t0\n" +
+			"tank/docker/a@2\t0\n" +
+			"tank/media@1\t0\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:          time.Second,
+		DatasetMaxSeries: 3,
+		Enabled:          map[string]bool{CollectorDatasetHistogram: true},
+	})
+
+	expected := `
+		# HELP zfs_dataset_series_truncated 1 if per-dataset metrics were limited by --dataset.max-series, 0 otherwise.
+		# TYPE zfs_dataset_series_truncated gauge
+		zfs_dataset_series_truncated 1
+		# HELP zfs_dataset_used_bytes Space consumed by dataset.
+		# TYPE zfs_dataset_used_bytes gauge
+		zfs_dataset_used_bytes{dataset="tank",pool="tank",type="filesystem"} 5.36870912e+09
+		zfs_dataset_used_bytes{dataset="tank/docker/b",pool="tank",type="filesystem"} 2048
+		zfs_dataset_used_bytes{dataset="tank/media",pool="tank",type="filesystem"} 4.294967296e+09
+		# HELP zfs_dataset_snapshot_count Number of snapshots of the dataset (not including its descendants).
+		# TYPE zfs_dataset_snapshot_count gauge
+		zfs_dataset_snapshot_count{dataset="tank/media",pool="tank"} 1
+	`
+
+	// Snapshot counts follow the datasets that kept their series.
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_dataset_series_truncated", "zfs_dataset_used_bytes", "zfs_dataset_snapshot_count"); err != nil {
+		t.Errorf("limited dataset metrics mismatch: %v", err)
+	}
+
+	// The histogram still counts every dataset.
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(coll)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	for _, mf := range families {
+		if mf.GetName() == "zfs_dataset_used_bytes_histogram" && mf.GetMetric()[0].GetHistogram().GetSampleCount() != 4 {
+			t.Errorf("histogram sample count = %d, want 4", mf.GetMetric()[0].GetHistogram().GetSampleCount())
+		}
+	}
+}
+
+func TestNameFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter nameFilter
+		in     string
+		want   bool
+	}{
+		{name: "no filter", in: "tank/a", want: true},
+		{name: "include match", filter: nameFilter{include: regexp.MustCompile(`^(?:tank/.*)$`)}, in: "tank/a", want: true},
+		{name: "include miss", filter: nameFilter{include: regexp.MustCompile(`^(?:tank/.*)$`)}, in: "rpool/a", want: false},
+		{name: "exclude match", filter: nameFilter{exclude: regexp.MustCompile(`^(?:.*/docker/.*)$`)}, in: "tank/docker/x", want: false},
+		{
+			name: "exclude wins over include",
+			filter: nameFilter{
+				include: regexp.MustCompile(`^(?:tank/.*)$`),
+				exclude: regexp.MustCompile(`^(?:tank/tmp)$`),
+			},
+			in:   "tank/tmp",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.match(tt.in); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollector_PoolFilter(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"usb\t1073741824\t536870912\t536870912\t5\t1.00\tDEGRADED\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n" +
+			"usb\t536870912\t536870912\t262144\tfilesystem\toff\toff\t1.00\t536870912\t262144\n" +
+			"usb/backup\t536870912\t536870912\t536870912\tfilesystem\toff\toff\t1.00\t536870912\t536870912\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+  scan: none requested
+
+  pool: usb
+ state: DEGRADED
+  scan: resilver in progress since Mon Feb  3 10:00:00 2025
+    500M resilvered, 10.00% done, 0 days 01:30:00 to go
+`,
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:     time.Second,
+		PoolExclude: regexp.MustCompile(`^(?:usb)$`),
+	})
+
+	expected := `
+		# HELP zfs_pool_resilver_active 1 if a resilver (rebuild) is in progress, 0 otherwise.
+		# TYPE zfs_pool_resilver_active gauge
+		zfs_pool_resilver_active{pool="tank"} 0
+		# HELP zfs_pool_size_bytes Total pool size in bytes.
+		# TYPE zfs_pool_size_bytes gauge
+		zfs_pool_size_bytes{pool="tank"} 1.073741824e+10
+		# HELP zfs_pools_total Number of collected pools.
+		# TYPE zfs_pools_total gauge
+		zfs_pools_total 1
+		# HELP zfs_pools_unhealthy Number of collected pools whose health is not ONLINE.
+		# TYPE zfs_pools_unhealthy gauge
+		zfs_pools_unhealthy 0
+		# HELP zfs_dataset_used_bytes Space consumed by dataset.
+		# TYPE zfs_dataset_used_bytes gauge
+		zfs_dataset_used_bytes{dataset="tank",pool="tank",type="filesystem"} 5.36870912e+09
+	`
+
+	err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_pool_resilver_active", "zfs_pool_size_bytes", "zfs_pools_total", "zfs_pools_unhealthy", "zfs_dataset_used_bytes")
+	if err != nil {
+		t.Errorf("pool filter mismatch: %v", err)
+	}
+}
+
+func TestCollector_DatasetProperties(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut:    "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		datasetOut: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n",
+		propOut: "tank\trecordsize\t131072\n" +
+			"tank\tcompression\tlz4\n",
+	}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:           time.Second,
+		DatasetProperties: []string{"recordsize", "compression"},
+	})
+
+	expected := `
+		# HELP zfs_dataset_property Numeric value of a ZFS property requested with --dataset.properties.
+		# TYPE zfs_dataset_property gauge
+		zfs_dataset_property{dataset="tank",pool="tank",property="recordsize",type="filesystem"} 131072
+		# HELP zfs_dataset_property_info Non-numeric value of a ZFS property requested with --dataset.properties, as a label. Always 1.
+		# TYPE zfs_dataset_property_info gauge
+		zfs_dataset_property_info{dataset="tank",pool="tank",property="compression",type="filesystem",value="lz4"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_dataset_property", "zfs_dataset_property_info"); err != nil {
+		t.Errorf("dataset property metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_KstatBackend(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"tank/state": "ONLINE\n",
+		"tank/objset-0x36": "34 1 0x01 7 2160 6165792836 1634264109\n" +
+			"name                            type data\n" +
+			"dataset_name                    7    tank/home\n" +
+			"writes                          4    3\n" +
+			"nwritten                        4    12\n" +
+			"reads                           4    1\n" +
+			"nread                           4    0\n",
+		"usb/state": "DEGRADED\n",
+	}
+
+	writeTree(t, root, files)
+
+	// Any zpool/zfs call would fail the scrape.
+	f := &fixtureRunner{poolErr: errors.New("zpool must not run"), datasetErr: errors.New("zfs must not run")}
+
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Kstat:   kstat.NewReader(root),
+		Enabled: map[string]bool{CollectorHistory: true},
+	})
+
+	expected := `
+		# HELP zfs_up Whether ZFS commands succeeded.
+		# TYPE zfs_up gauge
+		zfs_up 1
+		# HELP zfs_pools_total Number of collected pools.
+		# TYPE zfs_pools_total gauge
+		zfs_pools_total 2
+		# HELP zfs_pools_unhealthy Number of collected pools whose health is not ONLINE.
+		# TYPE zfs_pools_unhealthy gauge
+		zfs_pools_unhealthy 1
+		# HELP zfs_dataset_reads_total Read operations on the dataset since pool import (kstat backend).
+		# TYPE zfs_dataset_reads_total counter
+		zfs_dataset_reads_total{dataset="tank/home",pool="tank"} 1
+		# HELP zfs_dataset_written_bytes_total Bytes written to the dataset since pool import (kstat backend).
+		# TYPE zfs_dataset_written_bytes_total counter
+		zfs_dataset_written_bytes_total{dataset="tank/home",pool="tank"} 12
+	`
+
+	err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_up", "zfs_pools_total", "zfs_pools_unhealthy", "zfs_dataset_reads_total", "zfs_dataset_written_bytes_total")
+	if err != nil {
+		t.Errorf("kstat metrics mismatch: %v", err)
+	}
+
+	// Sub-collectors that run zpool or zfs are skipped, not failed.
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(coll)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	for _, mf := range families {
+		if mf.GetName() != "zfs_scrape_collector_success" {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == CollectorHistory {
+				t.Errorf("history collector reported with the kstat backend")
+			}
+		}
+	}
+}
+
+func TestCollector_KstatMaxSeries(t *testing.T) {
+	root := t.TempDir()
+	objset := func(name string, nread int) string {
+		return "34 1 0x01 7 2160 6165792836 1634264109\n" +
+			"name                            type data\n" +
+			"dataset_name                    7    " + name + "\n" +
+			"writes                          4    0\n" +
+			"nwritten                        4    0\n" +
+			"reads                           4    1\n" +
+			"nread                           4    " + strconv.Itoa(nread) + "\n"
+	}
+
+	writeTree(t, root, map[string]string{
+		"tank/state":       "ONLINE\n",
+		"tank/objset-0x36": objset("tank/home", 4096),
+		"tank/objset-0x37": objset("tank/idle", 0),
+		"tank/objset-0x38": objset("tank/media", 8192),
+	})
+
+	f := &fixtureRunner{poolErr: errors.New("zpool must not run")}
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout:          time.Second,
+		Kstat:            kstat.NewReader(root),
+		DatasetMaxSeries: 2,
+	})
+
+	expected := `
+		# HELP zfs_dataset_series_truncated 1 if per-dataset metrics were limited by --dataset.max-series, 0 otherwise.
+		# TYPE zfs_dataset_series_truncated gauge
+		zfs_dataset_series_truncated 1
+		# HELP zfs_dataset_reads_total Read operations on the dataset since pool import (kstat backend).
+		# TYPE zfs_dataset_reads_total counter
+		zfs_dataset_reads_total{dataset="tank/home",pool="tank"} 1
+		zfs_dataset_reads_total{dataset="tank/media",pool="tank"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_dataset_series_truncated", "zfs_dataset_reads_total"); err != nil {
+		t.Errorf("limited kstat metrics mismatch: %v", err)
+	}
+}
+
+// writeTree writes files, keyed by path relative to root, creating their
+// directories.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCollector_Events(t *testing.T) {
+	streamer := func(context.Context, string, ...string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(
+			"Feb  3 2025 10:00:00.000000001\tereport.fs.zfs.checksum\n" +
+				"Feb  3 2025 10:00:01.000000000\tereport.fs.zfs.checksum\n" +
+				"Feb  3 2025 10:00:02.000000000\tsysevent.fs.zfs.scrub_start\n",
+		)), nil
+	}
+
+	watcher := zfs.NewEventWatcher(streamer, testLogger(), "zpool")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go watcher.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(watcher.Counts()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Event counters are exported even when the pool scrape fails.
+	f := &fixtureRunner{poolErr: errors.New("zpool list failed")}
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+	coll := NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Events:  watcher,
+	})
+
+	expected := `
+		# HELP zfs_pool_events_total zpool events seen since the exporter started, by event class.
+		# TYPE zfs_pool_events_total counter
+		zfs_pool_events_total{class="ereport.fs.zfs.checksum"} 2
+		zfs_pool_events_total{class="sysevent.fs.zfs.scrub_start"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_pool_events_total"); err != nil {
+		t.Errorf("event metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_LastScrub(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+  scan: scrub repaired 1.00M in 0 days 01:00:00 with 2 errors on Sun Feb  2 00:24:01 2025
+`,
+	}
+
+	coll := newTestCollector(f)
+	end := time.Date(2025, time.February, 2, 0, 24, 1, 0, time.Local).Unix()
+
+	expected := fmt.Sprintf(`
+		# HELP zfs_pool_last_scrub_timestamp_seconds Unix time the most recent scrub completed.
+		# TYPE zfs_pool_last_scrub_timestamp_seconds gauge
+		zfs_pool_last_scrub_timestamp_seconds{pool="tank"} %d
+		# HELP zfs_pool_last_scrub_duration_seconds Run time of the most recent completed scrub, excluding time paused.
+		# TYPE zfs_pool_last_scrub_duration_seconds gauge
+		zfs_pool_last_scrub_duration_seconds{pool="tank"} 3600
+		# HELP zfs_pool_last_scrub_repaired_bytes Bytes repaired by the most recent completed scrub.
+		# TYPE zfs_pool_last_scrub_repaired_bytes gauge
+		zfs_pool_last_scrub_repaired_bytes{pool="tank"} 1.048576e+06
+		# HELP zfs_pool_last_scrub_errors Unrecoverable errors found by the most recent completed scrub.
+		# TYPE zfs_pool_last_scrub_errors gauge
+		zfs_pool_last_scrub_errors{pool="tank"} 2
+	`, end)
+
+	err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_pool_last_scrub_timestamp_seconds", "zfs_pool_last_scrub_duration_seconds",
+		"zfs_pool_last_scrub_repaired_bytes", "zfs_pool_last_scrub_errors", "zfs_pool_last_resilver_timestamp_seconds")
+	if err != nil {
+		t.Errorf("last scrub metrics mismatch: %v", err)
+	}
+
+	// The age is measured at collect time.
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(coll)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	for _, mf := range families {
+		if mf.GetName() != "zfs_pool_last_scrub_age_seconds" {
+			continue
+		}
+
+		want := float64(time.Now().Unix() - end)
+		if got := mf.GetMetric()[0].GetGauge().GetValue(); math.Abs(got-want) > 60 {
+			t.Errorf("last scrub age = %v, want about %v", got, want)
+		}
+
+		return
+	}
+
+	t.Error("zfs_pool_last_scrub_age_seconds not collected")
+}
+
+func TestCollector_StatusPerPool(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n" +
+			"stuck\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+	}
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if len(args) > 0 && args[0] == "status" {
+			pool := args[len(args)-1]
+			if pool == "stuck" {
+				return nil, &zfs.CommandError{Cmd: name, ExitCode: -1, Err: context.DeadlineExceeded}
+			}
+
+			return []byte("  pool: " + pool + "\n state: ONLINE\n  scan: none requested\n"), nil
+		}
+
+		return f.run(ctx, name, args...)
+	}
+
+	client := zfs.NewClient(runner, testLogger(), "zpool", "zfs")
+	client.SetStatusConcurrency(2)
+
+	coll := NewCollector(client, host.NewServiceChecker(runner, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Enabled: map[string]bool{CollectorPoolProperties: false, CollectorSnapshots: false, CollectorServices: false, CollectorDatasets: false},
+	})
+
+	expected := `
+		# HELP zfs_pool_scrub_active 1 if a scrub is in progress, 0 otherwise.
+		# TYPE zfs_pool_scrub_active gauge
+		zfs_pool_scrub_active{pool="tank"} 0
+		# HELP zfs_scrape_collector_failure 1 for a collector whose fetch failed in this scrape, labelled with the failure reason.
+		# TYPE zfs_scrape_collector_failure gauge
+		zfs_scrape_collector_failure{collector="scan",reason="timeout"} 1
+		zfs_scrape_collector_failure{collector="vdev",reason="timeout"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_pool_scrub_active", "zfs_scrape_collector_failure"); err != nil {
+		t.Errorf("per-pool status mismatch: %v", err)
+	}
+}
+
+func TestCollector_ScanBytes(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+		statusOut: `  pool: tank
+ state: ONLINE
+  scan: scrub in progress since Sun Jul 25 16:07:49 2025
+	1.00T / 2.00T scanned at 500M/s, 512G / 2.00T issued at 400M/s
+	0B repaired, 25.00% done, 01:00:00 to go
+`,
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_pool_scan_eta_seconds Estimated seconds until the active scan completes, 0 if no scan active, paused, or not yet estimated.
+		# TYPE zfs_pool_scan_eta_seconds gauge
+		zfs_pool_scan_eta_seconds{pool="tank"} 3600
+		# HELP zfs_pool_scan_issued_bytes Bytes read and verified by the active scan, 0 if no scan active.
+		# TYPE zfs_pool_scan_issued_bytes gauge
+		zfs_pool_scan_issued_bytes{pool="tank"} 5.49755813888e+11
+		# HELP zfs_pool_scan_rate_bytes_per_second Issue rate of the active scan averaged over the current pass, 0 if unknown or no scan active.
+		# TYPE zfs_pool_scan_rate_bytes_per_second gauge
+		zfs_pool_scan_rate_bytes_per_second{pool="tank"} 4.194304e+08
+		# HELP zfs_pool_scan_total_bytes Bytes the active scan has to cover, 0 if no scan active.
+		# TYPE zfs_pool_scan_total_bytes gauge
+		zfs_pool_scan_total_bytes{pool="tank"} 2.199023255552e+12
+	`
+
+	err := testutil.CollectAndCompare(coll, strings.NewReader(expected),
+		"zfs_pool_scan_eta_seconds", "zfs_pool_scan_issued_bytes", "zfs_pool_scan_rate_bytes_per_second", "zfs_pool_scan_total_bytes")
+	if err != nil {
+		t.Errorf("scan byte metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_VdevState(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tDEGRADED\toff\n",
+		statusOut: `  pool: tank
+ state: DEGRADED
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        DEGRADED     0     0     0
+	  mirror-0  DEGRADED     0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     FAULTED      3     0     0  too many errors
+
+errors: No known data errors
+`,
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_vdev_state 1 if the device is in the labeled state, 0 otherwise.
+		# TYPE zfs_vdev_state gauge
+		zfs_vdev_state{device="sda",pool="tank",state="degraded",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sda",pool="tank",state="faulted",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sda",pool="tank",state="offline",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sda",pool="tank",state="online",vdev="mirror-0"} 1
+		zfs_vdev_state{device="sda",pool="tank",state="removed",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sda",pool="tank",state="unavail",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sdb",pool="tank",state="degraded",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sdb",pool="tank",state="faulted",vdev="mirror-0"} 1
+		zfs_vdev_state{device="sdb",pool="tank",state="offline",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sdb",pool="tank",state="online",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sdb",pool="tank",state="removed",vdev="mirror-0"} 0
+		zfs_vdev_state{device="sdb",pool="tank",state="unavail",vdev="mirror-0"} 0
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_vdev_state"); err != nil {
+		t.Errorf("vdev state mismatch: %v", err)
+	}
+}
+
+func TestCollector_VdevCounts(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "backup\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n" +
+			"tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tDEGRADED\toff\n",
+		statusOut: `  pool: backup
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	backup      ONLINE       0     0     0
+	  sdx       ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: tank
+ state: DEGRADED
+config:
+
+	NAME          STATE     READ WRITE CKSUM
+	tank          DEGRADED     0     0     0
+	  mirror-0    DEGRADED     0     0     0
+	    sda       ONLINE       0     0     0
+	    spare-1   DEGRADED     0     0     0
+	      sdb     FAULTED      0    12     0  too many errors
+	      sdd     ONLINE       0     0     0
+	logs
+	  mirror-1    ONLINE       0     0     0
+	    nvme0n1   ONLINE       0     0     0
+	    nvme1n1   ONLINE       0     0     0
+	cache
+	  nvme2n1     ONLINE       0     0     0
+	spares
+	  sdd         INUSE     currently in use
+	  sde         AVAIL
+
+errors: No known data errors
+`,
+	}
+
+	coll := newTestCollector(f)
+
+	expected := `
+		# HELP zfs_pool_cache_count Number of cache (L2ARC) devices in the pool.
+		# TYPE zfs_pool_cache_count gauge
+		zfs_pool_cache_count{pool="backup"} 0
+		zfs_pool_cache_count{pool="tank"} 1
+		# HELP zfs_pool_log_count Number of log (SLOG) devices in the pool.
+		# TYPE zfs_pool_log_count gauge
+		zfs_pool_log_count{pool="backup"} 0
+		zfs_pool_log_count{pool="tank"} 2
+		# HELP zfs_pool_spare_count Number of hot spares configured for the pool.
+		# TYPE zfs_pool_spare_count gauge
+		zfs_pool_spare_count{pool="backup"} 0
+		zfs_pool_spare_count{pool="tank"} 2
+		# HELP zfs_pool_spare_in_use Number of hot spares currently replacing a failed device.
+		# TYPE zfs_pool_spare_in_use gauge
+		zfs_pool_spare_in_use{pool="backup"} 0
+		zfs_pool_spare_in_use{pool="tank"} 1
+	`
+
+	names := []string{"zfs_pool_spare_count", "zfs_pool_spare_in_use", "zfs_pool_cache_count", "zfs_pool_log_count"}
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), names...); err != nil {
+		t.Errorf("vdev count mismatch: %v", err)
+	}
+
+	// Spares are counted but get no error or state series of their own.
+	if n := testutil.CollectAndCount(coll, "zfs_vdev_read_errors"); n != 7 {
+		t.Errorf("expected 7 vdev read error series, got %d", n)
+	}
+}
+
+func TestCollector_PoolTopology(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "backup\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n" +
+			"fast\t536870912\t268435456\t268435456\t0\t1.00\tONLINE\toff\n" +
+			"tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tDEGRADED\toff\n",
+		statusOut: `  pool: backup
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	backup      ONLINE       0     0     0
+	  sdx       ONLINE       0     0     0
+	  sdy       ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: fast
+ state: ONLINE
+config:
+
+	NAME          STATE     READ WRITE CKSUM
+	fast          ONLINE       0     0     0
+	  mirror-0    ONLINE       0     0     0
+	    nvme0n1   ONLINE       0     0     0
+	    nvme1n1   ONLINE       0     0     0
+	  mirror-1    ONLINE       0     0     0
+	    nvme2n1   ONLINE       0     0     0
+	    nvme3n1   ONLINE       0     0     0
+	    nvme4n1   ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: tank
+ state: DEGRADED
+  scan: resilver in progress since Mon Feb  3 10:00:00 2025
+config:
+
+	NAME              STATE     READ WRITE CKSUM
+	tank              DEGRADED     0     0     0
+	  raidz2-0        DEGRADED     0     0     0
+	    sda           ONLINE       0     0     0
+	    replacing-1   DEGRADED     0     0     0
+	      sdb         UNAVAIL      0     0     0
+	      sdg         ONLINE       0     0     0  (resilvering)
+	    sdc           ONLINE       0     0     0
+	    sdd           ONLINE       0     0     0
+	  raidz2-1        ONLINE       0     0     0
+	    sde           ONLINE       0     0     0
+	    sdf           ONLINE       0     0     0
+	    sdh           ONLINE       0     0     0
+	    sdi           ONLINE       0     0     0
+	logs
+	  mirror-2        ONLINE       0     0     0
+	    nvme5n1       ONLINE       0     0     0
+	    nvme6n1       ONLINE       0     0     0
+	spares
+	  sdj             AVAIL
+
+errors: No known data errors
+`,
+	}
+
+	coll := newTestCollector(f)
+
+	// The disk being replaced counts once, and the log mirror and spare not
+	// at all.
+	expected := `
+		# HELP zfs_pool_topology_info Redundancy layout of the pool's data vdevs (mirror, raidz1-3, draid1-3, stripe, or mixed), with their number and disks per vdev.
+		# TYPE zfs_pool_topology_info gauge
+		zfs_pool_topology_info{disks_per_vdev="1",layout="stripe",pool="backup",vdev_count="2"} 1
+		zfs_pool_topology_info{disks_per_vdev="mixed",layout="mirror",pool="fast",vdev_count="2"} 1
+		zfs_pool_topology_info{disks_per_vdev="4",layout="raidz2",pool="tank",vdev_count="2"} 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_pool_topology_info"); err != nil {
+		t.Errorf("topology mismatch: %v", err)
+	}
+}
+
+func TestVdevLayout(t *testing.T) {
+	tests := []struct {
+		vdev string
+		want string
+	}{
+		{vdev: "mirror-0", want: "mirror"},
+		{vdev: "raidz-1", want: "raidz1"},
+		{vdev: "raidz3-2", want: "raidz3"},
+		{vdev: "draid2:4d:12c:1s-0", want: "draid2"},
+		{vdev: "sda", want: "stripe"},
+		{vdev: "wwn-0x5000c500a1b2c3d4", want: "stripe"},
+		{vdev: "spare-0", want: "stripe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vdev, func(t *testing.T) {
+			if got := vdevLayout(tt.vdev); got != tt.want {
+				t.Errorf("vdevLayout(%q) = %q, want %q", tt.vdev, got, tt.want)
+			}
+		})
+	}
+}
+
+// newKstatCollector returns a collector whose kstat reader is rooted at a
+// temporary directory holding files, keyed by path relative to the root.
+// The SPL slab table sits at ../../kmem/slab. opts.Stats is set and
+// opts.Timeout defaults to a second.
+func newKstatCollector(t *testing.T, files map[string]string, opts *Options) *Collector {
+	t.Helper()
+
+	root := filepath.Join(t.TempDir(), "kstat", "zfs")
+	writeTree(t, root, files)
+
+	opts.Stats = kstat.NewReader(root)
+	opts.Timeout = cmp.Or(opts.Timeout, time.Second)
+
+	f := &fixtureRunner{poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n"}
+	client := zfs.NewClient(f.run, testLogger(), "zpool", "zfs")
+
+	return NewCollector(client, host.NewServiceChecker(f.run, testLogger()), testLogger(), opts)
+}
+
+func TestCollector_Kstat(t *testing.T) {
+	tests := []struct {
+		name      string
+		collector string
+		files     map[string]string
+		metrics   []string
+		want      string
+
+		// gather compares through a non-pedantic registry, for collectors
+		// whose descriptors are built at collection time.
+		gather bool
+	}{
+		{
+			name:      "l2arc",
+			collector: CollectorL2ARC,
+			files: map[string]string{
+				"arcstats": "13 1 0x01 147 39984 6165792836 1634264109\n" +
+					"name                            type data\n" +
+					"l2_hits                         4    900\n" +
+					"l2_misses                       4    100\n" +
+					"l2_feeds                        4    5000\n" +
+					"l2_size                         4    4294967296\n",
+			},
+			metrics: []string{"zfs_l2arc_hits_total", "zfs_l2arc_misses_total", "zfs_l2arc_size_bytes"},
+			want: `
+				# HELP zfs_l2arc_hits_total ARC misses served from the L2ARC.
+				# TYPE zfs_l2arc_hits_total counter
+				zfs_l2arc_hits_total 900
+				# HELP zfs_l2arc_misses_total ARC misses not found in the L2ARC.
+				# TYPE zfs_l2arc_misses_total counter
+				zfs_l2arc_misses_total 100
+				# HELP zfs_l2arc_size_bytes Uncompressed size of data cached in the L2ARC.
+				# TYPE zfs_l2arc_size_bytes gauge
+				zfs_l2arc_size_bytes 4.294967296e+09
+			`,
+		},
+		{
+			name:      "l2arc without cache device",
+			collector: CollectorL2ARC,
+			files: map[string]string{
+				"arcstats": "13 1 0x01 147 39984 6165792836 1634264109\n" +
+					"name                            type data\n" +
+					"l2_hits                         4    0\n" +
+					"l2_feeds                        4    0\n" +
+					"l2_size                         4    0\n",
+			},
+			metrics: []string{"zfs_l2arc_hits_total", "zfs_l2arc_misses_total", "zfs_l2arc_size_bytes"},
+		},
+		{
+			name:      "zil",
+			collector: CollectorZIL,
+			files: map[string]string{
+				"zil": "12 1 0x01 13 3536 6165792836 1634264109\n" +
+					"name                            type data\n" +
+					"zil_commit_count                4    120\n" +
+					"zil_itx_metaslab_slog_count     4    80\n" +
+					"zil_itx_metaslab_slog_bytes     4    327680\n",
+			},
+			metrics: []string{"zfs_zil_commits_total", "zfs_zil_itx_slog_bytes_total", "zfs_zil_itx_normal_total"},
+			want: `
+				# HELP zfs_zil_commits_total ZIL commits (fsync, O_SYNC writes, sync=always).
+				# TYPE zfs_zil_commits_total counter
+				zfs_zil_commits_total 120
+				# HELP zfs_zil_itx_slog_bytes_total Bytes of log blocks written to separate log (SLOG) devices.
+				# TYPE zfs_zil_itx_slog_bytes_total counter
+				zfs_zil_itx_slog_bytes_total 327680
+			`,
+		},
+		{
+			// OpenZFS 2.1 has no future/stride/past or io_* keys; they are omitted.
+			name:      "zfetch",
+			collector: CollectorZfetch,
+			files: map[string]string{
+				"zfetchstats": "9 1 0x01 3 144 6165792836 1634264109\n" +
+					"name                            type data\n" +
+					"hits                            4    9000\n" +
+					"misses                          4    1000\n" +
+					"max_streams                     4    25\n",
+			},
+			metrics: []string{"zfs_zfetch_hits_total", "zfs_zfetch_misses_total", "zfs_zfetch_max_streams_total", "zfs_zfetch_io_active"},
+			want: `
+				# HELP zfs_zfetch_hits_total Reads that continued a prefetch stream.
+				# TYPE zfs_zfetch_hits_total counter
+				zfs_zfetch_hits_total 9000
+				# HELP zfs_zfetch_max_streams_total Misses that could not start a stream because the per-file stream limit was reached.
+				# TYPE zfs_zfetch_max_streams_total counter
+				zfs_zfetch_max_streams_total 25
+				# HELP zfs_zfetch_misses_total Reads that matched no prefetch stream.
+				# TYPE zfs_zfetch_misses_total counter
+				zfs_zfetch_misses_total 1000
+			`,
+		},
+		{
+			// Keys the module does not report (dmu_tx_wrlog_delay before 2.2) are omitted.
+			name:      "dmu_tx",
+			collector: CollectorDmuTx,
+			files: map[string]string{
+				"dmu_tx": "6 1 0x01 13 3536 6165792836 1634264109\n" +
+					"name                            type data\n" +
+					"dmu_tx_assigned                 4    8472911\n" +
+					"dmu_tx_delay                    4    0\n" +
+					"dmu_tx_error                    4    12\n" +
+					"dmu_tx_dirty_delay              4    4051\n",
+			},
+			metrics: []string{"zfs_dmu_tx_assigned_total", "zfs_dmu_tx_dirty_delay_total", "zfs_dmu_tx_errors_total", "zfs_dmu_tx_wrlog_delay_total"},
+			want: `
+				# HELP zfs_dmu_tx_assigned_total Transactions assigned to a txg.
+				# TYPE zfs_dmu_tx_assigned_total counter
+				zfs_dmu_tx_assigned_total 8.472911e+06
+				# HELP zfs_dmu_tx_dirty_delay_total Transaction assignments delayed by the write throttle.
+				# TYPE zfs_dmu_tx_dirty_delay_total counter
+				zfs_dmu_tx_dirty_delay_total 4051
+				# HELP zfs_dmu_tx_errors_total Transaction assignments that failed with an error.
+				# TYPE zfs_dmu_tx_errors_total counter
+				zfs_dmu_tx_errors_total 12
+			`,
+		},
+		{
+			name:      "txg",
+			collector: CollectorTxg,
+			files: map[string]string{
+				"tank/state": "ONLINE\n",
+				"tank/txgs": "18 0 0x01 100 11200 6165792836 1634264109\n" +
+					"txg      birth            state ndirty       nread        nwritten     reads    writes   otime        qtime        wtime        stime\n" +
+					"4311     6165795012       C     1261568      0            5242880      0        48       5000143012   21504        35840        2500000000\n" +
+					"4312     6170795155       S     0            0            0            0        0        5000106496   18432        30720        0\n",
+			},
+			metrics: []string{"zfs_pool_txg_dirty_bytes", "zfs_pool_txg_sync_seconds", "zfs_pool_txgs_total"},
+			want: `
+				# HELP zfs_pool_txg_dirty_bytes Dirty data carried by the last synced transaction group.
+				# TYPE zfs_pool_txg_dirty_bytes gauge
+				zfs_pool_txg_dirty_bytes{pool="tank"} 1.261568e+06
+				# HELP zfs_pool_txg_sync_seconds Time the last synced transaction group spent syncing.
+				# TYPE zfs_pool_txg_sync_seconds gauge
+				zfs_pool_txg_sync_seconds{pool="tank"} 2.5
+				# HELP zfs_pool_txgs_total Transaction groups synced over the pool's life (the last synced txg number).
+				# TYPE zfs_pool_txgs_total counter
+				zfs_pool_txgs_total{pool="tank"} 4311
+			`,
+		},
+		{
+			// zio_cache is backed by a Linux slab cache, so its size is not exported.
+			name:      "spl",
+			collector: CollectorSPL,
+			files: map[string]string{
+				"../../kmem/slab": "--------------------- cache ----------  ----- slab ------  ---- object -----  --- emergency ---\n" +
+					"name               flags      size     alloc slabsize  objsize  total alloc   max  total alloc   max  dlock alloc   max\n" +
+					"zio_cache        0x00040         -    940800        -     1200      -     -     -      -   784     -      -     -     -\n" +
+					"zfs_znode_cache  0x00000    163840    126720    32768      960      5     4     5    165   132   160      0     0     0\n",
+			},
+			metrics: []string{"zfs_spl_kmem_cache_alloc_bytes", "zfs_spl_kmem_cache_objects", "zfs_spl_kmem_cache_size_bytes"},
+			want: `
+				# HELP zfs_spl_kmem_cache_alloc_bytes Memory allocated to objects in an SPL kmem cache.
+				# TYPE zfs_spl_kmem_cache_alloc_bytes gauge
+				zfs_spl_kmem_cache_alloc_bytes{cache="zfs_znode_cache"} 126720
+				zfs_spl_kmem_cache_alloc_bytes{cache="zio_cache"} 940800
+				# HELP zfs_spl_kmem_cache_objects Objects allocated from an SPL kmem cache.
+				# TYPE zfs_spl_kmem_cache_objects gauge
+				zfs_spl_kmem_cache_objects{cache="zfs_znode_cache"} 132
+				zfs_spl_kmem_cache_objects{cache="zio_cache"} 784
+				# HELP zfs_spl_kmem_cache_size_bytes Memory held by an SPL kmem cache's slabs. Absent for caches backed by a Linux slab cache, which /proc/slabinfo accounts.
+				# TYPE zfs_spl_kmem_cache_size_bytes gauge
+				zfs_spl_kmem_cache_size_bytes{cache="zfs_znode_cache"} 163840
+			`,
+		},
+		{
+			name:      "node_compat",
+			collector: CollectorNodeCompat,
+			files: map[string]string{
+				"arcstats": "13 1 0x01 4 1088 6165792836 1634264109\n" +
+					"name                            type data\n" +
+					"hits                            4    1000\n" +
+					"size                            4    4096\n",
+				"tank/state": "ONLINE\n",
+				"tank/objset-0x36": "34 1 0x01 7 2160 6165792836 1634264109\n" +
+					"name                            type data\n" +
+					"dataset_name                    7    tank/home\n" +
+					"writes                          4    3\n" +
+					"nwritten                        4    12\n" +
+					"reads                           4    1\n" +
+					"nread                           4    0\n",
+			},
+			metrics: []string{"node_zfs_arc_hits", "node_zfs_arc_size", "node_zfs_zpool_dataset_nwritten", "node_zfs_zpool_state"},
+			want: `
+				# HELP node_zfs_arc_hits kstat.zfs.misc.arcstats.hits
+				# TYPE node_zfs_arc_hits untyped
+				node_zfs_arc_hits 1000
+				# HELP node_zfs_arc_size kstat.zfs.misc.arcstats.size
+				# TYPE node_zfs_arc_size untyped
+				node_zfs_arc_size 4096
+				# HELP node_zfs_zpool_dataset_nwritten kstat.zfs.misc.objset.nwritten
+				# TYPE node_zfs_zpool_dataset_nwritten untyped
+				node_zfs_zpool_dataset_nwritten{dataset="tank/home",zpool="tank"} 12
+				# HELP node_zfs_zpool_state kstat.zfs.misc.state
+				# TYPE node_zfs_zpool_state gauge
+				node_zfs_zpool_state{state="degraded",zpool="tank"} 0
+				node_zfs_zpool_state{state="faulted",zpool="tank"} 0
+				node_zfs_zpool_state{state="offline",zpool="tank"} 0
+				node_zfs_zpool_state{state="online",zpool="tank"} 1
+				node_zfs_zpool_state{state="removed",zpool="tank"} 0
+				node_zfs_zpool_state{state="suspended",zpool="tank"} 0
+				node_zfs_zpool_state{state="unavail",zpool="tank"} 0
+			`,
+			gather: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coll := newKstatCollector(t, tt.files, &Options{Enabled: map[string]bool{tt.collector: true}})
+
+			var err error
+			if tt.gather {
+				reg := prometheus.NewRegistry()
+				reg.MustRegister(coll)
+				err = testutil.GatherAndCompare(reg, strings.NewReader(tt.want), tt.metrics...)
+			} else {
+				err = testutil.CollectAndCompare(coll, strings.NewReader(tt.want), tt.metrics...)
+			}
+
+			if err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+
+			if err := coll.SetEnabled(tt.collector, false); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := testutil.CollectAndCount(coll, tt.metrics...); n != 0 {
+				t.Errorf("expected no metrics when disabled, got %d", n)
+			}
+		})
+	}
+}
+
+// stubClient is a ZFSClient that is not backed by zpool and zfs. Only the
+// methods the pool collector needs are implemented; the rest panic through
+// the nil embedded interface.
+type stubClient struct {
+	ZFSClient
+
+	pools []zfs.Pool
+}
+
+func (s *stubClient) Features(context.Context) zfs.Features { return zfs.Features{} }
+
+func (s *stubClient) GetPools(context.Context) ([]zfs.Pool, error) { return s.pools, nil }
+
+func TestCollector_ZFSClient(t *testing.T) {
+	client := &stubClient{pools: []zfs.Pool{{Name: "tank", Size: 1000, Allocated: 250, Free: 750, Health: "ONLINE"}}}
+
+	enabled := make(map[string]bool, len(CollectorNames))
+	for _, name := range CollectorNames {
+		enabled[name] = false
+	}
+
+	coll := NewCollector(client, nil, testLogger(), &Options{Timeout: time.Second, Enabled: enabled})
+
+	expected := `
+		# HELP zfs_pool_allocated_bytes Allocated space in bytes.
+		# TYPE zfs_pool_allocated_bytes gauge
+		zfs_pool_allocated_bytes{pool="tank"} 250
+		# HELP zfs_up Whether ZFS commands succeeded.
+		# TYPE zfs_up gauge
+		zfs_up 1
+	`
+
+	if err := testutil.CollectAndCompare(coll, strings.NewReader(expected), "zfs_pool_allocated_bytes", "zfs_up"); err != nil {
+		t.Errorf("metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_SubCollectorNames(t *testing.T) {
+	coll := newTestCollector(&fixtureRunner{})
+
+	names := make([]string, len(coll.subs))
+	for i, reg := range coll.subs {
+		names[i] = reg.name
+	}
+
+	if !slices.Equal(names, SubCollectorNames) {
+		t.Errorf("registered %v, want SubCollectorNames %v", names, SubCollectorNames)
+	}
+}
+
+func TestCollector_FetchSubs(t *testing.T) {
+	var deadlines sync.Map
+
+	sub := func(name string) *source[string] {
+		return &source[string]{
+			read: func(ctx context.Context, _ *fetchState) (string, error) {
+				deadline, _ := ctx.Deadline()
+				deadlines.Store(name, time.Until(deadline))
+
+				return name, nil
+			},
+		}
+	}
+
+	c := &Collector{subs: []registration{
+		{name: "own", sub: sub("own"), timeout: time.Minute},
+		{name: "budget", sub: sub("budget")},
+		{name: "disabled", sub: sub("disabled")},
+		{name: "unavailable", sub: sub("unavailable"), unavailable: true},
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	got := c.fetchSubs(ctx, map[string]bool{"own": true, "budget": true, "unavailable": true})
+	if len(got) != 2 || got["own"].data != "own" || got["budget"].data != "budget" {
+		t.Fatalf("fetched %v, want own and budget", got)
+	}
+
+	// The registration's timeout replaces the scrape deadline.
+	for name, want := range map[string]time.Duration{"own": time.Minute, "budget": time.Second} {
+		v, _ := deadlines.Load(name)
+		if remaining, _ := v.(time.Duration); remaining > want || remaining < want-10*time.Second {
+			t.Errorf("%s: deadline in %v, want about %v", name, remaining, want)
+		}
+	}
+}
+
+func TestCollector_FetchSubsOutlivesScrape(t *testing.T) {
+	// A fetch on its own deadline is not cancelled with the scrape; one on
+	// the scrape budget is.
+	sub := &source[string]{
+		read: func(ctx context.Context, _ *fetchState) (string, error) {
+			return "read", ctx.Err()
+		},
+	}
+
+	c := &Collector{subs: []registration{
+		{name: "own", sub: sub, timeout: time.Minute, partial: true},
+		{name: "budget", sub: sub, partial: true},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got := c.fetchSubs(ctx, map[string]bool{"own": true, "budget": true})
+	if err := got["own"].err; err != nil {
+		t.Errorf("own: unexpected error: %v", err)
+	}
+
+	if err := got["budget"].err; !errors.Is(err, context.Canceled) {
+		t.Errorf("budget: error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCommandDurations(t *testing.T) {
+	f := &fixtureRunner{
+		poolOut: "tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n",
+	}
+
+	durations := NewCommandDurations()
+	runner := zfs.TimedRunner(f.run, durations.Observe)
+	client := zfs.NewClient(runner, testLogger(), "/usr/sbin/zpool", "/usr/sbin/zfs")
+	coll := NewCollector(client, host.NewServiceChecker(runner, testLogger()), testLogger(), &Options{
+		Timeout: time.Second,
+		Enabled: map[string]bool{CollectorPoolProperties: false, CollectorDatasets: false, CollectorSnapshots: false},
+	})
+
+	testutil.CollectAndCount(coll)
+
+	// zpool version (JSON probe), zpool list, and zpool status (scan and
+	// vdev share one label).
+	if n := testutil.CollectAndCount(durations, "zfs_command_duration_seconds"); n != 3 {
+		t.Errorf("expected 3 command series, got %d", n)
+	}
+}
+
+func TestCommandLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"/usr/sbin/zpool", []string{"status", "-p"}, "zpool_status"},
+		{"zfs", []string{"list", "-Hp"}, "zfs_list"},
+		{"zfs", nil, "zfs"},
+		{"systemctl", []string{"is-active", "smbd.service"}, "systemctl"},
+		{"/etc/init.d/smbd", []string{"status"}, "smbd"},
+	}
+
+	for _, tt := range tests {
+		if got := commandLabel(tt.name, tt.args); got != tt.want {
+			t.Errorf("commandLabel(%q, %v) = %q, want %q", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CommandDurations is a histogram of external command run times, labeled by
+// command (zpool_list, zfs_get, systemctl, ...). Feed it by wrapping the
+// Runner with zfs.TimedRunner(runner, durations.Observe) and register it
+// alongside the Collector.
+type CommandDurations struct {
+	hist *prometheus.HistogramVec
+}
+
+// NewCommandDurations creates a CommandDurations with buckets from 5ms to
+// about 40s.
+func NewCommandDurations() *CommandDurations {
+	return &CommandDurations{
+		hist: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "command",
+			Name:      "duration_seconds",
+			Help:      "Wall-clock time of external commands run by the exporter.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, []string{"command"}),
+	}
+}
+
+// Observe records one command run.
+func (d *CommandDurations) Observe(name string, args []string, elapsed time.Duration) {
+	d.hist.WithLabelValues(commandLabel(name, args)).Observe(elapsed.Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (d *CommandDurations) Describe(ch chan<- *prometheus.Desc) {
+	d.hist.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (d *CommandDurations) Collect(ch chan<- prometheus.Metric) {
+	d.hist.Collect(ch)
+}
+
+// commandLabel names a command by its binary, plus the subcommand for zpool
+// and zfs ("zpool_status"). Other binaries (systemctl, init scripts, custom
+// hook commands) are labeled by binary alone to keep the label bounded.
+func commandLabel(name string, args []string) string {
+	base := filepath.Base(name)
+
+	if (base == "zpool" || base == "zfs") && len(args) > 0 {
+		return base + "_" + args[0]
+	}
+
+	return base
+}
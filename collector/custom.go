@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/custom"
+)
+
+// customHook pairs a hook definition with its value descriptor.
+type customHook struct {
+	hook *custom.Hook
+	desc *prometheus.Desc
+}
+
+// customResult is the outcome of running one hook.
+type customResult struct {
+	Hook     string          `json:"hook"`
+	Samples  []custom.Sample `json:"samples,omitempty"`
+	Duration time.Duration   `json:"duration"`
+
+	err error
+}
+
+// initCustomDescriptors builds one descriptor per configured hook plus the
+// shared success/duration descriptors.
+func (c *Collector) initCustomDescriptors(hooks []custom.Hook) {
+	c.customSuccess = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "custom", "success"),
+		"1 if the custom hook ran and produced valid output, 0 otherwise.",
+		[]string{"hook"},
+		nil,
+	)
+	c.customDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "custom", "duration_seconds"),
+		"Time taken to run the custom hook.",
+		[]string{"hook"},
+		nil,
+	)
+
+	for i := range hooks {
+		h := &hooks[i]
+
+		help := h.Help
+		if help == "" {
+			help = "Custom hook " + h.Name + "."
+		}
+
+		c.customHooks = append(c.customHooks, customHook{
+			hook: h,
+			desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "custom", h.Name), help, h.Labels, nil),
+		})
+	}
+}
+
+// customDescs returns the descriptors of the custom collector.
+func (c *Collector) customDescs() []*prometheus.Desc {
+	descs := []*prometheus.Desc{c.customSuccess, c.customDuration}
+	for _, h := range c.customHooks {
+		descs = append(descs, h.desc)
+	}
+
+	return descs
+}
+
+// runCustomHooks runs every hook concurrently and returns one result per
+// hook, in c.customHooks order, with the errors of the hooks that failed.
+func (c *Collector) runCustomHooks(ctx context.Context) ([]customResult, error) {
+	if len(c.customHooks) == 0 {
+		return nil, nil
+	}
+
+	results := make([]customResult, len(c.customHooks))
+
+	var wg sync.WaitGroup
+
+	for i, h := range c.customHooks {
+		wg.Go(func() {
+			start := time.Now()
+			samples, err := c.customRunner.Run(ctx, h.hook)
+			results[i] = customResult{Hook: h.hook.Name, Samples: samples, Duration: time.Since(start), err: err}
+		})
+	}
+
+	wg.Wait()
+
+	errs := make([]error, len(results))
+	for i := range results {
+		errs[i] = results[i].err
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// collectCustomMetrics emits each hook's samples plus its success and
+// duration series. A failing hook never affects zfs_up.
+func (c *Collector) collectCustomMetrics(ch chan<- prometheus.Metric, results []customResult) {
+	for i, r := range results {
+		h := c.customHooks[i]
+
+		ch <- prometheus.MustNewConstMetric(c.customDuration, prometheus.GaugeValue, r.Duration.Seconds(), h.hook.Name)
+
+		// The failure itself is logged with the collector's error.
+		if r.err != nil {
+			ch <- prometheus.MustNewConstMetric(c.customSuccess, prometheus.GaugeValue, 0, h.hook.Name)
+
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.customSuccess, prometheus.GaugeValue, 1, h.hook.Name)
+
+		for _, s := range r.Samples {
+			values := make([]string, len(h.hook.Labels))
+			for j, l := range h.hook.Labels {
+				values[j] = s.Labels[l]
+			}
+
+			m, err := prometheus.NewConstMetric(h.desc, prometheus.GaugeValue, s.Value, values...)
+			if err != nil {
+				c.logger.Warn("Custom hook produced invalid sample", "hook", h.hook.Name, "err", err)
+				continue
+			}
+
+			ch <- m
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// datasetState is what the dataset collector reads: the dataset listing
+// shared with dataset_histogram and dataset_tuning, and the user and extra
+// properties exported with it.
+type datasetState struct {
+	Datasets       []zfs.Dataset         `json:"datasets,omitempty"`
+	UserProperties zfs.UserProperties    `json:"user_properties,omitempty"`
+	Properties     zfs.DatasetProperties `json:"properties,omitempty"`
+
+	listed bool // the dataset listing succeeded
+}
+
+// fetchDatasets reads the dataset listing and, when configured, the user
+// properties and --dataset.properties concurrently. The datasets are
+// exported without their properties when only a property read failed.
+func (c *Collector) fetchDatasets(ctx context.Context, f *fetchState) (datasetState, error) {
+	var (
+		st                        datasetState
+		listErr, userErr, propErr error
+		wg                        sync.WaitGroup
+	)
+
+	wg.Go(func() { st.Datasets, listErr = f.datasets.get(ctx) })
+
+	if c.userPropPrefix != "" {
+		wg.Go(func() { st.UserProperties, userErr = c.client.GetUserProperties(ctx, c.userPropPrefix) })
+	}
+
+	if len(c.datasetProps) > 0 {
+		wg.Go(func() { st.Properties, propErr = c.client.GetDatasetProperties(ctx, c.datasetProps) })
+	}
+
+	wg.Wait()
+
+	st.listed = listErr == nil
+
+	return st, errors.Join(listErr, userErr, propErr)
+}
+
+// collectDatasets emits the dataset, threshold, and property metrics of the
+// datasets left by --dataset.max-series, and the pool compression ratios.
+func (c *Collector) collectDatasets(ch chan<- prometheus.Metric, st datasetState, s *collectState) {
+	if !st.listed {
+		return
+	}
+
+	datasets := c.limitedDatasets(s)
+
+	c.collectDatasetMetrics(ch, datasets, st.UserProperties)
+	c.collectThresholdMetrics(ch, datasets, st.UserProperties)
+	c.collectPropertyMetrics(ch, datasets, st.Properties)
+	c.collectPoolCompressRatio(ch, c.filterDatasets(st.Datasets, true))
+}
+
+// limitedDatasets returns the datasets whose per-dataset series are
+// exported: those that pass the filters, capped by --dataset.max-series. It
+// is worked out once per Collect from the listing of the dataset or
+// dataset_tuning collector, and nil if neither listed the datasets. The
+// snapshot series follow the same limit (datasetAllowlist), and
+// zfs_dataset_series_truncated covers all of them.
+func (c *Collector) limitedDatasets(s *collectState) []zfs.Dataset {
+	if s.limitDone {
+		return s.limited
+	}
+
+	s.limitDone = true
+
+	var listing []zfs.Dataset
+
+	if st, ok := result[datasetState](s, CollectorDatasets); ok && st.listed {
+		listing = st.Datasets
+	} else if st, ok := result[tuningState](s, CollectorDatasetTuning); ok {
+		listing = st.Datasets
+	} else {
+		return nil
+	}
+
+	limited, cut := c.limitDatasets(c.filterDatasets(listing, false))
+	if cut {
+		s.limitAllowed = datasetNames(limited)
+	}
+
+	s.limited = limited
+	s.limitTruncated = s.limitTruncated || cut
+	s.limitReported = true
+
+	return limited
+}
+
+// datasetAllowlist returns the datasets left by limitedDatasets, or nil
+// when none were dropped or no per-dataset series were listed.
+func (c *Collector) datasetAllowlist(s *collectState) map[string]bool {
+	c.limitedDatasets(s)
+
+	return s.limitAllowed
+}
+
+// collectDatasetMetrics emits per-dataset metrics. When user properties were
+// fetched, each matching property becomes an extra label on every series.
+// User properties are set by dataset owners, so a series that cannot be
+// built is logged and left out rather than failing the scrape.
+func (c *Collector) collectDatasetMetrics(ch chan<- prometheus.Metric, datasets []zfs.Dataset, props zfs.UserProperties) {
+	ul := newUserLabels(props, c.userPropPrefix)
+	descs := c.datasetDescsFor(ul.names)
+
+	var emitErr error
+
+	emit := func(desc *prometheus.Desc, value float64, labels []string) {
+		m, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+		if err != nil {
+			emitErr = err
+			return
+		}
+
+		ch <- m
+	}
+
+	for _, d := range datasets {
+		labels := append([]string{d.Name, d.Type, d.Pool}, ul.values(props, d.Name)...)
+
+		emit(descs.used, float64(d.Used), labels)
+		emit(descs.available, float64(d.Available), labels)
+		emit(descs.referenced, float64(d.Referenced), labels)
+
+		nfs := 0.0
+		if d.ShareNFS {
+			nfs = 1.0
+		}
+
+		smb := 0.0
+		if d.ShareSMB {
+			smb = 1.0
+		}
+
+		emit(descs.shareNFS, nfs, labels)
+		emit(descs.shareSMB, smb, labels)
+		emit(descs.compress, d.CompressRatio, labels)
+
+		if d.NoLogicalSpace {
+			continue
+		}
+
+		emit(descs.logicalUsed, float64(d.LogicalUsed), labels)
+		emit(descs.logicalReferenced, float64(d.LogicalReferenced), labels)
+	}
+
+	if emitErr != nil {
+		c.logger.Warn("Failed to build dataset metrics", "labels", ul.names, "err", emitErr)
+	}
+}
+
+// collectPoolCompressRatio emits the pool-wide compression ratio. A root
+// dataset's compressratio already covers all its descendants, so datasets
+// must not be filtered by dataset name for the root to be present.
+func (c *Collector) collectPoolCompressRatio(ch chan<- prometheus.Metric, datasets []zfs.Dataset) {
+	for _, d := range datasets {
+		if d.Name == d.Pool {
+			ch <- prometheus.MustNewConstMetric(c.poolCompressRatio, prometheus.GaugeValue, d.CompressRatio, d.Pool)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"maps"
+	"time"
+)
+
+// DebugState is the parsed data behind the most recent scrape, for
+// inspecting what the parsers produced when a metric looks wrong.
+// Sub-collectors that were not fetched are absent.
+type DebugState struct {
+	Fetched time.Time       `json:"fetched"`
+	Enabled map[string]bool `json:"enabled"`
+
+	// Collectors holds the data of each fetched sub-collector (pool,
+	// dataset, scan, vdev, services, kstat readers, ...) by collector name.
+	// A failed fetch is included when it returned partial data.
+	Collectors map[string]any `json:"collectors,omitempty"`
+
+	// Errors maps each failed sub-collector to its error message.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// DebugState returns the data behind the most recent scrape, or false if
+// nothing has been collected yet. Filters are not applied.
+func (c *Collector) DebugState() (*DebugState, bool) {
+	data := c.last.Load()
+	if data == nil {
+		return nil, false
+	}
+
+	s := &DebugState{
+		Fetched:    data.fetched,
+		Enabled:    maps.Clone(data.enabled),
+		Collectors: make(map[string]any, len(data.subs)),
+		Errors:     make(map[string]string),
+	}
+
+	for name, res := range data.subs {
+		if res.err != nil {
+			s.Errors[name] = res.err.Error()
+		}
+
+		if res.data != nil {
+			s.Collectors[name] = res.data
+		}
+	}
+
+	return s, true
+}
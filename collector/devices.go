@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// identifyVdevs returns vdevs with each device that has a /dev/disk/by-id
+// link renamed to it, so series keep their labels when sdX names are
+// reassigned at boot. A single-disk top-level vdev, named after its device,
+// is renamed with it. The identities are returned keyed by the new names.
+func (c *Collector) identifyVdevs(vdevs []zfs.VdevStatus) ([]zfs.VdevStatus, map[string]host.DeviceIdentity) {
+	names := make([]string, 0, len(vdevs))
+	for _, v := range vdevs {
+		names = append(names, v.Device)
+	}
+
+	ids := c.devices.Identify(names)
+
+	renamed := make([]zfs.VdevStatus, len(vdevs))
+	byName := make(map[string]host.DeviceIdentity, len(ids))
+
+	for i, v := range vdevs {
+		id, ok := ids[v.Device]
+		if ok && id.ByID != "" {
+			if v.Vdev == v.Device {
+				v.Vdev = id.ByID
+			}
+
+			v.Device = id.ByID
+		}
+
+		if ok {
+			byName[v.Device] = id
+		}
+
+		renamed[i] = v
+	}
+
+	return renamed, byName
+}
+
+// identifyVdevCapacities renames single-disk top-level vdevs in caps as
+// identifyVdevs does, so both carry the same vdev label.
+func (c *Collector) identifyVdevCapacities(caps []zfs.VdevCapacity) []zfs.VdevCapacity {
+	names := make([]string, 0, len(caps))
+	for _, v := range caps {
+		names = append(names, v.Vdev)
+	}
+
+	ids := c.devices.Identify(names)
+
+	renamed := make([]zfs.VdevCapacity, len(caps))
+
+	for i, v := range caps {
+		if id := ids[v.Vdev]; id.ByID != "" {
+			v.Vdev = id.ByID
+		}
+
+		renamed[i] = v
+	}
+
+	return renamed
+}
+
+// collectVdevDevices emits the identity of each device resolved by
+// identifyVdevs. Devices that are not block devices, such as file vdevs, have
+// no series.
+func (c *Collector) collectVdevDevices(ch chan<- prometheus.Metric, vdevs []zfs.VdevStatus, ids map[string]host.DeviceIdentity) {
+	for _, v := range vdevs {
+		if id, ok := ids[v.Device]; ok {
+			ch <- prometheus.MustNewConstMetric(c.vdevDevice, prometheus.GaugeValue, 1, v.Pool, v.Vdev, v.Device, id.Path, id.Serial)
+		}
+	}
+}
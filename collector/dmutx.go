@@ -0,0 +1,32 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// initDmuTxDescriptors builds the zfs_dmu_tx_* counters from the dmu_tx
+// kstat. Every write is a DMU transaction that must be assigned to the open
+// txg; the write throttle delays or blocks assignment when dirty data or the
+// ARC runs short, so growth in the delay and wait counters is throttling
+// applications feel as write latency.
+func (c *Collector) initDmuTxDescriptors() {
+	counter := func(name, key, help string) kstatMetric {
+		return newKstatMetric("dmu_tx", name, key, help, prometheus.CounterValue)
+	}
+
+	c.dmuTx = []kstatMetric{
+		counter("assigned_total", "dmu_tx_assigned", "Transactions assigned to a txg."),
+		counter("delayed_total", "dmu_tx_delay", "Transaction assignments that were delayed."),
+		counter("errors_total", "dmu_tx_error", "Transaction assignments that failed with an error."),
+		counter("suspended_total", "dmu_tx_suspended", "Transaction assignments that waited on a suspended pool."),
+		counter("group_total", "dmu_tx_group", "Transaction assignments that waited for the next txg."),
+		counter("memory_reserve_total", "dmu_tx_memory_reserve", "Transaction assignments that waited to reserve ARC memory."),
+		counter("memory_reclaim_total", "dmu_tx_memory_reclaim", "Transaction assignments that waited for the ARC to reclaim memory."),
+		counter("dirty_throttle_total", "dmu_tx_dirty_throttle", "Transaction assignments throttled because dirty data reached zfs_dirty_data_max."),
+		counter("dirty_delay_total", "dmu_tx_dirty_delay", "Transaction assignments delayed by the write throttle."),
+		counter("dirty_over_max_total", "dmu_tx_dirty_over_max", "Transaction assignments that waited for a txg sync because dirty data exceeded zfs_dirty_data_max."),
+		counter("dirty_frees_delay_total", "dmu_tx_dirty_frees_delay", "Transaction assignments delayed by a backlog of frees."),
+		counter("wrlog_delay_total", "dmu_tx_wrlog_delay", "Transaction assignments delayed because ZIL write-log data exceeded zfs_wrlog_data_max."),
+		counter("quota_total", "dmu_tx_quota", "Transaction assignments that failed on a quota."),
+	}
+}
@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// collectEventMetrics emits the per-class zpool event counters.
+func (c *Collector) collectEventMetrics(ch chan<- prometheus.Metric) {
+	counts := c.events.Counts()
+
+	for _, class := range slices.Sorted(maps.Keys(counts)) {
+		ch <- prometheus.MustNewConstMetric(c.poolEvents, prometheus.CounterValue, float64(counts[class]), class)
+	}
+}
+
+// collectHistoryMetrics emits the per-pool admin operation counters from
+// zpool history.
+func (c *Collector) collectHistoryMetrics(ch chan<- prometheus.Metric, counts []zfs.HistoryCount) {
+	for _, h := range counts {
+		ch <- prometheus.MustNewConstMetric(c.poolAdminOps, prometheus.CounterValue, float64(h.Count), h.Pool, h.Operation)
+	}
+}
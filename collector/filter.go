@@ -0,0 +1,206 @@
+package collector
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// nameFilter selects names by an include regex followed by an exclude regex.
+// A nil regex imposes no constraint.
+type nameFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// match reports whether name passes the filter.
+func (f nameFilter) match(name string) bool {
+	if f.include != nil && !f.include.MatchString(name) {
+		return false
+	}
+
+	return f.exclude == nil || !f.exclude.MatchString(name)
+}
+
+// active reports whether the filter can reject anything.
+func (f nameFilter) active() bool {
+	return f.include != nil || f.exclude != nil
+}
+
+// filterSlice returns the items for which keep is true. The input slice is
+// never modified, so cached scrape data stays intact.
+func filterSlice[T any](items []T, keep func(*T) bool) []T {
+	kept := make([]T, 0, len(items))
+
+	for i := range items {
+		if keep(&items[i]) {
+			kept = append(kept, items[i])
+		}
+	}
+
+	return kept
+}
+
+// filterPools returns the pools that pass c.poolFilter.
+func (c *Collector) filterPools(pools []zfs.Pool) []zfs.Pool {
+	if !c.poolFilter.active() {
+		return pools
+	}
+
+	return filterSlice(pools, func(p *zfs.Pool) bool { return c.poolFilter.match(p.Name) })
+}
+
+// filterDatasets returns the datasets whose pool passes c.poolFilter and,
+// unless poolOnly is set, whose name passes c.datasetFilter.
+func (c *Collector) filterDatasets(datasets []zfs.Dataset, poolOnly bool) []zfs.Dataset {
+	byName := c.datasetFilter.active() && !poolOnly
+	if !c.poolFilter.active() && !byName {
+		return datasets
+	}
+
+	return filterSlice(datasets, func(d *zfs.Dataset) bool {
+		return c.poolFilter.match(d.Pool) && (!byName || c.datasetFilter.match(d.Name))
+	})
+}
+
+// limitBy returns at most limit of items, keeping the largest by size in
+// their original order, and whether any were dropped. Zero means no limit.
+func limitBy[T any, K cmp.Ordered](items []T, limit int, size func(*T) K) ([]T, bool) {
+	if limit <= 0 || len(items) <= limit {
+		return items, false
+	}
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(size(&items[b]), size(&items[a])) })
+
+	keep := order[:limit]
+	slices.Sort(keep)
+
+	kept := make([]T, 0, limit)
+	for _, i := range keep {
+		kept = append(kept, items[i])
+	}
+
+	return kept, true
+}
+
+// limitDatasets returns at most c.maxDatasets of datasets, keeping the
+// largest by used bytes, and whether any were dropped.
+func (c *Collector) limitDatasets(datasets []zfs.Dataset) ([]zfs.Dataset, bool) {
+	return limitBy(datasets, c.maxDatasets, func(d *zfs.Dataset) uint64 { return d.Used })
+}
+
+// limitObjsets returns at most c.maxDatasets of objsets, keeping the busiest
+// by bytes read and written since mount, and whether any were dropped. kstat
+// has no used space to rank by.
+func (c *Collector) limitObjsets(objsets []kstat.Objset) ([]kstat.Objset, bool) {
+	return limitBy(objsets, c.maxDatasets, func(o *kstat.Objset) uint64 { return o.ReadBytes + o.WrittenBytes })
+}
+
+// limitSnapshotCounts returns the snapshot counts of the datasets in allowed,
+// or of all datasets when allowed is nil, capped at c.maxDatasets by snapshot
+// count, and whether any were dropped. allowed is the set of datasets left by
+// limitDatasets, so snapshot counts follow the dataset metrics when both are
+// collected.
+func (c *Collector) limitSnapshotCounts(snaps []zfs.SnapshotCount, allowed map[string]bool) ([]zfs.SnapshotCount, bool) {
+	dropped := false
+
+	if allowed != nil {
+		n := len(snaps)
+		snaps = filterSlice(snaps, func(s *zfs.SnapshotCount) bool { return allowed[s.Dataset] })
+		dropped = len(snaps) < n
+	}
+
+	snaps, cut := limitBy(snaps, c.maxDatasets, func(s *zfs.SnapshotCount) int { return s.Count })
+
+	return snaps, dropped || cut
+}
+
+// datasetNames returns the set of the datasets' names.
+func datasetNames(datasets []zfs.Dataset) map[string]bool {
+	names := make(map[string]bool, len(datasets))
+	for i := range datasets {
+		names[datasets[i].Name] = true
+	}
+
+	return names
+}
+
+// filterScans returns the scan statuses whose pool passes c.poolFilter.
+func (c *Collector) filterScans(scans []zfs.ScanStatus) []zfs.ScanStatus {
+	if !c.poolFilter.active() {
+		return scans
+	}
+
+	return filterSlice(scans, func(s *zfs.ScanStatus) bool { return c.poolFilter.match(s.Pool) })
+}
+
+// filterVdevs returns the vdev statuses whose pool passes c.poolFilter.
+func (c *Collector) filterVdevs(vdevs []zfs.VdevStatus) []zfs.VdevStatus {
+	if !c.poolFilter.active() {
+		return vdevs
+	}
+
+	return filterSlice(vdevs, func(v *zfs.VdevStatus) bool { return c.poolFilter.match(v.Pool) })
+}
+
+// filterVdevCapacities returns the vdev capacities whose pool passes
+// c.poolFilter.
+func (c *Collector) filterVdevCapacities(caps []zfs.VdevCapacity) []zfs.VdevCapacity {
+	if !c.poolFilter.active() {
+		return caps
+	}
+
+	return filterSlice(caps, func(v *zfs.VdevCapacity) bool { return c.poolFilter.match(v.Pool) })
+}
+
+// filterSnapshots returns the snapshot counts whose pool passes c.poolFilter
+// and whose dataset passes c.datasetFilter.
+func (c *Collector) filterSnapshots(snaps []zfs.SnapshotCount) []zfs.SnapshotCount {
+	if !c.poolFilter.active() && !c.datasetFilter.active() {
+		return snaps
+	}
+
+	return filterSlice(snaps, func(s *zfs.SnapshotCount) bool {
+		return c.poolFilter.match(s.Pool) && c.datasetFilter.match(s.Dataset)
+	})
+}
+
+// filterSpaceUsage returns the user and group space entries whose pool passes
+// c.poolFilter and whose dataset passes c.datasetFilter.
+func (c *Collector) filterSpaceUsage(usage []zfs.SpaceUsage) []zfs.SpaceUsage {
+	if !c.poolFilter.active() && !c.datasetFilter.active() {
+		return usage
+	}
+
+	return filterSlice(usage, func(u *zfs.SpaceUsage) bool {
+		return c.poolFilter.match(u.Pool) && c.datasetFilter.match(u.Dataset)
+	})
+}
+
+// filterHistory returns the admin operation counts whose pool passes
+// c.poolFilter.
+func (c *Collector) filterHistory(counts []zfs.HistoryCount) []zfs.HistoryCount {
+	if !c.poolFilter.active() {
+		return counts
+	}
+
+	return filterSlice(counts, func(h *zfs.HistoryCount) bool { return c.poolFilter.match(h.Pool) })
+}
+
+// filterLatency returns the latency histograms whose pool passes
+// c.poolFilter.
+func (c *Collector) filterLatency(hists []zfs.LatencyHistogram) []zfs.LatencyHistogram {
+	if !c.poolFilter.active() {
+		return hists
+	}
+
+	return filterSlice(hists, func(h *zfs.LatencyHistogram) bool { return c.poolFilter.match(h.Pool) })
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// health tracks collection liveness so the process supervisor (systemd
+// watchdog) can tell a slow exporter apart from a wedged one.
+type health struct {
+	readyOnce sync.Once
+	ready     chan struct{}
+
+	mu           sync.Mutex
+	inflight     int
+	lastStart    time.Time
+	lastFinish   time.Time
+	lastDuration time.Duration
+	lastOK       bool
+}
+
+func newHealth() *health {
+	return &health{ready: make(chan struct{})}
+}
+
+func (h *health) begin() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.inflight == 0 {
+		h.lastStart = time.Now()
+	}
+
+	h.inflight++
+}
+
+func (h *health) end(success bool, d time.Duration) {
+	h.mu.Lock()
+	h.inflight--
+	h.lastFinish = time.Now()
+	h.lastDuration = d
+	h.lastOK = success
+	h.mu.Unlock()
+
+	if success {
+		h.readyOnce.Do(func() { close(h.ready) })
+	}
+}
+
+// Ready returns a channel that is closed after the first collection in which
+// the required pool listing succeeded.
+func (c *Collector) Ready() <-chan struct{} {
+	return c.health.ready
+}
+
+// Stalled reports whether a collection has been in flight for longer than
+// threshold without any collection completing in the meantime. Collections
+// are bounded by the scrape timeout, so a stall well past it indicates a
+// stuck subprocess or deadlock rather than a slow host.
+func (c *Collector) Stalled(threshold time.Duration) bool {
+	h := c.health
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.inflight == 0 {
+		return false
+	}
+
+	now := time.Now()
+
+	return now.Sub(h.lastStart) > threshold && now.Sub(h.lastFinish) > threshold
+}
+
+// LastCollection reports when the most recent collection finished, how long
+// it took, and whether its pool listing succeeded. finished is zero before
+// the first collection completes.
+func (c *Collector) LastCollection() (finished time.Time, duration time.Duration, ok bool) {
+	h := c.health
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastFinish, h.lastDuration, h.lastOK
+}
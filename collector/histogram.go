@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// datasetUsedBuckets are the upper bounds for the dataset size histogram:
+// 1 MiB to 4 TiB in powers of four (12 buckets plus +Inf).
+var datasetUsedBuckets = prometheus.ExponentialBuckets(1<<20, 4, 12)
+
+// collectDatasetHistogram emits one classic histogram of dataset used bytes
+// per pool. It is a fixed-cardinality alternative to per-dataset series for
+// hosts with very many datasets.
+func (c *Collector) collectDatasetHistogram(ch chan<- prometheus.Metric, datasets []zfs.Dataset) {
+	type poolHist struct {
+		counts []uint64 // non-cumulative count per bucket
+		sum    float64
+		total  uint64
+	}
+
+	byPool := make(map[string]*poolHist)
+
+	for _, d := range datasets {
+		h, ok := byPool[d.Pool]
+		if !ok {
+			h = &poolHist{counts: make([]uint64, len(datasetUsedBuckets))}
+			byPool[d.Pool] = h
+		}
+
+		v := float64(d.Used)
+		h.sum += v
+		h.total++
+
+		for i, upper := range datasetUsedBuckets {
+			if v <= upper {
+				h.counts[i]++
+				break
+			}
+		}
+	}
+
+	for pool, h := range byPool {
+		buckets := make(map[float64]uint64, len(datasetUsedBuckets))
+
+		var cumulative uint64
+
+		for i, upper := range datasetUsedBuckets {
+			cumulative += h.counts[i]
+			buckets[upper] = cumulative
+		}
+
+		ch <- prometheus.MustNewConstHistogram(c.datasetUsedHistogram, h.total, h.sum, buckets, pool)
+	}
+}
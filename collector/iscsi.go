@@ -0,0 +1,31 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+)
+
+// collectISCSIMetrics emits the target, LUN, and session counts and, for
+// each LUN backed by a zvol that passes the filters, an info series tying
+// the LUN to its dataset.
+func (c *Collector) collectISCSIMetrics(ch chan<- prometheus.Metric, st host.ISCSIStatus) {
+	ch <- prometheus.MustNewConstMetric(c.iscsiTargets, prometheus.GaugeValue, float64(st.Targets))
+	ch <- prometheus.MustNewConstMetric(c.iscsiLUNs, prometheus.GaugeValue, float64(len(st.LUNs)))
+	ch <- prometheus.MustNewConstMetric(c.iscsiSessions, prometheus.GaugeValue, float64(st.Sessions))
+
+	for _, l := range st.LUNs {
+		if l.Dataset == "" {
+			continue
+		}
+
+		pool, _, _ := strings.Cut(l.Dataset, "/")
+		if !c.poolFilter.match(pool) || !c.datasetFilter.match(l.Dataset) {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.iscsiLUNInfo, prometheus.GaugeValue, 1, l.Target, l.LUN, l.Backstore, l.Dataset, pool)
+	}
+}
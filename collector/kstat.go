@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+)
+
+// fetchKstatPools reads pool state from procfs and hands the pools that
+// pass the pool filter to the other sub-collectors. A missing kstat tree
+// fails the listing, so zfs_up drops to 0.
+func (c *Collector) fetchKstatPools(_ context.Context, f *fetchState) ([]kstat.Pool, error) {
+	pools, err := c.kstat.Pools()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range pools {
+		if c.poolFilter.match(p.Name) {
+			f.pools = append(f.pools, p.Name)
+		}
+	}
+
+	return pools, nil
+}
+
+// collectKstatPools emits the pool metrics available from kstat: pool
+// health and pool counts.
+func (c *Collector) collectKstatPools(ch chan<- prometheus.Metric, pools []kstat.Pool, _ *collectState) {
+	total, unhealthy := 0, 0
+
+	for _, p := range pools {
+		if !c.poolFilter.match(p.Name) {
+			continue
+		}
+
+		total++
+
+		if !strings.EqualFold(p.State, "ONLINE") {
+			unhealthy++
+		}
+
+		c.collectPoolHealth(ch, p.Name, p.State)
+	}
+
+	c.collectPoolCounts(ch, total, unhealthy)
+}
+
+// fetchObjsets reads the per-dataset I/O counters of the listed pools. The
+// pools that were read are returned even if others failed.
+func (c *Collector) fetchObjsets(_ context.Context, f *fetchState) ([]kstat.Objset, error) {
+	var (
+		objsets []kstat.Objset
+		errs    []error
+	)
+
+	for _, pool := range f.pools {
+		o, err := c.kstat.Objsets(pool)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		objsets = append(objsets, o...)
+	}
+
+	return objsets, errors.Join(errs...)
+}
+
+// collectObjsets emits the dataset I/O counters. The dataset filter applies
+// as in CLI mode, and --dataset.max-series keeps the busiest datasets.
+func (c *Collector) collectObjsets(ch chan<- prometheus.Metric, objsets []kstat.Objset, s *collectState) {
+	objsets = filterSlice(objsets, func(o *kstat.Objset) bool {
+		return c.poolFilter.match(o.Pool) && c.datasetFilter.match(o.Dataset)
+	})
+
+	objsets, truncated := c.limitObjsets(objsets)
+
+	s.limitTruncated = s.limitTruncated || truncated
+	s.limitReported = true
+
+	for _, o := range objsets {
+		ch <- prometheus.MustNewConstMetric(c.datasetReads, prometheus.CounterValue, float64(o.Reads), o.Dataset, o.Pool)
+		ch <- prometheus.MustNewConstMetric(c.datasetWrites, prometheus.CounterValue, float64(o.Writes), o.Dataset, o.Pool)
+		ch <- prometheus.MustNewConstMetric(c.datasetReadBytes, prometheus.CounterValue, float64(o.ReadBytes), o.Dataset, o.Pool)
+		ch <- prometheus.MustNewConstMetric(c.datasetWrittenBytes, prometheus.CounterValue, float64(o.WrittenBytes), o.Dataset, o.Pool)
+	}
+}
+
+// kstatMetric maps one counter of a named kstat (arcstats, zil) to a metric.
+type kstatMetric struct {
+	key  string
+	desc *prometheus.Desc
+	typ  prometheus.ValueType
+}
+
+// newKstatMetric builds a kstatMetric named zfs_<subsystem>_<name>.
+func newKstatMetric(subsystem, name, key, help string, typ prometheus.ValueType) kstatMetric {
+	return kstatMetric{
+		key:  key,
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, nil, nil),
+		typ:  typ,
+	}
+}
+
+// collectNamedKstat emits each metric whose key is present in values.
+func collectNamedKstat(ch chan<- prometheus.Metric, metrics []kstatMetric, values map[string]uint64) {
+	for _, m := range metrics {
+		if v, ok := values[m.key]; ok {
+			ch <- prometheus.MustNewConstMetric(m.desc, m.typ, float64(v))
+		}
+	}
+}
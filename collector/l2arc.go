@@ -0,0 +1,29 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// initL2ARCDescriptors builds the zfs_l2arc_* metrics from arcstats.
+func (c *Collector) initL2ARCDescriptors() {
+	c.l2arc = []kstatMetric{
+		newKstatMetric("l2arc", "size_bytes", "l2_size", "Uncompressed size of data cached in the L2ARC.", prometheus.GaugeValue),
+		newKstatMetric("l2arc", "allocated_bytes", "l2_asize", "Space allocated on L2ARC devices (after compression).", prometheus.GaugeValue),
+		newKstatMetric("l2arc", "header_size_bytes", "l2_hdr_size", "ARC memory used by L2ARC headers.", prometheus.GaugeValue),
+		newKstatMetric("l2arc", "hits_total", "l2_hits", "ARC misses served from the L2ARC.", prometheus.CounterValue),
+		newKstatMetric("l2arc", "misses_total", "l2_misses", "ARC misses not found in the L2ARC.", prometheus.CounterValue),
+		newKstatMetric("l2arc", "read_bytes_total", "l2_read_bytes", "Bytes read from L2ARC devices.", prometheus.CounterValue),
+		newKstatMetric("l2arc", "written_bytes_total", "l2_write_bytes", "Bytes written to L2ARC devices.", prometheus.CounterValue),
+	}
+}
+
+// collectL2ARCMetrics emits L2ARC metrics when a cache device exists. The
+// l2_* arcstats are always present, so a pool without cache devices is
+// detected by the feed thread never having run and nothing being cached.
+func (c *Collector) collectL2ARCMetrics(ch chan<- prometheus.Metric, arcstats map[string]uint64) {
+	if arcstats["l2_size"] == 0 && arcstats["l2_feeds"] == 0 {
+		return
+	}
+
+	collectNamedKstat(ch, c.l2arc, arcstats)
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// latencySchema is the native histogram schema the zpool iostat -w buckets
+// are mapped to: 2^3 buckets per power of two. ZFS buckets are powers of two
+// in nanoseconds, which fall between the power-of-two bounds in seconds, so
+// each is placed in the native bucket holding its upper bound. At this
+// schema that bound is overstated by under 2%.
+const latencySchema = 3
+
+// latencyOps maps the zpool iostat -w columns exported as latency histograms
+// to their desc and op label.
+var latencyOps = map[string]struct {
+	disk bool
+	op   string
+}{
+	zfs.LatencyTotalRead:  {false, "read"},
+	zfs.LatencyTotalWrite: {false, "write"},
+	zfs.LatencyScrub:      {false, "scrub"},
+	zfs.LatencyDiskRead:   {true, "read"},
+	zfs.LatencyDiskWrite:  {true, "write"},
+}
+
+// collectLatencyMetrics emits the per-pool I/O latency distributions from
+// zpool iostat -w as native histograms. zpool does not report the total
+// latency, so the histogram sum is NaN.
+func (c *Collector) collectLatencyMetrics(ch chan<- prometheus.Metric, hists []zfs.LatencyHistogram) {
+	for _, h := range hists {
+		o, ok := latencyOps[h.Column]
+		if !ok {
+			continue
+		}
+
+		desc := c.poolLatency
+		if o.disk {
+			desc = c.poolDiskLatency
+		}
+
+		buckets, count := nativeLatencyBuckets(h.Buckets)
+
+		ch <- prometheus.MustNewConstNativeHistogram(desc, count, math.NaN(), buckets, nil,
+			0, latencySchema, 0, time.Time{}, h.Pool, o.op)
+	}
+}
+
+// nativeLatencyBuckets converts zpool iostat -w bucket counts, where bucket
+// i holds latencies below 2^(i+1) ns, to native histogram buckets in
+// seconds. It also returns the total count.
+func nativeLatencyBuckets(counts []uint64) (map[int]int64, uint64) {
+	buckets := make(map[int]int64)
+
+	var total uint64
+
+	for i, n := range counts {
+		if n == 0 || n > math.MaxInt64 {
+			continue
+		}
+
+		upper := math.Ldexp(1e-9, i+1)
+		idx := int(math.Ceil(math.Log2(upper) * (1 << latencySchema)))
+
+		buckets[idx] += int64(n)
+		total += n
+	}
+
+	return buckets, total
+}
@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"regexp"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fqNameRe extracts the metric name from prometheus.Desc.String(), which is
+// the only exported view of a descriptor's name.
+var fqNameRe = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// MetricNames returns the sorted names of every metric the exporter can
+// emit, as declared by Describe on a Collector and CommandDurations. Custom
+// hook metrics depend on configuration, and the node_zfs_<subsystem>_*
+// metrics of the node_compat collector on the loaded module, so neither is
+// included. Histograms are listed by base name, without the _bucket, _sum,
+// and _count suffixes.
+//
+// Tools such as the dashboard generator use it to validate queries without
+// keeping their own list.
+func MetricNames() []string {
+	c := NewCollector(nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), &Options{})
+
+	ch := make(chan *prometheus.Desc)
+
+	go func() {
+		c.Describe(ch)
+		NewCommandDurations().Describe(ch)
+		close(ch)
+	}()
+
+	var names []string
+
+	for d := range ch {
+		if m := fqNameRe.FindStringSubmatch(d.String()); m != nil {
+			names = append(names, m[1])
+		}
+	}
+
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// nfsProperties are fetched to match shared datasets against the kernel
+// export table.
+var nfsProperties = []string{"sharenfs", "mountpoint", "mounted"}
+
+// nfsState is what the nfs collector compares: the sharing properties of
+// every dataset and the paths the kernel currently exports.
+type nfsState struct {
+	Shares  zfs.DatasetProperties `json:"shares"`
+	Exports []string              `json:"exports"`
+}
+
+// fetchNFS reads the sharing properties and the kernel export table.
+func (c *Collector) fetchNFS(ctx context.Context) (nfsState, error) {
+	props, err := c.client.GetDatasetProperties(ctx, nfsProperties)
+	if err != nil {
+		return nfsState{}, err
+	}
+
+	exports, err := host.NFSExports(c.nfsExports)
+	if err != nil {
+		return nfsState{}, err
+	}
+
+	return nfsState{Shares: props, Exports: exports}, nil
+}
+
+// collectNFSMetrics emits the export count and, for each dataset with
+// sharenfs set, whether its mountpoint is actually exported. Datasets with a
+// legacy or no mountpoint are skipped: ZFS never shares them itself.
+func (c *Collector) collectNFSMetrics(ch chan<- prometheus.Metric, s nfsState) {
+	ch <- prometheus.MustNewConstMetric(c.nfsExportCount, prometheus.GaugeValue, float64(len(s.Exports)))
+
+	exported := make(map[string]bool, len(s.Exports))
+	for _, p := range s.Exports {
+		exported[p] = true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.Shares)) {
+		p := s.Shares[name]
+
+		if share := p["sharenfs"]; share == "" || share == "off" {
+			continue
+		}
+
+		mountpoint := p["mountpoint"]
+		if !strings.HasPrefix(mountpoint, "/") {
+			continue
+		}
+
+		pool, _, _ := strings.Cut(name, "/")
+		if !c.poolFilter.match(pool) || !c.datasetFilter.match(name) {
+			continue
+		}
+
+		up := p["mounted"] == "yes" && exported[mountpoint]
+		ch <- prometheus.MustNewConstMetric(c.datasetExported, prometheus.GaugeValue, boolToFloat(up), name, pool)
+	}
+}
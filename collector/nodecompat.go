@@ -0,0 +1,166 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+)
+
+// nodeNamespace is the metric prefix of node_exporter.
+const nodeNamespace = "node"
+
+// nodeKstats are the global kstats node_exporter's zfs collector reads, with
+// the subsystem of their node_zfs_<subsystem>_<stat> names. Releases
+// without one of them (xuio_stats and vdev_cache_stats are gone from
+// OpenZFS 2.2) just lack its metrics.
+var nodeKstats = []struct {
+	file      string
+	subsystem string
+}{
+	{"abdstats", "zfs_abd"},
+	{"arcstats", "zfs_arc"},
+	{"dbufstats", "zfs_dbuf"},
+	{"dmu_tx", "zfs_dmu_tx"},
+	{"dnodestats", "zfs_dnode"},
+	{"fm", "zfs_fm"},
+	{"vdev_cache_stats", "zfs_vdev_cache"},
+	{"vdev_mirror_stats", "zfs_vdev_mirror"},
+	{"xuio_stats", "zfs_xuio"},
+	{"zfetchstats", "zfs_zfetch"},
+	{"zil", "zfs_zil"},
+}
+
+// nodePoolStates are the values of node_zfs_zpool_state's state label.
+var nodePoolStates = []string{"online", "degraded", "faulted", "offline", "removed", "unavail", "suspended"}
+
+// nodeObjsetMetric is one node_zfs_zpool_dataset_* metric.
+type nodeObjsetMetric struct {
+	desc  *prometheus.Desc
+	value func(o *kstat.Objset) uint64
+}
+
+// nodeStats is one read of the kstats behind the node_compat collector.
+type nodeStats struct {
+	Named   map[string]map[string]uint64 // by kstat file
+	Pools   []kstat.Pool
+	Objsets []kstat.Objset
+}
+
+// initNodeCompatDescriptors builds the pool state and dataset metrics of
+// node_exporter's zfs collector. The node_zfs_<subsystem>_* metrics are
+// named after whatever stats the loaded module reports, so their
+// descriptors are built at collection time and not described.
+func (c *Collector) initNodeCompatDescriptors() {
+	c.nodePoolState = prometheus.NewDesc(
+		prometheus.BuildFQName(nodeNamespace, "zfs_zpool", "state"),
+		"kstat.zfs.misc.state",
+		[]string{"state", "zpool"}, nil,
+	)
+
+	objset := func(stat string, value func(o *kstat.Objset) uint64) nodeObjsetMetric {
+		return nodeObjsetMetric{
+			desc: prometheus.NewDesc(
+				prometheus.BuildFQName(nodeNamespace, "zfs_zpool_dataset", stat),
+				"kstat.zfs.misc.objset."+stat,
+				[]string{"dataset", "zpool"}, nil,
+			),
+			value: value,
+		}
+	}
+
+	c.nodeObjsets = []nodeObjsetMetric{
+		objset("nread", func(o *kstat.Objset) uint64 { return o.ReadBytes }),
+		objset("nwritten", func(o *kstat.Objset) uint64 { return o.WrittenBytes }),
+		objset("reads", func(o *kstat.Objset) uint64 { return o.Reads }),
+		objset("writes", func(o *kstat.Objset) uint64 { return o.Writes }),
+	}
+}
+
+// nodeCompatDescs returns the descriptors of the node_compat collector
+// known before collection.
+func (c *Collector) nodeCompatDescs() []*prometheus.Desc {
+	descs := []*prometheus.Desc{c.nodePoolState}
+	for _, m := range c.nodeObjsets {
+		descs = append(descs, m.desc)
+	}
+
+	return descs
+}
+
+// readNodeStats reads the global kstats, pool states, and dataset counters
+// node_exporter's zfs collector exports. Missing global kstats are skipped;
+// a missing kstat tree fails the read.
+func (c *Collector) readNodeStats() (*nodeStats, error) {
+	pools, err := c.stats.Pools()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &nodeStats{Named: make(map[string]map[string]uint64, len(nodeKstats)), Pools: pools}
+
+	for _, k := range nodeKstats {
+		values, err := c.stats.Named(k.file)
+
+		switch {
+		case errors.Is(err, kstat.ErrNoKstat):
+		case err != nil:
+			return nil, err
+		default:
+			stats.Named[k.file] = values
+		}
+	}
+
+	for _, p := range pools {
+		objsets, err := c.stats.Objsets(p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("reading objsets of %s: %w", p.Name, err)
+		}
+
+		stats.Objsets = append(stats.Objsets, objsets...)
+	}
+
+	return stats, nil
+}
+
+// collectNodeCompatMetrics emits stats under node_exporter's names. Like
+// node_exporter, every global kstat value is untyped.
+func (c *Collector) collectNodeCompatMetrics(ch chan<- prometheus.Metric, stats *nodeStats) {
+	for _, k := range nodeKstats {
+		values := stats.Named[k.file]
+
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			desc := prometheus.NewDesc(
+				prometheus.BuildFQName(nodeNamespace, k.subsystem, name),
+				"kstat.zfs.misc."+k.file+"."+name,
+				nil, nil,
+			)
+
+			// A stat whose name is no valid metric name fails here and is
+			// left out.
+			if m, err := prometheus.NewConstMetric(desc, prometheus.UntypedValue, float64(values[name])); err == nil {
+				ch <- m
+			}
+		}
+	}
+
+	for _, p := range stats.Pools {
+		for _, state := range nodePoolStates {
+			ch <- prometheus.MustNewConstMetric(c.nodePoolState, prometheus.GaugeValue,
+				boolToFloat(strings.EqualFold(p.State, state)), state, p.Name)
+		}
+	}
+
+	for i := range stats.Objsets {
+		o := &stats.Objsets[i]
+
+		for _, m := range c.nodeObjsets {
+			ch <- prometheus.MustNewConstMetric(m.desc, prometheus.UntypedValue, float64(m.value(o)), o.Dataset, o.Pool)
+		}
+	}
+}
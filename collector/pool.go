@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// poolState is the pool listing of the zpool/zfs backend.
+type poolState struct {
+	Pools []zfs.Pool `json:"pools"`
+
+	// Features is the detected ZFS version and what it supports.
+	Features zfs.Features `json:"features"`
+}
+
+// fetchPools lists the pools and detects the ZFS features, and hands the
+// pools that pass the pool filter to the other sub-collectors.
+func (c *Collector) fetchPools(ctx context.Context, f *fetchState) (poolState, error) {
+	pools, err := c.client.GetPools(ctx)
+	if err != nil {
+		return poolState{}, err
+	}
+
+	f.pools = poolNames(c.filterPools(pools))
+
+	return poolState{Pools: pools, Features: c.client.Features(ctx)}, nil
+}
+
+// poolNames returns the names of pools.
+func poolNames(pools []zfs.Pool) []string {
+	names := make([]string, len(pools))
+	for i := range pools {
+		names[i] = pools[i].Name
+	}
+
+	return names
+}
+
+// collectPools emits the pool, aggregate, and feature metrics. When the
+// scan collector ran, pools that zpool status reports as suspended are
+// exported as such.
+func (c *Collector) collectPools(ch chan<- prometheus.Metric, st poolState, s *collectState) {
+	pools := c.filterPools(st.Pools)
+	if scans, ok := result[[]zfs.ScanStatus](s, CollectorScan); ok {
+		pools = markSuspended(pools, scans)
+	}
+
+	c.collectPoolMetrics(ch, pools)
+	c.collectAggregateMetrics(ch, pools)
+	c.collectFeatureMetrics(ch, &st.Features)
+}
+
+// collectFeatureMetrics emits which version-gated ZFS features are active.
+func (c *Collector) collectFeatureMetrics(ch chan<- prometheus.Metric, f *zfs.Features) {
+	for name, on := range f.Enabled() {
+		ch <- prometheus.MustNewConstMetric(c.featureEnabled, prometheus.GaugeValue, boolToFloat(on), name)
+	}
+}
+
+func (c *Collector) collectPoolMetrics(ch chan<- prometheus.Metric, pools []zfs.Pool) {
+	for _, p := range pools {
+		ch <- prometheus.MustNewConstMetric(c.poolSize, prometheus.GaugeValue, float64(p.Size), p.Name)
+		ch <- prometheus.MustNewConstMetric(c.poolAllocated, prometheus.GaugeValue, float64(p.Allocated), p.Name)
+		ch <- prometheus.MustNewConstMetric(c.poolFree, prometheus.GaugeValue, float64(p.Free), p.Name)
+		ch <- prometheus.MustNewConstMetric(c.poolFragmentation, prometheus.GaugeValue, p.Fragmentation, p.Name)
+		ch <- prometheus.MustNewConstMetric(c.poolDedup, prometheus.GaugeValue, p.DedupRatio, p.Name)
+
+		ro := 0.0
+		if p.ReadOnly {
+			ro = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.poolReadOnly, prometheus.GaugeValue, ro, p.Name)
+
+		c.collectPoolHealth(ch, p.Name, p.Health)
+	}
+}
+
+// markSuspended returns pools with the health of every pool that zpool status
+// reports as suspended set to SUSPENDED. pools is shared with the scrape
+// cache, so it is copied rather than modified.
+func markSuspended(pools []zfs.Pool, scans []zfs.ScanStatus) []zfs.Pool {
+	var out []zfs.Pool
+
+	for i := range scans {
+		if !scans[i].Suspended {
+			continue
+		}
+
+		if out == nil {
+			out = slices.Clone(pools)
+		}
+
+		for j := range out {
+			if out[j].Name == scans[i].Pool {
+				out[j].Health = "SUSPENDED"
+			}
+		}
+	}
+
+	if out == nil {
+		return pools
+	}
+
+	return out
+}
+
+// poolStates returns healthStates followed by the lowercased extra states
+// not already in it.
+func poolStates(extra []string) []string {
+	states := slices.Clone(healthStates)
+
+	for _, s := range extra {
+		s = strings.ToLower(s)
+		if !slices.Contains(states, s) {
+			states = append(states, s)
+		}
+	}
+
+	return states
+}
+
+// collectPoolHealth emits the health state-set: one metric per possible
+// state, 1 for the current one. A state outside the set is reported by
+// zfs_pool_health_unknown instead, so that the pool does not silently
+// appear in no state at all.
+func (c *Collector) collectPoolHealth(ch chan<- prometheus.Metric, pool, health string) {
+	healthLower := strings.ToLower(health)
+	for _, state := range c.poolStates {
+		val := 0.0
+		if state == healthLower {
+			val = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.poolHealth, prometheus.GaugeValue, val, pool, state)
+	}
+
+	if !slices.Contains(c.poolStates, healthLower) {
+		ch <- prometheus.MustNewConstMetric(c.poolHealthUnknown, prometheus.GaugeValue, 1, pool, health)
+	}
+}
+
+// collectAggregateMetrics emits host-level totals computed over the same pools
+// that produced per-pool series, so aggregates always agree with sum() over
+// the per-pool metrics.
+func (c *Collector) collectAggregateMetrics(ch chan<- prometheus.Metric, pools []zfs.Pool) {
+	var size, allocated uint64
+
+	unhealthy := 0
+
+	for _, p := range pools {
+		size += p.Size
+		allocated += p.Allocated
+
+		if !strings.EqualFold(p.Health, "ONLINE") {
+			unhealthy++
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.totalSize, prometheus.GaugeValue, float64(size))
+	ch <- prometheus.MustNewConstMetric(c.totalAllocated, prometheus.GaugeValue, float64(allocated))
+	c.collectPoolCounts(ch, len(pools), unhealthy)
+}
+
+// collectPoolCounts emits the pool count aggregates.
+func (c *Collector) collectPoolCounts(ch chan<- prometheus.Metric, total, unhealthy int) {
+	ch <- prometheus.MustNewConstMetric(c.poolsTotal, prometheus.GaugeValue, float64(total))
+	ch <- prometheus.MustNewConstMetric(c.poolsUnhealthy, prometheus.GaugeValue, float64(unhealthy))
+}
@@ -0,0 +1,156 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// poolPropertyNames are the pool properties read with zpool get.
+var poolPropertyNames = []string{"ashift", "autotrim", "guid", "altroot", "cachefile"}
+
+// poolPropState is what the pool_properties collector reads: the
+// poolPropertyNames of each pool and its creation time.
+type poolPropState struct {
+	Properties zfs.PoolProperties   `json:"properties,omitempty"`
+	Created    map[string]time.Time `json:"created,omitempty"`
+}
+
+// fetchPoolProperties reads the pool properties and creation times
+// concurrently. What was read is returned even if the other command failed.
+func (c *Collector) fetchPoolProperties(ctx context.Context) (poolPropState, error) {
+	var (
+		st                 poolPropState
+		propErr, createErr error
+		wg                 sync.WaitGroup
+	)
+
+	wg.Go(func() { st.Properties, propErr = c.client.GetPoolProperties(ctx, poolPropertyNames) })
+	wg.Go(func() { st.Created, createErr = c.client.GetPoolCreationTimes(ctx) })
+	wg.Wait()
+
+	return st, errors.Join(propErr, createErr)
+}
+
+// collectPoolProperties emits the pool property metrics of the listed pools.
+func (c *Collector) collectPoolProperties(ch chan<- prometheus.Metric, st poolPropState, s *collectState) {
+	pools, _ := result[poolState](s, collectorPool)
+	c.collectPoolPropertyMetrics(ch, c.filterPools(pools.Pools), st.Properties, st.Created)
+}
+
+// collectPoolPropertyMetrics emits the settings and creation time of each
+// pool. A property the pool does not report is skipped; altroot and
+// cachefile report "-" when unset, which leaves their labels empty.
+func (c *Collector) collectPoolPropertyMetrics(ch chan<- prometheus.Metric, pools []zfs.Pool, props zfs.PoolProperties, ctime map[string]time.Time) {
+	for _, p := range pools {
+		if t, ok := ctime[p.Name]; ok {
+			ch <- prometheus.MustNewConstMetric(c.poolCreated, prometheus.GaugeValue, float64(t.Unix()), p.Name)
+		}
+
+		pp, ok := props[p.Name]
+		if !ok {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.poolInfo, prometheus.GaugeValue, 1, p.Name, pp["guid"], pp["altroot"], pp["cachefile"])
+
+		if v, err := strconv.ParseFloat(pp["ashift"], 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.poolAshift, prometheus.GaugeValue, v, p.Name)
+		}
+
+		switch pp["autotrim"] {
+		case "on":
+			ch <- prometheus.MustNewConstMetric(c.poolAutotrim, prometheus.GaugeValue, 1, p.Name)
+		case "off":
+			ch <- prometheus.MustNewConstMetric(c.poolAutotrim, prometheus.GaugeValue, 0, p.Name)
+		}
+	}
+}
+
+// datasetTuningProperties are the dataset properties exported as labels of
+// zfs_dataset_properties, in label order.
+var datasetTuningProperties = []string{"recordsize", "sync", "atime", "primarycache", "compression"}
+
+// tuningState is what the dataset_tuning collector reads: the shared
+// dataset listing and the datasetTuningProperties of each dataset.
+type tuningState struct {
+	Datasets   []zfs.Dataset         `json:"datasets,omitempty"`
+	Properties zfs.DatasetProperties `json:"properties,omitempty"`
+}
+
+// fetchTuning reads the dataset listing and the tuning properties
+// concurrently.
+func (c *Collector) fetchTuning(ctx context.Context, f *fetchState) (tuningState, error) {
+	var (
+		st              tuningState
+		listErr, getErr error
+		wg              sync.WaitGroup
+	)
+
+	wg.Go(func() { st.Datasets, listErr = f.datasets.get(ctx) })
+	wg.Go(func() { st.Properties, getErr = c.client.GetDatasetProperties(ctx, datasetTuningProperties) })
+	wg.Wait()
+
+	return st, errors.Join(listErr, getErr)
+}
+
+// collectTuning emits the tuning properties of the datasets left by
+// --dataset.max-series.
+func (c *Collector) collectTuning(ch chan<- prometheus.Metric, st tuningState, s *collectState) {
+	c.collectTuningMetrics(ch, c.limitedDatasets(s), st.Properties)
+}
+
+// collectTuningMetrics emits one zfs_dataset_properties series per dataset
+// carrying its tuning properties, so dashboards can match datasets against
+// policy (sync=disabled, atime=on) with a single selector.
+func (c *Collector) collectTuningMetrics(ch chan<- prometheus.Metric, datasets []zfs.Dataset, props zfs.DatasetProperties) {
+	for _, d := range datasets {
+		dsProps, ok := props[d.Name]
+		if !ok {
+			continue
+		}
+
+		labels := []string{d.Name, d.Type, d.Pool}
+		for _, name := range datasetTuningProperties {
+			labels = append(labels, dsProps[name])
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.datasetTuning, prometheus.GaugeValue, 1, labels...)
+	}
+}
+
+// collectPropertyMetrics emits the extra properties requested with
+// --dataset.properties. Numeric values (exact with zfs get -p) become
+// zfs_dataset_property; anything else (on/off, lz4, user strings) becomes a
+// zfs_dataset_property_info series carrying the value as a label.
+func (c *Collector) collectPropertyMetrics(ch chan<- prometheus.Metric, datasets []zfs.Dataset, props zfs.DatasetProperties) {
+	for _, d := range datasets {
+		dsProps := props[d.Name]
+
+		// Sorted for deterministic output order.
+		names := make([]string, 0, len(dsProps))
+		for name := range dsProps {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+
+		for _, name := range names {
+			raw := dsProps[name]
+
+			if v, err := strconv.ParseFloat(raw, 64); err == nil {
+				ch <- prometheus.MustNewConstMetric(c.datasetProperty, prometheus.GaugeValue, v, d.Name, d.Type, d.Pool, name)
+				continue
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.datasetPropertyInfo, prometheus.GaugeValue, 1, d.Name, d.Type, d.Pool, name, raw)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// A subCollector is one source of metrics: the required pool listing or an
+// optional sub-collector. Once the pool listing has succeeded, the enabled
+// sub-collectors are fetched concurrently, each with its own deadline and
+// zfs_scrape_collector_success series.
+type subCollector interface {
+	// Describe sends every descriptor the sub-collector can emit.
+	Describe(ch chan<- *prometheus.Desc)
+
+	// Fetch reads the sub-collector's source. f holds the pools found by
+	// the pool listing and the commands shared with other sub-collectors.
+	// The result is cached with the rest of the scrape, shown by
+	// DebugState, and passed to Collect.
+	Fetch(ctx context.Context, f *fetchState) (any, error)
+
+	// Collect emits the metrics of a fetch. s holds what the other
+	// sub-collectors of the scrape fetched.
+	Collect(ch chan<- prometheus.Metric, data any, s *collectState)
+}
+
+// source is a subCollector built from a typed fetch and collect function.
+type source[T any] struct {
+	descs []*prometheus.Desc
+	read  func(ctx context.Context, f *fetchState) (T, error)
+	emit  func(ch chan<- prometheus.Metric, data T, s *collectState)
+}
+
+// newSource returns a source whose fetch and collect need nothing from the
+// rest of the scrape.
+func newSource[T any](
+	descs []*prometheus.Desc,
+	read func(ctx context.Context) (T, error),
+	emit func(ch chan<- prometheus.Metric, data T),
+) *source[T] {
+	return &source[T]{
+		descs: descs,
+		read:  func(ctx context.Context, _ *fetchState) (T, error) { return read(ctx) },
+		emit:  func(ch chan<- prometheus.Metric, data T, _ *collectState) { emit(ch, data) },
+	}
+}
+
+func (s *source[T]) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range s.descs {
+		ch <- d
+	}
+}
+
+func (s *source[T]) Fetch(ctx context.Context, f *fetchState) (any, error) {
+	return s.read(ctx, f)
+}
+
+func (s *source[T]) Collect(ch chan<- prometheus.Metric, data any, st *collectState) {
+	if v, ok := data.(T); ok {
+		s.emit(ch, v, st)
+	}
+}
+
+// SubCollectorNames lists every registered collector in registration order:
+// the required pool listing followed by the toggleable sub-collectors of
+// CollectorNames. Each accepts a timeout in Options.CollectorTimeouts.
+var SubCollectorNames = append([]string{collectorPool}, CollectorNames...)
+
+// registration is one registered sub-collector.
+type registration struct {
+	name string // toggle name and zfs_scrape_collector_success label
+	sub  subCollector
+
+	// timeout replaces the scrape timeout for this sub-collector's fetch;
+	// zero keeps it on the scrape's deadline.
+	timeout time.Duration
+
+	// unavailable is set when the source is not configured (no kstat
+	// reader, no timers, no smbstatus, or no zpool and zfs with the kstat
+	// backend). The sub-collector is then described but never fetched,
+	// even when enabled.
+	unavailable bool
+
+	// required is set for the pool listing, which cannot be disabled. It
+	// is fetched before the others, and nothing else is fetched when it
+	// fails.
+	required bool
+
+	// partial is set when a failed fetch can still return data worth
+	// exporting, such as the pools zpool status answered for. Collect then
+	// runs despite the error.
+	partial bool
+}
+
+// subResult is the outcome of one sub-collector's fetch.
+type subResult struct {
+	data any
+	err  error
+}
+
+// initSubCollectors registers the sub-collectors in SubCollectorNames
+// order. Descriptors must already be built.
+func (c *Collector) initSubCollectors(timeouts map[string]time.Duration) {
+	c.subs = slices.Concat(
+		[]registration{c.poolSubCollector()},
+		c.zfsSubCollectors(),
+		c.cliSubCollectors(),
+		c.statusSubCollectors(),
+		c.hostSubCollectors(),
+		c.kstatSubCollectors(),
+		[]registration{{
+			name:        CollectorCustom,
+			unavailable: len(c.customHooks) == 0,
+			partial:     true,
+			sub:         newSource(c.customDescs(), c.runCustomHooks, c.collectCustomMetrics),
+		}},
+	)
+
+	for i := range c.subs {
+		c.subs[i].timeout = timeouts[c.subs[i].name]
+	}
+}
+
+// poolSubCollector returns the registration of the pool listing, read from
+// zpool list or, with the kstat backend, from procfs.
+func (c *Collector) poolSubCollector() registration {
+	descs := []*prometheus.Desc{
+		c.featureEnabled, c.totalSize, c.totalAllocated, c.poolsTotal, c.poolsUnhealthy,
+		c.poolSize, c.poolAllocated, c.poolFree, c.poolFragmentation, c.poolDedup, c.poolReadOnly,
+		c.poolHealth, c.poolHealthUnknown,
+	}
+
+	if c.kstat != nil {
+		return registration{name: collectorPool, required: true, sub: &source[[]kstat.Pool]{
+			descs: descs,
+			read:  c.fetchKstatPools,
+			emit:  c.collectKstatPools,
+		}}
+	}
+
+	return registration{name: collectorPool, required: true, sub: &source[poolState]{
+		descs: descs,
+		read:  c.fetchPools,
+		emit:  c.collectPools,
+	}}
+}
+
+// zfsSubCollectors returns the registrations of the sub-collectors built on
+// the shared pool, dataset, and snapshot listings. Only the dataset
+// collector is available with the kstat backend, which reads per-dataset
+// I/O counters instead.
+func (c *Collector) zfsSubCollectors() []registration {
+	noCLI := c.kstat != nil
+
+	datasetDescs := []*prometheus.Desc{
+		c.dataset.used, c.dataset.available, c.dataset.referenced, c.dataset.shareNFS, c.dataset.shareSMB,
+		c.dataset.compress, c.dataset.logicalUsed, c.dataset.logicalReferenced,
+		c.datasetThreshold, c.datasetProperty, c.datasetPropertyInfo, c.poolCompressRatio,
+		c.datasetReads, c.datasetWrites, c.datasetReadBytes, c.datasetWrittenBytes,
+	}
+
+	dataset := registration{name: CollectorDatasets, partial: true, sub: &source[datasetState]{
+		descs: datasetDescs,
+		read:  c.fetchDatasets,
+		emit:  c.collectDatasets,
+	}}
+
+	if noCLI {
+		dataset = registration{name: CollectorDatasets, partial: true, sub: &source[[]kstat.Objset]{
+			descs: datasetDescs,
+			read:  c.fetchObjsets,
+			emit:  c.collectObjsets,
+		}}
+	}
+
+	return []registration{
+		{name: CollectorPoolProperties, unavailable: noCLI, partial: true, sub: &source[poolPropState]{
+			descs: []*prometheus.Desc{c.poolAshift, c.poolAutotrim, c.poolInfo, c.poolCreated},
+			read:  func(ctx context.Context, _ *fetchState) (poolPropState, error) { return c.fetchPoolProperties(ctx) },
+			emit:  c.collectPoolProperties,
+		}},
+		dataset,
+		{name: CollectorDatasetHistogram, unavailable: noCLI, sub: &source[[]zfs.Dataset]{
+			descs: []*prometheus.Desc{c.datasetUsedHistogram},
+			read:  func(ctx context.Context, f *fetchState) ([]zfs.Dataset, error) { return f.datasets.get(ctx) },
+			emit: func(ch chan<- prometheus.Metric, datasets []zfs.Dataset, _ *collectState) {
+				c.collectDatasetHistogram(ch, c.filterDatasets(datasets, false))
+			},
+		}},
+		{name: CollectorDatasetTuning, unavailable: noCLI, sub: &source[tuningState]{
+			descs: []*prometheus.Desc{c.datasetTuning},
+			read:  c.fetchTuning,
+			emit:  c.collectTuning,
+		}},
+		{name: CollectorSnapshots, unavailable: noCLI, sub: &source[snapshotState]{
+			descs: []*prometheus.Desc{c.snapshotCount, c.snapshotHolds, c.snapshotNewest, c.snapshotsTruncated},
+			read:  c.fetchSnapshots,
+			emit:  c.collectSnapshots,
+		}},
+		{name: CollectorSnapshotPolicy, unavailable: noCLI || len(c.snapPolicies) == 0, sub: &source[[]zfs.Snapshot]{
+			descs: []*prometheus.Desc{c.snapshotPolicyCount, c.snapshotPolicyCompliant},
+			read:  func(ctx context.Context, _ *fetchState) ([]zfs.Snapshot, error) { return c.client.GetSnapshots(ctx) },
+			emit:  c.collectSnapshotPolicy,
+		}},
+	}
+}
+
+// cliSubCollectors returns the registrations of the sub-collectors that run
+// their own zpool or zfs command. They are unavailable with the kstat
+// backend.
+func (c *Collector) cliSubCollectors() []registration {
+	noCLI := c.kstat != nil
+
+	return []registration{
+		{name: CollectorNFS, unavailable: noCLI, sub: newSource(
+			[]*prometheus.Desc{c.nfsExportCount, c.datasetExported},
+			c.fetchNFS,
+			c.collectNFSMetrics,
+		)},
+		{name: CollectorUserspace, unavailable: noCLI || len(c.userspaceDS) == 0, sub: newSource(
+			[]*prometheus.Desc{c.userUsed, c.userQuota, c.groupUsed, c.groupQuota},
+			func(ctx context.Context) ([]zfs.SpaceUsage, error) { return c.client.GetSpaceUsage(ctx, c.userspaceDS) },
+			func(ch chan<- prometheus.Metric, usage []zfs.SpaceUsage) {
+				c.collectSpaceUsageMetrics(ch, c.filterSpaceUsage(usage))
+			},
+		)},
+		{name: CollectorHistory, unavailable: noCLI, sub: newSource(
+			[]*prometheus.Desc{c.poolAdminOps},
+			func(ctx context.Context) ([]zfs.HistoryCount, error) {
+				return c.client.GetHistoryCounts(ctx, c.started)
+			},
+			func(ch chan<- prometheus.Metric, counts []zfs.HistoryCount) {
+				c.collectHistoryMetrics(ch, c.filterHistory(counts))
+			},
+		)},
+		{name: CollectorLatency, unavailable: noCLI, sub: newSource(
+			[]*prometheus.Desc{c.poolLatency, c.poolDiskLatency},
+			func(ctx context.Context) ([]zfs.LatencyHistogram, error) { return c.client.GetLatencyHistograms(ctx) },
+			func(ch chan<- prometheus.Metric, hists []zfs.LatencyHistogram) {
+				c.collectLatencyMetrics(ch, c.filterLatency(hists))
+			},
+		)},
+	}
+}
+
+// statusSubCollectors returns the registrations of the sub-collectors built
+// on zpool status: scan, vdev, and SMART, which reads the vdevs' devices.
+// They are unavailable with the kstat backend. Pools that answered are
+// exported even when zpool status failed for others.
+func (c *Collector) statusSubCollectors() []registration {
+	noCLI := c.kstat != nil
+
+	return []registration{
+		{name: CollectorScan, unavailable: noCLI, partial: true, sub: &source[[]zfs.ScanStatus]{
+			descs: []*prometheus.Desc{
+				c.poolScrubActive, c.poolResilverActive, c.poolScanProgress, c.poolScrubPaused, c.poolScanDeferred,
+				c.poolResilverQueue, c.poolScanScanned, c.poolScanIssued, c.poolScanTotal, c.poolScanRate,
+				c.poolScanETA, c.poolStatusCode, c.poolDataErrors,
+				c.poolLastScrubTime, c.poolLastScrubDuration, c.poolLastScrubRepaired, c.poolLastScrubErrors,
+				c.poolLastScrubAge, c.poolLastResilverTime, c.poolLastResilverDuration,
+			},
+			read: func(ctx context.Context, f *fetchState) ([]zfs.ScanStatus, error) {
+				return c.client.GetScanStatuses(ctx, f.pools)
+			},
+			emit: func(ch chan<- prometheus.Metric, scans []zfs.ScanStatus, _ *collectState) {
+				c.collectScanMetrics(ch, c.filterScans(scans))
+			},
+		}},
+		{name: CollectorVdev, unavailable: noCLI, partial: true, sub: &source[vdevState]{
+			descs: []*prometheus.Desc{
+				c.vdevReadErrors, c.vdevWriteErrors, c.vdevChecksumErrors, c.vdevState, c.vdevDevice,
+				c.vdevCapacity, c.vdevFragmentation,
+				c.poolSpares, c.poolSparesInUse, c.poolCacheDevices, c.poolLogDevices, c.poolTopology,
+			},
+			read: c.fetchVdevs,
+			emit: c.collectVdevs,
+		}},
+		{name: CollectorSMART, unavailable: noCLI || c.smart == nil, partial: true, sub: &source[[]SmartDevice]{
+			descs: []*prometheus.Desc{c.vdevSmartHealthy, c.vdevTemperature, c.vdevReallocated},
+			read:  c.fetchSmart,
+			emit: func(ch chan<- prometheus.Metric, devices []SmartDevice, _ *collectState) {
+				c.collectSmartMetrics(ch, devices)
+			},
+		}},
+	}
+}
+
+// hostSubCollectors returns the registrations of the sub-collectors that
+// read the host rather than ZFS: systemd services and timers, Samba, and
+// iSCSI.
+func (c *Collector) hostSubCollectors() []registration {
+	return []registration{
+		{name: CollectorServices, sub: newSource(
+			[]*prometheus.Desc{c.serviceUp, c.serviceRestarts},
+			func(ctx context.Context) ([]host.ServiceStatus, error) {
+				return c.svcChecker.CheckServices(ctx, c.services)
+			},
+			c.collectServiceMetrics,
+		)},
+		{name: CollectorTimers, unavailable: len(c.timers) == 0, sub: newSource(
+			[]*prometheus.Desc{c.timerActive, c.timerLastTrigger, c.timerNextElapse},
+			func(ctx context.Context) ([]host.TimerStatus, error) {
+				return c.svcChecker.CheckTimers(ctx, c.timers)
+			},
+			c.collectTimerMetrics,
+		)},
+		{name: CollectorSMB, unavailable: c.smb == nil, sub: newSource(
+			[]*prometheus.Desc{c.smbSessions, c.smbOpenFiles, c.smbShareConnections},
+			func(ctx context.Context) (host.SMBStatus, error) { return c.smb.Read(ctx) },
+			c.collectSMBMetrics,
+		)},
+		{name: CollectorISCSI, unavailable: c.iscsi == nil, sub: newSource(
+			[]*prometheus.Desc{c.iscsiTargets, c.iscsiLUNs, c.iscsiSessions, c.iscsiLUNInfo},
+			func(ctx context.Context) (host.ISCSIStatus, error) { return c.iscsi.Read(ctx) },
+			c.collectISCSIMetrics,
+		)},
+	}
+}
+
+// kstatSubCollectors returns the registrations of the sub-collectors that
+// read global and per-pool kstats, independent of the backend.
+func (c *Collector) kstatSubCollectors() []registration {
+	noStats := c.stats == nil
+
+	named := func(kstatName string, metrics []kstatMetric) *source[map[string]uint64] {
+		return newSource(
+			kstatDescs(metrics),
+			func(context.Context) (map[string]uint64, error) { return c.stats.Named(kstatName) },
+			func(ch chan<- prometheus.Metric, values map[string]uint64) {
+				collectNamedKstat(ch, metrics, values)
+			},
+		)
+	}
+
+	l2arc := newSource(kstatDescs(c.l2arc), func(context.Context) (map[string]uint64, error) {
+		return c.stats.Named("arcstats")
+	}, c.collectL2ARCMetrics)
+
+	return []registration{
+		{name: CollectorL2ARC, unavailable: noStats, sub: l2arc},
+		{name: CollectorZIL, unavailable: noStats, sub: named("zil", c.zil)},
+		{name: CollectorZfetch, unavailable: noStats, sub: named("zfetchstats", c.zfetch)},
+		{name: CollectorDmuTx, unavailable: noStats, sub: named("dmu_tx", c.dmuTx)},
+		{name: CollectorTxg, unavailable: noStats, sub: newSource(
+			[]*prometheus.Desc{c.poolTxgs, c.poolTxgSyncSeconds, c.poolTxgDirtyBytes},
+			func(context.Context) ([]kstat.Txg, error) { return c.stats.Txgs() },
+			c.collectTxgMetrics,
+		)},
+		{name: CollectorSPL, unavailable: noStats, sub: newSource(
+			[]*prometheus.Desc{c.splCacheSize, c.splCacheAlloc, c.splCacheObjects},
+			func(context.Context) ([]kstat.SlabCache, error) { return c.stats.Slabs() },
+			c.collectSPLMetrics,
+		)},
+		{name: CollectorNodeCompat, unavailable: noStats, sub: newSource(
+			c.nodeCompatDescs(),
+			func(context.Context) (*nodeStats, error) { return c.readNodeStats() },
+			c.collectNodeCompatMetrics,
+		)},
+	}
+}
+
+// kstatDescs returns the descriptors of metrics.
+func kstatDescs(metrics []kstatMetric) []*prometheus.Desc {
+	descs := make([]*prometheus.Desc, len(metrics))
+	for i, m := range metrics {
+		descs[i] = m.desc
+	}
+
+	return descs
+}
+
+// describeSubs sends the descriptors of every registered sub-collector.
+func (c *Collector) describeSubs(ch chan<- *prometheus.Desc) {
+	for _, reg := range c.subs {
+		reg.sub.Describe(ch)
+	}
+}
+
+// fetchSubs fetches the required sub-collectors and then, if they all
+// succeeded, every enabled, available one concurrently, and returns the
+// results by name. Each fetch runs on its own deadline when the
+// registration has one, and on ctx's otherwise. Like a --timeout.zfs-list
+// command, a fetch with its own deadline is not cut short when ctx is
+// cancelled or expires, so a scrape can take as long as its slowest
+// sub-collector. Data of a failed fetch is only kept for partial
+// registrations.
+func (c *Collector) fetchSubs(ctx context.Context, enabled map[string]bool) map[string]subResult {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]subResult, len(c.subs))
+		f       = c.newFetchState()
+	)
+
+	fetch := func(ctx context.Context, reg *registration) {
+		data, err := reg.sub.Fetch(ctx, f)
+		if err != nil && !reg.partial {
+			data = nil
+		}
+
+		mu.Lock()
+		results[reg.name] = subResult{data: data, err: err}
+		mu.Unlock()
+	}
+
+	for i := range c.subs {
+		reg := &c.subs[i]
+		if !reg.required {
+			continue
+		}
+
+		subCtx, cancel := zfs.DeadlineContext(ctx, reg.timeout)
+		fetch(subCtx, reg)
+		cancel()
+
+		if results[reg.name].err != nil {
+			return results
+		}
+	}
+
+	// Contexts are made up front so that a command shared by several
+	// sub-collectors can run on the latest of their deadlines. Each lives
+	// until every fetch is done.
+	var (
+		ctxs    = make(map[string]context.Context, len(c.subs))
+		cancels []context.CancelFunc
+	)
+
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	for i := range c.subs {
+		reg := &c.subs[i]
+		if reg.required || !enabled[reg.name] || reg.unavailable {
+			continue
+		}
+
+		subCtx, cancel := zfs.DeadlineContext(ctx, reg.timeout)
+		ctxs[reg.name] = subCtx
+		cancels = append(cancels, cancel)
+	}
+
+	f.share(ctxs)
+
+	for i := range c.subs {
+		reg := &c.subs[i]
+
+		if subCtx, ok := ctxs[reg.name]; ok {
+			wg.Go(func() { fetch(subCtx, reg) })
+		}
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// collectSubs emits the metrics of each sub-collector fetched for this
+// scrape, in registration order, followed by zfs_dataset_series_truncated
+// when a sub-collector applied --dataset.max-series.
+func (c *Collector) collectSubs(ch chan<- prometheus.Metric, data *scrapeData) {
+	s := &collectState{results: data.subs, fetched: data.fetched}
+
+	for _, reg := range c.subs {
+		res, ok := data.subs[reg.name]
+		if !ok {
+			continue
+		}
+
+		if res.err != nil {
+			c.logSubError(reg.name, res.err)
+		}
+
+		if res.err == nil || reg.partial {
+			reg.sub.Collect(ch, res.data, s)
+		}
+	}
+
+	if s.limitReported {
+		ch <- prometheus.MustNewConstMetric(c.datasetsLimited, prometheus.GaugeValue, boolToFloat(s.limitTruncated))
+	}
+}
+
+// logSubError logs a failed sub-collector fetch. A missing kstat (no zfs
+// module, non-Linux host) is expected and only logged at debug level.
+func (c *Collector) logSubError(name string, err error) {
+	if errors.Is(err, kstat.ErrNoKstat) {
+		c.logger.Debug("kstat not available", "collector", name, "err", err)
+		return
+	}
+
+	c.logger.Warn("Sub-collector fetch failed", "collector", name, "err", err)
+}
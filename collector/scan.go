@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// collectScanMetrics emits the state and progress of each pool's current
+// scrub or resilver and the results of the last completed ones.
+func (c *Collector) collectScanMetrics(ch chan<- prometheus.Metric, scans []zfs.ScanStatus) {
+	for i := range scans {
+		s := &scans[i]
+
+		scrub := 0.0
+		if s.Scrub {
+			scrub = 1.0
+		}
+
+		resilver := 0.0
+		if s.Resilver {
+			resilver = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.poolScrubActive, prometheus.GaugeValue, scrub, s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolResilverActive, prometheus.GaugeValue, resilver, s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScanProgress, prometheus.GaugeValue, s.Progress, s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScrubPaused, prometheus.GaugeValue, boolToFloat(s.Paused), s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScanDeferred, prometheus.GaugeValue, boolToFloat(s.Deferred), s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolResilverQueue, prometheus.GaugeValue, float64(s.AwaitingResilver), s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScanScanned, prometheus.GaugeValue, float64(s.ScannedBytes), s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScanIssued, prometheus.GaugeValue, float64(s.IssuedBytes), s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScanTotal, prometheus.GaugeValue, float64(s.TotalBytes), s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScanRate, prometheus.GaugeValue, s.Rate, s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolScanETA, prometheus.GaugeValue, s.ETA.Seconds(), s.Pool)
+
+		ch <- prometheus.MustNewConstMetric(c.poolDataErrors, prometheus.GaugeValue, float64(s.DataErrors), s.Pool)
+
+		for _, code := range zfs.StatusCodes {
+			ch <- prometheus.MustNewConstMetric(c.poolStatusCode, prometheus.GaugeValue, boolToFloat(code == s.StatusCode), s.Pool, code)
+		}
+
+		c.collectLastScan(ch, s.Pool, &s.LastScan)
+	}
+}
+
+// collectLastScan emits the completion metrics of the pool's most recent
+// scrub or resilver, if one has finished. The scrub age is taken at collect
+// time, so it keeps growing while the scan data is served from the cache.
+func (c *Collector) collectLastScan(ch chan<- prometheus.Metric, pool string, last *zfs.CompletedScan) {
+	end := float64(last.End.Unix())
+	duration := last.Duration.Seconds()
+
+	switch last.Function {
+	case "scrub":
+		ch <- prometheus.MustNewConstMetric(c.poolLastScrubTime, prometheus.GaugeValue, end, pool)
+		ch <- prometheus.MustNewConstMetric(c.poolLastScrubDuration, prometheus.GaugeValue, duration, pool)
+		ch <- prometheus.MustNewConstMetric(c.poolLastScrubRepaired, prometheus.GaugeValue, float64(last.Repaired), pool)
+		ch <- prometheus.MustNewConstMetric(c.poolLastScrubErrors, prometheus.GaugeValue, float64(last.Errors), pool)
+		ch <- prometheus.MustNewConstMetric(c.poolLastScrubAge, prometheus.GaugeValue, time.Since(last.End).Seconds(), pool)
+	case "resilver":
+		ch <- prometheus.MustNewConstMetric(c.poolLastResilverTime, prometheus.GaugeValue, end, pool)
+		ch <- prometheus.MustNewConstMetric(c.poolLastResilverDuration, prometheus.GaugeValue, duration, pool)
+	}
+}
@@ -0,0 +1,24 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+)
+
+// collectServiceMetrics emits whether each monitored systemd unit is active
+// and how often it has restarted.
+func (c *Collector) collectServiceMetrics(ch chan<- prometheus.Metric, svcs []host.ServiceStatus) {
+	for _, s := range svcs {
+		val := 0.0
+		if s.Active {
+			val = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.serviceUp, prometheus.GaugeValue, val, s.Name)
+
+		if s.HasRestarts {
+			ch <- prometheus.MustNewConstMetric(c.serviceRestarts, prometheus.CounterValue, float64(s.Restarts), s.Name)
+		}
+	}
+}
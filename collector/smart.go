@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// smartParallelism caps concurrent smartctl processes in one scrape.
+const smartParallelism = 8
+
+// SmartDevice is the SMART status of one pool member device.
+type SmartDevice struct {
+	Pool   string           `json:"pool"`
+	Vdev   string           `json:"vdev"`
+	Device string           `json:"device"`
+	Path   string           `json:"path"`
+	Status host.SmartStatus `json:"status"`
+}
+
+// fetchSmart reads SMART data for the devices of the shared device list.
+func (c *Collector) fetchSmart(ctx context.Context, f *fetchState) ([]SmartDevice, error) {
+	l, err := f.vdevs.get(ctx)
+
+	return c.readSmart(ctx, l.vdevs, err)
+}
+
+// readSmart reads SMART data for every device in vdevs that passes the pool
+// filter. Devices that do not resolve to a disk (file vdevs) and disks in
+// standby are skipped; each disk is read once even if several partitions
+// of it are pool members. Devices read before a failure are still returned.
+func (c *Collector) readSmart(ctx context.Context, vdevs []zfs.VdevStatus, vdevErr error) ([]SmartDevice, error) {
+	if vdevErr != nil {
+		return nil, vdevErr
+	}
+
+	var devices []SmartDevice
+
+	paths := make(map[string]bool)
+
+	for _, v := range c.filterVdevs(vdevs) {
+		path, ok := c.smart.DevicePath(v.Device)
+		if !ok {
+			continue
+		}
+
+		devices = append(devices, SmartDevice{Pool: v.Pool, Vdev: v.Vdev, Device: v.Device, Path: path})
+		paths[path] = true
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		errs     []error
+		statuses = make(map[string]host.SmartStatus, len(paths))
+		sem      = make(chan struct{}, smartParallelism)
+	)
+
+	for path := range paths {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			st, err := c.smart.Read(ctx, path)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case errors.Is(err, host.ErrSmartStandby):
+			case err != nil:
+				errs = append(errs, err)
+			default:
+				statuses[path] = st
+			}
+		})
+	}
+
+	wg.Wait()
+
+	read := devices[:0]
+
+	for _, d := range devices {
+		if st, ok := statuses[d.Path]; ok {
+			d.Status = st
+			read = append(read, d)
+		}
+	}
+
+	return read, errors.Join(errs...)
+}
+
+// collectSmartMetrics emits the SMART health, temperature, and reallocated
+// sector count of each device, where the disk reports them.
+func (c *Collector) collectSmartMetrics(ch chan<- prometheus.Metric, devices []SmartDevice) {
+	for _, d := range devices {
+		st := d.Status
+
+		if st.HasPassed {
+			ch <- prometheus.MustNewConstMetric(c.vdevSmartHealthy, prometheus.GaugeValue, boolToFloat(st.Passed), d.Pool, d.Vdev, d.Device)
+		}
+
+		if st.HasTemperature {
+			ch <- prometheus.MustNewConstMetric(c.vdevTemperature, prometheus.GaugeValue, st.Temperature, d.Pool, d.Vdev, d.Device)
+		}
+
+		if st.HasReallocated {
+			ch <- prometheus.MustNewConstMetric(c.vdevReallocated, prometheus.GaugeValue, float64(st.Reallocated), d.Pool, d.Vdev, d.Device)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+)
+
+// collectSMBMetrics emits the Samba session and open file counts and the
+// connections to each share.
+func (c *Collector) collectSMBMetrics(ch chan<- prometheus.Metric, st host.SMBStatus) {
+	ch <- prometheus.MustNewConstMetric(c.smbSessions, prometheus.GaugeValue, float64(st.Sessions))
+	ch <- prometheus.MustNewConstMetric(c.smbOpenFiles, prometheus.GaugeValue, float64(st.OpenFiles))
+
+	for _, s := range st.Shares {
+		ch <- prometheus.MustNewConstMetric(c.smbShareConnections, prometheus.GaugeValue, float64(s.Connections), s.Name)
+	}
+}
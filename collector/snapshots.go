@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// snapshotState is what the snapshot collector reads: the per-dataset
+// snapshot counts and whether the listing was cut short.
+type snapshotState struct {
+	Counts    []zfs.SnapshotCount `json:"counts,omitempty"`
+	Truncated bool                `json:"truncated"`
+}
+
+// fetchSnapshots counts the snapshots of the listed pools.
+func (c *Collector) fetchSnapshots(ctx context.Context, f *fetchState) (snapshotState, error) {
+	counts, truncated, err := c.client.GetSnapshotCounts(ctx, f.pools)
+
+	return snapshotState{Counts: counts, Truncated: truncated}, err
+}
+
+// collectSnapshots emits the snapshot counts of the datasets left by
+// --dataset.max-series, or of the datasets with the most snapshots when no
+// dataset series were listed.
+func (c *Collector) collectSnapshots(ch chan<- prometheus.Metric, st snapshotState, s *collectState) {
+	snaps, cut := c.limitSnapshotCounts(c.filterSnapshots(st.Counts), c.datasetAllowlist(s))
+
+	s.limitTruncated = s.limitTruncated || cut
+	s.limitReported = true
+
+	c.collectSnapshotMetrics(ch, snaps, st.Truncated)
+}
+
+// collectSnapshotPolicy emits the retention class metrics of the datasets
+// left by --dataset.max-series, as of the time of the fetch.
+func (c *Collector) collectSnapshotPolicy(ch chan<- prometheus.Metric, snaps []zfs.Snapshot, s *collectState) {
+	c.collectSnapshotPolicyMetrics(ch, snaps, c.datasetAllowlist(s), s.fetched)
+}
+
+// collectSnapshotMetrics emits the per-dataset snapshot and hold counts, when
+// the newest snapshot was taken, and whether the counts were cut short.
+func (c *Collector) collectSnapshotMetrics(ch chan<- prometheus.Metric, snaps []zfs.SnapshotCount, truncated bool) {
+	for _, s := range snaps {
+		ch <- prometheus.MustNewConstMetric(c.snapshotCount, prometheus.GaugeValue, float64(s.Count), s.Dataset, s.Pool)
+		ch <- prometheus.MustNewConstMetric(c.snapshotHolds, prometheus.GaugeValue, float64(s.Holds), s.Dataset, s.Pool)
+
+		if !s.Newest.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.snapshotNewest, prometheus.GaugeValue, float64(s.Newest.Unix()), s.Dataset, s.Pool)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.snapshotsTruncated, prometheus.GaugeValue, boolToFloat(truncated))
+}
+
+// policyKey identifies one retention class of one dataset.
+type policyKey struct {
+	dataset string
+	class   int // index into c.snapPolicies
+}
+
+// policyState is what is known about one retention class of one dataset.
+type policyState struct {
+	pool   string
+	count  int
+	newest time.Time
+}
+
+// collectSnapshotPolicyMetrics emits, for each dataset with at least one
+// snapshot of a retention class, how many it has and whether the newest was
+// taken within the class's maximum age of now. A snapshot matching several
+// classes counts towards each. When allowed is not nil, only the datasets in
+// it are exported.
+func (c *Collector) collectSnapshotPolicyMetrics(ch chan<- prometheus.Metric, snaps []zfs.Snapshot, allowed map[string]bool, now time.Time) {
+	var (
+		keys   []policyKey
+		states = make(map[policyKey]*policyState)
+	)
+
+	for _, s := range snaps {
+		if !c.poolFilter.match(s.Pool) || !c.datasetFilter.match(s.Dataset) || (allowed != nil && !allowed[s.Dataset]) {
+			continue
+		}
+
+		for i := range c.snapPolicies {
+			if !c.snapPolicies[i].Match(s.Name) {
+				continue
+			}
+
+			k := policyKey{dataset: s.Dataset, class: i}
+
+			st, ok := states[k]
+			if !ok {
+				st = &policyState{pool: s.Pool}
+				states[k] = st
+				keys = append(keys, k)
+			}
+
+			st.count++
+			if s.Created.After(st.newest) {
+				st.newest = s.Created
+			}
+		}
+	}
+
+	for _, k := range keys {
+		st, p := states[k], &c.snapPolicies[k.class]
+		compliant := now.Sub(st.newest) <= p.MaxAge
+
+		ch <- prometheus.MustNewConstMetric(c.snapshotPolicyCount, prometheus.GaugeValue, float64(st.count), k.dataset, st.pool, p.Class)
+		ch <- prometheus.MustNewConstMetric(c.snapshotPolicyCompliant, prometheus.GaugeValue, boolToFloat(compliant), k.dataset, st.pool, p.Class)
+	}
+}
@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+)
+
+// initSPLDescriptors builds the zfs_spl_kmem_cache_* metrics from the SPL
+// kmem cache table. This is ZFS kernel memory outside the ARC: znodes,
+// dnodes, zios, and the other caches the module allocates from.
+func (c *Collector) initSPLDescriptors() {
+	labels := []string{"cache"}
+
+	c.splCacheSize = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "spl", "kmem_cache_size_bytes"),
+		"Memory held by an SPL kmem cache's slabs. Absent for caches backed by a Linux slab cache, which /proc/slabinfo accounts.",
+		labels, nil,
+	)
+	c.splCacheAlloc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "spl", "kmem_cache_alloc_bytes"),
+		"Memory allocated to objects in an SPL kmem cache.",
+		labels, nil,
+	)
+	c.splCacheObjects = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "spl", "kmem_cache_objects"),
+		"Objects allocated from an SPL kmem cache.",
+		labels, nil,
+	)
+}
+
+// collectSPLMetrics emits the memory use of each SPL kmem cache.
+func (c *Collector) collectSPLMetrics(ch chan<- prometheus.Metric, caches []kstat.SlabCache) {
+	for _, s := range caches {
+		if !s.LinuxSlab {
+			ch <- prometheus.MustNewConstMetric(c.splCacheSize, prometheus.GaugeValue, float64(s.Size), s.Name)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.splCacheAlloc, prometheus.GaugeValue, float64(s.Alloc), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.splCacheObjects, prometheus.GaugeValue, float64(s.Objects), s.Name)
+	}
+}
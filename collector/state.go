@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// fetchState is what the sub-collectors of one fetch share.
+type fetchState struct {
+	// pools are the names of the pools that pass the pool filter. The pool
+	// listing sets them before any other sub-collector is fetched.
+	pools []string
+
+	// datasets is the zfs list read by the dataset, dataset_histogram, and
+	// dataset_tuning collectors; vdevs is the zpool status device list read
+	// by the vdev and smart collectors.
+	datasets shared[[]zfs.Dataset]
+	vdevs    shared[vdevList]
+}
+
+// newFetchState returns the state of a new fetch.
+func (c *Collector) newFetchState() *fetchState {
+	f := &fetchState{}
+
+	f.datasets.run = func(ctx context.Context) ([]zfs.Dataset, error) {
+		return c.client.GetDatasets(ctx)
+	}
+	f.vdevs.run = func(ctx context.Context) (vdevList, error) {
+		return c.readVdevList(ctx, f.pools)
+	}
+
+	return f
+}
+
+// share gives each shared command the context, among ctxs, of the reader
+// with the latest deadline, so that no reader's own timeout is cut short by
+// another's.
+func (f *fetchState) share(ctxs map[string]context.Context) {
+	f.datasets.ctx = latest(ctxs, CollectorDatasets, CollectorDatasetHistogram, CollectorDatasetTuning)
+	f.vdevs.ctx = latest(ctxs, CollectorVdev, CollectorSMART)
+}
+
+// latest returns the context of the named sub-collectors with the latest
+// deadline, or nil if none of them is in ctxs. A context without a deadline
+// is the latest.
+func latest(ctxs map[string]context.Context, names ...string) context.Context {
+	var best context.Context
+
+	for _, name := range names {
+		ctx, ok := ctxs[name]
+		if !ok {
+			continue
+		}
+
+		if best == nil {
+			best = ctx
+			continue
+		}
+
+		deadline, ok := ctx.Deadline()
+		bestDeadline, bestOK := best.Deadline()
+
+		if !ok || (bestOK && deadline.After(bestDeadline)) {
+			best = ctx
+		}
+	}
+
+	return best
+}
+
+// shared is a command whose output several sub-collectors read. It runs at
+// most once per fetch, on ctx, when the first reader asks for it; each
+// reader waits for it only until its own context is done.
+type shared[T any] struct {
+	ctx context.Context
+	run func(ctx context.Context) (T, error)
+
+	once sync.Once
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// get returns the command's output, starting the command if no reader has
+// yet. A failed command may still return partial output.
+func (s *shared[T]) get(ctx context.Context) (T, error) {
+	s.once.Do(func() {
+		s.done = make(chan struct{})
+
+		go func() {
+			s.val, s.err = s.run(s.ctx)
+			close(s.done)
+		}()
+	})
+
+	select {
+	case <-s.done:
+		return s.val, s.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// collectState is what the sub-collectors of one Collect share: the results
+// of every fetch, and the --dataset.max-series limit that applies across
+// the dataset, dataset_tuning, snapshot, and snapshot_policy series.
+type collectState struct {
+	results map[string]subResult
+	fetched time.Time
+
+	// Set by limitedDatasets and the sub-collectors that apply the limit.
+	limited        []zfs.Dataset
+	limitAllowed   map[string]bool // datasets left by the limit, nil if none were dropped
+	limitDone      bool
+	limitTruncated bool // some per-dataset series were dropped
+	limitReported  bool // zfs_dataset_series_truncated is emitted
+}
+
+// result returns the data the named sub-collector fetched in this scrape,
+// or false if it was not fetched or failed without partial data.
+func result[T any](s *collectState, name string) (T, bool) {
+	v, ok := s.results[name].data.(T)
+	return v, ok
+}
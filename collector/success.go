@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// collectorPool is the name of the required pool listing, which is not a
+// toggleable sub-collector.
+const collectorPool = "pool"
+
+// collectSuccessMetrics emits zfs_scrape_collector_success for the pool
+// listing and each sub-collector that was fetched, so a failing optional
+// fetch is visible rather than just making its series disappear. A failed
+// fetch also emits zfs_scrape_collector_failure with the reason classified
+// by zfs.Reason. Custom hooks also report their own zfs_custom_success.
+func (c *Collector) collectSuccessMetrics(ch chan<- prometheus.Metric, data *scrapeData) {
+	for _, reg := range c.subs {
+		res, ok := data.subs[reg.name]
+		if !ok {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.collectorOK, prometheus.GaugeValue, boolToFloat(res.err == nil), reg.name)
+
+		if res.err != nil {
+			ch <- prometheus.MustNewConstMetric(c.collectorFail, prometheus.GaugeValue, 1, reg.name, zfs.Reason(res.err))
+		}
+	}
+}
+
+// errorSubsystems are the zfs_scrape_errors_total subsystems, each exported
+// from the start so that increase() sees the first failure.
+var errorSubsystems = []string{collectorPool, CollectorDatasets, CollectorScan, CollectorServices}
+
+// errorCounts counts failed fetches per subsystem for zfs_scrape_errors_total.
+type errorCounts struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func newErrorCounts() *errorCounts {
+	counts := make(map[string]uint64, len(errorSubsystems))
+	for _, s := range errorSubsystems {
+		counts[s] = 0
+	}
+
+	return &errorCounts{counts: counts}
+}
+
+func (e *errorCounts) add(subsystem string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.counts[subsystem]++
+}
+
+func (e *errorCounts) get(subsystem string) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.counts[subsystem]
+}
+
+// countFetchErrors counts the failures of one fetch for
+// zfs_scrape_errors_total. It runs once per fetch, so scrapes served from
+// the cache or sharing a concurrent fetch do not count a failure again.
+// Unlike zfs_scrape_collector_success, failures between two Prometheus
+// scrapes (e.g. from a second scraper) are not lost.
+func (c *Collector) countFetchErrors(data *scrapeData) {
+	for _, s := range errorSubsystems {
+		if res, ok := data.subs[s]; ok && res.err != nil {
+			c.fetchErrors.add(s)
+		}
+	}
+}
+
+// collectErrorCounts emits zfs_scrape_errors_total.
+func (c *Collector) collectErrorCounts(ch chan<- prometheus.Metric) {
+	for _, s := range errorSubsystems {
+		ch <- prometheus.MustNewConstMetric(c.scrapeErrors, prometheus.CounterValue, float64(c.fetchErrors.get(s)), s)
+	}
+}
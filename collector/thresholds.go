@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// thresholdKinds maps the user property suffix (after the configured prefix)
+// to a parser for its value. Properties listed here are exported through
+// zfs_dataset_threshold rather than as labels.
+var thresholdKinds = map[string]func(string) (float64, error){
+	"quota_warn_ratio": parseRatio,
+	"quota_crit_ratio": parseRatio,
+	"snapshot_max_age": parseAge,
+}
+
+// isThresholdProperty reports whether prop (with prefix) is a threshold.
+func isThresholdProperty(prop, prefix string) bool {
+	_, ok := thresholdKinds[strings.TrimPrefix(prop, prefix)]
+	return ok
+}
+
+// collectThresholdMetrics emits one zfs_dataset_threshold series per dataset
+// and threshold property. Invalid values are logged and skipped.
+func (c *Collector) collectThresholdMetrics(ch chan<- prometheus.Metric, datasets []zfs.Dataset, props zfs.UserProperties) {
+	for _, d := range datasets {
+		for prop, raw := range props[d.Name] {
+			kind := strings.TrimPrefix(prop, c.userPropPrefix)
+
+			parse, ok := thresholdKinds[kind]
+			if !ok {
+				continue
+			}
+
+			val, err := parse(raw)
+			if err != nil {
+				c.logger.Warn("Invalid threshold property", "dataset", d.Name, "property", prop, "err", err)
+				continue
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.datasetThreshold, prometheus.GaugeValue, val, d.Name, d.Type, d.Pool, kind)
+		}
+	}
+}
+
+// parseRatio parses a 0-1 ratio. A trailing "%" is accepted ("80%" -> 0.8).
+func parseRatio(s string) (float64, error) {
+	pct, isPct := strings.CutSuffix(s, "%")
+
+	v, err := strconv.ParseFloat(pct, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ratio %q: %w", s, err)
+	}
+
+	if isPct {
+		v /= 100
+	}
+
+	if v < 0 || v > 1 {
+		return 0, fmt.Errorf("ratio %q out of range 0-1", s)
+	}
+
+	return v, nil
+}
+
+// parseAge parses a positive age into seconds. Accepts plain seconds
+// ("3600"), Go durations ("36h"), and day/week suffixes ("7d", "2w").
+func parseAge(s string) (float64, error) {
+	v, err := parseSeconds(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if v <= 0 || math.IsNaN(v) || math.IsInf(v, 1) {
+		return 0, fmt.Errorf("age %q must be positive", s)
+	}
+
+	return v, nil
+}
+
+// parseSeconds parses the forms accepted by parseAge without checking the
+// sign.
+func parseSeconds(s string) (float64, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q: %w", s, err)
+			}
+
+			return v * unit.Seconds(), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q: %w", s, err)
+	}
+
+	return d.Seconds(), nil
+}
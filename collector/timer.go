@@ -0,0 +1,23 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+)
+
+// collectTimerMetrics emits whether each systemd timer is scheduled and when
+// it last and next elapses, where known.
+func (c *Collector) collectTimerMetrics(ch chan<- prometheus.Metric, timers []host.TimerStatus) {
+	for _, t := range timers {
+		ch <- prometheus.MustNewConstMetric(c.timerActive, prometheus.GaugeValue, boolToFloat(t.Active), t.Name)
+
+		if !t.LastTrigger.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.timerLastTrigger, prometheus.GaugeValue, float64(t.LastTrigger.Unix()), t.Name)
+		}
+
+		if !t.NextElapse.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.timerNextElapse, prometheus.GaugeValue, float64(t.NextElapse.Unix()), t.Name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// Names of the optional sub-collectors that can be switched on and off. Pool
+// metrics are required (they drive zfs_up) and cannot be disabled.
+const (
+	CollectorPoolProperties   = "pool_properties"
+	CollectorDatasets         = "dataset"
+	CollectorDatasetHistogram = "dataset_histogram"
+	CollectorDatasetTuning    = "dataset_tuning"
+	CollectorSnapshots        = "snapshot"
+	CollectorSnapshotPolicy   = "snapshot_policy"
+	CollectorNFS              = "nfs"
+	CollectorUserspace        = "userspace"
+	CollectorHistory          = "history"
+	CollectorLatency          = "latency"
+	CollectorScan             = "scan"
+	CollectorVdev             = "vdev"
+	CollectorSMART            = "smart"
+	CollectorServices         = "service"
+	CollectorTimers           = "timer"
+	CollectorSMB              = "smb"
+	CollectorISCSI            = "iscsi"
+	CollectorL2ARC            = "l2arc"
+	CollectorZIL              = "zil"
+	CollectorZfetch           = "zfetch"
+	CollectorDmuTx            = "dmu_tx"
+	CollectorTxg              = "txg"
+	CollectorSPL              = "spl"
+	CollectorNodeCompat       = "node_compat"
+	CollectorCustom           = "custom"
+)
+
+// CollectorNames lists every toggleable sub-collector in a stable order.
+var CollectorNames = []string{
+	CollectorPoolProperties,
+	CollectorDatasets,
+	CollectorDatasetHistogram,
+	CollectorDatasetTuning,
+	CollectorSnapshots,
+	CollectorSnapshotPolicy,
+	CollectorNFS,
+	CollectorUserspace,
+	CollectorHistory,
+	CollectorLatency,
+	CollectorScan,
+	CollectorVdev,
+	CollectorSMART,
+	CollectorServices,
+	CollectorTimers,
+	CollectorSMB,
+	CollectorISCSI,
+	CollectorL2ARC,
+	CollectorZIL,
+	CollectorZfetch,
+	CollectorDmuTx,
+	CollectorTxg,
+	CollectorSPL,
+	CollectorNodeCompat,
+	CollectorCustom,
+}
+
+// defaultDisabled lists sub-collectors that are off unless explicitly enabled.
+var defaultDisabled = map[string]bool{
+	CollectorDatasetHistogram: true,
+	CollectorDatasetTuning:    true,
+	CollectorNFS:              true,
+	CollectorHistory:          true,
+	CollectorLatency:          true,
+	CollectorSPL:              true,
+	CollectorNodeCompat:       true,
+}
+
+// ErrUnknownCollector is returned when toggling a collector name that does
+// not exist.
+var ErrUnknownCollector = errors.New("unknown collector")
+
+// toggles holds the runtime enabled state of each sub-collector alongside the
+// state configured at startup, so a reload can restore the latter.
+type toggles struct {
+	mu       sync.RWMutex
+	defaults map[string]bool
+	current  map[string]bool
+}
+
+// newToggles builds toggles from the startup configuration. Names missing
+// from cfg (or a nil cfg) fall back to their built-in default: enabled,
+// unless listed in defaultDisabled.
+func newToggles(cfg map[string]bool) *toggles {
+	defaults := make(map[string]bool, len(CollectorNames))
+
+	for _, name := range CollectorNames {
+		enabled, ok := cfg[name]
+		if !ok {
+			enabled = !defaultDisabled[name]
+		}
+
+		defaults[name] = enabled
+	}
+
+	return &toggles{defaults: defaults, current: maps.Clone(defaults)}
+}
+
+func (t *toggles) snapshot() map[string]bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return maps.Clone(t.current)
+}
+
+// SetEnabled switches a sub-collector on or off for subsequent scrapes.
+func (c *Collector) SetEnabled(name string, enabled bool) error {
+	t := c.toggles
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.current[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownCollector, name)
+	}
+
+	t.current[name] = enabled
+
+	return nil
+}
+
+// ResetEnabled restores every sub-collector to its startup configuration.
+func (c *Collector) ResetEnabled() {
+	t := c.toggles
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.current = maps.Clone(t.defaults)
+}
+
+// EnabledCollectors returns the current enabled state of each sub-collector.
+func (c *Collector) EnabledCollectors() map[string]bool {
+	return c.toggles.snapshot()
+}
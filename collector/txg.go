@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+)
+
+// initTxgDescriptors builds the per-pool transaction group metrics from each
+// pool's txgs kstat. Every few seconds the open txg is quiesced and its dirty
+// data synced to disk; a sync that takes longer than zfs_txg_timeout means
+// the pool cannot keep up with its write load, which capacity metrics never
+// show.
+func (c *Collector) initTxgDescriptors() {
+	labels := []string{"pool"}
+
+	c.poolTxgs = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "txgs_total"),
+		"Transaction groups synced over the pool's life (the last synced txg number).",
+		labels, nil,
+	)
+	c.poolTxgSyncSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "txg_sync_seconds"),
+		"Time the last synced transaction group spent syncing.",
+		labels, nil,
+	)
+	c.poolTxgDirtyBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "pool", "txg_dirty_bytes"),
+		"Dirty data carried by the last synced transaction group.",
+		labels, nil,
+	)
+}
+
+// collectTxgMetrics emits the last synced txg of each pool passing the pool
+// filter.
+func (c *Collector) collectTxgMetrics(ch chan<- prometheus.Metric, txgs []kstat.Txg) {
+	for _, t := range txgs {
+		if !c.poolFilter.match(t.Pool) {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.poolTxgs, prometheus.CounterValue, float64(t.Txg), t.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolTxgSyncSeconds, prometheus.GaugeValue, t.SyncTime.Seconds(), t.Pool)
+		ch <- prometheus.MustNewConstMetric(c.poolTxgDirtyBytes, prometheus.GaugeValue, float64(t.Dirty), t.Pool)
+	}
+}
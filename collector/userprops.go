@@ -0,0 +1,199 @@
+package collector
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// datasetDescs groups the per-dataset descriptors so they can be rebuilt with
+// extra user-property labels.
+type datasetDescs struct {
+	used              *prometheus.Desc
+	available         *prometheus.Desc
+	referenced        *prometheus.Desc
+	shareNFS          *prometheus.Desc
+	shareSMB          *prometheus.Desc
+	compress          *prometheus.Desc
+	logicalUsed       *prometheus.Desc
+	logicalReferenced *prometheus.Desc
+}
+
+// datasetDescCache memoizes datasetDescs keyed by their extra label names so a
+// stable set of user properties does not allocate new descriptors each scrape.
+type datasetDescCache struct {
+	mu      sync.Mutex
+	entries map[string]*datasetDescs
+}
+
+// userLabels describes the extra labels derived from ZFS user properties.
+type userLabels struct {
+	names []string          // sorted Prometheus label names
+	props map[string]string // label name -> full ZFS property name
+}
+
+// reservedDatasetLabels are the built-in dataset labels a user property must
+// not shadow.
+var reservedDatasetLabels = map[string]bool{"dataset": true, "type": true, "pool": true}
+
+// newUserLabels derives label names from every user property seen across all
+// datasets. Each property name has the prefix stripped and is sanitized into a
+// valid label name ("exporter:cost-center" -> "cost_center"). Properties that
+// would collide with a built-in label, names Prometheus reserves ("__"
+// prefix), and threshold properties (exported via zfs_dataset_threshold) are
+// ignored. Properties are taken in name order, so when two sanitize to the
+// same label ("cost-center", "cost_center") the same one wins every scrape.
+func newUserLabels(props zfs.UserProperties, prefix string) userLabels {
+	ul := userLabels{props: make(map[string]string)}
+
+	seen := make(map[string]bool)
+	for _, dsProps := range props {
+		for prop := range dsProps {
+			seen[prop] = true
+		}
+	}
+
+	for _, prop := range slices.Sorted(maps.Keys(seen)) {
+		if isThresholdProperty(prop, prefix) {
+			continue
+		}
+
+		label := sanitizeLabelName(strings.TrimPrefix(prop, prefix))
+		if label == "" || reservedDatasetLabels[label] || strings.HasPrefix(label, "__") {
+			continue
+		}
+
+		if _, ok := ul.props[label]; ok {
+			continue
+		}
+
+		ul.props[label] = prop
+		ul.names = append(ul.names, label)
+	}
+
+	slices.Sort(ul.names)
+
+	return ul
+}
+
+// values returns the label values for a dataset, in ul.names order. Datasets
+// without a property get an empty value.
+func (ul userLabels) values(props zfs.UserProperties, dataset string) []string {
+	vals := make([]string, len(ul.names))
+
+	for i, label := range ul.names {
+		vals[i] = props[dataset][ul.props[label]]
+	}
+
+	return vals
+}
+
+// sanitizeLabelName maps s onto the Prometheus label name charset
+// [a-zA-Z_][a-zA-Z0-9_]*.
+func sanitizeLabelName(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	var b strings.Builder
+
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	return b.String()
+}
+
+// datasetDescsFor returns the dataset descriptors with extra appended to the
+// standard dataset labels. With no extra labels the static descriptors built
+// in initDescriptors are returned.
+func (c *Collector) datasetDescsFor(extra []string) *datasetDescs {
+	if len(extra) == 0 {
+		return &c.dataset
+	}
+
+	key := strings.Join(extra, ",")
+
+	cache := &c.datasetDescCache
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if d, ok := cache.entries[key]; ok {
+		return d
+	}
+
+	d := newDatasetDescs(append([]string{"dataset", "type", "pool"}, extra...))
+
+	if cache.entries == nil {
+		cache.entries = make(map[string]*datasetDescs)
+	}
+
+	cache.entries[key] = d
+
+	return d
+}
+
+func newDatasetDescs(labels []string) *datasetDescs {
+	return &datasetDescs{
+		used: prometheus.NewDesc(prometheus.BuildFQName(namespace, "dataset", "used_bytes"), "Space consumed by dataset.", labels, nil),
+		available: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dataset", "available_bytes"),
+			"Space available to dataset.",
+			labels,
+			nil,
+		),
+		referenced: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dataset", "referenced_bytes"),
+			"Space referenced by dataset.",
+			labels,
+			nil,
+		),
+		shareNFS: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dataset", "share_nfs"),
+			"1 if NFS sharing is enabled, 0 otherwise.",
+			labels,
+			nil,
+		),
+		shareSMB: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dataset", "share_smb"),
+			"1 if SMB sharing is enabled, 0 otherwise.",
+			labels,
+			nil,
+		),
+		compress: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dataset", "compressratio"),
+			"Compression ratio achieved by the dataset (1 = uncompressed).",
+			labels,
+			nil,
+		),
+		logicalUsed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dataset", "logical_used_bytes"),
+			"Space consumed by dataset before compression.",
+			labels,
+			nil,
+		),
+		logicalReferenced: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "dataset", "logical_referenced_bytes"),
+			"Space referenced by dataset before compression.",
+			labels,
+			nil,
+		),
+	}
+}
@@ -0,0 +1,24 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// collectSpaceUsageMetrics emits per-user and per-group used bytes, and the
+// quota where one is set.
+func (c *Collector) collectSpaceUsageMetrics(ch chan<- prometheus.Metric, usage []zfs.SpaceUsage) {
+	for _, u := range usage {
+		used, quota := c.userUsed, c.userQuota
+		if u.Group {
+			used, quota = c.groupUsed, c.groupQuota
+		}
+
+		ch <- prometheus.MustNewConstMetric(used, prometheus.GaugeValue, float64(u.Used), u.Dataset, u.Pool, u.Name)
+
+		if u.Quota > 0 {
+			ch <- prometheus.MustNewConstMetric(quota, prometheus.GaugeValue, float64(u.Quota), u.Dataset, u.Pool, u.Name)
+		}
+	}
+}
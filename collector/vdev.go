@@ -0,0 +1,247 @@
+package collector
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// vdevList is the zpool status device list shared by the vdev and smart
+// collectors, with the identity of each device when devices are resolved.
+type vdevList struct {
+	vdevs []zfs.VdevStatus
+	ids   map[string]host.DeviceIdentity
+}
+
+// readVdevList reads the device status of pools and, with Options.Devices,
+// renames each device to its /dev/disk/by-id name. The pools that answered
+// are returned even if others failed.
+func (c *Collector) readVdevList(ctx context.Context, pools []string) (vdevList, error) {
+	vdevs, err := c.client.GetVdevStatuses(ctx, pools)
+
+	l := vdevList{vdevs: vdevs}
+	if c.devices != nil {
+		l.vdevs, l.ids = c.identifyVdevs(vdevs)
+	}
+
+	return l, err
+}
+
+// vdevState is what the vdev collector reads: the status of every device
+// and the capacity of each top-level vdev.
+type vdevState struct {
+	Vdevs      []zfs.VdevStatus               `json:"vdevs,omitempty"`
+	Capacities []zfs.VdevCapacity             `json:"capacities,omitempty"`
+	DeviceIDs  map[string]host.DeviceIdentity `json:"-"`
+}
+
+// fetchVdevs reads the shared device list and the vdev capacities
+// concurrently. What was read is returned even if the other command failed.
+func (c *Collector) fetchVdevs(ctx context.Context, f *fetchState) (vdevState, error) {
+	var (
+		st                vdevState
+		statusErr, capErr error
+		wg                sync.WaitGroup
+	)
+
+	wg.Go(func() {
+		var l vdevList
+
+		l, statusErr = f.vdevs.get(ctx)
+		st.Vdevs, st.DeviceIDs = l.vdevs, l.ids
+	})
+
+	wg.Go(func() {
+		st.Capacities, capErr = c.client.GetVdevCapacities(ctx)
+		if c.devices != nil {
+			st.Capacities = c.identifyVdevCapacities(st.Capacities)
+		}
+	})
+
+	wg.Wait()
+
+	return st, errors.Join(statusErr, capErr)
+}
+
+// collectVdevs emits the device, pool layout, and capacity metrics of the
+// pools that pass the pool filter.
+func (c *Collector) collectVdevs(ch chan<- prometheus.Metric, st vdevState, _ *collectState) {
+	vdevs := c.filterVdevs(st.Vdevs)
+
+	c.collectVdevMetrics(ch, vdevs)
+	c.collectVdevDevices(ch, vdevs, st.DeviceIDs)
+	c.collectVdevCounts(ch, vdevs)
+	c.collectPoolTopology(ch, vdevs)
+	c.collectVdevCapacities(ch, c.filterVdevCapacities(st.Capacities))
+}
+
+// collectVdevMetrics emits per-device error counters. They are gauges, not
+// counters: zpool clear resets them to zero.
+func (c *Collector) collectVdevMetrics(ch chan<- prometheus.Metric, vdevs []zfs.VdevStatus) {
+	for _, v := range vdevs {
+		// Spares have no counters and an AVAIL/INUSE state; they are only
+		// counted, by collectVdevCounts.
+		if v.Class == zfs.VdevClassSpare {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.vdevReadErrors, prometheus.GaugeValue, float64(v.ReadErrors), v.Pool, v.Vdev, v.Device)
+		ch <- prometheus.MustNewConstMetric(c.vdevWriteErrors, prometheus.GaugeValue, float64(v.WriteErrors), v.Pool, v.Vdev, v.Device)
+		ch <- prometheus.MustNewConstMetric(c.vdevChecksumErrors, prometheus.GaugeValue, float64(v.ChecksumErrors), v.Pool, v.Vdev, v.Device)
+
+		state := strings.ToLower(v.State)
+		for _, s := range vdevStates {
+			ch <- prometheus.MustNewConstMetric(c.vdevState, prometheus.GaugeValue, boolToFloat(s == state), v.Pool, v.Vdev, v.Device, s)
+		}
+	}
+}
+
+// collectVdevCapacities emits the capacity and fragmentation ratios of each
+// data-holding top-level vdev, so uneven fill after a vdev is added shows.
+func (c *Collector) collectVdevCapacities(ch chan<- prometheus.Metric, caps []zfs.VdevCapacity) {
+	for _, v := range caps {
+		if v.Size > 0 {
+			ch <- prometheus.MustNewConstMetric(c.vdevCapacity, prometheus.GaugeValue, float64(v.Allocated)/float64(v.Size), v.Pool, v.Vdev)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.vdevFragmentation, prometheus.GaugeValue, v.Fragmentation, v.Pool, v.Vdev)
+	}
+}
+
+// vdevCounts tallies the spare, cache, and log devices of one pool.
+type vdevCounts struct {
+	spares      int
+	sparesInUse int
+	cache       int
+	log         int
+}
+
+// collectVdevCounts emits per-pool counts of spare, cache, and log devices.
+// Every pool with at least one device gets a series, so a pool without
+// spares reports 0 rather than nothing.
+func (c *Collector) collectVdevCounts(ch chan<- prometheus.Metric, vdevs []zfs.VdevStatus) {
+	var pools []string
+
+	counts := make(map[string]*vdevCounts)
+
+	for i := range vdevs {
+		v := &vdevs[i]
+
+		n, ok := counts[v.Pool]
+		if !ok {
+			n = &vdevCounts{}
+			counts[v.Pool] = n
+			pools = append(pools, v.Pool)
+		}
+
+		switch v.Class {
+		case zfs.VdevClassSpare:
+			n.spares++
+
+			if v.State == "INUSE" {
+				n.sparesInUse++
+			}
+		case zfs.VdevClassCache:
+			n.cache++
+		case zfs.VdevClassLog:
+			n.log++
+		}
+	}
+
+	for _, pool := range pools {
+		n := counts[pool]
+		ch <- prometheus.MustNewConstMetric(c.poolSpares, prometheus.GaugeValue, float64(n.spares), pool)
+		ch <- prometheus.MustNewConstMetric(c.poolSparesInUse, prometheus.GaugeValue, float64(n.sparesInUse), pool)
+		ch <- prometheus.MustNewConstMetric(c.poolCacheDevices, prometheus.GaugeValue, float64(n.cache), pool)
+		ch <- prometheus.MustNewConstMetric(c.poolLogDevices, prometheus.GaugeValue, float64(n.log), pool)
+	}
+}
+
+// topVdevLayoutRe matches the names zpool status gives redundant top-level
+// vdevs, e.g. "mirror-0", "raidz2-1", or "draid2:4d:12c:1s-0".
+var topVdevLayoutRe = regexp.MustCompile(`^(mirror|raidz[123]?|draid[123]?)(?::\S*)?-\d+$`)
+
+// vdevLayout returns the redundancy layout of a top-level vdev. Single
+// disks, including one being replaced or spared, are "stripe".
+func vdevLayout(vdev string) string {
+	m := topVdevLayoutRe.FindStringSubmatch(vdev)
+
+	switch {
+	case m == nil:
+		return "stripe"
+	case m[1] == "raidz" || m[1] == "draid":
+		return m[1] + "1"
+	default:
+		return m[1]
+	}
+}
+
+// sameOrMixed returns next if it matches prev, or prev is unset, and
+// "mixed" otherwise.
+func sameOrMixed(prev, next string) string {
+	if prev == "" || prev == next {
+		return next
+	}
+
+	return "mixed"
+}
+
+// collectPoolTopology emits one zfs_pool_topology_info series per pool
+// from the top-level vdevs of its main tree; log, cache, spare, special, and
+// dedup vdevs hold no redundancy of the pool's data and are left out. A disk
+// being replaced or spared counts once, so the series is stable through a
+// resilver. Pools whose vdevs differ in layout or width report "mixed".
+func (c *Collector) collectPoolTopology(ch chan<- prometheus.Metric, vdevs []zfs.VdevStatus) {
+	var pools []string
+
+	// Children of each top-level vdev, by pool.
+	children := make(map[string]map[string]map[string]bool)
+
+	for i := range vdevs {
+		v := &vdevs[i]
+		if v.Class != "" {
+			continue
+		}
+
+		tops, ok := children[v.Pool]
+		if !ok {
+			tops = make(map[string]map[string]bool)
+			children[v.Pool] = tops
+			pools = append(pools, v.Pool)
+		}
+
+		if tops[v.Vdev] == nil {
+			tops[v.Vdev] = make(map[string]bool)
+		}
+
+		tops[v.Vdev][cmp.Or(v.Parent, v.Device)] = true
+	}
+
+	for _, pool := range pools {
+		var layout, disks string
+
+		for vdev, kids := range children[pool] {
+			l := vdevLayout(vdev)
+
+			n := len(kids)
+			if l == "stripe" {
+				n = 1
+			}
+
+			layout = sameOrMixed(layout, l)
+			disks = sameOrMixed(disks, strconv.Itoa(n))
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.poolTopology, prometheus.GaugeValue, 1,
+			pool, layout, strconv.Itoa(len(children[pool])), disks)
+	}
+}
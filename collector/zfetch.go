@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// initZfetchDescriptors builds the zfs_zfetch_* metrics from the zfetchstats
+// kstat. A read either continues one of the prefetcher's streams (a hit) or
+// matches none (a miss). OpenZFS 2.2 split hits by where the read landed
+// relative to the stream and added the I/O counts; older releases only
+// report hits, misses, and max_streams.
+func (c *Collector) initZfetchDescriptors() {
+	counter := func(name, key, help string) kstatMetric {
+		return newKstatMetric("zfetch", name, key, help, prometheus.CounterValue)
+	}
+
+	c.zfetch = []kstatMetric{
+		counter("hits_total", "hits", "Reads that continued a prefetch stream."),
+		counter("misses_total", "misses", "Reads that matched no prefetch stream."),
+		counter("max_streams_total", "max_streams", "Misses that could not start a stream because the per-file stream limit was reached."),
+		counter("future_total", "future", "Reads ahead of a stream's position, not yet prefetched."),
+		counter("stride_total", "stride", "Reads matching a strided access pattern."),
+		counter("past_total", "past", "Reads behind a stream's position, already prefetched."),
+		counter("io_issued_total", "io_issued", "Prefetch I/Os issued."),
+		newKstatMetric("zfetch", "io_active", "io_active", "Prefetch I/Os in flight.", prometheus.GaugeValue),
+	}
+}
@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// initZILDescriptors builds the zfs_zil_* counters from the zil kstat. An
+// itx is an intent-log transaction; "slog" counts those written to separate
+// log devices and "normal" those written to the main pool.
+func (c *Collector) initZILDescriptors() {
+	counter := func(name, key, help string) kstatMetric {
+		return newKstatMetric("zil", name, key, help, prometheus.CounterValue)
+	}
+
+	c.zil = []kstatMetric{
+		counter("commits_total", "zil_commit_count", "ZIL commits (fsync, O_SYNC writes, sync=always)."),
+		counter("commit_writers_total", "zil_commit_writer_count", "ZIL commits that issued log writes rather than waiting on another commit."),
+		counter("itx_total", "zil_itx_count", "Intent-log transactions created."),
+		counter("itx_indirect_total", "zil_itx_indirect_count", "Write itxs logged by reference to a block written in place."),
+		counter("itx_indirect_bytes_total", "zil_itx_indirect_bytes", "Bytes of write itxs logged by reference."),
+		counter("itx_copied_total", "zil_itx_copied_count", "Write itxs whose data was copied into the log record."),
+		counter("itx_copied_bytes_total", "zil_itx_copied_bytes", "Bytes of write itxs copied into the log record."),
+		counter("itx_needcopy_total", "zil_itx_needcopy_count", "Write itxs whose data was copied at commit time."),
+		counter("itx_needcopy_bytes_total", "zil_itx_needcopy_bytes", "Bytes of write itxs copied at commit time."),
+		counter("itx_normal_total", "zil_itx_metaslab_normal_count", "Log blocks written to the main pool."),
+		counter("itx_normal_bytes_total", "zil_itx_metaslab_normal_bytes", "Bytes of log blocks written to the main pool."),
+		counter("itx_slog_total", "zil_itx_metaslab_slog_count", "Log blocks written to separate log (SLOG) devices."),
+		counter("itx_slog_bytes_total", "zil_itx_metaslab_slog_bytes", "Bytes of log blocks written to separate log (SLOG) devices."),
+	}
+}
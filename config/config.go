@@ -3,96 +3,595 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
+	"path"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"go.yaml.in/yaml/v2"
+
+	"github.com/donaldgifford/zfs_exporter/collector"
+	"github.com/donaldgifford/zfs_exporter/exporter"
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// Supported --zfs.backend values.
+const (
+	BackendCLI   = "cli"
+	BackendKstat = "kstat"
 )
 
 // Config holds all exporter configuration.
 type Config struct {
+	// ConfigFile is an optional YAML file of flag settings (see LoadFile).
+	ConfigFile string
+	app        *kingpin.Application
+	setByUser  map[string]*bool
+
+	// Dump runs one collection, prints it to stdout, and exits.
+	Dump bool
+
 	ListenAddress string
 	MetricsPath   string
-	LogLevel      string
-	ScrapeTimeout time.Duration
-	ZpoolPath     string
-	ZfsPath       string
-	Services      []string
-	servicesRaw   string
+	// HostnameLabel, if set, is the name of a label carrying the host name
+	// (or probe target name) added to every exporter metric.
+	HostnameLabel string
+	// MetricsRenameFile is a YAML map of metric names to the names served
+	// instead. MetricRenames is populated from it by Validate.
+	MetricsRenameFile string
+	MetricRenames     map[string]string
+	// CompatPDF also serves the metric names and shapes of pdf/zfs_exporter.
+	CompatPDF bool
+	// CompatNode enables the node_compat collector, which serves the
+	// node_zfs_* metrics of node_exporter's zfs collector.
+	CompatNode bool
+	// WebMaxRequests, WebTimeout, and WebDisableCompression configure the
+	// /metrics handler.
+	WebMaxRequests        int
+	WebTimeout            time.Duration
+	WebDisableCompression bool
+	LogLevel              string
+	ScrapeTimeout         time.Duration
+	// CommandTimeouts give zpool, zfs list, and systemctl their own
+	// deadlines instead of the --scrape.timeout budget.
+	CommandTimeouts zfs.CommandTimeouts
+	ScrapeCacheTTL  time.Duration
+	ZpoolPath       string
+	ZfsPath         string
+	ZfsJSON         bool
+	// ZfsSudo runs zpool, zfs, and systemctl through SudoCommand (sudo or
+	// doas) so the exporter can run as an unprivileged user.
+	ZfsSudo     bool
+	SudoCommand string
+	Backend     string
+	KstatPath   string
+	Services    []string
+	servicesRaw string
+	HostInit    string
+
+	// Timers are systemd timer units (or globs) whose schedules are
+	// exported. Populated by Validate.
+	Timers    []string
+	timersRaw string
+
+	// ServicePorts maps service keys to the TCP port checked when no init
+	// service exists for the key. Populated by Validate.
+	ServicePorts    map[string]int
+	servicePortsRaw string
+
+	// CollectorTimeouts give sub-collectors, including the pool listing,
+	// their own deadline instead of ScrapeTimeout, by collector name.
+	// Populated by Validate.
+	CollectorTimeouts    map[string]time.Duration
+	collectorTimeoutsRaw string
+
+	// Startup state of the optional sub-collectors.
+	CollectorPoolProperties   bool
+	CollectorDataset          bool
+	CollectorDatasetHistogram bool
+	CollectorDatasetTuning    bool
+	CollectorSnapshot         bool
+	CollectorNFS              bool
+	CollectorHistory          bool
+	CollectorLatency          bool
+	CollectorScan             bool
+	CollectorVdev             bool
+	CollectorService          bool
+	CollectorL2ARC            bool
+	CollectorZIL              bool
+	CollectorZfetch           bool
+	CollectorDmuTx            bool
+	CollectorTxg              bool
+	CollectorSPL              bool
+	CollectorEvents           bool
+	CollectorSMART            bool
+	CollectorSMB              bool
+	CollectorISCSI            bool
+
+	// SmartctlPath is the smartctl binary used by the smart collector.
+	SmartctlPath string
+
+	// SmbstatusPath is the smbstatus binary used by the smb collector.
+	SmbstatusPath string
+
+	// CtladmPath is the ctladm binary used by the iscsi collector on
+	// FreeBSD.
+	CtladmPath string
+
+	// DeviceIDs names pool member devices in vdev and SMART series by their
+	// /dev/disk/by-id links and exports their serial numbers.
+	DeviceIDs bool
+
+	// PoolInclude and PoolExclude are anchored regexes selecting which pools
+	// are exported. Nil means no filtering. Populated by Validate.
+	PoolInclude    *regexp.Regexp
+	PoolExclude    *regexp.Regexp
+	poolIncludeRaw string
+	poolExcludeRaw string
+
+	// PoolHealthStates are extra states for the zfs_pool_health state-set.
+	PoolHealthStates    []string
+	poolHealthStatesRaw string
+
+	// DatasetInclude and DatasetExclude are anchored regexes selecting which
+	// datasets are exported. Nil means no filtering. Populated by Validate.
+	DatasetInclude    *regexp.Regexp
+	DatasetExclude    *regexp.Regexp
+	datasetIncludeRaw string
+	datasetExcludeRaw string
+
+	// DatasetDepth limits zfs list to this many levels below each pool's
+	// root dataset. -1 means no limit.
+	DatasetDepth int
+
+	// DatasetTypes are the dataset types listed by zfs list -t, from
+	// zfs.DatasetTypes.
+	DatasetTypes    []string
+	datasetTypesRaw string
+
+	// DatasetMaxSeries caps the datasets exported per scrape, keeping the
+	// largest by used bytes (busiest with kstat); 0 means no limit.
+	DatasetMaxSeries int
+
+	// DatasetProperties are extra zfs get properties exported per dataset.
+	DatasetProperties    []string
+	datasetPropertiesRaw string
+
+	// UserspaceDatasets are datasets whose per-user and per-group space
+	// usage is exported (zfs userspace/groupspace).
+	UserspaceDatasets    []string
+	userspaceDatasetsRaw string
+
+	// SnapshotPolicies are the snapshot retention classes checked per
+	// dataset. Populated by Validate.
+	SnapshotPolicies    []zfs.SnapshotPolicy
+	snapshotPoliciesRaw string
+
+	// SnapshotMax caps the snapshots counted per scrape; 0 means no limit.
+	SnapshotMax int
+
+	// SnapshotPerPool lists snapshots with one zfs list per pool.
+	SnapshotPerPool bool
+
+	// StatusConcurrency, when positive, runs zpool status once per pool
+	// with at most this many at a time.
+	StatusConcurrency int
+
+	// UserPropertyPrefix selects ZFS user properties exported as dataset labels.
+	UserPropertyPrefix string
+
+	// CustomHooksFile is a JSON file of custom metric hooks (pkg/custom).
+	CustomHooksFile string
+
+	// AdminTokenFile holds the bearer token for the /-/ admin API. The admin
+	// API is disabled when empty. AdminToken is populated by Validate.
+	AdminTokenFile string
+	AdminToken     string
+
+	// EnablePprof mounts net/http/pprof under /debug/pprof/, on PprofAddress
+	// if set and on the main listener otherwise.
+	EnablePprof  bool
+	PprofAddress string
+
+	// EnableDebugState mounts /debug/state, the last scrape's parsed data
+	// as JSON.
+	EnableDebugState bool
+
+	// ProbeTargets are the remote hosts served by /probe, keyed by name.
+	// Populated by LoadFile. SSHPath is the ssh client used to reach them.
+	ProbeTargets map[string]ProbeTarget
+	SSHPath      string
 }
 
 // NewConfig registers flags on the given kingpin application and returns a Config.
 func NewConfig(app *kingpin.Application) *Config {
 	cfg := &Config{}
 
+	app.Flag("config.file", "YAML file of settings keyed by flag name. Command-line flags and environment variables override it.").
+		Default("").StringVar(&cfg.ConfigFile)
+	app.Flag("dump", "Collect once, print the metrics in text format to stdout, and exit (non-zero if zfs_up is 0).").
+		Default("false").BoolVar(&cfg.Dump)
 	app.Flag("web.listen-address", "Address to listen on for HTTP requests.").
 		Default(":9134").StringVar(&cfg.ListenAddress)
 	app.Flag("web.metrics-path", "Path under which to expose metrics.").
 		Default("/metrics").StringVar(&cfg.MetricsPath)
+	app.Flag("metrics.hostname-label", "Add a label of this name set to the host name to every zfs_* metric, and set to the target name on /probe, "+
+		"for --dump, push, and probe setups where Prometheus cannot attach instance (e.g. host).").
+		Default("").StringVar(&cfg.HostnameLabel)
+	app.Flag("metrics.rename-file", "YAML file mapping exporter metric names to the names to serve instead, for migrating from another exporter.").
+		Default("").StringVar(&cfg.MetricsRenameFile)
+	app.Flag("compat.pdf-zfs-exporter", "Also serve the metric names and label shapes of pdf/zfs_exporter, for migrating its dashboards gradually.").
+		Default("false").BoolVar(&cfg.CompatPDF)
+	app.Flag("compat.node-exporter", "Also serve the node_zfs_* metrics of node_exporter's zfs collector from the kstats under --zfs.kstat-path (Linux), "+
+		"for replacing that collector without rewriting its recording rules.").
+		Default("false").BoolVar(&cfg.CompatNode)
+	app.Flag("web.max-requests", "Maximum number of parallel scrape requests (0 disables the limit).").
+		Default("40").IntVar(&cfg.WebMaxRequests)
+	app.Flag("web.timeout", "Abort a scrape request with 503 after this long (0 disables the timeout).").
+		Default("0s").DurationVar(&cfg.WebTimeout)
+	app.Flag("web.disable-compression", "Do not gzip /metrics responses, even when the scraper accepts it.").
+		Default("false").BoolVar(&cfg.WebDisableCompression)
 	app.Flag("log.level", "Log level.").
 		Default("info").EnumVar(&cfg.LogLevel, "debug", "info", "warn", "error")
 	app.Flag("scrape.timeout", "Total timeout budget for all commands in a single scrape.").
 		Default("10s").DurationVar(&cfg.ScrapeTimeout)
+	app.Flag("timeout.zpool", "Deadline for each zpool command, instead of the --scrape.timeout budget (0 uses the budget).").
+		Default("0s").DurationVar(&cfg.CommandTimeouts.Zpool)
+	app.Flag("timeout.zfs-list", "Deadline for each zfs list command, instead of the --scrape.timeout budget (0 uses the budget).").
+		Default("0s").DurationVar(&cfg.CommandTimeouts.ZfsList)
+	app.Flag("timeout.systemctl", "Deadline for each systemctl command, instead of the --scrape.timeout budget (0 uses the budget).").
+		Default("0s").DurationVar(&cfg.CommandTimeouts.Systemctl)
+	app.Flag("timeout.collectors", "Comma-separated collector=duration deadlines for sub-collectors, including pool "+
+		"(e.g. dataset=30s,smb=5s), instead of the --scrape.timeout budget.").
+		Default("").StringVar(&cfg.collectorTimeoutsRaw)
+	app.Flag("scrape.cache-ttl", "Reuse command results for scrapes within this window (0 disables caching).").
+		Default("0s").DurationVar(&cfg.ScrapeCacheTTL)
 	app.Flag("zfs.zpool-path", "Path to the zpool binary.").
 		Default("zpool").StringVar(&cfg.ZpoolPath)
 	app.Flag("zfs.zfs-path", "Path to the zfs binary.").
 		Default("zfs").StringVar(&cfg.ZfsPath)
+	app.Flag("zfs.json", "Use OpenZFS 2.3+ JSON output (zpool/zfs -j) when the host supports it.").
+		Default("true").BoolVar(&cfg.ZfsJSON)
+	app.Flag("zfs.sudo", "Run zpool, zfs, and systemctl through --zfs.sudo-command, for running the exporter as an unprivileged user.").
+		Default("false").BoolVar(&cfg.ZfsSudo)
+	app.Flag("zfs.sudo-command", "Privilege wrapper used by --zfs.sudo (sudo or doas); it is run with -n and must not prompt.").
+		Default("sudo").StringVar(&cfg.SudoCommand)
+	app.Flag("zfs.backend", "Data source for pool state: cli (zpool/zfs) or kstat (procfs only; no capacity, scan, or vdev metrics).").
+		Default(BackendCLI).EnumVar(&cfg.Backend, BackendCLI, BackendKstat)
+	app.Flag("zfs.kstat-path", "Root of the ZFS kstat tree used by --zfs.backend=kstat.").
+		Default(kstat.DefaultRoot).StringVar(&cfg.KstatPath)
 	app.Flag("host.services", "Comma-separated list of service keys to monitor.").
 		Default("zfs,nfs,smb,iscsi").StringVar(&cfg.servicesRaw)
+	app.Flag("host.init", "Init system used for service checks: auto, systemd, openrc, runit, or sysv.").
+		Default(host.InitAuto).EnumVar(&cfg.HostInit, host.InitSystems...)
+	app.Flag("host.timers", "Comma-separated systemd timer units or globs to monitor (e.g. zfs-scrub-weekly@*.timer,sanoid.timer).").
+		Default("").StringVar(&cfg.timersRaw)
+	app.Flag("host.service-ports", "Comma-separated key[=port] list; a key with no init service is up if its TCP port is listening (default ports: nfs=2049, smb=445, iscsi=3260).").
+		Default("").StringVar(&cfg.servicePortsRaw)
+	app.Flag("collector.pool-properties", "Export each pool's ashift, autotrim, and import settings (zpool get) and creation time.").
+		Default("true").BoolVar(&cfg.CollectorPoolProperties)
+	app.Flag("collector.dataset", "Enable the dataset collector (zfs list).").
+		Default("true").BoolVar(&cfg.CollectorDataset)
+	app.Flag("collector.dataset-histogram", "Enable the per-pool dataset used-bytes histogram (low-cardinality alternative to per-dataset series).").
+		Default("false").BoolVar(&cfg.CollectorDatasetHistogram)
+	app.Flag("collector.dataset-tuning", "Export recordsize, sync, atime, primarycache, and compression of each dataset as labels of zfs_dataset_properties.").
+		Default("false").BoolVar(&cfg.CollectorDatasetTuning)
+	app.Flag("collector.snapshot", "Enable the per-dataset snapshot count collector (zfs list -t snapshot).").
+		Default("true").BoolVar(&cfg.CollectorSnapshot)
+	app.Flag("collector.nfs", "Cross-check datasets with sharenfs set against the kernel NFS export table (/proc/fs/nfsd/exports).").
+		Default("false").BoolVar(&cfg.CollectorNFS)
+	app.Flag("collector.history", "Count administrative operations logged by zpool history since the exporter started (reads each pool's full history every scrape).").
+		Default("false").BoolVar(&cfg.CollectorHistory)
+	app.Flag("collector.latency", "Export per-pool I/O latency native histograms (zpool iostat -w).").
+		Default("false").BoolVar(&cfg.CollectorLatency)
+	app.Flag("collector.scan", "Enable the scan collector (zpool status).").
+		Default("true").BoolVar(&cfg.CollectorScan)
+	app.Flag("collector.vdev", "Enable the per-device error collector (zpool status -p).").
+		Default("true").BoolVar(&cfg.CollectorVdev)
+	app.Flag("collector.service", "Enable the service collector (systemctl).").
+		Default("true").BoolVar(&cfg.CollectorService)
+	app.Flag("collector.l2arc", "Enable the L2ARC collector (arcstats kstat; only emits when a cache device exists).").
+		Default("true").BoolVar(&cfg.CollectorL2ARC)
+	app.Flag("collector.zil", "Enable the ZIL collector (zil kstat).").
+		Default("true").BoolVar(&cfg.CollectorZIL)
+	app.Flag("collector.zfetch", "Enable the prefetch collector (zfetchstats kstat).").
+		Default("true").BoolVar(&cfg.CollectorZfetch)
+	app.Flag("collector.dmu-tx", "Enable the DMU transaction collector (dmu_tx kstat; write throttle counters).").
+		Default("true").BoolVar(&cfg.CollectorDmuTx)
+	app.Flag("collector.txg", "Enable the transaction group collector (per-pool txgs kstat).").
+		Default("true").BoolVar(&cfg.CollectorTxg)
+	app.Flag("collector.spl", "Export SPL kmem cache memory use (/proc/spl/kmem/slab), ZFS kernel memory outside the ARC.").
+		Default("false").BoolVar(&cfg.CollectorSPL)
+	app.Flag("collector.smart", "Export SMART health, temperature, and reallocated sectors of pool member disks (smartctl -j).").
+		Default("false").BoolVar(&cfg.CollectorSMART)
+	app.Flag("host.smartctl-path", "Path to the smartctl binary used by --collector.smart.").
+		Default("smartctl").StringVar(&cfg.SmartctlPath)
+	app.Flag("collector.smb", "Export Samba session, open file, and per-share connection counts (smbstatus --json, Samba 4.16+).").
+		Default("false").BoolVar(&cfg.CollectorSMB)
+	app.Flag("host.smbstatus-path", "Path to the smbstatus binary used by --collector.smb.").
+		Default("smbstatus").StringVar(&cfg.SmbstatusPath)
+	app.Flag("collector.iscsi", "Export iSCSI target, LUN, and session counts and the zvol behind each LUN (LIO configfs on Linux, ctladm on FreeBSD).").
+		Default("false").BoolVar(&cfg.CollectorISCSI)
+	app.Flag("host.ctladm-path", "Path to the ctladm binary used by --collector.iscsi on FreeBSD.").
+		Default("ctladm").StringVar(&cfg.CtladmPath)
+	app.Flag("host.device-ids", "Label vdev and SMART series with each device's /dev/disk/by-id name instead of its kernel name (sdX), "+
+		"and export zfs_vdev_device_info with its device node and serial number.").
+		Default("false").BoolVar(&cfg.DeviceIDs)
+	app.Flag("collector.events", "Follow zpool events -f in the background and count events by class.").
+		Default("false").BoolVar(&cfg.CollectorEvents)
+	app.Flag("pool.include", "Only export pools whose name matches this regex (anchored). Applies to pool, scan, vdev, and dataset metrics.").
+		Default("").StringVar(&cfg.poolIncludeRaw)
+	app.Flag("pool.exclude", "Do not export pools whose name matches this regex (anchored). Applied after --pool.include.").
+		Default("").StringVar(&cfg.poolExcludeRaw)
+	app.Flag("pool.health-states", "Comma-separated extra pool health states for the zfs_pool_health state-set (states this exporter does not know).").
+		Default("").StringVar(&cfg.poolHealthStatesRaw)
+	app.Flag("dataset.include", "Only export datasets whose full name matches this regex (anchored).").
+		Default("").StringVar(&cfg.datasetIncludeRaw)
+	app.Flag("dataset.exclude", "Do not export datasets whose full name matches this regex (anchored). Applied after --dataset.include.").
+		Default("").StringVar(&cfg.datasetExcludeRaw)
+	app.Flag("dataset.depth", "Only collect datasets at most this many levels below each pool's root dataset (zfs list -d); -1 for no limit.").
+		Default("-1").IntVar(&cfg.DatasetDepth)
+	app.Flag("dataset.types", "Comma-separated dataset types to collect (filesystem, volume, snapshot).").
+		Default("filesystem,volume").StringVar(&cfg.datasetTypesRaw)
+	app.Flag("dataset.max-series", "Export at most this many datasets, the largest by used bytes (busiest with kstat), and set zfs_dataset_series_truncated (0: no limit).").
+		Default("0").IntVar(&cfg.DatasetMaxSeries)
+	app.Flag("dataset.properties", "Comma-separated extra ZFS properties to export per dataset (e.g. logicalused,recordsize,com.sun:auto-snapshot).").
+		Default("").StringVar(&cfg.datasetPropertiesRaw)
+	app.Flag("dataset.userspace", "Comma-separated datasets whose per-user and per-group space usage and quotas are exported (zfs userspace/groupspace).").
+		Default("").StringVar(&cfg.userspaceDatasetsRaw)
+	app.Flag("snapshot.policies", "Comma-separated class=glob:max-age snapshot retention classes to check per dataset (e.g. hourly=autosnap_*_hourly:2h).").
+		Default("").StringVar(&cfg.snapshotPoliciesRaw)
+	app.Flag("snapshot.max", "Stop counting snapshots after this many and set zfs_snapshot_counts_truncated; 0 for no limit.").
+		Default("0").IntVar(&cfg.SnapshotMax)
+	app.Flag("snapshot.per-pool", "List snapshots with one zfs list per pool instead of one for the whole host.").
+		Default("false").BoolVar(&cfg.SnapshotPerPool)
+	app.Flag("zfs.status-concurrency", "Run zpool status per pool, this many at once, so a hung pool cannot block the others (0: one for all pools).").
+		Default("0").IntVar(&cfg.StatusConcurrency)
+	app.Flag("zfs.user-property-prefix", "Attach ZFS user properties with this prefix (e.g. exporter:) as dataset metric labels.").
+		Default("").StringVar(&cfg.UserPropertyPrefix)
+	app.Flag("custom.hooks-file", "JSON file defining custom command/channel-program hooks exposed as zfs_custom_* metrics.").
+		Default("").StringVar(&cfg.CustomHooksFile)
+	app.Flag("web.admin-token-file", "File containing the bearer token for the /-/ admin API. Admin API is disabled if unset.").
+		Default("").StringVar(&cfg.AdminTokenFile)
+	app.Flag("web.enable-pprof", "Expose net/http/pprof profiling handlers under /debug/pprof/.").
+		Default("false").BoolVar(&cfg.EnablePprof)
+	app.Flag("web.enable-debug-state", "Expose the last scrape's parsed data and command errors as JSON under /debug/state.").
+		Default("false").BoolVar(&cfg.EnableDebugState)
+	app.Flag("web.pprof-address", "Serve the pprof handlers on this separate address (e.g. localhost:6060) instead of --web.listen-address.").
+		Default("").StringVar(&cfg.PprofAddress)
+	app.Flag("probe.ssh-path", "Path to the ssh client used to collect the config file's probe targets.").
+		Default("ssh").StringVar(&cfg.SSHPath)
+
+	cfg.trackFlags(app)
 
 	return cfg
 }
 
-// Validate checks that required binaries exist and parses the service list.
+// Validate checks the backend and that its required binaries exist, parses the service list,
+// init system, service ports, timers, filters, extra health states, dataset depth, types, limit, and properties, snapshot limit and
+// policies, status concurrency, and command timeouts, loads the admin token, and checks for the sudo wrapper when
+// --zfs.sudo is set, ssh when probe targets are configured, smartctl when SMART is enabled,
+// smbstatus when SMB is enabled, and ctladm when iSCSI is enabled on FreeBSD.
 func (c *Config) Validate() error {
 	c.parseServices()
 
-	if err := c.validateBinary(c.ZpoolPath, ErrZpoolNotFound); err != nil {
+	if !slices.Contains(host.InitSystems, c.HostInit) {
+		return fmt.Errorf("%w: %q", ErrInvalidInit, c.HostInit)
+	}
+
+	if err := c.parseServicePorts(); err != nil {
 		return err
 	}
 
-	if err := c.validateBinary(c.ZfsPath, ErrZfsNotFound); err != nil {
+	if err := c.parseTimers(); err != nil {
 		return err
 	}
 
-	return nil
-}
+	if err := c.parseCollectorTimeouts(); err != nil {
+		return err
+	}
 
-// ApplyEnvironment applies environment variable overrides.
-func (c *Config) ApplyEnvironment() error {
-	if v := os.Getenv("ZFS_EXPORTER_LISTEN_ADDRESS"); v != "" {
-		c.ListenAddress = v
+	if err := c.compileFilters(); err != nil {
+		return err
 	}
 
-	if v := os.Getenv("ZFS_EXPORTER_METRICS_PATH"); v != "" {
-		c.MetricsPath = v
+	if c.HostnameLabel != "" && (!labelNameRe.MatchString(c.HostnameLabel) || strings.HasPrefix(c.HostnameLabel, "__")) {
+		return fmt.Errorf("%w: %q", ErrInvalidLabel, c.HostnameLabel)
 	}
 
-	if v := os.Getenv("ZFS_EXPORTER_LOG_LEVEL"); v != "" {
-		c.LogLevel = v
+	if c.DatasetDepth < -1 {
+		return fmt.Errorf("%w: %d", ErrInvalidDepth, c.DatasetDepth)
 	}
 
-	if v := os.Getenv("ZFS_EXPORTER_SCRAPE_TIMEOUT"); v != "" {
-		d, err := time.ParseDuration(v)
-		if err != nil {
-			return fmt.Errorf("invalid ZFS_EXPORTER_SCRAPE_TIMEOUT %q: %w", v, err)
+	if err := c.parseHealthStates(); err != nil {
+		return err
+	}
+
+	if err := c.parseDatasetTypes(); err != nil {
+		return err
+	}
+
+	if c.DatasetMaxSeries < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidMaxSeries, c.DatasetMaxSeries)
+	}
+
+	if c.SnapshotMax < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidSnapshotMax, c.SnapshotMax)
+	}
+
+	if c.StatusConcurrency < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidConcurrency, c.StatusConcurrency)
+	}
+
+	if t := c.CommandTimeouts; t.Zpool < 0 || t.ZfsList < 0 || t.Systemctl < 0 {
+		return fmt.Errorf("%w: %+v", ErrInvalidTimeout, t)
+	}
+
+	if err := c.parseDatasetProperties(); err != nil {
+		return err
+	}
+
+	if err := c.parseUserspaceDatasets(); err != nil {
+		return err
+	}
+
+	if err := c.parseSnapshotPolicies(); err != nil {
+		return err
+	}
+
+	if err := c.loadAdminToken(); err != nil {
+		return err
+	}
+
+	if err := c.loadMetricRenames(); err != nil {
+		return err
+	}
+
+	// systemctl runs through the wrapper with either backend.
+	if c.ZfsSudo {
+		if err := c.validateBinary(c.SudoCommand, ErrSudoNotFound); err != nil {
+			return err
+		}
+	}
+
+	if len(c.ProbeTargets) > 0 {
+		if err := c.validateBinary(c.SSHPath, ErrSSHNotFound); err != nil {
+			return err
+		}
+	}
+
+	// smbstatus and ctladm do not depend on the backend.
+	if c.CollectorSMB {
+		if err := c.validateBinary(c.SmbstatusPath, ErrSmbstatusNotFound); err != nil {
+			return err
+		}
+	}
+
+	if c.CollectorISCSI && runtime.GOOS == "freebsd" {
+		if err := c.validateBinary(c.CtladmPath, ErrCtladmNotFound); err != nil {
+			return err
 		}
+	}
+
+	switch c.Backend {
+	case BackendCLI:
+	case BackendKstat:
+		// zpool/zfs are never executed for pool data; custom hooks that
+		// need them fail at scrape time instead.
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidBackend, c.Backend)
+	}
+
+	if err := c.validateBinary(c.ZpoolPath, ErrZpoolNotFound); err != nil {
+		return err
+	}
+
+	if err := c.validateBinary(c.ZfsPath, ErrZfsNotFound); err != nil {
+		return err
+	}
 
-		c.ScrapeTimeout = d
+	if c.CollectorSMART {
+		if err := c.validateBinary(c.SmartctlPath, ErrSmartctlNotFound); err != nil {
+			return err
+		}
 	}
 
-	if v := os.Getenv("ZFS_EXPORTER_ZPOOL_PATH"); v != "" {
-		c.ZpoolPath = v
+	return nil
+}
+
+// ApplyEnvironment applies environment variable overrides.
+func (c *Config) ApplyEnvironment() error {
+	for _, env := range []struct {
+		name string
+		dst  *string
+	}{
+		{"ZFS_EXPORTER_LISTEN_ADDRESS", &c.ListenAddress},
+		{"ZFS_EXPORTER_METRICS_PATH", &c.MetricsPath},
+		{"ZFS_EXPORTER_METRICS_HOSTNAME_LABEL", &c.HostnameLabel},
+		{"ZFS_EXPORTER_METRICS_RENAME_FILE", &c.MetricsRenameFile},
+		{"ZFS_EXPORTER_LOG_LEVEL", &c.LogLevel},
+		{"ZFS_EXPORTER_ZPOOL_PATH", &c.ZpoolPath},
+		{"ZFS_EXPORTER_ZFS_PATH", &c.ZfsPath},
+		{"ZFS_EXPORTER_SUDO_COMMAND", &c.SudoCommand},
+		{"ZFS_EXPORTER_BACKEND", &c.Backend},
+		{"ZFS_EXPORTER_KSTAT_PATH", &c.KstatPath},
+		{"ZFS_EXPORTER_SERVICES", &c.servicesRaw},
+		{"ZFS_EXPORTER_HOST_INIT", &c.HostInit},
+		{"ZFS_EXPORTER_SERVICE_PORTS", &c.servicePortsRaw},
+		{"ZFS_EXPORTER_TIMEOUT_COLLECTORS", &c.collectorTimeoutsRaw},
+		{"ZFS_EXPORTER_TIMERS", &c.timersRaw},
+		{"ZFS_EXPORTER_POOL_INCLUDE", &c.poolIncludeRaw},
+		{"ZFS_EXPORTER_POOL_EXCLUDE", &c.poolExcludeRaw},
+		{"ZFS_EXPORTER_POOL_HEALTH_STATES", &c.poolHealthStatesRaw},
+		{"ZFS_EXPORTER_DATASET_INCLUDE", &c.datasetIncludeRaw},
+		{"ZFS_EXPORTER_DATASET_EXCLUDE", &c.datasetExcludeRaw},
+		{"ZFS_EXPORTER_DATASET_TYPES", &c.datasetTypesRaw},
+		{"ZFS_EXPORTER_DATASET_PROPERTIES", &c.datasetPropertiesRaw},
+		{"ZFS_EXPORTER_DATASET_USERSPACE", &c.userspaceDatasetsRaw},
+		{"ZFS_EXPORTER_SNAPSHOT_POLICIES", &c.snapshotPoliciesRaw},
+		{"ZFS_EXPORTER_USER_PROPERTY_PREFIX", &c.UserPropertyPrefix},
+		{"ZFS_EXPORTER_CUSTOM_HOOKS_FILE", &c.CustomHooksFile},
+		{"ZFS_EXPORTER_ADMIN_TOKEN_FILE", &c.AdminTokenFile},
+		{"ZFS_EXPORTER_PPROF_ADDRESS", &c.PprofAddress},
+		{"ZFS_EXPORTER_SSH_PATH", &c.SSHPath},
+		{"ZFS_EXPORTER_SMARTCTL_PATH", &c.SmartctlPath},
+		{"ZFS_EXPORTER_SMBSTATUS_PATH", &c.SmbstatusPath},
+		{"ZFS_EXPORTER_CTLADM_PATH", &c.CtladmPath},
+	} {
+		if v := os.Getenv(env.name); v != "" {
+			*env.dst = v
+		}
 	}
 
-	if v := os.Getenv("ZFS_EXPORTER_ZFS_PATH"); v != "" {
-		c.ZfsPath = v
+	for _, env := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"ZFS_EXPORTER_WEB_TIMEOUT", &c.WebTimeout},
+		{"ZFS_EXPORTER_SCRAPE_TIMEOUT", &c.ScrapeTimeout},
+		{"ZFS_EXPORTER_TIMEOUT_ZPOOL", &c.CommandTimeouts.Zpool},
+		{"ZFS_EXPORTER_TIMEOUT_ZFS_LIST", &c.CommandTimeouts.ZfsList},
+		{"ZFS_EXPORTER_TIMEOUT_SYSTEMCTL", &c.CommandTimeouts.Systemctl},
+		{"ZFS_EXPORTER_SCRAPE_CACHE_TTL", &c.ScrapeCacheTTL},
+	} {
+		if v := os.Getenv(env.name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", env.name, v, err)
+			}
+
+			*env.dst = d
+		}
 	}
 
-	if v := os.Getenv("ZFS_EXPORTER_SERVICES"); v != "" {
-		c.servicesRaw = v
+	for _, env := range []struct {
+		name string
+		dst  *int
+	}{
+		{"ZFS_EXPORTER_WEB_MAX_REQUESTS", &c.WebMaxRequests},
+		{"ZFS_EXPORTER_DATASET_DEPTH", &c.DatasetDepth},
+		{"ZFS_EXPORTER_DATASET_MAX_SERIES", &c.DatasetMaxSeries},
+		{"ZFS_EXPORTER_SNAPSHOT_MAX", &c.SnapshotMax},
+		{"ZFS_EXPORTER_STATUS_CONCURRENCY", &c.StatusConcurrency},
+	} {
+		if v := os.Getenv(env.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", env.name, v, err)
+			}
+
+			*env.dst = n
+		}
 	}
 
 	return nil
@@ -115,6 +614,344 @@ func (c *Config) parseServices() {
 	}
 }
 
+// parseTimers parses the comma-separated timer list. Names are passed to
+// systemctl show, so they must be .timer units and cannot start with "-".
+func (c *Config) parseTimers() error {
+	c.Timers = nil
+
+	for name := range strings.SplitSeq(c.timersRaw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if strings.HasPrefix(name, "-") || !strings.HasSuffix(name, ".timer") {
+			return fmt.Errorf("%w: %q", ErrInvalidTimer, name)
+		}
+
+		c.Timers = append(c.Timers, name)
+	}
+
+	return nil
+}
+
+// parseServicePorts parses "nfs=2049,smb" into ServicePorts. A key without
+// a port uses host.DefaultServicePorts.
+func (c *Config) parseServicePorts() error {
+	c.ServicePorts = nil
+
+	for entry := range strings.SplitSeq(c.servicePortsRaw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, rawPort, hasPort := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+
+		port, ok := host.DefaultServicePorts[key]
+		if hasPort {
+			p, err := strconv.ParseUint(strings.TrimSpace(rawPort), 10, 16)
+			if err != nil || p == 0 {
+				return fmt.Errorf("%w: %q", ErrInvalidPort, entry)
+			}
+
+			port, ok = int(p), true
+		}
+
+		if key == "" || !ok {
+			return fmt.Errorf("%w: %q has no default port", ErrInvalidPort, entry)
+		}
+
+		if c.ServicePorts == nil {
+			c.ServicePorts = make(map[string]int)
+		}
+
+		c.ServicePorts[key] = port
+	}
+
+	return nil
+}
+
+// parseCollectorTimeouts parses "smb=5s,service=2s" into CollectorTimeouts.
+// Only the sub-collectors in collector.SubCollectorNames take a timeout.
+func (c *Config) parseCollectorTimeouts() error {
+	c.CollectorTimeouts = nil
+
+	for entry := range strings.SplitSeq(c.collectorTimeoutsRaw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rawTimeout, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+
+		if !ok || !slices.Contains(collector.SubCollectorNames, name) {
+			return fmt.Errorf("%w: %q (collectors: %s)", ErrCollectorTimeout, entry, strings.Join(collector.SubCollectorNames, ", "))
+		}
+
+		d, err := time.ParseDuration(strings.TrimSpace(rawTimeout))
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%w: %q", ErrCollectorTimeout, entry)
+		}
+
+		if c.CollectorTimeouts == nil {
+			c.CollectorTimeouts = make(map[string]time.Duration)
+		}
+
+		c.CollectorTimeouts[name] = d
+	}
+
+	return nil
+}
+
+// labelNameRe matches a Prometheus label name. Names starting with "__" are
+// reserved and rejected separately.
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// healthStateRe matches a pool health state as printed by zpool list.
+var healthStateRe = regexp.MustCompile(`^[A-Za-z][A-Za-z_-]*$`)
+
+func (c *Config) parseHealthStates() error {
+	c.PoolHealthStates = nil
+
+	for s := range strings.SplitSeq(c.poolHealthStatesRaw, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !healthStateRe.MatchString(s) {
+			return fmt.Errorf("%w: %q", ErrInvalidHealthState, s)
+		}
+
+		c.PoolHealthStates = append(c.PoolHealthStates, s)
+	}
+
+	return nil
+}
+
+func (c *Config) parseDatasetTypes() error {
+	c.DatasetTypes = nil
+
+	for t := range strings.SplitSeq(c.datasetTypesRaw, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+
+		if !slices.Contains(zfs.DatasetTypes, t) {
+			return fmt.Errorf("%w: %q", ErrInvalidDatasetType, t)
+		}
+
+		if !slices.Contains(c.DatasetTypes, t) {
+			c.DatasetTypes = append(c.DatasetTypes, t)
+		}
+	}
+
+	return nil
+}
+
+// propertyNameRe matches native and user ZFS property names. Names cannot
+// start with "-", so they are never parsed as zfs get flags.
+var propertyNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9:+._-]*$`)
+
+func (c *Config) parseDatasetProperties() error {
+	c.DatasetProperties = nil
+
+	for p := range strings.SplitSeq(c.datasetPropertiesRaw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if !propertyNameRe.MatchString(p) {
+			return fmt.Errorf("%w: %q", ErrInvalidProp, p)
+		}
+
+		c.DatasetProperties = append(c.DatasetProperties, p)
+	}
+
+	return nil
+}
+
+// datasetNameRe matches ZFS dataset names. Like property names they cannot
+// start with "-", so they are never parsed as zfs userspace flags.
+var datasetNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:/ -]*$`)
+
+func (c *Config) parseUserspaceDatasets() error {
+	c.UserspaceDatasets = nil
+
+	for ds := range strings.SplitSeq(c.userspaceDatasetsRaw, ",") {
+		ds = strings.TrimSpace(ds)
+		if ds == "" {
+			continue
+		}
+
+		if !datasetNameRe.MatchString(ds) {
+			return fmt.Errorf("%w: %q", ErrInvalidDataset, ds)
+		}
+
+		c.UserspaceDatasets = append(c.UserspaceDatasets, ds)
+	}
+
+	return nil
+}
+
+// parseSnapshotPolicies parses "hourly=autosnap_*_hourly:2h,..." into
+// SnapshotPolicies. The maximum age follows the last colon, so patterns
+// may contain colons of their own.
+func (c *Config) parseSnapshotPolicies() error {
+	c.SnapshotPolicies = nil
+
+	for entry := range strings.SplitSeq(c.snapshotPoliciesRaw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		class, rest, ok := strings.Cut(entry, "=")
+
+		sep := strings.LastIndex(rest, ":")
+		if !ok || sep < 0 {
+			return fmt.Errorf("%w: %q is not class=glob:max-age", ErrInvalidPolicy, entry)
+		}
+
+		p := zfs.SnapshotPolicy{Class: strings.TrimSpace(class), Pattern: strings.TrimSpace(rest[:sep])}
+
+		if _, err := path.Match(p.Pattern, ""); err != nil || p.Class == "" || p.Pattern == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidPolicy, entry)
+		}
+
+		age, err := time.ParseDuration(strings.TrimSpace(rest[sep+1:]))
+		if err != nil || age <= 0 {
+			return fmt.Errorf("%w: %q has an invalid max age", ErrInvalidPolicy, entry)
+		}
+
+		p.MaxAge = age
+		c.SnapshotPolicies = append(c.SnapshotPolicies, p)
+	}
+
+	return nil
+}
+
+// compileFilters compiles each filter flag as a fully anchored regex,
+// matching the convention of Prometheus relabeling. Empty flags leave the
+// filter nil.
+func (c *Config) compileFilters() error {
+	filters := []struct {
+		flag string
+		raw  string
+		dst  **regexp.Regexp
+	}{
+		{"pool.include", c.poolIncludeRaw, &c.PoolInclude},
+		{"pool.exclude", c.poolExcludeRaw, &c.PoolExclude},
+		{"dataset.include", c.datasetIncludeRaw, &c.DatasetInclude},
+		{"dataset.exclude", c.datasetExcludeRaw, &c.DatasetExclude},
+	}
+
+	for _, f := range filters {
+		*f.dst = nil
+
+		if f.raw == "" {
+			continue
+		}
+
+		re, err := regexp.Compile("^(?:" + f.raw + ")$")
+		if err != nil {
+			return fmt.Errorf("%w: --%s: %w", ErrInvalidFilter, f.flag, err)
+		}
+
+		*f.dst = re
+	}
+
+	return nil
+}
+
+func (c *Config) loadAdminToken() error {
+	if c.AdminTokenFile == "" {
+		c.AdminToken = ""
+		return nil
+	}
+
+	data, err := os.ReadFile(c.AdminTokenFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAdminToken, err)
+	}
+
+	c.AdminToken = strings.TrimSpace(string(data))
+	if c.AdminToken == "" {
+		return fmt.Errorf("%w: %s is empty", ErrAdminToken, c.AdminTokenFile)
+	}
+
+	return nil
+}
+
+// metricNameRe matches a Prometheus metric name.
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// loadMetricRenames reads MetricsRenameFile, a YAML map of old to new metric
+// names:
+//
+//	zfs_pool_health: zfs_pool_health_status
+//	zfs_dataset_used_bytes: zfs_dataset_used
+//
+// Both sides must be valid metric names, and no two may be renamed alike.
+// With CompatPDF, no metric may be renamed to a name compatibility mode
+// serves.
+func (c *Config) loadMetricRenames() error {
+	c.MetricRenames = nil
+
+	if c.MetricsRenameFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.MetricsRenameFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMetricRename, err)
+	}
+
+	var renames map[string]string
+	if err := yaml.UnmarshalStrict(data, &renames); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrMetricRename, c.MetricsRenameFile, err)
+	}
+
+	targets := make(map[string]string, len(renames))
+
+	for from, to := range renames {
+		if !metricNameRe.MatchString(from) || !metricNameRe.MatchString(to) {
+			return fmt.Errorf("%w: %q: %q", ErrMetricRename, from, to)
+		}
+
+		if other, ok := targets[to]; ok {
+			return fmt.Errorf("%w: %q and %q both renamed to %q", ErrMetricRename, min(from, other), max(from, other), to)
+		}
+
+		targets[to] = from
+	}
+
+	// A rename onto a metric that keeps its name would conflict on every
+	// scrape; metrics named at scrape time are only checked then.
+	described := collector.MetricNames()
+
+	for _, from := range slices.Sorted(maps.Keys(renames)) {
+		to := renames[from]
+		if _, moved := renames[to]; !moved && slices.Contains(described, to) {
+			return fmt.Errorf("%w: %q renamed to %q, the name of another metric", ErrMetricRename, from, to)
+		}
+
+		// Compatibility families replace any family of their name.
+		if c.CompatPDF && slices.Contains(exporter.PDFMetrics, to) {
+			return fmt.Errorf("%w: %q renamed to %q, a pdf/zfs_exporter compatibility metric", ErrMetricRename, from, to)
+		}
+	}
+
+	c.MetricRenames = renames
+
+	return nil
+}
+
 func (*Config) validateBinary(path string, sentinel error) error {
 	// If the path is a bare name (no /), use LookPath.
 	if !strings.Contains(path, "/") {
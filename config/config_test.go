@@ -0,0 +1,445 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+func TestParseServicePorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "empty", raw: "", want: nil},
+		{name: "default ports", raw: "nfs, smb", want: map[string]int{"nfs": 2049, "smb": 445}},
+		{name: "explicit ports", raw: "nfs=20490,web=8080", want: map[string]int{"nfs": 20490, "web": 8080}},
+		{name: "unknown key without port", raw: "web", wantErr: true},
+		{name: "invalid port", raw: "nfs=http", wantErr: true},
+		{name: "port out of range", raw: "nfs=70000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{servicePortsRaw: tt.raw}
+
+			err := c.parseServicePorts()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPort) {
+					t.Fatalf("error = %v, want ErrInvalidPort", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !maps.Equal(c.ServicePorts, tt.want) {
+				t.Errorf("ServicePorts = %v, want %v", c.ServicePorts, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCollectorTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{name: "empty", raw: ""},
+		{name: "list", raw: "smb=5s, service = 1m30s,", want: map[string]time.Duration{"smb": 5 * time.Second, "service": 90 * time.Second}},
+		{name: "zfs command collector", raw: "history=1m", want: map[string]time.Duration{"history": time.Minute}},
+		{name: "shared listing", raw: "pool=20s,dataset=30s", want: map[string]time.Duration{"pool": 20 * time.Second, "dataset": 30 * time.Second}},
+		{name: "unknown collector", raw: "arc=5s", wantErr: true},
+		{name: "missing duration", raw: "smb", wantErr: true},
+		{name: "zero", raw: "smb=0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{collectorTimeoutsRaw: tt.raw}
+
+			err := c.parseCollectorTimeouts()
+			if tt.wantErr {
+				if !errors.Is(err, ErrCollectorTimeout) {
+					t.Fatalf("error = %v, want ErrCollectorTimeout", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !maps.Equal(c.CollectorTimeouts, tt.want) {
+				t.Errorf("CollectorTimeouts = %v, want %v", c.CollectorTimeouts, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMetricRenames(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		pdf     bool
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", content: ""},
+		{
+			name:    "map",
+			content: "zfs_pool_health: zfs_pool_status\nzfs_up: 'zfs:up'\n",
+			want:    map[string]string{"zfs_pool_health": "zfs_pool_status", "zfs_up": "zfs:up"},
+		},
+		{name: "invalid name", content: "zfs_up: zfs-up\n", wantErr: true},
+		{name: "same target", content: "zfs_up: up\nzfs_pool_health: up\n", wantErr: true},
+		{name: "existing metric", content: "zfs_up: zfs_pool_health\n", wantErr: true},
+		{
+			name:    "swap",
+			content: "zfs_up: zfs_pool_health\nzfs_pool_health: zfs_up\n",
+			want:    map[string]string{"zfs_up": "zfs_pool_health", "zfs_pool_health": "zfs_up"},
+		},
+		{name: "not a map", content: "- zfs_up\n", wantErr: true},
+		{
+			name:    "pdf metric without compat",
+			content: "zfs_pool_dedup_ratio: zfs_pool_deduplication_ratio\n",
+			want:    map[string]string{"zfs_pool_dedup_ratio": "zfs_pool_deduplication_ratio"},
+		},
+		{name: "pdf metric", content: "zfs_pool_dedup_ratio: zfs_pool_deduplication_ratio\n", pdf: true, wantErr: true},
+		{name: "pdf swap", content: "zfs_up: zfs_pool_health\nzfs_pool_health: zfs_up\n", pdf: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "renames.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &Config{MetricsRenameFile: path, CompatPDF: tt.pdf}
+
+			err := c.loadMetricRenames()
+			if tt.wantErr {
+				if !errors.Is(err, ErrMetricRename) {
+					t.Fatalf("error = %v, want ErrMetricRename", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !maps.Equal(c.MetricRenames, tt.want) {
+				t.Errorf("MetricRenames = %v, want %v", c.MetricRenames, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimers(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", raw: ""},
+		{name: "list", raw: "zfs-scrub-weekly@*.timer, sanoid.timer ,", want: []string{"zfs-scrub-weekly@*.timer", "sanoid.timer"}},
+		{name: "flag-like", raw: "--all.timer", wantErr: true},
+		{name: "not a timer", raw: "sanoid.service", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{timersRaw: tt.raw}
+
+			err := c.parseTimers()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTimer) {
+					t.Fatalf("error = %v, want ErrInvalidTimer", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(c.Timers, tt.want) {
+				t.Errorf("Timers = %v, want %v", c.Timers, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHealthStates(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", raw: ""},
+		{name: "list", raw: "REBUILDING, split_pending ,", want: []string{"REBUILDING", "split_pending"}},
+		{name: "label injection", raw: `online"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{poolHealthStatesRaw: tt.raw}
+
+			err := c.parseHealthStates()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidHealthState) {
+					t.Fatalf("error = %v, want ErrInvalidHealthState", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(c.PoolHealthStates, tt.want) {
+				t.Errorf("PoolHealthStates = %v, want %v", c.PoolHealthStates, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDatasetTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", raw: ""},
+		{name: "default", raw: "filesystem,volume", want: []string{"filesystem", "volume"}},
+		{name: "snapshots", raw: " filesystem, snapshot,snapshot", want: []string{"filesystem", "snapshot"}},
+		{name: "bookmark", raw: "filesystem,bookmark", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{datasetTypesRaw: tt.raw}
+
+			err := c.parseDatasetTypes()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDatasetType) {
+					t.Fatalf("error = %v, want ErrInvalidDatasetType", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(c.DatasetTypes, tt.want) {
+				t.Errorf("DatasetTypes = %v, want %v", c.DatasetTypes, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserspaceDatasets(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", raw: ""},
+		{name: "list", raw: "tank/home, tank/projects ,", want: []string{"tank/home", "tank/projects"}},
+		{name: "flag-like", raw: "tank/home,-o", wantErr: true},
+		{name: "snapshot", raw: "tank/home@daily", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{userspaceDatasetsRaw: tt.raw}
+
+			err := c.parseUserspaceDatasets()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDataset) {
+					t.Fatalf("error = %v, want ErrInvalidDataset", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(c.UserspaceDatasets, tt.want) {
+				t.Errorf("UserspaceDatasets = %v, want %v", c.UserspaceDatasets, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSnapshotPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []zfs.SnapshotPolicy
+		wantErr bool
+	}{
+		{name: "empty", raw: ""},
+		{
+			name: "sanoid and zfs-auto-snapshot",
+			raw:  "hourly=autosnap_*_hourly:2h, daily = zfs-auto-snap_daily-* : 26h",
+			want: []zfs.SnapshotPolicy{
+				{Class: "hourly", Pattern: "autosnap_*_hourly", MaxAge: 2 * time.Hour},
+				{Class: "daily", Pattern: "zfs-auto-snap_daily-*", MaxAge: 26 * time.Hour},
+			},
+		},
+		{
+			name: "colon in pattern",
+			raw:  "noon=autosnap_*_12:00:*:25h",
+			want: []zfs.SnapshotPolicy{{Class: "noon", Pattern: "autosnap_*_12:00:*", MaxAge: 25 * time.Hour}},
+		},
+		{name: "no class", raw: "autosnap_*_hourly:2h", wantErr: true},
+		{name: "no age", raw: "hourly=autosnap_*_hourly", wantErr: true},
+		{name: "bad age", raw: "hourly=autosnap_*_hourly:soon", wantErr: true},
+		{name: "bad glob", raw: "hourly=autosnap_[:2h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{snapshotPoliciesRaw: tt.raw}
+
+			err := c.parseSnapshotPolicies()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPolicy) {
+					t.Fatalf("error = %v, want ErrInvalidPolicy", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(c.SnapshotPolicies, tt.want) {
+				t.Errorf("SnapshotPolicies = %+v, want %+v", c.SnapshotPolicies, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	t.Setenv("ZFS_EXPORTER_LISTEN_ADDRESS", ":9200")
+	t.Setenv("ZFS_EXPORTER_WEB_MAX_REQUESTS", "5")
+	t.Setenv("ZFS_EXPORTER_WEB_TIMEOUT", "20s")
+	t.Setenv("ZFS_EXPORTER_DATASET_DEPTH", "1")
+	t.Setenv("ZFS_EXPORTER_TIMEOUT_ZFS_LIST", "1m")
+
+	c := &Config{ListenAddress: ":9134", WebMaxRequests: 40, DatasetDepth: -1}
+	if err := c.ApplyEnvironment(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ListenAddress != ":9200" || c.WebMaxRequests != 5 || c.WebTimeout != 20*time.Second {
+		t.Errorf("got ListenAddress=%q WebMaxRequests=%d WebTimeout=%v", c.ListenAddress, c.WebMaxRequests, c.WebTimeout)
+	}
+
+	if c.DatasetDepth != 1 {
+		t.Errorf("DatasetDepth = %d, want 1", c.DatasetDepth)
+	}
+
+	if c.CommandTimeouts.ZfsList != time.Minute {
+		t.Errorf("CommandTimeouts.ZfsList = %v, want 1m", c.CommandTimeouts.ZfsList)
+	}
+
+	t.Setenv("ZFS_EXPORTER_DATASET_DEPTH", "top")
+
+	if err := c.ApplyEnvironment(); err == nil {
+		t.Error("expected error for invalid ZFS_EXPORTER_DATASET_DEPTH")
+	}
+
+	t.Setenv("ZFS_EXPORTER_DATASET_DEPTH", "")
+
+	t.Setenv("ZFS_EXPORTER_WEB_MAX_REQUESTS", "many")
+
+	if err := c.ApplyEnvironment(); err == nil {
+		t.Error("expected error for invalid ZFS_EXPORTER_WEB_MAX_REQUESTS")
+	}
+
+	t.Setenv("ZFS_EXPORTER_WEB_MAX_REQUESTS", "")
+	t.Setenv("ZFS_EXPORTER_SCRAPE_TIMEOUT", "soon")
+
+	if err := c.ApplyEnvironment(); err == nil {
+		t.Error("expected error for invalid ZFS_EXPORTER_SCRAPE_TIMEOUT")
+	}
+}
+
+func TestValidate_Sudo(t *testing.T) {
+	tests := []struct {
+		name    string
+		sudo    bool
+		command string
+		wantErr error
+	}{
+		{name: "disabled", command: "/nonexistent/sudo"},
+		{name: "missing wrapper", sudo: true, command: "/nonexistent/sudo", wantErr: ErrSudoNotFound},
+		{name: "not executable", sudo: true, command: "./config_test.go", wantErr: ErrSudoNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{HostInit: host.InitAuto, Backend: BackendKstat, DatasetDepth: -1, ZfsSudo: tt.sudo, SudoCommand: tt.command}
+
+			if err := c.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_HostnameLabel(t *testing.T) {
+	tests := []struct {
+		label   string
+		wantErr error
+	}{
+		{label: ""},
+		{label: "host"},
+		{label: "_node"},
+		{label: "host-name", wantErr: ErrInvalidLabel},
+		{label: "0host", wantErr: ErrInvalidLabel},
+		{label: "__host", wantErr: ErrInvalidLabel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			c := &Config{HostInit: host.InitAuto, Backend: BackendKstat, DatasetDepth: -1, HostnameLabel: tt.label}
+
+			if err := c.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,32 @@ import "errors"
 
 // Sentinel errors for configuration validation.
 var (
-	ErrZpoolNotFound = errors.New("zpool binary not found or not executable")
-	ErrZfsNotFound   = errors.New("zfs binary not found or not executable")
+	ErrZpoolNotFound      = errors.New("zpool binary not found or not executable")
+	ErrZfsNotFound        = errors.New("zfs binary not found or not executable")
+	ErrAdminToken         = errors.New("admin token file unreadable or empty")
+	ErrMetricRename       = errors.New("invalid metric rename file")
+	ErrInvalidFilter      = errors.New("invalid filter regex")
+	ErrInvalidProp        = errors.New("invalid ZFS property name")
+	ErrInvalidDataset     = errors.New("invalid ZFS dataset name")
+	ErrInvalidHealthState = errors.New("invalid pool health state")
+	ErrInvalidLabel       = errors.New("invalid metric label name")
+	ErrInvalidDepth       = errors.New("invalid dataset depth")
+	ErrInvalidMaxSeries   = errors.New("invalid dataset series limit")
+	ErrInvalidDatasetType = errors.New("invalid dataset type")
+	ErrInvalidSnapshotMax = errors.New("invalid snapshot limit")
+	ErrInvalidTimeout     = errors.New("invalid command timeout")
+	ErrCollectorTimeout   = errors.New("invalid collector timeout")
+	ErrInvalidConcurrency = errors.New("invalid zpool status concurrency")
+	ErrInvalidPolicy      = errors.New("invalid snapshot policy")
+	ErrInvalidTimer       = errors.New("invalid systemd timer name")
+	ErrInvalidBackend     = errors.New("invalid backend")
+	ErrInvalidInit        = errors.New("invalid init system")
+	ErrInvalidPort        = errors.New("invalid service port")
+	ErrConfigFile         = errors.New("invalid config file")
+	ErrInvalidTarget      = errors.New("invalid probe target")
+	ErrSSHNotFound        = errors.New("ssh binary not found or not executable")
+	ErrSudoNotFound       = errors.New("sudo command not found or not executable")
+	ErrSmartctlNotFound   = errors.New("smartctl binary not found or not executable")
+	ErrSmbstatusNotFound  = errors.New("smbstatus binary not found or not executable")
+	ErrCtladmNotFound     = errors.New("ctladm binary not found or not executable")
 )
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"go.yaml.in/yaml/v2"
+)
+
+// trackFlags records, for every flag registered so far, whether it was set
+// on the command line, so LoadFile can give CLI flags precedence over the
+// file.
+func (c *Config) trackFlags(app *kingpin.Application) {
+	c.app = app
+	c.setByUser = make(map[string]*bool)
+
+	for _, f := range app.Model().Flags {
+		set := new(bool)
+		app.GetFlag(f.Name).IsSetByUser(set)
+		c.setByUser[f.Name] = set
+	}
+}
+
+// LoadFile applies settings from the YAML file named by --config.file (or
+// ZFS_EXPORTER_CONFIG_FILE). Keys are flag names, either flat or nested on
+// the dots:
+//
+//	web.listen-address: ":9134"
+//	zfs:
+//	  backend: kstat
+//	host:
+//	  services: [zfs, nfs]
+//
+// Lists are joined with commas. Flags given on the command line win over the
+// file; environment variables, applied afterwards, win over both. Call after
+// parsing and before ApplyEnvironment.
+//
+// The top-level targets key is not a flag: it defines the remote hosts served
+// by /probe (see ProbeTarget).
+func (c *Config) LoadFile() error {
+	if v := os.Getenv("ZFS_EXPORTER_CONFIG_FILE"); v != "" {
+		c.ConfigFile = v
+	}
+
+	if c.ConfigFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConfigFile, err)
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrConfigFile, c.ConfigFile, err)
+	}
+
+	c.ProbeTargets, err = parseTargets(doc[targetsKey])
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrConfigFile, c.ConfigFile, err)
+	}
+
+	delete(doc, targetsKey)
+
+	settings := make(map[string]string)
+	flattenSettings("", doc, settings)
+
+	// Sorted so the first invalid key reported is deterministic.
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		if err := c.applySetting(name, settings[name]); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrConfigFile, c.ConfigFile, err)
+		}
+	}
+
+	return nil
+}
+
+// applySetting sets flag name to value unless it was given on the command
+// line.
+func (c *Config) applySetting(name, value string) error {
+	set, ok := c.setByUser[name]
+	if !ok || name == "config.file" || name == "help" || name == "version" {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+
+	if *set {
+		return nil
+	}
+
+	if err := c.app.GetFlag(name).Model().Value.Set(value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
+
+// flattenSettings converts a decoded YAML mapping into dotted flag names and
+// their string values.
+func flattenSettings(prefix string, m map[string]any, out map[string]string) {
+	for k, v := range m {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[any]any:
+			sub := make(map[string]any, len(v))
+			for sk, sv := range v {
+				sub[fmt.Sprint(sk)] = sv
+			}
+
+			flattenSettings(name, sub, out)
+		case []any:
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = fmt.Sprint(item)
+			}
+
+			out[name] = strings.Join(parts, ",")
+		case nil:
+			out[name] = ""
+		default:
+			out[name] = fmt.Sprint(v)
+		}
+	}
+}
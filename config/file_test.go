@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeConfigFile(t, `
+web.listen-address: ":9200"
+scrape:
+  timeout: 30s
+host:
+  services: [zfs, nfs]
+collector:
+  scan: false
+zfs:
+  backend: kstat
+`)
+
+	app := kingpin.New("test", "")
+	cfg := NewConfig(app)
+
+	// The CLI flag must win over the file.
+	if _, err := app.Parse([]string{"--config.file", path, "--zfs.backend", "cli"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.LoadFile(); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	if cfg.ListenAddress != ":9200" {
+		t.Errorf("ListenAddress = %q, want :9200", cfg.ListenAddress)
+	}
+
+	if cfg.ScrapeTimeout != 30*time.Second {
+		t.Errorf("ScrapeTimeout = %v, want 30s", cfg.ScrapeTimeout)
+	}
+
+	if cfg.servicesRaw != "zfs,nfs" {
+		t.Errorf("servicesRaw = %q, want zfs,nfs", cfg.servicesRaw)
+	}
+
+	if cfg.CollectorScan {
+		t.Error("CollectorScan = true, want false")
+	}
+
+	if cfg.Backend != BackendCLI {
+		t.Errorf("Backend = %q, want CLI flag value %q", cfg.Backend, BackendCLI)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown key", "web.listen-adress: :9200\n"},
+		{"invalid value", "scrape.timeout: soon\n"},
+		{"invalid enum", "zfs.backend: sysctl\n"},
+		{"config file key", "config.file: other.yml\n"},
+		{"malformed yaml", "web: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := kingpin.New("test", "")
+			cfg := NewConfig(app)
+
+			if _, err := app.Parse([]string{"--config.file", writeConfigFile(t, tt.content)}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := cfg.LoadFile(); !errors.Is(err, ErrConfigFile) {
+				t.Errorf("LoadFile() error = %v, want ErrConfigFile", err)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Targets(t *testing.T) {
+	path := writeConfigFile(t, `
+web.listen-address: ":9200"
+targets:
+  nas1:
+    host: nas1.example.com
+    user: zfs
+    key: /etc/zfs_exporter/id_ed25519
+  nas2:
+    host: 10.0.0.5
+    port: 2222
+    zpool_path: /usr/local/sbin/zpool
+`)
+
+	app := kingpin.New("test", "")
+	cfg := NewConfig(app)
+
+	if _, err := app.Parse([]string{"--config.file", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.LoadFile(); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	want := map[string]ProbeTarget{
+		"nas1": {Host: "nas1.example.com", User: "zfs", Key: "/etc/zfs_exporter/id_ed25519", ZpoolPath: "zpool", ZfsPath: "zfs"},
+		"nas2": {Host: "10.0.0.5", Port: 2222, ZpoolPath: "/usr/local/sbin/zpool", ZfsPath: "zfs"},
+	}
+
+	if !maps.Equal(cfg.ProbeTargets, want) {
+		t.Errorf("ProbeTargets = %+v, want %+v", cfg.ProbeTargets, want)
+	}
+
+	if cfg.ListenAddress != ":9200" {
+		t.Errorf("ListenAddress = %q, want :9200", cfg.ListenAddress)
+	}
+}
+
+func TestLoadFile_TargetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing host", "targets:\n  nas1:\n    user: zfs\n"},
+		{"unknown field", "targets:\n  nas1:\n    host: nas1\n    hostname: nas1\n"},
+		{"invalid name", "targets:\n  \"nas 1\":\n    host: nas1\n"},
+		{"invalid port", "targets:\n  nas1:\n    host: nas1\n    port: 70000\n"},
+		{"not a mapping", "targets: [nas1]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := kingpin.New("test", "")
+			cfg := NewConfig(app)
+
+			if _, err := app.Parse([]string{"--config.file", writeConfigFile(t, tt.content)}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := cfg.LoadFile()
+			if !errors.Is(err, ErrConfigFile) || !errors.Is(err, ErrInvalidTarget) {
+				t.Errorf("LoadFile() error = %v, want ErrInvalidTarget", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// ProbeTarget is a remote host collected over ssh by /probe?target=<name>.
+// Targets are defined under the config file's top-level targets key:
+//
+//	targets:
+//	  nas1:
+//	    host: nas1.example.com
+//	    user: zfs
+//	    key: /etc/zfs_exporter/id_ed25519
+type ProbeTarget struct {
+	Host      string `yaml:"host"`
+	User      string `yaml:"user"`
+	Key       string `yaml:"key"`
+	Port      int    `yaml:"port"`
+	ZpoolPath string `yaml:"zpool_path"`
+	ZfsPath   string `yaml:"zfs_path"`
+}
+
+// targetsKey is the config file key holding probe targets. It is not a flag.
+const targetsKey = "targets"
+
+// validTargetName matches probe target names, which appear in the /probe
+// query string and in logs.
+var validTargetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// parseTargets decodes the targets section of the config file, filling in
+// default binary names. A missing section yields no targets.
+func parseTargets(raw any) (map[string]ProbeTarget, error) {
+	// Round-trip through YAML so unknown target fields are rejected.
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTarget, err)
+	}
+
+	var targets map[string]ProbeTarget
+	if err := yaml.UnmarshalStrict(data, &targets); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTarget, err)
+	}
+
+	for name, t := range targets {
+		switch {
+		case !validTargetName.MatchString(name):
+			return nil, fmt.Errorf("%w: invalid name %q", ErrInvalidTarget, name)
+		case t.Host == "":
+			return nil, fmt.Errorf("%w: %s: host is required", ErrInvalidTarget, name)
+		case t.Port < 0 || t.Port > 65535:
+			return nil, fmt.Errorf("%w: %s: invalid port %d", ErrInvalidTarget, name, t.Port)
+		}
+
+		if t.ZpoolPath == "" {
+			t.ZpoolPath = "zpool"
+		}
+
+		if t.ZfsPath == "" {
+			t.ZfsPath = "zfs"
+		}
+
+		targets[name] = t
+	}
+
+	return targets, nil
+}
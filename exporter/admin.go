@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// CollectorToggler switches sub-collectors on and off at runtime.
+type CollectorToggler interface {
+	SetEnabled(name string, enabled bool) error
+	ResetEnabled()
+	EnabledCollectors() map[string]bool
+}
+
+// RegisterAdminHandlers mounts the admin API on mux:
+//
+//	GET  /-/collectors                       current enabled state as JSON
+//	POST /-/collectors/{name}/enable|disable toggle a sub-collector
+//	POST /-/reload                           restore startup collector state
+//
+// Every request must carry "Authorization: Bearer <token>". Toggles are held
+// in memory only, so the process (and any client-side state) survives an
+// incident-time change without a restart.
+func RegisterAdminHandlers(mux *http.ServeMux, toggler CollectorToggler, token string, logger *slog.Logger) {
+	auth := func(h http.HandlerFunc) http.Handler {
+		return requireBearer(token, h)
+	}
+
+	mux.Handle("GET /-/collectors", auth(func(w http.ResponseWriter, _ *http.Request) {
+		writeCollectorState(w, toggler, logger)
+	}))
+
+	mux.Handle("POST /-/collectors/{name}/{action}", auth(func(w http.ResponseWriter, r *http.Request) {
+		name, action := r.PathValue("name"), r.PathValue("action")
+
+		var enabled bool
+
+		switch action {
+		case "enable":
+			enabled = true
+		case "disable":
+			enabled = false
+		default:
+			http.Error(w, "action must be enable or disable", http.StatusNotFound)
+			return
+		}
+
+		if err := toggler.SetEnabled(name, enabled); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		logger.Info("Collector toggled via admin API", "collector", name, "enabled", enabled, "remote", r.RemoteAddr)
+		writeCollectorState(w, toggler, logger)
+	}))
+
+	mux.Handle("POST /-/reload", auth(func(w http.ResponseWriter, r *http.Request) {
+		toggler.ResetEnabled()
+		logger.Info("Collector state reset via admin API", "remote", r.RemoteAddr)
+		writeCollectorState(w, toggler, logger)
+	}))
+}
+
+// requireBearer rejects requests whose bearer token does not match token.
+func requireBearer(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="zfs_exporter"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeCollectorState(w http.ResponseWriter, toggler CollectorToggler, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(toggler.EnabledCollectors()); err != nil {
+		logger.Error("Failed to write collector state", "err", err)
+	}
+}
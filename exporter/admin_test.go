@@ -0,0 +1,105 @@
+package exporter
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeToggler struct {
+	state map[string]bool
+	reset bool
+}
+
+func (f *fakeToggler) SetEnabled(name string, enabled bool) error {
+	if _, ok := f.state[name]; !ok {
+		return errors.New("unknown collector")
+	}
+
+	f.state[name] = enabled
+
+	return nil
+}
+
+func (f *fakeToggler) ResetEnabled() { f.reset = true }
+
+func (f *fakeToggler) EnabledCollectors() map[string]bool { return f.state }
+
+func newAdminServer(t *testing.T, toggler CollectorToggler) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	RegisterAdminHandlers(mux, toggler, "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func doRequest(t *testing.T, method, url, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), method, url, http.NoBody)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestAdmin_RequiresToken(t *testing.T) {
+	srv := newAdminServer(t, &fakeToggler{state: map[string]bool{"dataset": true}})
+
+	for _, token := range []string{"", "wrong"} {
+		resp := doRequest(t, http.MethodPost, srv.URL+"/-/collectors/dataset/disable", token)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, resp.StatusCode)
+		}
+	}
+}
+
+func TestAdmin_ToggleAndReload(t *testing.T) {
+	toggler := &fakeToggler{state: map[string]bool{"dataset": true}}
+	srv := newAdminServer(t, toggler)
+
+	resp := doRequest(t, http.MethodPost, srv.URL+"/-/collectors/dataset/disable", "s3cret")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("disable status = %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `"dataset":false`) {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/-/collectors/bogus/enable", "s3cret")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown collector status = %d, want 404", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/-/collectors/dataset/explode", "s3cret")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("bad action status = %d, want 404", resp.StatusCode)
+	}
+
+	resp = doRequest(t, http.MethodPost, srv.URL+"/-/reload", "s3cret")
+	if resp.StatusCode != http.StatusOK || !toggler.reset {
+		t.Errorf("reload status = %d, reset = %v", resp.StatusCode, toggler.reset)
+	}
+}
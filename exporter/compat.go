@@ -0,0 +1,235 @@
+package exporter
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// pdfHealthCodes are the zfs_pool_health values of pdf/zfs_exporter, keyed
+// by the state label of the exporter's own zfs_pool_health.
+var pdfHealthCodes = map[string]float64{
+	"online":    0,
+	"degraded":  1,
+	"faulted":   2,
+	"offline":   3,
+	"unavail":   4,
+	"removed":   5,
+	"suspended": 6,
+}
+
+// pdfDatasetMetrics are the dataset gauges pdf/zfs_exporter shares with the
+// exporter, with a name label in place of dataset.
+var pdfDatasetMetrics = map[string]string{
+	"zfs_dataset_used_bytes":         "The amount of space in bytes consumed by this dataset and all its descendents.",
+	"zfs_dataset_available_bytes":    "The amount of space in bytes available to the dataset and all its children.",
+	"zfs_dataset_referenced_bytes":   "The amount of data in bytes that is accessible by this dataset.",
+	"zfs_dataset_logical_used_bytes": "The amount of space in bytes that is logically consumed by this dataset and all its descendents.",
+}
+
+// PDFReplacedMetrics are the exporter's metrics whose names pdf/zfs_exporter
+// uses with other labels. In compatibility mode (MetricsOptions.PDFCompat)
+// they are served in pdf/zfs_exporter's shape; renaming them (Renames) keeps
+// the exporter's own series under the new name.
+var PDFReplacedMetrics = []string{
+	"zfs_dataset_available_bytes",
+	"zfs_dataset_logical_used_bytes",
+	"zfs_dataset_referenced_bytes",
+	"zfs_dataset_used_bytes",
+	"zfs_pool_health",
+}
+
+// PDFMetrics are the names of the families compatibility mode serves in
+// pdf/zfs_exporter's shape. They replace any family of the same name, so a
+// metric must not be renamed (Renames) to one of them.
+var PDFMetrics = []string{
+	"zfs_dataset_available_bytes",
+	"zfs_dataset_logical_used_bytes",
+	"zfs_dataset_referenced_bytes",
+	"zfs_dataset_used_bytes",
+	"zfs_pool_capacity_ratio",
+	"zfs_pool_deduplication_ratio",
+	"zfs_pool_health",
+}
+
+// pdfFamilies derives the metric families of pdf/zfs_exporter from the
+// exporter's own. Pool metrics both exporters share (size, allocated, free,
+// fragmentation, readonly) need no translation and are not returned. The
+// families are new, so families may be renamed afterwards.
+func pdfFamilies(families []*dto.MetricFamily) []*dto.MetricFamily {
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, mf := range families {
+		byName[mf.GetName()] = mf
+	}
+
+	var compat []*dto.MetricFamily
+
+	add := func(name, help string, metrics []*dto.Metric) {
+		if len(metrics) > 0 {
+			compat = append(compat, &dto.MetricFamily{
+				Name:   proto.String(name),
+				Help:   proto.String(help),
+				Type:   dto.MetricType_GAUGE.Enum(),
+				Metric: metrics,
+			})
+		}
+	}
+
+	add("zfs_pool_health",
+		"Health status code for the pool [0: ONLINE, 1: DEGRADED, 2: FAULTED, 3: OFFLINE, 4: UNAVAIL, 5: REMOVED, 6: SUSPENDED].",
+		pdfPoolHealth(byName["zfs_pool_health"]))
+	add("zfs_pool_deduplication_ratio",
+		"The ratio of deduplicated size vs undeduplicated size for data in this pool.",
+		relabel(byName["zfs_pool_dedup_ratio"], "", ""))
+	add("zfs_pool_capacity_ratio", "Ratio of pool space used.",
+		pdfPoolCapacity(byName["zfs_pool_allocated_bytes"], byName["zfs_pool_size_bytes"]))
+
+	for name, help := range pdfDatasetMetrics {
+		add(name, help, relabel(byName[name], "dataset", "name"))
+	}
+
+	return compat
+}
+
+// pdfPoolHealth returns one series per pool, without the state label, whose
+// value is the code of the pool's current state. Pools in a state
+// pdf/zfs_exporter has no code for are left out.
+func pdfPoolHealth(health *dto.MetricFamily) []*dto.Metric {
+	var metrics []*dto.Metric
+
+	for _, m := range health.GetMetric() {
+		code, ok := pdfHealthCodes[labelValue(m, "state")]
+		if !ok || m.GetGauge().GetValue() != 1 {
+			continue
+		}
+
+		metrics = append(metrics, gaugeMetric(withoutLabel(m.GetLabel(), "state"), code))
+	}
+
+	return metrics
+}
+
+// pdfPoolCapacity returns each pool's allocated space as a fraction of its
+// size.
+func pdfPoolCapacity(allocated, size *dto.MetricFamily) []*dto.Metric {
+	sizes := make(map[string]float64, len(size.GetMetric()))
+	for _, m := range size.GetMetric() {
+		sizes[labelKey(m.GetLabel())] = m.GetGauge().GetValue()
+	}
+
+	var metrics []*dto.Metric
+
+	for _, m := range allocated.GetMetric() {
+		if s := sizes[labelKey(m.GetLabel())]; s > 0 {
+			metrics = append(metrics, gaugeMetric(m.GetLabel(), m.GetGauge().GetValue()/s))
+		}
+	}
+
+	return metrics
+}
+
+// relabel returns the gauges of mf with the label from renamed to to. An
+// empty from keeps the labels unchanged.
+func relabel(mf *dto.MetricFamily, from, to string) []*dto.Metric {
+	var metrics []*dto.Metric
+
+	for _, m := range mf.GetMetric() {
+		labels := slices.Clone(m.GetLabel())
+
+		for i, lp := range labels {
+			if from != "" && lp.GetName() == from {
+				labels[i] = &dto.LabelPair{Name: proto.String(to), Value: lp.Value}
+			}
+		}
+
+		metrics = append(metrics, gaugeMetric(labels, m.GetGauge().GetValue()))
+	}
+
+	return metrics
+}
+
+// gaugeMetric returns a gauge with labels, sorted by name as the exposition
+// formats expect. The label pairs are shared, not copied; only family names
+// are changed after gathering.
+func gaugeMetric(labels []*dto.LabelPair, value float64) *dto.Metric {
+	pairs := slices.Clone(labels)
+
+	slices.SortFunc(pairs, func(a, b *dto.LabelPair) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+
+	return &dto.Metric{Label: pairs, Gauge: &dto.Gauge{Value: proto.Float64(value)}}
+}
+
+// labelValue returns the value of m's label name, or "" if it has none.
+func labelValue(m *dto.Metric, name string) string {
+	for _, lp := range m.GetLabel() {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+
+	return ""
+}
+
+// withoutLabel returns labels without the label name.
+func withoutLabel(labels []*dto.LabelPair, name string) []*dto.LabelPair {
+	return slices.DeleteFunc(slices.Clone(labels), func(lp *dto.LabelPair) bool {
+		return lp.GetName() == name
+	})
+}
+
+// labelKey identifies a series by its labels, which the registry sorts.
+func labelKey(labels []*dto.LabelPair) string {
+	var b strings.Builder
+
+	for _, lp := range labels {
+		b.WriteString(lp.GetName() + "=" + lp.GetValue() + "\xff")
+	}
+
+	return b.String()
+}
+
+// mergeFamilies adds compat to families, replacing families of the same
+// name, and returns the result sorted by name.
+func mergeFamilies(families, compat []*dto.MetricFamily) []*dto.MetricFamily {
+	replaced := make(map[string]bool, len(compat))
+	for _, mf := range compat {
+		replaced[mf.GetName()] = true
+	}
+
+	merged := slices.DeleteFunc(families, func(mf *dto.MetricFamily) bool {
+		return replaced[mf.GetName()]
+	})
+	merged = append(merged, compat...)
+
+	slices.SortFunc(merged, func(a, b *dto.MetricFamily) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+
+	return merged
+}
+
+// Gatherer returns g as MetricsHandler and ProbeHandler serve it: renamed
+// per o.Renames, and with the pdf/zfs_exporter families when o.PDFCompat is
+// set. The compatibility families are derived from the
+// exporter's names, before renaming, so a renamed metric keeps its
+// pdf/zfs_exporter counterpart. Rename conflicts are logged to logger.
+func (o MetricsOptions) Gatherer(g prometheus.Gatherer, logger *slog.Logger) prometheus.Gatherer {
+	if !o.PDFCompat {
+		return RenameGatherer(g, o.Renames, logger)
+	}
+
+	r := newRenamer(o.Renames, logger)
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		compat := pdfFamilies(families)
+
+		return mergeFamilies(r.rename(families), compat), err
+	})
+}
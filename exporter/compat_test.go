@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newCompatRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	health := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "zfs_pool_health", Help: "Pool health."}, []string{"pool", "state"})
+	for _, state := range []string{"online", "degraded", "split"} {
+		health.WithLabelValues("tank", state).Set(0)
+		health.WithLabelValues("usb", state).Set(0)
+	}
+
+	health.WithLabelValues("tank", "degraded").Set(1)
+	health.WithLabelValues("usb", "split").Set(1)
+
+	size := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "zfs_pool_size_bytes", Help: "Size."}, []string{"pool"})
+	size.WithLabelValues("tank").Set(1000)
+
+	allocated := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "zfs_pool_allocated_bytes", Help: "Allocated."}, []string{"pool"})
+	allocated.WithLabelValues("tank").Set(250)
+
+	dedup := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "zfs_pool_dedup_ratio", Help: "Dedup."}, []string{"pool"})
+	dedup.WithLabelValues("tank").Set(1.5)
+
+	used := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "zfs_dataset_used_bytes", Help: "Used."}, []string{"dataset", "pool", "type"})
+	used.WithLabelValues("tank/home", "tank", "filesystem").Set(100)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(health, size, allocated, dedup, used)
+
+	return reg
+}
+
+func TestMetricsOptions_PDFCompat(t *testing.T) {
+	opts := MetricsOptions{PDFCompat: true, Renames: map[string]string{"zfs_pool_health": "zfs_pool_health_state"}}
+
+	expected := `
+		# HELP zfs_dataset_used_bytes The amount of space in bytes consumed by this dataset and all its descendents.
+		# TYPE zfs_dataset_used_bytes gauge
+		zfs_dataset_used_bytes{name="tank/home",pool="tank",type="filesystem"} 100
+		# HELP zfs_pool_capacity_ratio Ratio of pool space used.
+		# TYPE zfs_pool_capacity_ratio gauge
+		zfs_pool_capacity_ratio{pool="tank"} 0.25
+		# HELP zfs_pool_deduplication_ratio The ratio of deduplicated size vs undeduplicated size for data in this pool.
+		# TYPE zfs_pool_deduplication_ratio gauge
+		zfs_pool_deduplication_ratio{pool="tank"} 1.5
+		# HELP zfs_pool_health Health status code for the pool [0: ONLINE, 1: DEGRADED, 2: FAULTED, 3: OFFLINE, 4: UNAVAIL, 5: REMOVED, 6: SUSPENDED].
+		# TYPE zfs_pool_health gauge
+		zfs_pool_health{pool="tank"} 1
+		# HELP zfs_pool_health_state Pool health.
+		# TYPE zfs_pool_health_state gauge
+		zfs_pool_health_state{pool="tank",state="degraded"} 1
+		zfs_pool_health_state{pool="tank",state="online"} 0
+		zfs_pool_health_state{pool="tank",state="split"} 0
+		zfs_pool_health_state{pool="usb",state="degraded"} 0
+		zfs_pool_health_state{pool="usb",state="online"} 0
+		zfs_pool_health_state{pool="usb",state="split"} 1
+		# HELP zfs_pool_size_bytes Size.
+		# TYPE zfs_pool_size_bytes gauge
+		zfs_pool_size_bytes{pool="tank"} 1000
+	`
+
+	names := []string{
+		"zfs_dataset_used_bytes", "zfs_pool_capacity_ratio", "zfs_pool_deduplication_ratio",
+		"zfs_pool_health", "zfs_pool_health_state", "zfs_pool_size_bytes",
+	}
+
+	if err := testutil.GatherAndCompare(opts.Gatherer(newCompatRegistry(t), slog.New(slog.NewTextHandler(io.Discard, nil))), strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+
+	// Without the flag, the exporter's own shapes are served unchanged.
+	expected = `
+		# HELP zfs_dataset_used_bytes Used.
+		# TYPE zfs_dataset_used_bytes gauge
+		zfs_dataset_used_bytes{dataset="tank/home",pool="tank",type="filesystem"} 100
+	`
+
+	if err := testutil.GatherAndCompare(MetricsOptions{}.Gatherer(newCompatRegistry(t), slog.New(slog.NewTextHandler(io.Discard, nil))), strings.NewReader(expected),
+		"zfs_dataset_used_bytes", "zfs_pool_deduplication_ratio"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPDFMetrics(t *testing.T) {
+	families, err := newCompatRegistry(t).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mf := range pdfFamilies(families) {
+		if !slices.Contains(PDFMetrics, mf.GetName()) {
+			t.Errorf("%s is served in compatibility mode but missing from PDFMetrics", mf.GetName())
+		}
+	}
+}
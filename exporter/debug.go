@@ -0,0 +1,33 @@
+package exporter
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// DebugStateHandler returns an HTTP handler that serves the value returned
+// by state as indented JSON, or 503 until the first collection has run.
+func DebugStateHandler(state func() (any, bool), logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s, ok := state()
+		if !ok {
+			http.Error(w, "no collection has run yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			logger.Error("Failed to encode debug state", "err", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if _, err := w.Write(append(body, '\n')); err != nil {
+			logger.Debug("Failed to write debug state", "err", err)
+		}
+	}
+}
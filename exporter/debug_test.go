@@ -0,0 +1,30 @@
+package exporter
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestDebugStateHandler(t *testing.T) {
+	var state map[string]any
+
+	h := DebugStateHandler(func() (any, bool) { return state, state != nil }, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if code, _ := get(t, h); code != http.StatusServiceUnavailable {
+		t.Errorf("before collection: status = %d, want 503", code)
+	}
+
+	state = map[string]any{"errors": map[string]string{"scan": "zpool status failed"}}
+
+	code, body := get(t, h)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+
+	want := "{\n  \"errors\": {\n    \"scan\": \"zpool status failed\"\n  }\n}\n"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
@@ -0,0 +1,30 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// WriteMetrics gathers once from gatherer and writes the result to w in the
+// Prometheus text format. Metrics gathered before a collection error are
+// still written; the error is returned afterwards.
+func WriteMetrics(w io.Writer, gatherer prometheus.Gatherer) error {
+	families, gatherErr := gatherer.Gather()
+
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return fmt.Errorf("encoding %s: %w", mf.GetName(), err)
+		}
+	}
+
+	if gatherErr != nil {
+		return fmt.Errorf("gathering metrics: %w", gatherErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type failingCollector struct {
+	desc *prometheus.Desc
+}
+
+func (f failingCollector) Describe(ch chan<- *prometheus.Desc) { ch <- f.desc }
+
+func (f failingCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.NewInvalidMetric(f.desc, errors.New("boom"))
+}
+
+func TestWriteMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_up", Help: "Whether ZFS commands succeeded."})
+	up.Set(1)
+	reg.MustRegister(up)
+
+	var out strings.Builder
+	if err := WriteMetrics(&out, reg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# HELP zfs_up Whether ZFS commands succeeded.\n# TYPE zfs_up gauge\nzfs_up 1\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	reg.MustRegister(failingCollector{desc: prometheus.NewDesc("zfs_broken", "Broken.", nil, nil)})
+	out.Reset()
+
+	if err := WriteMetrics(&out, reg); err == nil {
+		t.Error("expected gather error")
+	}
+
+	if !strings.Contains(out.String(), "zfs_up 1") {
+		t.Errorf("metrics gathered before the error were not written: %q", out.String())
+	}
+}
@@ -0,0 +1,182 @@
+package exporter
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/donaldgifford/zfs_exporter/collector"
+	"github.com/donaldgifford/zfs_exporter/pkg/host"
+	"github.com/donaldgifford/zfs_exporter/pkg/kstat"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// DefaultScrapeTimeout is the scrape budget New uses when the collector
+// options leave Timeout unset, as --scrape.timeout does.
+const DefaultScrapeTimeout = 10 * time.Second
+
+// Exporter is the ZFS collector and the command durations it records, for
+// Go programs that embed ZFS metrics instead of running zfs_exporter. It is
+// a prometheus.Collector, to register with the program's own registry, and
+// Handler serves it alone.
+type Exporter struct {
+	coll      *collector.Collector
+	durations *collector.CommandDurations
+	handler   http.Handler
+}
+
+// settings collects the Options passed to New.
+type settings struct {
+	logger     *slog.Logger
+	runner     zfs.Runner
+	client     collector.ZFSClient
+	svcChecker *host.ServiceChecker
+	zpoolPath  string
+	zfsPath    string
+	labels     prometheus.Labels
+	collector  collector.Options
+	metrics    MetricsOptions
+}
+
+// An Option configures New.
+type Option func(*settings)
+
+// WithLogger sets the logger of the collector and the commands it runs.
+// The default discards all output.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *settings) { s.logger = logger }
+}
+
+// WithRunner sets the runner for zpool, zfs, and service checks, e.g. one
+// wrapped with zfs.SudoRunner or zfs.SSHRunner. The default is
+// zfs.DefaultRunner. Commands are timed either way.
+func WithRunner(runner zfs.Runner) Option {
+	return func(s *settings) { s.runner = runner }
+}
+
+// WithPaths sets the zpool and zfs binaries of the default client. The
+// default is to look both up in PATH.
+func WithPaths(zpoolPath, zfsPath string) Option {
+	return func(s *settings) { s.zpoolPath, s.zfsPath = zpoolPath, zfsPath }
+}
+
+// WithClient replaces the zpool and zfs client New builds from the runner,
+// for a configured *zfs.Client or another collector.ZFSClient. Its commands
+// only appear in zfs_command_duration_seconds if it runs them through a
+// zfs.TimedRunner of its own.
+func WithClient(client collector.ZFSClient) Option {
+	return func(s *settings) { s.client = client }
+}
+
+// WithServiceChecker replaces the service checker New builds from the
+// runner, e.g. to select the init system or service ports.
+func WithServiceChecker(checker *host.ServiceChecker) Option {
+	return func(s *settings) { s.svcChecker = checker }
+}
+
+// WithCollectorOptions sets the collector options. A zero Timeout means
+// DefaultScrapeTimeout and a nil Stats reads the local kstat tree.
+func WithCollectorOptions(opts collector.Options) Option {
+	return func(s *settings) { s.collector = opts }
+}
+
+// WithLabels adds constant labels, e.g. the host name, to every metric
+// Handler serves. To label the Exporter in another registry, register it
+// through prometheus.WrapRegistererWith.
+func WithLabels(labels prometheus.Labels) Option {
+	return func(s *settings) { s.labels = labels }
+}
+
+// WithMetricsOptions configures the handler returned by Handler.
+func WithMetricsOptions(opts MetricsOptions) Option {
+	return func(s *settings) { s.metrics = opts }
+}
+
+// New wires a ZFS client, service checker, and collector from opts. With no
+// options it runs zpool and zfs from PATH on the local host, with every
+// sub-collector at its default.
+//
+// Column detection (zfs.Client.DetectColumns) and the event watcher's Run
+// loop are left to the caller, as they block or outlive New.
+//
+// The node_zfs_<subsystem>_* metrics of the node_compat collector are named
+// after the stats the loaded module reports, so Describe does not send
+// their descriptors. With node_compat enabled, register the Exporter in a
+// registry without pedantic checks, such as one from prometheus.NewRegistry.
+func New(opts ...Option) (*Exporter, error) {
+	s := settings{
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		runner:    zfs.DefaultRunner(),
+		zpoolPath: "zpool",
+		zfsPath:   "zfs",
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	durations := collector.NewCommandDurations()
+	runner := zfs.TimedRunner(s.runner, durations.Observe)
+
+	client := s.client
+	if client == nil {
+		client = zfs.NewClient(runner, s.logger, s.zpoolPath, s.zfsPath)
+	}
+
+	svcChecker := s.svcChecker
+	if svcChecker == nil {
+		svcChecker = host.NewServiceChecker(runner, s.logger)
+	}
+
+	collOpts := s.collector
+	collOpts.Timeout = cmp.Or(collOpts.Timeout, DefaultScrapeTimeout)
+
+	if collOpts.Stats == nil {
+		collOpts.Stats = kstat.NewReader("")
+	}
+
+	e := &Exporter{
+		coll:      collector.NewCollector(client, svcChecker, s.logger, &collOpts),
+		durations: durations,
+	}
+
+	reg := prometheus.NewRegistry()
+	if err := prometheus.WrapRegistererWith(s.labels, reg).Register(e); err != nil {
+		return nil, fmt.Errorf("registering collectors: %w", err)
+	}
+
+	e.handler = MetricsHandler(reg, reg, s.metrics, s.logger)
+
+	return e, nil
+}
+
+// Describe implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	e.coll.Describe(ch)
+	e.durations.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.coll.Collect(ch)
+	e.durations.Collect(ch)
+}
+
+// Handler returns an HTTP handler serving the Exporter's metrics, with the
+// promhttp_metric_handler_* metrics of its own registry, to mount at a path
+// of the embedding program's choice.
+func (e *Exporter) Handler() http.Handler {
+	return e.handler
+}
+
+// Collector returns the underlying collector, which also serves
+// HealthHandler, LandingPageHandler, RegisterAdminHandlers, and the debug
+// state.
+func (e *Exporter) Collector() *collector.Collector {
+	return e.coll
+}
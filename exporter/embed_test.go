@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/donaldgifford/zfs_exporter/collector"
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+type fakeClient struct {
+	collector.ZFSClient
+}
+
+func (fakeClient) Features(context.Context) zfs.Features { return zfs.Features{} }
+
+func (fakeClient) GetPools(context.Context) ([]zfs.Pool, error) {
+	return []zfs.Pool{{Name: "tank", Size: 1000, Allocated: 250, Free: 750, Health: "ONLINE"}}, nil
+}
+
+func newTestExporter(t *testing.T, opts ...Option) *Exporter {
+	t.Helper()
+
+	enabled := make(map[string]bool, len(collector.CollectorNames))
+	for _, name := range collector.CollectorNames {
+		enabled[name] = false
+	}
+
+	opts = append([]Option{
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithClient(fakeClient{}),
+		WithCollectorOptions(collector.Options{Enabled: enabled}),
+	}, opts...)
+
+	e, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestNew_Handler(t *testing.T) {
+	e := newTestExporter(t, WithLabels(prometheus.Labels{"host": "nas1"}))
+
+	code, body := get(t, e.Handler())
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+
+	for _, want := range []string{
+		`zfs_up{host="nas1"} 1`,
+		`zfs_pool_allocated_bytes{host="nas1",pool="tank"} 250`,
+		"promhttp_metric_handler_requests_total",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body does not contain %q", want)
+		}
+	}
+
+	if _, _, ok := e.Collector().LastCollection(); !ok {
+		t.Error("LastCollection reports a failed collection")
+	}
+}
+
+func TestNew_Collector(t *testing.T) {
+	e := newTestExporter(t)
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(e); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+		# HELP zfs_up Whether ZFS commands succeeded.
+		# TYPE zfs_up gauge
+		zfs_up 1
+	`
+
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "zfs_up"); err != nil {
+		t.Error(err)
+	}
+
+	// The Exporter still serves its own registry once registered elsewhere.
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("handler status = %d, want 200", rec.Code)
+	}
+}
@@ -1,30 +1,128 @@
-// Package exporter provides HTTP handlers for the ZFS exporter.
+// Package exporter provides HTTP handlers for the ZFS exporter, and New for
+// embedding its metrics in other Go programs.
 package exporter
 
 import (
+	"bytes"
 	"fmt"
+	"html/template"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
+	"time"
 )
 
-// LandingPageHandler returns an HTTP handler that serves a simple landing page
-// with a link to the metrics endpoint.
-func LandingPageHandler(metricsPath string, logger *slog.Logger) http.HandlerFunc {
-	html := fmt.Sprintf(`<!DOCTYPE html>
+// StatusReporter exposes the collector state shown on the landing page and
+// checked by /healthz.
+type StatusReporter interface {
+	EnabledCollectors() map[string]bool
+	LastCollection() (finished time.Time, duration time.Duration, ok bool)
+	Stalled(threshold time.Duration) bool
+}
+
+// LandingLink is a link listed on the landing page.
+type LandingLink struct {
+	Text string
+	Href string
+}
+
+// LandingConfig is the static content of the landing page.
+type LandingConfig struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	// Links are listed in order, typically /metrics and /healthz first.
+	Links []LandingLink
+}
+
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
 <html>
 <head><title>ZFS Exporter</title></head>
 <body>
 <h1>ZFS Exporter</h1>
-<p><a href="%s">Metrics</a></p>
+<p>Version {{.Version}} (commit {{.Commit}}, built {{.BuildDate}})</p>
+<ul>
+{{- range .Links}}
+<li><a href="{{.Href}}">{{.Text}}</a></li>
+{{- end}}
+</ul>
+<h2>Last collection</h2>
+{{- if .Finished.IsZero}}
+<p>No collection has completed yet.</p>
+{{- else}}
+<p>{{if .OK}}Succeeded{{else}}Failed{{end}} at {{.Finished.Format "2006-01-02T15:04:05Z07:00"}} in {{.Duration}}.</p>
+{{- end}}
+<h2>Collectors</h2>
+<table>
+{{- range .Collectors}}
+<tr><td>{{.Name}}</td><td>{{if .Enabled}}enabled{{else}}disabled{{end}}</td></tr>
+{{- end}}
+</table>
 </body>
-</html>`, metricsPath)
+</html>
+`))
+
+// landingData is the template input for one request.
+type landingData struct {
+	LandingConfig
+	Finished   time.Time
+	Duration   time.Duration
+	OK         bool
+	Collectors []collectorState
+}
+
+type collectorState struct {
+	Name    string
+	Enabled bool
+}
 
+// LandingPageHandler returns an HTTP handler that serves a landing page with
+// build information, the last collection's outcome, the sub-collector
+// states, and links to the other endpoints.
+func LandingPageHandler(cfg *LandingConfig, status StatusReporter, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := landingData{LandingConfig: *cfg}
+
+		finished, duration, ok := status.LastCollection()
+		data.Finished, data.Duration, data.OK = finished.UTC(), duration.Round(time.Millisecond), ok
+
+		enabled := status.EnabledCollectors()
+		for _, name := range slices.Sorted(maps.Keys(enabled)) {
+			data.Collectors = append(data.Collectors, collectorState{Name: name, Enabled: enabled[name]})
+		}
 
-		if _, err := fmt.Fprint(w, html); err != nil {
-			logger.Error("Failed to write landing page", "err", err)
+		// Render to a buffer so a template error still yields a clean 500.
+		var buf bytes.Buffer
+		if err := landingTemplate.Execute(&buf, &data); err != nil {
+			logger.Error("Failed to render landing page", "err", err)
 			http.Error(w, "internal error", http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		if _, err := buf.WriteTo(w); err != nil {
+			logger.Debug("Failed to write landing page", "err", err)
+		}
+	}
+}
+
+// HealthHandler returns an HTTP handler for /healthz. It responds 503 while a
+// collection has been stuck for longer than stallThreshold and 200
+// otherwise; a failing zpool does not make the exporter itself unhealthy.
+func HealthHandler(status StatusReporter, stallThreshold time.Duration, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if status.Stalled(stallThreshold) {
+			http.Error(w, "collection stalled", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		if _, err := fmt.Fprintln(w, "ok"); err != nil {
+			logger.Debug("Failed to write health response", "err", err)
 		}
 	}
 }
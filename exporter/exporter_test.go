@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeStatus struct {
+	enabled  map[string]bool
+	finished time.Time
+	duration time.Duration
+	ok       bool
+	stalled  bool
+}
+
+func (f *fakeStatus) EnabledCollectors() map[string]bool { return f.enabled }
+
+func (f *fakeStatus) LastCollection() (time.Time, time.Duration, bool) {
+	return f.finished, f.duration, f.ok
+}
+
+func (f *fakeStatus) Stalled(time.Duration) bool { return f.stalled }
+
+func get(t *testing.T, h http.Handler) (int, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+	return rec.Code, rec.Body.String()
+}
+
+func TestLandingPageHandler(t *testing.T) {
+	status := &fakeStatus{enabled: map[string]bool{"vdev": true, "dataset": false}}
+	cfg := &LandingConfig{
+		Version:   "1.2.3",
+		Commit:    "abc123",
+		BuildDate: "2025-01-01",
+		Links:     []LandingLink{{Text: "Metrics", Href: "/metrics"}, {Text: "Health", Href: "/healthz"}},
+	}
+
+	h := LandingPageHandler(cfg, status, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	code, body := get(t, h)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+
+	for _, want := range []string{
+		"Version 1.2.3 (commit abc123, built 2025-01-01)",
+		`<a href="/metrics">Metrics</a>`,
+		`<a href="/healthz">Health</a>`,
+		"No collection has completed yet.",
+		"<tr><td>dataset</td><td>disabled</td></tr>\n<tr><td>vdev</td><td>enabled</td></tr>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("landing page missing %q:\n%s", want, body)
+		}
+	}
+
+	status.finished = time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)
+	status.duration = 1234567 * time.Microsecond
+
+	if _, body = get(t, h); !strings.Contains(body, "Failed at 2025-02-03T10:00:00Z in 1.235s.") {
+		t.Errorf("landing page missing failed collection:\n%s", body)
+	}
+
+	status.ok = true
+
+	if _, body = get(t, h); !strings.Contains(body, "Succeeded at 2025-02-03T10:00:00Z") {
+		t.Errorf("landing page missing successful collection:\n%s", body)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	status := &fakeStatus{}
+	h := HealthHandler(status, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if code, body := get(t, h); code != http.StatusOK || body != "ok\n" {
+		t.Errorf("healthy: got %d %q", code, body)
+	}
+
+	status.stalled = true
+
+	if code, _ := get(t, h); code != http.StatusServiceUnavailable {
+		t.Errorf("stalled: status = %d, want 503", code)
+	}
+}
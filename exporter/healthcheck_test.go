@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthURL(t *testing.T) {
+	tests := []struct {
+		listen  string
+		want    string
+		wantErr bool
+	}{
+		{listen: ":9134", want: "http://localhost:9134/healthz"},
+		{listen: "0.0.0.0:9134", want: "http://localhost:9134/healthz"},
+		{listen: "[::]:9134", want: "http://localhost:9134/healthz"},
+		{listen: "10.0.0.5:9100", want: "http://10.0.0.5:9100/healthz"},
+		{listen: "[fe80::1]:9134", want: "http://[fe80::1]:9134/healthz"},
+		{listen: "9134", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listen, func(t *testing.T) {
+			got, err := HealthURL(tt.listen)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("HealthURL(%q) = %q, want %q", tt.listen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthcheck(t *testing.T) {
+	status := &fakeStatus{}
+
+	srv := httptest.NewServer(HealthHandler(status, 0, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	t.Cleanup(srv.Close)
+
+	if err := Healthcheck(context.Background(), srv.URL); err != nil {
+		t.Errorf("healthy exporter: %v", err)
+	}
+
+	status.stalled = true
+
+	err := Healthcheck(context.Background(), srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "503 Service Unavailable: collection stalled") {
+		t.Errorf("stalled exporter: error = %v", err)
+	}
+
+	srv.Close()
+
+	if err := Healthcheck(context.Background(), srv.URL); err == nil {
+		t.Error("expected error for unreachable exporter")
+	}
+
+	if err := Healthcheck(context.Background(), "http://%zz"); err == nil {
+		t.Error("expected error for invalid URL")
+	}
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsOptions configures the exposition handlers.
+type MetricsOptions struct {
+	// MaxRequestsInFlight limits concurrent scrapes; further requests get
+	// 503. Zero means no limit.
+	MaxRequestsInFlight int
+	// Timeout aborts a scrape with 503 after this long. Zero means none.
+	Timeout time.Duration
+	// DisableCompression turns off gzip even when the scraper accepts it.
+	DisableCompression bool
+	// HostnameLabel, if set, labels every metric served by /probe with the
+	// target name. /metrics is labeled where its collectors are registered.
+	HostnameLabel string
+	// Renames maps metric names to the names served instead (see
+	// RenameGatherer).
+	Renames map[string]string
+	// PDFCompat adds the metric names and shapes of pdf/zfs_exporter (see
+	// PDFReplacedMetrics).
+	PDFCompat bool
+}
+
+// handlerOpts returns the promhttp options shared by /metrics and /probe.
+// OpenMetrics is offered to scrapers that ask for it; others get the classic
+// text format.
+func (o MetricsOptions) handlerOpts(logger *slog.Logger) promhttp.HandlerOpts {
+	return promhttp.HandlerOpts{
+		ErrorLog:            slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		EnableOpenMetrics:   true,
+		DisableCompression:  o.DisableCompression,
+		MaxRequestsInFlight: o.MaxRequestsInFlight,
+		Timeout:             o.Timeout,
+	}
+}
+
+// MetricsHandler returns the /metrics handler for gatherer, instrumented
+// with the promhttp_metric_handler_* metrics registered on reg.
+func MetricsHandler(reg prometheus.Registerer, gatherer prometheus.Gatherer, opts MetricsOptions, logger *slog.Logger) http.Handler {
+	handlerOpts := opts.handlerOpts(logger)
+	handlerOpts.Registry = reg
+
+	return promhttp.InstrumentMetricHandler(reg, promhttp.HandlerFor(opts.Gatherer(gatherer, logger), handlerOpts))
+}
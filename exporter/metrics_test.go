@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         MetricsOptions
+		accept       string
+		wantType     string
+		wantEncoding string
+	}{
+		{
+			name:         "text gzip",
+			accept:       "text/plain",
+			wantType:     "text/plain",
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "openmetrics",
+			accept:       "application/openmetrics-text;version=1.0.0",
+			wantType:     "application/openmetrics-text",
+			wantEncoding: "gzip",
+		},
+		{
+			name:     "compression disabled",
+			opts:     MetricsOptions{DisableCompression: true},
+			accept:   "text/plain",
+			wantType: "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_up", Help: "Whether ZFS commands succeeded."}))
+
+			srv := httptest.NewServer(MetricsHandler(reg, reg, tt.opts, slog.New(slog.NewTextHandler(io.Discard, nil))))
+			t.Cleanup(srv.Close)
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Accept", tt.accept)
+			// Setting Accept-Encoding stops the transport from transparently
+			// decompressing, so Content-Encoding is visible.
+			req.Header.Set("Accept-Encoding", "gzip")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp.Body.Close()
+
+			if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %s", got, tt.wantType)
+			}
+
+			if got := resp.Header.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// RegisterPprofHandlers mounts the net/http/pprof profiling handlers under
+// /debug/pprof/ on mux. They expose goroutine stacks and command lines, so
+// only mount them on a listener restricted to operators.
+func RegisterPprofHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ProbeHandler returns a blackbox-style handler for GET /probe?target=<name>
+// that serves the metrics of one configured remote target. Only names in
+// targets are accepted, so the query string cannot point the exporter at an
+// arbitrary host. With opts.HostnameLabel set, the target name is added to
+// every metric under that label.
+func ProbeHandler(targets map[string]prometheus.Collector, opts MetricsOptions, logger *slog.Logger) http.HandlerFunc {
+	handlerOpts := opts.handlerOpts(logger)
+	// The in-flight limit is per handler, and a probe handler lives for one
+	// request only.
+	handlerOpts.MaxRequestsInFlight = 0
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("target")
+		if name == "" {
+			http.Error(w, "target parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		coll, ok := targets[name]
+		if !ok {
+			http.Error(w, "unknown target", http.StatusBadRequest)
+			return
+		}
+
+		reg := prometheus.NewRegistry()
+
+		var wrapped prometheus.Registerer = reg
+		if opts.HostnameLabel != "" {
+			wrapped = prometheus.WrapRegistererWith(prometheus.Labels{opts.HostnameLabel: name}, reg)
+		}
+
+		if err := wrapped.Register(coll); err != nil {
+			logger.Error("Failed to register probe collector", "target", name, "err", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+
+			return
+		}
+
+		promhttp.HandlerFor(opts.Gatherer(reg, logger), handlerOpts).ServeHTTP(w, r)
+	}
+}
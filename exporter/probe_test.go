@@ -0,0 +1,71 @@
+package exporter
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestProbeHandler(t *testing.T) {
+	nas1 := prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_up", Help: "Whether ZFS commands succeeded."})
+	nas1.Set(1)
+
+	handler := ProbeHandler(map[string]prometheus.Collector{"nas1": nas1}, MetricsOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"known target", "?target=nas1", http.StatusOK, "zfs_up 1"},
+		{"repeat scrape", "?target=nas1", http.StatusOK, "zfs_up 1"},
+		{"unknown target", "?target=evil.example.com", http.StatusBadRequest, "unknown target"},
+		{"missing target", "", http.StatusBadRequest, "target parameter is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProbeHandler_HostnameLabel(t *testing.T) {
+	nas1 := prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_up", Help: "Whether ZFS commands succeeded."})
+	nas1.Set(1)
+
+	handler := ProbeHandler(map[string]prometheus.Collector{"nas1": nas1}, MetricsOptions{HostnameLabel: "host"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?target=nas1", http.NoBody))
+
+	if want := `zfs_up{host="nas1"} 1`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("body = %q, want it to contain %q", rec.Body.String(), want)
+	}
+}
@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// RenameGatherer returns a Gatherer that renames the metric families of g
+// from the keys of renames to their values, e.g. to keep the names of
+// another exporter during a migration. Histogram and summary series follow
+// their family. Families left out of renames keep their names; an empty map
+// returns g itself.
+//
+// A rename onto the name of another gathered family, renamed or not, drops
+// the renamed family and logs the conflict once, so the rest of the scrape
+// is still served.
+func RenameGatherer(g prometheus.Gatherer, renames map[string]string, logger *slog.Logger) prometheus.Gatherer {
+	if len(renames) == 0 {
+		return g
+	}
+
+	r := newRenamer(renames, logger)
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+
+		return r.rename(families), err
+	})
+}
+
+// renamer renames gathered families, remembering which conflicts it has
+// logged.
+type renamer struct {
+	renames map[string]string
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	logged map[string]bool
+}
+
+func newRenamer(renames map[string]string, logger *slog.Logger) *renamer {
+	return &renamer{renames: renames, logger: logger, logged: make(map[string]bool)}
+}
+
+// rename renames families in place and returns them sorted by name, as
+// Gather requires, without the families whose new name is taken.
+func (r *renamer) rename(families []*dto.MetricFamily) []*dto.MetricFamily {
+	final := make(map[string]int, len(families))
+	for _, mf := range families {
+		final[cmp.Or(r.renames[mf.GetName()], mf.GetName())]++
+	}
+
+	kept := families[:0]
+
+	for _, mf := range families {
+		to, ok := r.renames[mf.GetName()]
+
+		switch {
+		case !ok:
+		case final[to] > 1:
+			r.logConflict(mf.GetName(), to)
+			continue
+		default:
+			mf.Name = &to
+		}
+
+		kept = append(kept, mf)
+	}
+
+	slices.SortFunc(kept, func(a, b *dto.MetricFamily) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+
+	return kept
+}
+
+// logConflict logs the first scrape that drops from for renaming onto a
+// name in use.
+func (r *renamer) logConflict(from, to string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.logged[from] {
+		return
+	}
+
+	r.logged[from] = true
+	r.logger.Warn("Dropping renamed metric: name already in use", "metric", from, "rename", to)
+}
@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRenameGatherer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_up", Help: "Whether ZFS commands succeeded."})
+	up.Set(1)
+
+	health := prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_pool_health", Help: "Pool health."})
+	durations := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "zfs_command_duration_seconds", Help: "Durations.", Buckets: []float64{1}})
+	reg.MustRegister(up, health, durations)
+
+	tests := []struct {
+		name    string
+		renames map[string]string
+		want    string
+		names   []string
+	}{
+		{
+			name:    "rename",
+			renames: map[string]string{"zfs_up": "zfs_exporter_up", "zfs_command_duration_seconds": "zfs_cmd_seconds"},
+			want: `
+				# HELP zfs_cmd_seconds Durations.
+				# TYPE zfs_cmd_seconds histogram
+				zfs_cmd_seconds_bucket{le="1"} 0
+				zfs_cmd_seconds_bucket{le="+Inf"} 0
+				zfs_cmd_seconds_sum 0
+				zfs_cmd_seconds_count 0
+				# HELP zfs_exporter_up Whether ZFS commands succeeded.
+				# TYPE zfs_exporter_up gauge
+				zfs_exporter_up 1
+			`,
+			names: []string{"zfs_cmd_seconds", "zfs_exporter_up", "zfs_up"},
+		},
+		{
+			name:    "swap",
+			renames: map[string]string{"zfs_up": "zfs_pool_health", "zfs_pool_health": "zfs_up"},
+			want: `
+				# HELP zfs_pool_health Whether ZFS commands succeeded.
+				# TYPE zfs_pool_health gauge
+				zfs_pool_health 1
+			`,
+			names: []string{"zfs_pool_health"},
+		},
+		{
+			name:    "conflict",
+			renames: map[string]string{"zfs_up": "zfs_pool_health"},
+			want: `
+				# HELP zfs_pool_health Pool health.
+				# TYPE zfs_pool_health gauge
+				zfs_pool_health 0
+			`,
+			names: []string{"zfs_pool_health", "zfs_up"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := RenameGatherer(reg, tt.renames, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			if err := testutil.GatherAndCompare(g, strings.NewReader(tt.want), tt.names...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_RenameConflict(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_a", Help: "A."}),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "zfs_b", Help: "B."}),
+	)
+
+	opts := MetricsOptions{Renames: map[string]string{"zfs_a": "zfs_b"}}
+	h := MetricsHandler(prometheus.NewRegistry(), reg, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+	// The conflicting family is dropped; the scrape still succeeds.
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "zfs_a") || !strings.Contains(rec.Body.String(), "zfs_b 0") {
+		t.Errorf("got %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
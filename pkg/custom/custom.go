@@ -0,0 +1,254 @@
+// Package custom runs operator-defined metric hooks: external commands or ZFS
+// channel programs whose JSON output is turned into samples. Hooks are
+// executed through the same zfs.Runner as every other command, so they share
+// its no-shell execution model and test injection.
+package custom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// DefaultTimeout bounds a hook that does not set its own timeout.
+const DefaultTimeout = 5 * time.Second
+
+// nameRe restricts hook and label names to the Prometheus name charset.
+var nameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedNames are hook names whose zfs_custom_<name> metric the collector
+// already exports for every hook.
+var reservedNames = map[string]bool{"success": true, "duration_seconds": true}
+
+// Hook is a single operator-defined metric source.
+type Hook struct {
+	// Name becomes the metric name suffix: zfs_custom_<name>.
+	Name string `json:"name"`
+
+	// Help is the metric help text.
+	Help string `json:"help"`
+
+	// Labels declares the label names every sample must carry.
+	Labels []string `json:"labels"`
+
+	// Command is an argv (no shell) to execute. Mutually exclusive with
+	// ChannelProgram.
+	Command []string `json:"command"`
+
+	// ChannelProgram is the path to a Lua script run read-only via
+	// "zfs program -j -n <pool> <script> [args...]".
+	ChannelProgram string   `json:"channel_program"`
+	Pool           string   `json:"pool"`
+	Args           []string `json:"args"`
+
+	// Timeout bounds a single execution (e.g. "5s"). Defaults to DefaultTimeout.
+	Timeout string `json:"timeout"`
+
+	timeout time.Duration
+}
+
+// Sample is one value emitted by a hook.
+type Sample struct {
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// ErrInvalidHook is returned for hook definitions that fail validation.
+var ErrInvalidHook = errors.New("invalid custom hook")
+
+// LoadHooks reads and validates a JSON array of hooks from path.
+func LoadHooks(path string) ([]Hook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading custom hooks file: %w", err)
+	}
+
+	var hooks []Hook
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("parsing custom hooks file %s: %w", path, err)
+	}
+
+	if err := ValidateHooks(hooks); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// ValidateHooks checks names, labels, and execution settings, and resolves
+// each hook's timeout.
+func ValidateHooks(hooks []Hook) error {
+	var errs []error
+
+	seen := make(map[string]bool, len(hooks))
+
+	for i := range hooks {
+		h := &hooks[i]
+
+		switch {
+		case !nameRe.MatchString(h.Name):
+			errs = append(errs, fmt.Errorf("%w: hook[%d]: invalid name %q", ErrInvalidHook, i, h.Name))
+		case reservedNames[h.Name]:
+			errs = append(errs, fmt.Errorf("%w: hook[%d]: name %q is reserved", ErrInvalidHook, i, h.Name))
+		}
+
+		if seen[h.Name] {
+			errs = append(errs, fmt.Errorf("%w: hook %q: duplicate name", ErrInvalidHook, h.Name))
+		}
+
+		seen[h.Name] = true
+
+		for _, l := range h.Labels {
+			if !nameRe.MatchString(l) || l == "hook" {
+				errs = append(errs, fmt.Errorf("%w: hook %q: invalid label %q", ErrInvalidHook, h.Name, l))
+			}
+		}
+
+		switch {
+		case len(h.Command) == 0 && h.ChannelProgram == "":
+			errs = append(errs, fmt.Errorf("%w: hook %q: one of command or channel_program is required", ErrInvalidHook, h.Name))
+		case len(h.Command) > 0 && h.ChannelProgram != "":
+			errs = append(errs, fmt.Errorf("%w: hook %q: command and channel_program are mutually exclusive", ErrInvalidHook, h.Name))
+		case h.ChannelProgram != "" && h.Pool == "":
+			errs = append(errs, fmt.Errorf("%w: hook %q: channel_program requires pool", ErrInvalidHook, h.Name))
+		}
+
+		h.timeout = DefaultTimeout
+
+		if h.Timeout != "" {
+			d, err := time.ParseDuration(h.Timeout)
+			if err != nil || d <= 0 {
+				errs = append(errs, fmt.Errorf("%w: hook %q: invalid timeout %q", ErrInvalidHook, h.Name, h.Timeout))
+			} else {
+				h.timeout = d
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Runner executes hooks.
+type Runner struct {
+	runner  zfs.Runner
+	zfsPath string
+}
+
+// NewRunner creates a hook Runner. zfsPath is used for channel programs.
+func NewRunner(runner zfs.Runner, zfsPath string) *Runner {
+	return &Runner{runner: runner, zfsPath: zfsPath}
+}
+
+// Run executes a hook within its timeout and returns its samples. Every
+// sample must carry exactly the hook's declared labels, and no two samples
+// the same label values.
+func (r *Runner) Run(ctx context.Context, h *Hook) ([]Sample, error) {
+	timeout := h.timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	name, args := h.argv(r.zfsPath)
+
+	out, err := r.runner(ctx, name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("custom hook %q failed: %w", h.Name, err)
+	}
+
+	samples, err := parseOutput(out)
+	if err != nil {
+		return nil, fmt.Errorf("custom hook %q: %w", h.Name, err)
+	}
+
+	seen := make(map[string]bool, len(samples))
+
+	for _, s := range samples {
+		if len(s.Labels) != len(h.Labels) {
+			return nil, fmt.Errorf("custom hook %q: sample labels %v do not match declared labels %v", h.Name, s.Labels, h.Labels)
+		}
+
+		for _, l := range h.Labels {
+			if _, ok := s.Labels[l]; !ok {
+				return nil, fmt.Errorf("custom hook %q: sample missing declared label %q", h.Name, l)
+			}
+		}
+
+		key := sampleKey(h.Labels, s.Labels)
+		if seen[key] {
+			return nil, fmt.Errorf("custom hook %q: duplicate sample for labels %v", h.Name, s.Labels)
+		}
+
+		seen[key] = true
+	}
+
+	return samples, nil
+}
+
+// sampleKey identifies a sample by its values of the declared labels.
+func sampleKey(names []string, labels map[string]string) string {
+	var b strings.Builder
+
+	for _, l := range names {
+		b.WriteString(labels[l] + "\xff")
+	}
+
+	return b.String()
+}
+
+func (h *Hook) argv(zfsPath string) (string, []string) {
+	if h.ChannelProgram != "" {
+		args := append([]string{"program", "-j", "-n", h.Pool, h.ChannelProgram}, h.Args...)
+		return zfsPath, args
+	}
+
+	return h.Command[0], h.Command[1:]
+}
+
+// parseOutput accepts a bare number, a single sample object, or an array of
+// samples. Channel program output ({"return": ...}) is unwrapped first.
+func parseOutput(data []byte) ([]Sample, error) {
+	var wrapped struct {
+		Return json.RawMessage `json:"return"`
+	}
+
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Return != nil {
+		data = wrapped.Return
+	}
+
+	var num float64
+	if err := json.Unmarshal(data, &num); err == nil {
+		return []Sample{{Value: num}}, nil
+	}
+
+	var samples []Sample
+	if err := strictUnmarshal(data, &samples); err == nil {
+		return samples, nil
+	}
+
+	var single Sample
+	if err := strictUnmarshal(data, &single); err != nil {
+		return nil, fmt.Errorf("output is not a number, sample, or sample array: %w", err)
+	}
+
+	return []Sample{single}, nil
+}
+
+// strictUnmarshal rejects unknown fields so arbitrary JSON objects are not
+// silently read as zero-valued samples.
+func strictUnmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(v)
+}
@@ -0,0 +1,185 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Sample
+		wantErr bool
+	}{
+		{name: "bare number", input: "42", want: []Sample{{Value: 42}}},
+		{
+			name:  "sample array",
+			input: `[{"labels":{"pool":"tank"},"value":1.5},{"labels":{"pool":"backup"},"value":2}]`,
+			want: []Sample{
+				{Labels: map[string]string{"pool": "tank"}, Value: 1.5},
+				{Labels: map[string]string{"pool": "backup"}, Value: 2},
+			},
+		},
+		{name: "single sample", input: `{"labels":{"pool":"tank"},"value":7}`, want: []Sample{{Labels: map[string]string{"pool": "tank"}, Value: 7}}},
+		{name: "channel program return", input: `{"return": 12}`, want: []Sample{{Value: 12}}},
+		{
+			name:  "channel program return samples",
+			input: `{"return": [{"labels":{"pool":"tank"},"value":3}]}`,
+			want:  []Sample{{Labels: map[string]string{"pool": "tank"}, Value: 3}},
+		},
+		{name: "unknown object", input: `{"foo": 1}`, wantErr: true},
+		{name: "string", input: `"hello"`, wantErr: true},
+		{name: "garbage", input: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOutput([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d samples, want %d", len(got), len(tt.want))
+			}
+
+			for i := range got {
+				if got[i].Value != tt.want[i].Value || len(got[i].Labels) != len(tt.want[i].Labels) {
+					t.Errorf("[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+
+				for k, v := range tt.want[i].Labels {
+					if got[i].Labels[k] != v {
+						t.Errorf("[%d] label %s = %q, want %q", i, k, got[i].Labels[k], v)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hooks   []Hook
+		wantErr bool
+	}{
+		{name: "command", hooks: []Hook{{Name: "ok", Command: []string{"/bin/true"}}}},
+		{name: "channel program", hooks: []Hook{{Name: "ok", ChannelProgram: "/etc/x.lua", Pool: "tank"}}},
+		{name: "bad name", hooks: []Hook{{Name: "bad-name", Command: []string{"/bin/true"}}}, wantErr: true},
+		{name: "bad label", hooks: []Hook{{Name: "ok", Labels: []string{"a b"}, Command: []string{"/bin/true"}}}, wantErr: true},
+		{name: "no source", hooks: []Hook{{Name: "ok"}}, wantErr: true},
+		{name: "both sources", hooks: []Hook{{Name: "ok", Command: []string{"x"}, ChannelProgram: "y", Pool: "tank"}}, wantErr: true},
+		{name: "program without pool", hooks: []Hook{{Name: "ok", ChannelProgram: "y"}}, wantErr: true},
+		{name: "bad timeout", hooks: []Hook{{Name: "ok", Command: []string{"x"}, Timeout: "soon"}}, wantErr: true},
+		{name: "reserved success", hooks: []Hook{{Name: "success", Command: []string{"x"}}}, wantErr: true},
+		{name: "reserved duration", hooks: []Hook{{Name: "duration_seconds", Command: []string{"x"}}}, wantErr: true},
+		{name: "duplicate", hooks: []Hook{{Name: "a", Command: []string{"x"}}, {Name: "a", Command: []string{"x"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHooks(tt.hooks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidHook) {
+				t.Errorf("expected ErrInvalidHook, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks.json")
+	content := `[{"name":"snaps","labels":["pool"],"command":["/usr/local/bin/snaps","--json"],"timeout":"2s"}]`
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks, err := LoadHooks(path)
+	if err != nil {
+		t.Fatalf("LoadHooks: %v", err)
+	}
+
+	if len(hooks) != 1 || hooks[0].timeout != 2*time.Second {
+		t.Errorf("unexpected hooks: %+v", hooks)
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	var gotName string
+
+	var gotArgs []string
+
+	runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		gotName, gotArgs = name, args
+		return []byte(`{"return": [{"labels":{"pool":"tank"},"value":5}]}`), nil
+	}
+
+	hooks := []Hook{{Name: "txg", Labels: []string{"pool"}, ChannelProgram: "/etc/txg.lua", Pool: "tank", Args: []string{"x"}}}
+	if err := ValidateHooks(hooks); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := NewRunner(runner, "/sbin/zfs").Run(context.Background(), &hooks[0])
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if gotName != "/sbin/zfs" || !slices.Equal(gotArgs, []string{"program", "-j", "-n", "tank", "/etc/txg.lua", "x"}) {
+		t.Errorf("unexpected argv: %s %v", gotName, gotArgs)
+	}
+
+	if len(samples) != 1 || samples[0].Value != 5 {
+		t.Errorf("unexpected samples: %+v", samples)
+	}
+}
+
+func TestRunner_Run_LabelMismatch(t *testing.T) {
+	runner := func(_ context.Context, _ string, _ ...string) ([]byte, error) {
+		return []byte(`[{"labels":{"other":"x"},"value":1}]`), nil
+	}
+
+	h := Hook{Name: "x", Labels: []string{"pool"}, Command: []string{"/bin/x"}}
+
+	if _, err := NewRunner(runner, "zfs").Run(context.Background(), &h); err == nil {
+		t.Fatal("expected label mismatch error")
+	}
+}
+
+func TestRunner_Run_DuplicateSamples(t *testing.T) {
+	runner := func(_ context.Context, _ string, _ ...string) ([]byte, error) {
+		return []byte(`[{"labels":{"pool":"tank"},"value":1},{"labels":{"pool":"tank"},"value":2}]`), nil
+	}
+
+	h := Hook{Name: "x", Labels: []string{"pool"}, Command: []string{"/bin/x"}}
+
+	if _, err := NewRunner(runner, "zfs").Run(context.Background(), &h); err == nil {
+		t.Fatal("expected duplicate sample error")
+	}
+}
+
+func TestRunner_Run_Timeout(t *testing.T) {
+	runner := func(ctx context.Context, _ string, _ ...string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	hooks := []Hook{{Name: "slow", Command: []string{"/bin/sleep"}, Timeout: "10ms"}}
+	if err := ValidateHooks(hooks); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewRunner(runner, "zfs").Run(context.Background(), &hooks[0]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
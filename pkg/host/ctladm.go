@@ -0,0 +1,109 @@
+package host
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+)
+
+// ctladmPortList is the subset of ctladm portlist -x output that is read.
+// Each iSCSI port is one target in one portal group, with its LUN mapping
+// (target LUN ID to CTL LUN number).
+type ctladmPortList struct {
+	Ports []struct {
+		Frontend string `xml:"frontend_type"`
+		Target   string `xml:"cfiscsi_target"`
+		LUNs     []struct {
+			ID  string `xml:"id,attr"`
+			LUN string `xml:",chardata"`
+		} `xml:"lun"`
+	} `xml:"targ_port"`
+}
+
+// ctladmLUNList is the subset of ctladm devlist -x output that is read.
+type ctladmLUNList struct {
+	LUNs []struct {
+		ID   string `xml:"id,attr"`
+		File string `xml:"file"`
+		Name string `xml:"ctld_name"`
+	} `xml:"lun"`
+}
+
+// ctladmSessionList is the subset of ctladm islist -x output that is read.
+type ctladmSessionList struct {
+	Connections []struct {
+		Initiator string `xml:"initiator"`
+	} `xml:"connection"`
+}
+
+// readCtladm reads the CTL target configuration through ctladm. A target
+// reachable through several portal groups is counted once.
+func (r *ISCSIReader) readCtladm(ctx context.Context) (ISCSIStatus, error) {
+	var (
+		ports    ctladmPortList
+		devs     ctladmLUNList
+		sessions ctladmSessionList
+	)
+
+	for _, cmd := range []struct {
+		args []string
+		dst  any
+	}{
+		{[]string{"portlist", "-x", "-f", "iscsi"}, &ports},
+		{[]string{"devlist", "-x"}, &devs},
+		{[]string{"islist", "-x"}, &sessions},
+	} {
+		if err := r.ctladmXML(ctx, cmd.dst, cmd.args...); err != nil {
+			return ISCSIStatus{}, err
+		}
+	}
+
+	backing := make(map[string]int, len(devs.LUNs))
+	for i, d := range devs.LUNs {
+		backing[d.ID] = i
+	}
+
+	st := ISCSIStatus{Sessions: len(sessions.Connections)}
+
+	targets := make(map[string]bool)
+	seen := make(map[ISCSILUN]bool)
+
+	for _, p := range ports.Ports {
+		if p.Frontend != "iscsi" || p.Target == "" {
+			continue
+		}
+
+		if !targets[p.Target] {
+			targets[p.Target] = true
+			st.Targets++
+		}
+
+		for _, l := range p.LUNs {
+			lun := ISCSILUN{Target: p.Target, LUN: l.ID}
+			if i, ok := backing[l.LUN]; ok {
+				lun.Backstore, lun.Device = devs.LUNs[i].Name, devs.LUNs[i].File
+			}
+
+			if !seen[lun] {
+				seen[lun] = true
+				st.LUNs = append(st.LUNs, lun)
+			}
+		}
+	}
+
+	return st, nil
+}
+
+// ctladmXML runs ctladm with args and decodes its XML output into dst.
+func (r *ISCSIReader) ctladmXML(ctx context.Context, dst any, args ...string) error {
+	out, err := r.runner(ctx, r.ctladm, args...)
+	if err != nil {
+		return fmt.Errorf("ctladm %s failed: %w", args[0], err)
+	}
+
+	if err := xml.Unmarshal(out, dst); err != nil {
+		return fmt.Errorf("failed to parse ctladm %s output: %w", args[0], err)
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Directories searched, in order, for a vdev device name that is not an
+// absolute path. zpool status prints names relative to the directory the
+// pool was imported from.
+var deviceDirs = []string{"", "disk/by-id", "disk/by-vdev", "disk/by-path", "disk/by-partlabel", "disk/by-uuid"}
+
+// byIDFallbacks are /dev/disk/by-id prefixes naming a device by an opaque
+// identifier rather than its model and serial. They are only chosen when a
+// device has no other by-id link.
+var byIDFallbacks = []string{"wwn-", "nvme-eui.", "nvme-nvme."}
+
+// vpdSerialHeader is the length of the SCSI Unit Serial Number VPD page
+// header (page 0x80) preceding the serial in sysfs vpd_pg80.
+const vpdSerialHeader = 4
+
+// DeviceIdentity names a block device in ways that survive a reboot, unlike
+// the kernel name (sdX), which follows probe order.
+type DeviceIdentity struct {
+	Path   string // kernel device node, e.g. "/dev/sda1"
+	ByID   string // link name under /dev/disk/by-id, "" if the device has none
+	Serial string // serial number of the disk holding the device, "" if unreadable
+}
+
+// DeviceResolver maps vdev device names from zpool status to block devices
+// under /dev and their stable identities.
+type DeviceResolver struct {
+	devRoot string
+	sysRoot string
+}
+
+// NewDeviceResolver creates a DeviceResolver for the host's /dev and /sys.
+func NewDeviceResolver() *DeviceResolver {
+	return &DeviceResolver{devRoot: "/dev", sysRoot: "/sys"}
+}
+
+// SetRoots sets where the device nodes and sysfs are mounted, for containers
+// that see the host's /dev and /sys elsewhere.
+func (r *DeviceResolver) SetRoots(devRoot, sysRoot string) {
+	r.devRoot, r.sysRoot = devRoot, sysRoot
+}
+
+// Path resolves a vdev device name from zpool status to its device node,
+// e.g. "ata-WDC_WD40-part1" to "/dev/sda1". It returns false for names that
+// are not block devices under /dev, such as file vdevs.
+func (r *DeviceResolver) Path(name string) (string, bool) {
+	var candidates []string
+
+	if filepath.IsAbs(name) {
+		if !strings.HasPrefix(name, r.devRoot+"/") {
+			return "", false
+		}
+
+		candidates = []string{name}
+	} else {
+		for _, dir := range deviceDirs {
+			candidates = append(candidates, filepath.Join(r.devRoot, dir, name))
+		}
+	}
+
+	for _, c := range candidates {
+		if resolved, err := filepath.EvalSymlinks(c); err == nil {
+			return resolved, true
+		}
+	}
+
+	return "", false
+}
+
+// WholeDisk returns the disk holding the partition at path, or path itself
+// if it is not a partition. Partitions are found through sysfs, where
+// /sys/class/block/<part> links into its disk's directory.
+func (r *DeviceResolver) WholeDisk(path string) string {
+	block := filepath.Join(r.sysRoot, "class", "block", filepath.Base(path))
+
+	if _, err := os.Stat(filepath.Join(block, "partition")); err != nil {
+		return path
+	}
+
+	link, err := filepath.EvalSymlinks(block)
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(path), filepath.Base(filepath.Dir(link)))
+}
+
+// Identify returns the identity of each name that resolves to a block
+// device, keyed by name. /dev/disk/by-id is read once for all names. A name
+// that is itself a by-id link keeps it; otherwise the device's first link
+// by name is chosen, preferring model and serial names over WWNs and EUIs.
+func (r *DeviceResolver) Identify(names []string) map[string]DeviceIdentity {
+	byID := r.byIDLinks()
+	ids := make(map[string]DeviceIdentity, len(names))
+
+	for _, name := range names {
+		path, ok := r.Path(name)
+		if !ok {
+			continue
+		}
+
+		id := DeviceIdentity{Path: path, Serial: r.serial(r.WholeDisk(path))}
+
+		links := byID[path]
+		if slices.Contains(links, name) {
+			id.ByID = name
+		} else if len(links) > 0 {
+			id.ByID = links[0]
+		}
+
+		ids[name] = id
+	}
+
+	return ids
+}
+
+// byIDLinks returns the /dev/disk/by-id links of each device node, in
+// order of preference. A missing directory yields no links.
+func (r *DeviceResolver) byIDLinks() map[string][]string {
+	dir := filepath.Join(r.devRoot, "disk", "by-id")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	links := make(map[string][]string)
+
+	for _, e := range entries {
+		target, err := filepath.EvalSymlinks(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+
+		links[target] = append(links[target], e.Name())
+	}
+
+	for _, names := range links {
+		slices.SortFunc(names, func(a, b string) int {
+			if fa, fb := isByIDFallback(a), isByIDFallback(b); fa != fb {
+				if fa {
+					return 1
+				}
+
+				return -1
+			}
+
+			return strings.Compare(a, b)
+		})
+	}
+
+	return links
+}
+
+// isByIDFallback reports whether a by-id link name is an opaque identifier.
+func isByIDFallback(name string) bool {
+	return slices.ContainsFunc(byIDFallbacks, func(prefix string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+}
+
+// serial reads the serial number of the disk at path from sysfs: the
+// serial attribute of NVMe and some SCSI devices, or else the Unit Serial
+// Number VPD page, which SCSI and libata (SATA) disks provide.
+func (r *DeviceResolver) serial(path string) string {
+	device := filepath.Join(r.sysRoot, "class", "block", filepath.Base(path), "device")
+
+	if b, err := os.ReadFile(filepath.Join(device, "serial")); err == nil {
+		if s := strings.TrimSpace(string(b)); s != "" {
+			return s
+		}
+	}
+
+	b, err := os.ReadFile(filepath.Join(device, "vpd_pg80"))
+	if err != nil || len(b) <= vpdSerialHeader {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.Trim(string(b[vpdSerialHeader:]), "\x00"))
+}
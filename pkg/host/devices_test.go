@@ -0,0 +1,86 @@
+package host
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeviceResolver_Identify(t *testing.T) {
+	root := t.TempDir()
+	dev := filepath.Join(root, "dev")
+	sys := filepath.Join(root, "sys")
+	byID := filepath.Join(dev, "disk", "by-id")
+
+	// A SATA disk sda with partition sda1 and its serial in the VPD page,
+	// an NVMe disk with a serial attribute, and sdb with no serial at all.
+	for _, p := range []string{
+		byID,
+		filepath.Join(sys, "devices", "block", "sda", "sda1"),
+		filepath.Join(sys, "devices", "block", "sda", "device"),
+		filepath.Join(sys, "devices", "block", "nvme0n1", "device"),
+		filepath.Join(sys, "devices", "block", "sdb"),
+		filepath.Join(sys, "class", "block"),
+	} {
+		mustMkdir(t, p)
+	}
+
+	for _, f := range []string{"sda", "sda1", "nvme0n1", "sdb"} {
+		mustWrite(t, filepath.Join(dev, f))
+	}
+
+	mustWrite(t, filepath.Join(sys, "devices", "block", "sda", "sda1", "partition"))
+
+	serials := map[string]string{
+		filepath.Join(sys, "devices", "block", "sda", "device", "vpd_pg80"):   "\x00\x80\x00\x0eWD-WCC7K1234567",
+		filepath.Join(sys, "devices", "block", "nvme0n1", "device", "serial"): "S4EWNX0N123456  \n",
+	}
+	for path, data := range serials {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for link, target := range map[string]string{
+		"ata-WDC_WD40EFRX_WD-WCC7K1234567":       "../../sda",
+		"ata-WDC_WD40EFRX_WD-WCC7K1234567-part1": "../../sda1",
+		"wwn-0x50014ee2b1234567-part1":           "../../sda1",
+		"nvme-eui.0025385b91234567":              "../../nvme0n1",
+		"nvme-Samsung_SSD_970_S4EWNX0N123456":    "../../nvme0n1",
+		"wwn-0x5000c500a1b2c3d4":                 "../../sdb",
+	} {
+		mustSymlink(t, target, filepath.Join(byID, link))
+	}
+
+	for _, b := range []string{"sda", "sda1", "nvme0n1", "sdb"} {
+		target := "../../devices/block/" + b
+		if b == "sda1" {
+			target = "../../devices/block/sda/sda1"
+		}
+
+		mustSymlink(t, target, filepath.Join(sys, "class", "block", b))
+	}
+
+	r := NewDeviceResolver()
+	r.SetRoots(dev, sys)
+
+	got := r.Identify([]string{"sda1", "wwn-0x50014ee2b1234567-part1", "nvme0n1", "sdb", "/var/tmp/file-vdev"})
+
+	want := map[string]DeviceIdentity{
+		"sda1": {
+			Path: filepath.Join(dev, "sda1"), ByID: "ata-WDC_WD40EFRX_WD-WCC7K1234567-part1", Serial: "WD-WCC7K1234567",
+		},
+		"wwn-0x50014ee2b1234567-part1": {
+			Path: filepath.Join(dev, "sda1"), ByID: "wwn-0x50014ee2b1234567-part1", Serial: "WD-WCC7K1234567",
+		},
+		"nvme0n1": {
+			Path: filepath.Join(dev, "nvme0n1"), ByID: "nvme-Samsung_SSD_970_S4EWNX0N123456", Serial: "S4EWNX0N123456",
+		},
+		"sdb": {Path: filepath.Join(dev, "sdb"), ByID: "wwn-0x5000c500a1b2c3d4"},
+	}
+
+	if !maps.Equal(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
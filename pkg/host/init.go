@@ -0,0 +1,135 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Init systems a ServiceChecker can query.
+const (
+	InitAuto    = "auto"
+	InitSystemd = "systemd"
+	InitOpenRC  = "openrc"
+	InitRunit   = "runit"
+	InitSysV    = "sysv"
+)
+
+// InitSystems lists the accepted --host.init values.
+var InitSystems = []string{InitAuto, InitSystemd, InitOpenRC, InitRunit, InitSysV}
+
+// defaultInitDir is where SysV init scripts live.
+const defaultInitDir = "/etc/init.d"
+
+// initServiceNames maps service keys to candidate service names for the
+// init systems other than systemd (see DefaultServiceUnits).
+var initServiceNames = map[string]map[string][]string{
+	InitOpenRC: {
+		"zfs":   {"zfs-zed"},
+		"nfs":   {"nfs", "nfsd"},
+		"smb":   {"samba", "smbd"},
+		"iscsi": {"iscsid", "tgtd", "open-iscsi"},
+	},
+	InitRunit: {
+		"zfs":   {"zed", "zfs-zed"},
+		"nfs":   {"nfs-server", "nfsd"},
+		"smb":   {"smbd", "samba"},
+		"iscsi": {"iscsid", "tgtd"},
+	},
+	InitSysV: {
+		"zfs":   {"zfs-zed"},
+		"nfs":   {"nfs-kernel-server", "nfs-server", "nfs"},
+		"smb":   {"smbd", "samba"},
+		"iscsi": {"open-iscsi", "iscsid", "tgt", "iscsitarget"},
+	},
+}
+
+// DefaultServices returns the service key to candidate name mapping for an
+// init system. Unknown init systems get the systemd units.
+func DefaultServices(system string) map[string][]string {
+	if names, ok := initServiceNames[system]; ok {
+		return names
+	}
+
+	return DefaultServiceUnits
+}
+
+// DetectInit returns the init system managing this host: systemd if
+// /run/systemd/system exists, then OpenRC, runit, and SysV by their runtime
+// or script directories. It falls back to systemd.
+func DetectInit() string {
+	return detectInit("/")
+}
+
+func detectInit(root string) string {
+	markers := []struct {
+		system string
+		path   string
+	}{
+		{InitSystemd, "run/systemd/system"},
+		{InitOpenRC, "run/openrc"},
+		{InitRunit, "run/runit"},
+		{InitRunit, "etc/runit/runsvdir"},
+		{InitSysV, "etc/init.d"},
+	}
+
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(root, m.path)); err == nil {
+			return m.system
+		}
+	}
+
+	return InitSystemd
+}
+
+// openrcStatus reports whether an OpenRC service exists and is started.
+// "rc-service --exists" exits non-zero for unknown services; "rc-service
+// <name> status" exits 0 only when the service is started.
+func (s *ServiceChecker) openrcStatus(ctx context.Context, name string) (exists, active bool) {
+	if _, err := s.runner(ctx, "rc-service", "--exists", name); err != nil {
+		return false, false
+	}
+
+	_, err := s.runner(ctx, "rc-service", name, "status")
+
+	return true, err == nil
+}
+
+// runitStatus reports whether a runit service exists and is running, from
+// the first word of "sv status <name>": run, down, or fail (no such
+// service).
+func (s *ServiceChecker) runitStatus(ctx context.Context, name string) (exists, active bool) {
+	// sv exits non-zero for down services, so the output is what matters.
+	out, _ := s.runner(ctx, "sv", "status", name)
+
+	state, _, _ := strings.Cut(strings.TrimSpace(string(out)), ":")
+
+	switch state {
+	case "run":
+		return true, true
+	case "down", "finish":
+		return true, false
+	default:
+		return false, false
+	}
+}
+
+// sysvStatus reports whether a SysV init script exists and its service is
+// running. Per LSB, "<script> status" exits 0 only when the service runs.
+func (s *ServiceChecker) sysvStatus(ctx context.Context, name string) (exists, active bool) {
+	script := filepath.Join(s.initDir, name)
+
+	if _, err := os.Stat(script); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.logger.Debug("init script not accessible", "script", script, "err", err)
+		}
+
+		return false, false
+	}
+
+	_, err := s.runner(ctx, script, "status")
+
+	return true, err == nil
+}
@@ -0,0 +1,185 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckServices_OpenRC(t *testing.T) {
+	started := map[string]bool{"samba": true, "nfs": false}
+
+	runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		if name != "rc-service" {
+			return nil, errors.New("unexpected command " + name)
+		}
+
+		if args[0] == "--exists" {
+			if _, ok := started[args[1]]; ok {
+				return nil, nil
+			}
+
+			return nil, errors.New("exit status 1")
+		}
+
+		if started[args[0]] {
+			return []byte(" * status: started\n"), nil
+		}
+
+		return []byte(" * status: stopped\n"), errors.New("exit status 3")
+	}
+
+	checker := NewServiceChecker(runner, testLogger())
+	checker.SetInit(InitOpenRC)
+
+	statuses, err := checker.CheckServices(context.Background(), map[string][]string{
+		"smb":   {"samba", "smbd"},
+		"nfs":   {"nfs", "nfsd"},
+		"iscsi": {"iscsid"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := statusMap(statuses)
+	want := map[string]bool{"smb": true, "nfs": false}
+
+	if len(got) != len(want) || got["smb"] != want["smb"] || got["nfs"] != want["nfs"] {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+}
+
+func TestCheckServices_Runit(t *testing.T) {
+	outputs := map[string]string{
+		"smbd": "run: smbd: (pid 123) 4567s; run: log: (pid 120) 4567s\n",
+		"zed":  "down: zed: 12s, normally up\n",
+	}
+
+	runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		if name != "sv" || args[0] != "status" {
+			return nil, errors.New("unexpected command " + name)
+		}
+
+		if out, ok := outputs[args[1]]; ok {
+			return []byte(out), nil
+		}
+
+		return []byte("fail: " + args[1] + ": unable to change to service directory: file does not exist\n"), errors.New("exit status 1")
+	}
+
+	checker := NewServiceChecker(runner, testLogger())
+	checker.SetInit(InitRunit)
+
+	statuses, err := checker.CheckServices(context.Background(), map[string][]string{
+		"smb": {"smbd"},
+		"zfs": {"zed", "zfs-zed"},
+		"nfs": {"nfs-server", "nfsd"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := statusMap(statuses)
+	if len(got) != 2 || !got["smb"] || got["zfs"] {
+		t.Errorf("statuses = %v, want smb up and zfs down only", got)
+	}
+}
+
+func TestCheckServices_SysV(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"smbd", "nfs-kernel-server"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calls []string
+
+	runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+
+		if filepath.Base(name) == "smbd" {
+			return []byte("smbd is running.\n"), nil
+		}
+
+		return []byte("nfsd not running\n"), errors.New("exit status 3")
+	}
+
+	checker := NewServiceChecker(runner, testLogger())
+	checker.SetInit(InitSysV)
+	checker.initDir = dir
+
+	statuses, err := checker.CheckServices(context.Background(), map[string][]string{
+		"smb":   {"smbd", "samba"},
+		"nfs":   {"nfs-kernel-server"},
+		"iscsi": {"open-iscsi"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := statusMap(statuses)
+	if len(got) != 2 || !got["smb"] || got["nfs"] {
+		t.Errorf("statuses = %v, want smb up and nfs down only", got)
+	}
+
+	// Missing scripts are never executed.
+	for _, c := range calls {
+		if strings.Contains(c, "open-iscsi") {
+			t.Errorf("unexpected call for missing script: %q", c)
+		}
+	}
+}
+
+func TestDetectInit(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{"systemd", []string{"run/systemd/system", "etc/init.d"}, InitSystemd},
+		{"openrc", []string{"run/openrc", "etc/init.d"}, InitOpenRC},
+		{"runit", []string{"etc/runit/runsvdir"}, InitRunit},
+		{"sysv", []string{"etc/init.d"}, InitSysV},
+		{"nothing", nil, InitSystemd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			for _, p := range tt.paths {
+				if err := os.MkdirAll(filepath.Join(root, p), 0o750); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := detectInit(root); got != tt.want {
+				t.Errorf("detectInit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultServices(t *testing.T) {
+	if got := DefaultServices(InitSystemd)["nfs"]; got[0] != "nfs-kernel-server.service" {
+		t.Errorf("systemd nfs = %v", got)
+	}
+
+	if got := DefaultServices(InitOpenRC)["smb"]; got[0] != "samba" {
+		t.Errorf("openrc smb = %v", got)
+	}
+}
+
+func statusMap(statuses []ServiceStatus) map[string]bool {
+	m := make(map[string]bool, len(statuses))
+	for _, s := range statuses {
+		m[s.Name] = s.Active
+	}
+
+	return m
+}
@@ -0,0 +1,288 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// DefaultConfigfsPath is where configfs is mounted on Linux. The LIO target
+// stack lives under its target directory.
+const DefaultConfigfsPath = "/sys/kernel/config"
+
+// zvolPartRe matches the partition links udev adds next to each zvol link.
+var zvolPartRe = regexp.MustCompile(`-part\d+$`)
+
+// ISCSIStatus is the iSCSI target configuration and activity of the host.
+type ISCSIStatus struct {
+	// Targets is the number of configured iSCSI target names (IQNs).
+	Targets int `json:"targets"`
+
+	// Sessions is the number of logged-in initiator sessions.
+	Sessions int `json:"sessions"`
+
+	// LUNs lists every LUN exported by a target.
+	LUNs []ISCSILUN `json:"luns,omitempty"`
+}
+
+// ISCSILUN is one LUN exported by an iSCSI target and the storage behind it.
+type ISCSILUN struct {
+	Target    string `json:"target"`
+	LUN       string `json:"lun"`
+	Backstore string `json:"backstore"`
+	Device    string `json:"device"`
+
+	// Dataset is the zvol backing the LUN, or empty if the backstore is
+	// not a zvol.
+	Dataset string `json:"dataset,omitempty"`
+}
+
+// ISCSIReader reads the iSCSI target configuration: the LIO configfs tree
+// on Linux, or ctladm on FreeBSD.
+type ISCSIReader struct {
+	runner   zfs.Runner
+	ctladm   string // ctladm binary; empty reads configfs instead
+	configfs string
+	devRoot  string
+}
+
+// NewISCSIReader creates an ISCSIReader for the host's target stack. On
+// FreeBSD it runs the ctladm binary at ctladmPath; elsewhere ctladmPath is
+// unused and LIO is read from configfs.
+func NewISCSIReader(runner zfs.Runner, ctladmPath string) *ISCSIReader {
+	r := &ISCSIReader{runner: runner, configfs: DefaultConfigfsPath, devRoot: "/dev"}
+	if runtime.GOOS == "freebsd" {
+		r.ctladm = ctladmPath
+	}
+
+	return r
+}
+
+// SetRoots sets where configfs and the device nodes are mounted, for
+// containers that see the host's /sys/kernel/config and /dev elsewhere.
+func (r *ISCSIReader) SetRoots(configfs, devRoot string) {
+	r.configfs, r.devRoot = configfs, devRoot
+}
+
+// Read returns the configured targets and LUNs and the active session count.
+func (r *ISCSIReader) Read(ctx context.Context) (ISCSIStatus, error) {
+	var (
+		st  ISCSIStatus
+		err error
+	)
+
+	if r.ctladm != "" {
+		st, err = r.readCtladm(ctx)
+	} else {
+		st, err = r.readConfigfs()
+	}
+
+	if err != nil {
+		return ISCSIStatus{}, err
+	}
+
+	resolve := r.zvolResolver()
+	for i := range st.LUNs {
+		st.LUNs[i].Dataset = resolve(st.LUNs[i].Device)
+	}
+
+	return st, nil
+}
+
+// readConfigfs walks target/iscsi/<iqn>/tpgt_<n>/ under configfs. Each
+// lun/lun_<n> directory holds a symlink to its backstore under target/core,
+// whose udev_path names the backing device. Sessions are listed one
+// initiator per line in dynamic_sessions, and in each ACL's info file.
+func (r *ISCSIReader) readConfigfs() (ISCSIStatus, error) {
+	var st ISCSIStatus
+
+	root := filepath.Join(r.configfs, "target", "iscsi")
+
+	targets, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		// iscsi_target_mod is not loaded, so nothing is exported.
+		return st, nil
+	}
+
+	if err != nil {
+		return st, fmt.Errorf("failed to read iSCSI targets: %w", err)
+	}
+
+	for _, t := range targets {
+		if !t.IsDir() || t.Name() == "discovery_auth" {
+			continue
+		}
+
+		st.Targets++
+
+		tpgs, err := filepath.Glob(filepath.Join(root, t.Name(), "tpgt_*"))
+		if err != nil {
+			return st, fmt.Errorf("failed to list target portal groups: %w", err)
+		}
+
+		for _, tpg := range tpgs {
+			st.Sessions += countConfigfsSessions(tpg)
+
+			luns, err := configfsLUNs(tpg, t.Name())
+			if err != nil {
+				return st, err
+			}
+
+			st.LUNs = append(st.LUNs, luns...)
+		}
+	}
+
+	return st, nil
+}
+
+// configfsLUNs returns the LUNs of one target portal group.
+func configfsLUNs(tpg, target string) ([]ISCSILUN, error) {
+	dirs, err := filepath.Glob(filepath.Join(tpg, "lun", "lun_*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list LUNs: %w", err)
+	}
+
+	luns := make([]ISCSILUN, 0, len(dirs))
+
+	for _, dir := range dirs {
+		lun := ISCSILUN{Target: target, LUN: strings.TrimPrefix(filepath.Base(dir), "lun_")}
+
+		if backstore, ok := configfsBackstore(dir); ok {
+			lun.Backstore = filepath.Base(backstore)
+			lun.Device = readTrimmed(filepath.Join(backstore, "udev_path"))
+		}
+
+		luns = append(luns, lun)
+	}
+
+	return luns, nil
+}
+
+// configfsBackstore returns the backstore directory a LUN directory links to.
+func configfsBackstore(lunDir string) (string, bool) {
+	entries, err := os.ReadDir(lunDir)
+	if err != nil {
+		return "", false
+	}
+
+	for _, e := range entries {
+		if e.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		resolved, err := filepath.EvalSymlinks(filepath.Join(lunDir, e.Name()))
+		if err == nil {
+			return resolved, true
+		}
+	}
+
+	return "", false
+}
+
+// countConfigfsSessions counts the sessions logged in to a target portal
+// group: demo-mode sessions in dynamic_sessions, and sessions of initiators
+// with an ACL, which the ACL's info file reports by session ID.
+func countConfigfsSessions(tpg string) int {
+	n := 0
+
+	for line := range strings.Lines(readTrimmed(filepath.Join(tpg, "dynamic_sessions"))) {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+
+	infos, err := filepath.Glob(filepath.Join(tpg, "acls", "*", "info"))
+	if err != nil {
+		return n
+	}
+
+	for _, info := range infos {
+		n += strings.Count(readTrimmed(info), "LIO Session ID:")
+	}
+
+	return n
+}
+
+// readTrimmed returns the trimmed contents of a configfs attribute, or ""
+// if it cannot be read.
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// zvolResolver returns a function mapping a block device path to the zvol
+// dataset it belongs to, or "" if it is not a zvol. /dev/zvol/<dataset>
+// paths are mapped directly; other paths, such as /dev/zd0, are matched
+// against the /dev/zvol links, which are read only when first needed.
+func (r *ISCSIReader) zvolResolver() func(string) string {
+	zvolDir := filepath.Join(r.devRoot, "zvol")
+
+	var links map[string]string // resolved device path -> dataset
+
+	return func(device string) string {
+		if ds, ok := strings.CutPrefix(device, zvolDir+"/"); ok {
+			return ds
+		}
+
+		if !strings.HasPrefix(device, r.devRoot+"/") {
+			return ""
+		}
+
+		if links == nil {
+			links = make(map[string]string)
+			readZvolLinks(zvolDir, "", links)
+		}
+
+		resolved, err := filepath.EvalSymlinks(device)
+		if err != nil {
+			return ""
+		}
+
+		return links[resolved]
+	}
+}
+
+// readZvolLinks adds the device behind each zvol link under dir, named
+// dataset relative to the /dev/zvol root, to links. Partition links
+// (<dataset>-part<n>) are skipped, as are unreadable entries.
+func readZvolLinks(dir, dataset string, links map[string]string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		name := e.Name()
+
+		if dataset != "" {
+			name = dataset + "/" + name
+		}
+
+		if e.IsDir() {
+			readZvolLinks(path, name, links)
+
+			continue
+		}
+
+		if zvolPartRe.MatchString(e.Name()) {
+			continue
+		}
+
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			links[resolved] = name
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package host
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestISCSIReader_Configfs(t *testing.T) {
+	root := t.TempDir()
+	configfs := filepath.Join(root, "config")
+	dev := filepath.Join(root, "dev")
+
+	core := filepath.Join(configfs, "target", "core")
+	iqn := "iqn.2003-01.org.linux-iscsi.nas:media"
+	tpg := filepath.Join(configfs, "target", "iscsi", iqn, "tpgt_1")
+
+	// Two zvol backstores, one by /dev/zvol path and one by /dev/zd16, and a
+	// fileio backstore.
+	for _, p := range []string{
+		filepath.Join(core, "iblock_0", "vol1"),
+		filepath.Join(core, "iblock_1", "vol2"),
+		filepath.Join(core, "fileio_0", "scratch"),
+		filepath.Join(tpg, "lun", "lun_0"),
+		filepath.Join(tpg, "lun", "lun_1"),
+		filepath.Join(tpg, "lun", "lun_2"),
+		filepath.Join(tpg, "acls", "iqn.1991-05.com.microsoft:win"),
+		filepath.Join(tpg, "acls", "iqn.1994-05.com.redhat:idle"),
+		filepath.Join(configfs, "target", "iscsi", "discovery_auth"),
+		filepath.Join(dev, "zvol", "tank", "vms"),
+	} {
+		mustMkdir(t, p)
+	}
+
+	writeFile(t, filepath.Join(core, "iblock_0", "vol1", "udev_path"), filepath.Join(dev, "zvol", "tank", "vms", "vol1")+"\n")
+	writeFile(t, filepath.Join(core, "iblock_1", "vol2", "udev_path"), filepath.Join(dev, "zd16")+"\n")
+	writeFile(t, filepath.Join(core, "fileio_0", "scratch", "udev_path"), "/srv/scratch.img\n")
+	writeFile(t, filepath.Join(configfs, "target", "iscsi", "lio_version"), "Datera Inc. iSCSI Target v4.1.0\n")
+	writeFile(t, filepath.Join(tpg, "dynamic_sessions"), "iqn.1994-05.com.redhat:demo\n")
+	writeFile(t, filepath.Join(tpg, "acls", "iqn.1991-05.com.microsoft:win", "info"),
+		"InitiatorName: iqn.1991-05.com.microsoft:win\nInitiatorAlias: \nLIO Session ID: 3   ISID: 0x40 00 01 37 00 00  TSIH: 3  SessionType: Normal\n")
+	writeFile(t, filepath.Join(tpg, "acls", "iqn.1994-05.com.redhat:idle", "info"),
+		"No active iSCSI Session for Initiator Endpoint: iqn.1994-05.com.redhat:idle\n")
+
+	mustWrite(t, filepath.Join(dev, "zd16"))
+	mustWrite(t, filepath.Join(dev, "zd32"))
+	mustSymlink(t, filepath.Join(dev, "zd16"), filepath.Join(dev, "zvol", "tank", "vol2"))
+	mustSymlink(t, filepath.Join(dev, "zd32"), filepath.Join(dev, "zvol", "tank", "vol2-part1"))
+
+	mustSymlink(t, filepath.Join(core, "iblock_0", "vol1"), filepath.Join(tpg, "lun", "lun_0", "a1b2c3"))
+	mustSymlink(t, filepath.Join(core, "iblock_1", "vol2"), filepath.Join(tpg, "lun", "lun_1", "d4e5f6"))
+	mustSymlink(t, filepath.Join(core, "fileio_0", "scratch"), filepath.Join(tpg, "lun", "lun_2", "0a1b2c"))
+
+	r := NewISCSIReader(nil, "ctladm")
+	r.ctladm = ""
+	r.SetRoots(configfs, dev)
+
+	got, err := r.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ISCSIStatus{
+		Targets:  1,
+		Sessions: 2,
+		LUNs: []ISCSILUN{
+			{Target: iqn, LUN: "0", Backstore: "vol1", Device: filepath.Join(dev, "zvol", "tank", "vms", "vol1"), Dataset: "tank/vms/vol1"},
+			{Target: iqn, LUN: "1", Backstore: "vol2", Device: filepath.Join(dev, "zd16"), Dataset: "tank/vol2"},
+			{Target: iqn, LUN: "2", Backstore: "scratch", Device: "/srv/scratch.img"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestISCSIReader_ConfigfsNotLoaded(t *testing.T) {
+	r := NewISCSIReader(nil, "ctladm")
+	r.ctladm = ""
+	r.SetRoots(t.TempDir(), "/dev")
+
+	got, err := r.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, ISCSIStatus{}) {
+		t.Errorf("got %+v, want nothing", got)
+	}
+}
+
+const (
+	portlistFixture = `<ctlportlist>
+<targ_port id="0">
+	<frontend_type>camsim</frontend_type>
+	<port_name>camsim</port_name>
+</targ_port>
+<targ_port id="1">
+	<frontend_type>iscsi</frontend_type>
+	<online>YES</online>
+	<cfiscsi_target>iqn.2012-06.org.example:vms</cfiscsi_target>
+	<cfiscsi_portal_group_tag>257</cfiscsi_portal_group_tag>
+	<lun id="0">0</lun>
+	<lun id="1">1</lun>
+</targ_port>
+<targ_port id="2">
+	<frontend_type>iscsi</frontend_type>
+	<online>YES</online>
+	<cfiscsi_target>iqn.2012-06.org.example:vms</cfiscsi_target>
+	<cfiscsi_portal_group_tag>258</cfiscsi_portal_group_tag>
+	<lun id="0">0</lun>
+	<lun id="1">1</lun>
+</targ_port>
+</ctlportlist>`
+	devlistFixture = `<ctllunlist>
+<lun id="0">
+	<backend_type>block</backend_type>
+	<file>/dev/zvol/tank/vm1</file>
+	<ctld_name>iqn.2012-06.org.example:vms,lun,0</ctld_name>
+</lun>
+<lun id="1">
+	<backend_type>ramdisk</backend_type>
+	<ctld_name>iqn.2012-06.org.example:vms,lun,1</ctld_name>
+</lun>
+</ctllunlist>`
+	islistFixture = `<ctlislist>
+<connection id="3">
+	<initiator>iqn.1994-09.org.freebsd:client</initiator>
+	<initiator_addr>192.168.1.10</initiator_addr>
+	<target>iqn.2012-06.org.example:vms</target>
+</connection>
+</ctlislist>`
+)
+
+func TestISCSIReader_Ctladm(t *testing.T) {
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "portlist":
+			return []byte(portlistFixture), nil
+		case "devlist":
+			return []byte(devlistFixture), nil
+		default:
+			return []byte(islistFixture), nil
+		}
+	}
+
+	r := NewISCSIReader(runner, "ctladm")
+	r.ctladm = "ctladm"
+
+	got, err := r.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := "iqn.2012-06.org.example:vms"
+	want := ISCSIStatus{
+		Targets:  1,
+		Sessions: 1,
+		LUNs: []ISCSILUN{
+			{Target: target, LUN: "0", Backstore: target + ",lun,0", Device: "/dev/zvol/tank/vm1", Dataset: "tank/vm1"},
+			{Target: target, LUN: "1", Backstore: target + ",lun,1"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
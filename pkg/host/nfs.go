@@ -0,0 +1,87 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultNFSExportsPath is the kernel NFS server's table of active exports.
+const DefaultNFSExportsPath = "/proc/fs/nfsd/exports"
+
+// NFSExports returns the distinct paths in the kernel NFS export table at
+// path, in file order. A missing table means nfsd is not running, so nothing
+// is exported and no error is returned.
+func NFSExports(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NFS exports: %w", err)
+	}
+
+	return parseNFSExports(data), nil
+}
+
+// parseNFSExports parses /proc/fs/nfsd/exports:
+//
+//	# Version 1.1
+//	# Path Client(Flags) # IPs
+//	/tank/media	192.168.1.0/24(rw,root_squash,...)
+//
+// One line is written per client, so paths repeat. Whitespace in a path is
+// escaped as \ooo octal.
+func parseNFSExports(data []byte) []string {
+	var paths []string
+
+	seen := make(map[string]bool)
+
+	for line := range strings.Lines(string(data)) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		p := unescapeOctal(fields[0])
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	return paths
+}
+
+// unescapeOctal decodes the \ooo escapes the kernel uses for whitespace and
+// backslashes in paths.
+func unescapeOctal(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const nfsExportsFixture = `# Version 1.1
+# Path Client(Flags) # IPs
+/tank/media	192.168.1.0/24(rw,root_squash,sync,wdelay,no_subtree_check,uuid=1a2b3c4d:00000000:00000000:00000000,sec=1)
+/tank/media	10.0.0.5(ro,root_squash,sync,wdelay,no_subtree_check,sec=1)
+/tank/my\040docs	*(rw,root_squash,sync,wdelay,no_subtree_check,sec=1)
+/srv/legacy	*(ro,root_squash,sync,wdelay,sec=1)
+`
+
+func TestNFSExports(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exports")
+	if err := os.WriteFile(path, []byte(nfsExportsFixture), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NFSExports(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"/tank/media", "/tank/my docs", "/srv/legacy"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNFSExports_NotRunning(t *testing.T) {
+	got, err := NFSExports(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || got != nil {
+		t.Errorf("got %q, %v; want nil, nil", got, err)
+	}
+}
+
+func TestUnescapeOctal(t *testing.T) {
+	tests := map[string]string{
+		`/plain`:         "/plain",
+		`/a\040b`:        "/a b",
+		`/tab\011x`:      "/tab\tx",
+		`/back\134slash`: `/back\slash`,
+		`/short\04`:      `/short\04`,
+		`/not\999octal`:  `/not\999octal`,
+	}
+
+	for in, want := range tests {
+		if got := unescapeOctal(in); got != want {
+			t.Errorf("unescapeOctal(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
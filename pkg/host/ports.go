@@ -0,0 +1,100 @@
+package host
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultServicePorts are the well-known TCP ports used by the port check
+// when a service key is configured without an explicit port.
+var DefaultServicePorts = map[string]int{
+	"nfs":   2049,
+	"smb":   445,
+	"iscsi": 3260,
+}
+
+// defaultProcNet is where the kernel lists sockets.
+const defaultProcNet = "/proc/net"
+
+// tcpListen is the st column value of a listening socket in /proc/net/tcp.
+const tcpListen = "0A"
+
+// SetPorts enables the port check: a service key in ports whose init
+// service cannot be found is reported up when something listens on its TCP
+// port. This covers daemons not managed by a local unit, such as NFS or
+// Samba running in a container.
+func (s *ServiceChecker) SetPorts(ports map[string]int) {
+	s.ports = ports
+}
+
+// listeningPorts returns the local TCP ports in LISTEN state, on any
+// address, from /proc/net/tcp and /proc/net/tcp6. Linux only.
+func (s *ServiceChecker) listeningPorts() (map[int]bool, error) {
+	ports := make(map[int]bool)
+	found := false
+
+	for _, name := range []string{"tcp", "tcp6"} {
+		data, err := os.ReadFile(filepath.Join(s.procNet, name))
+		if err != nil {
+			// tcp6 is absent when IPv6 is disabled.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("reading socket table: %w", err)
+		}
+
+		found = true
+
+		if err := parseListening(data, ports); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no socket table under %s", s.procNet)
+	}
+
+	return ports, nil
+}
+
+// parseListening adds the ports of LISTEN sockets in a /proc/net/tcp table
+// to ports:
+//
+//	sl  local_address rem_address   st ...
+//	 0: 00000000:0801 00000000:0000 0A ...
+func parseListening(data []byte, ports map[int]bool) error {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Scan() // header
+
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || fields[3] != tcpListen {
+			continue
+		}
+
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil {
+			return fmt.Errorf("invalid local address %q: %w", fields[1], err)
+		}
+
+		ports[int(port)] = true
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("scanning socket table: %w", err)
+	}
+
+	return nil
+}
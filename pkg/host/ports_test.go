@@ -0,0 +1,114 @@
+package host
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// procNetTCP lists sshd (22) and NFS (2049) listening and an established
+// connection from local port 445.
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 20561 1 0000000000000000 100 0 0 10 0
+   1: 00000000:0801 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 30211 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:01BD 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 40112 1 0000000000000000 20 4 30 10 -1
+`
+
+// procNetTCP6 lists iSCSI (3260) listening on [::].
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0CBC 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 51234 1 0000000000000000 100 0 0 10 0
+`
+
+func writeProcNet(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestCheckServices_PortFallback(t *testing.T) {
+	// No systemd units exist, so every key falls back to its port.
+	runner := mockRunner(map[string]unitResponse{})
+
+	checker := NewServiceChecker(runner, testLogger())
+	checker.procNet = writeProcNet(t, map[string]string{"tcp": procNetTCP, "tcp6": procNetTCP6})
+	checker.SetPorts(map[string]int{"nfs": 2049, "smb": 445, "iscsi": 3260})
+
+	statuses, err := checker.CheckServices(context.Background(), map[string][]string{
+		"nfs":   {"nfs-server.service"},
+		"smb":   {"smbd.service"},
+		"iscsi": nil,
+		"zfs":   {"zfs-zed.service"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := statusMap(statuses)
+	want := map[string]bool{"nfs": true, "smb": false, "iscsi": true}
+
+	if len(got) != len(want) || got["nfs"] != want["nfs"] || got["smb"] != want["smb"] || got["iscsi"] != want["iscsi"] {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+}
+
+func TestCheckServices_PortNotUsedWhenUnitExists(t *testing.T) {
+	runner := mockRunner(map[string]unitResponse{
+		"nfs-server.service": {loadState: "loaded", isActive: "failed"},
+	})
+
+	checker := NewServiceChecker(runner, testLogger())
+	checker.procNet = writeProcNet(t, map[string]string{"tcp": procNetTCP})
+	checker.SetPorts(map[string]int{"nfs": 2049})
+
+	statuses, err := checker.CheckServices(context.Background(), map[string][]string{
+		"nfs": {"nfs-server.service"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(statuses) != 1 || statuses[0].Active {
+		t.Errorf("statuses = %+v, want nfs down from its unit", statuses)
+	}
+}
+
+func TestCheckServices_PortTablesMissing(t *testing.T) {
+	checker := NewServiceChecker(mockRunner(map[string]unitResponse{}), testLogger())
+	checker.procNet = t.TempDir()
+	checker.SetPorts(map[string]int{"nfs": 2049})
+
+	statuses, err := checker.CheckServices(context.Background(), map[string][]string{"nfs": nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(statuses) != 0 {
+		t.Errorf("expected port-checked key to be skipped, got %+v", statuses)
+	}
+}
+
+func TestParseListening(t *testing.T) {
+	ports := make(map[int]bool)
+
+	if err := parseListening([]byte(procNetTCP), ports); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ports) != 2 || !ports[22] || !ports[2049] {
+		t.Errorf("ports = %v, want 22 and 2049", ports)
+	}
+
+	bad := "  sl  local_address rem_address   st\n   0: 00000000:ZZZZ 00000000:0000 0A\n"
+	if err := parseListening([]byte(bad), ports); err == nil {
+		t.Error("expected error for invalid port")
+	}
+}
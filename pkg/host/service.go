@@ -1,18 +1,30 @@
-// Package host checks host-level service states via systemctl.
+// Package host checks host-level service states via systemctl, or via
+// rc-service, sv, or /etc/init.d scripts on hosts without systemd, and
+// systemd timer schedules, reads
+// disk health through smartctl, Samba client activity through smbstatus,
+// and the iSCSI target configuration from LIO configfs or ctladm, and reads
+// the kernel NFS export table.
 package host
 
 import (
 	"context"
 	"log/slog"
+	"strconv"
 	"strings"
 
 	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
 )
 
-// ServiceStatus represents the health of a systemd service.
+// ServiceStatus represents the health of a host service.
 type ServiceStatus struct {
 	Name   string // service key (e.g. "nfs")
-	Active bool   // true if systemd unit reports "active"
+	Active bool   // true if the init system reports the service running
+
+	// Restarts is systemd's NRestarts for the unit: automatic restarts
+	// since it was last started by hand. HasRestarts is false for other
+	// init systems and for unit types that do not track restarts (sockets).
+	Restarts    uint64
+	HasRestarts bool
 }
 
 // DefaultServiceUnits maps service keys to candidate systemd unit names.
@@ -24,31 +36,70 @@ var DefaultServiceUnits = map[string][]string{
 	"iscsi": {"iscsid.socket", "iscsid.service", "iscsi.service", "tgt.service", "iscsitarget.service"},
 }
 
-// ServiceChecker checks systemd service states.
+// ServiceChecker checks service states through the host's init system.
 type ServiceChecker struct {
-	runner zfs.Runner
-	logger *slog.Logger
+	runner     zfs.Runner
+	logger     *slog.Logger
+	initSystem string
+	initDir    string
+	ports      map[string]int
+	procNet    string
 }
 
-// NewServiceChecker creates a ServiceChecker.
+// NewServiceChecker creates a ServiceChecker that queries systemd.
 func NewServiceChecker(runner zfs.Runner, logger *slog.Logger) *ServiceChecker {
 	return &ServiceChecker{
-		runner: runner,
-		logger: logger,
+		runner:     runner,
+		logger:     logger,
+		initSystem: InitSystemd,
+		initDir:    defaultInitDir,
+		procNet:    defaultProcNet,
 	}
 }
 
+// SetInit selects the init system to query: InitSystemd, InitOpenRC,
+// InitRunit, or InitSysV. Resolve InitAuto with DetectInit first.
+func (s *ServiceChecker) SetInit(system string) {
+	s.initSystem = system
+}
+
 // CheckServices checks the status of each service key. For each key, it tries
-// candidate unit names in order. If no unit exists for a key, the key is
-// silently skipped.
+// candidate unit names in order. If no unit exists for a key, its port (see
+// SetPorts) is checked instead; without a port the key is silently skipped.
 func (s *ServiceChecker) CheckServices(ctx context.Context, services map[string][]string) ([]ServiceStatus, error) {
-	var statuses []ServiceStatus
+	var (
+		statuses  []ServiceStatus
+		listening map[int]bool
+		portErr   error
+	)
 
 	for key, units := range services {
 		status, found := s.checkServiceUnits(ctx, key, units)
 		if found {
 			statuses = append(statuses, status)
+			continue
+		}
+
+		port, ok := s.ports[key]
+		if !ok {
+			continue
+		}
+
+		// The socket tables are read at most once per check. If they cannot
+		// be read, port-checked keys are skipped like missing units.
+		if listening == nil && portErr == nil {
+			listening, portErr = s.listeningPorts()
+			if portErr != nil {
+				s.logger.Warn("Port check unavailable", "err", portErr)
+			}
 		}
+
+		if portErr != nil {
+			continue
+		}
+
+		s.logger.Debug("checked service by port", "key", key, "port", port, "listening", listening[port])
+		statuses = append(statuses, ServiceStatus{Name: key, Active: listening[port]})
 	}
 
 	return statuses, nil
@@ -56,19 +107,34 @@ func (s *ServiceChecker) CheckServices(ctx context.Context, services map[string]
 
 // checkServiceUnits tries each candidate unit name for a service key.
 // Returns (status, true) if a unit was found, (zero, false) if none exist.
+// Non-systemd init systems are handled by checkInitServices.
 //
-// Unit existence is determined via "systemctl show --property=LoadState <unit>".
+// Unit existence is determined via "systemctl show --property=LoadState,NRestarts <unit>".
 // A unit with LoadState=not-found does not exist. This is reliable regardless
 // of whether the unit is active, inactive, or failed -- unlike "systemctl
 // is-active" which returns "inactive" with exit code 3 for both non-existent
 // and genuinely stopped units.
 func (s *ServiceChecker) checkServiceUnits(ctx context.Context, key string, units []string) (ServiceStatus, bool) {
+	if s.initSystem != InitSystemd {
+		return s.checkInitServices(ctx, key, units)
+	}
+
 	for _, unit := range units {
-		if !s.unitExists(ctx, unit) {
+		props, ok := s.showUnit(ctx, unit)
+		if !ok {
 			s.logger.Debug("unit not found, trying next", "key", key, "unit", unit)
 			continue
 		}
 
+		status := ServiceStatus{Name: key}
+
+		if v, ok := props["NRestarts"]; ok {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err == nil {
+				status.Restarts, status.HasRestarts = n, true
+			}
+		}
+
 		// Unit exists -- check if it's active.
 		out, err := s.runner(ctx, "systemctl", "is-active", unit)
 
@@ -76,10 +142,12 @@ func (s *ServiceChecker) checkServiceUnits(ctx context.Context, key string, unit
 		if err != nil && outStr == "" {
 			// Command failed with no output -- treat as not active.
 			s.logger.Debug("is-active failed with no output", "key", key, "unit", unit, "err", err)
-			return ServiceStatus{Name: key, Active: false}, true
+			return status, true
 		}
 
-		return ServiceStatus{Name: key, Active: outStr == "active"}, true
+		status.Active = outStr == "active"
+
+		return status, true
 	}
 
 	// No unit found for this key.
@@ -88,15 +156,54 @@ func (s *ServiceChecker) checkServiceUnits(ctx context.Context, key string, unit
 	return ServiceStatus{}, false
 }
 
-// unitExists checks whether a systemd unit is loaded (i.e. exists on disk).
-// Uses "systemctl show --property=LoadState" which returns "not-found" for
-// units that don't exist, regardless of active state.
-func (s *ServiceChecker) unitExists(ctx context.Context, unit string) bool {
-	out, err := s.runner(ctx, "systemctl", "show", "--property=LoadState", unit)
+// checkInitServices is checkServiceUnits for OpenRC, runit, and SysV init.
+func (s *ServiceChecker) checkInitServices(ctx context.Context, key string, names []string) (ServiceStatus, bool) {
+	for _, name := range names {
+		var exists, active bool
+
+		switch s.initSystem {
+		case InitOpenRC:
+			exists, active = s.openrcStatus(ctx, name)
+		case InitRunit:
+			exists, active = s.runitStatus(ctx, name)
+		case InitSysV:
+			exists, active = s.sysvStatus(ctx, name)
+		}
+
+		if exists {
+			return ServiceStatus{Name: key, Active: active}, true
+		}
+
+		s.logger.Debug("service not found, trying next", "key", key, "init", s.initSystem, "service", name)
+	}
+
+	s.logger.Debug("no service found for service key, skipping", "key", key, "init", s.initSystem)
+
+	return ServiceStatus{}, false
+}
+
+// showUnit returns the LoadState and NRestarts properties of a systemd unit,
+// and whether the unit is loaded (i.e. exists on disk). "systemctl show"
+// reports LoadState=not-found for units that don't exist, regardless of
+// active state.
+func (s *ServiceChecker) showUnit(ctx context.Context, unit string) (map[string]string, bool) {
+	out, err := s.runner(ctx, "systemctl", "show", "--property=LoadState,NRestarts", unit)
 	if err != nil {
 		s.logger.Debug("systemctl show failed", "unit", unit, "err", err)
-		return false
+		return nil, false
+	}
+
+	props := make(map[string]string)
+
+	for line := range strings.Lines(string(out)) {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[k] = v
+		}
+	}
+
+	if props["LoadState"] == "not-found" {
+		return nil, false
 	}
 
-	return !strings.Contains(string(out), "not-found")
+	return props, true
 }
@@ -24,21 +24,27 @@ type unitResponse struct {
 	loadState string // value for "systemctl show --property=LoadState" (e.g. "loaded", "not-found")
 	isActive  string // value for "systemctl is-active" (e.g. "active", "inactive", "failed")
 	isActErr  error  // error returned by "systemctl is-active" (non-nil for inactive/failed)
+	nRestarts string // value for NRestarts; omitted from "systemctl show" when empty
 }
 
 // mockRunner creates a Runner that dispatches by unit name. It handles both
-// "systemctl show --property=LoadState <unit>" and "systemctl is-active <unit>".
+// "systemctl show --property=LoadState,NRestarts <unit>" and "systemctl is-active <unit>".
 func mockRunner(responses map[string]unitResponse) zfs.Runner {
 	return func(_ context.Context, name string, args ...string) ([]byte, error) {
 		if name != "systemctl" || len(args) == 0 {
 			return nil, errors.New("unexpected command")
 		}
 
-		// "systemctl show --property=LoadState <unit>"
+		// "systemctl show --property=LoadState,NRestarts <unit>"
 		if args[0] == "show" {
 			unit := args[len(args)-1]
 			if r, ok := responses[unit]; ok {
-				return []byte("LoadState=" + r.loadState + "\n"), nil
+				out := "LoadState=" + r.loadState + "\n"
+				if r.nRestarts != "" {
+					out += "NRestarts=" + r.nRestarts + "\n"
+				}
+
+				return []byte(out), nil
 			}
 
 			return []byte("LoadState=not-found\n"), nil
@@ -258,6 +264,36 @@ func TestCheckServices_SocketActivation(t *testing.T) {
 	}
 }
 
+func TestCheckServices_Restarts(t *testing.T) {
+	runner := mockRunner(map[string]unitResponse{
+		"smbd.service":  {loadState: "loaded", isActive: "active", nRestarts: "14"},
+		"iscsid.socket": {loadState: "loaded", isActive: "active"},
+	})
+
+	checker := NewServiceChecker(runner, testLogger())
+
+	statuses, err := checker.CheckServices(context.Background(), map[string][]string{
+		"smb":   {"smbd.service"},
+		"iscsi": {"iscsid.socket"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, s := range statuses {
+		switch s.Name {
+		case "smb":
+			if !s.HasRestarts || s.Restarts != 14 {
+				t.Errorf("smb restarts = %d (has %v), want 14", s.Restarts, s.HasRestarts)
+			}
+		case "iscsi":
+			if s.HasRestarts {
+				t.Errorf("socket unit should not report restarts, got %d", s.Restarts)
+			}
+		}
+	}
+}
+
 func TestCheckServices_RunnerUsesSystemctl(t *testing.T) {
 	var calls []string
 
@@ -281,7 +317,7 @@ func TestCheckServices_RunnerUsesSystemctl(t *testing.T) {
 		t.Fatalf("expected 2 systemctl calls, got %d: %v", len(calls), calls)
 	}
 
-	expectedShow := "systemctl show --property=LoadState test.service"
+	expectedShow := "systemctl show --property=LoadState,NRestarts test.service"
 	if calls[0] != expectedShow {
 		t.Errorf("first call = %q, want %q", calls[0], expectedShow)
 	}
@@ -0,0 +1,160 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/donaldgifford/zfs_exporter/pkg/zfs"
+)
+
+// ErrSmartStandby is returned by SmartReader.Read for a disk that is spun
+// down. smartctl is run with -n standby so a scrape never wakes it.
+var ErrSmartStandby = errors.New("device is in standby")
+
+// smartFatalExit masks the smartctl exit status bits meaning no data was
+// read: bad command line, or the device could not be opened. The higher bits
+// report what was found on the disk (failing status, errors logged) and
+// still come with valid output.
+const smartFatalExit = 0x3
+
+// smartReallocatedID is the ATA attribute ID of Reallocated_Sector_Ct.
+const smartReallocatedID = 5
+
+// SmartStatus is the health of one disk as reported by smartctl.
+type SmartStatus struct {
+	// Passed is the SMART overall-health self-assessment. HasPassed is
+	// false if the disk did not report one.
+	Passed    bool
+	HasPassed bool
+
+	// Temperature is the current drive temperature in degrees Celsius.
+	Temperature    float64
+	HasTemperature bool
+
+	// Reallocated is the raw Reallocated_Sector_Ct attribute on ATA disks,
+	// or the grown defect list length on SCSI disks. NVMe disks report
+	// neither.
+	Reallocated    uint64
+	HasReallocated bool
+}
+
+// SmartReader reads disk health through smartctl.
+type SmartReader struct {
+	runner  zfs.Runner
+	path    string
+	devices *DeviceResolver
+}
+
+// NewSmartReader creates a SmartReader that runs the smartctl binary at path.
+func NewSmartReader(runner zfs.Runner, path string) *SmartReader {
+	return &SmartReader{runner: runner, path: path, devices: NewDeviceResolver()}
+}
+
+// SetRoots sets where the device nodes and sysfs are mounted, for containers
+// that see the host's /dev and /sys elsewhere.
+func (s *SmartReader) SetRoots(devRoot, sysRoot string) {
+	s.devices.SetRoots(devRoot, sysRoot)
+}
+
+// DevicePath resolves a vdev device name from zpool status to the whole-disk
+// device node to query, e.g. "ata-WDC_WD40-part1" to "/dev/sda". It returns
+// false for names that are not block devices under /dev, such as file vdevs.
+func (s *SmartReader) DevicePath(name string) (string, bool) {
+	path, ok := s.devices.Path(name)
+	if !ok {
+		return "", false
+	}
+
+	return s.devices.WholeDisk(path), true
+}
+
+// smartctlOutput is the subset of smartctl -j output that is exported.
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String string `json:"string"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current float64 `json:"current"`
+	} `json:"temperature"`
+	ATAAttributes *struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value uint64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	SCSIGrownDefects *uint64 `json:"scsi_grown_defect_list"`
+}
+
+// Read returns the SMART status of the disk at device, a path from
+// DevicePath.
+//
+// INFO(security): device is an absolute path under /dev resolved from zpool
+// status output, so it cannot be read as a smartctl flag.
+func (s *SmartReader) Read(ctx context.Context, device string) (SmartStatus, error) {
+	// smartctl exits non-zero when it finds a problem, so the output is
+	// parsed even when the runner reports an error.
+	out, runErr := s.runner(ctx, s.path, "-j", "-a", "-n", "standby", device)
+
+	var parsed smartctlOutput
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		if runErr != nil {
+			return SmartStatus{}, fmt.Errorf("smartctl %s failed: %w", device, runErr)
+		}
+
+		return SmartStatus{}, fmt.Errorf("failed to parse smartctl %s output: %w", device, err)
+	}
+
+	return parsed.status(device)
+}
+
+// status converts parsed smartctl output, checking its exit status.
+func (o *smartctlOutput) status(device string) (SmartStatus, error) {
+	var msgs []string
+	for _, m := range o.Smartctl.Messages {
+		if strings.Contains(m.String, "STANDBY") {
+			return SmartStatus{}, fmt.Errorf("%s: %w", device, ErrSmartStandby)
+		}
+
+		msgs = append(msgs, m.String)
+	}
+
+	if o.Smartctl.ExitStatus&smartFatalExit != 0 {
+		return SmartStatus{}, fmt.Errorf("smartctl %s exited with status %d: %s",
+			device, o.Smartctl.ExitStatus, strings.Join(msgs, "; "))
+	}
+
+	var st SmartStatus
+
+	if o.SmartStatus != nil {
+		st.Passed, st.HasPassed = o.SmartStatus.Passed, true
+	}
+
+	if o.Temperature != nil {
+		st.Temperature, st.HasTemperature = o.Temperature.Current, true
+	}
+
+	if o.ATAAttributes != nil {
+		for _, a := range o.ATAAttributes.Table {
+			if a.ID == smartReallocatedID {
+				st.Reallocated, st.HasReallocated = a.Raw.Value, true
+			}
+		}
+	}
+
+	if o.SCSIGrownDefects != nil {
+		st.Reallocated, st.HasReallocated = *o.SCSIGrownDefects, true
+	}
+
+	return st, nil
+}
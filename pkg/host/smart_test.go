@@ -0,0 +1,195 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	smartATA = `{
+  "smartctl": {"exit_status": 0},
+  "smart_status": {"passed": true},
+  "temperature": {"current": 34},
+  "ata_smart_attributes": {"table": [
+    {"id": 1, "name": "Raw_Read_Error_Rate", "raw": {"value": 12}},
+    {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 8}}
+  ]}
+}`
+	smartSCSIFailing = `{
+  "smartctl": {"exit_status": 8},
+  "smart_status": {"passed": false},
+  "temperature": {"current": 51},
+  "scsi_grown_defect_list": 120
+}`
+	smartNVMe = `{
+  "smartctl": {"exit_status": 0},
+  "smart_status": {"passed": true},
+  "temperature": {"current": 41}
+}`
+	smartStandby = `{
+  "smartctl": {"exit_status": 2, "messages": [{"string": "Device is in STANDBY mode, exit(2)", "severity": "information"}]}
+}`
+	smartOpenFailed = `{
+  "smartctl": {"exit_status": 2, "messages": [{"string": "Smartctl open device: /dev/sdz failed: No such device", "severity": "error"}]}
+}`
+)
+
+func TestSmartReader_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		runErr  error
+		want    SmartStatus
+		wantErr error
+	}{
+		{
+			name: "ata",
+			out:  smartATA,
+			want: SmartStatus{Passed: true, HasPassed: true, Temperature: 34, HasTemperature: true, Reallocated: 8, HasReallocated: true},
+		},
+		{
+			name:   "scsi failing",
+			out:    smartSCSIFailing,
+			runErr: errors.New("exit status 8"),
+			want:   SmartStatus{HasPassed: true, Temperature: 51, HasTemperature: true, Reallocated: 120, HasReallocated: true},
+		},
+		{
+			name: "nvme",
+			out:  smartNVMe,
+			want: SmartStatus{Passed: true, HasPassed: true, Temperature: 41, HasTemperature: true},
+		},
+		{
+			name:    "standby",
+			out:     smartStandby,
+			runErr:  errors.New("exit status 2"),
+			wantErr: ErrSmartStandby,
+		},
+		{
+			name:    "open failed",
+			out:     smartOpenFailed,
+			runErr:  errors.New("exit status 2"),
+			wantErr: errAny,
+		},
+		{
+			name:    "not installed",
+			runErr:  errors.New("executable file not found"),
+			wantErr: errAny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotArgs []string
+
+			runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+				gotArgs = args
+				return []byte(tt.out), tt.runErr
+			}
+
+			got, err := NewSmartReader(runner, "smartctl").Read(context.Background(), "/dev/sda")
+
+			switch {
+			case tt.wantErr == errAny:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+
+			if len(gotArgs) == 0 || gotArgs[len(gotArgs)-1] != "/dev/sda" {
+				t.Errorf("args = %q", gotArgs)
+			}
+		})
+	}
+}
+
+// errAny marks test cases that expect some error.
+var errAny = errors.New("any error")
+
+func TestSmartReader_DevicePath(t *testing.T) {
+	root := t.TempDir()
+	dev := filepath.Join(root, "dev")
+	sys := filepath.Join(root, "sys")
+
+	// /dev/sda with partition sda1, /dev/nvme0n1 without partitions, and
+	// by-id links to both.
+	for _, p := range []string{
+		filepath.Join(dev, "disk", "by-id"),
+		filepath.Join(sys, "devices", "block", "sda", "sda1"),
+		filepath.Join(sys, "devices", "block", "nvme0n1"),
+		filepath.Join(sys, "class", "block"),
+	} {
+		mustMkdir(t, p)
+	}
+
+	for _, f := range []string{"sda", "sda1", "nvme0n1"} {
+		mustWrite(t, filepath.Join(dev, f))
+	}
+
+	mustWrite(t, filepath.Join(sys, "devices", "block", "sda", "sda1", "partition"))
+	mustSymlink(t, "../../sda1", filepath.Join(dev, "disk", "by-id", "ata-WDC-part1"))
+	mustSymlink(t, "../../nvme0n1", filepath.Join(dev, "disk", "by-id", "nvme-Samsung"))
+	mustSymlink(t, "../../devices/block/sda/sda1", filepath.Join(sys, "class", "block", "sda1"))
+	mustSymlink(t, "../../devices/block/sda", filepath.Join(sys, "class", "block", "sda"))
+	mustSymlink(t, "../../devices/block/nvme0n1", filepath.Join(sys, "class", "block", "nvme0n1"))
+
+	s := NewSmartReader(nil, "smartctl")
+	s.SetRoots(dev, sys)
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"sda", filepath.Join(dev, "sda"), true},
+		{"sda1", filepath.Join(dev, "sda"), true},
+		{"ata-WDC-part1", filepath.Join(dev, "sda"), true},
+		{"nvme-Samsung", filepath.Join(dev, "nvme0n1"), true},
+		{filepath.Join(dev, "sda1"), filepath.Join(dev, "sda"), true},
+		{"missing", "", false},
+		{"/var/tmp/file-vdev", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := s.DevicePath(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("DevicePath(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func mustMkdir(t *testing.T, path string) {
+	t.Helper()
+
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustWrite(t *testing.T, path string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustSymlink(t *testing.T, target, link string) {
+	t.Helper()
+
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const smbstatusFixture = `{
+  "timestamp": "2025-02-03T10:00:00.000000+0000",
+  "version": "4.19.5",
+  "smb_conf": "/etc/samba/smb.conf",
+  "sessions": {
+    "3446520848": {"session_id": "3446520848", "username": "alice", "remote_machine": "192.168.1.10"},
+    "1190716921": {"session_id": "1190716921", "username": "bob", "remote_machine": "192.168.1.11"}
+  },
+  "tcons": {
+    "2796238153": {"service": "media", "session_id": "3446520848", "machine": "192.168.1.10"},
+    "3119264318": {"service": "media", "session_id": "1190716921", "machine": "192.168.1.11"},
+    "1672831960": {"service": "IPC$", "session_id": "1190716921", "machine": "192.168.1.11"}
+  },
+  "open_files": {
+    "/tank/media/movie.mkv": {"service_path": "/tank/media", "filename": "movie.mkv", "opens": {"a": {}, "b": {}}}
+  }
+}`
+
+func TestSMBReader_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		runErr  error
+		want    SMBStatus
+		wantErr bool
+	}{
+		{
+			name: "active",
+			out:  smbstatusFixture,
+			want: SMBStatus{
+				Sessions:  2,
+				OpenFiles: 1,
+				Shares:    []SMBShare{{Name: "IPC$", Connections: 1}, {Name: "media", Connections: 2}},
+			},
+		},
+		{
+			name: "idle",
+			out:  `{"timestamp": "2025-02-03T10:00:00.000000+0000", "version": "4.19.5", "sessions": {}, "tcons": {}, "open_files": {}}`,
+		},
+		{
+			// Samba before 4.16 does not know --json.
+			name:    "unsupported",
+			out:     "smbstatus: unknown option -- json",
+			runErr:  errors.New("exit status 1"),
+			wantErr: true,
+		},
+		{
+			name:    "bad json",
+			out:     "Samba version 4.15.13",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := func(context.Context, string, ...string) ([]byte, error) {
+				return []byte(tt.out), tt.runErr
+			}
+
+			got, err := NewSMBReader(runner, "smbstatus").Read(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
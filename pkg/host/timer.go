@@ -0,0 +1,98 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTimersUnsupported is returned by CheckTimers when the init system is not
+// systemd.
+var ErrTimersUnsupported = errors.New("timer checks require systemd")
+
+// timerProperties are read for each timer with systemctl show.
+const timerProperties = "--property=Id,LoadState,ActiveState,LastTriggerUSec,NextElapseUSecRealtime"
+
+// TimerStatus is the schedule state of a systemd timer unit.
+type TimerStatus struct {
+	Name string `json:"name"`
+
+	// Active is true if the timer is waiting to elapse. A stopped,
+	// disabled-and-inactive, or masked timer never runs its job.
+	Active bool `json:"active"`
+
+	// LastTrigger is when the timer last elapsed, zero if it never has.
+	LastTrigger time.Time `json:"last_trigger,omitzero"`
+
+	// NextElapse is when the timer elapses next on the wall clock, zero if
+	// it is not scheduled (inactive, or only monotonic OnBootSec-style
+	// triggers).
+	NextElapse time.Time `json:"next_elapse,omitzero"`
+}
+
+// CheckTimers returns the state of each systemd timer. Names may be globs
+// such as "zfs-scrub-*@*.timer", which systemctl matches against loaded
+// units only. Timers that do not exist are skipped.
+func (s *ServiceChecker) CheckTimers(ctx context.Context, timers []string) ([]TimerStatus, error) {
+	if s.initSystem != InitSystemd {
+		return nil, ErrTimersUnsupported
+	}
+
+	args := append([]string{"show", timerProperties, "--timestamp=unix"}, timers...)
+
+	out, err := s.runner(ctx, "systemctl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show timers failed: %w", err)
+	}
+
+	return parseTimers(string(out)), nil
+}
+
+// parseTimers parses systemctl show output for several units: one block of
+// Key=Value lines per unit, separated by blank lines. Timestamps are in
+// --timestamp=unix form, "@<seconds>"; unset ones are empty or "n/a".
+func parseTimers(out string) []TimerStatus {
+	var timers []TimerStatus
+
+	for block := range strings.SplitSeq(out, "\n\n") {
+		props := make(map[string]string)
+
+		for line := range strings.Lines(block) {
+			if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+				props[k] = v
+			}
+		}
+
+		if props["Id"] == "" || props["LoadState"] == "not-found" {
+			continue
+		}
+
+		timers = append(timers, TimerStatus{
+			Name:        props["Id"],
+			Active:      props["ActiveState"] == "active",
+			LastTrigger: parseUnixTimestamp(props["LastTriggerUSec"]),
+			NextElapse:  parseUnixTimestamp(props["NextElapseUSecRealtime"]),
+		})
+	}
+
+	return timers
+}
+
+// parseUnixTimestamp parses a systemctl --timestamp=unix value such as
+// "@1738540800", returning the zero time for anything else.
+func parseUnixTimestamp(v string) time.Time {
+	secs, ok := strings.CutPrefix(v, "@")
+	if !ok {
+		return time.Time{}
+	}
+
+	n, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(n, 0)
+}
@@ -0,0 +1,82 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const timersFixture = `Id=zfs-scrub-weekly@tank.timer
+LoadState=loaded
+ActiveState=active
+LastTriggerUSec=@1738540800
+NextElapseUSecRealtime=@1739145600
+
+Id=sanoid.timer
+LoadState=masked
+ActiveState=inactive
+LastTriggerUSec=@1730000000
+NextElapseUSecRealtime=
+
+Id=zfs-trim-monthly@tank.timer
+LoadState=loaded
+ActiveState=inactive
+LastTriggerUSec=n/a
+NextElapseUSecRealtime=n/a
+
+Id=missing.timer
+LoadState=not-found
+ActiveState=inactive
+LastTriggerUSec=n/a
+NextElapseUSecRealtime=n/a
+`
+
+func TestCheckTimers(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(timersFixture), nil
+	}
+
+	checker := NewServiceChecker(runner, testLogger())
+
+	got, err := checker.CheckTimers(context.Background(), []string{"zfs-scrub-weekly@*.timer", "sanoid.timer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []TimerStatus{
+		{Name: "zfs-scrub-weekly@tank.timer", Active: true, LastTrigger: time.Unix(1738540800, 0), NextElapse: time.Unix(1739145600, 0)},
+		{Name: "sanoid.timer", LastTrigger: time.Unix(1730000000, 0)},
+		{Name: "zfs-trim-monthly@tank.timer"},
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if args := strings.Join(gotArgs, " "); !strings.HasPrefix(args, "show ") ||
+		!strings.HasSuffix(args, "--timestamp=unix zfs-scrub-weekly@*.timer sanoid.timer") {
+		t.Errorf("args = %q", args)
+	}
+}
+
+func TestCheckTimers_Errors(t *testing.T) {
+	runner := func(context.Context, string, ...string) ([]byte, error) {
+		return nil, errors.New("exit status 1")
+	}
+
+	checker := NewServiceChecker(runner, testLogger())
+	if _, err := checker.CheckTimers(context.Background(), []string{"sanoid.timer"}); err == nil {
+		t.Error("expected an error from a failing systemctl")
+	}
+
+	checker.SetInit(InitOpenRC)
+	if _, err := checker.CheckTimers(context.Background(), []string{"sanoid.timer"}); !errors.Is(err, ErrTimersUnsupported) {
+		t.Errorf("err = %v, want ErrTimersUnsupported", err)
+	}
+}
@@ -0,0 +1,249 @@
+// Package kstat reads ZFS pool state and per-dataset I/O statistics directly
+// from the SPL kstat tree in procfs (/proc/spl/kstat/zfs). It never executes
+// zpool or zfs, so it works in minimal containers without the ZFS userland
+// and avoids fork/exec on every scrape.
+//
+// kstat does not expose pool capacity, scan state, or vdev error counters;
+// those remain CLI-only.
+package kstat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultRoot is the standard location of the ZFS kstat tree.
+const DefaultRoot = "/proc/spl/kstat/zfs"
+
+// kstat data types used by ZFS named kstats.
+const (
+	typeUint32 = "2"
+	typeUint64 = "4"
+	typeString = "7"
+)
+
+// ErrNoKstat is returned when the kstat root does not exist, typically
+// because the zfs kernel module is not loaded or procfs is not mounted.
+var ErrNoKstat = errors.New("zfs kstat tree not found")
+
+// Pool is the state of one imported pool.
+type Pool struct {
+	Name  string
+	State string // ONLINE, DEGRADED, FAULTED, ...
+}
+
+// Objset holds the cumulative I/O counters of one dataset since import.
+type Objset struct {
+	Pool         string
+	Dataset      string
+	Reads        uint64
+	Writes       uint64
+	ReadBytes    uint64
+	WrittenBytes uint64
+}
+
+// Reader reads the kstat tree rooted at a directory.
+type Reader struct {
+	root string
+}
+
+// NewReader creates a Reader. An empty root means DefaultRoot.
+func NewReader(root string) *Reader {
+	if root == "" {
+		root = DefaultRoot
+	}
+
+	return &Reader{root: root}
+}
+
+// Pools returns every pool directory under the root that has a state file,
+// sorted by name.
+func (r *Reader) Pools() ([]Pool, error) {
+	entries, err := os.ReadDir(r.root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNoKstat, r.root)
+		}
+
+		return nil, fmt.Errorf("reading kstat root: %w", err)
+	}
+
+	var pools []Pool
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(r.root, e.Name(), "state"))
+		if err != nil {
+			continue
+		}
+
+		pools = append(pools, Pool{Name: e.Name(), State: strings.ToUpper(strings.TrimSpace(string(data)))})
+	}
+
+	slices.SortFunc(pools, func(a, b Pool) int { return strings.Compare(a.Name, b.Name) })
+
+	return pools, nil
+}
+
+// Objsets returns the I/O counters of every mounted dataset in pool, sorted
+// by dataset name.
+func (r *Reader) Objsets(pool string) ([]Objset, error) {
+	paths, err := filepath.Glob(filepath.Join(r.root, pool, "objset-*"))
+	if err != nil {
+		return nil, fmt.Errorf("listing objsets for %s: %w", pool, err)
+	}
+
+	objsets := make([]Objset, 0, len(paths))
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// Objsets come and go with mounts; a vanished file is not an error.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		o, err := parseObjset(pool, data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+
+		objsets = append(objsets, o)
+	}
+
+	slices.SortFunc(objsets, func(a, b Objset) int { return strings.Compare(a.Dataset, b.Dataset) })
+
+	return objsets, nil
+}
+
+// Named reads a global named kstat under the root, such as arcstats or zil,
+// and returns its unsigned counters by name. Rows of other types are
+// skipped.
+func (r *Reader) Named(name string) (map[string]uint64, error) {
+	path := filepath.Join(r.root, name)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNoKstat, path)
+		}
+
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	values := make(map[string]uint64)
+
+	for _, row := range parseRows(data) {
+		if row.typ != typeUint64 && row.typ != typeUint32 {
+			continue
+		}
+
+		v, err := strconv.ParseUint(row.data, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: invalid %s %q: %w", path, row.name, row.data, err)
+		}
+
+		values[row.name] = v
+	}
+
+	return values, nil
+}
+
+// parseObjset parses an objset-0x<id> kstat:
+//
+//	34 1 0x01 7 2160 6165792836 1634264109
+//	name                            type data
+//	dataset_name                    7    tank/home
+//	writes                          4    3
+//	nwritten                        4    12
+//	reads                           4    1
+//	nread                           4    0
+func parseObjset(pool string, data []byte) (Objset, error) {
+	o := Objset{Pool: pool}
+
+	values := map[string]*uint64{
+		"reads":    &o.Reads,
+		"writes":   &o.Writes,
+		"nread":    &o.ReadBytes,
+		"nwritten": &o.WrittenBytes,
+	}
+
+	for _, row := range parseRows(data) {
+		switch {
+		case row.name == "dataset_name" && row.typ == typeString:
+			o.Dataset = row.data
+		case row.typ == typeUint64 && values[row.name] != nil:
+			v, err := strconv.ParseUint(row.data, 10, 64)
+			if err != nil {
+				return Objset{}, fmt.Errorf("invalid %s %q: %w", row.name, row.data, err)
+			}
+
+			*values[row.name] = v
+		}
+	}
+
+	if o.Dataset == "" {
+		return Objset{}, errors.New("missing dataset_name")
+	}
+
+	return o, nil
+}
+
+// row is one "name type data" line of a named kstat.
+type row struct {
+	name string
+	typ  string
+	data string
+}
+
+// parseRows returns the data rows of a named kstat, skipping the two header
+// lines. String data may contain spaces and is kept whole.
+func parseRows(data []byte) []row {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) <= 2 {
+		return nil
+	}
+
+	rows := make([]row, 0, len(lines)-2)
+
+	for _, line := range lines[2:] {
+		name, rest, ok := cutSpace(line)
+		if !ok {
+			continue
+		}
+
+		typ, value, ok := cutSpace(rest)
+		if !ok {
+			continue
+		}
+
+		rows = append(rows, row{name: name, typ: typ, data: strings.TrimSpace(value)})
+	}
+
+	return rows
+}
+
+// cutSpace splits s around the first run of whitespace after its first
+// field.
+func cutSpace(s string) (before, after string, found bool) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], strings.TrimLeftFunc(s[i:], unicode.IsSpace), true
+}
@@ -0,0 +1,160 @@
+package kstat
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const objsetHome = `34 1 0x01 7 2160 6165792836 1634264109
+name                            type data
+dataset_name                    7    tank/home
+writes                          4    3
+nwritten                        4    12288
+reads                           4    10
+nread                           4    40960
+nunlinks                        4    0
+nunlinked                       4    0
+`
+
+const objsetSpaces = `35 1 0x01 7 2160 6165792836 1634264109
+name                            type data
+dataset_name                    7    tank/my media
+writes                          4    0
+nwritten                        4    0
+reads                           4    1
+nread                           4    512
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTree(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "tank", "state"), "ONLINE\n")
+	writeFile(t, filepath.Join(root, "tank", "objset-0x36"), objsetHome)
+	writeFile(t, filepath.Join(root, "tank", "objset-0x37"), objsetSpaces)
+	writeFile(t, filepath.Join(root, "backup", "state"), "DEGRADED\n")
+	writeFile(t, filepath.Join(root, "arcstats"), "not a pool\n")
+	writeFile(t, filepath.Join(root, "nostate", "txgs"), "")
+
+	return root
+}
+
+func TestReader_Pools(t *testing.T) {
+	pools, err := NewReader(newTree(t)).Pools()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Pool{{Name: "backup", State: "DEGRADED"}, {Name: "tank", State: "ONLINE"}}
+	if !slices.Equal(pools, want) {
+		t.Errorf("got %+v, want %+v", pools, want)
+	}
+}
+
+func TestReader_Pools_MissingRoot(t *testing.T) {
+	_, err := NewReader(filepath.Join(t.TempDir(), "missing")).Pools()
+	if !errors.Is(err, ErrNoKstat) {
+		t.Fatalf("expected ErrNoKstat, got %v", err)
+	}
+}
+
+func TestReader_Objsets(t *testing.T) {
+	objsets, err := NewReader(newTree(t)).Objsets("tank")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Objset{
+		{Pool: "tank", Dataset: "tank/home", Reads: 10, Writes: 3, ReadBytes: 40960, WrittenBytes: 12288},
+		{Pool: "tank", Dataset: "tank/my media", Reads: 1, ReadBytes: 512},
+	}
+
+	if !slices.Equal(objsets, want) {
+		t.Errorf("got %+v, want %+v", objsets, want)
+	}
+}
+
+func TestParseObjset_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing dataset name", input: "h\nname type data\nreads 4 1\n"},
+		{name: "bad counter", input: "h\nname type data\ndataset_name 7 tank\nreads 4 many\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseObjset("tank", []byte(tt.input)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestReader_Named(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "arcstats"), `13 1 0x01 147 39984 6165792836 1634264109
+name                            type data
+hits                            4    1024
+l2_size                         4    4294967296
+arc_no_grow                     2    0
+memory_available_bytes          3    -1
+`)
+
+	got, err := NewReader(root).Named("arcstats")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]uint64{"hits": 1024, "l2_size": 4294967296, "arc_no_grow": 0}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := NewReader(root).Named("zil"); !errors.Is(err, ErrNoKstat) {
+		t.Errorf("expected ErrNoKstat for missing kstat, got %v", err)
+	}
+}
+
+func TestReader_Txgs(t *testing.T) {
+	root := newTree(t)
+	writeFile(t, filepath.Join(root, "tank", "txgs"), `18 0 0x01 100 11200 6165792836 1634264109
+txg      birth            state ndirty       nread        nwritten     reads    writes   otime        qtime        wtime        stime
+4310     6160794877       C     524288       0            2097152      0        20       5000135168   20480        33792        52428800
+4311     6165795012       C     1261568      0            5242880      0        48       5000143012   21504        35840        104857600
+4312     6170795155       S     0            0            0            0        0        5000106496   18432        30720        0
+4313     6175795261       O     0            0            0            0        0        0            0            0            0
+`)
+	// zfs_txg_history=0 leaves only the headers.
+	writeFile(t, filepath.Join(root, "backup", "txgs"), `18 0 0x01 0 0 6165792836 1634264109
+txg      birth            state ndirty       nread        nwritten     reads    writes   otime        qtime        wtime        stime
+`)
+
+	got, err := NewReader(root).Txgs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Txg{{Pool: "tank", Txg: 4311, Dirty: 1261568, Written: 5242880, SyncTime: 104857600}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package kstat
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const slabTable = `--------------------- cache -------------------------------------------------------  ----- slab ------  ---- object -----  --- emergency ---
+name                                    flags      size     alloc slabsize  objsize  total alloc   max  total alloc   max  dlock alloc   max
+zio_cache                             0x00040         -    940800        -     1200      -     -     -      -   784     -      -     -     -
+zfs_znode_cache                       0x00000    163840    126720    32768      960      5     4     5    165   132   160      0     0     0
+`
+
+func TestReadSlabs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slab")
+	writeFile(t, path, slabTable)
+
+	got, err := ReadSlabs(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []SlabCache{
+		{Name: "zio_cache", Alloc: 940800, ObjSize: 1200, Objects: 784, LinuxSlab: true},
+		{Name: "zfs_znode_cache", Size: 163840, Alloc: 126720, ObjSize: 960, Objects: 132},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ReadSlabs(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, ErrNoKstat) {
+		t.Errorf("expected ErrNoKstat for missing table, got %v", err)
+	}
+
+	writeFile(t, path, "zio_cache 0x00040 - 940800\n")
+
+	if _, err := ReadSlabs(path); err == nil {
+		t.Error("expected error for short row")
+	}
+}
+
+func TestReader_Slabs(t *testing.T) {
+	spl := t.TempDir()
+	writeFile(t, filepath.Join(spl, "kmem", "slab"), slabTable)
+
+	got, err := NewReader(filepath.Join(spl, "kstat", "zfs")).Slabs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Errorf("got %d caches, want 2", len(got))
+	}
+}
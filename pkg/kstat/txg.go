@@ -0,0 +1,125 @@
+package kstat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Txg is the most recently synced transaction group of one pool, from its
+// txgs kstat.
+type Txg struct {
+	Pool     string
+	Txg      uint64        // txg number; increases monotonically over the pool's life
+	Dirty    uint64        // bytes of dirty data the txg carried
+	Written  uint64        // bytes written while syncing it
+	SyncTime time.Duration // time spent in the syncing state
+}
+
+// txgCommitted is the state of a txg that has finished syncing.
+const txgCommitted = "C"
+
+// Txgs returns the last synced txg of every pool that keeps txg history
+// (zfs_txg_history > 0), sorted by pool name.
+func (r *Reader) Txgs() ([]Txg, error) {
+	pools, err := r.Pools()
+	if err != nil {
+		return nil, err
+	}
+
+	var txgs []Txg
+
+	for _, p := range pools {
+		path := filepath.Join(r.root, p.Name, "txgs")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// Exported between listing and reading.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		t, ok, err := parseTxgs(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+
+		if ok {
+			t.Pool = p.Name
+			txgs = append(txgs, t)
+		}
+	}
+
+	return txgs, nil
+}
+
+// parseTxgs returns the newest committed txg in a txgs kstat, or false if
+// the history holds none:
+//
+//	18 0 0x01 100 11200 6165792836 1634264109
+//	txg      birth            state ndirty       nread        nwritten     reads    writes   otime        qtime        wtime        stime
+//	4311     6165795012       C     1261568      0            5242880      0        48       5000143012   21504        35840        104857600
+//	4312     6170795155       S     0            0            0            0        0        5000106496   18432        30720        0
+//	4313     6175795261       O     0            0            0            0        0        0            0            0            0
+//
+// Times are in nanoseconds. Columns are located by header name.
+func parseTxgs(data []byte) (Txg, bool, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 2 {
+		return Txg{}, false, nil
+	}
+
+	header := strings.Fields(lines[1])
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[name] = i
+	}
+
+	for _, name := range []string{"txg", "state", "ndirty", "nwritten", "stime"} {
+		if _, ok := cols[name]; !ok {
+			return Txg{}, false, fmt.Errorf("missing %s column", name)
+		}
+	}
+
+	var (
+		last  Txg
+		found bool
+	)
+
+	// Rows are in txg order, so the last committed one is the newest.
+	for _, line := range lines[2:] {
+		fields := strings.Fields(line)
+		if len(fields) != len(header) || fields[cols["state"]] != txgCommitted {
+			continue
+		}
+
+		values := make(map[string]uint64, 4)
+
+		for _, name := range []string{"txg", "ndirty", "nwritten", "stime"} {
+			v, err := strconv.ParseUint(fields[cols[name]], 10, 64)
+			if err != nil {
+				return Txg{}, false, fmt.Errorf("invalid %s %q: %w", name, fields[cols[name]], err)
+			}
+
+			values[name] = v
+		}
+
+		last = Txg{
+			Txg:      values["txg"],
+			Dirty:    values["ndirty"],
+			Written:  values["nwritten"],
+			SyncTime: time.Duration(values["stime"]),
+		}
+		found = true
+	}
+
+	return last, found, nil
+}
@@ -0,0 +1,119 @@
+// Package sdnotify implements the systemd sd_notify protocol (readiness and
+// watchdog keepalives) without linking libsystemd. Messages are sent as
+// datagrams to the unix socket named by $NOTIFY_SOCKET.
+package sdnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notification states understood by systemd.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// ErrWatchdogDisabled is returned by WatchdogInterval when the service manager
+// has not requested watchdog keepalives for this process.
+var ErrWatchdogDisabled = errors.New("systemd watchdog not enabled")
+
+// Notifier sends state notifications to systemd. A Notifier with an empty
+// socket path is a no-op, so callers do not need to special-case running
+// outside of systemd.
+type Notifier struct {
+	socket string
+}
+
+// New returns a Notifier bound to $NOTIFY_SOCKET.
+func New() *Notifier {
+	return &Notifier{socket: os.Getenv("NOTIFY_SOCKET")}
+}
+
+// Enabled reports whether a notification socket is configured.
+func (n *Notifier) Enabled() bool {
+	return n.socket != ""
+}
+
+// Notify sends a single state string (e.g. Ready) to systemd.
+func (n *Notifier) Notify(state string) error {
+	if n.socket == "" {
+		return nil
+	}
+
+	// Abstract namespace sockets are given with a leading '@'.
+	addr := &net.UnixAddr{Name: n.socket, Net: "unixgram"}
+	if addr.Name[0] == '@' {
+		addr.Name = "\x00" + addr.Name[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return fmt.Errorf("dialing notify socket %q: %w", n.socket, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("writing %q to notify socket: %w", state, err)
+	}
+
+	return nil
+}
+
+// WatchdogInterval returns the keepalive deadline requested by systemd via
+// $WATCHDOG_USEC. It returns ErrWatchdogDisabled if the variable is unset or
+// $WATCHDOG_PID names a different process.
+func WatchdogInterval() (time.Duration, error) {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if usecStr == "" {
+		return 0, ErrWatchdogDisabled
+	}
+
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid WATCHDOG_PID %q: %w", pidStr, err)
+		}
+
+		if pid != os.Getpid() {
+			return 0, ErrWatchdogDisabled
+		}
+	}
+
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usecStr)
+	}
+
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// RunWatchdog sends a Watchdog keepalive every interval/2 for as long as
+// healthy returns true. When healthy returns false the keepalive is withheld,
+// letting systemd restart the process once the deadline passes. RunWatchdog
+// returns when ctx is cancelled.
+func (n *Notifier) RunWatchdog(ctx context.Context, interval time.Duration, healthy func() bool, onErr func(error)) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !healthy() {
+				continue
+			}
+
+			if err := n.Notify(Watchdog); err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package sdnotify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, path
+}
+
+func readMsg(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("set deadline: %v", err)
+	}
+
+	buf := make([]byte, 256)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	return string(buf[:n])
+}
+
+func TestNotify_SendsState(t *testing.T) {
+	conn, path := listen(t)
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	n := New()
+	if !n.Enabled() {
+		t.Fatal("expected notifier to be enabled")
+	}
+
+	if err := n.Notify(Ready); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if got := readMsg(t, conn); got != Ready {
+		t.Errorf("got %q, want %q", got, Ready)
+	}
+}
+
+func TestNotify_NoSocketIsNoop(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	n := New()
+	if n.Enabled() {
+		t.Fatal("expected notifier to be disabled")
+	}
+
+	if err := n.Notify(Ready); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		usec    string
+		pid     string
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "unset", usec: "", wantErr: ErrWatchdogDisabled},
+		{name: "set", usec: "30000000", want: 30 * time.Second},
+		{name: "matching pid", usec: "1000000", pid: strconv.Itoa(os.Getpid()), want: time.Second},
+		{name: "other pid", usec: "1000000", pid: "1", wantErr: ErrWatchdogDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+
+			got, err := WatchdogInterval()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("interval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchdogInterval_Invalid(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "abc")
+	t.Setenv("WATCHDOG_PID", "")
+
+	if _, err := WatchdogInterval(); err == nil {
+		t.Fatal("expected error for invalid WATCHDOG_USEC")
+	}
+}
+
+func TestRunWatchdog_WithholdsWhenUnhealthy(t *testing.T) {
+	conn, path := listen(t)
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	n := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	healthy := make(chan bool, 1)
+	healthy <- false
+
+	go n.RunWatchdog(ctx, 20*time.Millisecond, func() bool {
+		select {
+		case h := <-healthy:
+			return h
+		default:
+			return true
+		}
+	}, nil)
+
+	// First tick is unhealthy and withheld; the next one is sent.
+	if got := readMsg(t, conn); got != Watchdog {
+		t.Errorf("got %q, want %q", got, Watchdog)
+	}
+}
@@ -0,0 +1,132 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Older ZFS releases lack some of the zpool list and zfs list columns the
+// Client requests, and reject the whole command when one is unknown.
+// DetectColumns probes each optional column once by listing it alone;
+// unsupported ones are then dropped from the -o list and parsed as "-",
+// which the parsers treat as unavailable.
+
+// Optional columns of poolColumns and datasetColumns.
+var (
+	optionalPoolColumns    = []string{"frag"}
+	optionalDatasetColumns = []string{"logicalused", "logicalreferenced"}
+)
+
+// usageExitCode is the exit status of zpool and zfs when they print their
+// usage, as they do for an unknown -o column.
+const usageExitCode = 2
+
+// columnList is a -o column list and the columns dropped from it.
+type columnList struct {
+	names   []string
+	missing map[string]bool
+}
+
+func newColumnList(list string, missing map[string]bool) columnList {
+	return columnList{names: strings.Split(list, ","), missing: missing}
+}
+
+// String returns the -o argument: the supported columns, comma-separated.
+func (l columnList) String() string {
+	if len(l.missing) == 0 {
+		return strings.Join(l.names, ",")
+	}
+
+	return strings.Join(slices.DeleteFunc(slices.Clone(l.names), func(n string) bool { return l.missing[n] }), ",")
+}
+
+// width returns the number of fields in each output line.
+func (l columnList) width() int {
+	n := len(l.names)
+
+	for _, name := range l.names {
+		if l.missing[name] {
+			n--
+		}
+	}
+
+	return n
+}
+
+// fill returns fields with "-" inserted for each missing column, so every
+// field is at its index in the full list.
+func (l columnList) fill(fields []string) []string {
+	if len(l.missing) == 0 {
+		return fields
+	}
+
+	full := make([]string, 0, len(l.names))
+
+	for _, name := range l.names {
+		if l.missing[name] {
+			full = append(full, "-")
+			continue
+		}
+
+		full = append(full, fields[0])
+		fields = fields[1:]
+	}
+
+	return full
+}
+
+// DetectColumns probes which optional zpool list and zfs list columns the
+// installed zpool and zfs support, so that later commands leave out the
+// missing ones instead of failing. A column counts as missing only when the
+// binary exits with its usage status; any other probe failure is returned
+// and every column is still requested.
+func (c *Client) DetectColumns(ctx context.Context) error {
+	missing := make(map[string]bool)
+
+	for _, probe := range []struct {
+		bin  string
+		cols []string
+		args []string
+	}{
+		{c.zpoolPath, optionalPoolColumns, []string{"list", "-H", "-o"}},
+		{c.zfsPath, optionalDatasetColumns, []string{"list", "-H", "-d", "0", "-o"}},
+	} {
+		for _, col := range probe.cols {
+			_, err := c.runner(ctx, probe.bin, append(probe.args, col)...)
+			if err == nil {
+				continue
+			}
+
+			var cmdErr *CommandError
+			if ctx.Err() != nil || !errors.As(err, &cmdErr) || cmdErr.ExitCode != usageExitCode {
+				return fmt.Errorf("probing %s column %s failed: %w", probe.bin, col, err)
+			}
+
+			missing[col] = true
+		}
+	}
+
+	c.colMu.Lock()
+	c.missingCols = missing
+	c.colMu.Unlock()
+
+	if len(missing) > 0 {
+		c.logger.Warn("Columns not supported by this ZFS version; their metrics are omitted",
+			"columns", slices.Sorted(maps.Keys(missing)))
+	}
+
+	return nil
+}
+
+// columnList returns list without the columns found missing by
+// DetectColumns.
+func (c *Client) columnList(list string) columnList {
+	c.colMu.Lock()
+	defer c.colMu.Unlock()
+
+	return newColumnList(list, c.missingCols)
+}
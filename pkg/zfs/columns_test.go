@@ -0,0 +1,110 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClient_DetectColumns(t *testing.T) {
+	tests := []struct {
+		name         string
+		unsupported  []string // columns whose probe exits with the usage status
+		probeErr     error    // returned by every probe if set
+		wantErr      bool
+		wantPoolCols string
+		wantDSCols   string
+	}{
+		{
+			name:         "all supported",
+			wantPoolCols: poolColumns,
+			wantDSCols:   datasetColumns,
+		},
+		{
+			name:         "old zfs",
+			unsupported:  []string{"frag", "logicalused", "logicalreferenced"},
+			wantPoolCols: "name,size,alloc,free,dedup,health,readonly",
+			wantDSCols:   "name,used,avail,refer,type,sharenfs,sharesmb,compressratio",
+		},
+		{
+			name:         "probe failed",
+			probeErr:     &CommandError{Cmd: "zpool", ExitCode: 1, Err: errors.New("exit status 1")},
+			wantErr:      true,
+			wantPoolCols: poolColumns,
+			wantDSCols:   datasetColumns,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lists []string
+
+			runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+				col := args[len(args)-1]
+
+				switch {
+				case slices.Contains(args, "-Hp"):
+					lists = append(lists, args[slices.Index(args, "-o")+1])
+					return nil, nil
+				case tt.probeErr != nil:
+					return nil, tt.probeErr
+				case slices.Contains(tt.unsupported, col):
+					return nil, &CommandError{Cmd: "zfs", ExitCode: usageExitCode, Err: errors.New("exit status 2")}
+				default:
+					return nil, nil
+				}
+			}
+
+			client := NewClient(runner, testLogger(), "zpool", "zfs")
+			client.DisableJSON()
+
+			if err := client.DetectColumns(context.Background()); (err != nil) != tt.wantErr {
+				t.Fatalf("DetectColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if _, err := client.GetPools(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.GetDatasets(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if want := []string{tt.wantPoolCols, tt.wantDSCols}; !slices.Equal(lists, want) {
+				t.Errorf("listed columns %q, want %q", lists, want)
+			}
+		})
+	}
+}
+
+func TestParse_MissingColumns(t *testing.T) {
+	missing := map[string]bool{"frag": true, "logicalused": true, "logicalreferenced": true}
+
+	pools, err := parsePools(strings.NewReader("tank\t10737418240\t5368709120\t5368709120\t1.00\tONLINE\toff\n"),
+		newColumnList(poolColumns, missing))
+	if err != nil {
+		t.Fatalf("parsePools() error = %v", err)
+	}
+
+	if len(pools) != 1 || !math.IsNaN(pools[0].Fragmentation) || pools[0].Health != "ONLINE" {
+		t.Errorf("pools = %+v, want tank with NaN fragmentation", pools)
+	}
+
+	datasets, err := parseDatasets(strings.NewReader("tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.50\n"),
+		newColumnList(datasetColumns, missing))
+	if err != nil {
+		t.Fatalf("parseDatasets() error = %v", err)
+	}
+
+	if len(datasets) != 1 || !datasets[0].NoLogicalSpace || datasets[0].CompressRatio != 1.5 {
+		t.Errorf("datasets = %+v, want tank without logical space", datasets)
+	}
+
+	if _, err := parsePools(strings.NewReader("tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n"),
+		newColumnList(poolColumns, missing)); err == nil {
+		t.Error("expected a field count error when a dropped column is present")
+	}
+}
@@ -1,47 +1,69 @@
 package zfs
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 )
 
-// Dataset represents a ZFS dataset (filesystem or volume).
+// Dataset represents a ZFS dataset (filesystem, volume, or snapshot).
 type Dataset struct {
 	Name       string
 	Pool       string // extracted from Name: "tank/data" -> "tank"
 	Used       uint64
-	Available  uint64
+	Available  uint64 // zero for snapshots
 	Referenced uint64
-	Type       string // "filesystem" or "volume"
+	Type       string // "filesystem", "volume", or "snapshot"
 	ShareNFS   bool   // true if sharenfs != "off" and != "-"
 	ShareSMB   bool   // true if sharesmb != "off" and != "-"
+
+	// CompressRatio is the achieved compression ratio (1.00 = uncompressed).
+	// For a pool's root dataset it covers the whole pool.
+	CompressRatio float64
+
+	// LogicalUsed and LogicalReferenced are Used and Referenced before
+	// compression. LogicalUsed - Used is the space saved by compression.
+	// NoLogicalSpace is set, and both are zero, when zfs does not support
+	// the logicalused and logicalreferenced columns.
+	LogicalUsed       uint64
+	LogicalReferenced uint64
+	NoLogicalSpace    bool
 }
 
 // datasetColumns is the -o column list for zfs list.
-const datasetColumns = "name,used,avail,refer,type,sharenfs,sharesmb"
+const datasetColumns = "name,used,avail,refer,type,sharenfs,sharesmb,compressratio,logicalused,logicalreferenced"
 
-// parseDatasets parses the output of:
-// zfs list -Hp -o name,used,avail,refer,type,sharenfs,sharesmb -t filesystem,volume.
-func parseDatasets(data []byte) ([]Dataset, error) {
-	trimmed := strings.TrimSpace(string(data))
-	if trimmed == "" {
-		return nil, nil
-	}
+// Dataset types accepted by SetDatasetTypes.
+const (
+	TypeFilesystem = "filesystem"
+	TypeVolume     = "volume"
+	TypeSnapshot   = "snapshot"
+)
 
-	lines := strings.Split(trimmed, "\n")
-	datasets := make([]Dataset, 0, len(lines))
+// DatasetTypes lists the dataset types GetDatasets can list.
+var DatasetTypes = []string{TypeFilesystem, TypeVolume, TypeSnapshot}
 
-	for _, line := range lines {
+// parseDatasets parses the output of:
+// zfs list -Hp -o name,used,avail,refer,type,sharenfs,sharesmb,compressratio,logicalused,logicalreferenced -t <types>.
+func parseDatasets(r io.Reader, cols columnList) ([]Dataset, error) {
+	var datasets []Dataset
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
 		if line == "" {
 			continue
 		}
 
 		fields := strings.Split(line, "\t")
-		if len(fields) != 7 {
-			return nil, fmt.Errorf("expected 7 fields, got %d: %q", len(fields), line)
+		if len(fields) != cols.width() {
+			return nil, fmt.Errorf("expected %d fields, got %d: %q", cols.width(), len(fields), line)
 		}
 
+		fields = cols.fill(fields)
+
 		ds, err := parseDatasetFields(fields)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse dataset %q: %w", fields[0], err)
@@ -50,6 +72,10 @@ func parseDatasets(data []byte) ([]Dataset, error) {
 		datasets = append(datasets, ds)
 	}
 
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset output: %w", err)
+	}
+
 	return datasets, nil
 }
 
@@ -59,7 +85,7 @@ func parseDatasetFields(fields []string) (Dataset, error) {
 		return Dataset{}, fmt.Errorf("invalid used %q: %w", fields[1], err)
 	}
 
-	avail, err := strconv.ParseUint(fields[2], 10, 64)
+	avail, err := parseOptionalUint(fields[2])
 	if err != nil {
 		return Dataset{}, fmt.Errorf("invalid available %q: %w", fields[2], err)
 	}
@@ -69,24 +95,58 @@ func parseDatasetFields(fields []string) (Dataset, error) {
 		return Dataset{}, fmt.Errorf("invalid referenced %q: %w", fields[3], err)
 	}
 
-	return Dataset{
-		Name:       fields[0],
-		Pool:       extractPool(fields[0]),
-		Used:       used,
-		Available:  avail,
-		Referenced: ref,
-		Type:       fields[4],
-		ShareNFS:   isShareEnabled(fields[5]),
-		ShareSMB:   isShareEnabled(fields[6]),
-	}, nil
+	// -p prints "1.52"; tolerate the human-readable "1.52x" as well.
+	ratio, err := strconv.ParseFloat(strings.TrimSuffix(fields[7], "x"), 64)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("invalid compressratio %q: %w", fields[7], err)
+	}
+
+	ds := Dataset{
+		Name:          fields[0],
+		Pool:          extractPool(fields[0]),
+		Used:          used,
+		Available:     avail,
+		Referenced:    ref,
+		Type:          fields[4],
+		ShareNFS:      isShareEnabled(fields[5]),
+		ShareSMB:      isShareEnabled(fields[6]),
+		CompressRatio: ratio,
+	}
+
+	// "-" is filled in for columns this zfs does not support.
+	if fields[8] == "-" && fields[9] == "-" {
+		ds.NoLogicalSpace = true
+		return ds, nil
+	}
+
+	ds.LogicalUsed, err = strconv.ParseUint(fields[8], 10, 64)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("invalid logicalused %q: %w", fields[8], err)
+	}
+
+	ds.LogicalReferenced, err = strconv.ParseUint(fields[9], 10, 64)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("invalid logicalreferenced %q: %w", fields[9], err)
+	}
+
+	return ds, nil
+}
+
+// parseOptionalUint parses a numeric column that zfs prints as "-" where
+// it does not apply, such as avail for a snapshot. "-" parses as zero.
+func parseOptionalUint(s string) (uint64, error) {
+	if s == "-" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(s, 10, 64)
 }
 
-// extractPool returns the pool name from a dataset path.
-// "tank/data/photos" -> "tank", "tank" -> "tank".
+// extractPool returns the pool name from a dataset or snapshot path.
+// "tank/data/photos" -> "tank", "tank@daily" -> "tank", "tank" -> "tank".
 func extractPool(name string) string {
-	pool, _, found := strings.Cut(name, "/")
-	if found {
-		return pool
+	if i := strings.IndexAny(name, "/@"); i >= 0 {
+		return name[:i]
 	}
 
 	return name
@@ -1,6 +1,7 @@
 package zfs
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -13,163 +14,215 @@ func TestParseDatasets(t *testing.T) {
 	}{
 		{
 			name: "mixed filesystems and volumes",
-			input: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\n" +
-				"tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\n" +
-				"tank/backups\t1073741824\t5368709120\t1073741824\tfilesystem\trw=@10.0.0.0/24\toff\n" +
-				"tank/shared\t536870912\t5368709120\t536870912\tfilesystem\toff\ton\n" +
-				"tank/zvol0\t1073741824\t5368709120\t1073741824\tvolume\t-\t-\n",
+			input: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n" +
+				"tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.52\t6528350208\t6528350208\n" +
+				"tank/backups\t1073741824\t5368709120\t1073741824\tfilesystem\trw=@10.0.0.0/24\toff\t1.00\t1073741824\t1073741824\n" +
+				"tank/shared\t536870912\t5368709120\t536870912\tfilesystem\toff\ton\t1.00\t536870912\t536870912\n" +
+				"tank/zvol0\t1073741824\t5368709120\t1073741824\tvolume\t-\t-\t2.10x\t1073741824\t1073741824\n",
 			wantDatasets: []Dataset{
 				{
-					Name:       "tank",
-					Pool:       "tank",
-					Used:       5368709120,
-					Available:  5368709120,
-					Referenced: 262144,
-					Type:       "filesystem",
-					ShareNFS:   false,
-					ShareSMB:   false,
+					Name:              "tank",
+					Pool:              "tank",
+					Used:              5368709120,
+					Available:         5368709120,
+					Referenced:        262144,
+					Type:              "filesystem",
+					ShareNFS:          false,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       5368709120,
+					LogicalReferenced: 262144,
 				},
 				{
-					Name:       "tank/media",
-					Pool:       "tank",
-					Used:       4294967296,
-					Available:  5368709120,
-					Referenced: 4294967296,
-					Type:       "filesystem",
-					ShareNFS:   true,
-					ShareSMB:   false,
+					Name:              "tank/media",
+					Pool:              "tank",
+					Used:              4294967296,
+					Available:         5368709120,
+					Referenced:        4294967296,
+					Type:              "filesystem",
+					ShareNFS:          true,
+					ShareSMB:          false,
+					CompressRatio:     1.52,
+					LogicalUsed:       6528350208,
+					LogicalReferenced: 6528350208,
 				},
 				{
-					Name:       "tank/backups",
-					Pool:       "tank",
-					Used:       1073741824,
-					Available:  5368709120,
-					Referenced: 1073741824,
-					Type:       "filesystem",
-					ShareNFS:   true,
-					ShareSMB:   false,
+					Name:              "tank/backups",
+					Pool:              "tank",
+					Used:              1073741824,
+					Available:         5368709120,
+					Referenced:        1073741824,
+					Type:              "filesystem",
+					ShareNFS:          true,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       1073741824,
+					LogicalReferenced: 1073741824,
 				},
 				{
-					Name:       "tank/shared",
-					Pool:       "tank",
-					Used:       536870912,
-					Available:  5368709120,
-					Referenced: 536870912,
-					Type:       "filesystem",
-					ShareNFS:   false,
-					ShareSMB:   true,
+					Name:              "tank/shared",
+					Pool:              "tank",
+					Used:              536870912,
+					Available:         5368709120,
+					Referenced:        536870912,
+					Type:              "filesystem",
+					ShareNFS:          false,
+					ShareSMB:          true,
+					CompressRatio:     1,
+					LogicalUsed:       536870912,
+					LogicalReferenced: 536870912,
 				},
 				{
-					Name:       "tank/zvol0",
-					Pool:       "tank",
-					Used:       1073741824,
-					Available:  5368709120,
-					Referenced: 1073741824,
-					Type:       "volume",
-					ShareNFS:   false,
-					ShareSMB:   false,
+					Name:              "tank/zvol0",
+					Pool:              "tank",
+					Used:              1073741824,
+					Available:         5368709120,
+					Referenced:        1073741824,
+					Type:              "volume",
+					ShareNFS:          false,
+					ShareSMB:          false,
+					CompressRatio:     2.10,
+					LogicalUsed:       1073741824,
+					LogicalReferenced: 1073741824,
 				},
 			},
 		},
 		{
 			name:  "single root dataset",
-			input: "tank\t262144\t5368709120\t262144\tfilesystem\toff\toff\n",
+			input: "tank\t262144\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t262144\t262144\n",
 			wantDatasets: []Dataset{
 				{
-					Name:       "tank",
-					Pool:       "tank",
-					Used:       262144,
-					Available:  5368709120,
-					Referenced: 262144,
-					Type:       "filesystem",
-					ShareNFS:   false,
-					ShareSMB:   false,
+					Name:              "tank",
+					Pool:              "tank",
+					Used:              262144,
+					Available:         5368709120,
+					Referenced:        262144,
+					Type:              "filesystem",
+					ShareNFS:          false,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       262144,
+					LogicalReferenced: 262144,
+				},
+			},
+		},
+		{
+			name:  "snapshot",
+			input: "tank/media@daily\t1048576\t-\t4294967296\tsnapshot\t-\t-\t1.52\t1048576\t6528350208\n",
+			wantDatasets: []Dataset{
+				{
+					Name:              "tank/media@daily",
+					Pool:              "tank",
+					Used:              1048576,
+					Referenced:        4294967296,
+					Type:              "snapshot",
+					CompressRatio:     1.52,
+					LogicalUsed:       1048576,
+					LogicalReferenced: 6528350208,
 				},
 			},
 		},
 		{
 			name:  "deeply nested dataset",
-			input: "tank/data/photos/2025\t1073741824\t5368709120\t1073741824\tfilesystem\toff\toff\n",
+			input: "tank/data/photos/2025\t1073741824\t5368709120\t1073741824\tfilesystem\toff\toff\t1.00\t1073741824\t1073741824\n",
 			wantDatasets: []Dataset{
 				{
-					Name:       "tank/data/photos/2025",
-					Pool:       "tank",
-					Used:       1073741824,
-					Available:  5368709120,
-					Referenced: 1073741824,
-					Type:       "filesystem",
-					ShareNFS:   false,
-					ShareSMB:   false,
+					Name:              "tank/data/photos/2025",
+					Pool:              "tank",
+					Used:              1073741824,
+					Available:         5368709120,
+					Referenced:        1073741824,
+					Type:              "filesystem",
+					ShareNFS:          false,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       1073741824,
+					LogicalReferenced: 1073741824,
 				},
 			},
 		},
 		{
 			name:  "sharenfs with options string",
-			input: "tank/exports\t1073741824\t5368709120\t1073741824\tfilesystem\trw=@10.0.0.0/24,ro=@192.168.1.0/24\toff\n",
+			input: "tank/exports\t1073741824\t5368709120\t1073741824\tfilesystem\trw=@10.0.0.0/24,ro=@192.168.1.0/24\toff\t1.00\t1073741824\t1073741824\n",
 			wantDatasets: []Dataset{
 				{
-					Name:       "tank/exports",
-					Pool:       "tank",
-					Used:       1073741824,
-					Available:  5368709120,
-					Referenced: 1073741824,
-					Type:       "filesystem",
-					ShareNFS:   true,
-					ShareSMB:   false,
+					Name:              "tank/exports",
+					Pool:              "tank",
+					Used:              1073741824,
+					Available:         5368709120,
+					Referenced:        1073741824,
+					Type:              "filesystem",
+					ShareNFS:          true,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       1073741824,
+					LogicalReferenced: 1073741824,
 				},
 			},
 		},
 		{
 			name:  "both NFS and SMB enabled",
-			input: "tank/shared\t536870912\t5368709120\t536870912\tfilesystem\ton\ton\n",
+			input: "tank/shared\t536870912\t5368709120\t536870912\tfilesystem\ton\ton\t1.00\t536870912\t536870912\n",
 			wantDatasets: []Dataset{
 				{
-					Name:       "tank/shared",
-					Pool:       "tank",
-					Used:       536870912,
-					Available:  5368709120,
-					Referenced: 536870912,
-					Type:       "filesystem",
-					ShareNFS:   true,
-					ShareSMB:   true,
+					Name:              "tank/shared",
+					Pool:              "tank",
+					Used:              536870912,
+					Available:         5368709120,
+					Referenced:        536870912,
+					Type:              "filesystem",
+					ShareNFS:          true,
+					ShareSMB:          true,
+					CompressRatio:     1,
+					LogicalUsed:       536870912,
+					LogicalReferenced: 536870912,
 				},
 			},
 		},
 		{
 			name: "multiple pools",
-			input: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\n" +
-				"backup\t1073741824\t4294967296\t262144\tfilesystem\toff\toff\n" +
-				"backup/daily\t536870912\t4294967296\t536870912\tfilesystem\toff\toff\n",
+			input: "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n" +
+				"backup\t1073741824\t4294967296\t262144\tfilesystem\toff\toff\t1.00\t1073741824\t262144\n" +
+				"backup/daily\t536870912\t4294967296\t536870912\tfilesystem\toff\toff\t1.00\t536870912\t536870912\n",
 			wantDatasets: []Dataset{
 				{
-					Name:       "tank",
-					Pool:       "tank",
-					Used:       5368709120,
-					Available:  5368709120,
-					Referenced: 262144,
-					Type:       "filesystem",
-					ShareNFS:   false,
-					ShareSMB:   false,
+					Name:              "tank",
+					Pool:              "tank",
+					Used:              5368709120,
+					Available:         5368709120,
+					Referenced:        262144,
+					Type:              "filesystem",
+					ShareNFS:          false,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       5368709120,
+					LogicalReferenced: 262144,
 				},
 				{
-					Name:       "backup",
-					Pool:       "backup",
-					Used:       1073741824,
-					Available:  4294967296,
-					Referenced: 262144,
-					Type:       "filesystem",
-					ShareNFS:   false,
-					ShareSMB:   false,
+					Name:              "backup",
+					Pool:              "backup",
+					Used:              1073741824,
+					Available:         4294967296,
+					Referenced:        262144,
+					Type:              "filesystem",
+					ShareNFS:          false,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       1073741824,
+					LogicalReferenced: 262144,
 				},
 				{
-					Name:       "backup/daily",
-					Pool:       "backup",
-					Used:       536870912,
-					Available:  4294967296,
-					Referenced: 536870912,
-					Type:       "filesystem",
-					ShareNFS:   false,
-					ShareSMB:   false,
+					Name:              "backup/daily",
+					Pool:              "backup",
+					Used:              536870912,
+					Available:         4294967296,
+					Referenced:        536870912,
+					Type:              "filesystem",
+					ShareNFS:          false,
+					ShareSMB:          false,
+					CompressRatio:     1,
+					LogicalUsed:       536870912,
+					LogicalReferenced: 536870912,
 				},
 			},
 		},
@@ -185,24 +238,34 @@ func TestParseDatasets(t *testing.T) {
 		},
 		{
 			name:    "invalid used",
-			input:   "tank\tnotanumber\t5368709120\t262144\tfilesystem\toff\toff\n",
+			input:   "tank\tnotanumber\t5368709120\t262144\tfilesystem\toff\toff\t1.00\tnotanumber\t262144\n",
 			wantErr: true,
 		},
 		{
 			name:    "invalid available",
-			input:   "tank\t5368709120\tnotanumber\t262144\tfilesystem\toff\toff\n",
+			input:   "tank\t5368709120\tnotanumber\t262144\tfilesystem\toff\toff\t1.00\t5368709120\t262144\n",
 			wantErr: true,
 		},
 		{
 			name:    "invalid referenced",
-			input:   "tank\t5368709120\t5368709120\tnotanumber\tfilesystem\toff\toff\n",
+			input:   "tank\t5368709120\t5368709120\tnotanumber\tfilesystem\toff\toff\t1.00\t5368709120\tnotanumber\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid compressratio",
+			input:   "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\tfast\t5368709120\t262144\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid logicalused",
+			input:   "tank\t5368709120\t5368709120\t262144\tfilesystem\toff\toff\t1.00\t-\t262144\n",
 			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			datasets, err := parseDatasets([]byte(tt.input))
+			datasets, err := parseDatasets(strings.NewReader(tt.input), newColumnList(datasetColumns, nil))
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("parseDatasets() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -248,6 +311,15 @@ func TestParseDatasets(t *testing.T) {
 				if got.ShareSMB != want.ShareSMB {
 					t.Errorf("dataset[%d].ShareSMB = %v, want %v", i, got.ShareSMB, want.ShareSMB)
 				}
+
+				if got.CompressRatio != want.CompressRatio {
+					t.Errorf("dataset[%d].CompressRatio = %v, want %v", i, got.CompressRatio, want.CompressRatio)
+				}
+
+				if got.LogicalUsed != want.LogicalUsed || got.LogicalReferenced != want.LogicalReferenced {
+					t.Errorf("dataset[%d] logical = %d/%d, want %d/%d", i,
+						got.LogicalUsed, got.LogicalReferenced, want.LogicalUsed, want.LogicalReferenced)
+				}
 			}
 		})
 	}
@@ -262,6 +334,8 @@ func TestExtractPool(t *testing.T) {
 		{"tank/data", "tank"},
 		{"tank/data/photos", "tank"},
 		{"rpool/ROOT/ubuntu", "rpool"},
+		{"tank@daily", "tank"},
+		{"tank/data@daily", "tank"},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,108 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// Failure reasons returned by Reason.
+const (
+	ReasonTimeout          = "timeout"
+	ReasonNotFound         = "not_found"
+	ReasonPermissionDenied = "permission_denied"
+	ReasonNoPools          = "no_pools"
+	ReasonExit             = "exit_error"
+	ReasonOther            = "other"
+)
+
+// CommandError describes an external command that failed to start, exited
+// non-zero, or was killed when its context ended. DefaultRunner returns it
+// for every failure.
+type CommandError struct {
+	Cmd  string
+	Args []string
+	// ExitCode is the process exit status, or -1 if the command did not
+	// exit on its own (not started, or killed).
+	ExitCode int
+	// Stderr is the command's stderr joined into one trimmed line.
+	Stderr string
+	// Err is the underlying error: the context error when killed, or the
+	// exec error otherwise.
+	Err error
+}
+
+// Error formats the failure with stderr appended when present.
+func (e *CommandError) Error() string {
+	var msg string
+
+	switch {
+	case e.ExitCode >= 0:
+		msg = fmt.Sprintf("command %q exited %d", e.Cmd, e.ExitCode)
+	case isContextErr(e.Err):
+		msg = fmt.Sprintf("command %q killed: %v", e.Cmd, e.Err)
+	default:
+		msg = fmt.Sprintf("command %q failed: %v", e.Cmd, e.Err)
+	}
+
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Reason classifies err into a short, low-cardinality label for metrics:
+// one of the Reason* constants. Errors that are not command failures, such
+// as output parse errors, are ReasonOther.
+func Reason(err error) string {
+	if isContextErr(err) {
+		return ReasonTimeout
+	}
+
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return ReasonNotFound
+	}
+
+	if errors.Is(err, fs.ErrPermission) {
+		return ReasonPermissionDenied
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return ReasonOther
+	}
+
+	stderr := strings.ToLower(cmdErr.Stderr)
+
+	switch {
+	case strings.Contains(stderr, "permission denied"), strings.Contains(stderr, "must be root"):
+		return ReasonPermissionDenied
+	case strings.Contains(stderr, "a password is required"), strings.Contains(stderr, "authentication required"),
+		strings.Contains(stderr, "operation not permitted"):
+		// sudo -n and doas -n without a matching rule (--zfs.sudo).
+		return ReasonPermissionDenied
+	case strings.Contains(stderr, "no pools available"), strings.Contains(stderr, "no such pool"):
+		return ReasonNoPools
+	case cmdErr.ExitCode == 127:
+		// Shell convention for "command not found", used by wrappers.
+		return ReasonNotFound
+	case cmdErr.ExitCode >= 0:
+		return ReasonExit
+	default:
+		return ReasonOther
+	}
+}
+
+// isContextErr reports whether err stems from a cancelled or expired context.
+func isContextErr(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,109 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "deadline",
+			err:  fmt.Errorf("zpool list failed: %w", &CommandError{Cmd: "zpool", ExitCode: -1, Err: context.DeadlineExceeded}),
+			want: ReasonTimeout,
+		},
+		{
+			name: "binary missing",
+			err:  &CommandError{Cmd: "zpool", ExitCode: -1, Err: exec.ErrNotFound},
+			want: ReasonNotFound,
+		},
+		{
+			name: "path missing",
+			err:  &CommandError{Cmd: "/sbin/zpool", ExitCode: -1, Err: &os.PathError{Op: "fork/exec", Path: "/sbin/zpool", Err: os.ErrNotExist}},
+			want: ReasonNotFound,
+		},
+		{
+			name: "exit 127",
+			err:  &CommandError{Cmd: "zpool", ExitCode: 127},
+			want: ReasonNotFound,
+		},
+		{
+			name: "permission denied",
+			err:  &CommandError{Cmd: "zpool", ExitCode: 1, Stderr: "cannot open '/dev/zfs': Permission denied"},
+			want: ReasonPermissionDenied,
+		},
+		{
+			name: "sudo password required",
+			err:  &CommandError{Cmd: "sudo", ExitCode: 1, Stderr: "sudo: a password is required"},
+			want: ReasonPermissionDenied,
+		},
+		{
+			name: "doas not permitted",
+			err:  &CommandError{Cmd: "doas", ExitCode: 1, Stderr: "doas: Operation not permitted"},
+			want: ReasonPermissionDenied,
+		},
+		{
+			name: "no pools",
+			err:  &CommandError{Cmd: "zpool", ExitCode: 1, Stderr: "no pools available"},
+			want: ReasonNoPools,
+		},
+		{
+			name: "other exit",
+			err:  &CommandError{Cmd: "zpool", ExitCode: 2, Stderr: "internal error"},
+			want: ReasonExit,
+		},
+		{
+			name: "parse error",
+			err:  errors.New("failed to parse pool output"),
+			want: ReasonOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Reason(tt.err); got != tt.want {
+				t.Errorf("Reason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *CommandError
+		want string
+	}{
+		{
+			name: "exit with stderr",
+			err:  &CommandError{Cmd: "zpool", ExitCode: 1, Stderr: "no pools available"},
+			want: `command "zpool" exited 1: no pools available`,
+		},
+		{
+			name: "killed",
+			err:  &CommandError{Cmd: "zpool", ExitCode: -1, Err: context.DeadlineExceeded},
+			want: `command "zpool" killed: context deadline exceeded`,
+		},
+		{
+			name: "not started",
+			err:  &CommandError{Cmd: "zpool", ExitCode: -1, Err: exec.ErrNotFound},
+			want: `command "zpool" failed: executable file not found in $PATH`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
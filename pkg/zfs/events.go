@@ -0,0 +1,192 @@
+package zfs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventRestartDelay is how long EventWatcher waits before restarting an
+// exited zpool events process.
+const eventRestartDelay = 5 * time.Second
+
+// eventTimeLayout is the TIME column of zpool events, e.g.
+// "Feb  3 2025 10:00:00.123456789".
+const eventTimeLayout = "Jan _2 2006 15:04:05.000000000"
+
+// Streamer starts a long-running command and returns its stdout. Closing the
+// reader waits for the process to exit.
+// Production: wraps exec.CommandContext.
+// Tests: returns fixture data.
+type Streamer func(ctx context.Context, name string, args ...string) (io.ReadCloser, error)
+
+// DefaultStreamer returns a Streamer that uses exec.CommandContext. The same
+// no-shell guarantees as DefaultRunner apply.
+func DefaultStreamer() Streamer {
+	return func(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("command %q: %w", name, err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("command %q failed: %w", name, err)
+		}
+
+		return &cmdReader{ReadCloser: stdout, cmd: cmd}, nil
+	}
+}
+
+// cmdReader is a command's stdout whose Close reaps the process.
+type cmdReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Close waits for the process to exit.
+func (r *cmdReader) Close() error {
+	if err := r.cmd.Wait(); err != nil {
+		return fmt.Errorf("command %q: %w", r.cmd.Path, err)
+	}
+
+	return nil
+}
+
+// EventWatcher follows "zpool events -f" in the background and counts events
+// by class (e.g. ereport.fs.zfs.checksum).
+type EventWatcher struct {
+	streamer  Streamer
+	logger    *slog.Logger
+	zpoolPath string
+
+	mu     sync.Mutex
+	counts map[string]uint64
+	last   time.Time
+}
+
+// NewEventWatcher creates an EventWatcher. Call Run to start it.
+func NewEventWatcher(streamer Streamer, logger *slog.Logger, zpoolPath string) *EventWatcher {
+	return &EventWatcher{
+		streamer:  streamer,
+		logger:    logger,
+		zpoolPath: zpoolPath,
+		counts:    make(map[string]uint64),
+	}
+}
+
+// Run follows the event stream until ctx is done, restarting the zpool
+// process whenever it exits.
+func (w *EventWatcher) Run(ctx context.Context) {
+	for {
+		if err := w.watch(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Warn("zpool events exited, restarting", "err", err, "delay", eventRestartDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventRestartDelay):
+		}
+	}
+}
+
+// Counts returns a copy of the per-class event counts.
+func (w *EventWatcher) Counts() map[string]uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return maps.Clone(w.counts)
+}
+
+// watch runs one zpool events process to completion. zpool events first
+// replays the kernel's event buffer, so after a restart events no newer than
+// the last one already counted are skipped.
+func (w *EventWatcher) watch(ctx context.Context) error {
+	w.mu.Lock()
+	since := w.last
+	w.mu.Unlock()
+
+	r, err := w.streamer(ctx, w.zpoolPath, "events", "-H", "-f")
+	if err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		w.record(sc.Text(), since)
+	}
+
+	scanErr := sc.Err()
+
+	if err := r.Close(); err != nil {
+		return err
+	}
+
+	if scanErr != nil {
+		return fmt.Errorf("reading zpool events: %w", scanErr)
+	}
+
+	return nil
+}
+
+// record counts one "TIME<tab>CLASS" line of zpool events -H output.
+func (w *EventWatcher) record(line string, since time.Time) {
+	ts, class, ok := parseEventLine(line)
+	if !ok {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !ts.IsZero() {
+		if !since.IsZero() && !ts.After(since) {
+			return
+		}
+
+		w.last = ts
+	}
+
+	w.counts[class]++
+}
+
+// parseEventLine splits a zpool events -H line into its timestamp and class.
+// An unparsable timestamp yields the zero time.
+func parseEventLine(line string) (time.Time, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return time.Time{}, "", false
+	}
+
+	// -H separates columns with a tab; fall back to the last field for
+	// versions that pad with spaces.
+	var rawTime, class string
+	if i := strings.LastIndexByte(line, '\t'); i >= 0 {
+		rawTime, class = line[:i], line[i+1:]
+	} else {
+		fields := strings.Fields(line)
+		class = fields[len(fields)-1]
+		rawTime = strings.Join(fields[:len(fields)-1], " ")
+	}
+
+	class = strings.TrimSpace(class)
+	if class == "" || class == "CLASS" {
+		return time.Time{}, "", false
+	}
+
+	ts, err := time.ParseInLocation(eventTimeLayout, strings.TrimSpace(rawTime), time.Local)
+	if err != nil {
+		ts = time.Time{}
+	}
+
+	return ts, class, true
+}
@@ -0,0 +1,79 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+)
+
+// fixtureStreamer returns each output in turn, one per started process.
+func fixtureStreamer(outputs ...string) Streamer {
+	return func(_ context.Context, _ string, _ ...string) (io.ReadCloser, error) {
+		if len(outputs) == 0 {
+			return nil, errors.New("no more fixture outputs")
+		}
+
+		out := outputs[0]
+		outputs = outputs[1:]
+
+		return io.NopCloser(strings.NewReader(out)), nil
+	}
+}
+
+func TestParseEventLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantClass string
+		wantTime  bool
+		wantOK    bool
+	}{
+		{"tab separated", "Feb  3 2025 10:00:00.123456789\tereport.fs.zfs.checksum", "ereport.fs.zfs.checksum", true, true},
+		{"space padded", "Feb 13 2025 10:00:00.123456789 sysevent.fs.zfs.scrub_finish", "sysevent.fs.zfs.scrub_finish", true, true},
+		{"unparsable time", "yesterday\tereport.fs.zfs.io", "ereport.fs.zfs.io", false, true},
+		{"header", "TIME                           CLASS", "", false, false},
+		{"blank", "   ", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, class, ok := parseEventLine(tt.line)
+			if ok != tt.wantOK || class != tt.wantClass || ts.IsZero() == tt.wantTime {
+				t.Errorf("parseEventLine(%q) = (%v, %q, %v), want class %q ok %v time %v",
+					tt.line, ts, class, ok, tt.wantClass, tt.wantOK, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestEventWatcher_ReplayAfterRestart(t *testing.T) {
+	first := "Feb  3 2025 10:00:00.000000001\tereport.fs.zfs.checksum\n" +
+		"Feb  3 2025 10:00:01.000000000\tereport.fs.zfs.checksum\n"
+	// The restarted process replays the buffer before the new event.
+	second := first + "Feb  3 2025 10:05:00.000000000\tereport.fs.zfs.io\n"
+
+	w := NewEventWatcher(fixtureStreamer(first, second), slog.New(slog.DiscardHandler), "zpool")
+
+	for range 2 {
+		if err := w.watch(context.Background()); err != nil {
+			t.Fatalf("watch: %v", err)
+		}
+	}
+
+	want := map[string]uint64{"ereport.fs.zfs.checksum": 2, "ereport.fs.zfs.io": 1}
+	if got := w.Counts(); !maps.Equal(got, want) {
+		t.Errorf("Counts() = %v, want %v", got, want)
+	}
+}
+
+func TestEventWatcher_StartError(t *testing.T) {
+	w := NewEventWatcher(fixtureStreamer(), slog.New(slog.DiscardHandler), "zpool")
+
+	if err := w.watch(context.Background()); err == nil {
+		t.Error("expected error when the process cannot start")
+	}
+}
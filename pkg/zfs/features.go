@@ -0,0 +1,174 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Feature names, as returned by Features.Enabled.
+const (
+	FeatureJSON              = "json"
+	FeatureLatencyHistograms = "latency_histograms"
+	FeatureTrim              = "trim"
+	FeatureRaidzExpansion    = "raidz_expansion"
+)
+
+// Version is an OpenZFS release number. The zero Version means unknown.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// versionRe matches the release number in zpool version output such as
+// "zfs-2.2.6-1", "zfs-kmod-2.3.0-1", or "zfs-0.8.3-1ubuntu12".
+var versionRe = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion extracts the release number from a version string as
+// printed by zpool version.
+func ParseVersion(s string) (Version, error) {
+	m := versionRe.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version number in %q", s)
+	}
+
+	var (
+		v   Version
+		err error
+	)
+
+	if v.Major, err = strconv.Atoi(m[1]); err != nil {
+		return Version{}, fmt.Errorf("invalid major version %q: %w", m[1], err)
+	}
+
+	if v.Minor, err = strconv.Atoi(m[2]); err != nil {
+		return Version{}, fmt.Errorf("invalid minor version %q: %w", m[2], err)
+	}
+
+	if m[3] != "" {
+		if v.Patch, err = strconv.Atoi(m[3]); err != nil {
+			return Version{}, fmt.Errorf("invalid patch version %q: %w", m[3], err)
+		}
+	}
+
+	return v, nil
+}
+
+// AtLeast reports whether v is major.minor or later. It is false for the
+// zero Version.
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+
+	return v.Minor >= minor
+}
+
+// String formats v as major.minor.patch, or "unknown" for the zero Version.
+func (v Version) String() string {
+	if v == (Version{}) {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Features describes what the host's ZFS supports, derived from its
+// userland version. When the version is unknown, every feature but JSON is
+// reported as unsupported.
+type Features struct {
+	Version Version
+
+	// JSON is set when zpool and zfs accept -j (OpenZFS 2.3) and the
+	// Client uses it, i.e. DisableJSON was not called.
+	JSON bool
+
+	// LatencyHistograms is set when zpool iostat -w is available (0.7).
+	LatencyHistograms bool
+
+	// Trim is set when zpool trim and TRIM statistics are available (0.8).
+	Trim bool
+
+	// RaidzExpansion is set when disks can be attached to a raidz vdev
+	// (OpenZFS 2.3).
+	RaidzExpansion bool
+}
+
+// Enabled returns each feature by name.
+func (f *Features) Enabled() map[string]bool {
+	return map[string]bool{
+		FeatureJSON:              f.JSON,
+		FeatureLatencyHistograms: f.LatencyHistograms,
+		FeatureTrim:              f.Trim,
+		FeatureRaidzExpansion:    f.RaidzExpansion,
+	}
+}
+
+// Features returns what the host's ZFS supports, detecting it on first use
+// with "zpool version -j" or, when JSON is unavailable or disabled, "zpool
+// version". Detection cut short by ctx or a command timeout is not
+// remembered, so the next call retries it.
+func (c *Client) Features(ctx context.Context) Features {
+	c.featMu.Lock()
+	defer c.featMu.Unlock()
+
+	if c.features != nil {
+		return *c.features
+	}
+
+	f, ok := c.detectFeatures(ctx)
+	if !ok {
+		return f
+	}
+
+	c.features = &f
+
+	c.logger.Info("Detected ZFS features", "version", f.Version, "json", f.JSON, "latency_histograms", f.LatencyHistograms,
+		"trim", f.Trim, "raidz_expansion", f.RaidzExpansion)
+
+	return f
+}
+
+// detectFeatures runs the version commands for Features. ok is false if
+// ctx ended or a command timed out first. The caller holds featMu.
+func (c *Client) detectFeatures(ctx context.Context) (Features, bool) {
+	var (
+		f        Features
+		userland string
+	)
+
+	if !c.jsonOff {
+		out, err := c.runner(ctx, c.zpoolPath, "version", "-j")
+		if err != nil && (ctx.Err() != nil || isContextErr(err)) {
+			return Features{}, false
+		}
+
+		if err == nil {
+			userland, f.JSON = jsonUserland(out)
+		}
+	}
+
+	if !f.JSON {
+		out, err := c.runner(ctx, c.zpoolPath, "version")
+		if err != nil && (ctx.Err() != nil || isContextErr(err)) {
+			return Features{}, false
+		}
+
+		// The first line is the userland version, the second the kernel
+		// module's.
+		if err == nil {
+			userland, _, _ = strings.Cut(string(out), "\n")
+		}
+	}
+
+	if v, err := ParseVersion(userland); err == nil {
+		f.Version = v
+	}
+
+	f.LatencyHistograms = f.Version.AtLeast(0, 7)
+	f.Trim = f.Version.AtLeast(0, 8)
+	f.RaidzExpansion = f.Version.AtLeast(2, 3)
+
+	return f, true
+}
@@ -0,0 +1,142 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{input: "zfs-2.2.6-1", want: Version{2, 2, 6}},
+		{input: "zfs-kmod-2.3.0-1", want: Version{2, 3, 0}},
+		{input: "zfs-0.8.3-1ubuntu12.17", want: Version{0, 8, 3}},
+		{input: "2.1", want: Version{2, 1, 0}},
+		{input: "zfs-unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVersion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersion_AtLeast(t *testing.T) {
+	v := Version{2, 2, 6}
+
+	if !v.AtLeast(0, 8) || !v.AtLeast(2, 2) || v.AtLeast(2, 3) || v.AtLeast(3, 0) {
+		t.Errorf("AtLeast wrong for %v", v)
+	}
+
+	if (Version{}).AtLeast(0, 7) {
+		t.Error("unknown version must not satisfy AtLeast")
+	}
+}
+
+func TestClient_Features(t *testing.T) {
+	const jsonVersion = `{"zfs_version": {"userland": "zfs-2.3.1-1", "kernel": "zfs-kmod-2.3.1-1"}}`
+
+	tests := []struct {
+		name        string
+		jsonOut     string // zpool version -j; empty fails the command
+		textOut     string // zpool version; empty fails the command
+		disableJSON bool
+		wantVersion Version
+		wantEnabled map[string]bool
+	}{
+		{
+			name:        "openzfs 2.3",
+			jsonOut:     jsonVersion,
+			wantVersion: Version{2, 3, 1},
+			wantEnabled: map[string]bool{FeatureJSON: true, FeatureLatencyHistograms: true, FeatureTrim: true, FeatureRaidzExpansion: true},
+		},
+		{
+			name:        "json disabled",
+			jsonOut:     jsonVersion,
+			textOut:     "zfs-2.3.1-1\nzfs-kmod-2.3.1-1\n",
+			disableJSON: true,
+			wantVersion: Version{2, 3, 1},
+			wantEnabled: map[string]bool{FeatureLatencyHistograms: true, FeatureTrim: true, FeatureRaidzExpansion: true},
+		},
+		{
+			name:        "openzfs 2.1",
+			textOut:     "zfs-2.1.5-1ubuntu6~22.04.4\nzfs-kmod-2.1.5-1ubuntu6~22.04.4\n",
+			wantVersion: Version{2, 1, 5},
+			wantEnabled: map[string]bool{FeatureLatencyHistograms: true, FeatureTrim: true},
+		},
+		{
+			name:        "no version command",
+			wantEnabled: map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+				out := tt.textOut
+				if len(args) == 2 && args[1] == "-j" {
+					out = tt.jsonOut
+				}
+
+				if out == "" {
+					return nil, errors.New("unrecognized command 'version'")
+				}
+
+				return []byte(out), nil
+			}
+
+			client := NewClient(runner, testLogger(), "zpool", "zfs")
+			if tt.disableJSON {
+				client.DisableJSON()
+			}
+
+			f := client.Features(context.Background())
+			if f.Version != tt.wantVersion {
+				t.Errorf("Version = %v, want %v", f.Version, tt.wantVersion)
+			}
+
+			got := f.Enabled()
+			maps.DeleteFunc(got, func(_ string, on bool) bool { return !on })
+
+			if !maps.Equal(got, tt.wantEnabled) {
+				t.Errorf("enabled features = %v, want %v", got, tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestClient_FeaturesRetryAfterTimeout(t *testing.T) {
+	calls := 0
+
+	runner := func(ctx context.Context, _ string, _ ...string) ([]byte, error) {
+		calls++
+		return nil, ctx.Err()
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client.Features(ctx)
+	client.Features(context.Background())
+	client.Features(context.Background())
+
+	// One cut-short probe, then -j and the plain version once.
+	if calls != 3 {
+		t.Errorf("version commands run %d times, want 3", calls)
+	}
+}
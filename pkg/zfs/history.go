@@ -0,0 +1,138 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// historyTimeLayout is the timestamp zpool history prints before each
+// command, in local time.
+const historyTimeLayout = "2006-01-02.15:04:05"
+
+// Administrative operations counted from zpool history.
+const (
+	HistorySnapshotCreate  = "snapshot_create"
+	HistorySnapshotDestroy = "snapshot_destroy"
+	HistoryDatasetCreate   = "dataset_create"
+	HistoryDatasetDestroy  = "dataset_destroy"
+	HistoryPropertyChange  = "property_change"
+	HistoryImport          = "import"
+	HistoryVdevChange      = "vdev_change"
+	HistoryScrub           = "scrub"
+	HistoryOther           = "other"
+)
+
+// HistoryOperations lists every operation in a stable order.
+var HistoryOperations = []string{
+	HistorySnapshotCreate,
+	HistorySnapshotDestroy,
+	HistoryDatasetCreate,
+	HistoryDatasetDestroy,
+	HistoryPropertyChange,
+	HistoryImport,
+	HistoryVdevChange,
+	HistoryScrub,
+	HistoryOther,
+}
+
+// HistoryCount is the number of times one operation ran on a pool.
+type HistoryCount struct {
+	Pool      string
+	Operation string // one of HistoryOperations
+	Count     uint64
+}
+
+var (
+	// historyPoolRe matches the "History for 'tank':" header of each pool.
+	historyPoolRe = regexp.MustCompile(`^History for '([^']+)':`)
+
+	// historyEntryRe matches a logged command:
+	//   2025-02-03.10:00:00 zfs snapshot -r tank/home@daily
+	historyEntryRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}\.\d{2}:\d{2}:\d{2}) (zpool|zfs) (\S+)(.*)$`)
+)
+
+// GetHistoryCounts returns, for every pool, how many administrative
+// operations zpool history logged at or after since. Each pool has an entry
+// for every operation in HistoryOperations, zero if none ran.
+func (c *Client) GetHistoryCounts(ctx context.Context, since time.Time) ([]HistoryCount, error) {
+	out, err := c.runner(ctx, c.zpoolPath, "history")
+	if err != nil {
+		return nil, fmt.Errorf("zpool history failed: %w", err)
+	}
+
+	return parseHistory(out, since), nil
+}
+
+// parseHistory parses the output of: zpool history
+// Entries before since and lines that are not commands are skipped.
+func parseHistory(data []byte, since time.Time) []HistoryCount {
+	var (
+		counts []HistoryCount
+		offset int // index of the current pool's first entry in counts
+	)
+
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimRight(line, "\n")
+
+		if m := historyPoolRe.FindStringSubmatch(line); m != nil {
+			offset = len(counts)
+			for _, op := range HistoryOperations {
+				counts = append(counts, HistoryCount{Pool: m[1], Operation: op})
+			}
+
+			continue
+		}
+
+		m := historyEntryRe.FindStringSubmatch(line)
+		if m == nil || len(counts) == 0 {
+			continue
+		}
+
+		t, err := time.ParseInLocation(historyTimeLayout, m[1], time.Local)
+		if err != nil || t.Before(since) {
+			continue
+		}
+
+		op := historyOperation(m[2], m[3], m[4])
+		for i := offset; i < len(counts); i++ {
+			if counts[i].Operation == op {
+				counts[i].Count++
+
+				break
+			}
+		}
+	}
+
+	return counts
+}
+
+// historyOperation classifies one logged command by its binary, subcommand,
+// and arguments.
+func historyOperation(bin, sub, args string) string {
+	switch bin + " " + sub {
+	case "zfs snapshot":
+		return HistorySnapshotCreate
+	case "zfs destroy":
+		if strings.Contains(args, "@") {
+			return HistorySnapshotDestroy
+		}
+
+		return HistoryDatasetDestroy
+	case "zfs create", "zfs clone", "zfs receive", "zfs recv":
+		return HistoryDatasetCreate
+	case "zfs set", "zfs inherit", "zpool set":
+		return HistoryPropertyChange
+	case "zpool import", "zpool create":
+		return HistoryImport
+	case "zpool add", "zpool attach", "zpool detach", "zpool replace", "zpool remove",
+		"zpool online", "zpool offline":
+		return HistoryVdevChange
+	case "zpool scrub":
+		return HistoryScrub
+	default:
+		return HistoryOther
+	}
+}
@@ -0,0 +1,101 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const historyFixture = `History for 'tank':
+2025-01-01.09:00:00 zpool create tank mirror sda sdb
+2025-02-03.09:59:59 zfs snapshot tank@before
+2025-02-03.10:00:00 zfs snapshot -r tank/home@daily
+2025-02-03.10:05:00 zfs destroy tank/home@daily
+2025-02-03.10:06:00 zfs destroy -r tank/scratch
+2025-02-03.10:07:00 zfs set compression=zstd tank/home
+2025-02-03.10:08:00 zfs inherit compression tank/home
+2025-02-03.10:09:00 zpool scrub tank
+2025-02-03.10:10:00 zpool replace tank sda sdc
+2025-02-03.10:11:00 zfs create tank/new
+2025-02-03.10:12:00 zfs upgrade -a
+
+History for 'usb':
+2025-02-03.11:00:00 zpool import usb
+`
+
+func TestParseHistory(t *testing.T) {
+	since := time.Date(2025, time.February, 3, 10, 0, 0, 0, time.Local)
+
+	got := parseHistory([]byte(historyFixture), since)
+	if len(got) != 2*len(HistoryOperations) {
+		t.Fatalf("got %d counts, want %d", len(got), 2*len(HistoryOperations))
+	}
+
+	want := map[string]uint64{
+		"tank/" + HistorySnapshotCreate:  1,
+		"tank/" + HistorySnapshotDestroy: 1,
+		"tank/" + HistoryDatasetCreate:   1,
+		"tank/" + HistoryDatasetDestroy:  1,
+		"tank/" + HistoryPropertyChange:  2,
+		"tank/" + HistoryVdevChange:      1,
+		"tank/" + HistoryScrub:           1,
+		"tank/" + HistoryOther:           1,
+		"usb/" + HistoryImport:           1,
+	}
+
+	for _, c := range got {
+		if w := want[c.Pool+"/"+c.Operation]; c.Count != w {
+			t.Errorf("%s %s = %d, want %d", c.Pool, c.Operation, c.Count, w)
+		}
+	}
+}
+
+func TestParseHistory_Empty(t *testing.T) {
+	if got := parseHistory(nil, time.Time{}); got != nil {
+		t.Errorf("got %+v, want nil", got)
+	}
+
+	// A pool with no entries still reports every operation.
+	got := parseHistory([]byte("History for 'tank':\n"), time.Time{})
+	if len(got) != len(HistoryOperations) || got[0].Count != 0 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestHistoryOperation(t *testing.T) {
+	tests := []struct {
+		bin, sub, args string
+		want           string
+	}{
+		{"zfs", "snapshot", " tank@a", HistorySnapshotCreate},
+		{"zfs", "destroy", " tank@a", HistorySnapshotDestroy},
+		{"zfs", "destroy", " tank/a", HistoryDatasetDestroy},
+		{"zfs", "receive", " -F tank/b", HistoryDatasetCreate},
+		{"zpool", "set", " autotrim=on tank", HistoryPropertyChange},
+		{"zpool", "import", " -N tank", HistoryImport},
+		{"zpool", "attach", " tank sda sdb", HistoryVdevChange},
+		{"zpool", "scrub", " tank", HistoryScrub},
+		{"zfs", "rename", " tank/a tank/b", HistoryOther},
+	}
+
+	for _, tt := range tests {
+		if got := historyOperation(tt.bin, tt.sub, tt.args); got != tt.want {
+			t.Errorf("historyOperation(%q, %q, %q) = %q, want %q", tt.bin, tt.sub, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestClient_GetHistoryCounts_Error(t *testing.T) {
+	runner := func(context.Context, string, ...string) ([]byte, error) {
+		return nil, errors.New("permission denied")
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetHistoryCounts(context.Background(), time.Time{}); err == nil ||
+		!strings.Contains(err.Error(), "zpool history failed") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Latency histogram columns of zpool iostat -w. The total wait is the time
+// from queueing an I/O to its completion; the disk wait is the part spent
+// on the device.
+const (
+	LatencyTotalRead  = "total_wait_read"
+	LatencyTotalWrite = "total_wait_write"
+	LatencyDiskRead   = "disk_wait_read"
+	LatencyDiskWrite  = "disk_wait_write"
+	LatencyScrub      = "scrub"
+)
+
+// LatencyHistogram is the distribution of one I/O latency column of a pool
+// since it was imported.
+type LatencyHistogram struct {
+	Pool   string
+	Column string // e.g. LatencyTotalRead, LatencyScrub
+
+	// Buckets counts I/Os by latency: Buckets[i] took from 2^i to
+	// 2^(i+1)-1 nanoseconds.
+	Buckets []uint64
+}
+
+// GetLatencyHistograms returns the I/O latency histograms of every pool.
+func (c *Client) GetLatencyHistograms(ctx context.Context) ([]LatencyHistogram, error) {
+	out, err := c.runner(ctx, c.zpoolPath, "iostat", "-w", "-p")
+	if err != nil {
+		return nil, fmt.Errorf("zpool iostat -w failed: %w", err)
+	}
+
+	return parseLatencyHistograms(out)
+}
+
+// parseLatencyHistograms parses the output of: zpool iostat -w -p
+//
+//	tank         total_wait     disk_wait    syncq_wait    asyncq_wait
+//	latency      read  write   read  write   read  write   read  write  scrub   trim
+//	----------  -----  -----  -----  -----  -----  -----  -----  -----  -----  -----
+//	1               0      0      0      0      0      0      0      0      0      0
+//	3               0      0      0      0      0      0      0      0      0      0
+//	...
+//
+// Each pool has a block of rows headed by its name and the wait types, each
+// of which spans a read and a write column. The first field of a row is the
+// upper bound of its bucket in nanoseconds.
+func parseLatencyHistograms(data []byte) ([]LatencyHistogram, error) {
+	var (
+		hists  []LatencyHistogram
+		pool   string
+		groups []string // wait types from the pool header line
+		offset int      // index of the current pool's first histogram in hists
+	)
+
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "-"):
+		case fields[0] == "latency":
+			offset = len(hists)
+			for _, col := range latencyColumns(groups, fields[1:]) {
+				hists = append(hists, LatencyHistogram{Pool: pool, Column: col})
+			}
+		case fields[0][0] < '0' || fields[0][0] > '9':
+			pool, groups = fields[0], fields[1:]
+		default:
+			if err := addLatencyRow(hists[offset:], fields); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return hists, nil
+}
+
+// latencyColumns names the columns of a "latency" header line. Each wait
+// type in groups covers one read and one write column; the remaining
+// columns, such as scrub and trim, stand alone.
+func latencyColumns(groups, header []string) []string {
+	cols := make([]string, len(header))
+
+	for i, h := range header {
+		if (h == "read" || h == "write") && i/2 < len(groups) {
+			cols[i] = groups[i/2] + "_" + h
+		} else {
+			cols[i] = h
+		}
+	}
+
+	return cols
+}
+
+// addLatencyRow adds one bucket row to the histograms of its pool, which
+// are in column order.
+func addLatencyRow(hists []LatencyHistogram, fields []string) error {
+	upper, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid latency bucket %q: %w", fields[0], err)
+	}
+
+	// Bucket bounds are 2^(i+1)-1.
+	if upper == 0 || upper&(upper+1) != 0 {
+		return fmt.Errorf("invalid latency bucket %q", fields[0])
+	}
+
+	if len(fields)-1 != len(hists) {
+		return fmt.Errorf("expected %d latency columns, got %d: %q", len(hists), len(fields)-1, fields[0])
+	}
+
+	bucket := bits.Len64(upper) - 1
+
+	for i, f := range fields[1:] {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid latency count %q: %w", f, err)
+		}
+
+		h := &hists[i]
+		for len(h.Buckets) <= bucket {
+			h.Buckets = append(h.Buckets, 0)
+		}
+
+		h.Buckets[bucket] = n
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package zfs
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const iostatFixture = `
+tank         total_wait     disk_wait    syncq_wait    asyncq_wait
+latency      read  write   read  write   read  write   read  write  scrub   trim  rebuild
+----------  -----  -----  -----  -----  -----  -----  -----  -----  -----  -----  -----
+1               0      0      0      0      0      0      0      0      0      0      0
+3               0      0      0      0      0      0      0      0      0      0      0
+7               0      0      0      0      0      0      0      0      0      0      0
+15              2      0      2      0      0      0      0      0      0      0      0
+31             10      4      9      5      0      0      0      0      1      0      0
+63              1      7      1      6      0      0      0      0      3      0      0
+--------------------------------------------------------------------------------------
+
+usb          total_wait     disk_wait    syncq_wait    asyncq_wait
+latency      read  write   read  write   read  write   read  write  scrub   trim
+----------  -----  -----  -----  -----  -----  -----  -----  -----  -----  -----
+1               0      0      0      0      0      0      0      0      0      0
+3               5      1      5      1      0      0      0      0      0      0
+--------------------------------------------------------------------------------------
+`
+
+func TestParseLatencyHistograms(t *testing.T) {
+	hists, err := parseLatencyHistograms([]byte(iostatFixture))
+	if err != nil {
+		t.Fatalf("parseLatencyHistograms() error = %v", err)
+	}
+
+	// 11 columns for tank (with rebuild), 10 for usb.
+	if len(hists) != 21 {
+		t.Fatalf("got %d histograms, want 21", len(hists))
+	}
+
+	get := func(pool, col string) []uint64 {
+		t.Helper()
+
+		for _, h := range hists {
+			if h.Pool == pool && h.Column == col {
+				return h.Buckets
+			}
+		}
+
+		t.Fatalf("no %s histogram for pool %s", col, pool)
+
+		return nil
+	}
+
+	tests := []struct {
+		pool string
+		col  string
+		want []uint64
+	}{
+		{"tank", LatencyTotalRead, []uint64{0, 0, 0, 2, 10, 1}},
+		{"tank", LatencyTotalWrite, []uint64{0, 0, 0, 0, 4, 7}},
+		{"tank", LatencyDiskWrite, []uint64{0, 0, 0, 0, 5, 6}},
+		{"tank", LatencyScrub, []uint64{0, 0, 0, 0, 1, 3}},
+		{"tank", "rebuild", []uint64{0, 0, 0, 0, 0, 0}},
+		{"usb", LatencyDiskRead, []uint64{0, 5}},
+		{"usb", "asyncq_wait_write", []uint64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pool+"/"+tt.col, func(t *testing.T) {
+			if got := get(tt.pool, tt.col); !slices.Equal(got, tt.want) {
+				t.Errorf("buckets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLatencyHistograms_Errors(t *testing.T) {
+	header := "tank  total_wait\nlatency  read  write  scrub\n"
+
+	tests := []struct {
+		name string
+		rows string
+	}{
+		{"bucket not a number", "1ns  0  0  0\n"},
+		{"bucket not a power of two minus one", "4  0  0  0\n"},
+		{"missing column", "1  0  0\n"},
+		{"count not a number", "1  0  x  0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseLatencyHistograms([]byte(header + tt.rows)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestClient_GetLatencyHistograms(t *testing.T) {
+	var args []string
+
+	runner := func(_ context.Context, _ string, a ...string) ([]byte, error) {
+		args = a
+		return []byte(iostatFixture), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+	if _, err := client.GetLatencyHistograms(context.Background()); err != nil {
+		t.Fatalf("GetLatencyHistograms() error = %v", err)
+	}
+
+	if got := strings.Join(args, " "); got != "iostat -w -p" {
+		t.Errorf("args = %q, want %q", got, "iostat -w -p")
+	}
+}
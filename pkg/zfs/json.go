@@ -0,0 +1,492 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenZFS 2.3 added -j (JSON) output to zpool list, zpool status, and
+// zfs list. When the host supports it, the Client parses that structured
+// output instead of scraping tab-separated text and status prose. Older
+// versions fall back to the text parsers.
+
+// DisableJSON forces the text parsers even when the host supports JSON
+// output.
+func (c *Client) DisableJSON() {
+	c.featMu.Lock()
+	c.jsonOff = true
+	c.featMu.Unlock()
+}
+
+// useJSON reports whether JSON output should be requested. Unless JSON is
+// disabled, this detects the host's Features on first use.
+func (c *Client) useJSON(ctx context.Context) bool {
+	c.featMu.Lock()
+	off := c.jsonOff
+	c.featMu.Unlock()
+
+	return !off && c.Features(ctx).JSON
+}
+
+// jsonUserland returns the userland version from zpool version -j output,
+// e.g. {"zfs_version": {"userland": "zfs-2.3.0-1", "kernel": "zfs-kmod-2.3.0-1"}}.
+// ok is false if data is not such output.
+func jsonUserland(data []byte) (string, bool) {
+	var v struct {
+		ZfsVersion struct {
+			Userland string `json:"userland"`
+		} `json:"zfs_version"`
+	}
+
+	if json.Unmarshal(data, &v) != nil || v.ZfsVersion.Userland == "" {
+		return "", false
+	}
+
+	return v.ZfsVersion.Userland, true
+}
+
+// jsonValue decodes a JSON string, number, or boolean into its text form.
+// Depending on flags (-p, --json-int) OpenZFS emits numeric properties either
+// as strings or as numbers.
+type jsonValue string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *jsonValue) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		*v = jsonValue(s)
+
+		return nil
+	}
+
+	*v = jsonValue(bytes.TrimSpace(data))
+
+	return nil
+}
+
+// jsonProperty is a property entry: {"value": ..., "source": {...}}.
+type jsonProperty struct {
+	Value jsonValue `json:"value"`
+}
+
+// propertyFields returns the values of names from props, in order. Missing
+// properties are an error.
+func propertyFields(props map[string]jsonProperty, names ...string) ([]string, error) {
+	fields := make([]string, len(names))
+
+	for i, name := range names {
+		p, ok := props[name]
+		if !ok {
+			return nil, fmt.Errorf("missing property %q", name)
+		}
+
+		fields[i] = string(p.Value)
+	}
+
+	return fields, nil
+}
+
+// poolJSONProperties are the zpool list -j property keys matching
+// poolColumns after the leading name column.
+var poolJSONProperties = []string{"size", "allocated", "free", "fragmentation", "dedupratio", "health", "readonly"}
+
+// parsePoolsJSON parses the output of: zpool list -j -p -o <poolColumns>.
+// Values are mapped onto the text column order and parsed by the same
+// parsePoolFields as the text backend. Pools are sorted by name.
+func parsePoolsJSON(data []byte) ([]Pool, error) {
+	var doc struct {
+		Pools map[string]struct {
+			Name       string                  `json:"name"`
+			Properties map[string]jsonProperty `json:"properties"`
+		} `json:"pools"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid zpool list JSON: %w", err)
+	}
+
+	pools := make([]Pool, 0, len(doc.Pools))
+
+	for _, key := range slices.Sorted(maps.Keys(doc.Pools)) {
+		p := doc.Pools[key]
+
+		fields, err := propertyFields(p.Properties, poolJSONProperties...)
+		if err != nil {
+			return nil, fmt.Errorf("pool %q: %w", p.Name, err)
+		}
+
+		// -p already strips units; tolerate the human-readable forms too.
+		fields[3] = strings.TrimSuffix(fields[3], "%")
+		fields[4] = strings.TrimSuffix(fields[4], "x")
+
+		pool, err := parsePoolFields(append([]string{p.Name}, fields...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pool %q: %w", p.Name, err)
+		}
+
+		pools = append(pools, pool)
+	}
+
+	return pools, nil
+}
+
+// datasetJSONProperties are the zfs list -j property keys matching
+// datasetColumns after the leading name column.
+var datasetJSONProperties = []string{
+	"used", "available", "referenced", "type", "sharenfs", "sharesmb", "compressratio", "logicalused", "logicalreferenced",
+}
+
+// parseDatasetsJSON parses the output of:
+// zfs list -j -p -o <datasetColumns> -t <types>.
+// Datasets are sorted by name, matching zfs list text order.
+func parseDatasetsJSON(data []byte) ([]Dataset, error) {
+	var doc struct {
+		Datasets map[string]struct {
+			Name       string                  `json:"name"`
+			Properties map[string]jsonProperty `json:"properties"`
+		} `json:"datasets"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid zfs list JSON: %w", err)
+	}
+
+	datasets := make([]Dataset, 0, len(doc.Datasets))
+
+	for _, key := range slices.Sorted(maps.Keys(doc.Datasets)) {
+		d := doc.Datasets[key]
+
+		fields, err := propertyFields(d.Properties, datasetJSONProperties...)
+		if err != nil {
+			return nil, fmt.Errorf("dataset %q: %w", d.Name, err)
+		}
+
+		ds, err := parseDatasetFields(append([]string{d.Name}, fields...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dataset %q: %w", d.Name, err)
+		}
+
+		datasets = append(datasets, ds)
+	}
+
+	return datasets, nil
+}
+
+// jsonVdev is one node of the zpool status -j vdev tree.
+type jsonVdev struct {
+	Name             string              `json:"name"`
+	State            string              `json:"state"`
+	ReadErrors       jsonValue           `json:"read_errors"`
+	WriteErrors      jsonValue           `json:"write_errors"`
+	ChecksumErrors   jsonValue           `json:"checksum_errors"`
+	ResilverDeferred jsonValue           `json:"resilver_deferred"`
+	Vdevs            map[string]jsonVdev `json:"vdevs"`
+}
+
+// jsonScanStats is the scan_stats object of a zpool status -j pool.
+type jsonScanStats struct {
+	Function    string    `json:"function"`
+	State       string    `json:"state"`
+	StartTime   jsonValue `json:"start_time"`
+	EndTime     jsonValue `json:"end_time"`
+	PassStart   jsonValue `json:"pass_start"`
+	ToExamine   jsonValue `json:"to_examine"`
+	Examined    jsonValue `json:"examined"`
+	Skipped     jsonValue `json:"skipped"`
+	Issued      jsonValue `json:"issued"`
+	Processed   jsonValue `json:"processed"`
+	Errors      jsonValue `json:"errors"`
+	ScrubPause  jsonValue `json:"scrub_pause"`
+	SpentPaused jsonValue `json:"scrub_spent_paused"`
+}
+
+// rate returns the average issue rate in bytes/s since the current pass
+// started, excluding time paused. zpool status -j has no rate field; this is
+// the same calculation zpool status uses for "issued at".
+func (ss *jsonScanStats) rate(now time.Time) float64 {
+	start := parseJSONTime(string(ss.PassStart))
+	if start.IsZero() {
+		start = parseJSONTime(string(ss.StartTime))
+	}
+
+	if start.IsZero() {
+		return 0
+	}
+
+	paused := time.Duration(parseCount(string(ss.SpentPaused))) * time.Second
+
+	elapsed := now.Sub(start) - paused
+	if elapsed < time.Second {
+		return 0
+	}
+
+	return float64(parseCount(string(ss.Issued))) / elapsed.Seconds()
+}
+
+// eta estimates the time left for the active scan at the issue rate, as
+// zpool status does for its "to go" figure. Zero if the rate is unknown.
+func (ss *jsonScanStats) eta(rate float64) time.Duration {
+	toExamine := parseCount(string(ss.ToExamine))
+	total := toExamine - min(parseCount(string(ss.Skipped)), toExamine)
+
+	issued := parseCount(string(ss.Issued))
+	if rate <= 0 || issued >= total {
+		return 0
+	}
+
+	return time.Duration(float64(total-issued) / rate * float64(time.Second))
+}
+
+// completed returns the finished scan described by ss, or a zero value if
+// the scan is not FINISHED. The duration excludes time spent paused, as in
+// the zpool status text.
+func (ss *jsonScanStats) completed() CompletedScan {
+	if !strings.EqualFold(ss.State, "FINISHED") {
+		return CompletedScan{}
+	}
+
+	end := parseJSONTime(string(ss.EndTime))
+	if end.IsZero() {
+		return CompletedScan{}
+	}
+
+	done := CompletedScan{
+		Function: strings.ToLower(ss.Function),
+		End:      end,
+		Repaired: parseCount(string(ss.Processed)),
+		Errors:   parseCount(string(ss.Errors)),
+	}
+
+	if start := parseJSONTime(string(ss.StartTime)); !start.IsZero() && end.After(start) {
+		paused := time.Duration(parseCount(string(ss.SpentPaused))) * time.Second
+		done.Duration = max(end.Sub(start)-paused, 0)
+	}
+
+	return done
+}
+
+// parseJSONTime parses a scan timestamp, which is Unix seconds with -p on
+// some versions and a ctime-style string on others. Unparsable input yields
+// the zero time.
+func parseJSONTime(s string) time.Time {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if secs <= 0 {
+			return time.Time{}
+		}
+
+		return time.Unix(secs, 0)
+	}
+
+	t, err := time.ParseInLocation(scanTimeLayout, strings.Join(strings.Fields(s), " "), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+// progress returns issued / (to_examine - skipped), clamped to 0-1, which is
+// how zpool status computes "% done".
+func (ss *jsonScanStats) progress() float64 {
+	toExamine := parseCount(string(ss.ToExamine))
+	skipped := min(parseCount(string(ss.Skipped)), toExamine)
+
+	total := toExamine - skipped
+	if total == 0 {
+		return 0
+	}
+
+	return min(float64(parseCount(string(ss.Issued)))/float64(total), 1)
+}
+
+// jsonStatusPool is one pool of zpool status -j output. Allocation classes
+// other than the main tree (logs, l2cache, special, dedup) and the spares
+// are separate top-level maps of vdevs. Spares are reported with their
+// AVAIL or INUSE state, as in the text parser.
+type jsonStatusPool struct {
+	Name      string              `json:"name"`
+	State     string              `json:"state"`
+	Status    string              `json:"status"`
+	Action    string              `json:"action"`
+	ErrCount  jsonValue           `json:"error_count"`
+	Vdevs     map[string]jsonVdev `json:"vdevs"`
+	Logs      map[string]jsonVdev `json:"logs"`
+	L2Cache   map[string]jsonVdev `json:"l2cache"`
+	Special   map[string]jsonVdev `json:"special"`
+	Dedup     map[string]jsonVdev `json:"dedup"`
+	Spares    map[string]jsonVdev `json:"spares"`
+	ScanStats *jsonScanStats      `json:"scan_stats"`
+}
+
+// parseStatusJSON decodes zpool status -j -p output into pools sorted by
+// name.
+func parseStatusJSON(data []byte) ([]jsonStatusPool, error) {
+	var doc struct {
+		Pools map[string]jsonStatusPool `json:"pools"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid zpool status JSON: %w", err)
+	}
+
+	pools := make([]jsonStatusPool, 0, len(doc.Pools))
+	for _, key := range slices.Sorted(maps.Keys(doc.Pools)) {
+		pools = append(pools, doc.Pools[key])
+	}
+
+	return pools, nil
+}
+
+// scanStatusesFromJSON derives ScanStatus values from parsed zpool status -j
+// pools. Devices flagged resilver_deferred count toward AwaitingResilver. now
+// is used to compute the issue rate of active scans.
+func scanStatusesFromJSON(pools []jsonStatusPool, now time.Time) []ScanStatus {
+	statuses := make([]ScanStatus, 0, len(pools))
+
+	for i := range pools {
+		p := &pools[i]
+		status := ScanStatus{
+			Pool: p.Name,
+			Suspended: strings.EqualFold(p.State, "SUSPENDED") ||
+				suspendedRe.MatchString("status: "+p.Status),
+			StatusCode: (&statusMessage{status: p.Status, action: p.Action}).classify(),
+			DataErrors: parseCount(string(p.ErrCount)),
+		}
+
+		if ss := p.ScanStats; ss != nil && strings.EqualFold(ss.State, "SCANNING") {
+			switch strings.ToUpper(ss.Function) {
+			case "SCRUB":
+				status.Scrub = true
+			case "RESILVER":
+				status.Resilver = true
+			}
+
+			paused := string(ss.ScrubPause)
+			status.Paused = status.Scrub && paused != "" && paused != "-" && paused != "0"
+			status.Progress = ss.progress()
+			status.ScannedBytes = parseCount(string(ss.Examined))
+			status.IssuedBytes = parseCount(string(ss.Issued))
+			status.TotalBytes = parseCount(string(ss.ToExamine))
+			status.Rate = ss.rate(now)
+
+			if !status.Paused {
+				status.ETA = ss.eta(status.Rate)
+			}
+		}
+
+		if p.ScanStats != nil {
+			status.LastScan = p.ScanStats.completed()
+		}
+
+		for _, leaf := range jsonLeaves(p) {
+			if isTruthy(string(leaf.vdev.ResilverDeferred)) {
+				status.AwaitingResilver++
+				status.Deferred = true
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// vdevStatusesFromJSON returns one VdevStatus per leaf device.
+func vdevStatusesFromJSON(pools []jsonStatusPool) []VdevStatus {
+	var statuses []VdevStatus
+
+	for i := range pools {
+		p := &pools[i]
+
+		for _, leaf := range jsonLeaves(p) {
+			statuses = append(statuses, VdevStatus{
+				Pool:           p.Name,
+				Vdev:           leaf.top,
+				Device:         leaf.vdev.Name,
+				Parent:         leaf.parent,
+				State:          leaf.vdev.State,
+				Class:          leaf.class,
+				ReadErrors:     parseCount(string(leaf.vdev.ReadErrors)),
+				WriteErrors:    parseCount(string(leaf.vdev.WriteErrors)),
+				ChecksumErrors: parseCount(string(leaf.vdev.ChecksumErrors)),
+			})
+		}
+	}
+
+	return statuses
+}
+
+// jsonLeaf is a leaf device together with its top-level vdev name, the
+// interim vdev it sits under (see VdevStatus.Parent), and allocation class.
+type jsonLeaf struct {
+	top    string
+	parent string
+	class  string
+	vdev   *jsonVdev
+}
+
+// jsonLeaves walks the main tree (below the root vdev) and each allocation
+// class, returning leaves in name order.
+func jsonLeaves(p *jsonStatusPool) []jsonLeaf {
+	var leaves []jsonLeaf
+
+	var walk func(top, parent, class string, v *jsonVdev)
+
+	walk = func(top, parent, class string, v *jsonVdev) {
+		if len(v.Vdevs) == 0 {
+			leaves = append(leaves, jsonLeaf{top: top, parent: parent, class: class, vdev: v})
+			return
+		}
+
+		if parent == "" && v.Name != top {
+			parent = v.Name
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(v.Vdevs)) {
+			child := v.Vdevs[key]
+			walk(top, parent, class, &child)
+		}
+	}
+
+	walkTop := func(class string, vdevs map[string]jsonVdev) {
+		for _, key := range slices.Sorted(maps.Keys(vdevs)) {
+			v := vdevs[key]
+			walk(v.Name, "", class, &v)
+		}
+	}
+
+	// The main tree is rooted at a single "root" vdev named after the pool.
+	for _, key := range slices.Sorted(maps.Keys(p.Vdevs)) {
+		root := p.Vdevs[key]
+		walkTop("", root.Vdevs)
+	}
+
+	walkTop(VdevClassLog, p.Logs)
+	walkTop(VdevClassCache, p.L2Cache)
+	walkTop("special", p.Special)
+	walkTop("dedup", p.Dedup)
+	walkTop(VdevClassSpare, p.Spares)
+
+	return leaves
+}
+
+// isTruthy reports whether a JSON flag value is set.
+func isTruthy(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "1", "on", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,391 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+const poolListJSON = `{
+  "output_version": {"command": "zpool list", "vers_major": 0, "vers_minor": 1},
+  "pools": {
+    "tank": {
+      "name": "tank",
+      "type": "POOL",
+      "state": "ONLINE",
+      "properties": {
+        "size": {"value": "10737418240", "source": {"type": "NONE", "data": "-"}},
+        "allocated": {"value": "5368709120", "source": {"type": "NONE", "data": "-"}},
+        "free": {"value": "5368709120", "source": {"type": "NONE", "data": "-"}},
+        "fragmentation": {"value": "33", "source": {"type": "NONE", "data": "-"}},
+        "dedupratio": {"value": "1.00", "source": {"type": "NONE", "data": "-"}},
+        "health": {"value": "ONLINE", "source": {"type": "NONE", "data": "-"}},
+        "readonly": {"value": "off", "source": {"type": "NONE", "data": "-"}}
+      }
+    },
+    "backup": {
+      "name": "backup",
+      "properties": {
+        "size": {"value": 1073741824},
+        "allocated": {"value": 0},
+        "free": {"value": 1073741824},
+        "fragmentation": {"value": "-"},
+        "dedupratio": {"value": "1.50x"},
+        "health": {"value": "DEGRADED"},
+        "readonly": {"value": "on"}
+      }
+    }
+  }
+}`
+
+const zfsListJSON = `{
+  "output_version": {"command": "zfs list", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "tank/media": {
+      "name": "tank/media",
+      "type": "FILESYSTEM",
+      "pool": "tank",
+      "properties": {
+        "used": {"value": "4294967296"},
+        "available": {"value": "5368709120"},
+        "referenced": {"value": "4294967296"},
+        "type": {"value": "filesystem"},
+        "sharenfs": {"value": "on"},
+        "sharesmb": {"value": "off"},
+        "compressratio": {"value": "1.52"},
+        "logicalused": {"value": "6528350208"},
+        "logicalreferenced": {"value": "6528350208"}
+      }
+    },
+    "tank": {
+      "name": "tank",
+      "type": "FILESYSTEM",
+      "pool": "tank",
+      "properties": {
+        "used": {"value": "5368709120"},
+        "available": {"value": "5368709120"},
+        "referenced": {"value": "262144"},
+        "type": {"value": "filesystem"},
+        "sharenfs": {"value": "off"},
+        "sharesmb": {"value": "off"},
+        "compressratio": {"value": "1.00"},
+        "logicalused": {"value": "5368709120"},
+        "logicalreferenced": {"value": "262144"}
+      }
+    }
+  }
+}`
+
+const zpoolStatusJSON = `{
+  "output_version": {"command": "zpool status", "vers_major": 0, "vers_minor": 1},
+  "pools": {
+    "tank": {
+      "name": "tank",
+      "state": "DEGRADED",
+      "status": "One or more devices is currently being resilvered.  The pool will\n\tcontinue to function, possibly in a degraded state.",
+      "action": "Wait for the resilver to complete.",
+      "error_count": "2",
+      "vdevs": {
+        "tank": {
+          "name": "tank",
+          "vdev_type": "root",
+          "read_errors": "0", "write_errors": "0", "checksum_errors": "0",
+          "vdevs": {
+            "mirror-0": {
+              "name": "mirror-0",
+              "vdev_type": "mirror",
+              "read_errors": "0", "write_errors": "0", "checksum_errors": "0",
+              "vdevs": {
+                "sda": {"name": "sda", "vdev_type": "disk", "state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"},
+                "replacing-1": {
+                  "name": "replacing-1",
+                  "vdev_type": "replacing",
+                  "read_errors": "0", "write_errors": "0", "checksum_errors": "0",
+                  "vdevs": {
+                    "sdb": {"name": "sdb", "vdev_type": "disk", "state": "ONLINE", "read_errors": "2", "write_errors": "0", "checksum_errors": "1543"},
+                    "sde": {"name": "sde", "vdev_type": "disk", "state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"}
+                  }
+                }
+              }
+            },
+            "sdc": {"name": "sdc", "vdev_type": "disk", "state": "FAULTED", "read_errors": 0, "write_errors": 4, "checksum_errors": 0, "resilver_deferred": true}
+          }
+        }
+      },
+      "logs": {
+        "nvme0n1": {"name": "nvme0n1", "vdev_type": "disk", "state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"}
+      },
+      "spares": {
+        "sdd": {"name": "sdd", "vdev_type": "disk", "state": "AVAIL"}
+      },
+      "scan_stats": {
+        "function": "RESILVER",
+        "state": "SCANNING",
+        "pass_start": "1738576800",
+        "to_examine": "1000",
+        "examined": "600",
+        "skipped": "200",
+        "issued": "200",
+        "scrub_pause": "-"
+      }
+    },
+    "backup": {
+      "name": "backup",
+      "state": "ONLINE",
+      "vdevs": {
+        "backup": {
+          "name": "backup",
+          "vdev_type": "root",
+          "vdevs": {
+            "sdx": {"name": "sdx", "vdev_type": "disk", "state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"}
+          }
+        }
+      },
+      "scan_stats": {
+        "function": "SCRUB",
+        "state": "SCANNING",
+        "to_examine": "400",
+        "skipped": "0",
+        "issued": "100",
+        "scrub_pause": "Mon Feb  3 10:00:00 2025"
+      }
+    }
+  }
+}`
+
+func TestParsePoolsJSON(t *testing.T) {
+	pools, err := parsePoolsJSON([]byte(poolListJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pools) != 2 || pools[0].Name != "backup" || pools[1].Name != "tank" {
+		t.Fatalf("unexpected pools: %+v", pools)
+	}
+
+	tank := pools[1]
+	if tank.Size != 10737418240 || tank.Allocated != 5368709120 || tank.Fragmentation != 0.33 || tank.Health != "ONLINE" || tank.ReadOnly {
+		t.Errorf("unexpected tank: %+v", tank)
+	}
+
+	backup := pools[0]
+	if backup.Size != 1073741824 || backup.DedupRatio != 1.5 || !math.IsNaN(backup.Fragmentation) || !backup.ReadOnly {
+		t.Errorf("unexpected backup: %+v", backup)
+	}
+}
+
+func TestParsePoolsJSON_MissingProperty(t *testing.T) {
+	if _, err := parsePoolsJSON([]byte(`{"pools": {"tank": {"name": "tank", "properties": {}}}}`)); err == nil {
+		t.Fatal("expected error for missing properties")
+	}
+}
+
+func TestParseDatasetsJSON(t *testing.T) {
+	datasets, err := parseDatasetsJSON([]byte(zfsListJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Dataset{
+		{
+			Name: "tank", Pool: "tank", Used: 5368709120, Available: 5368709120, Referenced: 262144, Type: "filesystem", CompressRatio: 1,
+			LogicalUsed: 5368709120, LogicalReferenced: 262144,
+		},
+		{
+			Name: "tank/media", Pool: "tank", Used: 4294967296, Available: 5368709120, Referenced: 4294967296,
+			Type: "filesystem", ShareNFS: true, CompressRatio: 1.52,
+			LogicalUsed: 6528350208, LogicalReferenced: 6528350208,
+		},
+	}
+
+	if !slices.Equal(datasets, want) {
+		t.Errorf("got  %+v\nwant %+v", datasets, want)
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	pools, err := parseStatusJSON([]byte(zpoolStatusJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 100s into the tank resilver pass.
+	scans := scanStatusesFromJSON(pools, time.Unix(1738576900, 0))
+	wantScans := []ScanStatus{
+		{Pool: "backup", Scrub: true, Progress: 0.25, Paused: true, StatusCode: StatusOK, IssuedBytes: 100, TotalBytes: 400},
+		{
+			Pool: "tank", Resilver: true, Progress: 0.25, Deferred: true, AwaitingResilver: 1, StatusCode: StatusResilverInProgress, DataErrors: 2,
+			ScannedBytes: 600, IssuedBytes: 200, TotalBytes: 1000, Rate: 2, ETA: 5 * time.Minute,
+		},
+	}
+
+	if !slices.Equal(scans, wantScans) {
+		t.Errorf("scans:\ngot  %+v\nwant %+v", scans, wantScans)
+	}
+
+	vdevs := vdevStatusesFromJSON(pools)
+	wantVdevs := []VdevStatus{
+		{Pool: "backup", Vdev: "sdx", Device: "sdx", State: "ONLINE"},
+		{Pool: "tank", Vdev: "mirror-0", Device: "sdb", Parent: "replacing-1", State: "ONLINE", ReadErrors: 2, ChecksumErrors: 1543},
+		{Pool: "tank", Vdev: "mirror-0", Device: "sde", Parent: "replacing-1", State: "ONLINE"},
+		{Pool: "tank", Vdev: "mirror-0", Device: "sda", State: "ONLINE"},
+		{Pool: "tank", Vdev: "sdc", Device: "sdc", State: "FAULTED", WriteErrors: 4},
+		{Pool: "tank", Vdev: "nvme0n1", Device: "nvme0n1", State: "ONLINE", Class: VdevClassLog},
+		{Pool: "tank", Vdev: "sdd", Device: "sdd", State: "AVAIL", Class: VdevClassSpare},
+	}
+
+	if !slices.Equal(vdevs, wantVdevs) {
+		t.Errorf("vdevs:\ngot  %+v\nwant %+v", vdevs, wantVdevs)
+	}
+}
+
+// jsonRunner answers the JSON probe and dispatches JSON fixtures by command.
+func jsonRunner(calls *[][]string) Runner {
+	return func(_ context.Context, name string, args ...string) ([]byte, error) {
+		*calls = append(*calls, append([]string{name}, args...))
+
+		switch {
+		case args[0] == "version":
+			return []byte(`{"zfs_version": {"userland": "zfs-2.3.0-1", "kernel": "zfs-kmod-2.3.0-1"}}`), nil
+		case name == "zpool" && args[0] == "list":
+			return []byte(poolListJSON), nil
+		case name == "zfs" && args[0] == "list":
+			return []byte(zfsListJSON), nil
+		case name == "zpool" && args[0] == "status":
+			return []byte(zpoolStatusJSON), nil
+		default:
+			return nil, errors.New("unexpected command")
+		}
+	}
+}
+
+func TestClient_JSONDetection(t *testing.T) {
+	var calls [][]string
+
+	client := NewClient(jsonRunner(&calls), testLogger(), "zpool", "zfs")
+
+	pools, err := client.GetPools(context.Background())
+	if err != nil || len(pools) != 2 {
+		t.Fatalf("GetPools = %v, %v", pools, err)
+	}
+
+	datasets, err := client.GetDatasets(context.Background())
+	if err != nil || len(datasets) != 2 {
+		t.Fatalf("GetDatasets = %v, %v", datasets, err)
+	}
+
+	scans, err := client.GetScanStatuses(context.Background(), nil)
+	if err != nil || len(scans) != 2 {
+		t.Fatalf("GetScanStatuses = %v, %v", scans, err)
+	}
+
+	probes := 0
+
+	for _, c := range calls {
+		if c[1] == "version" {
+			probes++
+		}
+	}
+
+	if probes != 1 {
+		t.Errorf("expected a single version probe, got %d", probes)
+	}
+
+	if !slices.Contains(calls[1], "-j") {
+		t.Errorf("expected JSON flag in %v", calls[1])
+	}
+}
+
+func TestClient_JSONFallback(t *testing.T) {
+	var calls [][]string
+
+	runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+
+		if args[0] == "version" {
+			return nil, errors.New("invalid option 'j'")
+		}
+
+		return []byte("tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	pools, err := client.GetPools(context.Background())
+	if err != nil || len(pools) != 1 {
+		t.Fatalf("GetPools = %v, %v", pools, err)
+	}
+
+	// The -j probe is followed by a plain zpool version for the features.
+	if len(calls) != 3 || !slices.Contains(calls[2], "-Hp") {
+		t.Errorf("expected text flags after failed probe, got %v", calls)
+	}
+}
+
+func TestClient_DisableJSON(t *testing.T) {
+	var calls [][]string
+
+	client := NewClient(jsonRunner(&calls), testLogger(), "zpool", "zfs")
+	client.DisableJSON()
+
+	_, _ = client.GetPools(context.Background())
+
+	if len(calls) != 1 || !slices.Contains(calls[0], "-Hp") {
+		t.Errorf("expected a single text zpool list, got %v", calls)
+	}
+}
+
+func TestScanStatusesFromJSON_Completed(t *testing.T) {
+	pools, err := parseStatusJSON([]byte(`{"pools": {
+		"tank": {"name": "tank", "scan_stats": {
+			"function": "SCRUB", "state": "FINISHED",
+			"start_time": "1738451041", "end_time": "1738456041",
+			"processed": "4096", "errors": "2", "scrub_spent_paused": "1000"
+		}},
+		"usb": {"name": "usb", "scan_stats": {
+			"function": "RESILVER", "state": "FINISHED",
+			"start_time": "Sun Feb  2 00:00:00 2025", "end_time": "Sun Feb  2 00:10:00 2025",
+			"processed": "0", "errors": "0"
+		}},
+		"new": {"name": "new", "scan_stats": {"function": "NONE", "state": "NONE"}}
+	}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	scans := scanStatusesFromJSON(pools, time.Now())
+	want := []CompletedScan{
+		{},
+		{Function: "scrub", End: time.Unix(1738456041, 0), Duration: 4000 * time.Second, Repaired: 4096, Errors: 2},
+		{Function: "resilver", End: time.Date(2025, time.February, 2, 0, 10, 0, 0, time.Local), Duration: 10 * time.Minute},
+	}
+
+	for i, s := range scans {
+		if !completedScanEqual(s.LastScan, want[i]) {
+			t.Errorf("%s LastScan = %+v, want %+v", s.Pool, s.LastScan, want[i])
+		}
+	}
+}
+
+func TestScanStatusesFromJSON_Suspended(t *testing.T) {
+	pools, err := parseStatusJSON([]byte(`{"pools": {
+		"a": {"name": "a", "state": "SUSPENDED"},
+		"b": {"name": "b", "state": "ONLINE",
+			"status": "One or more devices are faulted in response to IO failures."},
+		"c": {"name": "c", "state": "ONLINE"}
+	}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]bool{"a": true, "b": true, "c": false}
+
+	for _, s := range scanStatusesFromJSON(pools, time.Now()) {
+		if s.Suspended != want[s.Pool] {
+			t.Errorf("%s Suspended = %v, want %v", s.Pool, s.Suspended, want[s.Pool])
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// LoggingRunner wraps r so that each command is logged at debug level with
+// its arguments, duration, exit status, and stdout size. When debug
+// logging is off the command runs without any extra work.
+func LoggingRunner(r Runner, logger *slog.Logger) Runner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			return r(ctx, name, args...)
+		}
+
+		start := time.Now()
+		out, err := r(ctx, name, args...)
+		logCommand(ctx, logger, name, args, time.Since(start), int64(len(out)), err)
+
+		return out, err
+	}
+}
+
+// LoggingStreamRunner is the StreamRunner counterpart of LoggingRunner. The
+// output size counts the bytes parse read.
+func LoggingStreamRunner(r StreamRunner, logger *slog.Logger) StreamRunner {
+	return func(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			return r(ctx, parse, name, args...)
+		}
+
+		var n int64
+
+		start := time.Now()
+		err := r(ctx, func(rd io.Reader) error {
+			return parse(&countingReader{r: rd, n: &n})
+		}, name, args...)
+		logCommand(ctx, logger, name, args, time.Since(start), n, err)
+
+		return err
+	}
+}
+
+// logCommand logs one finished command. The exit status is 0 on success,
+// the process's status when it exited non-zero, and -1 when it did not run
+// to completion.
+func logCommand(ctx context.Context, logger *slog.Logger, name string, args []string, d time.Duration, size int64, err error) {
+	exit := 0
+
+	if err != nil {
+		exit = -1
+
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) {
+			exit = cmdErr.ExitCode
+		}
+	}
+
+	attrs := []slog.Attr{
+		slog.String("cmd", name),
+		slog.Any("args", args),
+		slog.Duration("duration", d),
+		slog.Int("exit", exit),
+		slog.Int64("bytes", size),
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+	}
+
+	logger.LogAttrs(ctx, slog.LevelDebug, "Ran command", attrs...)
+}
+
+// countingReader counts the bytes read through it into n.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+
+	return n, err
+}
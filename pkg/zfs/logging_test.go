@@ -0,0 +1,97 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLoggingRunner(t *testing.T) {
+	base := func(_ context.Context, name string, _ ...string) ([]byte, error) {
+		if name == "zfs" {
+			return []byte("partial"), &CommandError{Cmd: name, ExitCode: 1, Err: errors.New("exit status 1")}
+		}
+
+		return []byte("tank\tONLINE\n"), nil
+	}
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		cmd   string
+		want  []string
+	}{
+		{
+			name:  "success",
+			level: slog.LevelDebug,
+			cmd:   "zpool",
+			want:  []string{`msg="Ran command"`, "cmd=zpool", `args="[list -Hp]"`, "duration=", "exit=0", "bytes=12"},
+		},
+		{
+			name:  "exit status",
+			level: slog.LevelDebug,
+			cmd:   "zfs",
+			want:  []string{"cmd=zfs", "exit=1", "bytes=7", `err="command \"zfs\" exited 1"`},
+		},
+		{
+			name:  "info level logs nothing",
+			level: slog.LevelInfo,
+			cmd:   "zpool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: tt.level}))
+
+			if _, err := LoggingRunner(base, logger)(context.Background(), tt.cmd, "list", "-Hp"); (err != nil) != (tt.cmd == "zfs") {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := buf.String()
+			if len(tt.want) == 0 && got != "" {
+				t.Errorf("logged %q, want nothing", got)
+			}
+
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("log %q does not contain %q", got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggingStreamRunner(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	base := func(ctx context.Context, parse func(io.Reader) error, _ string, _ ...string) error {
+		if err := parse(strings.NewReader("tank@a\t0\ntank@b\t1\n")); err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	}
+
+	err := LoggingStreamRunner(base, logger)(context.Background(), func(r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}, "zfs", "list", "-t", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range []string{"cmd=zfs", "exit=0", "bytes=18"} {
+		if !strings.Contains(buf.String(), w) {
+			t.Errorf("log %q does not contain %q", buf.String(), w)
+		}
+	}
+}
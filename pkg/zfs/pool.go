@@ -1,7 +1,9 @@
 package zfs
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"strconv"
 	"strings"
@@ -15,7 +17,7 @@ type Pool struct {
 	Free          uint64
 	Fragmentation float64 // 0-1 ratio, NaN if unavailable
 	DedupRatio    float64
-	Health        string // ONLINE, DEGRADED, FAULTED, OFFLINE, REMOVED, UNAVAIL
+	Health        string // ONLINE, DEGRADED, FAULTED, OFFLINE, REMOVED, UNAVAIL, SUSPENDED
 	ReadOnly      bool
 }
 
@@ -23,25 +25,24 @@ type Pool struct {
 const poolColumns = "name,size,alloc,free,frag,dedup,health,readonly"
 
 // parsePools parses the output of: zpool list -Hp -o name,size,alloc,free,frag,dedup,health,readonly.
-func parsePools(data []byte) ([]Pool, error) {
-	trimmed := strings.TrimSpace(string(data))
-	if trimmed == "" {
-		return nil, nil
-	}
-
-	lines := strings.Split(trimmed, "\n")
-	pools := make([]Pool, 0, len(lines))
+// The output is read a line at a time.
+func parsePools(r io.Reader, cols columnList) ([]Pool, error) {
+	var pools []Pool
 
-	for _, line := range lines {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
 		if line == "" {
 			continue
 		}
 
 		fields := strings.Split(line, "\t")
-		if len(fields) != 8 {
-			return nil, fmt.Errorf("expected 8 fields, got %d: %q", len(fields), line)
+		if len(fields) != cols.width() {
+			return nil, fmt.Errorf("expected %d fields, got %d: %q", cols.width(), len(fields), line)
 		}
 
+		fields = cols.fill(fields)
+
 		pool, err := parsePoolFields(fields)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse pool %q: %w", fields[0], err)
@@ -50,6 +51,10 @@ func parsePools(data []byte) ([]Pool, error) {
 		pools = append(pools, pool)
 	}
 
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pool output: %w", err)
+	}
+
 	return pools, nil
 }
 
@@ -2,6 +2,7 @@ package zfs
 
 import (
 	"math"
+	"strings"
 	"testing"
 )
 
@@ -179,7 +180,7 @@ func TestParsePools(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pools, err := parsePools([]byte(tt.input))
+			pools, err := parsePools(strings.NewReader(tt.input), newColumnList(poolColumns, nil))
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("parsePools() error = %v, wantErr %v", err, tt.wantErr)
 			}
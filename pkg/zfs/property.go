@@ -0,0 +1,146 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UserProperties maps dataset name to user property name to value. Only
+// properties matching the requested prefix are included.
+type UserProperties map[string]map[string]string
+
+// DatasetProperties maps dataset name to property name to value for an
+// explicitly requested set of native or user properties.
+type DatasetProperties map[string]map[string]string
+
+// PoolProperties maps pool name to property name to value.
+type PoolProperties map[string]map[string]string
+
+// GetUserProperties returns the locally set or inherited user properties whose
+// names start with prefix (e.g. "exporter:") for every filesystem and volume.
+// All datasets are fetched in a single batched zfs get call.
+func (c *Client) GetUserProperties(ctx context.Context, prefix string) (UserProperties, error) {
+	out, err := c.runner(ctx, c.zfsPath, "get", "-Hp", "-o", "name,property,value", "-s", "local,inherited", "-t", "filesystem,volume", "all")
+	if err != nil {
+		return nil, fmt.Errorf("zfs get failed: %w", err)
+	}
+
+	props, err := parseUserProperties(out, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse property output: %w", err)
+	}
+
+	return props, nil
+}
+
+// GetDatasetProperties returns the values of the named properties for every
+// filesystem and volume, regardless of where the value comes from (local,
+// inherited, default). Datasets where a property does not apply ("-") omit
+// it.
+//
+// INFO(security): names come from operator configuration rather than string
+// literals. config.Validate restricts them to the ZFS property charset, which
+// cannot start with "-", so they cannot be read as zfs get flags.
+func (c *Client) GetDatasetProperties(ctx context.Context, names []string) (DatasetProperties, error) {
+	out, err := c.runner(ctx, c.zfsPath, "get", "-Hp", "-o", "name,property,value", "-t", "filesystem,volume", strings.Join(names, ","))
+	if err != nil {
+		return nil, fmt.Errorf("zfs get failed: %w", err)
+	}
+
+	props, err := parseProperties(out, func(string) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse property output: %w", err)
+	}
+
+	return props, nil
+}
+
+// GetPoolProperties returns the values of the named pool properties for
+// every pool. Properties a pool does not report ("-") are omitted.
+func (c *Client) GetPoolProperties(ctx context.Context, names []string) (PoolProperties, error) {
+	out, err := c.runner(ctx, c.zpoolPath, "get", "-Hp", "-o", "name,property,value", strings.Join(names, ","))
+	if err != nil {
+		return nil, fmt.Errorf("zpool get failed: %w", err)
+	}
+
+	// zpool get prints the same columns as zfs get.
+	props, err := parseProperties(out, func(string) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pool property output: %w", err)
+	}
+
+	return props, nil
+}
+
+// GetPoolCreationTimes returns when each pool was created. zpool get has no
+// creation property, so it is read from each pool's root dataset.
+func (c *Client) GetPoolCreationTimes(ctx context.Context) (map[string]time.Time, error) {
+	out, err := c.runner(ctx, c.zfsPath, "get", "-Hp", "-o", "name,property,value", "-d", "0", "creation")
+	if err != nil {
+		return nil, fmt.Errorf("zfs get creation failed: %w", err)
+	}
+
+	props, err := parseProperties(out, func(property string) bool { return property == "creation" })
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse property output: %w", err)
+	}
+
+	created := make(map[string]time.Time, len(props))
+
+	for pool, p := range props {
+		secs, err := strconv.ParseInt(p["creation"], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creation %q for pool %q: %w", p["creation"], pool, err)
+		}
+
+		created[pool] = time.Unix(secs, 0)
+	}
+
+	return created, nil
+}
+
+// parseUserProperties parses the output of:
+// zfs get -Hp -o name,property,value -s local,inherited -t filesystem,volume all
+// keeping only properties whose name starts with prefix.
+func parseUserProperties(data []byte, prefix string) (UserProperties, error) {
+	return parseProperties(data, func(property string) bool { return strings.HasPrefix(property, prefix) })
+}
+
+// parseProperties parses zfs get -Hp -o name,property,value output into a
+// dataset -> property -> value map, keeping properties for which keep
+// returns true.
+func parseProperties(data []byte, keep func(property string) bool) (map[string]map[string]string, error) {
+	props := make(map[string]map[string]string)
+
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" {
+		return props, nil
+	}
+
+	for line := range strings.SplitSeq(trimmed, "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("expected 3 fields, got %d: %q", len(fields), line)
+		}
+
+		name, property, value := fields[0], fields[1], fields[2]
+		if !keep(property) || value == "-" {
+			continue
+		}
+
+		if props[name] == nil {
+			props[name] = make(map[string]string)
+		}
+
+		props[name][property] = value
+	}
+
+	return props, nil
+}
@@ -0,0 +1,195 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseUserProperties(t *testing.T) {
+	input := "tank\texporter:owner\tinfra\n" +
+		"tank/media\texporter:owner\tteamA\n" +
+		"tank/media\tcompression\tlz4\n" +
+		"tank/media\texporter:note\tvalue\twith\ttabs\n" +
+		"tank/vol\texporter:owner\t-\n"
+
+	got, err := parseUserProperties([]byte(input), "exporter:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got["tank"]["exporter:owner"] != "infra" {
+		t.Errorf("tank owner = %q, want infra", got["tank"]["exporter:owner"])
+	}
+
+	if got["tank/media"]["exporter:owner"] != "teamA" {
+		t.Errorf("tank/media owner = %q, want teamA", got["tank/media"]["exporter:owner"])
+	}
+
+	if got["tank/media"]["exporter:note"] != "value\twith\ttabs" {
+		t.Errorf("tank/media note = %q", got["tank/media"]["exporter:note"])
+	}
+
+	if _, ok := got["tank/media"]["compression"]; ok {
+		t.Error("non-prefixed property should be ignored")
+	}
+
+	if _, ok := got["tank/vol"]; ok {
+		t.Error("unset (-) property should be ignored")
+	}
+}
+
+func TestParseUserProperties_Malformed(t *testing.T) {
+	if _, err := parseUserProperties([]byte("tank\tonly-two\n"), "exporter:"); err == nil {
+		t.Fatal("expected error for malformed line")
+	}
+}
+
+func TestClient_GetUserProperties_Args(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("tank\texporter:owner\tinfra\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	props, err := client.GetUserProperties(context.Background(), "exporter:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if props["tank"]["exporter:owner"] != "infra" {
+		t.Errorf("unexpected props: %v", props)
+	}
+
+	if gotArgs[0] != "get" || !slices.Contains(gotArgs, "all") {
+		t.Errorf("unexpected args: %v", gotArgs)
+	}
+}
+
+func TestClient_GetUserProperties_CommandError(t *testing.T) {
+	runner := func(_ context.Context, _ string, _ ...string) ([]byte, error) {
+		return nil, errors.New("boom")
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetUserProperties(context.Background(), "exporter:"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestClient_GetDatasetProperties(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+
+		return []byte("tank\trecordsize\t131072\n" +
+			"tank\tcom.sun:auto-snapshot\ttrue\n" +
+			"tank/vol\trecordsize\t-\n" +
+			"tank/vol\tcom.sun:auto-snapshot\t-\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	props, err := client.GetDatasetProperties(context.Background(), []string{"recordsize", "com.sun:auto-snapshot"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if props["tank"]["recordsize"] != "131072" || props["tank"]["com.sun:auto-snapshot"] != "true" {
+		t.Errorf("unexpected props: %v", props)
+	}
+
+	if _, ok := props["tank/vol"]; ok {
+		t.Errorf("expected tank/vol to be omitted, got %v", props["tank/vol"])
+	}
+
+	if gotArgs[len(gotArgs)-1] != "recordsize,com.sun:auto-snapshot" || slices.Contains(gotArgs, "-s") {
+		t.Errorf("unexpected args: %v", gotArgs)
+	}
+}
+
+func TestClient_GetPoolProperties(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = append([]string{name}, args...)
+
+		return []byte("tank\tashift\t12\n" +
+			"tank\tautotrim\ton\n" +
+			"usb\tashift\t0\n" +
+			"usb\tautotrim\toff\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	props, err := client.GetPoolProperties(context.Background(), []string{"ashift", "autotrim"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if props["tank"]["ashift"] != "12" || props["tank"]["autotrim"] != "on" || props["usb"]["ashift"] != "0" {
+		t.Errorf("unexpected props: %v", props)
+	}
+
+	if got := strings.Join(gotArgs, " "); got != "zpool get -Hp -o name,property,value ashift,autotrim" {
+		t.Errorf("unexpected command: %q", got)
+	}
+}
+
+func TestClient_GetPoolProperties_CommandError(t *testing.T) {
+	runner := func(context.Context, string, ...string) ([]byte, error) {
+		return nil, errors.New("no such pool")
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetPoolProperties(context.Background(), []string{"ashift"}); err == nil ||
+		!strings.Contains(err.Error(), "zpool get failed") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestClient_GetPoolCreationTimes(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = append([]string{name}, args...)
+
+		return []byte("tank\tcreation\t1700000000\nusb\tcreation\t1738540800\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	created, err := client.GetPoolCreationTimes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !created["tank"].Equal(time.Unix(1700000000, 0)) || !created["usb"].Equal(time.Unix(1738540800, 0)) {
+		t.Errorf("unexpected creation times: %v", created)
+	}
+
+	if got := strings.Join(gotArgs, " "); got != "zfs get -Hp -o name,property,value -d 0 creation" {
+		t.Errorf("unexpected command: %q", got)
+	}
+}
+
+func TestClient_GetPoolCreationTimes_Invalid(t *testing.T) {
+	runner := func(context.Context, string, ...string) ([]byte, error) {
+		return []byte("tank\tcreation\tyesterday\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetPoolCreationTimes(context.Background()); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
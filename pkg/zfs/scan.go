@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ScanStatus represents the current scan state for a pool.
@@ -12,6 +13,60 @@ type ScanStatus struct {
 	Scrub    bool    // true if scrub in progress
 	Resilver bool    // true if resilver in progress
 	Progress float64 // 0-1 scan progress, 0 if no active scan
+
+	// Paused is true if a scrub has been paused (zpool scrub -p) or is
+	// waiting for a resilver to finish.
+	Paused bool
+
+	// Deferred is true if a resilver has been deferred until the current
+	// one completes, or the scan is waiting for a resilver.
+	Deferred bool
+
+	// AwaitingResilver counts devices marked "(awaiting resilver)" in the
+	// pool config, i.e. the deferred resilver queue length.
+	AwaitingResilver int
+
+	// Suspended is true if pool I/O is suspended after device failures
+	// ("state: SUSPENDED", or a status reporting I/O failures). Older
+	// releases still report the pool health from the vdev tree, often
+	// ONLINE, so zpool list alone misses it.
+	Suspended bool
+
+	// StatusCode classifies the pool's status: and action: messages, one
+	// of StatusCodes; StatusOK when zpool status prints none.
+	StatusCode string
+
+	// DataErrors is the number of known permanent data errors, from the
+	// errors: section ("errors: 3 data errors, use '-v' for a list", or
+	// the file list of zpool status -v).
+	DataErrors uint64
+
+	// Byte counts and issue rate of the active scan; zero when idle.
+	// Scanned is metadata traversed, Issued is data actually read and
+	// verified, Total is the amount the scan has to cover.
+	ScannedBytes uint64
+	IssuedBytes  uint64
+	TotalBytes   uint64
+	Rate         float64 // issue rate in bytes/s (scan rate before 0.8); 0 until known
+
+	// ETA is zpool status's estimate of the time left for the active scan
+	// ("00:42:27 to go"); zero when idle, paused, or not yet estimated.
+	ETA time.Duration
+
+	// LastScan is the most recently completed scrub or resilver. zpool
+	// status only reports the latest scan, so a resilver hides the scrub
+	// before it. Zero if no scan has completed or one is running.
+	LastScan CompletedScan
+}
+
+// CompletedScan describes a finished scrub or resilver, from the
+// "scan: scrub repaired 0B in 01:23:45 with 0 errors on <date>" line.
+type CompletedScan struct {
+	Function string // "scrub" or "resilver"; empty if none
+	End      time.Time
+	Duration time.Duration
+	Repaired uint64 // bytes repaired (scrub) or resilvered (resilver)
+	Errors   uint64
 }
 
 var (
@@ -23,8 +78,50 @@ var (
 
 	// progressRe matches percentage like "48.36% done".
 	progressRe = regexp.MustCompile(`(\d+\.?\d*)%\s+done`)
+
+	// etaRe matches the time left, "00:42:27 to go" or "1 days 02:00:00 to
+	// go", or "0h42m to go" before 0.8.
+	etaRe = regexp.MustCompile(`((?:\d+ days? )?\d+:\d{2}:\d{2}|\d+h\d+m) to go`)
+
+	// scanPausedRe matches "scan: scrub paused since ..." and
+	// "scan: scrub paused 'waiting for resilver'".
+	scanPausedRe = regexp.MustCompile(`^\s*scan:\s+(scrub|resilver) paused`)
+
+	// suspendedRe matches the state and status lines of a pool whose I/O is
+	// suspended (failmode=wait or continue, or a multihost write failure).
+	suspendedRe = regexp.MustCompile(`^\s*state:\s+SUSPENDED|^\s*status:.*(?:in response to IO failures|is suspended)`)
+
+	// deferredRe matches deferred resilver wording on scan or status lines.
+	deferredRe = regexp.MustCompile(`resilver deferred|waiting for resilver`)
+
+	// scanDoneRe matches a completed scan line:
+	//   scan: scrub repaired 0B in 01:23:45 with 0 errors on Sun Feb  2 00:24:01 2025
+	//   scan: resilvered 1.50G in 0 days 00:10:00 with 0 errors on Sun Feb  2 00:24:01 2025
+	scanDoneRe = regexp.MustCompile(`^\s*scan:\s+(scrub repaired|resilvered)\s+(\S+)\s+in\s+(.+?)\s+with\s+(\d+)\s+errors\s+on\s+(.+?)\s*$`)
+
+	// scanBytesRe matches the OpenZFS 2.2+ progress line:
+	//   1.23T / 2.00T scanned at 500M/s, 1.00T / 2.00T issued at 400M/s
+	scanBytesRe = regexp.MustCompile(`^\s*(\S+)\s*/\s*(\S+)\s+scanned(?:\s+at\s+(\S+)/s)?,\s*(\S+)\s*/\s*\S+\s+issued(?:\s+at\s+(\S+)/s)?`)
+
+	// scanBytesTotalRe matches the OpenZFS 0.8-2.1 progress line:
+	//   374G scanned at 161M/s, 340G issued at 146M/s, 703G total
+	scanBytesTotalRe = regexp.MustCompile(`^\s*(\S+)\s+scanned(?:\s+at\s+(\S+)/s)?,\s*(\S+)\s+issued(?:\s+at\s+(\S+)/s)?,\s*(\S+)\s+total`)
+
+	// scanBytesLegacyRe matches the pre-0.8 progress line, which has no
+	// separate issue phase:
+	//   374G scanned out of 703G at 161M/s, 0h42m to go
+	scanBytesLegacyRe = regexp.MustCompile(`^\s*(\S+)\s+scanned out of\s+(\S+)\s+at\s+(\S+)/s`)
+
+	// legacyDurationRe matches pre-0.8 durations such as "1h23m".
+	legacyDurationRe = regexp.MustCompile(`^(\d+)h(\d+)m$`)
 )
 
+// scanTimeLayout is the ctime-style date zpool status prints after "on".
+const scanTimeLayout = "Mon Jan _2 15:04:05 2006"
+
+// awaitingResilver marks a device queued behind the active resilver.
+const awaitingResilver = "(awaiting resilver)"
+
 // parseScanStatuses parses the output of: zpool status
 // It splits by pool sections and extracts scan state for each pool.
 func parseScanStatuses(data []byte) []ScanStatus {
@@ -37,18 +134,19 @@ func parseScanStatuses(data []byte) []ScanStatus {
 
 	var currentPool string
 
-	var scanSeen bool
+	var scanSeen, suspended bool
 
 	for line := range strings.SplitSeq(text, "\n") {
 		// Check for pool name — starts a new pool section.
 		if m := poolNameRe.FindStringSubmatch(line); m != nil {
 			// Close out previous pool if scan line was never seen.
 			if currentPool != "" && !scanSeen {
-				statuses = append(statuses, ScanStatus{Pool: currentPool})
+				statuses = append(statuses, ScanStatus{Pool: currentPool, Suspended: suspended})
 			}
 
 			currentPool = m[1]
 			scanSeen = false
+			suspended = false
 
 			continue
 		}
@@ -57,31 +155,56 @@ func parseScanStatuses(data []byte) []ScanStatus {
 			continue
 		}
 
+		// The state and status lines precede the scan line.
+		if !scanSeen && suspendedRe.MatchString(line) {
+			suspended = true
+
+			continue
+		}
+
 		// Check for active scan line.
 		if m := scanActiveRe.FindStringSubmatch(line); m != nil {
 			scanSeen = true
-			statuses = append(statuses, newActiveScan(currentPool, m[1]))
+			status := newActiveScan(currentPool, m[1])
+			status.Suspended = suspended
+			statuses = append(statuses, status)
 
 			continue
 		}
 
-		// Any other scan: line (none requested, completed, etc.) = no active scan.
+		// Any other scan: line (none requested, completed, paused, etc.) = no active scan.
 		if strings.Contains(line, "scan:") {
 			scanSeen = true
-			statuses = append(statuses, ScanStatus{Pool: currentPool})
+			statuses = append(statuses, ScanStatus{
+				Pool:      currentPool,
+				Suspended: suspended,
+				Paused:    scanPausedRe.MatchString(line),
+				Deferred:  deferredRe.MatchString(line),
+				LastScan:  parseCompletedScan(line),
+			})
 
 			continue
 		}
 
-		// Extract progress percentage from lines following an active scan.
+		// Deferred resilver queue state appears on later status/config lines.
+		if scanSeen && trackDeferred(statuses, currentPool, line) {
+			continue
+		}
+
+		// Extract progress percentage and byte counts from lines following an
+		// active scan.
 		tryParseProgress(&statuses, currentPool, line)
+		tryParseETA(statuses, currentPool, line)
+		tryParseScanBytes(statuses, currentPool, line)
 	}
 
 	// Close out last pool if scan was never seen.
 	if currentPool != "" && !scanSeen {
-		statuses = append(statuses, ScanStatus{Pool: currentPool})
+		statuses = append(statuses, ScanStatus{Pool: currentPool, Suspended: suspended})
 	}
 
+	setStatusMessages(statuses, parseStatusMessages(data))
+
 	return statuses
 }
 
@@ -99,6 +222,31 @@ func newActiveScan(pool, scanType string) ScanStatus {
 	return status
 }
 
+// trackDeferred records deferred-resilver markers on the current pool's
+// status. Returns true if the line carried such a marker.
+func trackDeferred(statuses []ScanStatus, currentPool, line string) bool {
+	if len(statuses) == 0 {
+		return false
+	}
+
+	last := &statuses[len(statuses)-1]
+	if last.Pool != currentPool {
+		return false
+	}
+
+	switch {
+	case strings.Contains(line, awaitingResilver):
+		last.AwaitingResilver++
+		last.Deferred = true
+	case deferredRe.MatchString(line):
+		last.Deferred = true
+	default:
+		return false
+	}
+
+	return true
+}
+
 // tryParseProgress extracts progress percentage from a line and updates the last status.
 func tryParseProgress(statuses *[]ScanStatus, currentPool, line string) {
 	if len(*statuses) == 0 {
@@ -117,3 +265,155 @@ func tryParseProgress(statuses *[]ScanStatus, currentPool, line string) {
 		}
 	}
 }
+
+// tryParseETA extracts the time left from a progress line and updates the
+// last status if it is an active scan of currentPool.
+func tryParseETA(statuses []ScanStatus, currentPool, line string) {
+	if len(statuses) == 0 {
+		return
+	}
+
+	last := &statuses[len(statuses)-1]
+	if last.Pool != currentPool || (!last.Scrub && !last.Resilver) || last.ETA != 0 {
+		return
+	}
+
+	if m := etaRe.FindStringSubmatch(line); m != nil {
+		last.ETA = parseScanDuration(m[1])
+	}
+}
+
+// tryParseScanBytes extracts scanned/issued/total bytes and the issue rate
+// from a progress line and updates the last status if it is an active scan
+// of currentPool.
+func tryParseScanBytes(statuses []ScanStatus, currentPool, line string) {
+	if len(statuses) == 0 {
+		return
+	}
+
+	last := &statuses[len(statuses)-1]
+	if last.Pool != currentPool || (!last.Scrub && !last.Resilver) || last.TotalBytes != 0 {
+		return
+	}
+
+	if m := scanBytesRe.FindStringSubmatch(line); m != nil {
+		last.ScannedBytes = parseHumanBytes(m[1])
+		last.TotalBytes = parseHumanBytes(m[2])
+		last.IssuedBytes = parseHumanBytes(m[4])
+		last.Rate = float64(parseHumanBytes(m[5]))
+
+		return
+	}
+
+	if m := scanBytesTotalRe.FindStringSubmatch(line); m != nil {
+		last.ScannedBytes = parseHumanBytes(m[1])
+		last.IssuedBytes = parseHumanBytes(m[3])
+		last.TotalBytes = parseHumanBytes(m[5])
+		last.Rate = float64(parseHumanBytes(m[4]))
+
+		return
+	}
+
+	if m := scanBytesLegacyRe.FindStringSubmatch(line); m != nil {
+		last.ScannedBytes = parseHumanBytes(m[1])
+		last.IssuedBytes = last.ScannedBytes
+		last.TotalBytes = parseHumanBytes(m[2])
+		last.Rate = float64(parseHumanBytes(m[3]))
+	}
+}
+
+// parseCompletedScan parses a completed scan line. Unrecognized lines (none
+// requested, canceled, paused) yield a zero CompletedScan.
+func parseCompletedScan(line string) CompletedScan {
+	m := scanDoneRe.FindStringSubmatch(line)
+	if m == nil {
+		return CompletedScan{}
+	}
+
+	end, err := time.ParseInLocation(scanTimeLayout, m[5], time.Local)
+	if err != nil {
+		return CompletedScan{}
+	}
+
+	function := "scrub"
+	if m[1] == "resilvered" {
+		function = "resilver"
+	}
+
+	return CompletedScan{
+		Function: function,
+		End:      end,
+		Duration: parseScanDuration(m[3]),
+		Repaired: parseHumanBytes(m[2]),
+		Errors:   parseCount(m[4]),
+	}
+}
+
+// parseScanDuration parses "01:23:45", "2 days 01:23:45", or the legacy
+// "1h23m". Unparsable input yields 0.
+func parseScanDuration(s string) time.Duration {
+	if m := legacyDurationRe.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mins, _ := strconv.Atoi(m[2])
+
+		return time.Duration(h)*time.Hour + time.Duration(mins)*time.Minute
+	}
+
+	var d time.Duration
+
+	fields := strings.Fields(s)
+	if len(fields) == 3 && strings.HasPrefix(fields[1], "day") {
+		days, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return 0
+		}
+
+		d = time.Duration(days) * 24 * time.Hour
+		fields = fields[2:]
+	}
+
+	if len(fields) != 1 {
+		return 0
+	}
+
+	parts := strings.Split(fields[0], ":")
+	if len(parts) != 3 {
+		return 0
+	}
+
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0
+		}
+
+		d += time.Duration(n) * unit
+	}
+
+	return d
+}
+
+// parseHumanBytes parses a zfs_nicebytes size such as "0B", "512", "12K", or
+// "1.50G" (binary multiples). Unparsable input yields 0.
+func parseHumanBytes(s string) uint64 {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "B")
+	if s == "" {
+		return 0
+	}
+
+	mult := 1.0
+	if i := strings.IndexByte("KMGTPE", s[len(s)-1]); i >= 0 {
+		for range i + 1 {
+			mult *= 1024
+		}
+
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+
+	return uint64(v * mult)
+}
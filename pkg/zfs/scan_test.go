@@ -3,12 +3,18 @@ package zfs
 import (
 	"math"
 	"testing"
+	"time"
 )
 
 func floatClose(a, b, epsilon float64) bool {
 	return math.Abs(a-b) < epsilon
 }
 
+func completedScanEqual(a, b CompletedScan) bool {
+	return a.Function == b.Function && a.End.Equal(b.End) && a.Duration == b.Duration &&
+		a.Repaired == b.Repaired && a.Errors == b.Errors
+}
+
 func TestParseScanStatuses(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -56,7 +62,11 @@ func TestParseScanStatuses(t *testing.T) {
   scan: scrub repaired 0B in 01:23:45 with 0 errors on Sun Feb  2 00:24:01 2025
 `,
 			want: []ScanStatus{
-				{Pool: "tank", Scrub: false, Resilver: false, Progress: 0},
+				{Pool: "tank", Scrub: false, Resilver: false, Progress: 0, LastScan: CompletedScan{
+					Function: "scrub",
+					End:      time.Date(2025, time.February, 2, 0, 24, 1, 0, time.Local),
+					Duration: time.Hour + 23*time.Minute + 45*time.Second,
+				}},
 			},
 		},
 		{
@@ -90,7 +100,97 @@ func TestParseScanStatuses(t *testing.T) {
 `,
 			want: []ScanStatus{
 				{Pool: "tank", Scrub: false, Resilver: true, Progress: 0.755},
-				{Pool: "backup", Scrub: false, Resilver: false, Progress: 0},
+				{Pool: "backup", Scrub: false, Resilver: false, Progress: 0, LastScan: CompletedScan{
+					Function: "scrub",
+					End:      time.Date(2025, time.February, 2, 0, 24, 1, 0, time.Local),
+					Duration: time.Hour + 23*time.Minute + 45*time.Second,
+				}},
+			},
+		},
+		{
+			name: "scrub paused",
+			input: `  pool: tank
+ state: ONLINE
+  scan: scrub paused since Sun Jul 25 16:07:49 2025
+	scrub started on Sun Jul 25 16:00:00 2025
+	374G scanned, 340G issued, 703G total
+	48.36% done
+`,
+			want: []ScanStatus{
+				{Pool: "tank", Paused: true},
+			},
+		},
+		{
+			name: "scrub paused waiting for resilver",
+			input: `  pool: tank
+ state: DEGRADED
+  scan: scrub paused 'waiting for resilver'
+`,
+			want: []ScanStatus{
+				{Pool: "tank", Paused: true, Deferred: true},
+			},
+		},
+		{
+			name: "resilver with deferred devices",
+			input: `  pool: tank
+ state: DEGRADED
+status: One or more devices is currently being resilvered.
+  scan: resilver in progress since Mon Feb  3 10:00:00 2025
+    1.23G scanned at 100M/s, 500M issued at 50M/s, 5.00G total
+    500M resilvered, 10.00% done, 0 days 01:30:00 to go
+config:
+
+	NAME             STATE     READ WRITE CKSUM
+	tank             DEGRADED     0     0     0
+	  raidz1-0       DEGRADED     0     0     0
+	    replacing-0  DEGRADED     0     0     0
+	      sda        OFFLINE      0     0     0
+	      sdd        ONLINE       0     0     0  (resilvering)
+	    replacing-1  DEGRADED     0     0     0
+	      sdb        OFFLINE      0     0     0
+	      sde        ONLINE       0     0     0  (awaiting resilver)
+	    replacing-2  DEGRADED     0     0     0
+	      sdc        OFFLINE      0     0     0
+	      sdf        ONLINE       0     0     0  (awaiting resilver)
+
+  pool: backup
+ state: ONLINE
+  scan: none requested
+`,
+			want: []ScanStatus{
+				{Pool: "tank", Resilver: true, Progress: 0.10, Deferred: true, AwaitingResilver: 2},
+				{Pool: "backup"},
+			},
+		},
+		{
+			name: "suspended pools",
+			input: `  pool: tank
+ state: SUSPENDED
+status: One or more devices are faulted in response to IO failures.
+action: Make sure the affected devices are connected, then run 'zpool clear'.
+  scan: scrub in progress since Sun Jul 25 16:07:49 2025
+    0B repaired, 10.00% done, 00:42:27 to go
+
+  pool: old
+ state: ONLINE
+status: One or more devices are faulted in response to IO failures.
+config:
+
+  pool: mmp
+ state: ONLINE
+status: The pool is suspended because multihost writes failed or were delayed;
+	another system could import the pool undetected.
+  scan: none requested
+
+  pool: usb
+ state: ONLINE
+  scan: none requested
+`,
+			want: []ScanStatus{
+				{Pool: "tank", Scrub: true, Progress: 0.10, Suspended: true},
+				{Pool: "old", Suspended: true},
+				{Pool: "mmp", Suspended: true},
+				{Pool: "usb"},
 			},
 		},
 		{
@@ -127,9 +227,176 @@ func TestParseScanStatuses(t *testing.T) {
 					t.Errorf("[%d].Resilver = %v, want %v", i, g.Resilver, w.Resilver)
 				}
 
+				if g.Suspended != w.Suspended {
+					t.Errorf("[%d].Suspended = %v, want %v", i, g.Suspended, w.Suspended)
+				}
+
 				if !floatClose(g.Progress, w.Progress, 0.001) {
 					t.Errorf("[%d].Progress = %f, want %f", i, g.Progress, w.Progress)
 				}
+
+				if g.Paused != w.Paused || g.Deferred != w.Deferred || g.AwaitingResilver != w.AwaitingResilver {
+					t.Errorf("[%d] paused/deferred/awaiting = %v/%v/%d, want %v/%v/%d", i,
+						g.Paused, g.Deferred, g.AwaitingResilver, w.Paused, w.Deferred, w.AwaitingResilver)
+				}
+
+				if !completedScanEqual(g.LastScan, w.LastScan) {
+					t.Errorf("[%d].LastScan = %+v, want %+v", i, g.LastScan, w.LastScan)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCompletedScan(t *testing.T) {
+	end := time.Date(2025, time.February, 2, 0, 24, 1, 0, time.Local)
+
+	tests := []struct {
+		name string
+		line string
+		want CompletedScan
+	}{
+		{
+			name: "scrub with repairs and errors",
+			line: "  scan: scrub repaired 1.50M in 01:23:45 with 3 errors on Sun Feb  2 00:24:01 2025",
+			want: CompletedScan{
+				Function: "scrub", End: end, Duration: time.Hour + 23*time.Minute + 45*time.Second,
+				Repaired: 1572864, Errors: 3,
+			},
+		},
+		{
+			name: "resilver with days",
+			line: "  scan: resilvered 12K in 2 days 00:10:00 with 0 errors on Sun Feb  2 00:24:01 2025",
+			want: CompletedScan{Function: "resilver", End: end, Duration: 48*time.Hour + 10*time.Minute, Repaired: 12288},
+		},
+		{
+			name: "legacy duration",
+			line: "  scan: scrub repaired 0 in 1h2m with 0 errors on Sun Feb  2 00:24:01 2025",
+			want: CompletedScan{Function: "scrub", End: end, Duration: time.Hour + 2*time.Minute},
+		},
+		{
+			name: "canceled",
+			line: "  scan: scrub canceled on Sun Feb  2 00:24:01 2025",
+		},
+		{
+			name: "none requested",
+			line: "  scan: none requested",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCompletedScan(tt.line); !completedScanEqual(got, tt.want) {
+				t.Errorf("parseCompletedScan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHumanBytes(t *testing.T) {
+	tests := map[string]uint64{
+		"0B":    0,
+		"512":   512,
+		"12K":   12288,
+		"1.50G": 1610612736,
+		"bogus": 0,
+		"":      0,
+	}
+
+	for in, want := range tests {
+		if got := parseHumanBytes(in); got != want {
+			t.Errorf("parseHumanBytes(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestParseScanStatuses_Bytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress string
+		scanned  uint64
+		issued   uint64
+		total    uint64
+		rate     float64
+	}{
+		{
+			name:     "openzfs 2.2",
+			progress: "\t1.00T / 2.00T scanned at 500M/s, 512G / 2.00T issued at 400M/s\n",
+			scanned:  1 << 40, issued: 512 << 30, total: 2 << 40, rate: 400 << 20,
+		},
+		{
+			name:     "openzfs 0.8",
+			progress: "    374G scanned at 161M/s, 340G issued at 146M/s, 703G total\n",
+			scanned:  374 << 30, issued: 340 << 30, total: 703 << 30, rate: 146 << 20,
+		},
+		{
+			name:     "issue rate not yet known",
+			progress: "\t1.00G / 2.00G scanned at 100M/s, 0B / 2.00G issued\n",
+			scanned:  1 << 30, total: 2 << 30,
+		},
+		{
+			name:     "legacy",
+			progress: "    374G scanned out of 703G at 161M/s, 0h42m to go\n",
+			scanned:  374 << 30, issued: 374 << 30, total: 703 << 30, rate: 161 << 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "  pool: tank\n state: ONLINE\n  scan: scrub in progress since Sun Jul 25 16:07:49 2025\n" + tt.progress
+
+			got := parseScanStatuses([]byte(input))
+			if len(got) != 1 {
+				t.Fatalf("got %d statuses, want 1", len(got))
+			}
+
+			g := got[0]
+			if g.ScannedBytes != tt.scanned || g.IssuedBytes != tt.issued || g.TotalBytes != tt.total || g.Rate != tt.rate {
+				t.Errorf("scanned/issued/total/rate = %d/%d/%d/%v, want %d/%d/%d/%v",
+					g.ScannedBytes, g.IssuedBytes, g.TotalBytes, g.Rate, tt.scanned, tt.issued, tt.total, tt.rate)
+			}
+		})
+	}
+}
+
+func TestParseScanStatuses_ETA(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines string
+		want  time.Duration
+	}{
+		{
+			name:  "hours",
+			lines: "\t1.00T / 2.00T scanned at 500M/s, 512G / 2.00T issued at 400M/s\n\t0B repaired, 25.00% done, 01:05:30 to go\n",
+			want:  time.Hour + 5*time.Minute + 30*time.Second,
+		},
+		{
+			name:  "days",
+			lines: "\t0B resilvered, 2.00% done, 2 days 03:00:00 to go\n",
+			want:  51 * time.Hour,
+		},
+		{
+			name:  "legacy",
+			lines: "    374G scanned out of 703G at 161M/s, 0h42m to go\n    0B repaired, 53.20% done\n",
+			want:  42 * time.Minute,
+		},
+		{
+			name:  "no estimate",
+			lines: "\t0B repaired, 0.01% done, no estimated completion time\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "  pool: tank\n state: ONLINE\n  scan: scrub in progress since Sun Jul 25 16:07:49 2025\n" + tt.lines
+
+			got := parseScanStatuses([]byte(input))
+			if len(got) != 1 {
+				t.Fatalf("got %d statuses, want 1", len(got))
+			}
+
+			if got[0].ETA != tt.want {
+				t.Errorf("ETA = %v, want %v", got[0].ETA, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,232 @@
+package zfs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnapshotCount is the number of snapshots taken of one dataset.
+type SnapshotCount struct {
+	Dataset string
+	Pool    string
+	Count   int
+
+	// Holds is the number of user holds (zfs hold) across the snapshots. A
+	// held snapshot cannot be destroyed.
+	Holds uint64
+
+	// Newest is when the most recent snapshot was taken, zero if unknown.
+	Newest time.Time
+}
+
+// GetSnapshotCounts counts the snapshots of every filesystem and volume, the
+// user holds on them, and when the newest was taken. Datasets without
+// snapshots are omitted. Only the name, userrefs, and creation columns are
+// requested, so zfs does not have to compute space accounting for each
+// snapshot, nor run zfs holds on each. The
+// snapshot_count property is not used: it is only maintained below a
+// snapshot_limit and includes descendants.
+//
+// With SetSnapshotPaging, the snapshots of each of the given pools are
+// listed by a separate zfs list -r, so no single command has to return every
+// snapshot on the host, and no new page is started once ctx is done. With
+// SetSnapshotLimit, counting stops after that many snapshots. The returned
+// bool reports whether either cut the counts short.
+func (c *Client) GetSnapshotCounts(ctx context.Context, pools []string) ([]SnapshotCount, bool, error) {
+	args := []string{"list", "-H", "-p", "-o", "name,userrefs,creation", "-t", "snapshot"}
+	sc := newSnapshotCounter(c.snapshotLimit)
+
+	if !c.snapshotPaging {
+		if err := c.countSnapshots(ctx, sc, args); err != nil {
+			return nil, false, fmt.Errorf("zfs list snapshots failed: %w", err)
+		}
+
+		return sc.result(), sc.truncated, nil
+	}
+
+	for i, pool := range pools {
+		if sc.truncated {
+			break
+		}
+
+		if ctx.Err() != nil {
+			// Keep the pools already counted rather than failing the scrape.
+			c.logger.Warn("Snapshot listing stopped by the scrape timeout", "pools_listed", i, "pools", len(pools))
+
+			return sc.result(), true, nil
+		}
+
+		if err := c.countSnapshots(ctx, sc, append(args, "-r", pool)); err != nil {
+			return nil, false, fmt.Errorf("zfs list snapshots of %s failed: %w", pool, err)
+		}
+	}
+
+	return sc.result(), sc.truncated, nil
+}
+
+// countSnapshots streams the output of one zfs list of snapshots into sc.
+// Reaching the limit stops the command early and is not an error.
+func (c *Client) countSnapshots(ctx context.Context, sc *snapshotCounter, args []string) error {
+	err := c.runStream(ctx, sc.add, c.zfsPath, args...)
+	if errors.Is(err, errSnapshotLimit) {
+		return nil
+	}
+
+	return err
+}
+
+// errSnapshotLimit stops a snapshot listing once the limit is reached.
+var errSnapshotLimit = errors.New("snapshot limit reached")
+
+// snapshotCounter accumulates per-dataset snapshot and hold counts across
+// one or more pages of zfs list output.
+type snapshotCounter struct {
+	counts    map[string]*SnapshotCount
+	total     int
+	limit     int // 0 means no limit
+	truncated bool
+}
+
+func newSnapshotCounter(limit int) *snapshotCounter {
+	return &snapshotCounter{counts: make(map[string]*SnapshotCount), limit: limit}
+}
+
+// add counts the snapshots in one page of output of:
+// zfs list -H -p -o name,userrefs,creation -t snapshot.
+// Each line is "dataset@snapshot<TAB>holds<TAB>unix-seconds"; lines without
+// an @ are ignored, a missing or invalid hold count counts as none, and a
+// missing or invalid creation time is skipped. Once the limit is
+// reached the counter is marked truncated and errSnapshotLimit is returned,
+// so the rest of the output is not read.
+func (sc *snapshotCounter) add(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, rest, _ := strings.Cut(strings.TrimSpace(scanner.Text()), "\t")
+		refs, created, _ := strings.Cut(rest, "\t")
+
+		dataset, _, ok := strings.Cut(name, "@")
+		if !ok || dataset == "" {
+			continue
+		}
+
+		if sc.limit > 0 && sc.total >= sc.limit {
+			sc.truncated = true
+			return errSnapshotLimit
+		}
+
+		sc.total++
+
+		count, ok := sc.counts[dataset]
+		if !ok {
+			count = &SnapshotCount{Dataset: dataset, Pool: extractPool(dataset)}
+			sc.counts[dataset] = count
+		}
+
+		count.Count++
+
+		if holds, err := strconv.ParseUint(refs, 10, 64); err == nil {
+			count.Holds += holds
+		}
+
+		if sec, err := strconv.ParseInt(created, 10, 64); err == nil {
+			if t := time.Unix(sec, 0); t.After(count.Newest) {
+				count.Newest = t
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read snapshot output: %w", err)
+	}
+
+	return nil
+}
+
+// result returns the counts sorted by dataset name.
+func (sc *snapshotCounter) result() []SnapshotCount {
+	snaps := make([]SnapshotCount, 0, len(sc.counts))
+
+	for _, name := range slices.Sorted(maps.Keys(sc.counts)) {
+		snaps = append(snaps, *sc.counts[name])
+	}
+
+	return snaps
+}
+
+// Snapshot is one snapshot and when it was taken.
+type Snapshot struct {
+	Dataset string
+	Pool    string
+	Name    string // the part after the @
+	Created time.Time
+}
+
+// SnapshotPolicy is a retention class of automatic snapshots, such as
+// sanoid's hourly snapshots: those whose name matches Pattern, of which a
+// new one is expected at least every MaxAge.
+type SnapshotPolicy struct {
+	Class   string
+	Pattern string // path.Match glob on the snapshot name, e.g. autosnap_*_hourly
+	MaxAge  time.Duration
+}
+
+// Match reports whether the snapshot name (without the dataset) belongs to
+// the policy's class.
+func (p *SnapshotPolicy) Match(name string) bool {
+	ok, err := path.Match(p.Pattern, name)
+	return err == nil && ok
+}
+
+// GetSnapshots lists every snapshot with its creation time. Unlike
+// GetSnapshotCounts this reads a property of each snapshot, but creation
+// needs no space accounting.
+func (c *Client) GetSnapshots(ctx context.Context) ([]Snapshot, error) {
+	out, err := c.runner(ctx, c.zfsPath, "list", "-H", "-p", "-o", "name,creation", "-t", "snapshot")
+	if err != nil {
+		return nil, fmt.Errorf("zfs list snapshots failed: %w", err)
+	}
+
+	return parseSnapshots(out), nil
+}
+
+// parseSnapshots parses the output of:
+// zfs list -H -p -o name,creation -t snapshot.
+// Lines that are not "dataset@snapshot<TAB>unix-seconds" are ignored.
+func parseSnapshots(data []byte) []Snapshot {
+	var snaps []Snapshot
+
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Split(strings.TrimRight(line, "\n"), "\t")
+		if len(fields) != 2 {
+			continue
+		}
+
+		dataset, name, ok := strings.Cut(fields[0], "@")
+		if !ok || dataset == "" {
+			continue
+		}
+
+		created, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		snaps = append(snaps, Snapshot{
+			Dataset: dataset,
+			Pool:    extractPool(dataset),
+			Name:    name,
+			Created: time.Unix(created, 0),
+		})
+	}
+
+	return snaps
+}
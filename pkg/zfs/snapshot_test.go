@@ -0,0 +1,213 @@
+package zfs
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotCounter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []SnapshotCount
+	}{
+		{
+			name: "several datasets",
+			input: "tank@daily-1\t0\n" +
+				"tank/media@daily-1\t0\n" +
+				"tank/media@daily-2\t0\n" +
+				"tank/media@syncoid_host_2025-01-01:00:00:00\t1\n" +
+				"tank/zvol0@before-upgrade\t2\n",
+			want: []SnapshotCount{
+				{Dataset: "tank", Pool: "tank", Count: 1},
+				{Dataset: "tank/media", Pool: "tank", Count: 3, Holds: 1},
+				{Dataset: "tank/zvol0", Pool: "tank", Count: 1, Holds: 2},
+			},
+		},
+		{
+			name:  "no snapshots",
+			input: "",
+			want:  []SnapshotCount{},
+		},
+		{
+			name:  "malformed lines ignored",
+			input: "tank\n@orphan\n\ntank@a\n",
+			want:  []SnapshotCount{{Dataset: "tank", Pool: "tank", Count: 1}},
+		},
+		{
+			name:  "newest snapshot",
+			input: "tank@b\t0\t1735693200\ntank@a\t0\t1735689600\ntank@c\t0\t-\n",
+			want:  []SnapshotCount{{Dataset: "tank", Pool: "tank", Count: 3, Newest: time.Unix(1735693200, 0)}},
+		},
+		{
+			name:  "invalid hold count",
+			input: "tank@a\t-\ntank@b\t3\n",
+			want:  []SnapshotCount{{Dataset: "tank", Pool: "tank", Count: 2, Holds: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newSnapshotCounter(0)
+			if err := sc.add(strings.NewReader(tt.input)); err != nil {
+				t.Fatalf("add() error = %v", err)
+			}
+
+			if got := sc.result(); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetSnapshotCounts(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("tank@a\ntank@b\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	counts, truncated, err := client.GetSnapshotCounts(context.Background(), []string{"tank"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(counts) != 1 || counts[0].Count != 2 || truncated {
+		t.Errorf("counts = %+v, truncated = %v, want tank with 2", counts, truncated)
+	}
+
+	want := "list -H -p -o name,userrefs,creation -t snapshot"
+	if got := strings.Join(gotArgs, " "); got != want {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestClient_GetSnapshotCounts_Paging(t *testing.T) {
+	pages := map[string]string{
+		"tank": "tank@a\t0\ntank/home@a\t1\ntank/home@b\t0\n",
+		"usb":  "usb@a\t0\n",
+	}
+
+	tests := []struct {
+		name          string
+		limit         int
+		canceled      bool
+		wantCalls     []string
+		wantCounts    map[string]int
+		wantTruncated bool
+	}{
+		{
+			name:       "all pools",
+			wantCalls:  []string{"tank", "usb"},
+			wantCounts: map[string]int{"tank": 1, "tank/home": 2, "usb": 1},
+		},
+		{
+			name:          "limit within first page",
+			limit:         2,
+			wantCalls:     []string{"tank"},
+			wantCounts:    map[string]int{"tank": 1, "tank/home": 1},
+			wantTruncated: true,
+		},
+		{
+			name:       "limit not reached",
+			limit:      4,
+			wantCalls:  []string{"tank", "usb"},
+			wantCounts: map[string]int{"tank": 1, "tank/home": 2, "usb": 1},
+		},
+		{
+			name:          "timed out",
+			canceled:      true,
+			wantCounts:    map[string]int{},
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+
+			runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+				pool := args[len(args)-1]
+				calls = append(calls, pool)
+
+				return []byte(pages[pool]), nil
+			}
+
+			client := NewClient(runner, testLogger(), "zpool", "zfs")
+			client.SetSnapshotPaging(true)
+			client.SetSnapshotLimit(tt.limit)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.canceled {
+				cancel()
+			}
+			defer cancel()
+
+			counts, truncated, err := client.GetSnapshotCounts(ctx, []string{"tank", "usb"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("listed pools %v, want %v", calls, tt.wantCalls)
+			}
+
+			got := make(map[string]int, len(counts))
+			for _, c := range counts {
+				got[c.Dataset] = c.Count
+			}
+
+			if !maps.Equal(got, tt.wantCounts) {
+				t.Errorf("counts = %v, want %v", got, tt.wantCounts)
+			}
+
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestParseSnapshots(t *testing.T) {
+	input := "tank/home@autosnap_2025-02-03_10:00:01_hourly\t1738576801\n" +
+		"tank/home@zfs-auto-snap_daily-2025-02-03-0000\t1738540800\n" +
+		"tank@manual\tnot-a-number\n" +
+		"no-at-sign\t1738540800\n" +
+		"\n"
+
+	want := []Snapshot{
+		{Dataset: "tank/home", Pool: "tank", Name: "autosnap_2025-02-03_10:00:01_hourly", Created: time.Unix(1738576801, 0)},
+		{Dataset: "tank/home", Pool: "tank", Name: "zfs-auto-snap_daily-2025-02-03-0000", Created: time.Unix(1738540800, 0)},
+	}
+
+	if got := parseSnapshots([]byte(input)); !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSnapshotPolicy_Match(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"autosnap_*_hourly", "autosnap_2025-02-03_10:00:01_hourly", true},
+		{"autosnap_*_hourly", "autosnap_2025-02-03_00:00:01_daily", false},
+		{"zfs-auto-snap_daily-*", "zfs-auto-snap_daily-2025-02-03-0000", true},
+		{"zfs-auto-snap_daily-*", "zfs-auto-snap_hourly-2025-02-03-1000", false},
+		{"[", "anything", false},
+	}
+
+	for _, tt := range tests {
+		p := SnapshotPolicy{Class: "c", Pattern: tt.pattern, MaxAge: time.Hour}
+		if got := p.Match(tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
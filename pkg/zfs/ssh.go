@@ -0,0 +1,75 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SSHTarget is a remote host whose zpool/zfs commands are run over ssh.
+type SSHTarget struct {
+	Host    string
+	User    string // empty uses the ssh default
+	Port    int    // 0 uses the ssh default
+	KeyFile string // empty uses the ssh default identities
+}
+
+// SSHRunner returns a Runner that executes each command on t through the
+// ssh client binary at sshPath, using r to run ssh itself. ssh runs in
+// batch mode so a missing key fails instead of prompting.
+//
+// INFO(security): unlike DefaultRunner, the remote side joins the command
+// into a string for the login shell. Every argument is therefore
+// single-quoted, so metacharacters in property names or paths stay literal
+// on the remote host as well.
+func SSHRunner(r Runner, sshPath string, t SSHTarget) Runner {
+	argv := sshArgv(t)
+
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return r(ctx, sshPath, argv(name, args)...)
+	}
+}
+
+// SSHStreamRunner is the StreamRunner counterpart of SSHRunner.
+func SSHStreamRunner(r StreamRunner, sshPath string, t SSHTarget) StreamRunner {
+	argv := sshArgv(t)
+
+	return func(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		return r(ctx, parse, sshPath, argv(name, args)...)
+	}
+}
+
+// sshArgv returns a function building the ssh arguments that run one
+// command on t.
+func sshArgv(t SSHTarget) func(name string, args []string) []string {
+	opts := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=5"}
+
+	if t.User != "" {
+		opts = append(opts, "-l", t.User)
+	}
+
+	if t.Port != 0 {
+		opts = append(opts, "-p", strconv.Itoa(t.Port))
+	}
+
+	if t.KeyFile != "" {
+		opts = append(opts, "-i", t.KeyFile, "-o", "IdentitiesOnly=yes")
+	}
+
+	return func(name string, args []string) []string {
+		words := make([]string, 0, len(args)+1)
+		words = append(words, shellQuote(name))
+
+		for _, a := range args {
+			words = append(words, shellQuote(a))
+		}
+
+		return append(append([]string{}, opts...), "--", t.Host, strings.Join(words, " "))
+	}
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,102 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSSHRunner(t *testing.T) {
+	var gotName string
+
+	var gotArgs []string
+
+	base := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		gotName, gotArgs = name, args
+		return []byte("ok"), nil
+	}
+
+	tests := []struct {
+		name   string
+		target SSHTarget
+		want   []string
+	}{
+		{
+			name:   "defaults",
+			target: SSHTarget{Host: "nas1"},
+			want: []string{
+				"-o", "BatchMode=yes", "-o", "ConnectTimeout=5",
+				"--", "nas1", "'zpool' 'list' '-Hp' 'it'\\''s; rm -rf /'",
+			},
+		},
+		{
+			name:   "user port key",
+			target: SSHTarget{Host: "10.0.0.5", User: "zfs", Port: 2222, KeyFile: "/etc/zfs_exporter/id_ed25519"},
+			want: []string{
+				"-o", "BatchMode=yes", "-o", "ConnectTimeout=5",
+				"-l", "zfs", "-p", "2222", "-i", "/etc/zfs_exporter/id_ed25519", "-o", "IdentitiesOnly=yes",
+				"--", "10.0.0.5", "'zpool' 'list' '-Hp' 'it'\\''s; rm -rf /'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := SSHRunner(base, "ssh", tt.target)
+
+			out, err := run(context.Background(), "zpool", "list", "-Hp", "it's; rm -rf /")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != "ok" {
+				t.Errorf("output = %q, want %q", out, "ok")
+			}
+
+			if gotName != "ssh" {
+				t.Errorf("name = %q, want ssh", gotName)
+			}
+
+			if !slices.Equal(gotArgs, tt.want) {
+				t.Errorf("args =\n%q\nwant\n%q", gotArgs, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSHStreamRunner(t *testing.T) {
+	var gotName string
+
+	var gotArgs []string
+
+	base := func(_ context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		gotName, gotArgs = name, args
+		return parse(strings.NewReader("ok"))
+	}
+
+	run := SSHStreamRunner(base, "ssh", SSHTarget{Host: "nas1", Port: 2222})
+
+	var out []byte
+
+	err := run(context.Background(), func(r io.Reader) error {
+		var err error
+		out, err = io.ReadAll(r)
+
+		return err
+	}, "zfs", "list", "-Hp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=5", "-p", "2222", "--", "nas1", "'zfs' 'list' '-Hp'"}
+
+	if gotName != "ssh" || !slices.Equal(gotArgs, want) {
+		t.Errorf("ran %s %q, want ssh %q", gotName, gotArgs, want)
+	}
+
+	if string(out) != "ok" {
+		t.Errorf("output = %q, want %q", out, "ok")
+	}
+}
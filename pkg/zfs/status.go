@@ -0,0 +1,174 @@
+package zfs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pool status codes, classifying the status: and action: paragraphs of
+// zpool status.
+const (
+	StatusOK                 = "ok"
+	StatusDeviceMissing      = "device_missing"
+	StatusDeviceOffline      = "device_offline"
+	StatusDeviceRemoved      = "device_removed"
+	StatusDeviceFault        = "device_fault"
+	StatusChecksumErrors     = "checksum_errors"
+	StatusDataCorruption     = "data_corruption"
+	StatusIOSuspended        = "io_suspended"
+	StatusResilverInProgress = "resilver_in_progress"
+	StatusUpgradeAvailable   = "upgrade_available"
+	StatusNonNativeAshift    = "non_native_ashift"
+	StatusFeatureUnsupported = "feature_unsupported"
+	StatusHostIDMismatch     = "hostid_mismatch"
+	StatusErrata             = "errata"
+	StatusOther              = "other"
+)
+
+// StatusCodes lists every code ScanStatus.StatusCode can take.
+var StatusCodes = []string{
+	StatusOK, StatusDeviceMissing, StatusDeviceOffline, StatusDeviceRemoved, StatusDeviceFault,
+	StatusChecksumErrors, StatusDataCorruption, StatusIOSuspended, StatusResilverInProgress,
+	StatusUpgradeAvailable, StatusNonNativeAshift, StatusFeatureUnsupported, StatusHostIDMismatch,
+	StatusErrata, StatusOther,
+}
+
+// statusPatterns map the messages zpool status prints (libzfs
+// zpool_get_status) to codes. The first match wins, so the label check
+// precedes the generic "could not be opened" one.
+var statusPatterns = []struct {
+	re   *regexp.Regexp
+	code string
+}{
+	{regexp.MustCompile(`in response to IO failures|is suspended`), StatusIOSuspended},
+	{regexp.MustCompile(`label is missing or invalid|are faulted|is faulted`), StatusDeviceFault},
+	{regexp.MustCompile(`could not be opened|top-level vdevs? (?:is|are) missing`), StatusDeviceMissing},
+	{regexp.MustCompile(`taken offline`), StatusDeviceOffline},
+	{regexp.MustCompile(`has been removed`), StatusDeviceRemoved},
+	{regexp.MustCompile(`unrecoverable error`), StatusChecksumErrors},
+	{regexp.MustCompile(`data corruption|metadata is corrupted`), StatusDataCorruption},
+	{regexp.MustCompile(`being resilvered|being rebuilt|resilver to complete`), StatusResilverInProgress},
+	{regexp.MustCompile(`features are not enabled|legacy on-disk format|older on-disk format|zpool upgrade`), StatusUpgradeAvailable},
+	{regexp.MustCompile(`non-native block size`), StatusNonNativeAshift},
+	{regexp.MustCompile(`not supported on this system|incompatible version`), StatusFeatureUnsupported},
+	{regexp.MustCompile(`hostid`), StatusHostIDMismatch},
+	{regexp.MustCompile(`[Ee]rrata`), StatusErrata},
+}
+
+// statusMessage holds the status: and action: paragraphs of one pool and
+// the data error count from its errors: section.
+type statusMessage struct {
+	status     string
+	action     string
+	dataErrors uint64
+}
+
+// classify returns the code for m, or StatusOK if the pool has no status
+// paragraph. The action paragraph is only consulted when the status one
+// matches no pattern, since actions mention other conditions in passing.
+func (m *statusMessage) classify() string {
+	if strings.TrimSpace(m.status) == "" {
+		return StatusOK
+	}
+
+	for _, text := range []string{m.status, m.action} {
+		for _, p := range statusPatterns {
+			if p.re.MatchString(text) {
+				return p.code
+			}
+		}
+	}
+
+	return StatusOther
+}
+
+// statusKeyRe matches the "key:" lines that start each section of a pool
+// in zpool status output.
+var statusKeyRe = regexp.MustCompile(`^\s*([a-z]+):\s*(.*)$`)
+
+// dataErrorsRe matches the errors: line of zpool status without -v, e.g.
+// "errors: 3 data errors, use '-v' for a list".
+var dataErrorsRe = regexp.MustCompile(`^(\d+) data errors?`)
+
+// parseStatusMessages returns each pool's status: and action: paragraphs
+// from zpool status output, each joined into one line, and its data error
+// count. Continuation lines are indented with a tab.
+func parseStatusMessages(data []byte) map[string]*statusMessage {
+	messages := make(map[string]*statusMessage)
+
+	var (
+		msg     *statusMessage
+		para    *string // the paragraph continuation lines extend
+		listing bool    // in the file list of zpool status -v
+	)
+
+	for line := range strings.SplitSeq(string(data), "\n") {
+		// The file list runs to the end of the pool's section.
+		if listing && !poolNameRe.MatchString(line) {
+			if strings.TrimSpace(line) != "" {
+				msg.dataErrors++
+			}
+
+			continue
+		}
+
+		listing = false
+
+		if strings.HasPrefix(line, "\t") {
+			if para != nil {
+				*para += " " + strings.TrimSpace(line)
+			}
+
+			continue
+		}
+
+		m := statusKeyRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		para = nil
+
+		switch {
+		case m[1] == "pool":
+			msg = &statusMessage{}
+			messages[m[2]] = msg
+		case msg == nil:
+		case m[1] == "status":
+			msg.status = m[2]
+			para = &msg.status
+		case m[1] == "action":
+			msg.action = m[2]
+			para = &msg.action
+		case m[1] == "errors":
+			msg.dataErrors, listing = parseDataErrors(m[2])
+		}
+	}
+
+	return messages
+}
+
+// parseDataErrors parses the text after "errors:". listing is set for
+// the "Permanent errors have been detected in the following files:" form
+// of zpool status -v, whose count is the number of files listed after it.
+func parseDataErrors(s string) (count uint64, listing bool) {
+	if m := dataErrorsRe.FindStringSubmatch(s); m != nil {
+		return parseCount(m[1]), false
+	}
+
+	return 0, strings.HasPrefix(s, "Permanent errors")
+}
+
+// setStatusMessages sets the status code and data error count of each of
+// statuses from messages.
+func setStatusMessages(statuses []ScanStatus, messages map[string]*statusMessage) {
+	for i := range statuses {
+		msg := messages[statuses[i].Pool]
+		if msg == nil {
+			msg = &statusMessage{}
+		}
+
+		statuses[i].StatusCode = msg.classify()
+		statuses[i].DataErrors = msg.dataErrors
+	}
+}
@@ -0,0 +1,165 @@
+package zfs
+
+import "testing"
+
+func TestParseScanStatuses_StatusCode(t *testing.T) {
+	input := `  pool: backup
+ state: ONLINE
+status: Some supported and requested features are not enabled on the pool.
+	The pool can still be used, but some features are unavailable.
+action: Enable all features using 'zpool upgrade'. Once this is done,
+	the pool may no longer be accessible by software that does not support
+	the features. See zpool-features(7) for details.
+  scan: scrub repaired 0B in 00:00:01 with 0 errors on Sun Feb  2 00:24:01 2025
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	backup      ONLINE       0     0     0
+	  sdd       ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: tank
+ state: ONLINE
+status: One or more devices has experienced an unrecoverable error.  An
+	attempt was made to correct the error.  Applications are unaffected.
+action: Determine if the device needs to be replaced, and clear the errors
+	using 'zpool clear' or replace the device with 'zpool replace'.
+   see: https://openzfs.github.io/openzfs-docs/msg/ZFS-8000-9P
+  scan: none requested
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+	  mirror-0  ONLINE       0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     ONLINE       0     0    12
+
+errors: No known data errors
+
+  pool: usb
+ state: DEGRADED
+status: One or more devices could not be used because the label is missing or
+	invalid.  Sufficient replicas exist for the pool to continue
+	functioning in a degraded state.
+action: Replace the device using 'zpool replace'.
+config:
+
+  pool: vault
+ state: ONLINE
+status: The pool has a hypothetical new condition.
+action: Wait for the resilver to complete.
+  scan: none requested
+
+  pool: weird
+ state: ONLINE
+status: Something this exporter has never seen.
+  scan: none requested
+
+  pool: zroot
+ state: ONLINE
+  scan: none requested
+`
+
+	want := map[string]string{
+		"backup": StatusUpgradeAvailable,
+		"tank":   StatusChecksumErrors,
+		"usb":    StatusDeviceFault,
+		"vault":  StatusResilverInProgress, // from the action
+		"weird":  StatusOther,
+		"zroot":  StatusOK,
+	}
+
+	got := parseScanStatuses([]byte(input))
+	if len(got) != len(want) {
+		t.Fatalf("got %d statuses, want %d", len(got), len(want))
+	}
+
+	for _, s := range got {
+		if s.StatusCode != want[s.Pool] {
+			t.Errorf("%s: StatusCode = %q, want %q", s.Pool, s.StatusCode, want[s.Pool])
+		}
+	}
+}
+
+func TestStatusMessage_Classify(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"", StatusOK},
+		{"One or more devices are faulted in response to IO failures.", StatusIOSuspended},
+		{"One or more devices are faulted in response to persistent errors.", StatusDeviceFault},
+		{"One or more devices could not be opened.  Sufficient replicas exist.", StatusDeviceMissing},
+		{"One or more devices has been taken offline by the administrator.", StatusDeviceOffline},
+		{"One or more devices has been removed by the administrator.", StatusDeviceRemoved},
+		{"One or more devices has experienced an error resulting in data corruption.", StatusDataCorruption},
+		{"One or more devices is currently being resilvered.", StatusResilverInProgress},
+		{"The pool is formatted using a legacy on-disk format.", StatusUpgradeAvailable},
+		{"One or more devices are configured to use a non-native block size.", StatusNonNativeAshift},
+		{"Mismatch between pool hostid and system hostid on imported pool.", StatusHostIDMismatch},
+		{"Errata #4 detected.", StatusErrata},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := (&statusMessage{status: tt.status}).classify(); got != tt.want {
+				t.Errorf("classify(%q) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScanStatuses_DataErrors(t *testing.T) {
+	input := `  pool: backup
+ state: ONLINE
+status: One or more devices has experienced an error resulting in data
+	corruption.  Applications may be affected.
+action: Restore the file in question if possible.  Otherwise restore the
+	entire pool from backup.
+  scan: scrub repaired 0B in 00:00:01 with 2 errors on Sun Feb  2 00:24:01 2025
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	backup      ONLINE       0     0     0
+	  sdd       ONLINE       0     0     4
+
+errors: Permanent errors have been detected in the following files:
+
+        /backup/photos/IMG_0001.jpg
+        backup/old:<0x1>
+
+  pool: tank
+ state: ONLINE
+status: One or more devices has experienced an error resulting in data
+	corruption.  Applications may be affected.
+  scan: none requested
+config:
+
+errors: 3 data errors, use '-v' for a list
+
+  pool: usb
+ state: ONLINE
+  scan: none requested
+config:
+
+errors: No known data errors
+`
+
+	want := map[string]uint64{"backup": 2, "tank": 3, "usb": 0}
+
+	got := parseScanStatuses([]byte(input))
+	if len(got) != len(want) {
+		t.Fatalf("got %d statuses, want %d", len(got), len(want))
+	}
+
+	for _, s := range got {
+		if s.DataErrors != want[s.Pool] {
+			t.Errorf("%s: DataErrors = %d, want %d", s.Pool, s.DataErrors, want[s.Pool])
+		}
+
+		if s.StatusCode != StatusDataCorruption && s.Pool != "usb" {
+			t.Errorf("%s: StatusCode = %q, want %q", s.Pool, s.StatusCode, StatusDataCorruption)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// StreamRunner executes a command and passes its stdout to parse while the
+// command runs, so output that can grow with the number of datasets or
+// snapshots is never held in memory as a whole. If parse returns an error
+// the command is killed and that error is returned; otherwise a failure of
+// the command itself is returned as for Runner.
+type StreamRunner func(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error
+
+// streamWaitDelay bounds how long Wait waits for stderr to close after the
+// command exits or is killed, in case a child it started still holds it.
+const streamWaitDelay = time.Second
+
+// DefaultStreamRunner returns a StreamRunner that uses exec.CommandContext,
+// with the same argv handling as DefaultRunner. Output that parse leaves
+// unread is discarded so the command can exit. Command failures are
+// returned as *CommandError.
+func DefaultStreamRunner() StreamRunner {
+	return func(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var stderr bytes.Buffer
+
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stderr = &stderr
+		cmd.WaitDelay = streamWaitDelay
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return commandError(ctx, name, args, nil, err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			return commandError(ctx, name, args, stderr.Bytes(), err)
+		}
+
+		parseErr := parse(stdout)
+		if parseErr != nil {
+			// Stop the command rather than wait for output nobody reads.
+			cancel()
+		} else if _, err := io.Copy(io.Discard, stdout); err != nil {
+			parseErr = err
+		}
+
+		// Wait closes stdout, so it must follow every read.
+		err = cmd.Wait()
+
+		switch {
+		case parseErr != nil:
+			return parseErr
+		case err != nil:
+			return commandError(ctx, name, args, stderr.Bytes(), err)
+		default:
+			return nil
+		}
+	}
+}
+
+// TimedStreamRunner wraps r so that observe is called with each command's
+// name, arguments, and wall-clock duration, including the time spent in
+// parse, whether or not it succeeded.
+func TimedStreamRunner(r StreamRunner, observe func(name string, args []string, d time.Duration)) StreamRunner {
+	return func(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		start := time.Now()
+		err := r(ctx, parse, name, args...)
+		observe(name, args, time.Since(start))
+
+		return err
+	}
+}
+
+// SetStreamRunner makes the Client stream the output of zpool list, zfs
+// list, and the snapshot listing through s instead of buffering it with the
+// Runner. Other commands still use the Runner.
+func (c *Client) SetStreamRunner(s StreamRunner) {
+	c.stream = s
+}
+
+// runStream runs a command through the StreamRunner if one is set, and
+// otherwise passes the Runner's buffered output to parse.
+func (c *Client) runStream(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error {
+	if c.stream != nil {
+		return c.stream(ctx, parse, name, args...)
+	}
+
+	out, err := c.runner(ctx, name, args...)
+	if err != nil {
+		return err
+	}
+
+	return parse(bytes.NewReader(out))
+}
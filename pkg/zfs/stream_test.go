@@ -0,0 +1,146 @@
+package zfs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultStreamRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	errStop := errors.New("stop")
+
+	countLines := func(n *int) func(io.Reader) error {
+		return func(r io.Reader) error {
+			sc := bufio.NewScanner(r)
+			for sc.Scan() {
+				*n++
+			}
+
+			return sc.Err()
+		}
+	}
+
+	tests := []struct {
+		name      string
+		script    string
+		parse     func(n *int) func(io.Reader) error
+		wantLines int
+		wantErr   string
+	}{
+		{
+			name:      "streams stdout",
+			script:    "seq 1 5000",
+			parse:     countLines,
+			wantLines: 5000,
+		},
+		{
+			name:   "unread output is discarded",
+			script: "seq 1 100000",
+			parse: func(*int) func(io.Reader) error {
+				return func(io.Reader) error { return nil }
+			},
+		},
+		{
+			name:   "parse error stops the command",
+			script: "echo a; sleep 10",
+			parse: func(*int) func(io.Reader) error {
+				return func(io.Reader) error { return errStop }
+			},
+			wantErr: "stop",
+		},
+		{
+			name:      "exit status with stderr",
+			script:    "echo partial; echo 'permission denied' >&2; exit 1",
+			parse:     countLines,
+			wantLines: 1,
+			wantErr:   `command "sh" exited 1: permission denied`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n int
+
+			start := time.Now()
+
+			err := DefaultStreamRunner()(context.Background(), tt.parse(&n), "sh", "-c", tt.script)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+
+			if n != tt.wantLines {
+				t.Errorf("parsed %d lines, want %d", n, tt.wantLines)
+			}
+
+			if d := time.Since(start); d > 5*time.Second {
+				t.Errorf("took %v, want the command stopped early", d)
+			}
+		})
+	}
+}
+
+func TestClient_StreamRunner(t *testing.T) {
+	var streamed []string
+
+	stream := func(_ context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		streamed = append(streamed, name+" "+strings.Join(args, " "))
+
+		switch name {
+		case "zpool":
+			return parse(strings.NewReader("tank\t10737418240\t5368709120\t5368709120\t33\t1.00\tONLINE\toff\n"))
+		default:
+			return parse(strings.NewReader("tank@a\t0\ntank@b\t1\n"))
+		}
+	}
+
+	runner := func(_ context.Context, name string, _ ...string) ([]byte, error) {
+		t.Errorf("%s run through the buffering runner", name)
+		return nil, nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+	client.DisableJSON()
+	client.SetStreamRunner(stream)
+
+	pools, err := client.GetPools(context.Background())
+	if err != nil || len(pools) != 1 || pools[0].Name != "tank" {
+		t.Errorf("GetPools() = %+v, %v, want tank", pools, err)
+	}
+
+	counts, _, err := client.GetSnapshotCounts(context.Background(), nil)
+	if err != nil || len(counts) != 1 || counts[0].Count != 2 || counts[0].Holds != 1 {
+		t.Errorf("GetSnapshotCounts() = %+v, %v, want tank with 2 snapshots and 1 hold", counts, err)
+	}
+
+	if len(streamed) != 2 {
+		t.Errorf("streamed commands = %q, want 2", streamed)
+	}
+}
+
+func TestClient_StreamParseError(t *testing.T) {
+	stream := func(_ context.Context, parse func(io.Reader) error, _ string, _ ...string) error {
+		return parse(strings.NewReader("tank\tnot-a-size\n"))
+	}
+
+	client := NewClient(nil, testLogger(), "zpool", "zfs")
+	client.DisableJSON()
+	client.SetStreamRunner(stream)
+
+	_, err := client.GetDatasets(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to parse dataset output") {
+		t.Errorf("error = %v, want a parse error", err)
+	}
+}
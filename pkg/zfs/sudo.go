@@ -0,0 +1,54 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"slices"
+)
+
+// SudoRunner returns a Runner that runs the commands named in names through
+// the privilege wrapper at wrapper (sudo or doas), using r to run it, so the
+// exporter itself can run unprivileged. Other commands run through r
+// unchanged. The wrapper gets -n so that a missing sudoers or doas.conf rule
+// fails the command instead of waiting for a password.
+//
+// INFO(security): the wrapper path is validated at startup like the zpool
+// and zfs paths, and it is exec'd directly: name and args stay separate
+// argv entries, so the no-shell guarantees of DefaultRunner still hold.
+func SudoRunner(r Runner, wrapper string, names ...string) Runner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if !slices.Contains(names, name) {
+			return r(ctx, name, args...)
+		}
+
+		return r(ctx, wrapper, sudoArgv(name, args)...)
+	}
+}
+
+// SudoStreamRunner is the StreamRunner counterpart of SudoRunner.
+func SudoStreamRunner(r StreamRunner, wrapper string, names ...string) StreamRunner {
+	return func(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		if !slices.Contains(names, name) {
+			return r(ctx, parse, name, args...)
+		}
+
+		return r(ctx, parse, wrapper, sudoArgv(name, args)...)
+	}
+}
+
+// SudoStreamer is the Streamer counterpart of SudoRunner.
+func SudoStreamer(s Streamer, wrapper string, names ...string) Streamer {
+	return func(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+		if !slices.Contains(names, name) {
+			return s(ctx, name, args...)
+		}
+
+		return s(ctx, wrapper, sudoArgv(name, args)...)
+	}
+}
+
+// sudoArgv returns the wrapper arguments that run name with args
+// non-interactively.
+func sudoArgv(name string, args []string) []string {
+	return append([]string{"-n", name}, args...)
+}
@@ -0,0 +1,82 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSudoRunner(t *testing.T) {
+	var got []string
+
+	base := func(_ context.Context, name string, args ...string) ([]byte, error) {
+		got = append([]string{name}, args...)
+		return []byte("ok"), nil
+	}
+
+	run := SudoRunner(base, "/usr/bin/doas", "/sbin/zpool", "zfs", "systemctl")
+
+	tests := []struct {
+		name string
+		cmd  []string
+		want []string
+	}{
+		{
+			name: "zpool",
+			cmd:  []string{"/sbin/zpool", "status", "-p"},
+			want: []string{"/usr/bin/doas", "-n", "/sbin/zpool", "status", "-p"},
+		},
+		{
+			name: "systemctl",
+			cmd:  []string{"systemctl", "is-active", "zfs-zed.service"},
+			want: []string{"/usr/bin/doas", "-n", "systemctl", "is-active", "zfs-zed.service"},
+		},
+		{
+			name: "other commands run directly",
+			cmd:  []string{"smartctl", "-j", "-a", "/dev/sda"},
+			want: []string{"smartctl", "-j", "-a", "/dev/sda"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := run(context.Background(), tt.cmd[0], tt.cmd[1:]...)
+			if err != nil || string(out) != "ok" {
+				t.Fatalf("run() = %q, %v", out, err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("argv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSudoStreamRunner(t *testing.T) {
+	var got []string
+
+	base := func(_ context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		got = append([]string{name}, args...)
+		return parse(strings.NewReader("ok"))
+	}
+
+	run := SudoStreamRunner(base, "sudo", "zfs")
+
+	var out string
+
+	err := run(context.Background(), func(r io.Reader) error {
+		b, err := io.ReadAll(r)
+		out = string(b)
+
+		return err
+	}, "zfs", "list", "-Hp")
+	if err != nil || out != "ok" {
+		t.Fatalf("run() output %q, error %v", out, err)
+	}
+
+	if want := []string{"sudo", "-n", "zfs", "list", "-Hp"}; !slices.Equal(got, want) {
+		t.Errorf("argv = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// CommandTimeouts are per-class command deadlines. A class with a non-zero
+// timeout runs on its own deadline instead of the caller's, so one slow
+// class (typically zfs list on a host with many datasets) cannot use up the
+// time the others share. Zero leaves the class on the caller's deadline.
+type CommandTimeouts struct {
+	// Zpool covers every zpool command.
+	Zpool time.Duration
+
+	// ZfsList covers zfs list, including snapshot listings.
+	ZfsList time.Duration
+
+	// Systemctl covers the service and timer checks.
+	Systemctl time.Duration
+}
+
+// timeout returns the deadline for the command name args, with zpoolPath
+// and zfsPath identifying the ZFS binaries, or 0 for the caller's.
+func (t CommandTimeouts) timeout(zpoolPath, zfsPath, name string, args []string) time.Duration {
+	switch {
+	case name == zpoolPath:
+		return t.Zpool
+	case name == zfsPath && len(args) > 0 && args[0] == "list":
+		return t.ZfsList
+	case name == "systemctl":
+		return t.Systemctl
+	default:
+		return 0
+	}
+}
+
+// DeadlineContext returns ctx itself when d is zero, and otherwise a context
+// that keeps ctx's values but expires after d regardless of ctx's deadline
+// or cancellation. Work run on it can therefore outlive ctx by up to d.
+func DeadlineContext(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(context.WithoutCancel(ctx), d)
+}
+
+// TimeoutRunner wraps r so that each command runs with the deadline t sets
+// for its class. zpoolPath and zfsPath are the binary names the commands
+// are run with.
+func TimeoutRunner(r Runner, t CommandTimeouts, zpoolPath, zfsPath string) Runner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		ctx, cancel := DeadlineContext(ctx, t.timeout(zpoolPath, zfsPath, name, args))
+		defer cancel()
+
+		return r(ctx, name, args...)
+	}
+}
+
+// TimeoutStreamRunner is the StreamRunner counterpart of TimeoutRunner.
+func TimeoutStreamRunner(r StreamRunner, t CommandTimeouts, zpoolPath, zfsPath string) StreamRunner {
+	return func(ctx context.Context, parse func(io.Reader) error, name string, args ...string) error {
+		ctx, cancel := DeadlineContext(ctx, t.timeout(zpoolPath, zfsPath, name, args))
+		defer cancel()
+
+		return r(ctx, parse, name, args...)
+	}
+}
@@ -0,0 +1,69 @@
+package zfs
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutRunner(t *testing.T) {
+	timeouts := CommandTimeouts{Zpool: time.Minute, ZfsList: time.Hour}
+
+	tests := []struct {
+		name string
+		cmd  []string
+		want time.Duration // 0 expects the caller's deadline
+	}{
+		{name: "zpool", cmd: []string{"/sbin/zpool", "status", "-p"}, want: time.Minute},
+		{name: "zfs list", cmd: []string{"/sbin/zfs", "list", "-Hp"}, want: time.Hour},
+		{name: "zfs get", cmd: []string{"/sbin/zfs", "get", "-Hp", "all"}},
+		{name: "systemctl unset", cmd: []string{"systemctl", "is-active", "zfs-zed.service"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var remaining time.Duration
+
+			base := func(ctx context.Context, _ string, _ ...string) ([]byte, error) {
+				deadline, _ := ctx.Deadline()
+				remaining = time.Until(deadline)
+
+				return nil, ctx.Err()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if _, err := TimeoutRunner(base, timeouts, "/sbin/zpool", "/sbin/zfs")(ctx, tt.cmd[0], tt.cmd[1:]...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := cmp.Or(tt.want, time.Second)
+			if remaining > want || remaining < want-10*time.Second {
+				t.Errorf("command deadline in %v, want about %v", remaining, want)
+			}
+		})
+	}
+}
+
+func TestTimeoutStreamRunner(t *testing.T) {
+	base := func(ctx context.Context, parse func(io.Reader) error, _ string, _ ...string) error {
+		<-ctx.Done()
+		if err := parse(strings.NewReader("")); err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	}
+
+	run := TimeoutStreamRunner(base, CommandTimeouts{ZfsList: 10 * time.Millisecond}, "zpool", "zfs")
+
+	// The class deadline applies even though the caller's never expires.
+	err := run(context.Background(), func(io.Reader) error { return nil }, "zfs", "list", "-t", "snapshot")
+	if !isContextErr(err) {
+		t.Errorf("error = %v, want the zfs list deadline", err)
+	}
+}
@@ -0,0 +1,110 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSpaceUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		group   bool
+		want    []SpaceUsage
+		wantErr bool
+	}{
+		{
+			name:  "users with and without quota",
+			input: "root\t1536\tnone\nalice\t1073741824\t2147483648\n1001\t512\t-\n",
+			want: []SpaceUsage{
+				{Dataset: "tank/home", Pool: "tank", Name: "root", Used: 1536},
+				{Dataset: "tank/home", Pool: "tank", Name: "alice", Used: 1073741824, Quota: 2147483648},
+				{Dataset: "tank/home", Pool: "tank", Name: "1001", Used: 512},
+			},
+		},
+		{
+			name:  "groups",
+			input: "staff\t4096\t10737418240\n",
+			group: true,
+			want:  []SpaceUsage{{Dataset: "tank/home", Pool: "tank", Group: true, Name: "staff", Used: 4096, Quota: 10737418240}},
+		},
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:    "wrong field count",
+			input:   "alice\t1024\n",
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric used",
+			input:   "alice\t1K\tnone\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSpaceUsage([]byte(tt.input), "tank/home", tt.group)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetSpaceUsage(t *testing.T) {
+	var calls []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(args, " "))
+
+		if args[0] == "groupspace" {
+			return []byte("staff\t2048\tnone\n"), nil
+		}
+
+		return []byte("alice\t1024\t4096\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	usage, err := client.GetSpaceUsage(context.Background(), []string{"tank/home", "tank/projects"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(usage) != 4 || usage[1].Name != "staff" || !usage[1].Group || usage[2].Dataset != "tank/projects" {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	want := []string{
+		"userspace -Hp -o name,used,quota tank/home",
+		"groupspace -Hp -o name,used,quota tank/home",
+		"userspace -Hp -o name,used,quota tank/projects",
+		"groupspace -Hp -o name,used,quota tank/projects",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestClient_GetSpaceUsage_Error(t *testing.T) {
+	runner := func(context.Context, string, ...string) ([]byte, error) {
+		return nil, errors.New("cannot open 'tank/nope': dataset does not exist")
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetSpaceUsage(context.Background(), []string{"tank/nope"}); err == nil ||
+		!strings.Contains(err.Error(), "zfs userspace tank/nope failed") {
+		t.Errorf("err = %v", err)
+	}
+}
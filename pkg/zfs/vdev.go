@@ -0,0 +1,294 @@
+package zfs
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Allocation classes of the zpool status config tree, named as zpool status
+// prints their headers. Devices in the main tree have an empty class.
+const (
+	VdevClassLog   = "logs"
+	VdevClassCache = "cache"
+	VdevClassSpare = "spares"
+)
+
+// VdevStatus represents one leaf device from the config section of
+// zpool status.
+type VdevStatus struct {
+	Pool   string
+	Vdev   string // top-level vdev (e.g. "mirror-0"), or the device itself for single-disk vdevs
+	Device string // leaf device name as printed by zpool status
+	Parent string // interim vdev directly below Vdev holding the device (e.g. "replacing-1", "spare-0"), "" if none
+	State  string // ONLINE, DEGRADED, FAULTED, OFFLINE, UNAVAIL, REMOVED; spares are AVAIL or INUSE
+	Class  string // "" for the main tree, otherwise VdevClassLog, VdevClassCache, VdevClassSpare, "special", or "dedup"
+
+	ReadErrors     uint64
+	WriteErrors    uint64
+	ChecksumErrors uint64
+}
+
+// vdevLine is one row of the config tree, before leaves are identified.
+type vdevLine struct {
+	indent int
+	fields []string
+}
+
+// parseVdevStatuses parses the output of: zpool status -p
+// It walks the config tree of each pool and returns one entry per leaf device
+// with its state and READ/WRITE/CKSUM counters. Spares carry no counters and
+// are returned with their state only. The pool root row is skipped.
+func parseVdevStatuses(data []byte) []VdevStatus {
+	var (
+		statuses    []VdevStatus
+		currentPool string
+		inConfig    bool
+		rows        []vdevLine
+	)
+
+	flush := func() {
+		statuses = append(statuses, vdevLeaves(currentPool, rows)...)
+		rows = nil
+	}
+
+	for line := range strings.SplitSeq(string(data), "\n") {
+		if m := poolNameRe.FindStringSubmatch(line); m != nil {
+			flush()
+
+			currentPool = m[1]
+			inConfig = false
+
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case currentPool == "":
+			continue
+		case strings.HasPrefix(trimmed, "config:"):
+			inConfig = true
+			continue
+		case !inConfig:
+			continue
+		case strings.HasPrefix(trimmed, "errors:"):
+			inConfig = false
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "NAME"):
+			continue
+		}
+
+		rows = append(rows, vdevLine{
+			indent: len(line) - len(strings.TrimLeft(line, " \t")),
+			fields: strings.Fields(trimmed),
+		})
+	}
+
+	flush()
+
+	return statuses
+}
+
+// vdevLeaves turns the config rows of one pool into leaf VdevStatus entries.
+// The shallowest rows are the pool root and allocation class headers (logs,
+// cache, special, ...); rows directly beneath them are top-level vdevs. A row
+// is a leaf when the next row is not indented deeper. Any other row directly
+// below a top-level vdev is an interim vdev, such as a disk being replaced
+// or spared, and becomes the Parent of the leaves beneath it.
+func vdevLeaves(pool string, rows []vdevLine) []VdevStatus {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	rootIndent := rows[0].indent
+	for _, r := range rows {
+		rootIndent = min(rootIndent, r.indent)
+	}
+
+	var (
+		leaves       []VdevStatus
+		topVdev      string
+		class        string
+		parent       string
+		parentIndent int
+	)
+
+	for i, r := range rows {
+		if r.indent == rootIndent {
+			// The first root row is the pool itself; later ones are class
+			// headers.
+			if i > 0 {
+				class = r.fields[0]
+			}
+
+			continue
+		}
+
+		if topVdev == "" || r.indent <= rootIndent+2 {
+			topVdev = r.fields[0]
+		}
+
+		if parent != "" && r.indent <= parentIndent {
+			parent = ""
+		}
+
+		if i+1 < len(rows) && rows[i+1].indent > r.indent {
+			if parent == "" && r.fields[0] != topVdev {
+				parent, parentIndent = r.fields[0], r.indent
+			}
+
+			continue
+		}
+
+		// Spares are NAME STATE [notes...].
+		if class == VdevClassSpare && len(r.fields) >= 2 {
+			leaves = append(leaves, VdevStatus{Pool: pool, Vdev: topVdev, Device: r.fields[0], State: r.fields[1], Class: class})
+			continue
+		}
+
+		// NAME STATE READ WRITE CKSUM [notes...].
+		if len(r.fields) < 5 {
+			continue
+		}
+
+		leaves = append(leaves, VdevStatus{
+			Pool:           pool,
+			Vdev:           topVdev,
+			Device:         r.fields[0],
+			Parent:         parent,
+			State:          r.fields[1],
+			Class:          class,
+			ReadErrors:     parseCount(r.fields[2]),
+			WriteErrors:    parseCount(r.fields[3]),
+			ChecksumErrors: parseCount(r.fields[4]),
+		})
+	}
+
+	return leaves
+}
+
+// VdevCapacity is the space usage of one top-level vdev of the main tree or
+// of the special or dedup allocation class, from zpool list -v.
+type VdevCapacity struct {
+	Pool  string
+	Vdev  string // top-level vdev (e.g. "mirror-0"), or the device itself for single-disk vdevs
+	Class string // "" for the main tree, otherwise "special" or "dedup"
+
+	Size          uint64
+	Allocated     uint64
+	Fragmentation float64 // 0-1 ratio, NaN if unavailable
+}
+
+// capacityClasses are the allocation classes whose vdevs hold pool data
+// and so take part in balancing. Log, cache, and spare devices do not.
+var capacityClasses = []string{"", "special", "dedup"}
+
+// parseVdevCapacities parses the output of: zpool list -v -p
+//
+//	NAME         SIZE         ALLOC        FREE  CKPOINT  EXPANDSZ   FRAG    CAP  DEDUP    HEALTH  ALTROOT
+//	tank         1992864825344  ...
+//	  mirror-0   996432412672  ...
+//	    sda      1000204886016      -          -        -         -      -      -      -    ONLINE
+//	special         -            -            -        -         -      -      -      -         -
+//	  mirror-1   ...
+//
+// Pools start at the left margin, followed by their top-level vdevs indented
+// by two spaces and the leaves beneath those. Allocation class headers
+// (special, logs, cache, ...) also start at the left margin but have no
+// size. Columns are located by the header line, since vdev rows ignore -o.
+func parseVdevCapacities(data []byte) ([]VdevCapacity, error) {
+	var (
+		caps  []VdevCapacity
+		cols  map[string]int
+		pool  string
+		class string
+	)
+
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "NAME" {
+			cols = make(map[string]int, len(fields))
+			for i, f := range fields {
+				cols[f] = i
+			}
+
+			continue
+		}
+
+		if cols == nil {
+			return nil, fmt.Errorf("zpool list -v output has no header line: %q", strings.TrimSpace(line))
+		}
+
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(fields) {
+				return fields[i]
+			}
+
+			return "-"
+		}
+
+		switch indent := len(line) - len(strings.TrimLeft(line, " ")); {
+		case indent == 0 && field("SIZE") == "-":
+			class = fields[0]
+		case indent == 0:
+			pool, class = fields[0], ""
+		case indent == 2 && slices.Contains(capacityClasses, class):
+			c, err := parseVdevCapacity(field("SIZE"), field("ALLOC"), field("FRAG"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse vdev %q of pool %q: %w", fields[0], pool, err)
+			}
+
+			c.Pool, c.Vdev, c.Class = pool, fields[0], class
+			caps = append(caps, c)
+		}
+	}
+
+	return caps, nil
+}
+
+// parseVdevCapacity parses the SIZE, ALLOC, and FRAG columns of a top-level
+// vdev row. "-" reads as 0, or NaN for the fragmentation.
+func parseVdevCapacity(size, alloc, frag string) (VdevCapacity, error) {
+	c := VdevCapacity{Fragmentation: math.NaN()}
+
+	var err error
+
+	if size != "-" {
+		if c.Size, err = strconv.ParseUint(size, 10, 64); err != nil {
+			return VdevCapacity{}, fmt.Errorf("invalid size %q: %w", size, err)
+		}
+	}
+
+	if alloc != "-" {
+		if c.Allocated, err = strconv.ParseUint(alloc, 10, 64); err != nil {
+			return VdevCapacity{}, fmt.Errorf("invalid allocated %q: %w", alloc, err)
+		}
+	}
+
+	if frag != "-" {
+		if c.Fragmentation, err = strconv.ParseFloat(strings.TrimSuffix(frag, "%"), 64); err != nil {
+			return VdevCapacity{}, fmt.Errorf("invalid fragmentation %q: %w", frag, err)
+		}
+
+		c.Fragmentation /= 100
+	}
+
+	return c, nil
+}
+
+// parseCount parses an exact (-p) counter. Unparsable values count as zero.
+func parseCount(s string) uint64 {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
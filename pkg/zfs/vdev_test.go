@@ -0,0 +1,279 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseVdevStatuses(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []VdevStatus
+	}{
+		{
+			name:  "empty output",
+			input: "",
+			want:  nil,
+		},
+		{
+			name: "mirror with checksum errors",
+			input: `  pool: tank
+ state: ONLINE
+status: One or more devices has experienced an unrecoverable error.
+  scan: scrub repaired 0B in 01:23:45 with 0 errors on Sun Feb  2 00:24:01 2025
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+	  mirror-0  ONLINE       0     0     0
+	    sda     ONLINE       0     0     0
+	    sdb     ONLINE       2     0  1543
+
+errors: No known data errors
+`,
+			want: []VdevStatus{
+				{Pool: "tank", Vdev: "mirror-0", Device: "sda", State: "ONLINE"},
+				{Pool: "tank", Vdev: "mirror-0", Device: "sdb", State: "ONLINE", ReadErrors: 2, ChecksumErrors: 1543},
+			},
+		},
+		{
+			name: "single disk vdevs, log, cache, and spares",
+			input: `  pool: tank
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+	  sda       ONLINE       0     7     0
+	  raidz1-1  ONLINE       0     0     0
+	    sdb     ONLINE       0     0     0
+	    sdc     ONLINE       0     0     0
+	logs
+	  nvme0n1   ONLINE       0     0     0
+	cache
+	  nvme1n1   ONLINE       0     0     3
+	spares
+	  sdd       AVAIL
+
+errors: No known data errors
+`,
+			want: []VdevStatus{
+				{Pool: "tank", Vdev: "sda", Device: "sda", State: "ONLINE", WriteErrors: 7},
+				{Pool: "tank", Vdev: "raidz1-1", Device: "sdb", State: "ONLINE"},
+				{Pool: "tank", Vdev: "raidz1-1", Device: "sdc", State: "ONLINE"},
+				{Pool: "tank", Vdev: "nvme0n1", Device: "nvme0n1", State: "ONLINE", Class: VdevClassLog},
+				{Pool: "tank", Vdev: "nvme1n1", Device: "nvme1n1", State: "ONLINE", Class: VdevClassCache, ChecksumErrors: 3},
+				{Pool: "tank", Vdev: "sdd", Device: "sdd", State: "AVAIL", Class: VdevClassSpare},
+			},
+		},
+		{
+			name: "spare in use",
+			input: `  pool: tank
+ state: DEGRADED
+config:
+
+	NAME          STATE     READ WRITE CKSUM
+	tank          DEGRADED     0     0     0
+	  mirror-0    DEGRADED     0     0     0
+	    sda       ONLINE       0     0     0
+	    spare-1   DEGRADED     0     0     0
+	      sdb     FAULTED      0    12     0  too many errors
+	      sdd     ONLINE       0     0     0
+	spares
+	  sdd         INUSE     currently in use
+	  sde         AVAIL
+
+errors: No known data errors
+`,
+			want: []VdevStatus{
+				{Pool: "tank", Vdev: "mirror-0", Device: "sda", State: "ONLINE"},
+				{Pool: "tank", Vdev: "mirror-0", Device: "sdb", Parent: "spare-1", State: "FAULTED", WriteErrors: 12},
+				{Pool: "tank", Vdev: "mirror-0", Device: "sdd", Parent: "spare-1", State: "ONLINE"},
+				{Pool: "tank", Vdev: "sdd", Device: "sdd", State: "INUSE", Class: VdevClassSpare},
+				{Pool: "tank", Vdev: "sde", Device: "sde", State: "AVAIL", Class: VdevClassSpare},
+			},
+		},
+		{
+			name: "nested replacing vdev and notes",
+			input: `  pool: tank
+ state: DEGRADED
+  scan: resilver in progress since Mon Feb  3 10:00:00 2025
+config:
+
+	NAME                STATE     READ WRITE CKSUM
+	tank                DEGRADED     0     0     0
+	  mirror-0          DEGRADED     0     0     0
+	    sda             ONLINE       0     0     0
+	    replacing-1     DEGRADED     0     0     0
+	      1234567890    UNAVAIL      0     0     0  was /dev/sdb1
+	      sdc           ONLINE       0     0     0  (resilvering)
+	    sdd             ONLINE       0     0     0
+
+errors: No known data errors
+`,
+			want: []VdevStatus{
+				{Pool: "tank", Vdev: "mirror-0", Device: "sda", State: "ONLINE"},
+				{Pool: "tank", Vdev: "mirror-0", Device: "1234567890", Parent: "replacing-1", State: "UNAVAIL"},
+				{Pool: "tank", Vdev: "mirror-0", Device: "sdc", Parent: "replacing-1", State: "ONLINE"},
+				{Pool: "tank", Vdev: "mirror-0", Device: "sdd", State: "ONLINE"},
+			},
+		},
+		{
+			name: "multiple pools",
+			input: `  pool: backup
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	backup      ONLINE       0     0     0
+	  sdx       ONLINE       0     0     0
+
+errors: No known data errors
+
+  pool: tank
+ state: ONLINE
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	tank        ONLINE       0     0     0
+	  mirror-0  ONLINE       0     0     0
+	    sda     ONLINE       1     0     0
+	    sdb     ONLINE       0     0     0
+
+errors: No known data errors
+`,
+			want: []VdevStatus{
+				{Pool: "backup", Vdev: "sdx", Device: "sdx", State: "ONLINE"},
+				{Pool: "tank", Vdev: "mirror-0", Device: "sda", State: "ONLINE", ReadErrors: 1},
+				{Pool: "tank", Vdev: "mirror-0", Device: "sdb", State: "ONLINE"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseVdevStatuses([]byte(tt.input))
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetVdevStatuses_Args(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return nil, nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetVdevStatuses(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(gotArgs, []string{"status", "-p"}) {
+		t.Errorf("args = %v, want [status -p]", gotArgs)
+	}
+}
+
+func TestClient_GetVdevStatuses_CommandError(t *testing.T) {
+	runner := func(_ context.Context, _ string, _ ...string) ([]byte, error) {
+		return nil, errors.New("command failed")
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetVdevStatuses(context.Background(), nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestParseVdevCapacities(t *testing.T) {
+	input := `NAME          SIZE           ALLOC          FREE           CKPOINT  EXPANDSZ  FRAG  CAP  DEDUP  HEALTH  ALTROOT
+tank          5991257358336  2396502943334  3594754414998  -        -         12    40   1.00   ONLINE  -
+  mirror-0    1992864825344  1793578342809  199286482535   -        -         31    90   -      ONLINE
+    sda       2000398934016  -              -              -        -         -     -    -      ONLINE
+    sdb       2000398934016  -              -              -        -         -     -    -      ONLINE
+  mirror-1    3998392533000  602924600525   3395467932475  -        -         2     15   -      ONLINE
+    sdc       4000787030016  -              -              -        -         -     -    -      ONLINE
+    sdd       4000787030016  -              -              -        -         -     -    -      ONLINE
+special       -              -              -              -        -         -     -    -      -
+  nvme0n1     498216206336   49821620633    448394585703   -        -         7     10   -      ONLINE
+logs          -              -              -              -        -         -     -    -      -
+  nvme1n1     16106127360    1048576        16105078784    -        -         0     0    -      ONLINE
+cache         -              -              -              -        -         -     -    -      -
+  nvme2n1     256060514304   128030257152   128030257152   -        -         0     50   -      ONLINE
+usb           1000204886016  0              1000204886016  -        -         -     0    1.00   ONLINE  -
+  sde         1000204886016  0              1000204886016  -        -         -     0    -      ONLINE
+`
+
+	got, err := parseVdevCapacities([]byte(input))
+	if err != nil {
+		t.Fatalf("parseVdevCapacities() error = %v", err)
+	}
+
+	want := []VdevCapacity{
+		{Pool: "tank", Vdev: "mirror-0", Size: 1992864825344, Allocated: 1793578342809, Fragmentation: 0.31},
+		{Pool: "tank", Vdev: "mirror-1", Size: 3998392533000, Allocated: 602924600525, Fragmentation: 0.02},
+		{Pool: "tank", Vdev: "nvme0n1", Class: "special", Size: 498216206336, Allocated: 49821620633, Fragmentation: 0.07},
+		{Pool: "usb", Vdev: "sde", Size: 1000204886016, Fragmentation: math.NaN()},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d vdevs, want %d: %+v", len(got), len(want), got)
+	}
+
+	for i, w := range want {
+		g := got[i]
+		if g.Pool != w.Pool || g.Vdev != w.Vdev || g.Class != w.Class || g.Size != w.Size || g.Allocated != w.Allocated {
+			t.Errorf("vdev %d = %+v, want %+v", i, g, w)
+		}
+
+		if math.IsNaN(w.Fragmentation) != math.IsNaN(g.Fragmentation) || math.Abs(g.Fragmentation-w.Fragmentation) > 1e-9 {
+			t.Errorf("%s: Fragmentation = %v, want %v", w.Vdev, g.Fragmentation, w.Fragmentation)
+		}
+	}
+}
+
+func TestParseVdevCapacities_Errors(t *testing.T) {
+	tests := map[string]string{
+		"no header":    "tank  100  50  50  -  -  1  50  1.00  ONLINE  -\n",
+		"invalid size": "NAME  SIZE  ALLOC  FRAG\ntank  100  50  1\n  sda  big  50  1\n",
+		"invalid frag": "NAME  SIZE  ALLOC  FRAG\ntank  100  50  1\n  sda  100  50  x\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseVdevCapacities([]byte(input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestClient_GetVdevCapacities_Args(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return nil, nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+
+	if _, err := client.GetVdevCapacities(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(gotArgs, []string{"list", "-v", "-p"}) {
+		t.Errorf("args = %v, want [list -v -p]", gotArgs)
+	}
+}
@@ -3,11 +3,18 @@
 package zfs
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Runner executes a command and returns stdout.
@@ -19,12 +26,22 @@ type Runner func(ctx context.Context, name string, args ...string) ([]byte, erro
 // the binary directly without a shell. Each argument is passed as a separate
 // argv entry, so shell metacharacters (;, |, &, etc.) are literal values, not
 // control operators. There is no command injection vector through args.
+// Failures are returned as *CommandError.
 //
 // The binary name comes from Client.zpoolPath / Client.zfsPath, which are
 // validated at startup by config.Validate() (exec.LookPath for bare names,
 // os.Stat + executable bit check for absolute paths). All args are hardcoded
 // string literals in the Client methods (GetPools, GetDatasets,
-// GetScanStatuses) -- no user input reaches the arg list.
+// GetScanStatuses, GetVdevStatuses) -- no user input reaches the arg list.
+// The exceptions are GetDatasetProperties and GetSpaceUsage, whose property
+// and dataset lists come from --dataset.properties and --dataset.userspace
+// and are charset-validated by config.Validate, and pkg/custom, whose hook
+// argv comes from the operator's hooks file. All are trusted configuration
+// with the same standing as the binary paths. GetScanStatuses,
+// GetVdevStatuses, and GetSnapshotCounts pass pool names read from zpool
+// list, which ZFS requires to start with a letter. host.SmartReader passes
+// device paths resolved under /dev from zpool status output, which cannot
+// start with "-".
 //
 // INFO(security): exec.CommandContext does NOT use a shell. Args are passed
 // directly as argv to the process. No shell injection is possible through this
@@ -32,86 +49,352 @@ type Runner func(ctx context.Context, name string, args ...string) ([]byte, erro
 // wrap this in a shell (e.g. bash -c) or the security model breaks.
 func DefaultRunner() Runner {
 	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
-		out, err := exec.CommandContext(ctx, name, args...).Output()
+		var stdout, stderr bytes.Buffer
+
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
 		if err == nil {
-			return out, nil
+			return stdout.Bytes(), nil
 		}
 
-		// Context cancellation/timeout killed the process.
-		if ctx.Err() != nil {
-			return nil, fmt.Errorf("command %q killed: %w", name, ctx.Err())
-		}
+		cmdErr := commandError(ctx, name, args, stderr.Bytes(), err)
 
 		// Process exited non-zero. Return stdout (callers like ServiceChecker
-		// need it) and include stderr in the error for diagnostics.
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			stderr := string(exitErr.Stderr)
-			if stderr != "" {
-				return out, fmt.Errorf("command %q exited %d: %s", name, exitErr.ExitCode(), stderr)
-			}
-
-			return out, fmt.Errorf("command %q exited %d", name, exitErr.ExitCode())
+		// need it) and include stderr in the error for diagnostics, e.g.
+		// "permission denied" when not run as root.
+		if cmdErr.ExitCode >= 0 {
+			return stdout.Bytes(), cmdErr
 		}
 
-		return nil, fmt.Errorf("command %q failed: %w", name, err)
+		return nil, cmdErr
+	}
+}
+
+// commandError wraps the failure err of the command name args, run with
+// ctx, in a *CommandError.
+func commandError(ctx context.Context, name string, args []string, stderr []byte, err error) *CommandError {
+	cmdErr := &CommandError{
+		Cmd:      name,
+		Args:     args,
+		ExitCode: -1,
+		Stderr:   stderrMessage(stderr),
+		Err:      err,
+	}
+
+	// Context cancellation/timeout killed the process.
+	if ctx.Err() != nil {
+		cmdErr.Err = ctx.Err()
+		return cmdErr
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cmdErr.ExitCode = exitErr.ExitCode()
+	}
+
+	return cmdErr
+}
+
+// maxStderrLen bounds the stderr included in a command error so a chatty
+// command cannot flood the logs.
+const maxStderrLen = 512
+
+// stderrMessage returns stderr as a single trimmed line for an error
+// message, truncated to maxStderrLen bytes.
+func stderrMessage(stderr []byte) string {
+	msg := strings.Join(strings.Fields(string(stderr)), " ")
+	if len(msg) > maxStderrLen {
+		msg = msg[:maxStderrLen] + "..."
+	}
+
+	return msg
+}
+
+// TimedRunner wraps r so that observe is called with each command's name,
+// arguments, and wall-clock duration, whether or not it succeeded.
+func TimedRunner(r Runner, observe func(name string, args []string, d time.Duration)) Runner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		start := time.Now()
+		out, err := r(ctx, name, args...)
+		observe(name, args, time.Since(start))
+
+		return out, err
 	}
 }
 
 // Client executes ZFS CLI commands and parses their output.
 type Client struct {
 	runner    Runner
+	stream    StreamRunner // nil buffers through runner
 	logger    *slog.Logger
 	zpoolPath string
 	zfsPath   string
+
+	// datasetDepth limits GetDatasets to this many levels below each pool's
+	// root dataset; negative means no limit. datasetTypes is the zfs list
+	// -t argument.
+	datasetDepth int
+	datasetTypes string
+
+	// snapshotLimit caps the snapshots counted by GetSnapshotCounts; 0
+	// means no limit. snapshotPaging lists them one pool at a time.
+	snapshotLimit  int
+	snapshotPaging bool
+
+	// statusConcurrency, when positive, runs zpool status per pool with at
+	// most this many at once.
+	statusConcurrency int
+
+	// missingCols are the optional list columns found unsupported by
+	// DetectColumns.
+	colMu       sync.Mutex
+	missingCols map[string]bool
+
+	// features is nil until detected by Features. jsonOff is set by
+	// DisableJSON.
+	featMu   sync.Mutex
+	features *Features
+	jsonOff  bool
 }
 
 // NewClient creates a Client with the given runner, logger, and binary paths.
 func NewClient(runner Runner, logger *slog.Logger, zpoolPath, zfsPath string) *Client {
 	return &Client{
-		runner:    runner,
-		logger:    logger,
-		zpoolPath: zpoolPath,
-		zfsPath:   zfsPath,
+		runner:       runner,
+		logger:       logger,
+		zpoolPath:    zpoolPath,
+		zfsPath:      zfsPath,
+		datasetDepth: -1,
+		datasetTypes: TypeFilesystem + "," + TypeVolume,
 	}
 }
 
+// SetDatasetDepth limits GetDatasets to datasets at most depth levels below
+// each pool's root dataset, as with zfs list -d. A negative depth removes the
+// limit.
+func (c *Client) SetDatasetDepth(depth int) {
+	c.datasetDepth = depth
+}
+
+// SetDatasetTypes sets the dataset types GetDatasets lists, from
+// DatasetTypes. An empty list keeps the default of filesystems and volumes.
+func (c *Client) SetDatasetTypes(types []string) {
+	if len(types) > 0 {
+		c.datasetTypes = strings.Join(types, ",")
+	}
+}
+
+// SetSnapshotLimit stops GetSnapshotCounts after limit snapshots. Zero or a
+// negative limit removes the cap.
+func (c *Client) SetSnapshotLimit(limit int) {
+	c.snapshotLimit = max(limit, 0)
+}
+
+// SetSnapshotPaging makes GetSnapshotCounts list the snapshots of each pool
+// with a separate command instead of all at once.
+func (c *Client) SetSnapshotPaging(perPool bool) {
+	c.snapshotPaging = perPool
+}
+
+// SetStatusConcurrency makes GetScanStatuses and GetVdevStatuses run zpool
+// status once per pool, at most n at a time, so that a hung or suspended
+// pool does not hold up the others. Zero or a negative n runs one zpool
+// status for all pools.
+func (c *Client) SetStatusConcurrency(n int) {
+	c.statusConcurrency = max(n, 0)
+}
+
+// statusPerPool reports whether zpool status runs once for each of pools.
+// A single pool is the same either way.
+func (c *Client) statusPerPool(pools []string) bool {
+	return c.statusConcurrency > 0 && len(pools) > 1
+}
+
+// perPool calls fetch for each pool, at most limit at a time, and returns
+// the results in pool order. Errors are joined, each naming its pool; pools
+// not yet started when ctx ends fail with ctx's error.
+func perPool[T any](ctx context.Context, pools []string, limit int, fetch func(context.Context, ...string) ([]T, error)) ([]T, error) {
+	var (
+		results = make([][]T, len(pools))
+		errs    = make([]error, len(pools))
+		sem     = make(chan struct{}, limit)
+		wg      sync.WaitGroup
+	)
+
+	for i, pool := range pools {
+		wg.Go(func() {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("pool %s: %w", pool, ctx.Err())
+				return
+			}
+
+			if results[i], errs[i] = fetch(ctx, pool); errs[i] != nil {
+				errs[i] = fmt.Errorf("pool %s: %w", pool, errs[i])
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return slices.Concat(results...), errors.Join(errs...)
+}
+
 // GetPools returns all ZFS pools.
 func (c *Client) GetPools(ctx context.Context) ([]Pool, error) {
-	out, err := c.runner(ctx, c.zpoolPath, "list", "-Hp", "-o", poolColumns)
-	if err != nil {
-		return nil, fmt.Errorf("zpool list failed: %w", err)
+	if c.useJSON(ctx) {
+		out, err := c.runner(ctx, c.zpoolPath, "list", "-j", "-p", "-o", poolColumns)
+		if err != nil {
+			return nil, fmt.Errorf("zpool list failed: %w", err)
+		}
+
+		return parsePoolsJSON(out)
 	}
 
-	pools, err := parsePools(out)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse pool output: %w", err)
+	var (
+		pools    []Pool
+		parseErr error
+		cols     = c.columnList(poolColumns)
+	)
+
+	err := c.runStream(ctx, func(r io.Reader) error {
+		pools, parseErr = parsePools(r, cols)
+		return parseErr
+	}, c.zpoolPath, "list", "-Hp", "-o", cols.String())
+
+	switch {
+	case parseErr != nil:
+		return nil, fmt.Errorf("failed to parse pool output: %w", parseErr)
+	case err != nil:
+		return nil, fmt.Errorf("zpool list failed: %w", err)
 	}
 
 	return pools, nil
 }
 
-// GetDatasets returns all ZFS datasets (filesystems and volumes).
+// GetDatasets returns all ZFS datasets of the types set with SetDatasetTypes
+// (filesystems and volumes by default), down to the depth set with
+// SetDatasetDepth.
 func (c *Client) GetDatasets(ctx context.Context) ([]Dataset, error) {
-	out, err := c.runner(ctx, c.zfsPath, "list", "-Hp", "-o", datasetColumns, "-t", "filesystem,volume")
-	if err != nil {
-		return nil, fmt.Errorf("zfs list failed: %w", err)
+	args := []string{"-t", c.datasetTypes}
+	if c.datasetDepth >= 0 {
+		args = append(args, "-d", strconv.Itoa(c.datasetDepth))
 	}
 
-	datasets, err := parseDatasets(out)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse dataset output: %w", err)
+	if c.useJSON(ctx) {
+		out, err := c.runner(ctx, c.zfsPath, append([]string{"list", "-j", "-p", "-o", datasetColumns}, args...)...)
+		if err != nil {
+			return nil, fmt.Errorf("zfs list failed: %w", err)
+		}
+
+		return parseDatasetsJSON(out)
+	}
+
+	var (
+		datasets []Dataset
+		parseErr error
+		cols     = c.columnList(datasetColumns)
+	)
+
+	err := c.runStream(ctx, func(r io.Reader) error {
+		datasets, parseErr = parseDatasets(r, cols)
+		return parseErr
+	}, c.zfsPath, append([]string{"list", "-Hp", "-o", cols.String()}, args...)...)
+
+	switch {
+	case parseErr != nil:
+		return nil, fmt.Errorf("failed to parse dataset output: %w", parseErr)
+	case err != nil:
+		return nil, fmt.Errorf("zfs list failed: %w", err)
 	}
 
 	return datasets, nil
 }
 
-// GetScanStatuses returns the scan status for all pools.
-func (c *Client) GetScanStatuses(ctx context.Context) ([]ScanStatus, error) {
-	out, err := c.runner(ctx, c.zpoolPath, "status")
+// GetScanStatuses returns the scan status for all pools. With
+// SetStatusConcurrency, zpool status runs once for each of pools; the
+// statuses of the pools that answered are returned along with the errors of
+// those that did not.
+func (c *Client) GetScanStatuses(ctx context.Context, pools []string) ([]ScanStatus, error) {
+	if c.statusPerPool(pools) {
+		return perPool(ctx, pools, c.statusConcurrency, c.getScanStatuses)
+	}
+
+	return c.getScanStatuses(ctx)
+}
+
+// getScanStatuses runs zpool status for pools, or all pools if none are
+// given.
+func (c *Client) getScanStatuses(ctx context.Context, pools ...string) ([]ScanStatus, error) {
+	if c.useJSON(ctx) {
+		status, err := c.getStatusJSON(ctx, pools)
+		if err != nil {
+			return nil, err
+		}
+
+		return scanStatusesFromJSON(status, time.Now()), nil
+	}
+
+	out, err := c.runner(ctx, c.zpoolPath, append([]string{"status"}, pools...)...)
 	if err != nil {
 		return nil, fmt.Errorf("zpool status failed: %w", err)
 	}
 
 	return parseScanStatuses(out), nil
 }
+
+// GetVdevStatuses returns per-device error counters for all pools. It runs
+// zpool status with -p so counters are exact rather than abbreviated (1.2K).
+// pools are handled as by GetScanStatuses.
+func (c *Client) GetVdevStatuses(ctx context.Context, pools []string) ([]VdevStatus, error) {
+	if c.statusPerPool(pools) {
+		return perPool(ctx, pools, c.statusConcurrency, c.getVdevStatuses)
+	}
+
+	return c.getVdevStatuses(ctx)
+}
+
+// getVdevStatuses runs zpool status -p for pools, or all pools if none are
+// given.
+func (c *Client) getVdevStatuses(ctx context.Context, pools ...string) ([]VdevStatus, error) {
+	if c.useJSON(ctx) {
+		status, err := c.getStatusJSON(ctx, pools)
+		if err != nil {
+			return nil, err
+		}
+
+		return vdevStatusesFromJSON(status), nil
+	}
+
+	out, err := c.runner(ctx, c.zpoolPath, append([]string{"status", "-p"}, pools...)...)
+	if err != nil {
+		return nil, fmt.Errorf("zpool status -p failed: %w", err)
+	}
+
+	return parseVdevStatuses(out), nil
+}
+
+// GetVdevCapacities returns the size, allocated space, and fragmentation of
+// the data-holding top-level vdevs of every pool, from zpool list -v -p.
+func (c *Client) GetVdevCapacities(ctx context.Context) ([]VdevCapacity, error) {
+	out, err := c.runner(ctx, c.zpoolPath, "list", "-v", "-p")
+	if err != nil {
+		return nil, fmt.Errorf("zpool list -v failed: %w", err)
+	}
+
+	return parseVdevCapacities(out)
+}
+
+func (c *Client) getStatusJSON(ctx context.Context, pools []string) ([]jsonStatusPool, error) {
+	out, err := c.runner(ctx, c.zpoolPath, append([]string{"status", "-j", "-p"}, pools...)...)
+	if err != nil {
+		return nil, fmt.Errorf("zpool status failed: %w", err)
+	}
+
+	return parseStatusJSON(out)
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func testLogger() *slog.Logger {
@@ -68,7 +74,7 @@ func TestClient_GetPools_EmptyOutput(t *testing.T) {
 
 func TestClient_GetDatasets_Success(t *testing.T) {
 	runner := func(_ context.Context, _ string, _ ...string) ([]byte, error) {
-		return []byte("tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\n"), nil
+		return []byte("tank/media\t4294967296\t5368709120\t4294967296\tfilesystem\ton\toff\t1.00\t4294967296\t4294967296\n"), nil
 	}
 
 	client := NewClient(runner, testLogger(), "zpool", "zfs")
@@ -91,6 +97,64 @@ func TestClient_GetDatasets_Success(t *testing.T) {
 	}
 }
 
+func TestClient_GetDatasets_Depth(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{-1, "list -Hp -o " + datasetColumns + " -t filesystem,volume"},
+		{0, "list -Hp -o " + datasetColumns + " -t filesystem,volume -d 0"},
+		{2, "list -Hp -o " + datasetColumns + " -t filesystem,volume -d 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.depth), func(t *testing.T) {
+			var gotArgs []string
+
+			runner := func(_ context.Context, name string, args ...string) ([]byte, error) {
+				if name == "zfs" {
+					gotArgs = args
+				}
+
+				return nil, nil
+			}
+
+			client := NewClient(runner, testLogger(), "zpool", "zfs")
+			client.DisableJSON()
+			client.SetDatasetDepth(tt.depth)
+
+			if _, err := client.GetDatasets(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := strings.Join(gotArgs, " "); got != tt.want {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetDatasets_Types(t *testing.T) {
+	var gotArgs []string
+
+	runner := func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return nil, nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+	client.DisableJSON()
+	client.SetDatasetTypes([]string{TypeFilesystem, TypeSnapshot})
+
+	if _, err := client.GetDatasets(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "list -Hp -o " + datasetColumns + " -t filesystem,snapshot"; strings.Join(gotArgs, " ") != want {
+		t.Errorf("args = %q, want %q", strings.Join(gotArgs, " "), want)
+	}
+}
+
 func TestClient_GetDatasets_CommandError(t *testing.T) {
 	runner := func(_ context.Context, _ string, _ ...string) ([]byte, error) {
 		return nil, errors.New("command failed")
@@ -116,7 +180,7 @@ func TestClient_GetScanStatuses_Success(t *testing.T) {
 
 	client := NewClient(runner, testLogger(), "zpool", "zfs")
 
-	statuses, err := client.GetScanStatuses(context.Background())
+	statuses, err := client.GetScanStatuses(context.Background(), nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -137,12 +201,79 @@ func TestClient_GetScanStatuses_CommandError(t *testing.T) {
 
 	client := NewClient(runner, testLogger(), "zpool", "zfs")
 
-	_, err := client.GetScanStatuses(context.Background())
+	_, err := client.GetScanStatuses(context.Background(), nil)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 }
 
+func TestClient_GetScanStatuses_PerPool(t *testing.T) {
+	var (
+		mu               sync.Mutex
+		running, maxRuns int
+		calls            [][]string
+	)
+
+	runner := func(ctx context.Context, _ string, args ...string) ([]byte, error) {
+		if args[0] == "version" {
+			return nil, errors.New("unrecognized command 'version'")
+		}
+
+		mu.Lock()
+		calls = append(calls, args)
+		running++
+		maxRuns = max(maxRuns, running)
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+
+		pool := args[len(args)-1]
+		if pool == "suspended" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		return []byte("  pool: " + pool + "\n state: ONLINE\n  scan: scrub in progress since Sun Jul 25 16:07:49 2025\n"), nil
+	}
+
+	client := NewClient(runner, testLogger(), "zpool", "zfs")
+	client.SetStatusConcurrency(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	statuses, err := client.GetScanStatuses(ctx, []string{"tank", "suspended", "backup", "scratch"})
+	if err == nil || !strings.Contains(err.Error(), "pool suspended:") || !isContextErr(err) {
+		t.Errorf("error = %v, want the suspended pool's timeout", err)
+	}
+
+	var got []string
+	for _, s := range statuses {
+		got = append(got, s.Pool)
+	}
+
+	if want := []string{"tank", "backup", "scratch"}; !slices.Equal(got, want) {
+		t.Errorf("pools with status = %v, want %v", got, want)
+	}
+
+	if len(calls) != 4 || maxRuns > 2 {
+		t.Errorf("ran %d zpool status commands, %d at once; want 4, at most 2", len(calls), maxRuns)
+	}
+
+	// A single pool needs no per-pool run.
+	calls = nil
+
+	if _, err := client.GetScanStatuses(context.Background(), []string{"tank"}); err != nil || len(calls) != 1 || len(calls[0]) != 1 {
+		t.Errorf("single pool ran %q, %v; want one plain zpool status", calls, err)
+	}
+}
+
 func TestClient_VerifiesBinaryPaths(t *testing.T) {
 	var capturedName string
 
@@ -166,3 +297,78 @@ func TestClient_VerifiesBinaryPaths(t *testing.T) {
 		t.Errorf("expected runner called with %q, got %q", "/usr/sbin/zfs", capturedName)
 	}
 }
+
+func TestTimedRunner(t *testing.T) {
+	wantErr := errors.New("exit status 1")
+
+	runner := func(_ context.Context, _ string, _ ...string) ([]byte, error) {
+		return []byte("out"), wantErr
+	}
+
+	var (
+		gotName string
+		gotArgs []string
+		calls   int
+	)
+
+	timed := TimedRunner(runner, func(name string, args []string, d time.Duration) {
+		gotName, gotArgs = name, args
+		calls++
+
+		if d < 0 {
+			t.Errorf("negative duration %v", d)
+		}
+	})
+
+	out, err := timed(context.Background(), "zpool", "list", "-Hp")
+	if string(out) != "out" || !errors.Is(err, wantErr) {
+		t.Errorf("timed runner changed the result: %q, %v", out, err)
+	}
+
+	if calls != 1 || gotName != "zpool" || len(gotArgs) != 2 || gotArgs[0] != "list" {
+		t.Errorf("observe called %d times with %q %v", calls, gotName, gotArgs)
+	}
+}
+
+func TestDefaultRunner_Stderr(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	runner := DefaultRunner()
+
+	out, err := runner(context.Background(), "sh", "-c", "echo partial; echo 'cannot open /dev/zfs:\n  Permission denied' >&2; exit 1")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	want := `command "sh" exited 1: cannot open /dev/zfs: Permission denied`
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 || len(cmdErr.Args) != 2 {
+		t.Errorf("error = %#v, want *CommandError with exit code 1 and args", err)
+	}
+
+	if string(out) != "partial\n" {
+		t.Errorf("stdout = %q, want %q", out, "partial\n")
+	}
+
+	if _, err := runner(context.Background(), "sh", "-c", "exit 2"); err == nil || err.Error() != `command "sh" exited 2` {
+		t.Errorf("error without stderr = %v", err)
+	}
+}
+
+func TestStderrMessage(t *testing.T) {
+	long := strings.Repeat("x", maxStderrLen+10)
+
+	if got := stderrMessage([]byte(long)); len(got) != maxStderrLen+3 || !strings.HasSuffix(got, "...") {
+		t.Errorf("long stderr not truncated: %d bytes", len(got))
+	}
+
+	if got := stderrMessage([]byte("\n  \n")); got != "" {
+		t.Errorf("blank stderr = %q, want empty", got)
+	}
+}
@@ -1,46 +1,116 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
 )
 
 // ServiceConfig defines a service whose panels appear in generated dashboards.
 type ServiceConfig struct {
 	// Key is the service identifier used in metrics (e.g. "nfs", "smb", "iscsi").
-	Key string
+	Key string `yaml:"key"`
 
 	// Label is the display name in dashboard panels (e.g. "NFS", "SMB", "iSCSI").
-	Label string
+	Label string `yaml:"label"`
 
 	// ShareMetric is the metric name for share detection.
 	// For NFS: "zfs_dataset_share_nfs", for SMB: "zfs_dataset_share_smb".
 	// Empty means this service does not use share metrics.
-	ShareMetric string
+	ShareMetric string `yaml:"share_metric"`
 
 	// UseZvols indicates this service should show zvol inventory instead of
 	// share datasets (true for iSCSI).
-	UseZvols bool
+	UseZvols bool `yaml:"use_zvols"`
 }
 
 // DashboardSet controls which dashboards to generate.
 type DashboardSet struct {
-	Status   bool // zfs-status.json
-	Details  bool // zfs-details.json
-	Combined bool // zfs-combined.json
+	Status   bool `yaml:"status"`   // zfs-status.json
+	Details  bool `yaml:"details"`  // zfs-details.json
+	Combined bool `yaml:"combined"` // zfs-combined.json
+	Devices  bool `yaml:"devices"`  // zfs-devices.json
 }
 
 // Config defines what the dashboard generator produces.
 type Config struct {
 	// Services to include in dashboards. Only listed services get panels.
-	Services []ServiceConfig
+	Services []ServiceConfig `yaml:"services"`
 
 	// Dashboards to generate.
-	Dashboards DashboardSet
+	Dashboards DashboardSet `yaml:"dashboards"`
 
 	// OutputDir is the directory to write JSON files.
-	OutputDir string
+	OutputDir string `yaml:"output_dir"`
+
+	// Thresholds tunes the alert rules and the matching dashboard bands.
+	Thresholds ThresholdConfig `yaml:"thresholds"`
+
+	// Provisioning configures the bundle written by -provision.
+	Provisioning ProvisioningConfig `yaml:"provisioning"`
+
+	// SLO enables burn-rate alerts for availability targets.
+	SLO SLOConfig `yaml:"slo"`
+}
+
+// ThresholdConfig holds the site policy shared by the generated alerts and
+// dashboards. Ratios are fractions of 1.
+type ThresholdConfig struct {
+	// CapacityWarning and CapacityCritical are the pool allocated/size
+	// ratios of the capacity alerts and the orange/red capacity bands.
+	CapacityWarning  float64 `yaml:"capacity_warning"`
+	CapacityCritical float64 `yaml:"capacity_critical"`
+
+	// Fragmentation is the pool fragmentation ratio of the fragmentation
+	// alert and the red band on the fragmentation graph.
+	Fragmentation float64 `yaml:"fragmentation"`
+
+	// ScrubMaxAgeDays is how many days may pass after a pool's last
+	// completed scrub before the scrub age alert fires.
+	ScrubMaxAgeDays int `yaml:"scrub_max_age_days"`
+
+	// For overrides alert for: durations by alert name, e.g.
+	// ZfsPoolCapacityWarning: 30m.
+	For map[string]string `yaml:"for"`
+}
+
+// SLOConfig holds availability targets as fractions of 1 (e.g. 0.999 for
+// three nines). Each non-zero target adds a fast-burn (critical) and a
+// slow-burn (warning) alert in a zfs_slo rule group.
+type SLOConfig struct {
+	// ExporterAvailability is the share of time zfs_up should be 1.
+	ExporterAvailability float64 `yaml:"exporter_availability"`
+
+	// PoolOnline is the share of time every pool should be ONLINE.
+	PoolOnline float64 `yaml:"pool_online"`
+}
+
+// ProvisioningConfig describes where Grafana finds provisioned dashboards.
+type ProvisioningConfig struct {
+	// Folder is the Grafana folder title the dashboards are placed in.
+	Folder string `yaml:"folder"`
+
+	// FolderUID is the folder's stable UID, so links and alert rules can
+	// reference it.
+	FolderUID string `yaml:"folder_uid"`
+
+	// Path is where the dashboard JSON files live on the Grafana host. The
+	// provider YAML points Grafana at it.
+	Path string `yaml:"path"`
+
+	// DatasourceUID is the UID of the Prometheus datasource queried by the
+	// provisioned Grafana alert rules.
+	DatasourceUID string `yaml:"datasource_uid"`
 }
 
 // DefaultConfig generates all dashboards with all services enabled.
@@ -50,8 +120,43 @@ var DefaultConfig = Config{
 		{Key: "smb", Label: "SMB", ShareMetric: "zfs_dataset_share_smb"},
 		{Key: "iscsi", Label: "iSCSI", UseZvols: true},
 	},
-	Dashboards: DashboardSet{Status: true, Details: true, Combined: true},
+	Dashboards: DashboardSet{Status: true, Details: true, Combined: true, Devices: true},
 	OutputDir:  "../../contrib/grafana/data",
+	Thresholds: ThresholdConfig{
+		CapacityWarning:  0.8,
+		CapacityCritical: 0.9,
+		Fragmentation:    0.5,
+		ScrubMaxAgeDays:  35,
+	},
+	Provisioning: ProvisioningConfig{
+		Folder:        "ZFS",
+		FolderUID:     "zfs-exporter",
+		Path:          "/etc/grafana/provisioning/dashboards/zfs_exporter",
+		DatasourceUID: "prometheus",
+	},
+}
+
+// LoadConfig reads a YAML config file on top of DefaultConfig, so a file only
+// needs the keys it changes. A services list replaces the default list
+// entirely. Unknown keys are errors. A relative output_dir is resolved
+// against the working directory, like the default.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig
+	cfg.Services = slices.Clone(DefaultConfig.Services)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	return cfg, nil
 }
 
 // RulesDir returns the Prometheus rules output directory, derived from
@@ -101,9 +206,69 @@ func (c *Config) Validate() error {
 		errs = append(errs, errors.New("output_dir is required"))
 	}
 
-	if !c.Dashboards.Status && !c.Dashboards.Details && !c.Dashboards.Combined {
+	errs = append(errs, c.validateThresholds()...)
+
+	for name, v := range map[string]float64{
+		"exporter_availability": c.SLO.ExporterAvailability,
+		"pool_online":           c.SLO.PoolOnline,
+	} {
+		if v < 0 || v >= 1 {
+			errs = append(errs, fmt.Errorf("slo.%s: %v not in (0, 1); use 0 to disable", name, v))
+		}
+	}
+
+	if c.Provisioning.Path == "" {
+		errs = append(errs, errors.New("provisioning.path is required"))
+	}
+
+	if c.Provisioning.DatasourceUID == "" {
+		errs = append(errs, errors.New("provisioning.datasource_uid is required"))
+	}
+
+	if !c.Dashboards.Status && !c.Dashboards.Details && !c.Dashboards.Combined && !c.Dashboards.Devices {
 		errs = append(errs, errors.New("at least one dashboard must be enabled"))
 	}
 
 	return errors.Join(errs...)
 }
+
+// validateThresholds checks that the ratios are ordered and in (0, 1], that
+// the scrub age is positive, and that every for: override names a generated
+// alert and parses as a duration.
+func (c *Config) validateThresholds() []error {
+	var errs []error
+
+	th := c.Thresholds
+
+	for name, v := range map[string]float64{
+		"capacity_warning":  th.CapacityWarning,
+		"capacity_critical": th.CapacityCritical,
+		"fragmentation":     th.Fragmentation,
+	} {
+		if v <= 0 || v > 1 {
+			errs = append(errs, fmt.Errorf("thresholds.%s: %v not in (0, 1]", name, v))
+		}
+	}
+
+	if th.CapacityWarning >= th.CapacityCritical {
+		errs = append(errs, errors.New("thresholds: capacity_warning must be below capacity_critical"))
+	}
+
+	if th.ScrubMaxAgeDays <= 0 {
+		errs = append(errs, fmt.Errorf("thresholds.scrub_max_age_days: %d must be positive", th.ScrubMaxAgeDays))
+	}
+
+	alerts := rules.AlertNames(toRulesServiceConfigs(c.Services))
+
+	for _, name := range slices.Sorted(maps.Keys(th.For)) {
+		if !slices.Contains(alerts, name) {
+			errs = append(errs, fmt.Errorf("thresholds.for: unknown alert %q", name))
+		}
+
+		if _, err := model.ParseDuration(th.For[name]); err != nil {
+			errs = append(errs, fmt.Errorf("thresholds.for.%s: %w", name, err))
+		}
+	}
+
+	return errs
+}
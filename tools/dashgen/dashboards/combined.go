@@ -9,6 +9,10 @@ import (
 // CombinedConfig holds the parameters needed to build the combined dashboard.
 type CombinedConfig struct {
 	Services []panels.ServiceConfig
+
+	// Thresholds colors the capacity and fragmentation panels. Zero fields
+	// use panels.DefaultThresholds.
+	Thresholds panels.Thresholds
 }
 
 // BuildCombined creates the ZFS Combined dashboard — status stat panels at the
@@ -24,11 +28,13 @@ func BuildCombined(cfg CombinedConfig) (*dashboard.DashboardBuilder, error) {
 		Tooltip(dashboard.DashboardCursorSyncCrosshair)
 
 	b = b.WithVariable(datasourceVar()).
+		WithVariable(jobVar()).
+		WithVariable(instanceVar()).
 		WithVariable(poolVar())
 
 	// Top stat panels (no row header): 6 across at w:4, h:4.
 	b = b.WithPanel(panels.PoolHealth().Height(4).Span(4)).
-		WithPanel(panels.PoolCapacity().Height(4).Span(4)).
+		WithPanel(panels.PoolCapacity(cfg.Thresholds).Height(4).Span(4)).
 		WithPanel(panels.ServiceStatusAll().Height(4).Span(4)).
 		WithPanel(panels.ResilverScrub().Height(4).Span(4)).
 		WithPanel(panels.DaysUntilFull().Height(4).Span(4)).
@@ -38,8 +44,8 @@ func BuildCombined(cfg CombinedConfig) (*dashboard.DashboardBuilder, error) {
 	b = b.WithRow(
 		dashboard.NewRowBuilder("Pool Details").
 			WithPanel(panels.PoolUsageOverTime()).
-			WithPanel(panels.PoolUsageBars()).
-			WithPanel(panels.Fragmentation().Span(6)),
+			WithPanel(panels.PoolUsageBars(cfg.Thresholds)).
+			WithPanel(panels.Fragmentation(cfg.Thresholds).Span(6)),
 	)
 
 	// Dataset Details (collapsed row).
@@ -9,6 +9,10 @@ import (
 // DetailsConfig holds the parameters needed to build the details dashboard.
 type DetailsConfig struct {
 	Services []panels.ServiceConfig
+
+	// Thresholds colors the capacity and fragmentation panels. Zero fields
+	// use panels.DefaultThresholds.
+	Thresholds panels.Thresholds
 }
 
 // BuildDetails creates the ZFS Details dashboard — expanded rows with
@@ -24,13 +28,15 @@ func BuildDetails(cfg DetailsConfig) (*dashboard.DashboardBuilder, error) {
 		Tooltip(dashboard.DashboardCursorSyncCrosshair)
 
 	b = b.WithVariable(datasourceVar()).
+		WithVariable(jobVar()).
+		WithVariable(instanceVar()).
 		WithVariable(poolVar())
 
 	// Row: Pool Capacity (expanded, panels as siblings).
 	b = b.WithRow(dashboard.NewRowBuilder("Pool Capacity")).
 		WithPanel(panels.PoolUsageOverTime().Span(10)).
-		WithPanel(panels.PoolUsageBars()).
-		WithPanel(panels.Fragmentation())
+		WithPanel(panels.PoolUsageBars(cfg.Thresholds)).
+		WithPanel(panels.Fragmentation(cfg.Thresholds))
 
 	// Row: Dataset Usage (expanded, panels as siblings).
 	b = b.WithRow(dashboard.NewRowBuilder("Dataset Usage")).
@@ -0,0 +1,49 @@
+package dashboards
+
+import (
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/panels"
+)
+
+// DevicesConfig holds the parameters needed to build the devices dashboard.
+type DevicesConfig struct{}
+
+// BuildDevices creates the ZFS Devices dashboard — per-device state and error
+// counters from zpool status, for deciding which disk to pull when a pool
+// degrades, and how evenly the top-level vdevs are filled.
+func BuildDevices(_ DevicesConfig) (*dashboard.DashboardBuilder, error) {
+	b := dashboard.NewDashboardBuilder("ZFS Devices").
+		Uid("zfs-devices").
+		Tags([]string{"zfs", "prometheus"}).
+		Refresh("30s").
+		Time("now-6h", "now").
+		Timezone("browser").
+		Editable().
+		Tooltip(dashboard.DashboardCursorSyncCrosshair)
+
+	b = b.WithVariable(datasourceVar()).
+		WithVariable(jobVar()).
+		WithVariable(instanceVar()).
+		WithVariable(poolVar())
+
+	// Row: Device Health (stat panels).
+	b = b.WithRow(dashboard.NewRowBuilder("Device Health")).
+		WithPanel(panels.DevicesNotOnline()).
+		WithPanel(panels.DevicesWithErrors()).
+		WithPanel(panels.SparesInUse()).
+		WithPanel(panels.SparesAvailable())
+
+	// Row: Devices (pool → vdev → device mapping and error counters).
+	b = b.WithRow(dashboard.NewRowBuilder("Devices")).
+		WithPanel(panels.DeviceStateTable()).
+		WithPanel(panels.DeviceErrorTable()).
+		WithPanel(panels.DeviceErrorsOverTime())
+
+	// Row: Vdev Balance (per top-level vdev fill from zpool list -v).
+	b = b.WithRow(dashboard.NewRowBuilder("Vdev Balance")).
+		WithPanel(panels.VdevCapacity()).
+		WithPanel(panels.VdevFragmentation())
+
+	return b, nil
+}
@@ -13,6 +13,10 @@ import (
 // StatusConfig holds the parameters needed to build the status dashboard.
 type StatusConfig struct {
 	Services []panels.ServiceConfig
+
+	// Thresholds colors the capacity and fragmentation panels. Zero fields
+	// use panels.DefaultThresholds.
+	Thresholds panels.Thresholds
 }
 
 // BuildStatus creates the ZFS Status dashboard — a NOC-screen overview with
@@ -29,12 +33,14 @@ func BuildStatus(cfg StatusConfig) (*dashboard.DashboardBuilder, error) {
 
 	// Variables: datasource + pool.
 	b = b.WithVariable(datasourceVar()).
+		WithVariable(jobVar()).
+		WithVariable(instanceVar()).
 		WithVariable(poolVar())
 
 	// Row: Pool Health.
 	b = b.WithRow(dashboard.NewRowBuilder("Pool Health")).
 		WithPanel(panels.PoolHealth()).
-		WithPanel(panels.PoolCapacity()).
+		WithPanel(panels.PoolCapacity(cfg.Thresholds)).
 		WithPanel(panels.ResilverScrub()).
 		WithPanel(panels.DaysUntilFull())
 
@@ -62,12 +68,39 @@ func datasourceVar() *dashboard.DatasourceVariableBuilder {
 		Type("prometheus")
 }
 
-// poolVar returns the common "pool" template variable.
+// jobVar returns the common "job" template variable: the scrape jobs that
+// export ZFS metrics.
+func jobVar() *dashboard.QueryVariableBuilder {
+	return dashboard.NewQueryVariableBuilder("job").
+		Label("Job").
+		Datasource(panels.DSRef()).
+		Query(dashboard.StringOrMap{String: cog.ToPtr("label_values(zfs_up, job)")}).
+		Refresh(dashboard.VariableRefreshOnTimeRangeChanged).
+		Sort(dashboard.VariableSortAlphabeticalAsc).
+		Multi(true).
+		IncludeAll(true)
+}
+
+// instanceVar returns the common "instance" template variable, limited to
+// the selected jobs.
+func instanceVar() *dashboard.QueryVariableBuilder {
+	return dashboard.NewQueryVariableBuilder("instance").
+		Label("Instance").
+		Datasource(panels.DSRef()).
+		Query(dashboard.StringOrMap{String: cog.ToPtr(`label_values(zfs_up{job=~"$job"}, instance)`)}).
+		Refresh(dashboard.VariableRefreshOnTimeRangeChanged).
+		Sort(dashboard.VariableSortAlphabeticalAsc).
+		Multi(true).
+		IncludeAll(true)
+}
+
+// poolVar returns the common "pool" template variable, limited to the
+// selected hosts.
 func poolVar() *dashboard.QueryVariableBuilder {
 	return dashboard.NewQueryVariableBuilder("pool").
 		Label("Pool").
 		Datasource(panels.DSRef()).
-		Query(dashboard.StringOrMap{String: cog.ToPtr("label_values(zfs_pool_size_bytes, pool)")}).
+		Query(dashboard.StringOrMap{String: cog.ToPtr("label_values(zfs_pool_size_bytes{" + panels.HostFilter() + "}, pool)")}).
 		Refresh(dashboard.VariableRefreshOnTimeRangeChanged).
 		Sort(dashboard.VariableSortAlphabeticalAsc).
 		Multi(true).
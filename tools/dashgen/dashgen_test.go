@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/grafana/grafana-foundation-sdk/go/timeseries"
+	promparser "github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+
 	"github.com/donaldgifford/zfs_exporter/tools/dashgen/dashboards"
 	"github.com/donaldgifford/zfs_exporter/tools/dashgen/panels"
 	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
@@ -38,6 +48,9 @@ func TestBuildStatusDashboard(t *testing.T) {
 	if !result.Ok() {
 		t.Errorf("validation errors: %v", result.Errors)
 	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("validation warnings: %v", result.Warnings)
+	}
 
 	data, err := json.MarshalIndent(dash, "", "  ")
 	if err != nil {
@@ -67,6 +80,9 @@ func TestBuildDetailsDashboard(t *testing.T) {
 	if !result.Ok() {
 		t.Errorf("validation errors: %v", result.Errors)
 	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("validation warnings: %v", result.Warnings)
+	}
 
 	data, err := json.MarshalIndent(dash, "", "  ")
 	if err != nil {
@@ -92,6 +108,9 @@ func TestBuildCombinedDashboard(t *testing.T) {
 	if !result.Ok() {
 		t.Errorf("validation errors: %v", result.Errors)
 	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("validation warnings: %v", result.Warnings)
+	}
 
 	data, err := json.MarshalIndent(dash, "", "  ")
 	if err != nil {
@@ -102,6 +121,280 @@ func TestBuildCombinedDashboard(t *testing.T) {
 	assertJSONField(t, data, "title", "ZFS Combined")
 }
 
+func TestBuildDevicesDashboard(t *testing.T) {
+	b, err := dashboards.BuildDevices(dashboards.DevicesConfig{})
+	if err != nil {
+		t.Fatalf("BuildDevices: %v", err)
+	}
+
+	dash, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	result := validate.Dashboard(dash)
+	if !result.Ok() {
+		t.Errorf("validation errors: %v", result.Errors)
+	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("validation warnings: %v", result.Warnings)
+	}
+
+	data, err := json.MarshalIndent(dash, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	assertJSONField(t, data, "uid", "zfs-devices")
+	assertJSONField(t, data, "title", "ZFS Devices")
+
+	for _, metric := range []string{
+		"zfs_vdev_state", "zfs_vdev_read_errors", "zfs_vdev_write_errors", "zfs_vdev_checksum_errors",
+		"zfs_vdev_capacity_ratio", "zfs_vdev_fragmentation_ratio",
+	} {
+		if !strings.Contains(string(data), metric) {
+			t.Errorf("dashboard does not query %s", metric)
+		}
+	}
+}
+
+func TestDashboardsFilterByHost(t *testing.T) {
+	for name, build := range map[string]func(Config) (*dashboard.DashboardBuilder, error){
+		"status":   buildStatusDashboard,
+		"details":  buildDetailsDashboard,
+		"combined": buildCombinedDashboard,
+		"devices":  buildDevicesDashboard,
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, err := build(DefaultConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dash, err := b.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := json.Marshal(dash)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc struct {
+				Templating struct {
+					List []struct {
+						Name string `json:"name"`
+					} `json:"list"`
+				} `json:"templating"`
+				Panels []struct {
+					Targets []struct {
+						Expr string `json:"expr"`
+					} `json:"targets"`
+					Panels []struct {
+						Targets []struct {
+							Expr string `json:"expr"`
+						} `json:"targets"`
+					} `json:"panels"`
+				} `json:"panels"`
+			}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			var vars []string
+			for _, v := range doc.Templating.List {
+				vars = append(vars, v.Name)
+			}
+
+			if want := []string{"datasource", "job", "instance", "pool"}; !slices.Equal(vars, want) {
+				t.Errorf("variables = %v, want %v", vars, want)
+			}
+
+			var exprs []string
+			for _, p := range doc.Panels {
+				for _, tg := range p.Targets {
+					exprs = append(exprs, tg.Expr)
+				}
+
+				for _, sub := range p.Panels {
+					for _, tg := range sub.Targets {
+						exprs = append(exprs, tg.Expr)
+					}
+				}
+			}
+
+			if len(exprs) == 0 {
+				t.Fatal("no queries found")
+			}
+
+			for _, expr := range exprs {
+				if !strings.Contains(expr, `job=~"$job"`) || !strings.Contains(expr, `instance=~"$instance"`) {
+					t.Errorf("query not filtered by $job and $instance: %s", expr)
+				}
+			}
+		})
+	}
+}
+
+func TestKnownMetrics(t *testing.T) {
+	for _, name := range []string{
+		"zfs_up",
+		"zfs_pool_dedup_ratio",
+		"zfs_dataset_referenced_bytes",
+		"zfs_vdev_state",
+		"zfs_command_duration_seconds_bucket",
+		"zfs:dataset_used_bytes:avg7d",
+	} {
+		if !validate.KnownMetrics[name] {
+			t.Errorf("KnownMetrics missing %q", name)
+		}
+	}
+}
+
+func TestValidateUnknownMetric(t *testing.T) {
+	dash, err := dashboard.NewDashboardBuilder("Test").
+		WithPanel(timeseries.NewPanelBuilder().
+			Title("Known").
+			WithTarget(panels.PromQuery(`zfs_pool_dedup_ratio{pool=~"$pool"}`, "", "A"))).
+		WithPanel(timeseries.NewPanelBuilder().
+			Title("Unknown").
+			WithTarget(panels.PromQuery(`zfs_pool_no_such_metric`, "", "A"))).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	result := validate.Dashboard(dash)
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "zfs_pool_no_such_metric") {
+		t.Errorf("warnings = %v, want one for zfs_pool_no_such_metric", result.Warnings)
+	}
+}
+
+func TestValidateLayout(t *testing.T) {
+	panel := func(title string, x, y, w, h uint32) dashboard.PanelOrRowPanel {
+		return dashboard.PanelOrRowPanel{Panel: &dashboard.Panel{
+			Title:   &title,
+			GridPos: &dashboard.GridPos{X: x, Y: y, W: w, H: h},
+		}}
+	}
+	row := func(title string, x, y, w uint32, children ...dashboard.PanelOrRowPanel) dashboard.PanelOrRowPanel {
+		r := &dashboard.RowPanel{Title: &title, GridPos: &dashboard.GridPos{X: x, Y: y, W: w, H: 1}}
+		for _, c := range children {
+			r.Panels = append(r.Panels, *c.Panel)
+		}
+		return dashboard.PanelOrRowPanel{RowPanel: r}
+	}
+
+	tests := []struct {
+		name    string
+		panels  []dashboard.PanelOrRowPanel
+		wantErr string
+	}{
+		{
+			name:   "tiled",
+			panels: []dashboard.PanelOrRowPanel{panel("A", 0, 0, 12, 8), panel("B", 12, 0, 12, 8), row("R", 0, 8, 24, panel("C", 0, 9, 24, 8))},
+		},
+		{
+			name:    "overlap",
+			panels:  []dashboard.PanelOrRowPanel{panel("A", 0, 0, 12, 8), panel("B", 8, 4, 12, 8)},
+			wantErr: `"A" (x=0 y=0 w=12 h=8) overlaps "B"`,
+		},
+		{
+			name:    "too wide",
+			panels:  []dashboard.PanelOrRowPanel{panel("A", 16, 0, 12, 8)},
+			wantErr: "exceeds 24 columns",
+		},
+		{
+			name:    "zero size",
+			panels:  []dashboard.PanelOrRowPanel{panel("A", 0, 0, 0, 8)},
+			wantErr: "zero-size",
+		},
+		{
+			name:    "row collides with panel",
+			panels:  []dashboard.PanelOrRowPanel{panel("A", 0, 0, 12, 8), row("R", 0, 4, 24)},
+			wantErr: `"A" (x=0 y=0 w=12 h=8) overlaps "R"`,
+		},
+		{
+			name:    "collapsed panels collide with next row",
+			panels:  []dashboard.PanelOrRowPanel{row("R1", 0, 0, 24, panel("A", 0, 1, 24, 8)), row("R2", 0, 5, 24)},
+			wantErr: `"A" (x=0 y=1 w=24 h=8) overlaps "R2"`,
+		},
+		{
+			name:    "narrow row",
+			panels:  []dashboard.PanelOrRowPanel{row("R", 0, 0, 12)},
+			wantErr: "does not span the full width",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title := "Test"
+			result := validate.Dashboard(dashboard.Dashboard{Title: &title, Panels: tt.panels})
+
+			if tt.wantErr == "" {
+				if !result.Ok() {
+					t.Errorf("unexpected errors: %v", result.Errors)
+				}
+				return
+			}
+
+			if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], tt.wantErr) {
+				t.Errorf("errors = %v, want one containing %q", result.Errors, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeQuerier answers live validation queries from memory. Queries not in
+// counts return one series.
+type fakeQuerier struct {
+	names  []string
+	counts map[string]int
+}
+
+func (f fakeQuerier) MetricNames(context.Context) ([]string, error) { return f.names, nil }
+
+func (f fakeQuerier) Query(_ context.Context, expr string) (int, error) {
+	if n, ok := f.counts[expr]; ok {
+		return n, nil
+	}
+	return 1, nil
+}
+
+func TestValidateLive(t *testing.T) {
+	q := fakeQuerier{
+		names: []string{"zfs_up", "zfs_pool_health", "zfs_pool_spare_in_use"},
+		counts: map[string]int{
+			`zfs_pool_health{pool=~".*"}`: 0,
+			`zfs_pool_spare_in_use > 0`:   0,
+		},
+	}
+
+	exprs := []validate.Expr{
+		{Source: "ok", Expr: `zfs_up{job=~"$job"}`, WantData: true},
+		{Source: "missing", Expr: `zfs_pool_dedup_ratio`, WantData: true},
+		{Source: "no series", Expr: `zfs_pool_health{pool=~"$pool"}`, WantData: true},
+		{Source: "no data", Expr: `zfs_pool_spare_in_use > 0`, WantData: true},
+		{Source: "alert", Expr: `zfs_pool_spare_in_use > 0`},
+	}
+
+	result, err := validate.Live(context.Background(), q, exprs)
+	if err != nil {
+		t.Fatalf("Live: %v", err)
+	}
+
+	if len(result.Errors) != 1 || !strings.HasPrefix(result.Errors[0], `missing: metric "zfs_pool_dedup_ratio"`) {
+		t.Errorf("errors = %v", result.Errors)
+	}
+
+	if len(result.Warnings) != 2 ||
+		!strings.HasPrefix(result.Warnings[0], "no series: selector") ||
+		!strings.HasPrefix(result.Warnings[1], "no data: query returned no data") {
+		t.Errorf("warnings = %v", result.Warnings)
+	}
+}
+
 func TestRecordingRules(t *testing.T) {
 	rf := rules.RecordingRules()
 	if len(rf.Groups) == 0 {
@@ -122,7 +415,7 @@ func TestAlertRules(t *testing.T) {
 		{Key: "smb", Label: "SMB", ShareMetric: "zfs_dataset_share_smb"},
 	}
 
-	rf := rules.AlertRules(svcs)
+	rf := rules.AlertRules(svcs, rules.Thresholds{})
 	if len(rf.Groups) == 0 {
 		t.Fatal("expected at least one rule group")
 	}
@@ -138,12 +431,16 @@ func TestAlertRules(t *testing.T) {
 	for _, want := range []string{
 		"ZfsExporterDown",
 		"ZfsPoolDegraded",
+		"ZfsPoolSuspended",
 		"ZfsPoolCapacityWarning",
 		"ZfsServiceDown",
 		"ZfsNFSSharesWithoutService",
 		"ZfsSMBSharesWithoutService",
 		"ZfsDatasetAbnormalGrowth",
 		"ZfsPoolPredictedFull7d",
+		"ZfsDatasetQuotaWarning",
+		"ZfsDatasetQuotaCritical",
+		"ZfsDatasetSnapshotTooOld",
 	} {
 		if !ruleNames[want] {
 			t.Errorf("missing expected alert %q", want)
@@ -157,7 +454,7 @@ func TestAlertRulesNoShareServices(t *testing.T) {
 		{Key: "iscsi", Label: "iSCSI"},
 	}
 
-	rf := rules.AlertRules(svcs)
+	rf := rules.AlertRules(svcs, rules.Thresholds{})
 	for _, r := range rf.Groups[0].Rules {
 		if r.Alert == "ZfsISCSISharesWithoutService" {
 			t.Error("unexpected mismatch alert for iSCSI (no ShareMetric)")
@@ -165,6 +462,387 @@ func TestAlertRulesNoShareServices(t *testing.T) {
 	}
 }
 
+func TestGrafanaAlertRules(t *testing.T) {
+	svcs := []rules.ServiceConfig{
+		{Key: "nfs", Label: "NFS", ShareMetric: "zfs_dataset_share_nfs"},
+	}
+
+	prom := rules.AlertRules(svcs, rules.Thresholds{}).Groups[0].Rules
+	gf := rules.GrafanaAlertRules(svcs, rules.Thresholds{}, rules.GrafanaAlertConfig{Folder: "ZFS", DatasourceUID: "prom", Interval: "1m"})
+
+	if gf.APIVersion != 1 || len(gf.Groups) != 1 {
+		t.Fatalf("got %+v, want one apiVersion 1 group", gf)
+	}
+
+	g := gf.Groups[0]
+	if g.Folder != "ZFS" || g.Interval != "1m" {
+		t.Errorf("group folder/interval = %q/%q", g.Folder, g.Interval)
+	}
+
+	if len(g.Rules) != len(prom) {
+		t.Fatalf("got %d Grafana rules, want %d", len(g.Rules), len(prom))
+	}
+
+	uids := make(map[string]bool, len(g.Rules))
+
+	for i, r := range g.Rules {
+		if r.Title != prom[i].Alert || r.For != prom[i].For || r.Labels["severity"] != prom[i].Labels["severity"] {
+			t.Errorf("rule %d = %s/%s, want %s/%s", i, r.Title, r.For, prom[i].Alert, prom[i].For)
+		}
+
+		if len(r.UID) > 40 || uids[r.UID] {
+			t.Errorf("rule %s: UID %q too long or duplicate", r.Title, r.UID)
+		}
+
+		uids[r.UID] = true
+
+		if r.Condition != "B" || len(r.Data) != 2 || r.Data[0].Model["expr"] != prom[i].Expr || r.Data[0].DatasourceUID != "prom" {
+			t.Errorf("rule %s: unexpected query data %+v", r.Title, r.Data)
+		}
+
+		if r.NoDataState != "OK" {
+			t.Errorf("rule %s: noDataState = %q, want OK", r.Title, r.NoDataState)
+		}
+
+		for k, v := range r.Annotations {
+			if strings.Contains(v, "$value ") {
+				t.Errorf("rule %s: annotation %s still uses $value: %q", r.Title, k, v)
+			}
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashgen.yaml")
+	content := `
+services:
+  - key: nfs
+    label: NFS
+    share_metric: zfs_dataset_share_nfs
+dashboards:
+  combined: false
+output_dir: out
+provisioning:
+  folder: Storage
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if len(cfg.Services) != 1 || cfg.Services[0].Key != "nfs" {
+		t.Errorf("Services = %+v, want only nfs", cfg.Services)
+	}
+
+	// Unset keys keep their defaults.
+	if !cfg.Dashboards.Status || !cfg.Dashboards.Details || cfg.Dashboards.Combined {
+		t.Errorf("Dashboards = %+v, want status and details only", cfg.Dashboards)
+	}
+
+	if cfg.OutputDir != "out" {
+		t.Errorf("OutputDir = %q, want out", cfg.OutputDir)
+	}
+
+	if cfg.Provisioning.Folder != "Storage" || cfg.Provisioning.Path != DefaultConfig.Provisioning.Path {
+		t.Errorf("Provisioning = %+v, want folder Storage and default path", cfg.Provisioning)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	// Loading must not modify the defaults.
+	if len(DefaultConfig.Services) != 3 {
+		t.Errorf("DefaultConfig.Services modified: %+v", DefaultConfig.Services)
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Thresholds = ThresholdConfig{
+		CapacityWarning:  0.85,
+		CapacityCritical: 0.95,
+		Fragmentation:    0.6,
+		ScrubMaxAgeDays:  14,
+		For:              map[string]string{"ZfsPoolCapacityWarning": "30m"},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	alerts := make(map[string]rules.Rule)
+	for _, r := range rules.AlertRules(toRulesServiceConfigs(cfg.Services), toRulesThresholds(cfg.Thresholds, cfg.SLO)).Groups[0].Rules {
+		alerts[r.Alert] = r
+	}
+
+	for name, want := range map[string]string{
+		"ZfsPoolCapacityWarning":   "> 0.85",
+		"ZfsPoolCapacityCritical":  "> 0.95",
+		"ZfsPoolFragmentationHigh": "> 0.60",
+		"ZfsPoolNotScrubbed":       "> 14 * 86400",
+	} {
+		if !strings.HasSuffix(alerts[name].Expr, want) {
+			t.Errorf("%s expr = %q, want suffix %q", name, alerts[name].Expr, want)
+		}
+	}
+
+	// Pools without a recorded scrub (never scrubbed, or resilvered since)
+	// fire as well, unless a scrub is running.
+	if expr := alerts["ZfsPoolNotScrubbed"].Expr; !strings.Contains(expr, "zfs_pool_scrub_active == 0") ||
+		!strings.Contains(expr, "unless on(pool)\n  zfs_pool_last_scrub_age_seconds") {
+		t.Errorf("ZfsPoolNotScrubbed expr = %q, want a clause for pools without a scrub age", expr)
+	}
+
+	if got := alerts["ZfsPoolCapacityWarning"].For; got != "30m" {
+		t.Errorf("ZfsPoolCapacityWarning for = %q, want 30m", got)
+	}
+
+	if got := alerts["ZfsPoolCapacityCritical"].For; got != "5m" {
+		t.Errorf("ZfsPoolCapacityCritical for = %q, want default 5m", got)
+	}
+
+	// The same values color the dashboard panels.
+	b, err := buildDetailsDashboard(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dash, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(dash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`{"value":0.75,"color":"yellow"}`, `{"value":0.85,"color":"orange"}`, `{"value":0.95,"color":"red"}`, `{"value":0.6,"color":"red"}`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("details dashboard missing threshold step %s", want)
+		}
+	}
+}
+
+func TestThresholdsValidate(t *testing.T) {
+	for name, th := range map[string]ThresholdConfig{
+		"warning above critical": {CapacityWarning: 0.9, CapacityCritical: 0.8, Fragmentation: 0.5, ScrubMaxAgeDays: 35},
+		"ratio above one":        {CapacityWarning: 0.8, CapacityCritical: 1.5, Fragmentation: 0.5, ScrubMaxAgeDays: 35},
+		"zero fragmentation":     {CapacityWarning: 0.8, CapacityCritical: 0.9, ScrubMaxAgeDays: 35},
+		"zero scrub age":         {CapacityWarning: 0.8, CapacityCritical: 0.9, Fragmentation: 0.5},
+		"unknown alert":          {CapacityWarning: 0.8, CapacityCritical: 0.9, Fragmentation: 0.5, ScrubMaxAgeDays: 35, For: map[string]string{"ZfsNope": "5m"}},
+		"bad duration":           {CapacityWarning: 0.8, CapacityCritical: 0.9, Fragmentation: 0.5, ScrubMaxAgeDays: 35, For: map[string]string{"ZfsPoolDegraded": "soon"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig
+			cfg.Thresholds = th
+
+			if err := cfg.Validate(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSLOAlerts(t *testing.T) {
+	svcs := toRulesServiceConfigs(DefaultConfig.Services)
+
+	if groups := rules.AlertRules(svcs, rules.Thresholds{}).Groups; len(groups) != 1 {
+		t.Fatalf("SLO alerts generated without targets: %d groups", len(groups))
+	}
+
+	rf := rules.AlertRules(svcs, rules.Thresholds{SLO: rules.SLO{ExporterAvailability: 0.999, PoolOnline: 0.9995}})
+	if len(rf.Groups) != 2 || rf.Groups[1].Name != "zfs_slo" {
+		t.Fatalf("groups = %+v, want zfs_exporter and zfs_slo", rf.Groups)
+	}
+
+	want := map[string]string{
+		"ZfsExporterAvailabilityBudgetBurnFast": "(1 - avg_over_time(zfs_up[5m])) > (14.4 * 0.001)",
+		"ZfsExporterAvailabilityBudgetBurnSlow": "(1 - avg_over_time(zfs_up[3d])) > (1 * 0.001)",
+		"ZfsPoolOnlineBudgetBurnFast":           `(1 - avg_over_time(zfs_pool_health{state="online"}[6h])) > (6 * 0.0005)`,
+		"ZfsPoolOnlineBudgetBurnSlow":           `(1 - avg_over_time(zfs_pool_health{state="online"}[2h])) > (3 * 0.0005)`,
+	}
+
+	slo := rf.Groups[1].Rules
+	if len(slo) != len(want) {
+		t.Fatalf("got %d SLO alerts, want %d", len(slo), len(want))
+	}
+
+	for _, r := range slo {
+		if !strings.Contains(r.Expr, want[r.Alert]) {
+			t.Errorf("%s expr missing %q:\n%s", r.Alert, want[r.Alert], r.Expr)
+		}
+
+		if _, err := promparser.ParseExpr(r.Expr); err != nil {
+			t.Errorf("%s: invalid PromQL: %v", r.Alert, err)
+		}
+	}
+
+	cfg := DefaultConfig
+	cfg.SLO = SLOConfig{ExporterAvailability: 1}
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for a 100% SLO target")
+	}
+}
+
+func TestAlertmanagerRouting(t *testing.T) {
+	svcs := toRulesServiceConfigs(DefaultConfig.Services)
+	cfg := rules.AlertmanagerRouting(svcs, rules.Thresholds{SLO: rules.SLO{PoolOnline: 0.999}})
+
+	zfs := cfg.Route.Routes[0]
+	if !slices.Contains(zfs.GroupBy, "pool") {
+		t.Errorf("ZFS route group_by = %v, want pool", zfs.GroupBy)
+	}
+
+	var receivers []string
+	for _, r := range zfs.Routes {
+		receivers = append(receivers, r.Receiver+" "+r.Matchers[0])
+	}
+
+	if want := []string{`zfs-critical severity="critical"`, `zfs-warning severity="warning"`}; !slices.Equal(receivers, want) {
+		t.Errorf("severity routes = %q, want %q", receivers, want)
+	}
+
+	names := []string{cfg.Route.Receiver}
+	for _, r := range cfg.Receivers {
+		names = append(names, r.Name)
+	}
+
+	for _, r := range zfs.Routes {
+		if !slices.Contains(names, r.Receiver) {
+			t.Errorf("route receiver %q not defined", r.Receiver)
+		}
+	}
+
+	var inhibited []string
+	for _, in := range cfg.InhibitRules {
+		inhibited = append(inhibited, in.TargetMatchers[0])
+	}
+
+	for _, want := range []string{`alertname="ZfsPoolCapacityWarning"`, `alertname="ZfsPoolOnlineBudgetBurnSlow"`} {
+		if !slices.Contains(inhibited, want) {
+			t.Errorf("no inhibit rule for %s in %q", want, inhibited)
+		}
+	}
+
+	if slices.Contains(inhibited, `alertname="ZfsExporterAvailabilityBudgetBurnSlow"`) {
+		t.Error("inhibit rule generated for a disabled SLO")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"unknown key": "output_directory: out\n",
+		"bad type":    "dashboards: yes\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".yaml")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadConfig(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestDashboardProvisioning(t *testing.T) {
+	dir := t.TempDir()
+	writeProvisioning(dir, DefaultConfig)
+
+	data, err := os.ReadFile(filepath.Join(dir, "dashboards", "zfs_exporter.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got dashboardProviders
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got.APIVersion != 1 || len(got.Providers) != 1 {
+		t.Fatalf("got %+v, want one apiVersion 1 provider", got)
+	}
+
+	p := got.Providers[0]
+	if p.Type != "file" || p.Folder != "ZFS" || p.FolderUID != "zfs-exporter" {
+		t.Errorf("provider = %+v", p)
+	}
+
+	if p.Options.Path != DefaultConfig.Provisioning.Path {
+		t.Errorf("options.path = %q, want %q", p.Options.Path, DefaultConfig.Provisioning.Path)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "alerting", "zfs_exporter.yaml")); err != nil {
+		t.Errorf("alert rules not written: %v", err)
+	}
+
+	if want := filepath.Join(dir, "dashboards", "zfs_exporter"); provisioningDashboardsDir(dir) != want {
+		t.Errorf("provisioningDashboardsDir = %q, want %q", provisioningDashboardsDir(dir), want)
+	}
+}
+
+func TestMixin(t *testing.T) {
+	dir := t.TempDir()
+	writeMixin(dir, DefaultConfig, []string{"zfs-status.json", "zfs-devices.json"})
+
+	lib, err := os.ReadFile(filepath.Join(dir, "mixin.libsonnet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"grafanaDashboards+::",
+		"'zfs-status.json': (import 'dashboards/zfs-status.json')",
+		"'zfs-devices.json': (import 'dashboards/zfs-devices.json')",
+		"prometheusAlerts+:: (import 'alerts.json')",
+		"prometheusRules+:: (import 'rules.json')",
+	} {
+		if !strings.Contains(string(lib), want) {
+			t.Errorf("mixin.libsonnet missing %q:\n%s", want, lib)
+		}
+	}
+
+	for file, key := range map[string]string{"alerts.json": "alert", "rules.json": "record"} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rf struct {
+			Groups []struct {
+				Name  string           `json:"name"`
+				Rules []map[string]any `json:"rules"`
+			} `json:"groups"`
+		}
+		if err := json.Unmarshal(data, &rf); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+
+		if len(rf.Groups) == 0 || len(rf.Groups[0].Rules) == 0 {
+			t.Fatalf("%s: no rules", file)
+		}
+
+		if _, ok := rf.Groups[0].Rules[0][key]; !ok {
+			t.Errorf("%s: first rule has no %q key: %v", file, key, rf.Groups[0].Rules[0])
+		}
+	}
+}
+
 func assertJSONField(t *testing.T, data []byte, key, want string) {
 	t.Helper()
 	var m map[string]json.RawMessage
@@ -3,7 +3,9 @@ module github.com/donaldgifford/zfs_exporter/tools/dashgen
 go 1.25.7
 
 require (
+	github.com/donaldgifford/zfs_exporter v0.0.0-00010101000000-000000000000
 	github.com/grafana/grafana-foundation-sdk/go v0.0.7
+	github.com/prometheus/common v0.67.4
 	github.com/prometheus/prometheus v0.309.1
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -17,7 +19,6 @@ require (
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
 	github.com/prometheus/client_golang v1.23.2 // indirect
 	github.com/prometheus/client_model v0.6.2 // indirect
-	github.com/prometheus/common v0.67.4 // indirect
 	github.com/prometheus/procfs v0.16.1 // indirect
 	go.uber.org/atomic v1.11.0 // indirect
 	go.yaml.in/yaml/v2 v2.4.3 // indirect
@@ -25,3 +26,7 @@ require (
 	golang.org/x/text v0.32.0 // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
 )
+
+// The collector package is the source of truth for metric names (see
+// validate.KnownMetrics).
+replace github.com/donaldgifford/zfs_exporter => ../..
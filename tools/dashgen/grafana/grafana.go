@@ -0,0 +1,133 @@
+// Package grafana uploads generated dashboards through the Grafana HTTP API.
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client talks to one Grafana instance with a service account token.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a Client for the Grafana at baseURL (e.g.
+// https://grafana.example.com). token is a service account token with
+// dashboard and folder write access.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// folder is the subset of the folder API response dashgen needs.
+type folder struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+// EnsureFolder returns the UID of the top-level folder named title,
+// creating it if it does not exist.
+func (c *Client) EnsureFolder(ctx context.Context, title string) (string, error) {
+	var folders []folder
+	if err := c.do(ctx, http.MethodGet, "/api/folders?limit=1000", nil, &folders); err != nil {
+		return "", fmt.Errorf("listing folders: %w", err)
+	}
+
+	for _, f := range folders {
+		if f.Title == title {
+			return f.UID, nil
+		}
+	}
+
+	var created folder
+	if err := c.do(ctx, http.MethodPost, "/api/folders", map[string]string{"title": title}, &created); err != nil {
+		return "", fmt.Errorf("creating folder %q: %w", title, err)
+	}
+
+	return created.UID, nil
+}
+
+// PushResult is the Grafana response to a dashboard upload.
+type PushResult struct {
+	UID     string `json:"uid"`
+	URL     string `json:"url"`
+	Version int    `json:"version"`
+	Status  string `json:"status"`
+}
+
+// PushDashboard uploads dash into the folder with folderUID (empty for the
+// General folder). A dashboard with the same UID is overwritten, so pushing
+// again updates it in place and bumps its version.
+func (c *Client) PushDashboard(ctx context.Context, dash any, folderUID, message string) (PushResult, error) {
+	body := map[string]any{
+		"dashboard": dash,
+		"folderUid": folderUID,
+		"overwrite": true,
+		"message":   message,
+	}
+
+	var res PushResult
+	if err := c.do(ctx, http.MethodPost, "/api/dashboards/db", body, &res); err != nil {
+		return PushResult{}, fmt.Errorf("pushing dashboard: %w", err)
+	}
+
+	return res, nil
+}
+
+// do sends a JSON request and decodes a JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader = http.NoBody
+
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeGrafana serves the folder and dashboard endpoints from memory.
+type fakeGrafana struct {
+	folders    []folder
+	dashboards map[string]map[string]any // uid -> upload body
+	versions   map[string]int
+}
+
+func (f *fakeGrafana) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer t0ken" {
+		http.Error(w, `{"message":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/folders":
+		_ = json.NewEncoder(w).Encode(f.folders)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/folders":
+		var req folder
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		created := folder{UID: "f" + req.Title, Title: req.Title}
+		f.folders = append(f.folders, created)
+		_ = json.NewEncoder(w).Encode(created)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		uid := req["dashboard"].(map[string]any)["uid"].(string)
+		f.dashboards[uid] = req
+		f.versions[uid]++
+		_ = json.NewEncoder(w).Encode(PushResult{UID: uid, URL: "/d/" + uid, Version: f.versions[uid], Status: "success"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestEnsureFolder(t *testing.T) {
+	fake := &fakeGrafana{folders: []folder{{UID: "abc", Title: "Storage"}}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	c := NewClient(srv.URL+"/", "t0ken")
+
+	uid, err := c.EnsureFolder(context.Background(), "Storage")
+	if err != nil || uid != "abc" {
+		t.Errorf("existing folder: uid = %q, err = %v", uid, err)
+	}
+
+	uid, err = c.EnsureFolder(context.Background(), "ZFS")
+	if err != nil || uid != "fZFS" {
+		t.Errorf("new folder: uid = %q, err = %v", uid, err)
+	}
+
+	if len(fake.folders) != 2 {
+		t.Errorf("expected folder to be created once, have %+v", fake.folders)
+	}
+}
+
+func TestPushDashboard(t *testing.T) {
+	fake := &fakeGrafana{dashboards: map[string]map[string]any{}, versions: map[string]int{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	c := NewClient(srv.URL, "t0ken")
+	dash := map[string]any{"uid": "zfs-status", "title": "ZFS Status"}
+
+	for want := 1; want <= 2; want++ {
+		res, err := c.PushDashboard(context.Background(), dash, "fZFS", "dashgen")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.UID != "zfs-status" || res.Version != want {
+			t.Errorf("push %d: result = %+v", want, res)
+		}
+	}
+
+	got := fake.dashboards["zfs-status"]
+	if got["folderUid"] != "fZFS" || got["overwrite"] != true || got["message"] != "dashgen" {
+		t.Errorf("upload body = %v", got)
+	}
+}
+
+func TestPushDashboard_Error(t *testing.T) {
+	srv := httptest.NewServer(&fakeGrafana{})
+	t.Cleanup(srv.Close)
+
+	_, err := NewClient(srv.URL, "wrong").PushDashboard(context.Background(), map[string]any{"uid": "x"}, "", "")
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("error = %v, want 401", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/promapi"
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/validate"
+)
+
+// liveExprs returns every panel expression in dashes and every recording and
+// alert rule expression cfg generates.
+func liveExprs(dashes []dashboard.Dashboard, cfg Config) []validate.Expr {
+	var exprs []validate.Expr
+	for _, d := range dashes {
+		exprs = append(exprs, validate.DashboardExprs(d)...)
+	}
+
+	exprs = append(exprs, validate.RuleExprs(rules.RecordingRules())...)
+	exprs = append(exprs, validate.RuleExprs(rules.AlertRules(toRulesServiceConfigs(cfg.Services), toRulesThresholds(cfg.Thresholds, cfg.SLO)))...)
+
+	return exprs
+}
+
+// validateLive checks exprs against the Prometheus at url, prints the
+// result, and reports whether it passed.
+func validateLive(ctx context.Context, url string, exprs []validate.Expr) (bool, error) {
+	result, err := validate.Live(ctx, promapi.NewClient(url), exprs)
+	if err != nil {
+		return false, fmt.Errorf("validating against %s: %w", url, err)
+	}
+
+	fmt.Print(validate.FormatResult(url, result))
+
+	return result.Ok(), nil
+}
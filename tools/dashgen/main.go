@@ -1,8 +1,10 @@
 // dashgen generates Grafana dashboard JSON files and Prometheus rules YAML from
-// a Go config struct using the Grafana Foundation SDK. Run via go generate.
+// a Go config struct using the Grafana Foundation SDK. Run via go generate, or
+// with -config to read the config from a YAML file instead.
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -21,14 +23,66 @@ import (
 
 func main() {
 	validateOnly := flag.Bool("validate", false, "validate dashboards without writing files")
+	configPath := flag.String("config", "", "YAML config file overriding the built-in DefaultConfig")
+	outputDir := flag.String("output-dir", "", "directory for dashboard JSON, overriding the config's output_dir")
+	provision := flag.String("provision", "", "write a Grafana provisioning bundle into this directory instead of output_dir")
+	mixin := flag.String("mixin", "", "write a monitoring mixin (mixin.libsonnet, dashboards, rules) into this directory instead of output_dir")
+	push := flag.Bool("push", false, "upload dashboards to Grafana instead of writing files")
+	grafanaURL := flag.String("grafana-url", os.Getenv("GRAFANA_URL"), "Grafana base URL for -push (env GRAFANA_URL)")
+	grafanaToken := flag.String("grafana-token", os.Getenv("GRAFANA_TOKEN"), "Grafana service account token for -push (env GRAFANA_TOKEN)")
+	grafanaFolder := flag.String("grafana-folder", envOr("GRAFANA_FOLDER", "ZFS"), "Grafana folder for -push, created if missing; empty for General (env GRAFANA_FOLDER)")
+	promURL := flag.String("prom-url", "", "also validate every query against this Prometheus; implies -validate")
 	flag.Parse()
 
+	if *promURL != "" {
+		*validateOnly = true
+	}
+
 	cfg := DefaultConfig
 
+	if *configPath != "" {
+		var err error
+
+		cfg, err = LoadConfig(*configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *outputDir != "" {
+		cfg.OutputDir = *outputDir
+	}
+
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("config validation failed:\n%v", err)
 	}
 
+	if modes := btoi(*push) + btoi(*provision != "") + btoi(*mixin != ""); modes > 1 {
+		log.Fatal("-push, -provision, and -mixin are mutually exclusive")
+	}
+
+	jsonDir := cfg.OutputDir
+
+	switch {
+	case *provision != "":
+		jsonDir = provisioningDashboardsDir(*provision)
+	case *mixin != "":
+		jsonDir = mixinDashboardsDir(*mixin)
+	}
+
+	ctx := context.Background()
+
+	var p *pusher
+
+	if *push && !*validateOnly {
+		var err error
+
+		p, err = newPusher(ctx, *grafanaURL, *grafanaToken, *grafanaFolder)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	type dashEntry struct {
 		filename string
 		builder  func(cfg Config) (*dashboard.DashboardBuilder, error)
@@ -48,8 +102,17 @@ func main() {
 		entries = append(entries, dashEntry{"zfs-combined.json", buildCombinedDashboard})
 	}
 
+	if cfg.Dashboards.Devices {
+		entries = append(entries, dashEntry{"zfs-devices.json", buildDevicesDashboard})
+	}
+
 	hasErrors := false
 
+	var (
+		written []string
+		built   []dashboard.Dashboard
+	)
+
 	for _, e := range entries {
 		builder, err := e.builder(cfg)
 		if err != nil {
@@ -72,37 +135,87 @@ func main() {
 		}
 
 		if *validateOnly {
+			built = append(built, dash)
+
 			continue
 		}
 
-		if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
-			log.Fatalf("creating output directory: %v", err)
+		if p != nil {
+			if err := p.push(ctx, e.filename, dash); err != nil {
+				log.Fatal(err)
+			}
+
+			continue
 		}
 
-		data, err := json.MarshalIndent(dash, "", "  ")
+		writeJSON(jsonDir, e.filename, dash)
+		written = append(written, e.filename)
+	}
+
+	// Generate Prometheus rules, or the rest of a provisioning bundle or
+	// mixin (skip in validate-only and push modes).
+	switch {
+	case *validateOnly || p != nil:
+	case *provision != "":
+		writeProvisioning(*provision, cfg)
+	case *mixin != "":
+		writeMixin(*mixin, cfg, written)
+	default:
+		generateRules(cfg)
+	}
+
+	if *promURL != "" {
+		ok, err := validateLive(ctx, *promURL, liveExprs(built, cfg))
 		if err != nil {
-			log.Fatalf("marshaling %s: %v", e.filename, err)
+			log.Fatal(err)
 		}
 
-		// Append trailing newline for POSIX compliance.
-		data = append(data, '\n')
+		hasErrors = hasErrors || !ok
+	}
 
-		path := filepath.Join(cfg.OutputDir, e.filename)
-		if err := os.WriteFile(path, data, 0o644); err != nil {
-			log.Fatalf("writing %s: %v", path, err)
-		}
+	if hasErrors {
+		os.Exit(1)
+	}
+}
 
-		fmt.Printf("wrote %s\n", path)
+// envOr returns the environment variable key, or def if it is unset.
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
 	}
 
-	// Generate Prometheus rules (skip in validate-only mode).
-	if !*validateOnly {
-		generateRules(cfg)
+	return def
+}
+
+// btoi returns 1 for true and 0 for false.
+func btoi(b bool) int {
+	if b {
+		return 1
 	}
 
-	if hasErrors {
-		os.Exit(1)
+	return 0
+}
+
+// writeJSON writes v as indented JSON to dir/filename.
+func writeJSON(dir, filename string, v any) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatalf("creating output directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatalf("marshaling %s: %v", filename, err)
 	}
+
+	// Append trailing newline for POSIX compliance.
+	data = append(data, '\n')
+
+	path := filepath.Join(dir, filename)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		log.Fatalf("writing %s: %v", path, err)
+	}
+
+	fmt.Printf("wrote %s\n", path)
 }
 
 func generateRules(cfg Config) {
@@ -114,9 +227,14 @@ func generateRules(cfg Config) {
 
 	svcConfigs := toRulesServiceConfigs(cfg.Services)
 
+	th := toRulesThresholds(cfg.Thresholds, cfg.SLO)
+
 	// PrometheusRule CRs for Kubernetes deployment.
 	writeYAML(rulesDir, "zfs-recording-rules.yaml", rules.RecordingPrometheusRule())
-	writeYAML(rulesDir, "zfs-alerts.yaml", rules.AlertPrometheusRule(svcConfigs))
+	writeYAML(rulesDir, "zfs-alerts.yaml", rules.AlertPrometheusRule(svcConfigs, th))
+
+	// Example routing for the alerts, not a Kubernetes resource.
+	writeYAML(rulesDir, "zfs-alertmanager.yaml", rules.AlertmanagerRouting(svcConfigs, th))
 }
 
 func writeYAML(dir, filename string, v any) {
@@ -165,20 +283,53 @@ func toRulesServiceConfigs(svcs []ServiceConfig) []rules.ServiceConfig {
 	return out
 }
 
+// toRulesThresholds converts the main config's thresholds and SLO targets
+// to the rules package's Thresholds type.
+func toRulesThresholds(th ThresholdConfig, slo SLOConfig) rules.Thresholds {
+	return rules.Thresholds{
+		CapacityWarning:  th.CapacityWarning,
+		CapacityCritical: th.CapacityCritical,
+		Fragmentation:    th.Fragmentation,
+		ScrubMaxAgeDays:  th.ScrubMaxAgeDays,
+		For:              th.For,
+		SLO: rules.SLO{
+			ExporterAvailability: slo.ExporterAvailability,
+			PoolOnline:           slo.PoolOnline,
+		},
+	}
+}
+
+// toPanelThresholds converts the main config's thresholds to the panels
+// package's Thresholds type.
+func toPanelThresholds(th ThresholdConfig) panels.Thresholds {
+	return panels.Thresholds{
+		CapacityWarning:  th.CapacityWarning,
+		CapacityCritical: th.CapacityCritical,
+		Fragmentation:    th.Fragmentation,
+	}
+}
+
 func buildStatusDashboard(cfg Config) (*dashboard.DashboardBuilder, error) {
 	return dashboards.BuildStatus(dashboards.StatusConfig{
-		Services: toServiceConfigs(cfg.Services),
+		Services:   toServiceConfigs(cfg.Services),
+		Thresholds: toPanelThresholds(cfg.Thresholds),
 	})
 }
 
 func buildDetailsDashboard(cfg Config) (*dashboard.DashboardBuilder, error) {
 	return dashboards.BuildDetails(dashboards.DetailsConfig{
-		Services: toServiceConfigs(cfg.Services),
+		Services:   toServiceConfigs(cfg.Services),
+		Thresholds: toPanelThresholds(cfg.Thresholds),
 	})
 }
 
 func buildCombinedDashboard(cfg Config) (*dashboard.DashboardBuilder, error) {
 	return dashboards.BuildCombined(dashboards.CombinedConfig{
-		Services: toServiceConfigs(cfg.Services),
+		Services:   toServiceConfigs(cfg.Services),
+		Thresholds: toPanelThresholds(cfg.Thresholds),
 	})
 }
+
+func buildDevicesDashboard(_ Config) (*dashboard.DashboardBuilder, error) {
+	return dashboards.BuildDevices(dashboards.DevicesConfig{})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
+)
+
+// mixinDashboardsDir returns the directory holding dashboard JSON in a
+// monitoring mixin rooted at dir:
+//
+//	dir/mixin.libsonnet     grafanaDashboards, prometheusAlerts, prometheusRules
+//	dir/dashboards/*.json   dashboards
+//	dir/alerts.json         alert rule groups
+//	dir/rules.json          recording rule groups
+//
+// JSON is valid Jsonnet, so mixin.libsonnet imports the files directly and
+// the mixin needs no jsonnet-bundler dependencies.
+func mixinDashboardsDir(dir string) string {
+	return filepath.Join(dir, "dashboards")
+}
+
+// mixinLibsonnet renders mixin.libsonnet for the given dashboard files.
+func mixinLibsonnet(dashboardFiles []string) string {
+	var b strings.Builder
+
+	b.WriteString("// Generated by dashgen. Do not edit.\n{\n  grafanaDashboards+:: {\n")
+
+	for _, f := range dashboardFiles {
+		fmt.Fprintf(&b, "    '%s': (import 'dashboards/%s'),\n", f, f)
+	}
+
+	b.WriteString("  },\n\n")
+	b.WriteString("  prometheusAlerts+:: (import 'alerts.json'),\n\n")
+	b.WriteString("  prometheusRules+:: (import 'rules.json'),\n}\n")
+
+	return b.String()
+}
+
+// writeMixin writes the rules and mixin.libsonnet of the mixin rooted at
+// dir. The dashboards themselves are written by the caller into
+// mixinDashboardsDir.
+func writeMixin(dir string, cfg Config, dashboardFiles []string) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatalf("creating mixin directory: %v", err)
+	}
+
+	svcs := toRulesServiceConfigs(cfg.Services)
+
+	writeJSON(dir, "alerts.json", rules.AlertRules(svcs, toRulesThresholds(cfg.Thresholds, cfg.SLO)))
+	writeJSON(dir, "rules.json", rules.RecordingRules())
+
+	path := filepath.Join(dir, "mixin.libsonnet")
+	if err := os.WriteFile(path, []byte(mixinLibsonnet(dashboardFiles)), 0o644); err != nil {
+		log.Fatalf("writing %s: %v", path, err)
+	}
+
+	fmt.Printf("wrote %s\n", path)
+}
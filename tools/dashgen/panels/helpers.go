@@ -60,6 +60,17 @@ func ThresholdsRedGreen(greenAbove float64) *dashboard.ThresholdsConfigBuilder {
 		})
 }
 
+// ThresholdsGreenRed returns a threshold config that shows green below the
+// threshold value and red at or above it.
+func ThresholdsGreenRed(redAt float64) *dashboard.ThresholdsConfigBuilder {
+	return dashboard.NewThresholdsConfigBuilder().
+		Mode(dashboard.ThresholdsModeAbsolute).
+		Steps([]dashboard.Threshold{
+			{Value: nil, Color: "green"},
+			{Value: cog.ToPtr(redAt), Color: "red"},
+		})
+}
+
 // ThresholdsGreenYellowRed returns a threshold config with green (base),
 // yellow at a warning level, and red at a critical level.
 func ThresholdsGreenYellowRed(yellow, red float64) *dashboard.ThresholdsConfigBuilder {
@@ -97,14 +108,23 @@ func ColorSchemePaletteClassic() *dashboard.FieldColorBuilder {
 		Mode(dashboard.FieldColorModeIdPaletteClassic)
 }
 
-// PoolFilter returns the PromQL pool label filter for the $pool variable.
+// HostFilter returns the PromQL label filter for the $job and $instance
+// variables. Every query includes it so one Prometheus scraping several ZFS
+// hosts does not mix their data.
+func HostFilter() string {
+	return `job=~"$job", instance=~"$instance"`
+}
+
+// PoolFilter returns the PromQL label filter for the $pool variable, scoped
+// to the selected hosts.
 func PoolFilter() string {
-	return `pool=~"$pool"`
+	return HostFilter() + `, pool=~"$pool"`
 }
 
-// ServiceFilter returns a PromQL filter matching a specific service key.
+// ServiceFilter returns a PromQL filter matching a specific service key on
+// the selected hosts.
 func ServiceFilter(serviceKey string) string {
-	return `service="` + serviceKey + `"`
+	return `service="` + serviceKey + `", ` + HostFilter()
 }
 
 // ValueMapOnOff returns a value mapping for 0/1 binary metrics with custom
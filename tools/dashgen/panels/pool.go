@@ -44,8 +44,11 @@ func PoolHealth() *stat.PanelBuilder {
 		})
 }
 
-// PoolCapacity returns a stat panel showing pool capacity as a percentage.
-func PoolCapacity() *stat.PanelBuilder {
+// PoolCapacity returns a stat panel showing pool capacity as a percentage,
+// colored by the capacity alert thresholds.
+func PoolCapacity(th Thresholds) *stat.PanelBuilder {
+	th = th.withDefaults()
+
 	return stat.NewPanelBuilder().
 		Title("Pool Capacity").
 		Description("Allocated bytes as a fraction of total pool size.").
@@ -62,7 +65,7 @@ func PoolCapacity() *stat.PanelBuilder {
 		Max(1).
 		ColorMode(common.BigValueColorModeBackground).
 		GraphMode(common.BigValueGraphModeNone).
-		Thresholds(ThresholdsGreenYellowRed(0.8, 0.9)).
+		Thresholds(ThresholdsGreenYellowRed(th.CapacityWarning, th.CapacityCritical)).
 		ColorScheme(ColorSchemeThresholds())
 }
 
@@ -156,8 +159,11 @@ func PoolUsageOverTime() *timeseries.PanelBuilder {
 		)
 }
 
-// PoolUsageBars returns a bar gauge showing pool usage percentage per pool.
-func PoolUsageBars() *bargauge.PanelBuilder {
+// PoolUsageBars returns a bar gauge showing pool usage percentage per pool,
+// colored by the capacity alert thresholds.
+func PoolUsageBars(th Thresholds) *bargauge.PanelBuilder {
+	th = th.withDefaults()
+
 	return bargauge.NewPanelBuilder().
 		Title("Pool Usage % (Allocated / Total)").
 		Description("Current allocated bytes compared to total pool size.").
@@ -182,9 +188,9 @@ func PoolUsageBars() *bargauge.PanelBuilder {
 				Mode(dashboard.ThresholdsModeAbsolute).
 				Steps([]dashboard.Threshold{
 					{Value: nil, Color: "green"},
-					{Value: cog.ToPtr(0.7), Color: "yellow"},
-					{Value: cog.ToPtr(0.8), Color: "orange"},
-					{Value: cog.ToPtr(0.9), Color: "red"},
+					{Value: cog.ToPtr(th.capacityNotice()), Color: "yellow"},
+					{Value: cog.ToPtr(th.CapacityWarning), Color: "orange"},
+					{Value: cog.ToPtr(th.CapacityCritical), Color: "red"},
 				}),
 		).
 		ColorScheme(
@@ -193,8 +199,11 @@ func PoolUsageBars() *bargauge.PanelBuilder {
 		)
 }
 
-// Fragmentation returns a timeseries panel showing pool fragmentation over time.
-func Fragmentation() *timeseries.PanelBuilder {
+// Fragmentation returns a timeseries panel showing pool fragmentation over
+// time, with the fragmentation alert threshold drawn as a red band.
+func Fragmentation(th Thresholds) *timeseries.PanelBuilder {
+	th = th.withDefaults()
+
 	return timeseries.NewPanelBuilder().
 		Title("Fragmentation Over Time").
 		Description("Pool fragmentation ratio over time. High fragmentation can degrade performance.").
@@ -215,7 +224,7 @@ func Fragmentation() *timeseries.PanelBuilder {
 				Mode(dashboard.ThresholdsModeAbsolute).
 				Steps([]dashboard.Threshold{
 					{Value: nil, Color: "transparent"},
-					{Value: cog.ToPtr(0.5), Color: "red"},
+					{Value: cog.ToPtr(th.Fragmentation), Color: "red"},
 				}),
 		).
 		ThresholdsStyle(
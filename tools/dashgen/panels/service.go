@@ -45,7 +45,7 @@ func ServiceStatusAll() *stat.PanelBuilder {
 		Span(svcStatusAllWidth).
 		Datasource(DSRef()).
 		WithTarget(PromQuery(
-			"zfs_service_up",
+			fmt.Sprintf(`zfs_service_up{%s}`, HostFilter()),
 			"{{ service }}", "A",
 		)).
 		Unit("none").
@@ -61,9 +61,11 @@ func ServiceStatusAll() *stat.PanelBuilder {
 // ShareMismatch returns a stat panel detecting when shares exist but the
 // service is down. Only applicable for services with a ShareMetric.
 func ShareMismatch(svc ServiceConfig) *stat.PanelBuilder {
+	// Matched per instance: shares on one host say nothing about the
+	// service on another.
 	expr := fmt.Sprintf(
-		`(count(%s == 1) > 0) and (zfs_service_up{%s} == 0)`,
-		svc.ShareMetric, ServiceFilter(svc.Key),
+		`(count by (instance) (%s{%s} == 1) > 0) and on(instance) (zfs_service_up{%s} == 0)`,
+		svc.ShareMetric, HostFilter(), ServiceFilter(svc.Key),
 	)
 
 	return stat.NewPanelBuilder().
@@ -107,7 +109,7 @@ func ExporterUp() *stat.PanelBuilder {
 		Height(svcExporterHeight).
 		Span(svcExporterWidth).
 		Datasource(DSRef()).
-		WithTarget(PromQuery("zfs_up", "ZFS commands", "A")).
+		WithTarget(PromQuery(fmt.Sprintf(`zfs_up{%s}`, HostFilter()), "ZFS commands", "A")).
 		Unit("none").
 		ColorMode(common.BigValueColorModeBackground).
 		GraphMode(common.BigValueGraphModeNone).
@@ -0,0 +1,43 @@
+package panels
+
+import "math"
+
+// Thresholds are the site-tunable values the pool panels color against.
+// They mirror the capacity and fragmentation alert thresholds so the
+// dashboards turn red when the alerts fire. Zero fields use
+// DefaultThresholds.
+type Thresholds struct {
+	CapacityWarning  float64 // pool allocated/size ratio, orange
+	CapacityCritical float64 // pool allocated/size ratio, red
+	Fragmentation    float64 // pool fragmentation ratio, red
+}
+
+// DefaultThresholds matches the default alert rules.
+var DefaultThresholds = Thresholds{
+	CapacityWarning:  0.8,
+	CapacityCritical: 0.9,
+	Fragmentation:    0.5,
+}
+
+// withDefaults fills zero fields from DefaultThresholds.
+func (t Thresholds) withDefaults() Thresholds {
+	if t.CapacityWarning == 0 {
+		t.CapacityWarning = DefaultThresholds.CapacityWarning
+	}
+
+	if t.CapacityCritical == 0 {
+		t.CapacityCritical = DefaultThresholds.CapacityCritical
+	}
+
+	if t.Fragmentation == 0 {
+		t.Fragmentation = DefaultThresholds.Fragmentation
+	}
+
+	return t
+}
+
+// capacityNotice is where capacity bars turn yellow: 10 points below the
+// warning threshold, rounded to whole percent.
+func (t Thresholds) capacityNotice() float64 {
+	return math.Round((t.CapacityWarning-0.1)*100) / 100
+}
@@ -0,0 +1,277 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-foundation-sdk/go/common"
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/grafana/grafana-foundation-sdk/go/stat"
+	"github.com/grafana/grafana-foundation-sdk/go/table"
+	"github.com/grafana/grafana-foundation-sdk/go/timeseries"
+)
+
+// Default grid sizes for device panels.
+const (
+	vdevStatWidth    = 6
+	vdevStatHeight   = 4
+	vdevTableWidth   = 12
+	vdevTableHeight  = 10
+	vdevTSWidth      = 24
+	vdevTSHeight     = 8
+	vdevBalanceWidth = 12
+	vdevErrorColumnW = 120
+)
+
+// vdevStates lists the zfs_vdev_state state label values with the color each
+// is shown in, in display order.
+var vdevStates = []struct {
+	state string
+	color string
+}{
+	{"online", "green"},
+	{"degraded", "orange"},
+	{"faulted", "red"},
+	{"offline", "blue"},
+	{"removed", "purple"},
+	{"unavail", "red"},
+}
+
+// vdevErrorsExpr sums all three error counters per device.
+func vdevErrorsExpr() string {
+	return fmt.Sprintf(`zfs_vdev_read_errors{%[1]s} + zfs_vdev_write_errors{%[1]s} + zfs_vdev_checksum_errors{%[1]s}`, PoolFilter())
+}
+
+// DevicesNotOnline returns a stat panel counting devices whose state is not
+// ONLINE. Any non-zero value names a disk to look at in the state table.
+func DevicesNotOnline() *stat.PanelBuilder {
+	return stat.NewPanelBuilder().
+		Title("Devices Not Online").
+		Description("Leaf devices in a state other than ONLINE (degraded, faulted, offline, removed, unavail).").
+		Height(vdevStatHeight).
+		Span(vdevStatWidth).
+		Datasource(DSRef()).
+		WithTarget(PromQuery(
+			fmt.Sprintf(`count(zfs_vdev_state{state!="online", %s} == 1) or vector(0)`, PoolFilter()),
+			"", "A",
+		)).
+		ColorMode(common.BigValueColorModeBackground).
+		GraphMode(common.BigValueGraphModeNone).
+		Thresholds(ThresholdsGreenRed(1)).
+		ColorScheme(ColorSchemeThresholds())
+}
+
+// DevicesWithErrors returns a stat panel counting devices with any read,
+// write, or checksum errors since the last zpool clear.
+func DevicesWithErrors() *stat.PanelBuilder {
+	return stat.NewPanelBuilder().
+		Title("Devices With Errors").
+		Description("Leaf devices with non-zero read, write, or checksum error counters. Counters reset on zpool clear.").
+		Height(vdevStatHeight).
+		Span(vdevStatWidth).
+		Datasource(DSRef()).
+		WithTarget(PromQuery(
+			fmt.Sprintf(`count((%s) > 0) or vector(0)`, vdevErrorsExpr()),
+			"", "A",
+		)).
+		ColorMode(common.BigValueColorModeBackground).
+		GraphMode(common.BigValueGraphModeNone).
+		Thresholds(ThresholdsGreenRed(1)).
+		ColorScheme(ColorSchemeThresholds())
+}
+
+// SparesInUse returns a stat panel showing hot spares currently replacing a
+// failed device, per pool.
+func SparesInUse() *stat.PanelBuilder {
+	return stat.NewPanelBuilder().
+		Title("Spares In Use").
+		Description("Hot spares currently replacing a failed device. Replace the failed disk and detach the spare.").
+		Height(vdevStatHeight).
+		Span(vdevStatWidth).
+		Datasource(DSRef()).
+		WithTarget(PromQuery(
+			fmt.Sprintf(`zfs_pool_spare_in_use{%s}`, PoolFilter()),
+			"{{ pool }}", "A",
+		)).
+		ColorMode(common.BigValueColorModeBackground).
+		GraphMode(common.BigValueGraphModeNone).
+		Thresholds(
+			dashboard.NewThresholdsConfigBuilder().
+				Mode(dashboard.ThresholdsModeAbsolute).
+				Steps([]dashboard.Threshold{
+					{Value: nil, Color: "green"},
+					{Value: cog.ToPtr(1.0), Color: "orange"},
+				}),
+		).
+		ColorScheme(ColorSchemeThresholds())
+}
+
+// SparesAvailable returns a stat panel showing idle hot spares per pool.
+func SparesAvailable() *stat.PanelBuilder {
+	return stat.NewPanelBuilder().
+		Title("Spares Available").
+		Description("Configured hot spares not currently in use.").
+		Height(vdevStatHeight).
+		Span(vdevStatWidth).
+		Datasource(DSRef()).
+		WithTarget(PromQuery(
+			fmt.Sprintf(`zfs_pool_spare_count{%[1]s} - zfs_pool_spare_in_use{%[1]s}`, PoolFilter()),
+			"{{ pool }}", "A",
+		)).
+		ColorMode(common.BigValueColorModeNone).
+		GraphMode(common.BigValueGraphModeNone).
+		Thresholds(ThresholdsGreenOnly()).
+		ColorScheme(ColorSchemeThresholds())
+}
+
+// DeviceStateTable returns a table mapping each pool and vdev to its
+// physical devices and their current state.
+func DeviceStateTable() *table.PanelBuilder {
+	options := make(map[string]dashboard.ValueMappingResult, len(vdevStates))
+	for i, s := range vdevStates {
+		options[s.state] = dashboard.ValueMappingResult{
+			Text:  cog.ToPtr(strings.ToUpper(s.state)),
+			Color: cog.ToPtr(s.color),
+			Index: cog.ToPtr(int32(i)),
+		}
+	}
+
+	return table.NewPanelBuilder().
+		Title("Device State").
+		Description("Every leaf device by pool and top-level vdev, with its current state. Spares are not listed.").
+		Height(vdevTableHeight).
+		Span(vdevTableWidth).
+		Datasource(DSRef()).
+		WithTarget(PromInstantQuery(
+			fmt.Sprintf(`zfs_vdev_state{%s} == 1`, PoolFilter()),
+			"", "A",
+		)).
+		Thresholds(ThresholdsGreenOnly()).
+		ColorScheme(ColorSchemeThresholds()).
+		OverrideByName("state", []dashboard.DynamicConfigValue{
+			{Id: "mappings", Value: []dashboard.ValueMapping{{
+				ValueMap: &dashboard.ValueMap{Type: dashboard.MappingTypeValueToText, Options: options},
+			}}},
+			{Id: "custom.cellOptions", Value: map[string]any{"type": "color-background"}},
+		}).
+		OverrideByName("device", []dashboard.DynamicConfigValue{
+			{Id: "custom.width", Value: 280},
+		}).
+		CellHeight(common.TableCellHeightSm).
+		ShowHeader(true).
+		WithTransformation(organizeTransform(
+			map[string]bool{"Time": true, "__name__": true, "instance": true, "job": true, "Value": true},
+			map[string]int{"pool": 0, "vdev": 1, "device": 2, "state": 3},
+		))
+}
+
+// DeviceErrorTable returns a table of read, write, and checksum error
+// counters per device, worst first.
+func DeviceErrorTable() *table.PanelBuilder {
+	errorCell := []dashboard.DynamicConfigValue{
+		{Id: "custom.cellOptions", Value: map[string]any{"type": "color-background", "mode": "basic"}},
+		{Id: "custom.width", Value: vdevErrorColumnW},
+	}
+
+	return table.NewPanelBuilder().
+		Title("Device Errors").
+		Description("Read, write, and checksum errors per device as reported by zpool status. Counters reset on zpool clear.").
+		Height(vdevTableHeight).
+		Span(vdevTableWidth).
+		Datasource(DSRef()).
+		WithTarget(PromInstantQuery(fmt.Sprintf(`zfs_vdev_read_errors{%s}`, PoolFilter()), "", "A")).
+		WithTarget(PromInstantQuery(fmt.Sprintf(`zfs_vdev_write_errors{%s}`, PoolFilter()), "", "B")).
+		WithTarget(PromInstantQuery(fmt.Sprintf(`zfs_vdev_checksum_errors{%s}`, PoolFilter()), "", "C")).
+		Thresholds(
+			dashboard.NewThresholdsConfigBuilder().
+				Mode(dashboard.ThresholdsModeAbsolute).
+				Steps([]dashboard.Threshold{
+					{Value: nil, Color: "transparent"},
+					{Value: cog.ToPtr(1.0), Color: "red"},
+				}),
+		).
+		ColorScheme(ColorSchemeThresholds()).
+		OverrideByName("Read", errorCell).
+		OverrideByName("Write", errorCell).
+		OverrideByName("Checksum", errorCell).
+		CellHeight(common.TableCellHeightSm).
+		ShowHeader(true).
+		SortBy([]cog.Builder[common.TableSortByFieldState]{
+			common.NewTableSortByFieldStateBuilder().DisplayName("Checksum").Desc(true),
+		}).
+		WithTransformation(dashboard.DataTransformerConfig{Id: "merge", Options: map[string]any{}}).
+		WithTransformation(dashboard.DataTransformerConfig{
+			Id: "organize",
+			Options: map[string]any{
+				"excludeByName": map[string]bool{"Time": true, "__name__": true, "instance": true, "job": true},
+				"indexByName":   map[string]int{"pool": 0, "vdev": 1, "device": 2, "Value #A": 3, "Value #B": 4, "Value #C": 5},
+				"renameByName":  map[string]any{"Value #A": "Read", "Value #B": "Write", "Value #C": "Checksum"},
+			},
+		})
+}
+
+// DeviceErrorsOverTime returns a timeseries panel showing the combined error
+// count of every device with errors, so the moment a disk started failing
+// is visible.
+func DeviceErrorsOverTime() *timeseries.PanelBuilder {
+	return timeseries.NewPanelBuilder().
+		Title("Device Errors Over Time").
+		Description("Read + write + checksum errors per device. Drops to zero mark a zpool clear.").
+		Height(vdevTSHeight).
+		Span(vdevTSWidth).
+		Datasource(DSRef()).
+		WithTarget(PromQuery(
+			fmt.Sprintf(`(%s) > 0`, vdevErrorsExpr()),
+			"{{pool}} {{device}}", "A",
+		)).
+		LineInterpolation(common.LineInterpolationStepAfter).
+		LineWidth(2).
+		ShowPoints(common.VisibilityModeNever).
+		Thresholds(ThresholdsGreenOnly()).
+		ColorScheme(ColorSchemePaletteClassic()).
+		Legend(TableLegend("lastNotNull", "max")).
+		Tooltip(MultiTooltip())
+}
+
+// VdevCapacity returns a timeseries panel showing how full each top-level
+// vdev is, so a newly added vdev filling slower than the old ones (or an
+// old one near full) stands out.
+func VdevCapacity() *timeseries.PanelBuilder {
+	return vdevRatioPanel("Vdev Capacity",
+		"Allocated share of each top-level vdev (zpool list -v). Diverging lines mean writes are unevenly spread.",
+		"zfs_vdev_capacity_ratio")
+}
+
+// VdevFragmentation returns a timeseries panel showing the fragmentation of
+// each top-level vdev.
+func VdevFragmentation() *timeseries.PanelBuilder {
+	return vdevRatioPanel("Vdev Fragmentation",
+		"Free-space fragmentation of each top-level vdev (zpool list -v).",
+		"zfs_vdev_fragmentation_ratio")
+}
+
+// vdevRatioPanel builds a half-width 0-1 timeseries of a per-vdev ratio
+// metric.
+func vdevRatioPanel(title, description, metric string) *timeseries.PanelBuilder {
+	return timeseries.NewPanelBuilder().
+		Title(title).
+		Description(description).
+		Height(vdevTSHeight).
+		Span(vdevBalanceWidth).
+		Datasource(DSRef()).
+		WithTarget(PromQuery(
+			fmt.Sprintf(`%s{%s}`, metric, PoolFilter()),
+			"{{pool}} {{vdev}}", "A",
+		)).
+		Unit("percentunit").
+		Min(0).
+		Max(1).
+		LineInterpolation(common.LineInterpolationSmooth).
+		LineWidth(2).
+		ShowPoints(common.VisibilityModeNever).
+		Thresholds(ThresholdsGreenOnly()).
+		ColorScheme(ColorSchemePaletteClassic()).
+		Legend(TableLegend("lastNotNull", "max")).
+		Tooltip(MultiTooltip())
+}
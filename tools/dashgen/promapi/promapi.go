@@ -0,0 +1,123 @@
+// Package promapi runs queries against the Prometheus HTTP API, for checking
+// generated dashboards and rules against a live server.
+package promapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to one Prometheus server (or any server implementing its
+// query API, such as Thanos or Mimir).
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient returns a Client for the Prometheus at baseURL (e.g.
+// http://prometheus:9090).
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// response is the envelope of every Prometheus API response.
+type response struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+}
+
+// queryData is the data of an instant query response. Only the size of the
+// result matters here, so samples are left undecoded.
+type queryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+// MetricNames returns the name of every metric Prometheus has series for.
+func (c *Client) MetricNames(ctx context.Context) ([]string, error) {
+	var names []string
+	if err := c.get(ctx, "/api/v1/label/__name__/values", nil, &names); err != nil {
+		return nil, fmt.Errorf("listing metric names: %w", err)
+	}
+
+	return names, nil
+}
+
+// Query runs expr as an instant query and returns the number of series (or
+// 1 for a scalar or string) in the result.
+func (c *Client) Query(ctx context.Context, expr string) (int, error) {
+	var data queryData
+	if err := c.get(ctx, "/api/v1/query", url.Values{"query": {expr}}, &data); err != nil {
+		return 0, err
+	}
+
+	switch data.ResultType {
+	case "vector", "matrix":
+		var series []json.RawMessage
+		if err := json.Unmarshal(data.Result, &series); err != nil {
+			return 0, fmt.Errorf("decoding %s result: %w", data.ResultType, err)
+		}
+
+		return len(series), nil
+	default:
+		return 1, nil
+	}
+}
+
+// get sends a GET request and decodes the data field of a successful
+// response into out.
+func (c *Client) get(ctx context.Context, path string, params url.Values, out any) error {
+	u := c.baseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	// Query errors come back as 4xx/5xx with a JSON envelope whose error
+	// field is more useful than the status line.
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+		}
+
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	if r.Status != "success" {
+		return fmt.Errorf("GET %s: %s: %s", path, resp.Status, r.Error)
+	}
+
+	if err := json.Unmarshal(r.Data, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package promapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakePrometheus serves the label values and query endpoints with canned
+// responses.
+func fakePrometheus(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/v1/label/__name__/values":
+			fmt.Fprint(w, `{"status":"success","data":["zfs_pool_health","zfs_up"]}`)
+		case "/api/v1/query":
+			switch q := r.URL.Query().Get("query"); q {
+			case "zfs_up":
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[`+
+					`{"metric":{"instance":"a"},"value":[1,"1"]},{"metric":{"instance":"b"},"value":[1,"1"]}]}}`)
+			case "zfs_missing":
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+			case "1":
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1,"1"]}}`)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestMetricNames(t *testing.T) {
+	c := NewClient(fakePrometheus(t).URL + "/")
+
+	names, err := c.MetricNames(context.Background())
+	if err != nil {
+		t.Fatalf("MetricNames: %v", err)
+	}
+
+	if len(names) != 2 || names[1] != "zfs_up" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	c := NewClient(fakePrometheus(t).URL)
+
+	tests := []struct {
+		expr    string
+		want    int
+		wantErr string
+	}{
+		{expr: "zfs_up", want: 2},
+		{expr: "zfs_missing", want: 0},
+		{expr: "1", want: 1},
+		{expr: "zfs_up{", wantErr: "parse error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			n, err := c.Query(context.Background(), tt.expr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil || n != tt.want {
+				t.Errorf("Query = %d, %v; want %d", n, err, tt.want)
+			}
+		})
+	}
+}
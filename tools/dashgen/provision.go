@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
+)
+
+// provisioningProvider is the name of the dashboard provider dashgen writes,
+// also used for the file and directory names in the bundle.
+const provisioningProvider = "zfs_exporter"
+
+// dashboardProviders is a Grafana dashboards provisioning file.
+type dashboardProviders struct {
+	APIVersion int                 `yaml:"apiVersion"`
+	Providers  []dashboardProvider `yaml:"providers"`
+}
+
+// dashboardProvider is one file-based dashboard provider.
+type dashboardProvider struct {
+	Name                  string                 `yaml:"name"`
+	OrgID                 int                    `yaml:"orgId"`
+	Folder                string                 `yaml:"folder"`
+	FolderUID             string                 `yaml:"folderUid,omitempty"`
+	Type                  string                 `yaml:"type"`
+	DisableDeletion       bool                   `yaml:"disableDeletion"`
+	AllowUIUpdates        bool                   `yaml:"allowUiUpdates"`
+	UpdateIntervalSeconds int                    `yaml:"updateIntervalSeconds"`
+	Options               dashboardProviderPaths `yaml:"options"`
+}
+
+// dashboardProviderPaths holds the options of a file provider.
+type dashboardProviderPaths struct {
+	Path string `yaml:"path"`
+}
+
+// provisioningDashboardsDir returns the directory holding dashboard JSON in
+// a bundle rooted at dir, laid out like /etc/grafana/provisioning:
+//
+//	dir/dashboards/zfs_exporter.yaml   provider config
+//	dir/dashboards/zfs_exporter/*.json dashboards
+//	dir/alerting/zfs_exporter.yaml     Grafana-managed alert rules
+func provisioningDashboardsDir(dir string) string {
+	return filepath.Join(dir, "dashboards", provisioningProvider)
+}
+
+// dashboardProvisioning returns the provider config for p. Dashboards are
+// read-only in the UI so edits are not silently lost on the next reload.
+func dashboardProvisioning(p ProvisioningConfig) dashboardProviders {
+	return dashboardProviders{
+		APIVersion: 1,
+		Providers: []dashboardProvider{{
+			Name:                  provisioningProvider,
+			OrgID:                 1,
+			Folder:                p.Folder,
+			FolderUID:             p.FolderUID,
+			Type:                  "file",
+			UpdateIntervalSeconds: 30,
+			Options:               dashboardProviderPaths{Path: p.Path},
+		}},
+	}
+}
+
+// grafanaAlertRules returns the alert rules as Grafana-managed rules in the
+// provisioning folder.
+func grafanaAlertRules(cfg Config) rules.GrafanaAlertFile {
+	svcs := toRulesServiceConfigs(cfg.Services)
+
+	return rules.GrafanaAlertRules(svcs, toRulesThresholds(cfg.Thresholds, cfg.SLO), rules.GrafanaAlertConfig{
+		Folder:        cfg.Provisioning.Folder,
+		DatasourceUID: cfg.Provisioning.DatasourceUID,
+		Interval:      "1m",
+	})
+}
+
+// writeProvisioning writes the dashboard provider config and the Grafana
+// alert rules of the bundle rooted at dir. The dashboards themselves are
+// written by the caller into provisioningDashboardsDir.
+func writeProvisioning(dir string, cfg Config) {
+	dashDir := filepath.Join(dir, "dashboards")
+	alertDir := filepath.Join(dir, "alerting")
+
+	for _, d := range []string{dashDir, alertDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			log.Fatalf("creating provisioning directory: %v", err)
+		}
+	}
+
+	writeYAML(dashDir, provisioningProvider+".yaml", dashboardProvisioning(cfg.Provisioning))
+	writeYAML(alertDir, provisioningProvider+".yaml", grafanaAlertRules(cfg))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/grafana"
+)
+
+// pusher uploads generated dashboards into one Grafana folder.
+type pusher struct {
+	client    *grafana.Client
+	folderUID string
+}
+
+// newPusher connects to Grafana and resolves (or creates) the target folder.
+func newPusher(ctx context.Context, url, token, folder string) (*pusher, error) {
+	if url == "" {
+		return nil, errors.New("-push requires -grafana-url or GRAFANA_URL")
+	}
+
+	client := grafana.NewClient(url, token)
+
+	p := &pusher{client: client}
+
+	if folder != "" {
+		uid, err := client.EnsureFolder(ctx, folder)
+		if err != nil {
+			return nil, err
+		}
+
+		p.folderUID = uid
+	}
+
+	return p, nil
+}
+
+// push uploads one dashboard, replacing any existing dashboard with the same
+// UID.
+func (p *pusher) push(ctx context.Context, name string, dash any) error {
+	res, err := p.client.PushDashboard(ctx, dash, p.folderUID, "Generated by dashgen")
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	fmt.Printf("pushed %s as %s (version %d)\n", name, res.URL, res.Version)
+
+	return nil
+}
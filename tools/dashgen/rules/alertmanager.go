@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AlertmanagerConfig is an example alertmanager.yml routing the generated
+// alerts. Receivers have no integrations; fill them in before use.
+type AlertmanagerConfig struct {
+	Route        AlertmanagerRoute      `yaml:"route"`
+	Receivers    []AlertmanagerReceiver `yaml:"receivers"`
+	InhibitRules []InhibitRule          `yaml:"inhibit_rules"`
+}
+
+// AlertmanagerRoute is a node of the Alertmanager routing tree. An empty
+// Receiver inherits the parent's.
+type AlertmanagerRoute struct {
+	Receiver string              `yaml:"receiver,omitempty"`
+	Matchers []string            `yaml:"matchers,omitempty"`
+	GroupBy  []string            `yaml:"group_by,omitempty"`
+	Routes   []AlertmanagerRoute `yaml:"routes,omitempty"`
+}
+
+// AlertmanagerReceiver is a named notification target.
+type AlertmanagerReceiver struct {
+	Name string `yaml:"name"`
+}
+
+// InhibitRule mutes target alerts while a matching source alert fires with
+// the same Equal labels.
+type InhibitRule struct {
+	SourceMatchers []string `yaml:"source_matchers"`
+	TargetMatchers []string `yaml:"target_matchers"`
+	Equal          []string `yaml:"equal"`
+}
+
+// defaultReceiver catches everything the ZFS routes do not.
+const defaultReceiver = "default"
+
+// severityOrder lists known severities most urgent first; others sort after
+// them by name.
+var severityOrder = []string{"critical", "warning", "info"}
+
+// inhibitions pairs an alert with the less severe alert it supersedes.
+// Pairs whose alerts are not generated are skipped.
+var inhibitions = []struct {
+	source, target string
+	equal          []string
+}{
+	{"ZfsPoolCapacityCritical", "ZfsPoolCapacityWarning", []string{"instance", "pool"}},
+	{"ZfsDatasetQuotaCritical", "ZfsDatasetQuotaWarning", []string{"instance", "pool", "dataset"}},
+	{"ZfsPoolPredictedFull1d", "ZfsPoolPredictedFull7d", []string{"instance", "pool"}},
+	{"ZfsExporterAvailabilityBudgetBurnFast", "ZfsExporterAvailabilityBudgetBurnSlow", []string{"instance"}},
+	{"ZfsPoolOnlineBudgetBurnFast", "ZfsPoolOnlineBudgetBurnSlow", []string{"instance", "pool"}},
+}
+
+// AlertmanagerRouting returns an example Alertmanager config for the alerts
+// generated from services and th: ZFS alerts are grouped per pool and routed
+// to one receiver per severity label, and critical alerts inhibit their
+// warning counterparts.
+func AlertmanagerRouting(services []ServiceConfig, th Thresholds) AlertmanagerConfig {
+	alerts := make(map[string]bool)
+
+	var severities []string
+
+	for _, g := range alertRuleGroups(services, th) {
+		for _, r := range g.Rules {
+			alerts[r.Alert] = true
+
+			if s := r.Labels["severity"]; s != "" && !slices.Contains(severities, s) {
+				severities = append(severities, s)
+			}
+		}
+	}
+
+	slices.SortFunc(severities, compareSeverity)
+
+	cfg := AlertmanagerConfig{
+		Route: AlertmanagerRoute{
+			Receiver: defaultReceiver,
+			Routes: []AlertmanagerRoute{{
+				Matchers: []string{`alertname=~"Zfs.+"`},
+				GroupBy:  []string{"alertname", "instance", "pool"},
+			}},
+		},
+		Receivers: []AlertmanagerReceiver{{Name: defaultReceiver}},
+	}
+
+	zfs := &cfg.Route.Routes[0]
+
+	for _, s := range severities {
+		receiver := "zfs-" + s
+		zfs.Routes = append(zfs.Routes, AlertmanagerRoute{
+			Receiver: receiver,
+			Matchers: []string{fmt.Sprintf("severity=%q", s)},
+		})
+		cfg.Receivers = append(cfg.Receivers, AlertmanagerReceiver{Name: receiver})
+	}
+
+	for _, in := range inhibitions {
+		if !alerts[in.source] || !alerts[in.target] {
+			continue
+		}
+
+		cfg.InhibitRules = append(cfg.InhibitRules, InhibitRule{
+			SourceMatchers: []string{fmt.Sprintf("alertname=%q", in.source)},
+			TargetMatchers: []string{fmt.Sprintf("alertname=%q", in.target)},
+			Equal:          in.equal,
+		})
+	}
+
+	return cfg
+}
+
+// compareSeverity orders severities by severityOrder, then by name.
+func compareSeverity(a, b string) int {
+	ia, ib := slices.Index(severityOrder, a), slices.Index(severityOrder, b)
+
+	switch {
+	case ia >= 0 && ib >= 0:
+		return ia - ib
+	case ia >= 0:
+		return -1
+	case ib >= 0:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -4,7 +4,9 @@ import "fmt"
 
 // alertRuleGroups generates the alert rule groups. Service-specific mismatch
 // alerts are only generated for services with a ShareMetric configured.
-func alertRuleGroups(services []ServiceConfig) []RuleGroup {
+func alertRuleGroups(services []ServiceConfig, th Thresholds) []RuleGroup {
+	th = th.withDefaults()
+
 	rules := []Rule{
 		// Exporter health.
 		{
@@ -46,6 +48,16 @@ func alertRuleGroups(services []ServiceConfig) []RuleGroup {
 				"description": "Pool {{ $labels.pool }} has experienced too many failures and is no longer accessible.",
 			},
 		},
+		{
+			Alert:  "ZfsPoolSuspended",
+			Expr:   `zfs_pool_health{state="suspended"} == 1`,
+			For:    "0m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "ZFS pool {{ $labels.pool }} is SUSPENDED (all I/O blocked)",
+				"description": "Pool {{ $labels.pool }} suspended I/O after device failures. Processes using it hang until the devices return and zpool clear is run.",
+			},
+		},
 		{
 			Alert: "ZfsPoolDegradedNotResilvering",
 			Expr: `(zfs_pool_health{state="degraded"} == 1)
@@ -80,6 +92,27 @@ func alertRuleGroups(services []ServiceConfig) []RuleGroup {
 				"description": "Resilver progress has not advanced in 30 minutes.",
 			},
 		},
+		// zpool status only reports the latest scan, so a pool that was never
+		// scrubbed, or was resilvered since, has no scrub age at all. The
+		// scan collector's zfs_pool_scrub_active is always present; a scrub
+		// in progress also hides the age, so the for covers a long one.
+		{
+			Alert: "ZfsPoolNotScrubbed",
+			Expr: fmt.Sprintf(`(
+  (zfs_pool_scrub_active == 0 and on(pool) zfs_pool_health{state="online"} == 1)
+    unless on(pool)
+  zfs_pool_last_scrub_age_seconds
+)
+  or
+zfs_pool_last_scrub_age_seconds > %d * 86400`, th.ScrubMaxAgeDays),
+			For:    "24h",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary": "ZFS pool {{ $labels.pool }} is overdue for a scrub",
+				"description": fmt.Sprintf("Pool {{ $labels.pool }} has no completed scrub in the last %d days, or none recorded since "+
+					"it was created or last resilvered.", th.ScrubMaxAgeDays),
+			},
+		},
 		// Pool health catch-all.
 		{
 			Alert: "ZfsPoolNotOnline",
@@ -87,7 +120,9 @@ func alertRuleGroups(services []ServiceConfig) []RuleGroup {
   unless on(pool)
 (zfs_pool_health{state="degraded"} == 1)
   unless on(pool)
-(zfs_pool_health{state="faulted"} == 1)`,
+(zfs_pool_health{state="faulted"} == 1)
+  unless on(pool)
+(zfs_pool_health{state="suspended"} == 1)`,
 			For:    "1m",
 			Labels: map[string]string{"severity": "critical"},
 			Annotations: map[string]string{
@@ -103,10 +138,30 @@ func alertRuleGroups(services []ServiceConfig) []RuleGroup {
 				"summary": "ZFS pool {{ $labels.pool }} is read-only",
 			},
 		},
+		{
+			Alert:  "ZfsPoolNotAutoImported",
+			Expr:   `zfs_pool_info{cachefile="none"} == 1`,
+			For:    "1h",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "ZFS pool {{ $labels.pool }} will not be imported at boot",
+				"description": "Pool {{ $labels.pool }} has cachefile=none, set explicitly or by importing with a temporary altroot (zpool import -R), so it is missing from zpool.cache. Run zpool set cachefile=\"\" {{ $labels.pool }} if it should import on boot.",
+			},
+		},
+		{
+			Alert:  "ZfsPoolDataErrors",
+			Expr:   "zfs_pool_data_errors > 0",
+			For:    "0m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "ZFS pool {{ $labels.pool }} has {{ $value }} permanent data errors",
+				"description": "Pool {{ $labels.pool }} has data that could not be repaired, even if its health is ONLINE. Run zpool status -v {{ $labels.pool }} to list the affected files and restore them from backup.",
+			},
+		},
 		// Capacity.
 		{
 			Alert:  "ZfsPoolCapacityWarning",
-			Expr:   "(zfs_pool_allocated_bytes / zfs_pool_size_bytes) > 0.80",
+			Expr:   "(zfs_pool_allocated_bytes / zfs_pool_size_bytes) > " + ratio(th.CapacityWarning),
 			For:    "15m",
 			Labels: map[string]string{"severity": "warning"},
 			Annotations: map[string]string{
@@ -115,7 +170,7 @@ func alertRuleGroups(services []ServiceConfig) []RuleGroup {
 		},
 		{
 			Alert:  "ZfsPoolCapacityCritical",
-			Expr:   "(zfs_pool_allocated_bytes / zfs_pool_size_bytes) > 0.90",
+			Expr:   "(zfs_pool_allocated_bytes / zfs_pool_size_bytes) > " + ratio(th.CapacityCritical),
 			For:    "5m",
 			Labels: map[string]string{"severity": "critical"},
 			Annotations: map[string]string{
@@ -124,13 +179,51 @@ func alertRuleGroups(services []ServiceConfig) []RuleGroup {
 		},
 		{
 			Alert:  "ZfsPoolFragmentationHigh",
-			Expr:   "zfs_pool_fragmentation_ratio > 0.50",
+			Expr:   "zfs_pool_fragmentation_ratio > " + ratio(th.Fragmentation),
 			For:    "1h",
 			Labels: map[string]string{"severity": "warning"},
 			Annotations: map[string]string{
 				"summary": "ZFS pool {{ $labels.pool }} fragmentation is {{ $value | humanizePercentage }}",
 			},
 		},
+		// Per-dataset thresholds set by dataset owners via ZFS user
+		// properties (exported as zfs_dataset_threshold{kind}).
+		{
+			Alert: "ZfsDatasetQuotaWarning",
+			Expr: `(zfs_dataset_used_bytes / (zfs_dataset_used_bytes + zfs_dataset_available_bytes))
+  > on(dataset, pool) group_left
+zfs_dataset_threshold{kind="quota_warn_ratio"}`,
+			For:    "15m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Dataset {{ $labels.dataset }} is {{ $value | humanizePercentage }} of its quota",
+				"description": "Usage exceeds the quota_warn_ratio threshold set on the dataset.",
+			},
+		},
+		{
+			Alert: "ZfsDatasetQuotaCritical",
+			Expr: `(zfs_dataset_used_bytes / (zfs_dataset_used_bytes + zfs_dataset_available_bytes))
+  > on(dataset, pool) group_left
+zfs_dataset_threshold{kind="quota_crit_ratio"}`,
+			For:    "5m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "Dataset {{ $labels.dataset }} is {{ $value | humanizePercentage }} of its quota",
+				"description": "Usage exceeds the quota_crit_ratio threshold set on the dataset.",
+			},
+		},
+		{
+			Alert: "ZfsDatasetSnapshotTooOld",
+			Expr: `(time() - zfs_dataset_snapshot_newest_timestamp_seconds)
+  > on(dataset, pool) group_left
+zfs_dataset_threshold{kind="snapshot_max_age"}`,
+			For:    "15m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Newest snapshot of dataset {{ $labels.dataset }} is {{ $value | humanizeDuration }} old",
+				"description": "The newest snapshot is older than the snapshot_max_age threshold set on the dataset.",
+			},
+		},
 		// Service down (generic, applies to all configured services).
 		{
 			Alert:  "ZfsServiceDown",
@@ -161,6 +254,40 @@ func alertRuleGroups(services []ServiceConfig) []RuleGroup {
 		})
 	}
 
+	rules = append(rules, Rule{
+		Alert:  "ZfsDatasetNFSNotExported",
+		Expr:   "zfs_dataset_nfs_exported == 0",
+		For:    "10m",
+		Labels: map[string]string{"severity": "warning"},
+		Annotations: map[string]string{
+			"summary":     "Dataset {{ $labels.dataset }} has sharenfs set but is not exported on {{ $labels.instance }}",
+			"description": "The kernel NFS export table does not list the dataset's mountpoint. Check exportfs -v and the sharenfs options. Requires --collector.nfs.",
+		},
+	})
+
+	rules = append(rules, Rule{
+		Alert:  "ZfsTimerInactive",
+		Expr:   "zfs_timer_active == 0",
+		For:    "1h",
+		Labels: map[string]string{"severity": "warning"},
+		Annotations: map[string]string{
+			"summary":     "systemd timer {{ $labels.timer }} is not scheduled on {{ $labels.instance }}",
+			"description": "The timer is stopped, disabled, or masked, so its scrub or snapshot job will not run. Check systemctl list-timers --all. Requires --host.timers.",
+		},
+	})
+
+	// Snapshot retention.
+	rules = append(rules, Rule{
+		Alert:  "ZfsSnapshotPolicyViolation",
+		Expr:   "zfs_dataset_snapshot_policy_compliant == 0",
+		For:    "15m",
+		Labels: map[string]string{"severity": "warning"},
+		Annotations: map[string]string{
+			"summary":     "No recent {{ $labels.class }} snapshot of {{ $labels.dataset }} on {{ $labels.instance }}",
+			"description": "The newest {{ $labels.class }} snapshot is older than the class's maximum age. Check that sanoid or zfs-auto-snapshot is still running. Requires --snapshot.policies.",
+		},
+	})
+
 	// Anomaly detection alerts.
 	rules = append(rules,
 		Rule{
@@ -221,22 +348,30 @@ and
 		},
 	)
 
-	return []RuleGroup{
+	th.applyFor(rules)
+
+	groups := []RuleGroup{
 		{
 			Name:  "zfs_exporter",
 			Rules: rules,
 		},
 	}
+
+	if g, ok := sloRuleGroup(th.SLO); ok {
+		groups = append(groups, g)
+	}
+
+	return groups
 }
 
 // AlertRules generates the alert rules as a raw Prometheus RuleFile.
-func AlertRules(services []ServiceConfig) RuleFile {
-	return RuleFile{Groups: alertRuleGroups(services)}
+func AlertRules(services []ServiceConfig, th Thresholds) RuleFile {
+	return RuleFile{Groups: alertRuleGroups(services, th)}
 }
 
 // AlertPrometheusRule generates the alert rules wrapped in a
 // Kubernetes PrometheusRule CR.
-func AlertPrometheusRule(services []ServiceConfig) PrometheusRule {
+func AlertPrometheusRule(services []ServiceConfig, th Thresholds) PrometheusRule {
 	return PrometheusRule{
 		APIVersion: "monitoring.coreos.com/v1",
 		Kind:       "PrometheusRule",
@@ -247,6 +382,6 @@ func AlertPrometheusRule(services []ServiceConfig) PrometheusRule {
 				"prometheus": "system-rules-prometheus",
 			},
 		},
-		Spec: PrometheusRuleSpec{Groups: alertRuleGroups(services)},
+		Spec: PrometheusRuleSpec{Groups: alertRuleGroups(services, th)},
 	}
 }
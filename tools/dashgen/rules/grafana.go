@@ -0,0 +1,131 @@
+package rules
+
+import "strings"
+
+// GrafanaAlertConfig controls how alert rules are converted to Grafana
+// Alerting provisioning format.
+type GrafanaAlertConfig struct {
+	// Folder is the title of the Grafana folder the rule group lives in.
+	Folder string
+
+	// DatasourceUID is the UID of the Prometheus datasource the rules query.
+	DatasourceUID string
+
+	// Interval is the group evaluation interval, e.g. "1m".
+	Interval string
+}
+
+// GrafanaAlertFile is a Grafana Alerting provisioning file
+// (provisioning/alerting/*.yaml).
+type GrafanaAlertFile struct {
+	APIVersion int                 `yaml:"apiVersion"`
+	Groups     []GrafanaAlertGroup `yaml:"groups"`
+}
+
+// GrafanaAlertGroup is a Grafana-managed rule group.
+type GrafanaAlertGroup struct {
+	OrgID    int                `yaml:"orgId"`
+	Name     string             `yaml:"name"`
+	Folder   string             `yaml:"folder"`
+	Interval string             `yaml:"interval"`
+	Rules    []GrafanaAlertRule `yaml:"rules"`
+}
+
+// GrafanaAlertRule is one Grafana-managed alert rule.
+type GrafanaAlertRule struct {
+	UID          string             `yaml:"uid"`
+	Title        string             `yaml:"title"`
+	Condition    string             `yaml:"condition"`
+	Data         []GrafanaAlertData `yaml:"data"`
+	NoDataState  string             `yaml:"noDataState"`
+	ExecErrState string             `yaml:"execErrState"`
+	For          string             `yaml:"for"`
+	Labels       map[string]string  `yaml:"labels,omitempty"`
+	Annotations  map[string]string  `yaml:"annotations,omitempty"`
+}
+
+// GrafanaAlertData is one query or expression of a Grafana alert rule.
+type GrafanaAlertData struct {
+	RefID             string             `yaml:"refId"`
+	RelativeTimeRange *RelativeTimeRange `yaml:"relativeTimeRange,omitempty"`
+	DatasourceUID     string             `yaml:"datasourceUid"`
+	Model             map[string]any     `yaml:"model"`
+}
+
+// RelativeTimeRange is a query time range in seconds before evaluation.
+type RelativeTimeRange struct {
+	From int `yaml:"from"`
+	To   int `yaml:"to"`
+}
+
+// grafanaExprDatasource is the pseudo-datasource UID of server-side
+// expressions.
+const grafanaExprDatasource = "__expr__"
+
+// GrafanaAlertRules converts the Prometheus alert rules into Grafana-managed
+// alert rules, for Grafana Alerting without a separate Alertmanager.
+//
+// Each rule runs its PromQL as an instant query (A) and fires for every
+// series the query returns (B), matching Prometheus semantics: an empty
+// result is OK rather than No Data. Annotations referring to $value are
+// rewritten to the query value, $values.A.Value.
+func GrafanaAlertRules(services []ServiceConfig, th Thresholds, cfg GrafanaAlertConfig) GrafanaAlertFile {
+	var out []GrafanaAlertRule
+
+	for _, g := range alertRuleGroups(services, th) {
+		for _, r := range g.Rules {
+			out = append(out, grafanaAlertRule(r, cfg.DatasourceUID))
+		}
+	}
+
+	return GrafanaAlertFile{
+		APIVersion: 1,
+		Groups: []GrafanaAlertGroup{{
+			OrgID:    1,
+			Name:     "zfs_exporter",
+			Folder:   cfg.Folder,
+			Interval: cfg.Interval,
+			Rules:    out,
+		}},
+	}
+}
+
+// grafanaAlertRule converts one Prometheus alert rule.
+func grafanaAlertRule(r Rule, datasourceUID string) GrafanaAlertRule {
+	annotations := make(map[string]string, len(r.Annotations))
+	for k, v := range r.Annotations {
+		annotations[k] = strings.ReplaceAll(v, "$value ", "$values.A.Value ")
+	}
+
+	return GrafanaAlertRule{
+		UID:       strings.ToLower(r.Alert),
+		Title:     r.Alert,
+		Condition: "B",
+		Data: []GrafanaAlertData{
+			{
+				RefID:             "A",
+				RelativeTimeRange: &RelativeTimeRange{From: 600},
+				DatasourceUID:     datasourceUID,
+				Model: map[string]any{
+					"refId":   "A",
+					"expr":    r.Expr,
+					"instant": true,
+				},
+			},
+			{
+				RefID:         "B",
+				DatasourceUID: grafanaExprDatasource,
+				Model: map[string]any{
+					"refId":      "B",
+					"type":       "math",
+					"expression": "is_number($A) || is_nan($A) || is_inf($A)",
+				},
+			},
+		},
+		NoDataState:  "OK",
+		ExecErrState: "Error",
+		For:          r.For,
+		Labels:       r.Labels,
+		Annotations:  annotations,
+	}
+}
@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SLO holds availability targets as fractions of 1, e.g. 0.999. A zero
+// target generates no alerts for that SLO.
+type SLO struct {
+	// ExporterAvailability is the share of time zfs_up should be 1, i.e.
+	// zpool and zfs commands succeed.
+	ExporterAvailability float64
+
+	// PoolOnline is the share of time every pool should be ONLINE.
+	PoolOnline float64
+}
+
+// burnWindow is one multi-window burn-rate condition: the error budget
+// burns faster than factor over both the long and the short window. The
+// short window makes the alert resolve soon after the burn stops.
+type burnWindow struct {
+	long, short string
+	factor      float64
+}
+
+// Burn-rate windows from the Google SRE workbook for a 30-day budget. Fast
+// burns (2% and 5% of the budget in 1h and 6h) page; slow burns (10% in 3d
+// at 1x, 10% in 1d at 3x) open a ticket.
+var (
+	fastBurn = []burnWindow{{"1h", "5m", 14.4}, {"6h", "30m", 6}}
+	slowBurn = []burnWindow{{"1d", "2h", 3}, {"3d", "6h", 1}}
+)
+
+// sloIndicator is an availability SLO over a 0/1 good-state series.
+type sloIndicator struct {
+	alert  string // alert name prefix
+	name   string // slo label value
+	good   string // selector that is 1 when the target is available
+	target float64
+	what   string // what is unavailable, for annotations
+}
+
+// sloRuleGroup returns the burn-rate alerts for every SLO with a target,
+// and false if none has one.
+func sloRuleGroup(slo SLO) (RuleGroup, bool) {
+	indicators := []sloIndicator{
+		{
+			alert:  "ZfsExporterAvailability",
+			name:   "exporter-availability",
+			good:   "zfs_up",
+			target: slo.ExporterAvailability,
+			what:   "ZFS commands on {{ $labels.instance }}",
+		},
+		{
+			alert:  "ZfsPoolOnline",
+			name:   "pool-online",
+			good:   `zfs_pool_health{state="online"}`,
+			target: slo.PoolOnline,
+			what:   "Pool {{ $labels.pool }} on {{ $labels.instance }}",
+		},
+	}
+
+	var rules []Rule
+
+	for _, ind := range indicators {
+		if ind.target == 0 {
+			continue
+		}
+
+		rules = append(rules,
+			burnRateRule(ind, "BudgetBurnFast", "critical", fastBurn),
+			burnRateRule(ind, "BudgetBurnSlow", "warning", slowBurn),
+		)
+	}
+
+	if len(rules) == 0 {
+		return RuleGroup{}, false
+	}
+
+	return RuleGroup{Name: "zfs_slo", Rules: rules}, true
+}
+
+// burnRateRule returns an alert firing when any of windows burns the error
+// budget of ind.
+func burnRateRule(ind sloIndicator, suffix, severity string, windows []burnWindow) Rule {
+	budget := decimal(1 - ind.target)
+	target := decimal(ind.target * 100)
+
+	conds := make([]string, len(windows))
+	spans := make([]string, len(windows))
+
+	for i, w := range windows {
+		factor := decimal(w.factor)
+		conds[i] = fmt.Sprintf(`(
+  (1 - avg_over_time(%[1]s[%[2]s])) > (%[4]s * %[5]s)
+and
+  (1 - avg_over_time(%[1]s[%[3]s])) > (%[4]s * %[5]s)
+)`, ind.good, w.long, w.short, factor, budget)
+		spans[i] = fmt.Sprintf("%sx over %s and %s", factor, w.long, w.short)
+	}
+
+	return Rule{
+		Alert:  ind.alert + suffix,
+		Expr:   strings.Join(conds, "\nor\n"),
+		For:    "0m",
+		Labels: map[string]string{"severity": severity, "slo": ind.name},
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("%s is burning its %s%% availability error budget", ind.what, target),
+			"description": fmt.Sprintf("Unavailability exceeds the rate the %s%% SLO allows by %s.",
+				target, strings.Join(spans, ", or ")),
+		},
+	}
+}
+
+// decimal formats v without the float noise of, say, 1 - 0.999.
+func decimal(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e9)/1e9, 'f', -1, 64)
+}
@@ -0,0 +1,91 @@
+package rules
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Thresholds are the site-tunable parts of the alert rules. Zero fields use
+// DefaultThresholds.
+type Thresholds struct {
+	CapacityWarning  float64 // ZfsPoolCapacityWarning allocated/size ratio
+	CapacityCritical float64 // ZfsPoolCapacityCritical allocated/size ratio
+	Fragmentation    float64 // ZfsPoolFragmentationHigh ratio
+	ScrubMaxAgeDays  int     // ZfsPoolNotScrubbed days since the last scrub
+
+	// For overrides the for: duration of alerts by alert name. SLO alerts
+	// are not affected.
+	For map[string]string
+
+	// SLO adds burn-rate alerts for the availability targets it sets.
+	SLO SLO
+}
+
+// DefaultThresholds are the thresholds of the shipped alerts.yml.
+var DefaultThresholds = Thresholds{
+	CapacityWarning:  0.8,
+	CapacityCritical: 0.9,
+	Fragmentation:    0.5,
+	ScrubMaxAgeDays:  35,
+}
+
+// withDefaults fills zero fields from DefaultThresholds.
+func (t Thresholds) withDefaults() Thresholds {
+	if t.CapacityWarning == 0 {
+		t.CapacityWarning = DefaultThresholds.CapacityWarning
+	}
+
+	if t.CapacityCritical == 0 {
+		t.CapacityCritical = DefaultThresholds.CapacityCritical
+	}
+
+	if t.Fragmentation == 0 {
+		t.Fragmentation = DefaultThresholds.Fragmentation
+	}
+
+	if t.ScrubMaxAgeDays == 0 {
+		t.ScrubMaxAgeDays = DefaultThresholds.ScrubMaxAgeDays
+	}
+
+	return t
+}
+
+// applyFor replaces the for: duration of every rule named in t.For.
+func (t Thresholds) applyFor(rules []Rule) {
+	for i := range rules {
+		if d, ok := t.For[rules[i].Alert]; ok {
+			rules[i].For = d
+		}
+	}
+}
+
+// AlertNames returns the names of the alerts generated for services, for
+// validating Thresholds.For keys.
+func AlertNames(services []ServiceConfig) []string {
+	var names []string
+
+	for _, g := range alertRuleGroups(services, Thresholds{}) {
+		for _, r := range g.Rules {
+			names = append(names, r.Alert)
+		}
+	}
+
+	return names
+}
+
+// ratio formats a threshold ratio for PromQL with at least two decimals, so
+// 0.8 renders as 0.80.
+func ratio(v float64) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+
+	_, frac, ok := strings.Cut(s, ".")
+	if !ok {
+		return s + ".00"
+	}
+
+	if len(frac) < 2 {
+		s += strings.Repeat("0", 2-len(frac))
+	}
+
+	return s
+}
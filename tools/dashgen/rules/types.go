@@ -1,5 +1,8 @@
 // Package rules generates Prometheus recording and alert rules YAML from the
-// same service configuration that drives dashboard generation.
+// same service configuration that drives dashboard generation. The alert rules
+// can also be rendered as Grafana-managed rules (see GrafanaAlertRules), and
+// AlertmanagerRouting derives an example Alertmanager config from their
+// severity labels.
 package rules
 
 // PrometheusRule is a Kubernetes PrometheusRule CR that wraps rule groups
@@ -25,29 +28,29 @@ type PrometheusRuleSpec struct {
 
 // RuleFile is the top-level Prometheus rules file structure.
 type RuleFile struct {
-	Groups []RuleGroup `yaml:"groups"`
+	Groups []RuleGroup `yaml:"groups" json:"groups"`
 }
 
 // RuleGroup is a named set of recording or alert rules.
 type RuleGroup struct {
-	Name     string `yaml:"name"`
-	Interval string `yaml:"interval,omitempty"`
-	Rules    []Rule `yaml:"rules"`
+	Name     string `yaml:"name"               json:"name"`
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Rules    []Rule `yaml:"rules"              json:"rules"`
 }
 
 // Rule represents a single recording or alert rule.
 type Rule struct {
 	// Recording rule fields.
-	Record string `yaml:"record,omitempty"`
+	Record string `yaml:"record,omitempty" json:"record,omitempty"`
 
 	// Alert rule fields.
-	Alert string `yaml:"alert,omitempty"`
-	For   string `yaml:"for,omitempty"`
+	Alert string `yaml:"alert,omitempty" json:"alert,omitempty"`
+	For   string `yaml:"for,omitempty"   json:"for,omitempty"`
 
 	// Common fields.
-	Expr        string            `yaml:"expr"`
-	Labels      map[string]string `yaml:"labels,omitempty"`
-	Annotations map[string]string `yaml:"annotations,omitempty"`
+	Expr        string            `yaml:"expr"                  json:"expr"`
+	Labels      map[string]string `yaml:"labels,omitempty"      json:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 }
 
 // ServiceConfig mirrors the main config's service definition for rules generation.
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"os"
 	"path/filepath"
 	"testing"
@@ -13,15 +14,21 @@ import (
 	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
 )
 
+// update rewrites stale committed files instead of failing:
+//
+//	go test -run TestStaleness -update
+var update = flag.Bool("update", false, "rewrite stale committed dashboards and rules in place")
+
 // TestStaleness verifies that committed dashboard JSON and rules YAML files
-// match what the generator produces. If this test fails, run `make dashboards`
-// to regenerate.
+// match what the generator produces. If this test fails, run it again with
+// -update (or `make dashboards`) to regenerate.
 func TestStaleness(t *testing.T) {
 	cfg := DefaultConfig
 	svcs := toServiceConfigs(cfg.Services)
+	th := toPanelThresholds(cfg.Thresholds)
 
 	t.Run("zfs-status.json", func(t *testing.T) {
-		b, err := dashboards.BuildStatus(dashboards.StatusConfig{Services: svcs})
+		b, err := dashboards.BuildStatus(dashboards.StatusConfig{Services: svcs, Thresholds: th})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -29,7 +36,7 @@ func TestStaleness(t *testing.T) {
 	})
 
 	t.Run("zfs-details.json", func(t *testing.T) {
-		b, err := dashboards.BuildDetails(dashboards.DetailsConfig{Services: svcs})
+		b, err := dashboards.BuildDetails(dashboards.DetailsConfig{Services: svcs, Thresholds: th})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -37,20 +44,33 @@ func TestStaleness(t *testing.T) {
 	})
 
 	t.Run("zfs-combined.json", func(t *testing.T) {
-		b, err := dashboards.BuildCombined(dashboards.CombinedConfig{Services: svcs})
+		b, err := dashboards.BuildCombined(dashboards.CombinedConfig{Services: svcs, Thresholds: th})
 		if err != nil {
 			t.Fatal(err)
 		}
 		assertDashboardFresh(t, cfg.OutputDir, "zfs-combined.json", b)
 	})
 
+	t.Run("zfs-devices.json", func(t *testing.T) {
+		b, err := dashboards.BuildDevices(dashboards.DevicesConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertDashboardFresh(t, cfg.OutputDir, "zfs-devices.json", b)
+	})
+
 	t.Run("zfs-recording-rules.yaml", func(t *testing.T) {
 		assertRulesFresh(t, cfg.RulesDir(), "zfs-recording-rules.yaml", rules.RecordingPrometheusRule())
 	})
 
 	t.Run("zfs-alerts.yaml", func(t *testing.T) {
 		rsvcs := toRulesServiceConfigs(cfg.Services)
-		assertRulesFresh(t, cfg.RulesDir(), "zfs-alerts.yaml", rules.AlertPrometheusRule(rsvcs))
+		assertRulesFresh(t, cfg.RulesDir(), "zfs-alerts.yaml", rules.AlertPrometheusRule(rsvcs, toRulesThresholds(cfg.Thresholds, cfg.SLO)))
+	})
+
+	t.Run("zfs-alertmanager.yaml", func(t *testing.T) {
+		rsvcs := toRulesServiceConfigs(cfg.Services)
+		assertRulesFresh(t, cfg.RulesDir(), "zfs-alertmanager.yaml", rules.AlertmanagerRouting(rsvcs, toRulesThresholds(cfg.Thresholds, cfg.SLO)))
 	})
 }
 
@@ -67,15 +87,7 @@ func assertDashboardFresh(t *testing.T, dir, filename string, b *dashboard.Dashb
 	}
 	want = append(want, '\n')
 
-	path := filepath.Join(dir, filename)
-	got, err := os.ReadFile(path)
-	if err != nil {
-		t.Fatalf("reading committed file %s: %v", path, err)
-	}
-
-	if string(got) != string(want) {
-		t.Errorf("%s is stale — run `make dashboards` to regenerate", filename)
-	}
+	assertFileFresh(t, filepath.Join(dir, filename), want)
 }
 
 func assertRulesFresh(t *testing.T, dir, filename string, rf any) {
@@ -87,13 +99,32 @@ func assertRulesFresh(t *testing.T, dir, filename string, rf any) {
 	}
 	want := append([]byte("---\n"), body...)
 
-	path := filepath.Join(dir, filename)
+	assertFileFresh(t, filepath.Join(dir, filename), want)
+}
+
+// assertFileFresh compares the committed file at path with want, rewriting
+// it when -update is set.
+func assertFileFresh(t *testing.T, path string, want []byte) {
+	t.Helper()
+
 	got, err := os.ReadFile(path)
-	if err != nil {
+	if err != nil && !(*update && os.IsNotExist(err)) {
 		t.Fatalf("reading committed file %s: %v", path, err)
 	}
 
-	if string(got) != string(want) {
-		t.Errorf("%s is stale — run `make dashboards` to regenerate", filename)
+	if string(got) == string(want) {
+		return
 	}
+
+	if *update {
+		if err := os.WriteFile(path, want, 0o644); err != nil {
+			t.Fatalf("updating %s: %v", path, err)
+		}
+
+		t.Logf("updated %s", path)
+
+		return
+	}
+
+	t.Errorf("%s is stale — run `go test -run TestStaleness -update` or `make dashboards` to regenerate", filepath.Base(path))
 }
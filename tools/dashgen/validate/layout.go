@@ -0,0 +1,74 @@
+package validate
+
+import "github.com/grafana/grafana-foundation-sdk/go/dashboard"
+
+// gridColumns is the width of the Grafana dashboard grid.
+const gridColumns = 24
+
+// gridItem is a panel or row and where it sits on the grid.
+type gridItem struct {
+	title string
+	row   bool
+	pos   dashboard.GridPos
+}
+
+// collectGrid returns every positioned panel and row, including the panels
+// inside collapsed rows. Grafana stores those at the position they take when
+// the row is expanded, so the whole set must tile without overlaps.
+func collectGrid(dash dashboard.Dashboard) []gridItem {
+	var out []gridItem
+	add := func(title *string, row bool, pos *dashboard.GridPos) {
+		if pos == nil {
+			return
+		}
+		t := ""
+		if title != nil {
+			t = *title
+		}
+		out = append(out, gridItem{title: t, row: row, pos: *pos})
+	}
+
+	for _, por := range dash.Panels {
+		if por.Panel != nil {
+			add(por.Panel.Title, false, por.Panel.GridPos)
+		}
+		if por.RowPanel != nil {
+			add(por.RowPanel.Title, true, por.RowPanel.GridPos)
+			for _, p := range por.RowPanel.Panels {
+				add(p.Title, false, p.GridPos)
+			}
+		}
+	}
+	return out
+}
+
+// checkLayout verifies that every panel fits in the grid, rows span its full
+// width, and nothing overlaps.
+func checkLayout(r *Result, dashTitle string, items []gridItem) {
+	for _, it := range items {
+		p := it.pos
+		switch {
+		case p.W == 0 || p.H == 0:
+			r.errorf("%s > %s: zero-size gridPos %dx%d", dashTitle, it.title, p.W, p.H)
+		case p.X+p.W > gridColumns:
+			r.errorf("%s > %s: gridPos x=%d w=%d exceeds %d columns", dashTitle, it.title, p.X, p.W, gridColumns)
+		case it.row && (p.X != 0 || p.W != gridColumns):
+			r.errorf("%s > row %s: gridPos x=%d w=%d does not span the full width", dashTitle, it.title, p.X, p.W)
+		}
+	}
+
+	for i, a := range items {
+		for _, b := range items[i+1:] {
+			if overlaps(a.pos, b.pos) {
+				r.errorf("%s: %q (x=%d y=%d w=%d h=%d) overlaps %q (x=%d y=%d w=%d h=%d)",
+					dashTitle, a.title, a.pos.X, a.pos.Y, a.pos.W, a.pos.H,
+					b.title, b.pos.X, b.pos.Y, b.pos.W, b.pos.H)
+			}
+		}
+	}
+}
+
+// overlaps reports whether two grid rectangles share any cell.
+func overlaps(a, b dashboard.GridPos) bool {
+	return a.X < b.X+b.W && b.X < a.X+a.W && a.Y < b.Y+b.H && b.Y < a.Y+a.H
+}
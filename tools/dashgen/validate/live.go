@@ -0,0 +1,158 @@
+package validate
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	promparser "github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
+)
+
+// Querier runs queries against a live Prometheus. promapi.Client implements
+// it.
+type Querier interface {
+	MetricNames(ctx context.Context) ([]string, error)
+	Query(ctx context.Context, expr string) (int, error)
+}
+
+// Expr is one PromQL expression to check live.
+type Expr struct {
+	// Source says where the expression came from, for messages.
+	Source string
+	Expr   string
+	// WantData is false for expressions that are normally empty, such as
+	// alert conditions; only their selectors are checked.
+	WantData bool
+}
+
+// DashboardExprs returns the expression of every panel target in dash.
+func DashboardExprs(dash dashboard.Dashboard) []Expr {
+	title := "unknown"
+	if dash.Title != nil {
+		title = *dash.Title
+	}
+
+	var exprs []Expr
+	for _, p := range collectPanels(dash) {
+		for _, t := range p.Targets {
+			if t.Expr != "" {
+				exprs = append(exprs, Expr{Source: title + " > " + p.Title, Expr: t.Expr, WantData: true})
+			}
+		}
+	}
+	return exprs
+}
+
+// RuleExprs returns the expression of every rule in rf. Recording rules must
+// return data; alert rules need not.
+func RuleExprs(rf rules.RuleFile) []Expr {
+	var exprs []Expr
+	for _, g := range rf.Groups {
+		for _, r := range g.Rules {
+			if r.Record != "" {
+				exprs = append(exprs, Expr{Source: "record " + r.Record, Expr: r.Expr, WantData: true})
+			} else {
+				exprs = append(exprs, Expr{Source: "alert " + r.Alert, Expr: r.Expr})
+			}
+		}
+	}
+	return exprs
+}
+
+// Live checks exprs against a running Prometheus. A metric Prometheus has
+// never seen, or a query it rejects, is an error: the deployed exporter does
+// not match the dashboards. A selector matching no series, or a query
+// returning no data, is a warning since it may just reflect the host (no
+// spares, no errors, a disabled collector).
+func Live(ctx context.Context, q Querier, exprs []Expr) (Result, error) {
+	var r Result
+
+	names, err := q.MetricNames(ctx)
+	if err != nil {
+		return r, err
+	}
+
+	known := make(map[string]bool, len(names))
+	for _, n := range names {
+		known[n] = true
+	}
+
+	// Dashboards share selectors and rules; query each once.
+	counts := make(map[string]int)
+	query := func(expr string) (int, error) {
+		if n, ok := counts[expr]; ok {
+			return n, nil
+		}
+		n, err := q.Query(ctx, expr)
+		if err == nil {
+			counts[expr] = n
+		}
+		return n, err
+	}
+
+	for _, e := range exprs {
+		checkLive(&r, e, known, query)
+	}
+	return r, nil
+}
+
+// checkLive checks one expression: its metric names, then each selector,
+// then the whole query.
+func checkLive(r *Result, e Expr, known map[string]bool, query func(string) (int, error)) {
+	sanitized := grafanaVarRe.ReplaceAllString(e.Expr, ".*")
+	parsed, err := promparser.ParseExpr(sanitized)
+	if err != nil {
+		return // reported by the static checks
+	}
+
+	missing := false
+	for _, name := range extractMetricNames(parsed) {
+		if !known[name] {
+			r.errorf("%s: metric %q not found in Prometheus", e.Source, name)
+			missing = true
+		}
+	}
+	if missing {
+		return
+	}
+
+	for _, sel := range vectorSelectors(parsed) {
+		n, err := query(sel)
+		if err != nil {
+			r.errorf("%s: selector %s: %s", e.Source, sel, err)
+			return
+		}
+		if n == 0 {
+			r.warnf("%s: selector %s matches no series", e.Source, sel)
+			return
+		}
+	}
+
+	if !e.WantData {
+		return
+	}
+
+	n, err := query(sanitized)
+	if err != nil {
+		r.errorf("%s: query failed: %s\n  expr: %s", e.Source, err, e.Expr)
+		return
+	}
+	if n == 0 {
+		r.warnf("%s: query returned no data\n  expr: %s", e.Source, e.Expr)
+	}
+}
+
+// vectorSelectors returns every vector selector in node, without range or
+// offset, so each can be queried on its own.
+func vectorSelectors(node promparser.Node) []string {
+	var sels []string
+	promparser.Inspect(node, func(n promparser.Node, _ []promparser.Node) error {
+		if vs, ok := n.(*promparser.VectorSelector); ok {
+			plain := &promparser.VectorSelector{Name: vs.Name, LabelMatchers: vs.LabelMatchers}
+			sels = append(sels, plain.String())
+		}
+		return nil
+	})
+	return sels
+}
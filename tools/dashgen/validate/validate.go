@@ -1,5 +1,6 @@
 // Package validate checks generated dashboards for correctness: PromQL syntax,
-// metric name cross-referencing, and panel structure invariants.
+// metric name cross-referencing, and panel structure and grid layout
+// invariants.
 package validate
 
 import (
@@ -10,6 +11,9 @@ import (
 	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
 	"github.com/grafana/grafana-foundation-sdk/go/prometheus"
 	promparser "github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/donaldgifford/zfs_exporter/collector"
+	"github.com/donaldgifford/zfs_exporter/tools/dashgen/rules"
 )
 
 // Result holds the outcome of validating one or more dashboards.
@@ -32,29 +36,29 @@ func (r *Result) warnf(format string, args ...any) {
 // grafanaVarRe matches Grafana template variable references like $pool or ${datasource}.
 var grafanaVarRe = regexp.MustCompile(`\$\{?\w+\}?`)
 
-// KnownMetrics is the set of metric names exported by the ZFS exporter.
-// Derived from the prometheus.NewDesc calls in collector/collector.go.
-var KnownMetrics = map[string]bool{
-	"zfs_up":                      true,
-	"zfs_scrape_duration_seconds": true,
-	// Pool metrics.
-	"zfs_pool_health":              true,
-	"zfs_pool_allocated_bytes":     true,
-	"zfs_pool_size_bytes":          true,
-	"zfs_pool_free_bytes":          true,
-	"zfs_pool_fragmentation_ratio": true,
-	"zfs_pool_resilver_active":     true,
-	"zfs_pool_scrub_active":        true,
-	// Dataset metrics.
-	"zfs_dataset_used_bytes":      true,
-	"zfs_dataset_available_bytes": true,
-	"zfs_dataset_share_nfs":       true,
-	"zfs_dataset_share_smb":       true,
-	// Service metrics.
-	"zfs_service_up": true,
-	// Recording rules (not exported by the exporter, but expected in dashboards).
-	"zfs:dataset_used_bytes:avg7d":    true,
-	"zfs:dataset_used_bytes:stddev7d": true,
+// KnownMetrics is the set of metric names dashboards may query: every metric
+// the collector describes, the series Prometheus derives from its histograms,
+// and the recording rules dashgen generates. It is built from the collector's
+// descriptors, so new metrics need no change here.
+var KnownMetrics = knownMetrics()
+
+// histogramSuffixes are the series Prometheus exposes for each histogram.
+var histogramSuffixes = []string{"_bucket", "_sum", "_count"}
+
+func knownMetrics() map[string]bool {
+	known := make(map[string]bool)
+	for _, name := range collector.MetricNames() {
+		known[name] = true
+		for _, suffix := range histogramSuffixes {
+			known[name+suffix] = true
+		}
+	}
+	for _, g := range rules.RecordingRules().Groups {
+		for _, r := range g.Rules {
+			known[r.Record] = true
+		}
+	}
+	return known
 }
 
 // Dashboard validates a single built dashboard.
@@ -70,6 +74,7 @@ func Dashboard(dash dashboard.Dashboard) Result {
 	checkPromQL(&r, title, allPanels)
 	checkMetricNames(&r, title, allPanels)
 	checkUniqueIDs(&r, title, allPanels)
+	checkLayout(&r, title, collectGrid(dash))
 
 	return r
 }
@@ -104,8 +109,12 @@ func extractPanel(p dashboard.Panel) panel {
 	}
 	var targets []prometheus.Dataquery
 	for _, t := range p.Targets {
-		if pq, ok := t.(*prometheus.Dataquery); ok {
-			targets = append(targets, *pq)
+		// Builders store queries by value; accept pointers too.
+		switch q := t.(type) {
+		case prometheus.Dataquery:
+			targets = append(targets, q)
+		case *prometheus.Dataquery:
+			targets = append(targets, *q)
 		}
 	}
 	return panel{Title: title, ID: p.Id, Targets: targets}